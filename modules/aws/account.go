@@ -21,7 +21,7 @@ func GetAccountId(t testing.TestingT) string {
 
 // GetAccountIdE gets the Account ID for the currently logged in IAM User.
 func GetAccountIdE(t testing.TestingT) (string, error) {
-	stsClient, err := NewStsClientE(t, defaultRegion)
+	stsClient, err := NewStsClientE(t, getDefaultRegion())
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +48,7 @@ func extractAccountIDFromARN(arn string) (string, error) {
 
 // NewStsClientE creates a new STS client.
 func NewStsClientE(t testing.TestingT, region string) (*sts.STS, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -1,11 +1,35 @@
 package aws
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/route53"
 
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
 
+const (
+	// acmValidationRecordsMaxRetries and acmValidationRecordsSleepBetweenRetries bound how long to wait for ACM to
+	// generate the DNS validation records of a new certificate, which usually takes a few seconds.
+	acmValidationRecordsMaxRetries          = 30
+	acmValidationRecordsSleepBetweenRetries = 2 * time.Second
+
+	// acmCertificateIssuedPollInterval is how often the status of a certificate is checked while waiting for it to be
+	// issued.
+	acmCertificateIssuedPollInterval = 15 * time.Second
+
+	// acmDeleteCertificateMaxRetries and acmDeleteCertificateSleepBetweenRetries bound how long to wait for a deleted load
+	// balancer or distribution to release a certificate, so that it can be deleted.
+	acmDeleteCertificateMaxRetries          = 30
+	acmDeleteCertificateSleepBetweenRetries = 10 * time.Second
+)
+
 // GetAcmCertificateArn gets the ACM certificate for the given domain name in the given region.
 func GetAcmCertificateArn(t testing.TestingT, awsRegion string, certDomainName string) string {
 	arn, err := GetAcmCertificateArnE(t, awsRegion, certDomainName)
@@ -36,6 +60,213 @@ func GetAcmCertificateArnE(t testing.TestingT, awsRegion string, certDomainName
 	return "", nil
 }
 
+// RequestAcmCertificateAndValidate requests an ACM certificate for the given domain name and subject alternative names,
+// validates it with DNS records in the given Route 53 hosted zone, and waits up to the given timeout for it to be
+// issued. This will fail the test if there is an error.
+func RequestAcmCertificateAndValidate(t testing.TestingT, awsRegion string, hostedZoneID string, domainName string, subjectAlternativeNames []string, timeout time.Duration) string {
+	certArn, err := RequestAcmCertificateAndValidateE(t, awsRegion, hostedZoneID, domainName, subjectAlternativeNames, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certArn
+}
+
+// RequestAcmCertificateAndValidateE requests an ACM certificate for the given domain name and subject alternative names,
+// creates the DNS validation records of each name in the given Route 53 hosted zone, waits up to the given timeout for
+// the certificate to be issued (usually a few minutes), and returns its ARN. The validation records are deleted once
+// the certificate is issued, and the certificate is deleted if it can't be issued. Delete the certificate when done:
+//
+//	certArn := aws.RequestAcmCertificateAndValidate(t, awsRegion, hostedZoneID, "test-abc123.example.com", nil, 15*time.Minute)
+//	defer aws.DeleteAcmCertificate(t, awsRegion, certArn)
+//
+// Certificates for CloudFront distributions must be requested in us-east-1.
+func RequestAcmCertificateAndValidateE(t testing.TestingT, awsRegion string, hostedZoneID string, domainName string, subjectAlternativeNames []string, timeout time.Duration) (string, error) {
+	acmClient, err := NewAcmClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	route53Client, err := NewRoute53ClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+
+	input := &acm.RequestCertificateInput{
+		DomainName:       aws.String(domainName),
+		ValidationMethod: aws.String(acm.ValidationMethodDns),
+	}
+	if len(subjectAlternativeNames) > 0 {
+		input.SubjectAlternativeNames = aws.StringSlice(append([]string{domainName}, subjectAlternativeNames...))
+	}
+	logger.Logf(t, "Requesting ACM certificate for %s", domainName)
+	output, err := acmClient.RequestCertificate(input)
+	if err != nil {
+		return "", err
+	}
+	certArn := aws.StringValue(output.CertificateArn)
+
+	if err := validateAcmCertificate(t, acmClient, route53Client, hostedZoneID, certArn, timeout); err != nil {
+		if deleteErr := DeleteAcmCertificateE(t, awsRegion, certArn); deleteErr != nil {
+			logger.Logf(t, "Failed to delete ACM certificate %s: %v", certArn, deleteErr)
+		}
+		return "", err
+	}
+	return certArn, nil
+}
+
+// validateAcmCertificate creates the DNS validation records of the given certificate in the given hosted zone, waits
+// for the certificate to be issued, and deletes the records.
+func validateAcmCertificate(t testing.TestingT, acmClient *acm.ACM, route53Client *route53.Route53, hostedZoneID string, certArn string, timeout time.Duration) error {
+	var records []*route53.ResourceRecordSet
+	_, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the DNS validation records of ACM certificate %s.", certArn),
+		acmValidationRecordsMaxRetries,
+		acmValidationRecordsSleepBetweenRetries,
+		func() (string, error) {
+			output, err := acmClient.DescribeCertificate(&acm.DescribeCertificateInput{CertificateArn: aws.String(certArn)})
+			if err != nil {
+				return "", err
+			}
+			records, err = getAcmValidationRecords(output.Certificate)
+			return "", err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	logger.Logf(t, "Creating %d DNS validation records for ACM certificate %s in hosted zone %s", len(records), certArn, hostedZoneID)
+	if err := changeAcmValidationRecords(route53Client, hostedZoneID, records, route53.ChangeActionUpsert); err != nil {
+		return err
+	}
+	defer func() {
+		if err := changeAcmValidationRecords(route53Client, hostedZoneID, records, route53.ChangeActionDelete); err != nil {
+			logger.Logf(t, "Failed to delete the DNS validation records of ACM certificate %s: %v", certArn, err)
+		}
+	}()
+
+	sleepBetweenRetries := acmCertificateIssuedPollInterval
+	if timeout < sleepBetweenRetries {
+		sleepBetweenRetries = timeout
+	}
+	maxRetries := 1
+	if sleepBetweenRetries > 0 {
+		maxRetries = int(timeout/sleepBetweenRetries) + 1
+	}
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for ACM certificate %s to be issued.", certArn),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			output, err := acmClient.DescribeCertificate(&acm.DescribeCertificateInput{CertificateArn: aws.String(certArn)})
+			if err != nil {
+				return "", err
+			}
+			if err := checkAcmCertificateIssued(output.Certificate); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("ACM certificate %s is now issued", certArn), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// getAcmValidationRecords returns the distinct DNS validation records of the given certificate, or an error if ACM
+// hasn't generated all of them yet. Names that share a validation domain (e.g., example.com and *.example.com) share
+// their record.
+func getAcmValidationRecords(cert *acm.CertificateDetail) ([]*route53.ResourceRecordSet, error) {
+	records := []*route53.ResourceRecordSet{}
+	seen := map[string]bool{}
+	for _, validation := range cert.DomainValidationOptions {
+		record := validation.ResourceRecord
+		if record == nil {
+			return nil, fmt.Errorf("ACM has not generated the DNS validation record of %s yet", aws.StringValue(validation.DomainName))
+		}
+		name := aws.StringValue(record.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		records = append(records, &route53.ResourceRecordSet{
+			Name:            record.Name,
+			Type:            record.Type,
+			TTL:             aws.Int64(60),
+			ResourceRecords: []*route53.ResourceRecord{{Value: record.Value}},
+		})
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("ACM certificate %s has no DNS validation records yet", aws.StringValue(cert.CertificateArn))
+	}
+	return records, nil
+}
+
+// changeAcmValidationRecords applies the given action to the given records in the given hosted zone.
+func changeAcmValidationRecords(client *route53.Route53, hostedZoneID string, records []*route53.ResourceRecordSet, action string) error {
+	changes := []*route53.Change{}
+	for _, record := range records {
+		changes = append(changes, &route53.Change{Action: aws.String(action), ResourceRecordSet: record})
+	}
+	_, err := client.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneID),
+		ChangeBatch: &route53.ChangeBatch{
+			Comment: aws.String("Terratest ACM certificate validation"),
+			Changes: changes,
+		},
+	})
+	return err
+}
+
+// checkAcmCertificateIssued returns an error if the given certificate is not issued, wrapped in a retry.FatalError if it
+// will never be issued.
+func checkAcmCertificateIssued(cert *acm.CertificateDetail) error {
+	certArn := aws.StringValue(cert.CertificateArn)
+	status := aws.StringValue(cert.Status)
+	switch status {
+	case acm.CertificateStatusIssued:
+		return nil
+	case acm.CertificateStatusPendingValidation:
+		return NewAcmCertificateNotIssuedError(certArn, status, "")
+	default:
+		return retry.FatalError{Underlying: NewAcmCertificateNotIssuedError(certArn, status, aws.StringValue(cert.FailureReason))}
+	}
+}
+
+// DeleteAcmCertificate deletes the ACM certificate with the given ARN. This will fail the test if there is an error.
+func DeleteAcmCertificate(t testing.TestingT, awsRegion string, certArn string) {
+	if err := DeleteAcmCertificateE(t, awsRegion, certArn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// DeleteAcmCertificateE deletes the ACM certificate with the given ARN, retrying for up to 5 minutes while it is still
+// in use, e.g., by a load balancer that is being deleted.
+func DeleteAcmCertificateE(t testing.TestingT, awsRegion string, certArn string) error {
+	acmClient, err := NewAcmClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	_, err = retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Deleting ACM certificate %s.", certArn),
+		acmDeleteCertificateMaxRetries,
+		acmDeleteCertificateSleepBetweenRetries,
+		func() (string, error) {
+			_, err := acmClient.DeleteCertificate(&acm.DeleteCertificateInput{CertificateArn: aws.String(certArn)})
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() != acm.ErrCodeResourceInUseException {
+				return "", retry.FatalError{Underlying: err}
+			}
+			return "", err
+		},
+	)
+	if fatalErr, isFatalErr := err.(retry.FatalError); isFatalErr {
+		return fatalErr.Underlying
+	}
+	return err
+}
+
 // NewAcmClient create a new ACM client.
 func NewAcmClient(t testing.TestingT, region string) *acm.ACM {
 	client, err := NewAcmClientE(t, region)
@@ -47,7 +278,7 @@ func NewAcmClient(t testing.TestingT, region string) *acm.ACM {
 
 // NewAcmClientE creates a new ACM client.
 func NewAcmClientE(t testing.TestingT, awsRegion string) (*acm.ACM, error) {
-	sess, err := NewAuthenticatedSession(awsRegion)
+	sess, err := newAuthenticatedSessionForTest(t, awsRegion)
 	if err != nil {
 		return nil, err
 	}
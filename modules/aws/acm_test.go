@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAcmValidationRecords(t *testing.T) {
+	t.Parallel()
+
+	record := &acm.ResourceRecord{
+		Name:  aws.String("_abc.example.com."),
+		Type:  aws.String("CNAME"),
+		Value: aws.String("_xyz.acm-validations.aws."),
+	}
+	cert := &acm.CertificateDetail{
+		DomainValidationOptions: []*acm.DomainValidation{
+			{DomainName: aws.String("example.com"), ResourceRecord: record},
+			{DomainName: aws.String("*.example.com"), ResourceRecord: record},
+		},
+	}
+
+	records, err := getAcmValidationRecords(cert)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "_abc.example.com.", aws.StringValue(records[0].Name))
+	assert.Equal(t, "CNAME", aws.StringValue(records[0].Type))
+	assert.Equal(t, "_xyz.acm-validations.aws.", aws.StringValue(records[0].ResourceRecords[0].Value))
+
+	cert.DomainValidationOptions = append(cert.DomainValidationOptions, &acm.DomainValidation{DomainName: aws.String("www.example.org")})
+	_, err = getAcmValidationRecords(cert)
+	assert.Error(t, err)
+
+	_, err = getAcmValidationRecords(&acm.CertificateDetail{})
+	assert.Error(t, err)
+}
+
+func TestCheckAcmCertificateIssued(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkAcmCertificateIssued(&acm.CertificateDetail{Status: aws.String(acm.CertificateStatusIssued)}))
+	assert.IsType(t, AcmCertificateNotIssuedError{}, checkAcmCertificateIssued(&acm.CertificateDetail{Status: aws.String(acm.CertificateStatusPendingValidation)}))
+
+	err := checkAcmCertificateIssued(&acm.CertificateDetail{
+		Status:        aws.String(acm.CertificateStatusFailed),
+		FailureReason: aws.String(acm.FailureReasonCaaError),
+	})
+	require.IsType(t, retry.FatalError{}, err)
+	assert.Contains(t, err.Error(), acm.FailureReasonCaaError)
+}
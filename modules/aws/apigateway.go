@@ -0,0 +1,289 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// ApiGatewayRequest is a request to an API Gateway REST or HTTP API.
+type ApiGatewayRequest struct {
+	Method  string            // The HTTP method, or GET if empty
+	Path    string            // The path of the request relative to the stage, including the query string (e.g., /orders?status=open)
+	Headers map[string]string // The headers of the request
+	Body    string            // The body of the request
+}
+
+// ApiGatewayResponse is a response from an API Gateway REST or HTTP API.
+type ApiGatewayResponse struct {
+	StatusCode int
+	Headers    http.Header
+	Body       string
+	Log        string // The execution log, only set by InvokeRestApiIntegration
+}
+
+// RequestID returns the ID API Gateway assigned to the request, which is the ID access log entries are written with.
+func (response *ApiGatewayResponse) RequestID() string {
+	if requestID := response.Headers.Get("X-Amzn-Requestid"); requestID != "" {
+		return requestID
+	}
+	// HTTP APIs return the request ID in a different header.
+	return response.Headers.Get("Apigw-Requestid")
+}
+
+// InvokeApiGateway sends the given request to the API Gateway stage with the given invoke URL (e.g.,
+// https://abc123.execute-api.us-east-1.amazonaws.com/prod) without authentication, and returns the response.
+func InvokeApiGateway(t testing.TestingT, invokeURL string, request ApiGatewayRequest) *ApiGatewayResponse {
+	response, err := InvokeApiGatewayE(t, invokeURL, request)
+	require.NoError(t, err)
+	return response
+}
+
+// InvokeApiGatewayE sends the given request to the API Gateway stage with the given invoke URL (e.g.,
+// https://abc123.execute-api.us-east-1.amazonaws.com/prod) without authentication, and returns the response. Only
+// errors sending the request are returned, so the response may have any status code.
+func InvokeApiGatewayE(t testing.TestingT, invokeURL string, request ApiGatewayRequest) (*ApiGatewayResponse, error) {
+	httpRequest, err := newApiGatewayHTTPRequest(invokeURL, request)
+	if err != nil {
+		return nil, err
+	}
+	return sendApiGatewayRequest(t, httpRequest)
+}
+
+// InvokeApiGatewayWithIam sends the given request to the API Gateway stage with the given invoke URL, signed with
+// SigV4 using the AWS credentials of the test, and returns the response. This is for routes that use IAM authorization.
+func InvokeApiGatewayWithIam(t testing.TestingT, region string, invokeURL string, request ApiGatewayRequest) *ApiGatewayResponse {
+	response, err := InvokeApiGatewayWithIamE(t, region, invokeURL, request)
+	require.NoError(t, err)
+	return response
+}
+
+// InvokeApiGatewayWithIamE sends the given request to the API Gateway stage with the given invoke URL, signed with
+// SigV4 using the AWS credentials of the test, and returns the response. This is for routes that use IAM authorization.
+// Only errors sending the request are returned, so the response may have any status code (e.g., 403 if the
+// credentials are not allowed to invoke the route).
+func InvokeApiGatewayWithIamE(t testing.TestingT, region string, invokeURL string, request ApiGatewayRequest) (*ApiGatewayResponse, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := newApiGatewayHTTPRequest(invokeURL, request)
+	if err != nil {
+		return nil, err
+	}
+	signer := v4.NewSigner(sess.Config.Credentials)
+	if _, err := signer.Sign(httpRequest, bytes.NewReader([]byte(request.Body)), "execute-api", region, time.Now()); err != nil {
+		return nil, err
+	}
+	return sendApiGatewayRequest(t, httpRequest)
+}
+
+// InvokeApiGatewayWithAuthorizerToken sends the given request to the API Gateway stage with the given invoke URL, with
+// the given token in the Authorization header, and returns the response. This is for routes that use a Lambda (custom)
+// authorizer or a JWT authorizer.
+func InvokeApiGatewayWithAuthorizerToken(t testing.TestingT, invokeURL string, request ApiGatewayRequest, token string) *ApiGatewayResponse {
+	response, err := InvokeApiGatewayWithAuthorizerTokenE(t, invokeURL, request, token)
+	require.NoError(t, err)
+	return response
+}
+
+// InvokeApiGatewayWithAuthorizerTokenE sends the given request to the API Gateway stage with the given invoke URL, with
+// the given token in the Authorization header, and returns the response. This is for routes that use a Lambda (custom)
+// authorizer or a JWT authorizer. For authorizers with another identity source, set it in the headers of the request
+// and use InvokeApiGatewayE instead.
+func InvokeApiGatewayWithAuthorizerTokenE(t testing.TestingT, invokeURL string, request ApiGatewayRequest, token string) (*ApiGatewayResponse, error) {
+	httpRequest, err := newApiGatewayHTTPRequest(invokeURL, request)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Authorization", token)
+	return sendApiGatewayRequest(t, httpRequest)
+}
+
+func newApiGatewayHTTPRequest(invokeURL string, request ApiGatewayRequest) (*http.Request, error) {
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	url := strings.TrimSuffix(invokeURL, "/") + "/" + strings.TrimPrefix(request.Path, "/")
+	httpRequest, err := http.NewRequest(method, url, strings.NewReader(request.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range request.Headers {
+		httpRequest.Header.Set(name, value)
+	}
+	return httpRequest, nil
+}
+
+func sendApiGatewayRequest(t testing.TestingT, httpRequest *http.Request) (*ApiGatewayResponse, error) {
+	logger.Logf(t, "Sending %s request to %s", httpRequest.Method, httpRequest.URL)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &ApiGatewayResponse{StatusCode: response.StatusCode, Headers: response.Header, Body: string(body)}, nil
+}
+
+// InvokeRestApiIntegration invokes the integration of the method of the given resource (e.g., /orders/{id}) of the
+// given REST API with the given request, and returns the response and the execution log.
+func InvokeRestApiIntegration(t testing.TestingT, region string, restApiID string, resourcePath string, request ApiGatewayRequest) *ApiGatewayResponse {
+	response, err := InvokeRestApiIntegrationE(t, region, restApiID, resourcePath, request)
+	require.NoError(t, err)
+	return response
+}
+
+// InvokeRestApiIntegrationE invokes the integration of the method of the given resource (e.g., /orders/{id}) of the
+// given REST API with the given request, and returns the response and the execution log. This uses the API Gateway test
+// invoke feature, so the method doesn't need to be deployed to a stage, and authorizers are skipped. The path of the
+// request is the resource path with the path parameters filled in (e.g., /orders/42), or the resource path if empty.
+func InvokeRestApiIntegrationE(t testing.TestingT, region string, restApiID string, resourcePath string, request ApiGatewayRequest) (*ApiGatewayResponse, error) {
+	client, err := NewApiGatewayClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	resourceID, err := getRestApiResourceID(client, region, restApiID, resourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := request.Path
+	if path == "" {
+		path = resourcePath
+	}
+	input := &apigateway.TestInvokeMethodInput{
+		RestApiId:           aws.String(restApiID),
+		ResourceId:          aws.String(resourceID),
+		HttpMethod:          aws.String(method),
+		PathWithQueryString: aws.String(path),
+		Headers:             aws.StringMap(request.Headers),
+	}
+	if request.Body != "" {
+		input.Body = aws.String(request.Body)
+	}
+
+	logger.Logf(t, "Invoking integration of %s %s of REST API %s", method, resourcePath, restApiID)
+	output, err := client.TestInvokeMethod(input)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	for name, values := range output.MultiValueHeaders {
+		for _, value := range values {
+			headers.Add(name, aws.StringValue(value))
+		}
+	}
+	for name, value := range output.Headers {
+		if headers.Get(name) == "" {
+			headers.Set(name, aws.StringValue(value))
+		}
+	}
+	return &ApiGatewayResponse{
+		StatusCode: int(aws.Int64Value(output.Status)),
+		Headers:    headers,
+		Body:       aws.StringValue(output.Body),
+		Log:        aws.StringValue(output.Log),
+	}, nil
+}
+
+func getRestApiResourceID(client *apigateway.APIGateway, region string, restApiID string, resourcePath string) (string, error) {
+	resourceID := ""
+	err := client.GetResourcesPages(&apigateway.GetResourcesInput{RestApiId: aws.String(restApiID)}, func(output *apigateway.GetResourcesOutput, lastPage bool) bool {
+		for _, resource := range output.Items {
+			if aws.StringValue(resource.Path) == resourcePath {
+				resourceID = aws.StringValue(resource.Id)
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if resourceID == "" {
+		return "", NewNotFoundError("REST API resource", resourcePath, region)
+	}
+	return resourceID, nil
+}
+
+// AssertRestApiIntegrationResponse invokes the integration of the method of the given resource of the given REST API
+// with the given request, and checks that the response has the given status code and contains the given body
+// substring. This will fail the test if it does not.
+func AssertRestApiIntegrationResponse(t testing.TestingT, region string, restApiID string, resourcePath string, request ApiGatewayRequest, expectedStatusCode int, expectedBodySubstring string) {
+	err := AssertRestApiIntegrationResponseE(t, region, restApiID, resourcePath, request, expectedStatusCode, expectedBodySubstring)
+	require.NoError(t, err)
+}
+
+// AssertRestApiIntegrationResponseE invokes the integration of the method of the given resource of the given REST API
+// with the given request, and checks that the response has the given status code and contains the given body
+// substring. The execution log is included in the error if it does not, which shows how the integration request and
+// response were mapped.
+func AssertRestApiIntegrationResponseE(t testing.TestingT, region string, restApiID string, resourcePath string, request ApiGatewayRequest, expectedStatusCode int, expectedBodySubstring string) error {
+	response, err := InvokeRestApiIntegrationE(t, region, restApiID, resourcePath, request)
+	if err != nil {
+		return err
+	}
+	return checkApiGatewayResponse(resourcePath, response, expectedStatusCode, expectedBodySubstring)
+}
+
+func checkApiGatewayResponse(path string, response *ApiGatewayResponse, expectedStatusCode int, expectedBodySubstring string) error {
+	if response.StatusCode != expectedStatusCode || !strings.Contains(response.Body, expectedBodySubstring) {
+		return NewApiGatewayResponseMismatchError(path, expectedStatusCode, expectedBodySubstring, response.StatusCode, response.Body, response.Log)
+	}
+	return nil
+}
+
+// WaitForApiGatewayAccessLogEntry waits until the access log of an API Gateway stage in the given CloudWatch Logs group
+// has an entry for the request with the given ID (see ApiGatewayResponse.RequestID), and returns the entry. This will
+// fail the test if there is no entry within the given timeout.
+func WaitForApiGatewayAccessLogEntry(t testing.TestingT, region string, logGroupName string, requestID string, timeout time.Duration) string {
+	entry, err := WaitForApiGatewayAccessLogEntryE(t, region, logGroupName, requestID, timeout)
+	require.NoError(t, err)
+	return entry
+}
+
+// WaitForApiGatewayAccessLogEntryE waits until the access log of an API Gateway stage in the given CloudWatch Logs
+// group has an entry for the request with the given ID (see ApiGatewayResponse.RequestID), and returns the entry. The
+// access log format of the stage must include the $context.requestId variable. Access logs usually take a few seconds
+// to a minute to be written.
+func WaitForApiGatewayAccessLogEntryE(t testing.TestingT, region string, logGroupName string, requestID string, timeout time.Duration) (string, error) {
+	return WaitForLogEventMatchingE(t, region, logGroupName, fmt.Sprintf("%q", requestID), timeout)
+}
+
+// NewApiGatewayClient creates an API Gateway client for REST APIs.
+func NewApiGatewayClient(t testing.TestingT, region string) *apigateway.APIGateway {
+	client, err := NewApiGatewayClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewApiGatewayClientE creates an API Gateway client for REST APIs.
+func NewApiGatewayClientE(t testing.TestingT, region string) (*apigateway.APIGateway, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return apigateway.New(sess), nil
+}
@@ -2,6 +2,8 @@ package aws
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -124,6 +126,223 @@ func WaitForCapacityE(
 	return err
 }
 
+// WaitForCapacityWithInstanceRefresh waits for the latest instance refresh of the ASG, if any, to complete, and for the
+// currently set desired capacity to be reached on the ASG.
+func WaitForCapacityWithInstanceRefresh(
+	t testing.TestingT,
+	asgName string,
+	region string,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) {
+	err := WaitForCapacityWithInstanceRefreshE(t, asgName, region, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitForCapacityWithInstanceRefreshE waits for the latest instance refresh of the ASG, if any, to complete, and for the
+// currently set desired capacity to be reached on the ASG. This is useful to test rolling out a new AMI or launch
+// template version, as the capacity is at its desired value during most of an instance refresh. This stops waiting
+// early if the instance refresh fails or is cancelled.
+func WaitForCapacityWithInstanceRefreshE(
+	t testing.TestingT,
+	asgName string,
+	region string,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) error {
+	asgClient, err := NewAsgClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for instance refresh of ASG %s to complete and ASG to reach desired capacity.", asgName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			output, err := asgClient.DescribeInstanceRefreshes(&autoscaling.DescribeInstanceRefreshesInput{
+				AutoScalingGroupName: aws.String(asgName),
+				MaxRecords:           aws.Int64(1),
+			})
+			if err != nil {
+				return "", err
+			}
+			// Instance refreshes are listed from the most recent one.
+			if len(output.InstanceRefreshes) > 0 {
+				if err := checkInstanceRefreshComplete(asgName, output.InstanceRefreshes[0]); err != nil {
+					return "", err
+				}
+			}
+
+			capacityInfo, err := GetCapacityInfoForAsgE(t, asgName, region)
+			if err != nil {
+				return "", err
+			}
+			if capacityInfo.CurrentCapacity != capacityInfo.DesiredCapacity {
+				return "", NewAsgCapacityNotMetError(asgName, capacityInfo.DesiredCapacity, capacityInfo.CurrentCapacity)
+			}
+			return fmt.Sprintf("ASG %s completed its instance refresh and is now at desired capacity %d", asgName, capacityInfo.DesiredCapacity), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkInstanceRefreshComplete returns an error if the given instance refresh of the ASG hasn't succeeded. The error is
+// wrapped in a retry.FatalError if the instance refresh finished without succeeding.
+func checkInstanceRefreshComplete(asgName string, refresh *autoscaling.InstanceRefresh) error {
+	status := aws.StringValue(refresh.Status)
+	err := NewAsgInstanceRefreshNotCompleteError(asgName, aws.StringValue(refresh.InstanceRefreshId), status, aws.Int64Value(refresh.PercentageComplete), aws.StringValue(refresh.StatusReason))
+	switch status {
+	case autoscaling.InstanceRefreshStatusSuccessful:
+		return nil
+	// Instance refreshes that are rolled back first go through RollbackInProgress, which is not known by this version of
+	// the SDK.
+	case autoscaling.InstanceRefreshStatusPending, autoscaling.InstanceRefreshStatusInProgress, autoscaling.InstanceRefreshStatusCancelling, "RollbackInProgress":
+		return err
+	default:
+		return retry.FatalError{Underlying: err}
+	}
+}
+
+// AsgMixedInstancesPolicy is a summary of the mixed instances policy of an ASG.
+type AsgMixedInstancesPolicy struct {
+	InstanceTypes                       []string // The instance types of the launch template overrides, sorted
+	OnDemandBaseCapacity                int64
+	OnDemandPercentageAboveBaseCapacity int64
+	OnDemandAllocationStrategy          string
+	SpotAllocationStrategy              string
+}
+
+// GetMixedInstancesPolicyForAsg returns a summary of the mixed instances policy of the given ASG.
+func GetMixedInstancesPolicyForAsg(t testing.TestingT, asgName string, awsRegion string) AsgMixedInstancesPolicy {
+	policy, err := GetMixedInstancesPolicyForAsgE(t, asgName, awsRegion)
+	require.NoError(t, err)
+	return policy
+}
+
+// GetMixedInstancesPolicyForAsgE returns a summary of the mixed instances policy of the given ASG. An error of type
+// NotFoundError is returned if the ASG has no mixed instances policy.
+func GetMixedInstancesPolicyForAsgE(t testing.TestingT, asgName string, awsRegion string) (AsgMixedInstancesPolicy, error) {
+	group, err := getAsgE(t, asgName, awsRegion)
+	if err != nil {
+		return AsgMixedInstancesPolicy{}, err
+	}
+	if group.MixedInstancesPolicy == nil {
+		return AsgMixedInstancesPolicy{}, NewNotFoundError("mixed instances policy of ASG", asgName, awsRegion)
+	}
+	return newAsgMixedInstancesPolicy(group.MixedInstancesPolicy), nil
+}
+
+func newAsgMixedInstancesPolicy(policy *autoscaling.MixedInstancesPolicy) AsgMixedInstancesPolicy {
+	summary := AsgMixedInstancesPolicy{InstanceTypes: []string{}}
+	if policy.LaunchTemplate != nil {
+		for _, override := range policy.LaunchTemplate.Overrides {
+			if override.InstanceType != nil {
+				summary.InstanceTypes = append(summary.InstanceTypes, aws.StringValue(override.InstanceType))
+			}
+		}
+	}
+	sort.Strings(summary.InstanceTypes)
+	if distribution := policy.InstancesDistribution; distribution != nil {
+		summary.OnDemandBaseCapacity = aws.Int64Value(distribution.OnDemandBaseCapacity)
+		summary.OnDemandPercentageAboveBaseCapacity = aws.Int64Value(distribution.OnDemandPercentageAboveBaseCapacity)
+		summary.OnDemandAllocationStrategy = aws.StringValue(distribution.OnDemandAllocationStrategy)
+		summary.SpotAllocationStrategy = aws.StringValue(distribution.SpotAllocationStrategy)
+	}
+	return summary
+}
+
+// AssertAsgMixedInstancesPolicy checks that the mixed instances policy of the given ASG is the expected one, and fails
+// the test if it is not.
+func AssertAsgMixedInstancesPolicy(t testing.TestingT, asgName string, awsRegion string, expected AsgMixedInstancesPolicy) {
+	err := AssertAsgMixedInstancesPolicyE(t, asgName, awsRegion, expected)
+	require.NoError(t, err)
+}
+
+// AssertAsgMixedInstancesPolicyE checks that the mixed instances policy of the given ASG is the expected one, and returns
+// an error if it is not. The instance types are compared in any order.
+func AssertAsgMixedInstancesPolicyE(t testing.TestingT, asgName string, awsRegion string, expected AsgMixedInstancesPolicy) error {
+	actual, err := GetMixedInstancesPolicyForAsgE(t, asgName, awsRegion)
+	if err != nil {
+		return err
+	}
+	expectedTypes := append([]string{}, expected.InstanceTypes...)
+	sort.Strings(expectedTypes)
+	expected.InstanceTypes = expectedTypes
+	if !reflect.DeepEqual(actual, expected) {
+		return NewAsgMixedInstancesPolicyMismatchError(asgName, expected, actual)
+	}
+	return nil
+}
+
+// GetLifecycleHooksForAsg gets the lifecycle hooks of the given ASG.
+func GetLifecycleHooksForAsg(t testing.TestingT, asgName string, awsRegion string) []*autoscaling.LifecycleHook {
+	hooks, err := GetLifecycleHooksForAsgE(t, asgName, awsRegion)
+	require.NoError(t, err)
+	return hooks
+}
+
+// GetLifecycleHooksForAsgE gets the lifecycle hooks of the given ASG.
+func GetLifecycleHooksForAsgE(t testing.TestingT, asgName string, awsRegion string) ([]*autoscaling.LifecycleHook, error) {
+	asgClient, err := NewAsgClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	output, err := asgClient.DescribeLifecycleHooks(&autoscaling.DescribeLifecycleHooksInput{AutoScalingGroupName: aws.String(asgName)})
+	if err != nil {
+		return nil, err
+	}
+	return output.LifecycleHooks, nil
+}
+
+// AssertAsgHasLifecycleHook checks that the given ASG has a lifecycle hook with the given name for the given
+// transition (e.g., autoscaling:EC2_INSTANCE_TERMINATING), and fails the test if it does not.
+func AssertAsgHasLifecycleHook(t testing.TestingT, asgName string, awsRegion string, hookName string, transition string) {
+	err := AssertAsgHasLifecycleHookE(t, asgName, awsRegion, hookName, transition)
+	require.NoError(t, err)
+}
+
+// AssertAsgHasLifecycleHookE checks that the given ASG has a lifecycle hook with the given name for the given
+// transition (e.g., autoscaling:EC2_INSTANCE_TERMINATING), and returns an error if it does not.
+func AssertAsgHasLifecycleHookE(t testing.TestingT, asgName string, awsRegion string, hookName string, transition string) error {
+	hooks, err := GetLifecycleHooksForAsgE(t, asgName, awsRegion)
+	if err != nil {
+		return err
+	}
+	return checkAsgHasLifecycleHook(asgName, awsRegion, hooks, hookName, transition)
+}
+
+func checkAsgHasLifecycleHook(asgName string, awsRegion string, hooks []*autoscaling.LifecycleHook, hookName string, transition string) error {
+	for _, hook := range hooks {
+		if aws.StringValue(hook.LifecycleHookName) != hookName {
+			continue
+		}
+		if actual := aws.StringValue(hook.LifecycleTransition); actual != transition {
+			return NewAsgLifecycleHookTransitionMismatchError(asgName, hookName, transition, actual)
+		}
+		return nil
+	}
+	return NewNotFoundError("lifecycle hook of ASG "+asgName, hookName, awsRegion)
+}
+
+// getAsgE fetches the ASG with the given name.
+func getAsgE(t testing.TestingT, asgName string, awsRegion string) (*autoscaling.Group, error) {
+	asgClient, err := NewAsgClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	output, err := asgClient.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: []*string{aws.String(asgName)}})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.AutoScalingGroups) == 0 {
+		return nil, NewNotFoundError("ASG", asgName, awsRegion)
+	}
+	return output.AutoScalingGroups[0], nil
+}
+
 // NewAsgClient creates an Auto Scaling Group client.
 func NewAsgClient(t testing.TestingT, region string) *autoscaling.AutoScaling {
 	client, err := NewAsgClientE(t, region)
@@ -135,7 +354,7 @@ func NewAsgClient(t testing.TestingT, region string) *autoscaling.AutoScaling {
 
 // NewAsgClientE creates an Auto Scaling Group client.
 func NewAsgClientE(t testing.TestingT, region string) (*autoscaling.AutoScaling, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
 )
 
 func TestGetCapacityInfoForAsg(t *testing.T) {
@@ -158,3 +159,64 @@ func scaleAsgToZero(t *testing.T, name string, region string) {
 	// scaling activity so we add a 5 second pause here to work around it.
 	time.Sleep(5 * time.Second)
 }
+
+func TestCheckInstanceRefreshComplete(t *testing.T) {
+	t.Parallel()
+
+	refresh := func(status string) *autoscaling.InstanceRefresh {
+		return &autoscaling.InstanceRefresh{
+			InstanceRefreshId:  aws.String("08b91cf7-8fa6-48af-b6a6-d227f40f1b9b"),
+			Status:             aws.String(status),
+			PercentageComplete: aws.Int64(50),
+		}
+	}
+
+	assert.NoError(t, checkInstanceRefreshComplete("asg", refresh(autoscaling.InstanceRefreshStatusSuccessful)))
+	assert.IsType(t, AsgInstanceRefreshNotCompleteError{}, checkInstanceRefreshComplete("asg", refresh(autoscaling.InstanceRefreshStatusPending)))
+	assert.IsType(t, AsgInstanceRefreshNotCompleteError{}, checkInstanceRefreshComplete("asg", refresh(autoscaling.InstanceRefreshStatusInProgress)))
+	assert.IsType(t, retry.FatalError{}, checkInstanceRefreshComplete("asg", refresh(autoscaling.InstanceRefreshStatusFailed)))
+	assert.IsType(t, retry.FatalError{}, checkInstanceRefreshComplete("asg", refresh(autoscaling.InstanceRefreshStatusCancelled)))
+	assert.IsType(t, retry.FatalError{}, checkInstanceRefreshComplete("asg", refresh("RollbackSuccessful")))
+}
+
+func TestNewAsgMixedInstancesPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := &autoscaling.MixedInstancesPolicy{
+		LaunchTemplate: &autoscaling.LaunchTemplate{
+			Overrides: []*autoscaling.LaunchTemplateOverrides{
+				{InstanceType: aws.String("t3.medium")},
+				{InstanceType: aws.String("t3a.medium")},
+				{InstanceType: aws.String("m5.large")},
+			},
+		},
+		InstancesDistribution: &autoscaling.InstancesDistribution{
+			OnDemandBaseCapacity:                aws.Int64(1),
+			OnDemandPercentageAboveBaseCapacity: aws.Int64(25),
+			OnDemandAllocationStrategy:          aws.String("prioritized"),
+			SpotAllocationStrategy:              aws.String("capacity-optimized"),
+		},
+	}
+
+	assert.Equal(t, AsgMixedInstancesPolicy{
+		InstanceTypes:                       []string{"m5.large", "t3.medium", "t3a.medium"},
+		OnDemandBaseCapacity:                1,
+		OnDemandPercentageAboveBaseCapacity: 25,
+		OnDemandAllocationStrategy:          "prioritized",
+		SpotAllocationStrategy:              "capacity-optimized",
+	}, newAsgMixedInstancesPolicy(policy))
+	assert.Equal(t, AsgMixedInstancesPolicy{InstanceTypes: []string{}}, newAsgMixedInstancesPolicy(&autoscaling.MixedInstancesPolicy{}))
+}
+
+func TestCheckAsgHasLifecycleHook(t *testing.T) {
+	t.Parallel()
+
+	hooks := []*autoscaling.LifecycleHook{
+		{LifecycleHookName: aws.String("drain"), LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_TERMINATING")},
+		{LifecycleHookName: aws.String("bootstrap"), LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_LAUNCHING")},
+	}
+
+	assert.NoError(t, checkAsgHasLifecycleHook("asg", "us-east-1", hooks, "drain", "autoscaling:EC2_INSTANCE_TERMINATING"))
+	assert.IsType(t, AsgLifecycleHookTransitionMismatchError{}, checkAsgHasLifecycleHook("asg", "us-east-1", hooks, "bootstrap", "autoscaling:EC2_INSTANCE_TERMINATING"))
+	assert.IsType(t, NotFoundError{}, checkAsgHasLifecycleHook("asg", "us-east-1", hooks, "warmup", "autoscaling:EC2_INSTANCE_LAUNCHING"))
+}
@@ -0,0 +1,189 @@
+package aws
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	athenaQueryMaxRetries          = 300
+	athenaQuerySleepBetweenRetries = 2 * time.Second
+)
+
+// athenaDMLStatementType is the statement type of queries whose results start with a row of column names.
+const athenaDMLStatementType = "DML"
+
+// AthenaRow is a row of the results of an Athena query, by column name. The values are converted to Go types according
+// to the type of their column: int64 for integer types, float64 for floating point types, bool for booleans, and
+// string for all other types (e.g., varchar, decimal, date or timestamp). NULL values are nil.
+type AthenaRow map[string]interface{}
+
+// RunAthenaQuery runs the given query in the given Athena workgroup, writing the results to the given S3 location
+// (e.g., s3://my-bucket/athena-results/), waits until it completes, and returns the rows of the results. This will fail
+// the test if there is an error.
+func RunAthenaQuery(t testing.TestingT, region string, workgroup string, query string, outputLocation string) []AthenaRow {
+	rows, err := RunAthenaQueryE(t, region, workgroup, query, outputLocation)
+	require.NoError(t, err)
+	return rows
+}
+
+// RunAthenaQueryE runs the given query in the given Athena workgroup, writing the results to the given S3 location
+// (e.g., s3://my-bucket/athena-results/), waits until it completes, and returns the rows of the results. The output
+// location can be empty if the workgroup has one configured. This waits up to 10 minutes for the query to complete, and
+// returns an error if the query fails or is cancelled. This is useful to check that a data lake module produces
+// queryable data:
+//
+//	rows := aws.RunAthenaQuery(t, region, "primary", "SELECT count(*) AS events FROM logs.events", outputLocation)
+//	assert.Greater(t, rows[0]["events"], int64(0))
+func RunAthenaQueryE(t testing.TestingT, region string, workgroup string, query string, outputLocation string) ([]AthenaRow, error) {
+	client, err := NewAthenaClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &athena.StartQueryExecutionInput{
+		QueryString: aws.String(query),
+		WorkGroup:   aws.String(workgroup),
+	}
+	if outputLocation != "" {
+		input.ResultConfiguration = &athena.ResultConfiguration{OutputLocation: aws.String(outputLocation)}
+	}
+	logger.Logf(t, "Running Athena query in workgroup %s: %s", workgroup, query)
+	started, err := client.StartQueryExecution(input)
+	if err != nil {
+		return nil, err
+	}
+	queryExecutionID := aws.StringValue(started.QueryExecutionId)
+
+	var execution *athena.QueryExecution
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Athena query %s to complete.", queryExecutionID),
+		athenaQueryMaxRetries,
+		athenaQuerySleepBetweenRetries,
+		func() (string, error) {
+			output, err := client.GetQueryExecution(&athena.GetQueryExecutionInput{QueryExecutionId: aws.String(queryExecutionID)})
+			if err != nil {
+				return "", err
+			}
+			execution = output.QueryExecution
+			if err := checkAthenaQueryExecutionState(execution); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Athena query %s completed", queryExecutionID), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []AthenaRow{}
+	var parseErr error
+	firstPage := true
+	err = client.GetQueryResultsPages(&athena.GetQueryResultsInput{QueryExecutionId: aws.String(queryExecutionID)}, func(output *athena.GetQueryResultsOutput, lastPage bool) bool {
+		// The results of DML queries (e.g., SELECT) start with a row of column names.
+		skipHeader := firstPage && aws.StringValue(execution.StatementType) == athenaDMLStatementType
+		firstPage = false
+
+		pageRows, err := parseAthenaRows(output.ResultSet.ResultSetMetadata.ColumnInfo, output.ResultSet.Rows, skipHeader)
+		if err != nil {
+			parseErr = err
+			return false
+		}
+		rows = append(rows, pageRows...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return rows, nil
+}
+
+// checkAthenaQueryExecutionState returns an error if the given query execution hasn't succeeded. The error is wrapped in
+// a retry.FatalError if the query failed or was cancelled.
+func checkAthenaQueryExecutionState(execution *athena.QueryExecution) error {
+	queryExecutionID := aws.StringValue(execution.QueryExecutionId)
+	state, reason := "", ""
+	if execution.Status != nil {
+		state = aws.StringValue(execution.Status.State)
+		reason = aws.StringValue(execution.Status.StateChangeReason)
+	}
+	switch state {
+	case athena.QueryExecutionStateSucceeded:
+		return nil
+	case athena.QueryExecutionStateFailed, athena.QueryExecutionStateCancelled:
+		return retry.FatalError{Underlying: NewAthenaQueryNotSucceededError(queryExecutionID, state, reason)}
+	default:
+		return NewAthenaQueryNotSucceededError(queryExecutionID, state, reason)
+	}
+}
+
+// parseAthenaRows converts the given rows of the results of an Athena query to AthenaRows, skipping the first row if
+// it is a row of column names.
+func parseAthenaRows(columns []*athena.ColumnInfo, rows []*athena.Row, skipHeader bool) ([]AthenaRow, error) {
+	if skipHeader && len(rows) > 0 {
+		rows = rows[1:]
+	}
+	parsed := []AthenaRow{}
+	for _, row := range rows {
+		if len(row.Data) != len(columns) {
+			return nil, fmt.Errorf("Athena result row has %d values, but the results have %d columns", len(row.Data), len(columns))
+		}
+		parsedRow := AthenaRow{}
+		for i, column := range columns {
+			value, err := parseAthenaValue(aws.StringValue(column.Type), row.Data[i].VarCharValue)
+			if err != nil {
+				return nil, err
+			}
+			parsedRow[aws.StringValue(column.Name)] = value
+		}
+		parsed = append(parsed, parsedRow)
+	}
+	return parsed, nil
+}
+
+// parseAthenaValue converts the given value of a column of the given type of the results of an Athena query, which
+// Athena returns as a string, to a Go type.
+func parseAthenaValue(columnType string, value *string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch columnType {
+	case "tinyint", "smallint", "integer", "int", "bigint":
+		return strconv.ParseInt(*value, 10, 64)
+	case "float", "real", "double":
+		return strconv.ParseFloat(*value, 64)
+	case "boolean":
+		return strconv.ParseBool(*value)
+	default:
+		return *value, nil
+	}
+}
+
+// NewAthenaClient creates an Athena client.
+func NewAthenaClient(t testing.TestingT, region string) *athena.Athena {
+	client, err := NewAthenaClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewAthenaClientE creates an Athena client.
+func NewAthenaClientE(t testing.TestingT, region string) (*athena.Athena, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return athena.New(sess), nil
+}
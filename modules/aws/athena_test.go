@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAthenaRows(t *testing.T) {
+	t.Parallel()
+
+	columns := []*athena.ColumnInfo{
+		{Name: aws.String("id"), Type: aws.String("bigint")},
+		{Name: aws.String("name"), Type: aws.String("varchar")},
+		{Name: aws.String("price"), Type: aws.String("double")},
+		{Name: aws.String("in_stock"), Type: aws.String("boolean")},
+		{Name: aws.String("amount"), Type: aws.String("decimal")},
+	}
+	row := func(values ...*string) *athena.Row {
+		data := []*athena.Datum{}
+		for _, value := range values {
+			data = append(data, &athena.Datum{VarCharValue: value})
+		}
+		return &athena.Row{Data: data}
+	}
+	rows := []*athena.Row{
+		row(aws.String("id"), aws.String("name"), aws.String("price"), aws.String("in_stock"), aws.String("amount")),
+		row(aws.String("1"), aws.String("book"), aws.String("12.5"), aws.String("true"), aws.String("12.50")),
+		row(aws.String("2"), nil, nil, aws.String("false"), nil),
+	}
+
+	parsed, err := parseAthenaRows(columns, rows, true)
+	require.NoError(t, err)
+	assert.Equal(t, []AthenaRow{
+		{"id": int64(1), "name": "book", "price": 12.5, "in_stock": true, "amount": "12.50"},
+		{"id": int64(2), "name": nil, "price": nil, "in_stock": false, "amount": nil},
+	}, parsed)
+
+	parsed, err = parseAthenaRows(columns, rows[1:], false)
+	require.NoError(t, err)
+	assert.Len(t, parsed, 2)
+
+	_, err = parseAthenaRows(columns, rows, false)
+	require.Error(t, err)
+
+	_, err = parseAthenaRows(columns[:1], rows[1:], false)
+	require.Error(t, err)
+}
+
+func TestCheckAthenaQueryExecutionState(t *testing.T) {
+	t.Parallel()
+
+	execution := func(state string) *athena.QueryExecution {
+		return &athena.QueryExecution{
+			QueryExecutionId: aws.String("a1b2c3"),
+			Status:           &athena.QueryExecutionStatus{State: aws.String(state), StateChangeReason: aws.String("TABLE_NOT_FOUND")},
+		}
+	}
+
+	assert.NoError(t, checkAthenaQueryExecutionState(execution(athena.QueryExecutionStateSucceeded)))
+	assert.IsType(t, AthenaQueryNotSucceededError{}, checkAthenaQueryExecutionState(execution(athena.QueryExecutionStateQueued)))
+	assert.IsType(t, AthenaQueryNotSucceededError{}, checkAthenaQueryExecutionState(execution(athena.QueryExecutionStateRunning)))
+	assert.IsType(t, retry.FatalError{}, checkAthenaQueryExecutionState(execution(athena.QueryExecutionStateFailed)))
+	assert.IsType(t, retry.FatalError{}, checkAthenaQueryExecutionState(execution(athena.QueryExecutionStateCancelled)))
+}
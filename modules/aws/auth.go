@@ -3,34 +3,305 @@ package aws
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/pquerna/otp/totp"
 )
 
 const (
-	AuthAssumeRoleEnvVar = "TERRATEST_IAM_ROLE" // OS environment variable name through which Assume Role ARN may be passed for authentication
+	AuthAssumeRoleEnvVar           = "TERRATEST_IAM_ROLE"                // OS environment variable name through which Assume Role ARN may be passed for authentication
+	AuthAssumeRoleExternalIDEnvVar = "TERRATEST_IAM_ROLE_EXTERNAL_ID"    // OS environment variable name through which the external ID of the Assume Role ARN may be passed
+	AuthProfileEnvVar              = "TERRATEST_AWS_PROFILE"             // OS environment variable name through which a named profile may be passed for authentication
+	AuthWebIdentityTokenFileEnvVar = "TERRATEST_WEB_IDENTITY_TOKEN_FILE" // OS environment variable name through which a web identity token file may be passed to assume the role
 )
 
+// testCredentialsCaches cache the credentials of the role assumed by the New*Client helpers of each test, so that
+// creating a client doesn't assume the role again, while the tests, and the reruns of a test, don't share credentials.
+var testCredentialsCaches = newPerTestValues()
+
+// AuthOptions configures how to authenticate AWS sessions. The zero value uses the standard AWS credential chain
+// (environment variables, shared config and credentials files, instance or container roles).
+type AuthOptions struct {
+	Profile              string             // The named profile of the shared config and credentials files to use
+	RoleARN              string             // The ARN of a role to assume, e.g., in another account
+	ExternalID           string             // The external ID required by the trust policy of the role, if any
+	RoleSessionName      string             // The session name of the assumed role. Defaults to a unique name.
+	SessionTags          map[string]string  // The session tags to pass when assuming the role
+	WebIdentityTokenFile string             // The path of a web identity (OIDC) token to assume the role with, e.g., in CI
+	Duration             time.Duration      // How long the assumed role credentials are valid for. Defaults to the STS default.
+	Cache                *CredentialsCache  // If set, sessions with the same options share credentials through this cache
+	EndpointResolver     endpoints.Resolver // If set, overrides the endpoints set with SetEndpointResolver or UseLocalStack
+}
+
+// AuthOptionsFromEnv returns the AuthOptions configured through the TERRATEST_ environment variables, used by
+// NewAuthenticatedSession.
+func AuthOptionsFromEnv() AuthOptions {
+	return AuthOptions{
+		Profile:              os.Getenv(AuthProfileEnvVar),
+		RoleARN:              os.Getenv(AuthAssumeRoleEnvVar),
+		ExternalID:           os.Getenv(AuthAssumeRoleExternalIDEnvVar),
+		WebIdentityTokenFile: os.Getenv(AuthWebIdentityTokenFileEnvVar),
+	}
+}
+
+// cacheKey returns a key that is the same for options that result in the same credentials.
+func (opts AuthOptions) cacheKey() string {
+	tagKeys := []string{}
+	for key := range opts.SessionTags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	tags := []string{}
+	for _, key := range tagKeys {
+		tags = append(tags, key+"="+opts.SessionTags[key])
+	}
+	return strings.Join([]string{
+		opts.Profile,
+		opts.RoleARN,
+		opts.ExternalID,
+		opts.RoleSessionName,
+		strings.Join(tags, ","),
+		opts.WebIdentityTokenFile,
+		opts.Duration.String(),
+		GetLocalStackURL(),
+	}, "|")
+}
+
+// CredentialsCache caches AWS credentials by AuthOptions, so that tests creating many clients don't assume the same
+// role over and over. The credentials are refreshed when they expire. Use one cache per test to keep the credentials of
+// tests apart:
+//
+//	opts := aws.AuthOptions{RoleARN: "arn:aws:iam::111111111111:role/deployer", Cache: aws.NewCredentialsCache()}
+//	sess, err := aws.NewAuthenticatedSessionWithOptions(region, opts)
+type CredentialsCache struct {
+	mutex       sync.Mutex
+	credentials map[string]*credentials.Credentials
+}
+
+// NewCredentialsCache creates an empty CredentialsCache.
+func NewCredentialsCache() *CredentialsCache {
+	return &CredentialsCache{credentials: map[string]*credentials.Credentials{}}
+}
+
+// getOrCreate returns the cached credentials for the given options, creating them with the given function if there are
+// none.
+func (cache *CredentialsCache) getOrCreate(opts AuthOptions, create func() *credentials.Credentials) *credentials.Credentials {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	key := opts.cacheKey()
+	if creds, ok := cache.credentials[key]; ok {
+		return creds
+	}
+	creds := create()
+	cache.credentials[key] = creds
+	return creds
+}
+
 // NewAuthenticatedSession creates an AWS session following to standard AWS authentication workflow.
-// If AuthAssumeIamRoleEnvVar environment variable is set, assumes IAM role specified in it.
+// If AuthAssumeIamRoleEnvVar environment variable is set, assumes IAM role specified in it. The other TERRATEST_
+// environment variables configure the external ID of the role, a named profile, or a web identity token to assume the
+// role with (see AuthOptionsFromEnv). Each session assumes the role on its own; the New*Client helpers of this package
+// share the assumed role credentials between the clients of the same test instead.
 func NewAuthenticatedSession(region string) (*session.Session, error) {
-	if assumeRoleArn, ok := os.LookupEnv(AuthAssumeRoleEnvVar); ok {
-		return NewAuthenticatedSessionFromRole(region, assumeRoleArn)
+	opts, err := authOptionsFromEnvE()
+	if err != nil {
+		return nil, err
+	}
+	return NewAuthenticatedSessionWithOptions(region, opts)
+}
+
+// newAuthenticatedSessionForTest creates an AWS session like NewAuthenticatedSession, which shares the assumed role
+// credentials with the other sessions of the given test, and whose requests are retried within the retry budget of the
+// test (see RetryOptions).
+func newAuthenticatedSessionForTest(t testing.TestingT, region string) (*session.Session, error) {
+	opts, err := authOptionsFromEnvE()
+	if err != nil {
+		return nil, err
+	}
+	if opts.RoleARN != "" {
+		opts.Cache = getTestCredentialsCache(t)
+	}
+	sess, err := NewAuthenticatedSessionWithOptions(region, opts)
+	if err != nil {
+		return nil, err
+	}
+	sess.Config.Retryer = getRetryer().forTest(t)
+	return sess, nil
+}
+
+// authOptionsFromEnvE returns the AuthOptions configured through the TERRATEST_ environment variables, or an error if
+// the TERRATEST_IAM_ROLE environment variable is set to an empty role ARN, rather than falling back to the default
+// credentials.
+func authOptionsFromEnvE() (AuthOptions, error) {
+	if roleARN, ok := os.LookupEnv(AuthAssumeRoleEnvVar); ok && roleARN == "" {
+		return AuthOptions{}, CredentialsError{UnderlyingErr: fmt.Errorf("the %s environment variable is set to an empty role ARN", AuthAssumeRoleEnvVar)}
+	}
+	return AuthOptionsFromEnv(), nil
+}
+
+// getTestCredentialsCache returns the cache of the assumed role credentials of the given test.
+func getTestCredentialsCache(t testing.TestingT) *CredentialsCache {
+	return testCredentialsCaches.getOrCreate(t, func() interface{} {
+		return NewCredentialsCache()
+	}).(*CredentialsCache)
+}
+
+// perTestValues holds a value for each running test. The value of a test is removed when the test ends, if it
+// supports Cleanup like testing.T does, so that a rerun of the test, e.g., with go test -count, gets a new value.
+type perTestValues struct {
+	mutex  sync.Mutex
+	values map[testing.TestingT]interface{}
+}
+
+func newPerTestValues() *perTestValues {
+	return &perTestValues{values: map[testing.TestingT]interface{}{}}
+}
+
+// getOrCreate returns the value of the given test, creating it with the given function if it has none. A test that
+// can't be used as a map key gets a new value every time.
+func (values *perTestValues) getOrCreate(t testing.TestingT, create func() interface{}) interface{} {
+	if !reflect.TypeOf(t).Comparable() {
+		return create()
+	}
+
+	values.mutex.Lock()
+	defer values.mutex.Unlock()
+
+	if value, ok := values.values[t]; ok {
+		return value
+	}
+	value := create()
+	values.values[t] = value
+	if cleaner, ok := t.(interface{ Cleanup(func()) }); ok {
+		cleaner.Cleanup(func() {
+			values.mutex.Lock()
+			defer values.mutex.Unlock()
+
+			delete(values.values, t)
+		})
+	}
+	return value
+}
+
+// NewAuthenticatedSessionWithOptions creates an AWS session authenticated as configured by the given options, checking
+// that the credentials can be retrieved. This is useful to test multi-account modules from one runner, by creating the
+// clients of each account from a session with its own options:
+//
+//	sess, err := aws.NewAuthenticatedSessionWithOptions(region, aws.AuthOptions{
+//		RoleARN:     "arn:aws:iam::222222222222:role/terratest",
+//		ExternalID:  "terratest",
+//		SessionTags: map[string]string{"TestRun": runID},
+//	})
+//	s3Client := s3.New(sess)
+func NewAuthenticatedSessionWithOptions(region string, opts AuthOptions) (*session.Session, error) {
+	sess, err := CreateAwsSessionWithOptions(region, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = sess.Config.Credentials.Get(); err != nil {
+		return nil, CredentialsError{UnderlyingErr: err}
+	}
+
+	return sess, nil
+}
+
+// CreateAwsSessionWithOptions returns a new AWS session authenticated as configured by the given options, without
+// checking that the credentials can be retrieved.
+func CreateAwsSessionWithOptions(region string, opts AuthOptions) (*session.Session, error) {
+	if opts.WebIdentityTokenFile != "" && opts.RoleARN == "" {
+		return nil, fmt.Errorf("a role ARN is required to authenticate with the web identity token file %s", opts.WebIdentityTokenFile)
+	}
+
+	config := newAwsConfig(region)
+	if opts.EndpointResolver != nil {
+		config = config.WithEndpointResolver(opts.EndpointResolver)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		Profile:           opts.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if GetLocalStackURL() != "" && opts.Profile == "" {
+		sess.Config.Credentials = newLocalStackCredentials()
+	}
+
+	if opts.Cache != nil {
+		sess.Config.Credentials = opts.Cache.getOrCreate(opts, func() *credentials.Credentials {
+			return newAuthOptionsCredentials(sess, opts)
+		})
 	} else {
-		return NewAuthenticatedSessionFromDefaultCredentials(region)
+		sess.Config.Credentials = newAuthOptionsCredentials(sess, opts)
+	}
+	return sess, nil
+}
+
+// newAuthOptionsCredentials returns the credentials configured by the given options, using the given session, which has
+// the credentials of the profile, to assume the role if any.
+func newAuthOptionsCredentials(sess *session.Session, opts AuthOptions) *credentials.Credentials {
+	sessionName := opts.RoleSessionName
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("terratest-%d", time.Now().UnixNano())
+	}
+
+	switch {
+	case opts.WebIdentityTokenFile != "":
+		provider := stscreds.NewWebIdentityRoleProviderWithOptions(sts.New(sess), opts.RoleARN, sessionName, stscreds.FetchTokenPath(opts.WebIdentityTokenFile), func(provider *stscreds.WebIdentityRoleProvider) {
+			provider.Duration = opts.Duration
+		})
+		return credentials.NewCredentials(provider)
+	case opts.RoleARN != "":
+		return stscreds.NewCredentials(sess, opts.RoleARN, func(provider *stscreds.AssumeRoleProvider) {
+			provider.RoleSessionName = sessionName
+			if len(opts.SessionTags) > 0 {
+				provider.Tags = newStsTags(opts.SessionTags)
+			}
+			if opts.ExternalID != "" {
+				provider.ExternalID = aws.String(opts.ExternalID)
+			}
+			if opts.Duration > 0 {
+				provider.Duration = opts.Duration
+			}
+		})
+	default:
+		return sess.Config.Credentials
+	}
+}
+
+// newStsTags converts the given session tags to STS tags, sorted by key.
+func newStsTags(tags map[string]string) []*sts.Tag {
+	keys := []string{}
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	stsTags := []*sts.Tag{}
+	for _, key := range keys {
+		stsTags = append(stsTags, &sts.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
 	}
+	return stsTags
 }
 
 // NewAuthenticatedSessionFromDefaultCredentials gets an AWS Session, checking that the user has credentials properly configured in their environment.
 func NewAuthenticatedSessionFromDefaultCredentials(region string) (*session.Session, error) {
-	awsConfig := aws.NewConfig().WithRegion(region)
+	awsConfig := newAwsConfig(region)
 
 	sessionOptions := session.Options{
 		Config:            *awsConfig,
@@ -68,7 +339,7 @@ func NewAuthenticatedSessionFromRole(region string, roleARN string) (*session.Se
 // CreateAwsSessionFromRole returns a new AWS session after assuming the role
 // whose ARN is provided in roleARN.
 func CreateAwsSessionFromRole(region string, roleARN string) (*session.Session, error) {
-	sess, err := session.NewSession(aws.NewConfig().WithRegion(region))
+	sess, err := session.NewSession(newAwsConfig(region))
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +358,7 @@ func AssumeRole(sess *session.Session, roleARN string) *session.Session {
 // create an AWS session authenticated as the new IAM User.
 func CreateAwsSessionWithCreds(region string, accessKeyID string, secretAccessKey string) (*session.Session, error) {
 	creds := CreateAwsCredentials(accessKeyID, secretAccessKey)
-	return session.NewSession(aws.NewConfig().WithRegion(region).WithCredentials(creds))
+	return session.NewSession(newAwsConfig(region).WithCredentials(creds))
 }
 
 // CreateAwsSessionWithMfa creates a new AWS session authenticated using an MFA token retrieved using the given STS client and MFA Device.
@@ -110,7 +381,7 @@ func CreateAwsSessionWithMfa(region string, stsClient *sts.STS, mfaDevice *iam.V
 	sessionToken := *output.Credentials.SessionToken
 
 	creds := CreateAwsCredentialsWithSessionToken(accessKeyID, secretAccessKey, sessionToken)
-	return session.NewSession(aws.NewConfig().WithRegion(region).WithCredentials(creds))
+	return session.NewSession(newAwsConfig(region).WithCredentials(creds))
 }
 
 // CreateAwsCredentials creates an AWS Credentials configuration with specific AWS credentials.
@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthOptionsCacheKey(t *testing.T) {
+	t.Parallel()
+
+	opts := AuthOptions{
+		RoleARN:     "arn:aws:iam::111111111111:role/terratest",
+		SessionTags: map[string]string{"b": "2", "a": "1"},
+		Duration:    time.Hour,
+	}
+	sameOpts := AuthOptions{
+		RoleARN:     "arn:aws:iam::111111111111:role/terratest",
+		SessionTags: map[string]string{"a": "1", "b": "2"},
+		Duration:    time.Hour,
+		Cache:       NewCredentialsCache(),
+	}
+	otherOpts := opts
+	otherOpts.ExternalID = "terratest"
+
+	assert.Equal(t, opts.cacheKey(), sameOpts.cacheKey())
+	assert.NotEqual(t, opts.cacheKey(), otherOpts.cacheKey())
+	assert.NotEqual(t, opts.cacheKey(), AuthOptions{}.cacheKey())
+}
+
+func TestCreateAwsSessionWithOptionsSharesCachedCredentials(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCredentialsCache()
+	opts := AuthOptions{RoleARN: "arn:aws:iam::111111111111:role/terratest", Cache: cache}
+
+	first, err := CreateAwsSessionWithOptions("us-east-1", opts)
+	require.NoError(t, err)
+	second, err := CreateAwsSessionWithOptions("eu-west-1", opts)
+	require.NoError(t, err)
+	assert.Same(t, first.Config.Credentials, second.Config.Credentials)
+
+	opts.RoleARN = "arn:aws:iam::222222222222:role/terratest"
+	other, err := CreateAwsSessionWithOptions("us-east-1", opts)
+	require.NoError(t, err)
+	assert.NotSame(t, first.Config.Credentials, other.Config.Credentials)
+
+	uncached, err := CreateAwsSessionWithOptions("us-east-1", AuthOptions{RoleARN: "arn:aws:iam::111111111111:role/terratest"})
+	require.NoError(t, err)
+	assert.NotSame(t, first.Config.Credentials, uncached.Config.Credentials)
+}
+
+func TestGetTestCredentialsCache(t *testing.T) {
+	t.Parallel()
+
+	cache := getTestCredentialsCache(t)
+	assert.Same(t, cache, getTestCredentialsCache(t))
+
+	// Subtests, and reruns of a test, get their own cache, which is removed when they end.
+	var subtestCache *CredentialsCache
+	t.Run("subtest", func(t *testing.T) {
+		subtestCache = getTestCredentialsCache(t)
+		assert.NotSame(t, cache, subtestCache)
+		assert.Same(t, subtestCache, getTestCredentialsCache(t))
+	})
+	testCredentialsCaches.mutex.Lock()
+	defer testCredentialsCaches.mutex.Unlock()
+	for _, value := range testCredentialsCaches.values {
+		assert.NotSame(t, subtestCache, value)
+	}
+}
+
+func TestAuthOptionsFromEnvERejectsEmptyRole(t *testing.T) {
+	t.Setenv(AuthAssumeRoleEnvVar, "")
+	_, err := authOptionsFromEnvE()
+	require.IsType(t, CredentialsError{}, err)
+
+	t.Setenv(AuthAssumeRoleEnvVar, "arn:aws:iam::111111111111:role/terratest")
+	opts, err := authOptionsFromEnvE()
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::111111111111:role/terratest", opts.RoleARN)
+}
+
+func TestCreateAwsSessionWithOptionsRequiresRoleForWebIdentity(t *testing.T) {
+	t.Parallel()
+
+	_, err := CreateAwsSessionWithOptions("us-east-1", AuthOptions{WebIdentityTokenFile: "/var/run/secrets/token"})
+	assert.Error(t, err)
+}
+
+func TestNewStsTags(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []*sts.Tag{
+		{Key: aws.String("Project"), Value: aws.String("terratest")},
+		{Key: aws.String("TestRun"), Value: aws.String("abc123")},
+	}, newStsTags(map[string]string{"TestRun": "abc123", "Project": "terratest"}))
+}
@@ -0,0 +1,260 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cloudFrontDeployedStatus             = "Deployed"
+	cloudFrontInvalidationCompleteStatus = "Completed"
+)
+
+// The cache statuses CloudFront reports in the X-Cache header of responses.
+const (
+	CloudFrontCacheHit        = "Hit"
+	CloudFrontCacheMiss       = "Miss"
+	CloudFrontCacheRefreshHit = "RefreshHit"
+)
+
+// CloudFrontRequestExpectation is what VerifyCloudFrontResponse expects of the response to a request through a
+// CloudFront distribution.
+type CloudFrontRequestExpectation struct {
+	RequestHeaders map[string]string // Headers to send with the request (e.g., Origin or Accept-Encoding)
+	StatusCode     int               // The expected status code
+	Headers        map[string]string // The expected values of response headers (e.g., Cache-Control)
+	CacheStatus    string            // The expected cache status (e.g., CloudFrontCacheHit), or empty to not check it
+}
+
+// GetCloudFrontDistribution fetches the CloudFront distribution with the given ID.
+func GetCloudFrontDistribution(t testing.TestingT, distributionID string) *cloudfront.Distribution {
+	distribution, err := GetCloudFrontDistributionE(t, distributionID)
+	require.NoError(t, err)
+	return distribution
+}
+
+// GetCloudFrontDistributionE fetches the CloudFront distribution with the given ID.
+func GetCloudFrontDistributionE(t testing.TestingT, distributionID string) (*cloudfront.Distribution, error) {
+	client, err := NewCloudFrontClientE(t, getDefaultRegion())
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.GetDistribution(&cloudfront.GetDistributionInput{Id: aws.String(distributionID)})
+	if err != nil {
+		return nil, err
+	}
+	return output.Distribution, nil
+}
+
+// WaitUntilCloudFrontDeployed waits until the latest changes of the given CloudFront distribution are deployed to all
+// edge locations, retrying the check for the specified amount of times, sleeping for the provided duration between
+// each try. This will fail the test if the changes are not deployed in time.
+func WaitUntilCloudFrontDeployed(t testing.TestingT, distributionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilCloudFrontDeployedE(t, distributionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilCloudFrontDeployedE waits until the latest changes of the given CloudFront distribution are deployed to all
+// edge locations, retrying the check for the specified amount of times, sleeping for the provided duration between
+// each try. Deploying changes usually takes several minutes.
+func WaitUntilCloudFrontDeployedE(t testing.TestingT, distributionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for CloudFront distribution %s to be deployed.", distributionID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			distribution, err := GetCloudFrontDistributionE(t, distributionID)
+			if err != nil {
+				return "", err
+			}
+			if status := aws.StringValue(distribution.Status); status != cloudFrontDeployedStatus {
+				return "", NewCloudFrontNotDeployedError(distributionID, status)
+			}
+			return fmt.Sprintf("CloudFront distribution %s is now deployed", distributionID), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// CreateInvalidationAndWait invalidates the given paths (e.g., "/index.html" or "/*") in the cache of the given
+// CloudFront distribution, waits until the invalidation completes, retrying the check for the specified amount of
+// times, sleeping for the provided duration between each try, and returns the ID of the invalidation. This will fail
+// the test if there is an error.
+func CreateInvalidationAndWait(t testing.TestingT, distributionID string, paths []string, maxRetries int, sleepBetweenRetries time.Duration) string {
+	invalidationID, err := CreateInvalidationAndWaitE(t, distributionID, paths, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return invalidationID
+}
+
+// CreateInvalidationAndWaitE invalidates the given paths (e.g., "/index.html" or "/*") in the cache of the given
+// CloudFront distribution, waits until the invalidation completes, retrying the check for the specified amount of
+// times, sleeping for the provided duration between each try, and returns the ID of the invalidation.
+func CreateInvalidationAndWaitE(t testing.TestingT, distributionID string, paths []string, maxRetries int, sleepBetweenRetries time.Duration) (string, error) {
+	logger.Logf(t, "Creating invalidation of %v for CloudFront distribution %s", paths, distributionID)
+
+	client, err := NewCloudFrontClientE(t, getDefaultRegion())
+	if err != nil {
+		return "", err
+	}
+	output, err := client.CreateInvalidation(&cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(distributionID),
+		InvalidationBatch: &cloudfront.InvalidationBatch{
+			CallerReference: aws.String(fmt.Sprintf("terratest-%s", random.UniqueId())),
+			Paths: &cloudfront.Paths{
+				Items:    aws.StringSlice(paths),
+				Quantity: aws.Int64(int64(len(paths))),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	invalidationID := aws.StringValue(output.Invalidation.Id)
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for invalidation %s of CloudFront distribution %s to complete.", invalidationID, distributionID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			invalidation, err := client.GetInvalidation(&cloudfront.GetInvalidationInput{
+				DistributionId: aws.String(distributionID),
+				Id:             aws.String(invalidationID),
+			})
+			if err != nil {
+				return "", err
+			}
+			if status := aws.StringValue(invalidation.Invalidation.Status); status != cloudFrontInvalidationCompleteStatus {
+				return "", NewCloudFrontInvalidationNotCompleteError(distributionID, invalidationID, status)
+			}
+			return fmt.Sprintf("Invalidation %s of CloudFront distribution %s is now complete", invalidationID, distributionID), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return "", err
+	}
+	return invalidationID, nil
+}
+
+// VerifyCloudFrontResponse sends a request for the given path to the domain of the given CloudFront distribution, and
+// checks that the response meets the given expectation, retrying for the specified amount of times, sleeping for the
+// provided duration between each try. This will fail the test if it does not.
+func VerifyCloudFrontResponse(t testing.TestingT, distributionID string, path string, expectation CloudFrontRequestExpectation, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := VerifyCloudFrontResponseE(t, distributionID, path, expectation, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// VerifyCloudFrontResponseE sends a request for the given path to the domain of the given CloudFront distribution, and
+// checks that the response meets the given expectation, retrying for the specified amount of times, sleeping for the
+// provided duration between each try. Retrying gives time for changes to the distribution to reach the edge location
+// that serves the request.
+//
+// To check the cache behavior for the path, the request is sent twice on each try, and the cache status CloudFront
+// reports for the second request is compared to the expected one, so a path that is cached is expected to be a hit:
+//
+//	aws.VerifyCloudFrontResponse(t, distributionID, "/static/app.js", aws.CloudFrontRequestExpectation{
+//		StatusCode:  200,
+//		Headers:     map[string]string{"Content-Type": "application/javascript"},
+//		CacheStatus: aws.CloudFrontCacheHit,
+//	}, 30, 10*time.Second)
+func VerifyCloudFrontResponseE(t testing.TestingT, distributionID string, path string, expectation CloudFrontRequestExpectation, maxRetries int, sleepBetweenRetries time.Duration) error {
+	distribution, err := GetCloudFrontDistributionE(t, distributionID)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://%s/%s", aws.StringValue(distribution.DomainName), strings.TrimPrefix(path, "/"))
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	sendRequest := func() (*http.Response, error) {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, retry.FatalError{Underlying: err}
+		}
+		for name, value := range expectation.RequestHeaders {
+			request.Header.Set(name, value)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Close()
+		return response, nil
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Sending request to %s.", url),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			if expectation.CacheStatus != "" {
+				if _, err := sendRequest(); err != nil {
+					return "", err
+				}
+			}
+			response, err := sendRequest()
+			if err != nil {
+				return "", err
+			}
+			if err := checkCloudFrontResponse(url, response, expectation); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Request to %s returned the expected response", url), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkCloudFrontResponse returns an error if the given response doesn't meet the given expectation.
+func checkCloudFrontResponse(url string, response *http.Response, expectation CloudFrontRequestExpectation) error {
+	if expectation.StatusCode != 0 && response.StatusCode != expectation.StatusCode {
+		return NewCloudFrontResponseMismatchError(url, "status code", fmt.Sprint(expectation.StatusCode), fmt.Sprint(response.StatusCode))
+	}
+	for name, expected := range expectation.Headers {
+		if actual := response.Header.Get(name); actual != expected {
+			return NewCloudFrontResponseMismatchError(url, name+" header", expected, actual)
+		}
+	}
+	if expectation.CacheStatus != "" {
+		if actual := getCloudFrontCacheStatus(response); actual != expectation.CacheStatus {
+			return NewCloudFrontResponseMismatchError(url, "cache status", expectation.CacheStatus, actual)
+		}
+	}
+	return nil
+}
+
+// getCloudFrontCacheStatus returns the cache status in the X-Cache header of the given response, which looks like
+// "Hit from cloudfront".
+func getCloudFrontCacheStatus(response *http.Response) string {
+	return strings.TrimSuffix(response.Header.Get("X-Cache"), " from cloudfront")
+}
+
+// NewCloudFrontClient creates a CloudFront client.
+func NewCloudFrontClient(t testing.TestingT, region string) *cloudfront.CloudFront {
+	client, err := NewCloudFrontClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewCloudFrontClientE creates a CloudFront client.
+func NewCloudFrontClientE(t testing.TestingT, region string) (*cloudfront.CloudFront, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return cloudfront.New(sess), nil
+}
@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCloudFrontResponse(t *testing.T) {
+	t.Parallel()
+
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":  []string{"text/html"},
+			"Cache-Control": []string{"max-age=300"},
+			"X-Cache":       []string{"Hit from cloudfront"},
+		},
+	}
+	url := "https://d111111abcdef8.cloudfront.net/index.html"
+
+	assert.NoError(t, checkCloudFrontResponse(url, response, CloudFrontRequestExpectation{
+		StatusCode:  http.StatusOK,
+		Headers:     map[string]string{"content-type": "text/html", "Cache-Control": "max-age=300"},
+		CacheStatus: CloudFrontCacheHit,
+	}))
+	assert.NoError(t, checkCloudFrontResponse(url, response, CloudFrontRequestExpectation{}))
+	assert.IsType(t, CloudFrontResponseMismatchError{}, checkCloudFrontResponse(url, response, CloudFrontRequestExpectation{StatusCode: http.StatusNotFound}))
+	assert.IsType(t, CloudFrontResponseMismatchError{}, checkCloudFrontResponse(url, response, CloudFrontRequestExpectation{Headers: map[string]string{"Content-Encoding": "gzip"}}))
+	assert.IsType(t, CloudFrontResponseMismatchError{}, checkCloudFrontResponse(url, response, CloudFrontRequestExpectation{CacheStatus: CloudFrontCacheMiss}))
+}
+
+func TestGetCloudFrontCacheStatus(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"Hit from cloudfront":        CloudFrontCacheHit,
+		"Miss from cloudfront":       CloudFrontCacheMiss,
+		"RefreshHit from cloudfront": CloudFrontCacheRefreshHit,
+		"":                           "",
+	}
+	for header, expected := range testCases {
+		response := &http.Response{Header: http.Header{}}
+		if header != "" {
+			response.Header.Set("X-Cache", header)
+		}
+		assert.Equal(t, expected, getCloudFrontCacheStatus(response), header)
+	}
+}
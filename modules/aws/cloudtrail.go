@@ -0,0 +1,264 @@
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// cloudTrailEventsPollInterval is how often CloudTrail is polled for new events. CloudTrail usually delivers events
+// within 5 to 15 minutes of the API call.
+const cloudTrailEventsPollInterval = 30 * time.Second
+
+// The lookup attributes CloudTrail can look up events by, from the most to the least selective. CloudTrail only
+// supports one lookup attribute per lookup, so the most selective one is used for the lookup and the others filter the
+// events it returns.
+var cloudTrailLookupAttributeKeys = []string{
+	cloudtrail.LookupAttributeKeyEventId,
+	cloudtrail.LookupAttributeKeyResourceName,
+	cloudtrail.LookupAttributeKeyAccessKeyId,
+	cloudtrail.LookupAttributeKeyUsername,
+	cloudtrail.LookupAttributeKeyEventName,
+	cloudtrail.LookupAttributeKeyResourceType,
+	cloudtrail.LookupAttributeKeyEventSource,
+	cloudtrail.LookupAttributeKeyReadOnly,
+}
+
+// LookupCloudTrailEvents returns the management events recorded by CloudTrail in the given region since the given time
+// that match all the given lookup attributes. This will fail the test if there is an error.
+func LookupCloudTrailEvents(t testing.TestingT, region string, lookupAttributes map[string]string, since time.Time) []*cloudtrail.Event {
+	events, err := LookupCloudTrailEventsE(t, region, lookupAttributes, since)
+	require.NoError(t, err)
+	return events
+}
+
+// LookupCloudTrailEventsE returns the management events recorded by CloudTrail in the given region since the given time
+// (or in the last 90 days if it is zero) that match all the given lookup attributes, newest first. The lookup attributes
+// are keyed by the cloudtrail.LookupAttributeKey constants, e.g.:
+//
+//	events, err := aws.LookupCloudTrailEventsE(t, region, map[string]string{
+//		cloudtrail.LookupAttributeKeyUsername:  "automation-role-session",
+//		cloudtrail.LookupAttributeKeyEventName: "PutObject",
+//	}, startTime)
+func LookupCloudTrailEventsE(t testing.TestingT, region string, lookupAttributes map[string]string, since time.Time) ([]*cloudtrail.Event, error) {
+	lookupKey, err := selectCloudTrailLookupAttribute(lookupAttributes)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewCloudTrailClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &cloudtrail.LookupEventsInput{
+		LookupAttributes: []*cloudtrail.LookupAttribute{{
+			AttributeKey:   aws.String(lookupKey),
+			AttributeValue: aws.String(lookupAttributes[lookupKey]),
+		}},
+	}
+	if !since.IsZero() {
+		input.StartTime = aws.Time(since)
+	}
+
+	events := []*cloudtrail.Event{}
+	err = client.LookupEventsPages(input, func(output *cloudtrail.LookupEventsOutput, lastPage bool) bool {
+		for _, event := range output.Events {
+			if cloudTrailEventMatches(event, lookupAttributes) {
+				events = append(events, event)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// WaitForCloudTrailEvent waits until CloudTrail records a management event in the given region that matches all the
+// given lookup attributes, and returns the latest such event. This will fail the test if there is an error.
+func WaitForCloudTrailEvent(t testing.TestingT, region string, lookupAttributes map[string]string, timeout time.Duration) *cloudtrail.Event {
+	event, err := WaitForCloudTrailEventE(t, region, lookupAttributes, timeout)
+	require.NoError(t, err)
+	return event
+}
+
+// WaitForCloudTrailEventE waits up to the given timeout until CloudTrail records a management event in the given region
+// that matches all the given lookup attributes, and returns the latest such event. As this matches events of the last
+// 90 days, use attributes that are unique to the test run, such as the name of a resource the test created. The full
+// record of the event, with its request parameters, is the JSON in the CloudTrailEvent field. Events of global services
+// such as IAM are recorded in us-east-1.
+func WaitForCloudTrailEventE(t testing.TestingT, region string, lookupAttributes map[string]string, timeout time.Duration) (*cloudtrail.Event, error) {
+	maxRetries, sleepBetweenRetries := getCloudTrailRetries(timeout)
+
+	var event *cloudtrail.Event
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for CloudTrail event matching %v in %s.", lookupAttributes, region),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			events, err := LookupCloudTrailEventsE(t, region, lookupAttributes, time.Time{})
+			if err != nil {
+				return "", err
+			}
+			if len(events) == 0 {
+				return "", NewNotFoundError("CloudTrail event", fmt.Sprintf("%v", lookupAttributes), region)
+			}
+			event = events[0]
+			return fmt.Sprintf("Found CloudTrail event %s (%s)", aws.StringValue(event.EventId), aws.StringValue(event.EventName)), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// AssertCloudTrailEventNames checks that the names of the management events recorded by CloudTrail in the given region
+// since the given time that match all the given lookup attributes are exactly the expected ones, waiting up to the given
+// timeout for CloudTrail to deliver them. This will fail the test if they aren't.
+func AssertCloudTrailEventNames(t testing.TestingT, region string, lookupAttributes map[string]string, since time.Time, expectedEventNames []string, timeout time.Duration) {
+	err := AssertCloudTrailEventNamesE(t, region, lookupAttributes, since, expectedEventNames, timeout)
+	require.NoError(t, err)
+}
+
+// AssertCloudTrailEventNamesE checks that the names of the management events recorded by CloudTrail in the given region
+// since the given time that match all the given lookup attributes are exactly the expected ones, in any order, waiting
+// up to the given timeout for CloudTrail to deliver them. A name that is expected several times must be recorded as many
+// times. This is useful to check that an automation role performed exactly the expected calls:
+//
+//	start := time.Now()
+//	terraform.Apply(t, terraformOptions)
+//	aws.AssertCloudTrailEventNames(t, region, map[string]string{cloudtrail.LookupAttributeKeyUsername: sessionName},
+//		start, []string{"CreateBucket", "PutBucketTagging"}, 20*time.Minute)
+func AssertCloudTrailEventNamesE(t testing.TestingT, region string, lookupAttributes map[string]string, since time.Time, expectedEventNames []string, timeout time.Duration) error {
+	maxRetries, sleepBetweenRetries := getCloudTrailRetries(timeout)
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for CloudTrail events %v matching %v in %s.", expectedEventNames, lookupAttributes, region),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			events, err := LookupCloudTrailEventsE(t, region, lookupAttributes, since)
+			if err != nil {
+				return "", err
+			}
+			if err := checkCloudTrailEventNames(events, expectedEventNames); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Found CloudTrail events %v", expectedEventNames), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkCloudTrailEventNames returns an error if the names of the given events are not the expected ones, in any order.
+func checkCloudTrailEventNames(events []*cloudtrail.Event, expectedEventNames []string) error {
+	actual := []string{}
+	for _, event := range events {
+		actual = append(actual, aws.StringValue(event.EventName))
+	}
+	expected := append([]string{}, expectedEventNames...)
+	sort.Strings(actual)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(actual, expected) {
+		return NewCloudTrailEventNamesMismatchError(expected, actual)
+	}
+	return nil
+}
+
+// selectCloudTrailLookupAttribute returns the most selective of the given lookup attributes, which is the one to look
+// up events by, or an error if there are none or some are unknown.
+func selectCloudTrailLookupAttribute(lookupAttributes map[string]string) (string, error) {
+	if len(lookupAttributes) == 0 {
+		return "", fmt.Errorf("at least one CloudTrail lookup attribute is required")
+	}
+	selected := ""
+	known := map[string]bool{}
+	for _, key := range cloudTrailLookupAttributeKeys {
+		known[key] = true
+		if _, ok := lookupAttributes[key]; ok && selected == "" {
+			selected = key
+		}
+	}
+	for key := range lookupAttributes {
+		if !known[key] {
+			return "", fmt.Errorf("unknown CloudTrail lookup attribute %s", key)
+		}
+	}
+	return selected, nil
+}
+
+// cloudTrailEventMatches returns true if the given event matches all the given lookup attributes.
+func cloudTrailEventMatches(event *cloudtrail.Event, lookupAttributes map[string]string) bool {
+	for key, value := range lookupAttributes {
+		var matches bool
+		switch key {
+		case cloudtrail.LookupAttributeKeyEventId:
+			matches = aws.StringValue(event.EventId) == value
+		case cloudtrail.LookupAttributeKeyEventName:
+			matches = aws.StringValue(event.EventName) == value
+		case cloudtrail.LookupAttributeKeyEventSource:
+			matches = aws.StringValue(event.EventSource) == value
+		case cloudtrail.LookupAttributeKeyUsername:
+			matches = aws.StringValue(event.Username) == value
+		case cloudtrail.LookupAttributeKeyAccessKeyId:
+			matches = aws.StringValue(event.AccessKeyId) == value
+		case cloudtrail.LookupAttributeKeyReadOnly:
+			matches = aws.StringValue(event.ReadOnly) == value
+		case cloudtrail.LookupAttributeKeyResourceName:
+			for _, resource := range event.Resources {
+				matches = matches || aws.StringValue(resource.ResourceName) == value
+			}
+		case cloudtrail.LookupAttributeKeyResourceType:
+			for _, resource := range event.Resources {
+				matches = matches || aws.StringValue(resource.ResourceType) == value
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+// getCloudTrailRetries returns the retries and the sleep between them to poll CloudTrail for up to the given timeout.
+func getCloudTrailRetries(timeout time.Duration) (int, time.Duration) {
+	sleepBetweenRetries := cloudTrailEventsPollInterval
+	if timeout < sleepBetweenRetries {
+		sleepBetweenRetries = timeout
+	}
+	maxRetries := 1
+	if sleepBetweenRetries > 0 {
+		maxRetries = int(timeout/sleepBetweenRetries) + 1
+	}
+	return maxRetries, sleepBetweenRetries
+}
+
+// NewCloudTrailClient creates a CloudTrail client.
+func NewCloudTrailClient(t testing.TestingT, region string) *cloudtrail.CloudTrail {
+	client, err := NewCloudTrailClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewCloudTrailClientE creates a CloudTrail client.
+func NewCloudTrailClientE(t testing.TestingT, region string) (*cloudtrail.CloudTrail, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return cloudtrail.New(sess), nil
+}
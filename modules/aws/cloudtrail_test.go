@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectCloudTrailLookupAttribute(t *testing.T) {
+	t.Parallel()
+
+	key, err := selectCloudTrailLookupAttribute(map[string]string{
+		cloudtrail.LookupAttributeKeyEventName:    "PutObject",
+		cloudtrail.LookupAttributeKeyResourceName: "my-bucket",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, cloudtrail.LookupAttributeKeyResourceName, key)
+
+	_, err = selectCloudTrailLookupAttribute(map[string]string{})
+	assert.Error(t, err)
+
+	_, err = selectCloudTrailLookupAttribute(map[string]string{"Region": "us-east-1"})
+	assert.Error(t, err)
+}
+
+func TestCloudTrailEventMatches(t *testing.T) {
+	t.Parallel()
+
+	event := &cloudtrail.Event{
+		EventName:   aws.String("CreateBucket"),
+		EventSource: aws.String("s3.amazonaws.com"),
+		Username:    aws.String("automation"),
+		ReadOnly:    aws.String("false"),
+		Resources: []*cloudtrail.Resource{
+			{ResourceName: aws.String("my-bucket"), ResourceType: aws.String("AWS::S3::Bucket")},
+		},
+	}
+
+	assert.True(t, cloudTrailEventMatches(event, map[string]string{
+		cloudtrail.LookupAttributeKeyEventName:    "CreateBucket",
+		cloudtrail.LookupAttributeKeyUsername:     "automation",
+		cloudtrail.LookupAttributeKeyResourceName: "my-bucket",
+		cloudtrail.LookupAttributeKeyResourceType: "AWS::S3::Bucket",
+		cloudtrail.LookupAttributeKeyReadOnly:     "false",
+	}))
+	assert.False(t, cloudTrailEventMatches(event, map[string]string{cloudtrail.LookupAttributeKeyUsername: "someone-else"}))
+	assert.False(t, cloudTrailEventMatches(event, map[string]string{cloudtrail.LookupAttributeKeyResourceName: "other-bucket"}))
+}
+
+func TestCheckCloudTrailEventNames(t *testing.T) {
+	t.Parallel()
+
+	events := []*cloudtrail.Event{
+		{EventName: aws.String("PutBucketTagging")},
+		{EventName: aws.String("CreateBucket")},
+	}
+
+	assert.NoError(t, checkCloudTrailEventNames(events, []string{"CreateBucket", "PutBucketTagging"}))
+	assert.IsType(t, CloudTrailEventNamesMismatchError{}, checkCloudTrailEventNames(events, []string{"CreateBucket"}))
+	assert.IsType(t, CloudTrailEventNamesMismatchError{}, checkCloudTrailEventNames(events, []string{"CreateBucket", "CreateBucket", "PutBucketTagging"}))
+}
+
+func TestGetCloudTrailRetries(t *testing.T) {
+	t.Parallel()
+
+	maxRetries, sleep := getCloudTrailRetries(10 * time.Minute)
+	assert.Equal(t, 21, maxRetries)
+	assert.Equal(t, 30*time.Second, sleep)
+
+	maxRetries, sleep = getCloudTrailRetries(0)
+	assert.Equal(t, 1, maxRetries)
+	assert.Equal(t, time.Duration(0), sleep)
+}
@@ -1,11 +1,25 @@
 package aws
 
 import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
 
+// cloudWatchLogsPollInterval is how often CloudWatch Logs is polled for new log events.
+const cloudWatchLogsPollInterval = 5 * time.Second
+
+// cloudWatchAlarmPollInterval is how often the state of a CloudWatch alarm is checked while waiting for it to change.
+const cloudWatchAlarmPollInterval = 10 * time.Second
+
 // GetCloudWatchLogEntries returns the CloudWatch log messages in the given region for the given log stream and log group.
 func GetCloudWatchLogEntries(t testing.TestingT, awsRegion string, logStreamName string, logGroupName string) []string {
 	out, err := GetCloudWatchLogEntriesE(t, awsRegion, logStreamName, logGroupName)
@@ -39,6 +53,381 @@ func GetCloudWatchLogEntriesE(t testing.TestingT, awsRegion string, logStreamNam
 	return entries, nil
 }
 
+// WaitForLogEventMatching waits until a log event that matches the given CloudWatch Logs filter pattern (or any log
+// event, if the pattern is empty) is logged to the given log group within the given timeout, and returns its message.
+// This will fail the test if there are any errors, or if no matching log event is found.
+func WaitForLogEventMatching(t testing.TestingT, awsRegion string, logGroupName string, filterPattern string, timeout time.Duration) string {
+	message, err := WaitForLogEventMatchingE(t, awsRegion, logGroupName, filterPattern, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// WaitForLogEventMatchingE waits until a log event that matches the given CloudWatch Logs filter pattern (or any log
+// event, if the pattern is empty) is logged to the given log group within the given timeout, and returns its message.
+// Log events that were logged up to the timeout before this is called are also matched, so that events caused by an
+// action taken right before calling this are found even though CloudWatch Logs takes a few seconds to ingest them.
+func WaitForLogEventMatchingE(t testing.TestingT, awsRegion string, logGroupName string, filterPattern string, timeout time.Duration) (string, error) {
+	client, err := NewCloudWatchLogsClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Logf(t, "Waiting for a log event matching '%s' in log group %s", filterPattern, logGroupName)
+	cursor := newCloudWatchLogCursor(time.Now().Add(-timeout))
+	deadline := time.Now().Add(timeout)
+	for {
+		events, err := cursor.fetchNewEvents(client, logGroupName, filterPattern)
+		if err != nil {
+			return "", err
+		}
+		if len(events) > 0 {
+			return aws.StringValue(events[0].Message), nil
+		}
+		if !time.Now().Before(deadline) {
+			return "", LogEventNotFound{LogGroupName: logGroupName, FilterPattern: filterPattern, Timeout: timeout}
+		}
+		time.Sleep(cloudWatchLogsPollInterval)
+	}
+}
+
+// CloudWatchLogTailer streams the log events of a CloudWatch log group to the test logger in the background. Create one
+// with TailCloudWatchLogs, and call Stop when you no longer need it.
+type CloudWatchLogTailer struct {
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// TailCloudWatchLogs starts streaming the log events that match the given CloudWatch Logs filter pattern (or all log
+// events, if the pattern is empty) and are logged to the given log group from now on to the test logger, until Stop is
+// called on the returned tailer. This is useful to see what an application or lambda function logs while a test
+// exercises it. This will fail the test if the CloudWatch Logs client can't be created.
+func TailCloudWatchLogs(t testing.TestingT, awsRegion string, logGroupName string, filterPattern string) *CloudWatchLogTailer {
+	tailer, err := TailCloudWatchLogsE(t, awsRegion, logGroupName, filterPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tailer
+}
+
+// TailCloudWatchLogsE starts streaming the log events that match the given CloudWatch Logs filter pattern (or all log
+// events, if the pattern is empty) and are logged to the given log group from now on to the test logger, until Stop is
+// called on the returned tailer. Errors fetching the log events are logged rather than returned.
+func TailCloudWatchLogsE(t testing.TestingT, awsRegion string, logGroupName string, filterPattern string) (*CloudWatchLogTailer, error) {
+	client, err := NewCloudWatchLogsClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	tailer := &CloudWatchLogTailer{stop: make(chan struct{}), stopped: make(chan struct{})}
+	cursor := newCloudWatchLogCursor(time.Now())
+	go func() {
+		defer close(tailer.stopped)
+		for {
+			events, err := cursor.fetchNewEvents(client, logGroupName, filterPattern)
+			if err != nil {
+				logger.Logf(t, "WARN: Failed to fetch log events from log group %s: %v", logGroupName, err)
+			}
+			for _, event := range events {
+				logger.Logf(t, "[%s] %s", aws.StringValue(event.LogStreamName), aws.StringValue(event.Message))
+			}
+
+			select {
+			case <-time.After(cloudWatchLogsPollInterval):
+			case <-tailer.stop:
+				return
+			}
+		}
+	}()
+	return tailer, nil
+}
+
+// Stop stops streaming log events, and waits for the log events that are being fetched to be logged. It is safe to call
+// Stop more than once.
+func (tailer *CloudWatchLogTailer) Stop() {
+	tailer.once.Do(func() {
+		close(tailer.stop)
+	})
+	<-tailer.stopped
+}
+
+// cloudWatchLogCursor keeps track of the log events of a log group that have already been fetched, so that each event
+// is only returned once across repeated calls to FilterLogEvents.
+type cloudWatchLogCursor struct {
+	startTime int64           // The timestamp (in milliseconds) to fetch events from
+	seen      map[string]bool // The IDs of the events fetched at startTime, which may be fetched again
+}
+
+func newCloudWatchLogCursor(startTime time.Time) *cloudWatchLogCursor {
+	return &cloudWatchLogCursor{startTime: startTime.UnixNano() / int64(time.Millisecond), seen: map[string]bool{}}
+}
+
+// fetchNewEvents returns the log events of the given log group that match the filter pattern and have not been returned
+// before, in chronological order.
+func (cursor *cloudWatchLogCursor) fetchNewEvents(client *cloudwatchlogs.CloudWatchLogs, logGroupName string, filterPattern string) ([]*cloudwatchlogs.FilteredLogEvent, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(logGroupName),
+		StartTime:    aws.Int64(cursor.startTime),
+	}
+	if filterPattern != "" {
+		input.FilterPattern = aws.String(filterPattern)
+	}
+
+	events := []*cloudwatchlogs.FilteredLogEvent{}
+	err := client.FilterLogEventsPages(input, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+		events = append(events, page.Events...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cursor.advance(events), nil
+}
+
+// advance returns the given events that have not been returned before, and moves the cursor past them. Events that have
+// the same timestamp as the last returned event are remembered by ID, as the next fetch will include them again.
+func (cursor *cloudWatchLogCursor) advance(events []*cloudwatchlogs.FilteredLogEvent) []*cloudwatchlogs.FilteredLogEvent {
+	newEvents := []*cloudwatchlogs.FilteredLogEvent{}
+	for _, event := range events {
+		eventID := aws.StringValue(event.EventId)
+		timestamp := aws.Int64Value(event.Timestamp)
+		if cursor.seen[eventID] || timestamp < cursor.startTime {
+			continue
+		}
+		if timestamp > cursor.startTime {
+			cursor.startTime = timestamp
+			cursor.seen = map[string]bool{}
+		}
+		cursor.seen[eventID] = true
+		newEvents = append(newEvents, event)
+	}
+	return newEvents
+}
+
+// GetMetricStatistics returns the datapoints of the given statistic (e.g., cloudwatch.StatisticAverage) of the
+// CloudWatch metric with the given namespace, name, and dimensions, aggregated over the given period, for the given
+// duration before now, in chronological order. This will fail the test if there are any errors.
+func GetMetricStatistics(
+	t testing.TestingT,
+	awsRegion string,
+	namespace string,
+	metricName string,
+	dimensions map[string]string,
+	statistic string,
+	period time.Duration,
+	since time.Duration,
+) []*cloudwatch.Datapoint {
+	datapoints, err := GetMetricStatisticsE(t, awsRegion, namespace, metricName, dimensions, statistic, period, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return datapoints
+}
+
+// GetMetricStatisticsE returns the datapoints of the given statistic (e.g., cloudwatch.StatisticAverage) of the
+// CloudWatch metric with the given namespace, name, and dimensions, aggregated over the given period, for the given
+// duration before now, in chronological order.
+func GetMetricStatisticsE(
+	t testing.TestingT,
+	awsRegion string,
+	namespace string,
+	metricName string,
+	dimensions map[string]string,
+	statistic string,
+	period time.Duration,
+	since time.Duration,
+) ([]*cloudwatch.Datapoint, error) {
+	client, err := NewCloudWatchClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	input := &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String(namespace),
+		MetricName: aws.String(metricName),
+		Statistics: aws.StringSlice([]string{statistic}),
+		Period:     aws.Int64(int64(period.Seconds())),
+		StartTime:  aws.Time(now.Add(-since)),
+		EndTime:    aws.Time(now),
+	}
+	for name, value := range dimensions {
+		input.Dimensions = append(input.Dimensions, &cloudwatch.Dimension{Name: aws.String(name), Value: aws.String(value)})
+	}
+
+	output, err := client.GetMetricStatistics(input)
+	if err != nil {
+		return nil, err
+	}
+	datapoints := output.Datapoints
+	sort.Slice(datapoints, func(i, j int) bool {
+		return aws.TimeValue(datapoints[i].Timestamp).Before(aws.TimeValue(datapoints[j].Timestamp))
+	})
+	return datapoints, nil
+}
+
+// GetAlarmState returns the state (e.g., cloudwatch.StateValueAlarm) of the CloudWatch metric or composite alarm with
+// the given name. This will fail the test if there are any errors.
+func GetAlarmState(t testing.TestingT, awsRegion string, alarmName string) string {
+	state, err := GetAlarmStateE(t, awsRegion, alarmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// GetAlarmStateE returns the state (e.g., cloudwatch.StateValueAlarm) of the CloudWatch metric or composite alarm with
+// the given name.
+func GetAlarmStateE(t testing.TestingT, awsRegion string, alarmName string) (string, error) {
+	client, err := NewCloudWatchClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	return getAlarmState(client, awsRegion, alarmName)
+}
+
+func getAlarmState(client *cloudwatch.CloudWatch, awsRegion string, alarmName string) (string, error) {
+	output, err := client.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+		AlarmNames: aws.StringSlice([]string{alarmName}),
+		AlarmTypes: aws.StringSlice([]string{cloudwatch.AlarmTypeMetricAlarm, cloudwatch.AlarmTypeCompositeAlarm}),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.MetricAlarms) > 0 {
+		return aws.StringValue(output.MetricAlarms[0].StateValue), nil
+	}
+	if len(output.CompositeAlarms) > 0 {
+		return aws.StringValue(output.CompositeAlarms[0].StateValue), nil
+	}
+	return "", NewNotFoundError("CloudWatch alarm", alarmName, awsRegion)
+}
+
+// WaitUntilAlarmInState waits until the CloudWatch metric or composite alarm with the given name is in the given state
+// (e.g., cloudwatch.StateValueAlarm) within the given timeout. This will fail the test if there are any errors, or if the
+// alarm doesn't reach the state in time.
+func WaitUntilAlarmInState(t testing.TestingT, awsRegion string, alarmName string, state string, timeout time.Duration) {
+	err := WaitUntilAlarmInStateE(t, awsRegion, alarmName, state, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WaitUntilAlarmInStateE waits until the CloudWatch metric or composite alarm with the given name is in the given state
+// (e.g., cloudwatch.StateValueAlarm) within the given timeout. This is useful to test that an alarm fires once the
+// conditions it monitors are induced. Note that CloudWatch only evaluates alarms once per period of their metric.
+func WaitUntilAlarmInStateE(t testing.TestingT, awsRegion string, alarmName string, state string, timeout time.Duration) error {
+	client, err := NewCloudWatchClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	sleepBetweenRetries := cloudWatchAlarmPollInterval
+	if timeout < sleepBetweenRetries {
+		sleepBetweenRetries = timeout
+	}
+	maxRetries := 1
+	if sleepBetweenRetries > 0 {
+		maxRetries = int(timeout/sleepBetweenRetries) + 1
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for CloudWatch alarm %s to be in state %s.", alarmName, state),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			actualState, err := getAlarmState(client, awsRegion, alarmName)
+			if err != nil {
+				return "", err
+			}
+			if actualState != state {
+				return "", AlarmNotInState{AlarmName: alarmName, ExpectedState: state, ActualState: actualState}
+			}
+			return fmt.Sprintf("CloudWatch alarm %s is in state %s", alarmName, state), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// SetAlarmState temporarily sets the state of the CloudWatch alarm with the given name, with the given reason. This will
+// fail the test if there are any errors.
+func SetAlarmState(t testing.TestingT, awsRegion string, alarmName string, state string, reason string) {
+	err := SetAlarmStateE(t, awsRegion, alarmName, state, reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SetAlarmStateE temporarily sets the state of the CloudWatch alarm with the given name, with the given reason. The
+// alarm goes back to its actual state the next time it is evaluated. This is useful to test the actions of an alarm
+// (e.g., that it notifies an SNS topic), or that a composite alarm fires when the alarms in its rule do.
+func SetAlarmStateE(t testing.TestingT, awsRegion string, alarmName string, state string, reason string) error {
+	logger.Logf(t, "Setting state of CloudWatch alarm %s to %s", alarmName, state)
+
+	client, err := NewCloudWatchClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+	_, err = client.SetAlarmState(&cloudwatch.SetAlarmStateInput{
+		AlarmName:   aws.String(alarmName),
+		StateValue:  aws.String(state),
+		StateReason: aws.String(reason),
+	})
+	return err
+}
+
+// GetCompositeAlarmRule returns the rule of the CloudWatch composite alarm with the given name (e.g.,
+// "ALARM(cpu-high) OR ALARM(memory-high)"). This will fail the test if there are any errors.
+func GetCompositeAlarmRule(t testing.TestingT, awsRegion string, alarmName string) string {
+	rule, err := GetCompositeAlarmRuleE(t, awsRegion, alarmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+// GetCompositeAlarmRuleE returns the rule of the CloudWatch composite alarm with the given name (e.g.,
+// "ALARM(cpu-high) OR ALARM(memory-high)").
+func GetCompositeAlarmRuleE(t testing.TestingT, awsRegion string, alarmName string) (string, error) {
+	client, err := NewCloudWatchClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	output, err := client.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+		AlarmNames: aws.StringSlice([]string{alarmName}),
+		AlarmTypes: aws.StringSlice([]string{cloudwatch.AlarmTypeCompositeAlarm}),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.CompositeAlarms) == 0 {
+		return "", NewNotFoundError("CloudWatch composite alarm", alarmName, awsRegion)
+	}
+	return aws.StringValue(output.CompositeAlarms[0].AlarmRule), nil
+}
+
+// NewCloudWatchClient creates a new CloudWatch client.
+func NewCloudWatchClient(t testing.TestingT, region string) *cloudwatch.CloudWatch {
+	client, err := NewCloudWatchClientE(t, region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// NewCloudWatchClientE creates a new CloudWatch client.
+func NewCloudWatchClientE(t testing.TestingT, region string) (*cloudwatch.CloudWatch, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return cloudwatch.New(sess), nil
+}
+
 // NewCloudWatchLogsClient creates a new CloudWatch Logs client.
 func NewCloudWatchLogsClient(t testing.TestingT, region string) *cloudwatchlogs.CloudWatchLogs {
 	client, err := NewCloudWatchLogsClientE(t, region)
@@ -50,9 +439,31 @@ func NewCloudWatchLogsClient(t testing.TestingT, region string) *cloudwatchlogs.
 
 // NewCloudWatchLogsClientE creates a new CloudWatch Logs client.
 func NewCloudWatchLogsClientE(t testing.TestingT, region string) (*cloudwatchlogs.CloudWatchLogs, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
 	return cloudwatchlogs.New(sess), nil
 }
+
+// LogEventNotFound is an error that occurs if no log event matching a filter pattern is logged within a timeout.
+type LogEventNotFound struct {
+	LogGroupName  string
+	FilterPattern string
+	Timeout       time.Duration
+}
+
+func (err LogEventNotFound) Error() string {
+	return fmt.Sprintf("No log event matching '%s' was logged to log group %s within %s", err.FilterPattern, err.LogGroupName, err.Timeout)
+}
+
+// AlarmNotInState is an error that occurs if a CloudWatch alarm is not in the expected state.
+type AlarmNotInState struct {
+	AlarmName     string
+	ExpectedState string
+	ActualState   string
+}
+
+func (err AlarmNotInState) Error() string {
+	return fmt.Sprintf("CloudWatch alarm %s is in state %s rather than %s", err.AlarmName, err.ActualState, err.ExpectedState)
+}
@@ -0,0 +1,67 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudWatchLogCursorAdvance(t *testing.T) {
+	t.Parallel()
+
+	cursor := newCloudWatchLogCursor(time.Unix(100, 0))
+	event := func(id string, timestampMillis int64) *cloudwatchlogs.FilteredLogEvent {
+		return &cloudwatchlogs.FilteredLogEvent{EventId: aws.String(id), Timestamp: aws.Int64(timestampMillis), Message: aws.String(id)}
+	}
+
+	// Events from before the start time are skipped.
+	newEvents := cursor.advance([]*cloudwatchlogs.FilteredLogEvent{event("old", 99000), event("a", 100000), event("b", 101000)})
+	assert.Equal(t, []string{"a", "b"}, eventIDs(newEvents))
+
+	// The next fetch starts at the timestamp of the last event, so it includes that event again.
+	newEvents = cursor.advance([]*cloudwatchlogs.FilteredLogEvent{event("b", 101000), event("c", 101000), event("d", 102000)})
+	assert.Equal(t, []string{"c", "d"}, eventIDs(newEvents))
+
+	assert.Empty(t, cursor.advance([]*cloudwatchlogs.FilteredLogEvent{event("d", 102000)}))
+}
+
+func eventIDs(events []*cloudwatchlogs.FilteredLogEvent) []string {
+	ids := []string{}
+	for _, event := range events {
+		ids = append(ids, aws.StringValue(event.EventId))
+	}
+	return ids
+}
+
+func TestWaitUntilAlarmInState(t *testing.T) {
+	t.Parallel()
+
+	region := GetRandomStableRegion(t, nil, nil)
+	alarmName := "terratest-alarm-" + random.UniqueId()
+	client := NewCloudWatchClient(t, region)
+
+	_, err := client.PutMetricAlarm(&cloudwatch.PutMetricAlarmInput{
+		AlarmName:          aws.String(alarmName),
+		Namespace:          aws.String("Terratest"),
+		MetricName:         aws.String("TestMetric"),
+		Statistic:          aws.String(cloudwatch.StatisticSum),
+		Period:             aws.Int64(60),
+		EvaluationPeriods:  aws.Int64(1),
+		Threshold:          aws.Float64(1),
+		ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold),
+	})
+	require.NoError(t, err)
+	defer client.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{AlarmNames: aws.StringSlice([]string{alarmName})})
+
+	SetAlarmState(t, region, alarmName, cloudwatch.StateValueAlarm, "Testing")
+	WaitUntilAlarmInState(t, region, alarmName, cloudwatch.StateValueAlarm, time.Minute)
+
+	_, err = GetAlarmStateE(t, region, alarmName+"-does-not-exist")
+	assert.IsType(t, NotFoundError{}, err)
+}
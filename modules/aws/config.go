@@ -0,0 +1,237 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/configservice"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	configComplianceMaxRetries          = 60
+	configComplianceSleepBetweenRetries = 10 * time.Second
+)
+
+// StartConfigRuleEvaluation triggers an evaluation of the given AWS Config rule against all the resources in its scope.
+// This will fail the test if there is an error.
+func StartConfigRuleEvaluation(t testing.TestingT, region string, ruleName string) {
+	err := StartConfigRuleEvaluationE(t, region, ruleName)
+	require.NoError(t, err)
+}
+
+// StartConfigRuleEvaluationE triggers an evaluation of the given AWS Config rule against all the resources in its
+// scope, without waiting for it to complete. This is useful to evaluate resources a test just created without waiting
+// for the next periodic or change-triggered evaluation.
+func StartConfigRuleEvaluationE(t testing.TestingT, region string, ruleName string) error {
+	client, err := NewConfigServiceClientE(t, region)
+	if err != nil {
+		return err
+	}
+	logger.Logf(t, "Starting evaluation of AWS Config rule %s", ruleName)
+	_, err = client.StartConfigRulesEvaluation(&configservice.StartConfigRulesEvaluationInput{
+		ConfigRuleNames: aws.StringSlice([]string{ruleName}),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == configservice.ErrCodeNoSuchConfigRuleException {
+		return NewNotFoundError("AWS Config rule", ruleName, region)
+	}
+	return err
+}
+
+// EvaluateConfigRule triggers an evaluation of the given AWS Config rule and waits until it completes, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try. This will fail the
+// test if there is an error.
+func EvaluateConfigRule(t testing.TestingT, region string, ruleName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := EvaluateConfigRuleE(t, region, ruleName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// EvaluateConfigRuleE triggers an evaluation of the given AWS Config rule and waits until it completes, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try. This returns an error
+// if the evaluation fails, e.g., if the lambda function of a custom rule returns an error.
+func EvaluateConfigRuleE(t testing.TestingT, region string, ruleName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	startTime := time.Now()
+	if err := StartConfigRuleEvaluationE(t, region, ruleName); err != nil {
+		return err
+	}
+	return WaitUntilConfigRuleEvaluatedE(t, region, ruleName, startTime, maxRetries, sleepBetweenRetries)
+}
+
+// WaitUntilConfigRuleEvaluated waits until an evaluation of the given AWS Config rule completes after the given time,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This will
+// fail the test if there is an error.
+func WaitUntilConfigRuleEvaluated(t testing.TestingT, region string, ruleName string, since time.Time, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilConfigRuleEvaluatedE(t, region, ruleName, since, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilConfigRuleEvaluatedE waits until an evaluation of the given AWS Config rule completes after the given time,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This
+// stops waiting if an evaluation fails after the given time.
+func WaitUntilConfigRuleEvaluatedE(t testing.TestingT, region string, ruleName string, since time.Time, maxRetries int, sleepBetweenRetries time.Duration) error {
+	client, err := NewConfigServiceClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for AWS Config rule %s to be evaluated.", ruleName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			output, err := client.DescribeConfigRuleEvaluationStatus(&configservice.DescribeConfigRuleEvaluationStatusInput{
+				ConfigRuleNames: aws.StringSlice([]string{ruleName}),
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(output.ConfigRulesEvaluationStatus) == 0 {
+				return "", retry.FatalError{Underlying: NewNotFoundError("AWS Config rule", ruleName, region)}
+			}
+			if err := checkConfigRuleEvaluated(output.ConfigRulesEvaluationStatus[0], since); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("AWS Config rule %s is now evaluated", ruleName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkConfigRuleEvaluated returns an error if the given rule hasn't been evaluated successfully since the given time,
+// wrapped in a retry.FatalError if its evaluation failed since then.
+func checkConfigRuleEvaluated(status *configservice.ConfigRuleEvaluationStatus, since time.Time) error {
+	ruleName := aws.StringValue(status.ConfigRuleName)
+	if status.LastSuccessfulEvaluationTime != nil && !status.LastSuccessfulEvaluationTime.Before(since) {
+		return nil
+	}
+	if status.LastFailedEvaluationTime != nil && !status.LastFailedEvaluationTime.Before(since) {
+		return retry.FatalError{Underlying: NewConfigRuleEvaluationFailedError(ruleName, aws.StringValue(status.LastErrorCode), aws.StringValue(status.LastErrorMessage))}
+	}
+	return NewConfigRuleEvaluationFailedError(ruleName, "", "evaluation not completed yet")
+}
+
+// GetResourceComplianceForConfigRule returns the compliance of the resource with the given ID according to the given
+// AWS Config rule (e.g., configservice.ComplianceTypeCompliant). This will fail the test if there is an error.
+func GetResourceComplianceForConfigRule(t testing.TestingT, region string, ruleName string, resourceID string) string {
+	compliance, err := GetResourceComplianceForConfigRuleE(t, region, ruleName, resourceID)
+	require.NoError(t, err)
+	return compliance
+}
+
+// GetResourceComplianceForConfigRuleE returns the compliance of the resource with the given ID according to the latest
+// evaluation of the given AWS Config rule, which is one of the configservice.ComplianceType constants. This returns a
+// NotFoundError if the rule hasn't evaluated the resource.
+func GetResourceComplianceForConfigRuleE(t testing.TestingT, region string, ruleName string, resourceID string) (string, error) {
+	client, err := NewConfigServiceClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+
+	compliance := ""
+	err = client.GetComplianceDetailsByConfigRulePages(&configservice.GetComplianceDetailsByConfigRuleInput{
+		ConfigRuleName: aws.String(ruleName),
+		ComplianceTypes: aws.StringSlice([]string{
+			configservice.ComplianceTypeCompliant,
+			configservice.ComplianceTypeNonCompliant,
+			configservice.ComplianceTypeNotApplicable,
+		}),
+	}, func(output *configservice.GetComplianceDetailsByConfigRuleOutput, lastPage bool) bool {
+		compliance = findConfigEvaluationCompliance(output.EvaluationResults, resourceID)
+		return compliance == ""
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == configservice.ErrCodeNoSuchConfigRuleException {
+			return "", NewNotFoundError("AWS Config rule", ruleName, region)
+		}
+		return "", err
+	}
+	if compliance == "" {
+		return "", NewNotFoundError(fmt.Sprintf("evaluation of AWS Config rule %s for resource", ruleName), resourceID, region)
+	}
+	return compliance, nil
+}
+
+// findConfigEvaluationCompliance returns the compliance of the resource with the given ID among the given evaluation
+// results, or an empty string if there is no evaluation result for it.
+func findConfigEvaluationCompliance(results []*configservice.EvaluationResult, resourceID string) string {
+	for _, result := range results {
+		if result.EvaluationResultIdentifier == nil || result.EvaluationResultIdentifier.EvaluationResultQualifier == nil {
+			continue
+		}
+		if aws.StringValue(result.EvaluationResultIdentifier.EvaluationResultQualifier.ResourceId) == resourceID {
+			return aws.StringValue(result.ComplianceType)
+		}
+	}
+	return ""
+}
+
+// WaitUntilResourceCompliant waits until the given AWS Config rule evaluates the resource with the given ID as
+// compliant. This will fail the test if there is an error.
+func WaitUntilResourceCompliant(t testing.TestingT, region string, ruleName string, resourceID string) {
+	err := WaitUntilResourceCompliantE(t, region, ruleName, resourceID)
+	require.NoError(t, err)
+}
+
+// WaitUntilResourceCompliantE waits up to 10 minutes until the given AWS Config rule evaluates the resource with the
+// given ID (e.g., the name of an S3 bucket or the ID of a security group) as compliant. Use StartConfigRuleEvaluationE
+// first to evaluate resources a test just created without waiting for the next triggered evaluation.
+func WaitUntilResourceCompliantE(t testing.TestingT, region string, ruleName string, resourceID string) error {
+	return waitUntilResourceComplianceE(t, region, ruleName, resourceID, configservice.ComplianceTypeCompliant)
+}
+
+// WaitUntilResourceNonCompliant waits until the given AWS Config rule evaluates the resource with the given ID as
+// noncompliant. This will fail the test if there is an error.
+func WaitUntilResourceNonCompliant(t testing.TestingT, region string, ruleName string, resourceID string) {
+	err := WaitUntilResourceNonCompliantE(t, region, ruleName, resourceID)
+	require.NoError(t, err)
+}
+
+// WaitUntilResourceNonCompliantE waits up to 10 minutes until the given AWS Config rule evaluates the resource with the
+// given ID as noncompliant. This is useful to check that a rule detects a deliberately misconfigured resource.
+func WaitUntilResourceNonCompliantE(t testing.TestingT, region string, ruleName string, resourceID string) error {
+	return waitUntilResourceComplianceE(t, region, ruleName, resourceID, configservice.ComplianceTypeNonCompliant)
+}
+
+func waitUntilResourceComplianceE(t testing.TestingT, region string, ruleName string, resourceID string, expectedCompliance string) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for AWS Config rule %s to evaluate resource %s as %s.", ruleName, resourceID, expectedCompliance),
+		configComplianceMaxRetries,
+		configComplianceSleepBetweenRetries,
+		func() (string, error) {
+			compliance, err := GetResourceComplianceForConfigRuleE(t, region, ruleName, resourceID)
+			if err != nil {
+				return "", err
+			}
+			if compliance != expectedCompliance {
+				return "", NewConfigResourceComplianceMismatchError(ruleName, resourceID, expectedCompliance, compliance)
+			}
+			return fmt.Sprintf("AWS Config rule %s evaluated resource %s as %s", ruleName, resourceID, compliance), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// NewConfigServiceClient creates an AWS Config client.
+func NewConfigServiceClient(t testing.TestingT, region string) *configservice.ConfigService {
+	client, err := NewConfigServiceClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewConfigServiceClientE creates an AWS Config client.
+func NewConfigServiceClientE(t testing.TestingT, region string) (*configservice.ConfigService, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return configservice.New(sess), nil
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/configservice"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfigRuleEvaluated(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour)
+	after := since.Add(time.Minute)
+
+	assert.NoError(t, checkConfigRuleEvaluated(&configservice.ConfigRuleEvaluationStatus{LastSuccessfulEvaluationTime: aws.Time(after)}, since))
+
+	err := checkConfigRuleEvaluated(&configservice.ConfigRuleEvaluationStatus{LastSuccessfulEvaluationTime: aws.Time(before)}, since)
+	assert.IsType(t, ConfigRuleEvaluationFailedError{}, err)
+
+	err = checkConfigRuleEvaluated(&configservice.ConfigRuleEvaluationStatus{
+		LastSuccessfulEvaluationTime: aws.Time(before),
+		LastFailedEvaluationTime:     aws.Time(after),
+		LastErrorCode:                aws.String("InvalidResult"),
+		LastErrorMessage:             aws.String("lambda returned an error"),
+	}, since)
+	require.IsType(t, retry.FatalError{}, err)
+	assert.Contains(t, err.Error(), "lambda returned an error")
+}
+
+func TestFindConfigEvaluationCompliance(t *testing.T) {
+	t.Parallel()
+
+	newResult := func(resourceID string, compliance string) *configservice.EvaluationResult {
+		return &configservice.EvaluationResult{
+			ComplianceType: aws.String(compliance),
+			EvaluationResultIdentifier: &configservice.EvaluationResultIdentifier{
+				EvaluationResultQualifier: &configservice.EvaluationResultQualifier{ResourceId: aws.String(resourceID)},
+			},
+		}
+	}
+	results := []*configservice.EvaluationResult{
+		newResult("bucket-a", configservice.ComplianceTypeCompliant),
+		newResult("bucket-b", configservice.ComplianceTypeNonCompliant),
+		{ComplianceType: aws.String(configservice.ComplianceTypeCompliant)},
+	}
+
+	assert.Equal(t, configservice.ComplianceTypeCompliant, findConfigEvaluationCompliance(results, "bucket-a"))
+	assert.Equal(t, configservice.ComplianceTypeNonCompliant, findConfigEvaluationCompliance(results, "bucket-b"))
+	assert.Equal(t, "", findConfigEvaluationCompliance(results, "bucket-c"))
+}
@@ -1,8 +1,15 @@
 package aws
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +69,241 @@ func GetDynamoDBTableE(t testing.TestingT, region string, tableName string) (*dy
 	return out.Table, err
 }
 
+// WaitUntilDynamoDBTableActive waits until the specified dynamoDB table exists and it and all its global secondary
+// indexes are active, retrying the check for the specified amount of times, sleeping for the provided duration between
+// each try. This will fail the test if there are any errors.
+func WaitUntilDynamoDBTableActive(t testing.TestingT, region string, tableName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilDynamoDBTableActiveE(t, region, tableName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilDynamoDBTableActiveE waits until the specified dynamoDB table exists and it and all its global secondary
+// indexes are active, retrying the check for the specified amount of times, sleeping for the provided duration between
+// each try.
+func WaitUntilDynamoDBTableActiveE(t testing.TestingT, region string, tableName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	client, err := NewDynamoDBClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for dynamoDB table %s to be active.", tableName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			out, err := client.DescribeTable(&dynamodb.DescribeTableInput{
+				TableName: aws.String(tableName),
+			})
+			if err != nil {
+				return "", err
+			}
+			if err := checkDynamoDBTableActive(out.Table); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("DynamoDB table %s is now active", tableName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkDynamoDBTableActive returns an error if the given dynamoDB table or any of its global secondary indexes is not
+// active.
+func checkDynamoDBTableActive(table *dynamodb.TableDescription) error {
+	tableName := aws.StringValue(table.TableName)
+	if status := aws.StringValue(table.TableStatus); status != dynamodb.TableStatusActive {
+		return NewDynamoDBTableNotActiveError(tableName, "", status)
+	}
+	for _, index := range table.GlobalSecondaryIndexes {
+		if status := aws.StringValue(index.IndexStatus); status != dynamodb.IndexStatusActive {
+			return NewDynamoDBTableNotActiveError(tableName, aws.StringValue(index.IndexName), status)
+		}
+	}
+	return nil
+}
+
+// AssertDynamoDBTableExists checks if the specified dynamoDB table exists in the given region and fails the test if it
+// does not.
+func AssertDynamoDBTableExists(t testing.TestingT, region string, tableName string) {
+	err := AssertDynamoDBTableExistsE(t, region, tableName)
+	require.NoError(t, err)
+}
+
+// AssertDynamoDBTableExistsE checks if the specified dynamoDB table exists in the given region and returns an error if
+// it does not.
+func AssertDynamoDBTableExistsE(t testing.TestingT, region string, tableName string) error {
+	_, err := GetDynamoDBTableE(t, region, tableName)
+	if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+		return NewNotFoundError("DynamoDB table", tableName, region)
+	}
+	return err
+}
+
+// PutDynamoDBItem marshals the given item (e.g., a struct with dynamodbav tags, or a map) into dynamoDB attributes and
+// writes it to the specified dynamoDB table. This will fail the test if there are any errors.
+func PutDynamoDBItem(t testing.TestingT, region string, tableName string, item interface{}) {
+	err := PutDynamoDBItemE(t, region, tableName, item)
+	require.NoError(t, err)
+}
+
+// PutDynamoDBItemE marshals the given item (e.g., a struct with dynamodbav tags, or a map) into dynamoDB attributes and
+// writes it to the specified dynamoDB table.
+func PutDynamoDBItemE(t testing.TestingT, region string, tableName string, item interface{}) error {
+	attributes, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	client, err := NewDynamoDBClientE(t, region)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      attributes,
+	})
+	return err
+}
+
+// GetDynamoDBItem reads the item with the given key (e.g., a struct or map with just the key attributes) from the
+// specified dynamoDB table and unmarshals it into the given value. This will fail the test if there are any errors, or
+// if the item does not exist.
+func GetDynamoDBItem(t testing.TestingT, region string, tableName string, key interface{}, item interface{}) {
+	err := GetDynamoDBItemE(t, region, tableName, key, item)
+	require.NoError(t, err)
+}
+
+// GetDynamoDBItemE reads the item with the given key (e.g., a struct or map with just the key attributes) from the
+// specified dynamoDB table and unmarshals it into the given value. The item is read with strong consistency, so that
+// items that were just written are found. Returns a DynamoDBItemNotFoundError if the item does not exist.
+func GetDynamoDBItemE(t testing.TestingT, region string, tableName string, key interface{}, item interface{}) error {
+	keyAttributes, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return err
+	}
+	client, err := NewDynamoDBClientE(t, region)
+	if err != nil {
+		return err
+	}
+	out, err := client.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            keyAttributes,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	if len(out.Item) == 0 {
+		return NewDynamoDBItemNotFoundError(tableName, keyAttributes)
+	}
+	return dynamodbattribute.UnmarshalMap(out.Item, item)
+}
+
+// AssertDynamoDBTableHasGlobalSecondaryIndex checks that the specified dynamoDB table has a global secondary index with
+// the given name, and fails the test if it does not.
+func AssertDynamoDBTableHasGlobalSecondaryIndex(t testing.TestingT, region string, tableName string, indexName string) {
+	err := AssertDynamoDBTableHasGlobalSecondaryIndexE(t, region, tableName, indexName)
+	require.NoError(t, err)
+}
+
+// AssertDynamoDBTableHasGlobalSecondaryIndexE checks that the specified dynamoDB table has a global secondary index with
+// the given name, and returns an error if it does not.
+func AssertDynamoDBTableHasGlobalSecondaryIndexE(t testing.TestingT, region string, tableName string, indexName string) error {
+	table, err := GetDynamoDBTableE(t, region, tableName)
+	if err != nil {
+		return err
+	}
+	for _, index := range table.GlobalSecondaryIndexes {
+		if aws.StringValue(index.IndexName) == indexName {
+			return nil
+		}
+	}
+	return NewNotFoundError("DynamoDB global secondary index", fmt.Sprintf("%s/%s", tableName, indexName), region)
+}
+
+// AssertDynamoDBTableTimeToLiveEnabled checks that TTL is enabled on the specified dynamoDB table with the given
+// attribute, and fails the test if it is not.
+func AssertDynamoDBTableTimeToLiveEnabled(t testing.TestingT, region string, tableName string, attributeName string) {
+	err := AssertDynamoDBTableTimeToLiveEnabledE(t, region, tableName, attributeName)
+	require.NoError(t, err)
+}
+
+// AssertDynamoDBTableTimeToLiveEnabledE checks that TTL is enabled on the specified dynamoDB table with the given
+// attribute, and returns an error if it is not.
+func AssertDynamoDBTableTimeToLiveEnabledE(t testing.TestingT, region string, tableName string, attributeName string) error {
+	ttl, err := GetDynamoDBTableTimeToLiveE(t, region, tableName)
+	if err != nil {
+		return err
+	}
+	if status := aws.StringValue(ttl.TimeToLiveStatus); status != dynamodb.TimeToLiveStatusEnabled {
+		return NewDynamoDBTableConfigMismatchError(tableName, "TTL status", dynamodb.TimeToLiveStatusEnabled, status)
+	}
+	if actualAttributeName := aws.StringValue(ttl.AttributeName); actualAttributeName != attributeName {
+		return NewDynamoDBTableConfigMismatchError(tableName, "TTL attribute", attributeName, actualAttributeName)
+	}
+	return nil
+}
+
+// AssertDynamoDBTablePointInTimeRecoveryEnabled checks that point-in-time recovery is enabled on the specified dynamoDB
+// table, and fails the test if it is not.
+func AssertDynamoDBTablePointInTimeRecoveryEnabled(t testing.TestingT, region string, tableName string) {
+	err := AssertDynamoDBTablePointInTimeRecoveryEnabledE(t, region, tableName)
+	require.NoError(t, err)
+}
+
+// AssertDynamoDBTablePointInTimeRecoveryEnabledE checks that point-in-time recovery is enabled on the specified
+// dynamoDB table, and returns an error if it is not.
+func AssertDynamoDBTablePointInTimeRecoveryEnabledE(t testing.TestingT, region string, tableName string) error {
+	client, err := NewDynamoDBClientE(t, region)
+	if err != nil {
+		return err
+	}
+	out, err := client.DescribeContinuousBackups(&dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	status := dynamodb.PointInTimeRecoveryStatusDisabled
+	if out.ContinuousBackupsDescription != nil && out.ContinuousBackupsDescription.PointInTimeRecoveryDescription != nil {
+		status = aws.StringValue(out.ContinuousBackupsDescription.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus)
+	}
+	if status != dynamodb.PointInTimeRecoveryStatusEnabled {
+		return NewDynamoDBTableConfigMismatchError(tableName, "point-in-time recovery status", dynamodb.PointInTimeRecoveryStatusEnabled, status)
+	}
+	return nil
+}
+
+// AssertDynamoDBTableBillingMode checks that the specified dynamoDB table uses the given billing mode (e.g.,
+// dynamodb.BillingModePayPerRequest), and fails the test if it does not.
+func AssertDynamoDBTableBillingMode(t testing.TestingT, region string, tableName string, billingMode string) {
+	err := AssertDynamoDBTableBillingModeE(t, region, tableName, billingMode)
+	require.NoError(t, err)
+}
+
+// AssertDynamoDBTableBillingModeE checks that the specified dynamoDB table uses the given billing mode (e.g.,
+// dynamodb.BillingModePayPerRequest), and returns an error if it does not.
+func AssertDynamoDBTableBillingModeE(t testing.TestingT, region string, tableName string, billingMode string) error {
+	table, err := GetDynamoDBTableE(t, region, tableName)
+	if err != nil {
+		return err
+	}
+	if actualBillingMode := getDynamoDBTableBillingMode(table); actualBillingMode != billingMode {
+		return NewDynamoDBTableConfigMismatchError(tableName, "billing mode", billingMode, actualBillingMode)
+	}
+	return nil
+}
+
+// getDynamoDBTableBillingMode returns the billing mode of the given dynamoDB table. Tables that were always provisioned
+// don't report a billing mode.
+func getDynamoDBTableBillingMode(table *dynamodb.TableDescription) string {
+	if table.BillingModeSummary == nil || table.BillingModeSummary.BillingMode == nil {
+		return dynamodb.BillingModeProvisioned
+	}
+	return aws.StringValue(table.BillingModeSummary.BillingMode)
+}
+
 // NewDynamoDBClient creates a DynamoDB client.
 func NewDynamoDBClient(t testing.TestingT, region string) *dynamodb.DynamoDB {
 	client, err := NewDynamoDBClientE(t, region)
@@ -71,7 +313,7 @@ func NewDynamoDBClient(t testing.TestingT, region string) *dynamodb.DynamoDB {
 
 // NewDynamoDBClientE creates a DynamoDB client.
 func NewDynamoDBClientE(t testing.TestingT, region string) (*dynamodb.DynamoDB, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
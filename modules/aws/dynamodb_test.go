@@ -0,0 +1,41 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDynamoDBTableActive(t *testing.T) {
+	t.Parallel()
+
+	table := &dynamodb.TableDescription{
+		TableName:   aws.String("test-table"),
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{IndexName: aws.String("by-owner"), IndexStatus: aws.String(dynamodb.IndexStatusActive)},
+		},
+	}
+	assert.NoError(t, checkDynamoDBTableActive(table))
+
+	table.GlobalSecondaryIndexes[0].IndexStatus = aws.String(dynamodb.IndexStatusCreating)
+	err := checkDynamoDBTableActive(table)
+	assert.IsType(t, DynamoDBTableNotActiveError{}, err)
+	assert.Contains(t, err.Error(), "by-owner")
+
+	table.TableStatus = aws.String(dynamodb.TableStatusCreating)
+	assert.IsType(t, DynamoDBTableNotActiveError{}, checkDynamoDBTableActive(table))
+}
+
+func TestGetDynamoDBTableBillingMode(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, dynamodb.BillingModeProvisioned, getDynamoDBTableBillingMode(&dynamodb.TableDescription{}))
+
+	table := &dynamodb.TableDescription{
+		BillingModeSummary: &dynamodb.BillingModeSummary{BillingMode: aws.String(dynamodb.BillingModePayPerRequest)},
+	}
+	assert.Equal(t, dynamodb.BillingModePayPerRequest, getDynamoDBTableBillingMode(table))
+}
@@ -533,7 +533,7 @@ func NewEc2Client(t testing.TestingT, region string) *ec2.EC2 {
 
 // NewEc2ClientE creates an EC2 client.
 func NewEc2ClientE(t testing.TestingT, region string) (*ec2.EC2, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,326 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/fis"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// spotFulfilledStatusCode is the status code of spot instance requests whose instance is running.
+	spotFulfilledStatusCode = "fulfilled"
+
+	// fisSpotInterruptionActionID is the FIS action that sends interruption notices to spot instances.
+	fisSpotInterruptionActionID = "aws:ec2:send-spot-instance-interruptions"
+
+	// spotInterruptionNotice is how long before the interruption the notice is sent. This is the minimum FIS allows, and
+	// the notice period of real interruptions.
+	spotInterruptionNotice = "PT2M"
+
+	fisExperimentMaxRetries          = 60
+	fisExperimentSleepBetweenRetries = 10 * time.Second
+)
+
+// SpotInstanceOptions are the options to launch a spot instance with LaunchSpotInstance.
+type SpotInstanceOptions struct {
+	AmiID        string            // The ID of the AMI to launch
+	InstanceType string            // The instance type (e.g., t3.micro)
+	SubnetID     string            // The subnet to launch the instance in, or empty for the default VPC
+	MaxPrice     string            // The maximum hourly price, or empty for the on-demand price
+	Tags         map[string]string // The tags of the instance
+}
+
+// LaunchSpotInstance launches a one-time spot instance with the given options, and returns its ID.
+func LaunchSpotInstance(t testing.TestingT, region string, options SpotInstanceOptions) string {
+	instanceID, err := LaunchSpotInstanceE(t, region, options)
+	require.NoError(t, err)
+	return instanceID
+}
+
+// LaunchSpotInstanceE launches a one-time spot instance with the given options, and returns its ID. The instance is
+// terminated if it is interrupted. The spot request may not be fulfilled yet when this returns, so use
+// WaitUntilSpotRequestFulfilled to wait for the instance to run.
+func LaunchSpotInstanceE(t testing.TestingT, region string, options SpotInstanceOptions) (string, error) {
+	logger.Logf(t, "Launching %s spot instance from AMI %s in %s", options.InstanceType, options.AmiID, region)
+
+	client, err := NewEc2ClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	output, err := client.RunInstances(newSpotRunInstancesInput(options))
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.Instances[0].InstanceId), nil
+}
+
+func newSpotRunInstancesInput(options SpotInstanceOptions) *ec2.RunInstancesInput {
+	spotOptions := &ec2.SpotMarketOptions{
+		SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+		InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+	}
+	if options.MaxPrice != "" {
+		spotOptions.MaxPrice = aws.String(options.MaxPrice)
+	}
+
+	input := &ec2.RunInstancesInput{
+		ImageId:      aws.String(options.AmiID),
+		InstanceType: aws.String(options.InstanceType),
+		MinCount:     aws.Int64(1),
+		MaxCount:     aws.Int64(1),
+		InstanceMarketOptions: &ec2.InstanceMarketOptionsRequest{
+			MarketType:  aws.String(ec2.MarketTypeSpot),
+			SpotOptions: spotOptions,
+		},
+	}
+	if options.SubnetID != "" {
+		input.SubnetId = aws.String(options.SubnetID)
+	}
+	if len(options.Tags) > 0 {
+		tags := []*ec2.Tag{}
+		for key, value := range options.Tags {
+			tags = append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		input.TagSpecifications = []*ec2.TagSpecification{{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: tags}}
+	}
+	return input
+}
+
+// GetSpotEc2InstanceIdsByFilters returns the IDs of the spot EC2 instances in the given region which match the given
+// EC2 filters (see GetEc2InstanceIdsByFilters).
+func GetSpotEc2InstanceIdsByFilters(t testing.TestingT, region string, ec2Filters map[string][]string) []string {
+	instanceIDs, err := GetSpotEc2InstanceIdsByFiltersE(t, region, ec2Filters)
+	require.NoError(t, err)
+	return instanceIDs
+}
+
+// GetSpotEc2InstanceIdsByFiltersE returns the IDs of the spot EC2 instances in the given region which match the given
+// EC2 filters (see GetEc2InstanceIdsByFilters). This is useful to check how many instances of a mixed-purchase ASG
+// are spot instances.
+func GetSpotEc2InstanceIdsByFiltersE(t testing.TestingT, region string, ec2Filters map[string][]string) ([]string, error) {
+	spotFilters := map[string][]string{"instance-lifecycle": {ec2.InstanceLifecycleSpot}}
+	for name, values := range ec2Filters {
+		spotFilters[name] = values
+	}
+	return GetEc2InstanceIdsByFiltersE(t, region, spotFilters)
+}
+
+// AssertSpotRequestFulfilled checks that the given EC2 instance is a spot instance whose spot request is fulfilled, and
+// fails the test if it is not.
+func AssertSpotRequestFulfilled(t testing.TestingT, region string, instanceID string) {
+	err := AssertSpotRequestFulfilledE(t, region, instanceID)
+	require.NoError(t, err)
+}
+
+// AssertSpotRequestFulfilledE checks that the given EC2 instance is a spot instance whose spot request is fulfilled, and
+// returns an error if it is not.
+func AssertSpotRequestFulfilledE(t testing.TestingT, region string, instanceID string) error {
+	request, err := getSpotInstanceRequestE(t, region, instanceID)
+	if err != nil {
+		return err
+	}
+	return checkSpotRequestFulfilled(request)
+}
+
+// WaitUntilSpotRequestFulfilled waits until the spot request of the given EC2 instance is fulfilled, retrying the check
+// for the specified amount of times, sleeping for the provided duration between each try. This will fail the test if
+// the request is not fulfilled in time.
+func WaitUntilSpotRequestFulfilled(t testing.TestingT, region string, instanceID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilSpotRequestFulfilledE(t, region, instanceID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilSpotRequestFulfilledE waits until the spot request of the given EC2 instance is fulfilled, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try. This stops waiting
+// early if the request is closed, cancelled, or failed (e.g., because there is no spot capacity for the instance type).
+func WaitUntilSpotRequestFulfilledE(t testing.TestingT, region string, instanceID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for spot request of instance %s to be fulfilled.", instanceID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			request, err := getSpotInstanceRequestE(t, region, instanceID)
+			if err != nil {
+				return "", err
+			}
+			if err := checkSpotRequestFulfilled(request); err != nil {
+				if aws.StringValue(request.State) != ec2.SpotInstanceStateOpen && aws.StringValue(request.State) != ec2.SpotInstanceStateActive {
+					return "", retry.FatalError{Underlying: err}
+				}
+				return "", err
+			}
+			return fmt.Sprintf("Spot request of instance %s is now fulfilled", instanceID), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+func getSpotInstanceRequestE(t testing.TestingT, region string, instanceID string) (*ec2.SpotInstanceRequest, error) {
+	client, err := NewEc2ClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := client.DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice([]string{instanceID})})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances.Reservations) == 0 || len(instances.Reservations[0].Instances) == 0 {
+		return nil, NewNotFoundError("EC2 instance", instanceID, region)
+	}
+	instance := instances.Reservations[0].Instances[0]
+	if aws.StringValue(instance.InstanceLifecycle) != ec2.InstanceLifecycleSpot || instance.SpotInstanceRequestId == nil {
+		return nil, NewEc2InstanceNotSpotError(instanceID)
+	}
+
+	requests, err := client.DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []*string{instance.SpotInstanceRequestId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(requests.SpotInstanceRequests) == 0 {
+		return nil, NewNotFoundError("spot instance request", aws.StringValue(instance.SpotInstanceRequestId), region)
+	}
+	return requests.SpotInstanceRequests[0], nil
+}
+
+// checkSpotRequestFulfilled returns an error if the given spot instance request is not active and fulfilled.
+func checkSpotRequestFulfilled(request *ec2.SpotInstanceRequest) error {
+	state := aws.StringValue(request.State)
+	code, message := "", ""
+	if request.Status != nil {
+		code = aws.StringValue(request.Status.Code)
+		message = aws.StringValue(request.Status.Message)
+	}
+	if state != ec2.SpotInstanceStateActive || code != spotFulfilledStatusCode {
+		return NewSpotRequestNotFulfilledError(aws.StringValue(request.SpotInstanceRequestId), state, code, message)
+	}
+	return nil
+}
+
+// SimulateSpotInterruption sends a spot interruption notice to the given spot instance using AWS Fault Injection
+// Simulator (FIS), and waits until the instance is interrupted. This will fail the test if there is an error.
+func SimulateSpotInterruption(t testing.TestingT, region string, instanceID string, fisRoleArn string) {
+	err := SimulateSpotInterruptionE(t, region, instanceID, fisRoleArn)
+	require.NoError(t, err)
+}
+
+// SimulateSpotInterruptionE sends a spot interruption notice to the given spot instance using AWS Fault Injection
+// Simulator (FIS), and waits until the instance is interrupted, two minutes after the notice, like a real interruption.
+// This is useful to test that workloads drain gracefully and are replaced. The given IAM role is assumed by FIS, and must
+// allow ec2:SendSpotInstanceInterruptions on the instance. The experiment template used to run the experiment is
+// deleted before returning.
+func SimulateSpotInterruptionE(t testing.TestingT, region string, instanceID string, fisRoleArn string) error {
+	client, err := NewFisClientE(t, region)
+	if err != nil {
+		return err
+	}
+	accountID, err := GetAccountIdE(t)
+	if err != nil {
+		return err
+	}
+
+	instanceArn := NewArnForRegion("ec2", region, accountID, "instance/"+instanceID)
+	template, err := client.CreateExperimentTemplate(newSpotInterruptionExperimentTemplateInput(instanceArn, fisRoleArn))
+	if err != nil {
+		return err
+	}
+	templateID := template.ExperimentTemplate.Id
+	defer func() {
+		if _, err := client.DeleteExperimentTemplate(&fis.DeleteExperimentTemplateInput{Id: templateID}); err != nil {
+			logger.Logf(t, "Failed to delete FIS experiment template %s: %v", aws.StringValue(templateID), err)
+		}
+	}()
+
+	logger.Logf(t, "Sending spot interruption notice to instance %s", instanceID)
+	experiment, err := client.StartExperiment(&fis.StartExperimentInput{ExperimentTemplateId: templateID})
+	if err != nil {
+		return err
+	}
+	experimentID := aws.StringValue(experiment.Experiment.Id)
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for FIS experiment %s to interrupt instance %s.", experimentID, instanceID),
+		fisExperimentMaxRetries,
+		fisExperimentSleepBetweenRetries,
+		func() (string, error) {
+			output, err := client.GetExperiment(&fis.GetExperimentInput{Id: aws.String(experimentID)})
+			if err != nil {
+				return "", err
+			}
+			if err := checkFisExperimentCompleted(experimentID, output.Experiment.State); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("FIS experiment %s interrupted instance %s", experimentID, instanceID), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+func newSpotInterruptionExperimentTemplateInput(instanceArn string, fisRoleArn string) *fis.CreateExperimentTemplateInput {
+	return &fis.CreateExperimentTemplateInput{
+		Description: aws.String("Terratest spot interruption of " + instanceArn),
+		RoleArn:     aws.String(fisRoleArn),
+		Actions: map[string]*fis.CreateExperimentTemplateActionInput{
+			"interrupt": {
+				ActionId:   aws.String(fisSpotInterruptionActionID),
+				Parameters: map[string]*string{"durationBeforeInterruption": aws.String(spotInterruptionNotice)},
+				Targets:    map[string]*string{"SpotInstances": aws.String("instance")},
+			},
+		},
+		Targets: map[string]*fis.CreateExperimentTemplateTargetInput{
+			"instance": {
+				ResourceType:  aws.String("aws:ec2:spot-instance"),
+				ResourceArns:  aws.StringSlice([]string{instanceArn}),
+				SelectionMode: aws.String("ALL"),
+			},
+		},
+		StopConditions: []*fis.CreateExperimentTemplateStopConditionInput{{Source: aws.String("none")}},
+	}
+}
+
+// checkFisExperimentCompleted returns an error if the given state of an FIS experiment is not completed. The error is
+// wrapped in a retry.FatalError if the experiment stopped or failed.
+func checkFisExperimentCompleted(experimentID string, state *fis.ExperimentState) error {
+	status, reason := "", ""
+	if state != nil {
+		status = aws.StringValue(state.Status)
+		reason = aws.StringValue(state.Reason)
+	}
+	err := NewFisExperimentNotCompletedError(experimentID, status, reason)
+	switch status {
+	case fis.ExperimentStatusCompleted:
+		return nil
+	case fis.ExperimentStatusStopped, fis.ExperimentStatusFailed:
+		return retry.FatalError{Underlying: err}
+	default:
+		return err
+	}
+}
+
+// NewFisClient creates a Fault Injection Simulator client.
+func NewFisClient(t testing.TestingT, region string) *fis.FIS {
+	client, err := NewFisClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewFisClientE creates a Fault Injection Simulator client.
+func NewFisClientE(t testing.TestingT, region string) (*fis.FIS, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return fis.New(sess), nil
+}
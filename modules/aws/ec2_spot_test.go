@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/fis"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSpotRunInstancesInput(t *testing.T) {
+	t.Parallel()
+
+	input := newSpotRunInstancesInput(SpotInstanceOptions{
+		AmiID:        "ami-0123456789abcdef0",
+		InstanceType: "t3.micro",
+		SubnetID:     "subnet-0123456789abcdef0",
+		MaxPrice:     "0.01",
+		Tags:         map[string]string{"Name": "terratest"},
+	})
+	assert.Equal(t, "ami-0123456789abcdef0", aws.StringValue(input.ImageId))
+	assert.Equal(t, "subnet-0123456789abcdef0", aws.StringValue(input.SubnetId))
+	assert.Equal(t, ec2.MarketTypeSpot, aws.StringValue(input.InstanceMarketOptions.MarketType))
+	assert.Equal(t, ec2.SpotInstanceTypeOneTime, aws.StringValue(input.InstanceMarketOptions.SpotOptions.SpotInstanceType))
+	assert.Equal(t, "0.01", aws.StringValue(input.InstanceMarketOptions.SpotOptions.MaxPrice))
+	require.Len(t, input.TagSpecifications, 1)
+	assert.Equal(t, "terratest", aws.StringValue(input.TagSpecifications[0].Tags[0].Value))
+
+	input = newSpotRunInstancesInput(SpotInstanceOptions{AmiID: "ami-0123456789abcdef0", InstanceType: "t3.micro"})
+	assert.Nil(t, input.SubnetId)
+	assert.Nil(t, input.InstanceMarketOptions.SpotOptions.MaxPrice)
+	assert.Nil(t, input.TagSpecifications)
+}
+
+func TestCheckSpotRequestFulfilled(t *testing.T) {
+	t.Parallel()
+
+	request := func(state string, code string) *ec2.SpotInstanceRequest {
+		return &ec2.SpotInstanceRequest{
+			SpotInstanceRequestId: aws.String("sir-12345678"),
+			State:                 aws.String(state),
+			Status:                &ec2.SpotInstanceStatus{Code: aws.String(code), Message: aws.String("message")},
+		}
+	}
+
+	assert.NoError(t, checkSpotRequestFulfilled(request(ec2.SpotInstanceStateActive, "fulfilled")))
+	assert.IsType(t, SpotRequestNotFulfilledError{}, checkSpotRequestFulfilled(request(ec2.SpotInstanceStateOpen, "pending-fulfillment")))
+	assert.IsType(t, SpotRequestNotFulfilledError{}, checkSpotRequestFulfilled(request(ec2.SpotInstanceStateActive, "marked-for-termination")))
+	assert.IsType(t, SpotRequestNotFulfilledError{}, checkSpotRequestFulfilled(request(ec2.SpotInstanceStateClosed, "instance-terminated-by-service")))
+	assert.IsType(t, SpotRequestNotFulfilledError{}, checkSpotRequestFulfilled(&ec2.SpotInstanceRequest{State: aws.String(ec2.SpotInstanceStateActive)}))
+}
+
+func TestNewSpotInterruptionExperimentTemplateInput(t *testing.T) {
+	t.Parallel()
+
+	instanceArn := "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0"
+	input := newSpotInterruptionExperimentTemplateInput(instanceArn, "arn:aws:iam::123456789012:role/fis")
+	require.NoError(t, input.Validate())
+
+	action := input.Actions["interrupt"]
+	assert.Equal(t, "aws:ec2:send-spot-instance-interruptions", aws.StringValue(action.ActionId))
+	target := input.Targets[aws.StringValue(action.Targets["SpotInstances"])]
+	require.NotNil(t, target)
+	assert.Equal(t, []string{instanceArn}, aws.StringValueSlice(target.ResourceArns))
+}
+
+func TestCheckFisExperimentCompleted(t *testing.T) {
+	t.Parallel()
+
+	state := func(status string) *fis.ExperimentState {
+		return &fis.ExperimentState{Status: aws.String(status), Reason: aws.String("reason")}
+	}
+
+	assert.NoError(t, checkFisExperimentCompleted("EXP123", state(fis.ExperimentStatusCompleted)))
+	assert.IsType(t, FisExperimentNotCompletedError{}, checkFisExperimentCompleted("EXP123", state(fis.ExperimentStatusRunning)))
+	assert.IsType(t, FisExperimentNotCompletedError{}, checkFisExperimentCompleted("EXP123", nil))
+	assert.IsType(t, retry.FatalError{}, checkFisExperimentCompleted("EXP123", state(fis.ExperimentStatusFailed)))
+	assert.IsType(t, retry.FatalError{}, checkFisExperimentCompleted("EXP123", state(fis.ExperimentStatusStopped)))
+}
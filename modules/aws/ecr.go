@@ -1,12 +1,23 @@
 package aws
 
 import (
+	"encoding/json"
 	goerrors "errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/gruntwork-io/go-commons/errors"
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -92,7 +103,7 @@ func NewECRClient(t testing.TestingT, region string) *ecr.ECR {
 
 // NewECRClient returns a client for the Elastic Container Registry.
 func NewECRClientE(t testing.TestingT, region string) (*ecr.ECR, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -141,3 +152,258 @@ func PutECRRepoLifecyclePolicyE(t testing.TestingT, region string, repo *ecr.Rep
 	_, err = client.PutLifecyclePolicy(input)
 	return err
 }
+
+// ECRLifecyclePolicyRule is a rule of the lifecycle policy of an ECR repository.
+type ECRLifecyclePolicyRule struct {
+	RulePriority int                         `json:"rulePriority"`
+	Description  string                      `json:"description,omitempty"`
+	Selection    ECRLifecyclePolicySelection `json:"selection"`
+	Action       ECRLifecyclePolicyAction    `json:"action"`
+}
+
+// ECRLifecyclePolicySelection selects the images a rule of the lifecycle policy of an ECR repository applies to.
+type ECRLifecyclePolicySelection struct {
+	TagStatus     string   `json:"tagStatus"`               // tagged, untagged or any
+	TagPrefixList []string `json:"tagPrefixList,omitempty"` // Only for tagged images
+	CountType     string   `json:"countType"`               // imageCountMoreThan or sinceImagePushed
+	CountUnit     string   `json:"countUnit,omitempty"`     // Only for sinceImagePushed (e.g., days)
+	CountNumber   int      `json:"countNumber"`
+}
+
+// ECRLifecyclePolicyAction is the action a rule of the lifecycle policy of an ECR repository takes on the images it
+// selects.
+type ECRLifecyclePolicyAction struct {
+	Type string `json:"type"`
+}
+
+// GetECRRepoLifecyclePolicyRules gets the rules of the lifecycle policy of the given ECR repository, ordered by
+// priority. This will fail the test and stop execution if there is an error.
+func GetECRRepoLifecyclePolicyRules(t testing.TestingT, region string, repo *ecr.Repository) []ECRLifecyclePolicyRule {
+	rules, err := GetECRRepoLifecyclePolicyRulesE(t, region, repo)
+	require.NoError(t, err)
+	return rules
+}
+
+// GetECRRepoLifecyclePolicyRulesE gets the rules of the lifecycle policy of the given ECR repository, ordered by
+// priority.
+func GetECRRepoLifecyclePolicyRulesE(t testing.TestingT, region string, repo *ecr.Repository) ([]ECRLifecyclePolicyRule, error) {
+	policy, err := GetECRRepoLifecyclePolicyE(t, region, repo)
+	if err != nil {
+		return nil, err
+	}
+	return parseECRLifecyclePolicyRules(policy)
+}
+
+func parseECRLifecyclePolicyRules(policy string) ([]ECRLifecyclePolicyRule, error) {
+	var parsed struct {
+		Rules []ECRLifecyclePolicyRule `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(policy), &parsed); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	sort.SliceStable(parsed.Rules, func(i, j int) bool {
+		return parsed.Rules[i].RulePriority < parsed.Rules[j].RulePriority
+	})
+	return parsed.Rules, nil
+}
+
+// AssertECRRepoLifecyclePolicy checks that the lifecycle policy of the given ECR repository is the given JSON policy.
+// This will fail the test and stop execution if it is not.
+func AssertECRRepoLifecyclePolicy(t testing.TestingT, region string, repo *ecr.Repository, expectedPolicy string) {
+	err := AssertECRRepoLifecyclePolicyE(t, region, repo, expectedPolicy)
+	require.NoError(t, err)
+}
+
+// AssertECRRepoLifecyclePolicyE checks that the lifecycle policy of the given ECR repository is the given JSON policy,
+// and returns an error if it is not. The policies are compared as JSON, so formatting and the order of keys don't
+// matter.
+func AssertECRRepoLifecyclePolicyE(t testing.TestingT, region string, repo *ecr.Repository, expectedPolicy string) error {
+	policy, err := GetECRRepoLifecyclePolicyE(t, region, repo)
+	if err != nil {
+		return err
+	}
+	equal, err := jsonDocumentsEqual(policy, expectedPolicy)
+	if err != nil {
+		return err
+	}
+	if !equal {
+		return NewECRLifecyclePolicyMismatchError(aws.StringValue(repo.RepositoryName), expectedPolicy, policy)
+	}
+	return nil
+}
+
+// jsonDocumentsEqual returns true if the given JSON documents have the same content.
+func jsonDocumentsEqual(actual string, expected string) (bool, error) {
+	var actualValue, expectedValue interface{}
+	if err := json.Unmarshal([]byte(actual), &actualValue); err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+	return reflect.DeepEqual(actualValue, expectedValue), nil
+}
+
+// PushImageToEcr tags the given local Docker image with the given tag in the given ECR repository and pushes it, and
+// returns the URI of the pushed image. This will fail the test and stop execution if there is an error.
+func PushImageToEcr(t testing.TestingT, region string, repo *ecr.Repository, localImage string, tag string) string {
+	imageURI, err := PushImageToEcrE(t, region, repo, localImage, tag)
+	require.NoError(t, err)
+	return imageURI
+}
+
+// PushImageToEcrE tags the given local Docker image with the given tag in the given ECR repository and pushes it, and
+// returns the URI of the pushed image. This requires the docker CLI. The registry credentials are fetched from ECR and
+// written to a temporary Docker config directory that is only used for the push, so no docker login is needed and the
+// Docker config of the user is left untouched.
+func PushImageToEcrE(t testing.TestingT, region string, repo *ecr.Repository, localImage string, tag string) (string, error) {
+	client, err := NewECRClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.AuthorizationData) == 0 {
+		return "", errors.WithStackTrace(goerrors.New("ECR returned no authorization data"))
+	}
+	authData := resp.AuthorizationData[0]
+
+	configDir, err := ioutil.TempDir("", "terratest-ecr-")
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	dockerConfig, err := newEcrDockerConfig(aws.StringValue(authData.ProxyEndpoint), aws.StringValue(authData.AuthorizationToken))
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(configDir, "config.json"), dockerConfig, 0600); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	imageURI := fmt.Sprintf("%s:%s", aws.StringValue(repo.RepositoryUri), tag)
+	logger.Logf(t, "Pushing image %s to %s", localImage, imageURI)
+	if err := shell.RunCommandE(t, shell.Command{Command: "docker", Args: []string{"tag", localImage, imageURI}}); err != nil {
+		return "", err
+	}
+	if err := shell.RunCommandE(t, shell.Command{Command: "docker", Args: []string{"--config", configDir, "push", imageURI}}); err != nil {
+		return "", err
+	}
+	return imageURI, nil
+}
+
+// newEcrDockerConfig returns a Docker config file with the given ECR authorization token for the registry at the given
+// endpoint. The token is already in the base64 user:password form Docker expects.
+func newEcrDockerConfig(proxyEndpoint string, authorizationToken string) ([]byte, error) {
+	registry := strings.TrimPrefix(strings.TrimPrefix(proxyEndpoint, "https://"), "http://")
+	config := map[string]interface{}{
+		"auths": map[string]interface{}{
+			registry: map[string]string{"auth": authorizationToken},
+		},
+	}
+	dockerConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return dockerConfig, nil
+}
+
+// ECRVulnerabilityCounts is the number of vulnerabilities of each severity that an image scan found.
+type ECRVulnerabilityCounts struct {
+	Critical      int64
+	High          int64
+	Medium        int64
+	Low           int64
+	Informational int64
+	Undefined     int64
+}
+
+// Total returns the total number of vulnerabilities found.
+func (counts ECRVulnerabilityCounts) Total() int64 {
+	return counts.Critical + counts.High + counts.Medium + counts.Low + counts.Informational + counts.Undefined
+}
+
+// WaitForScanFindings waits until the scan of the image with the given tag in the given ECR repository completes,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try, and
+// returns the number of vulnerabilities found. This will fail the test and stop execution if there is an error.
+func WaitForScanFindings(t testing.TestingT, region string, repo *ecr.Repository, tag string, maxRetries int, sleepBetweenRetries time.Duration) ECRVulnerabilityCounts {
+	counts, err := WaitForScanFindingsE(t, region, repo, tag, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return counts
+}
+
+// WaitForScanFindingsE waits until the scan of the image with the given tag in the given ECR repository completes,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try, and
+// returns the number of vulnerabilities found. This stops waiting early if the scan fails. This is useful to check that
+// an image built by a pipeline has no critical vulnerabilities:
+//
+//	counts := aws.WaitForScanFindings(t, region, repo, "latest", 30, 10*time.Second)
+//	assert.Zero(t, counts.Critical)
+func WaitForScanFindingsE(t testing.TestingT, region string, repo *ecr.Repository, tag string, maxRetries int, sleepBetweenRetries time.Duration) (ECRVulnerabilityCounts, error) {
+	client, err := NewECRClientE(t, region)
+	if err != nil {
+		return ECRVulnerabilityCounts{}, err
+	}
+
+	var counts ECRVulnerabilityCounts
+	repoName := aws.StringValue(repo.RepositoryName)
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for scan of image %s:%s to complete.", repoName, tag),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			resp, err := client.DescribeImageScanFindings(&ecr.DescribeImageScanFindingsInput{
+				RepositoryName: repo.RepositoryName,
+				RegistryId:     repo.RegistryId,
+				ImageId:        &ecr.ImageIdentifier{ImageTag: aws.String(tag)},
+			})
+			if err != nil {
+				return "", err
+			}
+			if err := checkImageScanStatus(repoName, tag, resp.ImageScanStatus); err != nil {
+				return "", err
+			}
+			if resp.ImageScanFindings != nil {
+				counts = newECRVulnerabilityCounts(resp.ImageScanFindings.FindingSeverityCounts)
+			}
+			return fmt.Sprintf("Scan of image %s:%s found %d vulnerabilities", repoName, tag, counts.Total()), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return ECRVulnerabilityCounts{}, err
+	}
+	return counts, nil
+}
+
+// checkImageScanStatus returns an error if the given image scan status is not complete. The error is wrapped in a
+// retry.FatalError if the scan can't complete anymore.
+func checkImageScanStatus(repoName string, tag string, scanStatus *ecr.ImageScanStatus) error {
+	status := ""
+	if scanStatus != nil {
+		status = aws.StringValue(scanStatus.Status)
+	}
+	switch status {
+	case ecr.ScanStatusComplete, ecr.ScanStatusActive:
+		return nil
+	case "", ecr.ScanStatusInProgress, ecr.ScanStatusPending:
+		return NewECRImageScanNotCompleteError(repoName, tag, status, "")
+	default:
+		return retry.FatalError{Underlying: NewECRImageScanNotCompleteError(repoName, tag, status, aws.StringValue(scanStatus.Description))}
+	}
+}
+
+func newECRVulnerabilityCounts(severityCounts map[string]*int64) ECRVulnerabilityCounts {
+	return ECRVulnerabilityCounts{
+		Critical:      aws.Int64Value(severityCounts[ecr.FindingSeverityCritical]),
+		High:          aws.Int64Value(severityCounts[ecr.FindingSeverityHigh]),
+		Medium:        aws.Int64Value(severityCounts[ecr.FindingSeverityMedium]),
+		Low:           aws.Int64Value(severityCounts[ecr.FindingSeverityLow]),
+		Informational: aws.Int64Value(severityCounts[ecr.FindingSeverityInformational]),
+		Undefined:     aws.Int64Value(severityCounts[ecr.FindingSeverityUndefined]),
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -75,3 +77,90 @@ func TestCanSetECRRepoLifecyclePolicyWithSingleRule(t *testing.T) {
 	policy := GetECRRepoLifecyclePolicy(t, region, repo1)
 	assert.JSONEq(t, lifecyclePolicy, policy)
 }
+
+func TestParseECRLifecyclePolicyRules(t *testing.T) {
+	t.Parallel()
+
+	policy := `{
+		"rules": [
+			{
+				"rulePriority": 2,
+				"selection": {"tagStatus": "tagged", "tagPrefixList": ["release"], "countType": "imageCountMoreThan", "countNumber": 10},
+				"action": {"type": "expire"}
+			},
+			{
+				"rulePriority": 1,
+				"description": "Expire images older than 14 days",
+				"selection": {"tagStatus": "untagged", "countType": "sinceImagePushed", "countUnit": "days", "countNumber": 14},
+				"action": {"type": "expire"}
+			}
+		]
+	}`
+	rules, err := parseECRLifecyclePolicyRules(policy)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, ECRLifecyclePolicyRule{
+		RulePriority: 1,
+		Description:  "Expire images older than 14 days",
+		Selection:    ECRLifecyclePolicySelection{TagStatus: "untagged", CountType: "sinceImagePushed", CountUnit: "days", CountNumber: 14},
+		Action:       ECRLifecyclePolicyAction{Type: "expire"},
+	}, rules[0])
+	assert.Equal(t, 2, rules[1].RulePriority)
+	assert.Equal(t, []string{"release"}, rules[1].Selection.TagPrefixList)
+
+	_, err = parseECRLifecyclePolicyRules("not json")
+	require.Error(t, err)
+}
+
+func TestJsonDocumentsEqual(t *testing.T) {
+	t.Parallel()
+
+	equal, err := jsonDocumentsEqual(`{"rules":[{"rulePriority":1,"action":{"type":"expire"}}]}`, `{
+		"rules": [{"action": {"type": "expire"}, "rulePriority": 1}]
+	}`)
+	require.NoError(t, err)
+	assert.True(t, equal)
+
+	equal, err = jsonDocumentsEqual(`{"rules":[{"rulePriority":1}]}`, `{"rules":[{"rulePriority":2}]}`)
+	require.NoError(t, err)
+	assert.False(t, equal)
+
+	_, err = jsonDocumentsEqual(`{}`, `{`)
+	require.Error(t, err)
+}
+
+func TestNewEcrDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := newEcrDockerConfig("https://123456789012.dkr.ecr.us-east-1.amazonaws.com", "QVdTOnBhc3N3b3Jk")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"auths":{"123456789012.dkr.ecr.us-east-1.amazonaws.com":{"auth":"QVdTOnBhc3N3b3Jk"}}}`, string(config))
+}
+
+func TestCheckImageScanStatus(t *testing.T) {
+	t.Parallel()
+
+	scanStatus := func(status string) *ecr.ImageScanStatus {
+		return &ecr.ImageScanStatus{Status: aws.String(status), Description: aws.String("description")}
+	}
+
+	assert.NoError(t, checkImageScanStatus("repo", "latest", scanStatus(ecr.ScanStatusComplete)))
+	assert.NoError(t, checkImageScanStatus("repo", "latest", scanStatus(ecr.ScanStatusActive)))
+	assert.IsType(t, ECRImageScanNotCompleteError{}, checkImageScanStatus("repo", "latest", scanStatus(ecr.ScanStatusInProgress)))
+	assert.IsType(t, ECRImageScanNotCompleteError{}, checkImageScanStatus("repo", "latest", nil))
+	assert.IsType(t, retry.FatalError{}, checkImageScanStatus("repo", "latest", scanStatus(ecr.ScanStatusFailed)))
+	assert.IsType(t, retry.FatalError{}, checkImageScanStatus("repo", "latest", scanStatus(ecr.ScanStatusUnsupportedImage)))
+}
+
+func TestNewECRVulnerabilityCounts(t *testing.T) {
+	t.Parallel()
+
+	counts := newECRVulnerabilityCounts(map[string]*int64{
+		ecr.FindingSeverityCritical: aws.Int64(1),
+		ecr.FindingSeverityHigh:     aws.Int64(2),
+		ecr.FindingSeverityLow:      aws.Int64(4),
+	})
+	assert.Equal(t, ECRVulnerabilityCounts{Critical: 1, High: 2, Low: 4}, counts)
+	assert.Equal(t, int64(7), counts.Total())
+	assert.Equal(t, ECRVulnerabilityCounts{}, newECRVulnerabilityCounts(nil))
+}
@@ -2,9 +2,13 @@ package aws
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -145,6 +149,292 @@ func GetEcsTaskDefinitionE(t testing.TestingT, region string, taskDefinition str
 	return output.TaskDefinition, nil
 }
 
+// WaitUntilEcsServiceStable waits until the given ECS service is stable, which means its latest deployment is the only
+// one left and is running the desired number of tasks, retrying the check for the specified amount of times, sleeping
+// for the provided duration between each try.
+func WaitUntilEcsServiceStable(
+	t testing.TestingT,
+	region string,
+	clusterName string,
+	serviceName string,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) {
+	err := WaitUntilEcsServiceStableE(t, region, clusterName, serviceName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilEcsServiceStableE waits until the given ECS service is stable, which means its latest deployment is the only
+// one left and is running the desired number of tasks, retrying the check for the specified amount of times, sleeping
+// for the provided duration between each try. This stops waiting early if the deployment failed.
+func WaitUntilEcsServiceStableE(
+	t testing.TestingT,
+	region string,
+	clusterName string,
+	serviceName string,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for ECS service %s in cluster %s to be stable.", serviceName, clusterName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			service, err := GetEcsServiceE(t, region, clusterName, serviceName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkEcsServiceStable(clusterName, service); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("ECS service %s in cluster %s is now stable", serviceName, clusterName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkEcsServiceStable returns an error if the given ECS service is not stable. If the latest deployment of the service
+// failed, the error is wrapped in a retry.FatalError, as the service will never become stable.
+func checkEcsServiceStable(clusterName string, service *ecs.Service) error {
+	serviceName := aws.StringValue(service.ServiceName)
+	if len(service.Deployments) != 1 {
+		return NewEcsServiceNotStableError(clusterName, serviceName, fmt.Sprintf("%d deployments are in progress", len(service.Deployments)))
+	}
+
+	deployment := service.Deployments[0]
+	switch aws.StringValue(deployment.RolloutState) {
+	case ecs.DeploymentRolloutStateFailed:
+		err := NewEcsServiceNotStableError(clusterName, serviceName, fmt.Sprintf("deployment failed: %s", aws.StringValue(deployment.RolloutStateReason)))
+		return retry.FatalError{Underlying: err}
+	case ecs.DeploymentRolloutStateInProgress:
+		return NewEcsServiceNotStableError(clusterName, serviceName, "deployment is in progress")
+	}
+
+	if aws.Int64Value(service.RunningCount) != aws.Int64Value(service.DesiredCount) {
+		reason := fmt.Sprintf("%d of %d desired tasks are running", aws.Int64Value(service.RunningCount), aws.Int64Value(service.DesiredCount))
+		return NewEcsServiceNotStableError(clusterName, serviceName, reason)
+	}
+	return nil
+}
+
+// EcsTaskResult is the result of running an ECS task until it stops.
+type EcsTaskResult struct {
+	Task      *ecs.Task           // The stopped task
+	ExitCodes map[string]int64    // The exit code of each container that ran, by container name
+	Logs      map[string][]string // The CloudWatch log messages of each container that logs with the awslogs driver, by container name
+}
+
+// ExitCode returns the exit code of the given container of the task, and whether the container ran.
+func (result EcsTaskResult) ExitCode(containerName string) (int64, bool) {
+	exitCode, ran := result.ExitCodes[containerName]
+	return exitCode, ran
+}
+
+// RunEcsTaskAndWait runs an ECS task with the given input in the given region, waits for it to stop, and returns its
+// exit codes and logs. This will fail the test if the task can't be started or doesn't stop in time, but not if the task
+// itself fails, so that you can make assertions about the result.
+func RunEcsTaskAndWait(
+	t testing.TestingT,
+	region string,
+	input *ecs.RunTaskInput,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) *EcsTaskResult {
+	result, err := RunEcsTaskAndWaitE(t, region, input, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return result
+}
+
+// RunEcsTaskAndWaitE runs an ECS task with the given input in the given region, waits for it to stop, retrying the check
+// for the specified amount of times, sleeping for the provided duration between each try, and returns its exit codes and
+// the CloudWatch log messages of the containers that log with the awslogs driver.
+func RunEcsTaskAndWaitE(
+	t testing.TestingT,
+	region string,
+	input *ecs.RunTaskInput,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) (*EcsTaskResult, error) {
+	client, err := NewEcsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.RunTask(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Failures) > 0 {
+		failure := output.Failures[0]
+		return nil, fmt.Errorf("Failed to run ECS task %s: %s %s", aws.StringValue(input.TaskDefinition), aws.StringValue(failure.Reason), aws.StringValue(failure.Detail))
+	}
+	if len(output.Tasks) != 1 {
+		return nil, fmt.Errorf("Expected RunTask to start 1 ECS task, but it started %d", len(output.Tasks))
+	}
+	taskArn := aws.StringValue(output.Tasks[0].TaskArn)
+	logger.Logf(t, "Started ECS task %s", taskArn)
+
+	var task *ecs.Task
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for ECS task %s to stop.", taskArn),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			describeOutput, err := client.DescribeTasks(&ecs.DescribeTasksInput{
+				Cluster: input.Cluster,
+				Tasks:   []*string{aws.String(taskArn)},
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(describeOutput.Tasks) != 1 {
+				return "", fmt.Errorf("Expected to find 1 ECS task %s, but found %d", taskArn, len(describeOutput.Tasks))
+			}
+			task = describeOutput.Tasks[0]
+			if status := aws.StringValue(task.LastStatus); status != ecs.DesiredStatusStopped {
+				return "", NewEcsTaskNotStoppedError(taskArn, status)
+			}
+			return fmt.Sprintf("ECS task %s stopped: %s", taskArn, aws.StringValue(task.StoppedReason)), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	taskDefinition, err := GetEcsTaskDefinitionE(t, region, aws.StringValue(task.TaskDefinitionArn))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EcsTaskResult{Task: task, ExitCodes: map[string]int64{}, Logs: map[string][]string{}}
+	for _, container := range task.Containers {
+		if container.ExitCode != nil {
+			result.ExitCodes[aws.StringValue(container.Name)] = aws.Int64Value(container.ExitCode)
+		}
+	}
+	for _, containerDefinition := range taskDefinition.ContainerDefinitions {
+		logGroupName, logStreamName, logRegion, logsToCloudWatch := getEcsContainerLogStream(region, taskArn, containerDefinition)
+		if !logsToCloudWatch {
+			continue
+		}
+		entries, err := GetCloudWatchLogEntriesE(t, logRegion, logStreamName, logGroupName)
+		if err != nil {
+			return nil, err
+		}
+		result.Logs[aws.StringValue(containerDefinition.Name)] = entries
+	}
+	return result, nil
+}
+
+// getEcsContainerLogStream returns the CloudWatch log group, log stream, and region the given container of the task
+// logs to, if it uses the awslogs log driver with a stream prefix (which is required for tasks on Fargate).
+func getEcsContainerLogStream(region string, taskArn string, containerDefinition *ecs.ContainerDefinition) (string, string, string, bool) {
+	logConfiguration := containerDefinition.LogConfiguration
+	if logConfiguration == nil || aws.StringValue(logConfiguration.LogDriver) != ecs.LogDriverAwslogs {
+		return "", "", "", false
+	}
+	logGroupName := aws.StringValue(logConfiguration.Options["awslogs-group"])
+	streamPrefix := aws.StringValue(logConfiguration.Options["awslogs-stream-prefix"])
+	if logGroupName == "" || streamPrefix == "" {
+		return "", "", "", false
+	}
+	if logRegion := aws.StringValue(logConfiguration.Options["awslogs-region"]); logRegion != "" {
+		region = logRegion
+	}
+
+	taskID := taskArn[strings.LastIndex(taskArn, "/")+1:]
+	logStreamName := fmt.Sprintf("%s/%s/%s", streamPrefix, aws.StringValue(containerDefinition.Name), taskID)
+	return logGroupName, logStreamName, region, true
+}
+
+// GetEcsTaskDefinitionContainer fetches the definition of the given container of the specified ECS task definition.
+func GetEcsTaskDefinitionContainer(t testing.TestingT, region string, taskDefinition string, containerName string) *ecs.ContainerDefinition {
+	containerDefinition, err := GetEcsTaskDefinitionContainerE(t, region, taskDefinition, containerName)
+	require.NoError(t, err)
+	return containerDefinition
+}
+
+// GetEcsTaskDefinitionContainerE fetches the definition of the given container of the specified ECS task definition.
+func GetEcsTaskDefinitionContainerE(t testing.TestingT, region string, taskDefinition string, containerName string) (*ecs.ContainerDefinition, error) {
+	definition, err := GetEcsTaskDefinitionE(t, region, taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	return findEcsContainerDefinition(definition, containerName)
+}
+
+// findEcsContainerDefinition returns the definition of the given container in the task definition.
+func findEcsContainerDefinition(taskDefinition *ecs.TaskDefinition, containerName string) (*ecs.ContainerDefinition, error) {
+	for _, containerDefinition := range taskDefinition.ContainerDefinitions {
+		if aws.StringValue(containerDefinition.Name) == containerName {
+			return containerDefinition, nil
+		}
+	}
+	return nil, NewEcsContainerDefinitionNotFoundError(aws.StringValue(taskDefinition.TaskDefinitionArn), containerName)
+}
+
+// AssertEcsTaskDefinitionContainerImage checks that the given container of the specified ECS task definition uses the
+// expected image, and fails the test if it does not.
+func AssertEcsTaskDefinitionContainerImage(t testing.TestingT, region string, taskDefinition string, containerName string, expectedImage string) {
+	err := AssertEcsTaskDefinitionContainerImageE(t, region, taskDefinition, containerName, expectedImage)
+	require.NoError(t, err)
+}
+
+// AssertEcsTaskDefinitionContainerImageE checks that the given container of the specified ECS task definition uses the
+// expected image, and returns an error if it does not.
+func AssertEcsTaskDefinitionContainerImageE(t testing.TestingT, region string, taskDefinition string, containerName string, expectedImage string) error {
+	containerDefinition, err := GetEcsTaskDefinitionContainerE(t, region, taskDefinition, containerName)
+	if err != nil {
+		return err
+	}
+	return checkEcsContainerImage(taskDefinition, containerDefinition, expectedImage)
+}
+
+func checkEcsContainerImage(taskDefinition string, containerDefinition *ecs.ContainerDefinition, expectedImage string) error {
+	if image := aws.StringValue(containerDefinition.Image); image != expectedImage {
+		return NewEcsContainerDefinitionMismatchError(taskDefinition, aws.StringValue(containerDefinition.Name), "image", expectedImage, image)
+	}
+	return nil
+}
+
+// AssertEcsTaskDefinitionContainerEnvVars checks that the given container of the specified ECS task definition sets
+// the expected environment variables (it may set others too), and fails the test if it does not.
+func AssertEcsTaskDefinitionContainerEnvVars(t testing.TestingT, region string, taskDefinition string, containerName string, expectedEnvVars map[string]string) {
+	err := AssertEcsTaskDefinitionContainerEnvVarsE(t, region, taskDefinition, containerName, expectedEnvVars)
+	require.NoError(t, err)
+}
+
+// AssertEcsTaskDefinitionContainerEnvVarsE checks that the given container of the specified ECS task definition sets
+// the expected environment variables (it may set others too), and returns an error if it does not.
+func AssertEcsTaskDefinitionContainerEnvVarsE(t testing.TestingT, region string, taskDefinition string, containerName string, expectedEnvVars map[string]string) error {
+	containerDefinition, err := GetEcsTaskDefinitionContainerE(t, region, taskDefinition, containerName)
+	if err != nil {
+		return err
+	}
+	return checkEcsContainerEnvVars(taskDefinition, containerDefinition, expectedEnvVars)
+}
+
+func checkEcsContainerEnvVars(taskDefinition string, containerDefinition *ecs.ContainerDefinition, expectedEnvVars map[string]string) error {
+	envVars := map[string]string{}
+	for _, envVar := range containerDefinition.Environment {
+		envVars[aws.StringValue(envVar.Name)] = aws.StringValue(envVar.Value)
+	}
+	for name, expectedValue := range expectedEnvVars {
+		value, isSet := envVars[name]
+		if !isSet {
+			value = "<not set>"
+		}
+		if !isSet || value != expectedValue {
+			return NewEcsContainerDefinitionMismatchError(taskDefinition, aws.StringValue(containerDefinition.Name), "environment variable "+name, expectedValue, value)
+		}
+	}
+	return nil
+}
+
 // NewEcsClient creates en ECS client.
 func NewEcsClient(t testing.TestingT, region string) *ecs.ECS {
 	client, err := NewEcsClientE(t, region)
@@ -154,7 +444,7 @@ func NewEcsClient(t testing.TestingT, region string) *ecs.ECS {
 
 // NewEcsClientE creates an ECS client.
 func NewEcsClientE(t testing.TestingT, region string) (*ecs.ECS, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -6,7 +6,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEcsCluster(t *testing.T) {
@@ -60,3 +62,77 @@ func TestEcsClusterWithInclude(t *testing.T) {
 	assert.NotEmpty(t, c3.Statistics)
 	assert.Empty(t, c3.Tags)
 }
+
+func TestCheckEcsServiceStable(t *testing.T) {
+	t.Parallel()
+
+	service := &ecs.Service{
+		ServiceName:  aws.String("test-service"),
+		DesiredCount: aws.Int64(2),
+		RunningCount: aws.Int64(2),
+		Deployments:  []*ecs.Deployment{{RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)}},
+	}
+	assert.NoError(t, checkEcsServiceStable("test-cluster", service))
+
+	service.RunningCount = aws.Int64(1)
+	assert.IsType(t, EcsServiceNotStableError{}, checkEcsServiceStable("test-cluster", service))
+
+	service.RunningCount = aws.Int64(2)
+	service.Deployments = append(service.Deployments, &ecs.Deployment{RolloutState: aws.String(ecs.DeploymentRolloutStateInProgress)})
+	assert.IsType(t, EcsServiceNotStableError{}, checkEcsServiceStable("test-cluster", service))
+
+	service.Deployments = []*ecs.Deployment{{RolloutState: aws.String(ecs.DeploymentRolloutStateFailed)}}
+	assert.IsType(t, retry.FatalError{}, checkEcsServiceStable("test-cluster", service))
+}
+
+func TestGetEcsContainerLogStream(t *testing.T) {
+	t.Parallel()
+
+	taskArn := "arn:aws:ecs:us-east-1:123456789012:task/test-cluster/0123456789abcdef"
+	containerDefinition := &ecs.ContainerDefinition{
+		Name: aws.String("app"),
+		LogConfiguration: &ecs.LogConfiguration{
+			LogDriver: aws.String(ecs.LogDriverAwslogs),
+			Options: map[string]*string{
+				"awslogs-group":         aws.String("/ecs/test"),
+				"awslogs-stream-prefix": aws.String("ecs"),
+				"awslogs-region":        aws.String("us-west-2"),
+			},
+		},
+	}
+
+	logGroupName, logStreamName, region, logsToCloudWatch := getEcsContainerLogStream("us-east-1", taskArn, containerDefinition)
+	assert.True(t, logsToCloudWatch)
+	assert.Equal(t, "/ecs/test", logGroupName)
+	assert.Equal(t, "ecs/app/0123456789abcdef", logStreamName)
+	assert.Equal(t, "us-west-2", region)
+
+	containerDefinition.LogConfiguration.LogDriver = aws.String(ecs.LogDriverJsonFile)
+	_, _, _, logsToCloudWatch = getEcsContainerLogStream("us-east-1", taskArn, containerDefinition)
+	assert.False(t, logsToCloudWatch)
+}
+
+func TestEcsTaskDefinitionContainerChecks(t *testing.T) {
+	t.Parallel()
+
+	taskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/test:1"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{
+			Name:        aws.String("app"),
+			Image:       aws.String("nginx:1.21"),
+			Environment: []*ecs.KeyValuePair{{Name: aws.String("PORT"), Value: aws.String("8080")}},
+		}},
+	}
+
+	_, err := findEcsContainerDefinition(taskDefinition, "sidecar")
+	assert.IsType(t, EcsContainerDefinitionNotFoundError{}, err)
+
+	containerDefinition, err := findEcsContainerDefinition(taskDefinition, "app")
+	require.NoError(t, err)
+	assert.NoError(t, checkEcsContainerImage("test:1", containerDefinition, "nginx:1.21"))
+	assert.IsType(t, EcsContainerDefinitionMismatchError{}, checkEcsContainerImage("test:1", containerDefinition, "nginx:latest"))
+
+	assert.NoError(t, checkEcsContainerEnvVars("test:1", containerDefinition, map[string]string{"PORT": "8080"}))
+	assert.IsType(t, EcsContainerDefinitionMismatchError{}, checkEcsContainerEnvVars("test:1", containerDefinition, map[string]string{"PORT": "80"}))
+	assert.IsType(t, EcsContainerDefinitionMismatchError{}, checkEcsContainerEnvVars("test:1", containerDefinition, map[string]string{"HOST": "localhost"}))
+}
@@ -0,0 +1,455 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/efs"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// GetEfsFileSystem returns the description of the EFS file system with the given ID. This will fail the test if there
+// is an error.
+func GetEfsFileSystem(t testing.TestingT, region string, fileSystemID string) *efs.FileSystemDescription {
+	fileSystem, err := GetEfsFileSystemE(t, region, fileSystemID)
+	require.NoError(t, err)
+	return fileSystem
+}
+
+// GetEfsFileSystemE returns the description of the EFS file system with the given ID, or a NotFoundError if it doesn't
+// exist.
+func GetEfsFileSystemE(t testing.TestingT, region string, fileSystemID string) (*efs.FileSystemDescription, error) {
+	client, err := NewEfsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeFileSystems(&efs.DescribeFileSystemsInput{FileSystemId: aws.String(fileSystemID)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == efs.ErrCodeFileSystemNotFound {
+			return nil, NewNotFoundError("EFS file system", fileSystemID, region)
+		}
+		return nil, err
+	}
+	if len(output.FileSystems) == 0 {
+		return nil, NewNotFoundError("EFS file system", fileSystemID, region)
+	}
+	return output.FileSystems[0], nil
+}
+
+// WaitUntilEfsFileSystemAvailable waits until the EFS file system with the given ID is available, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try. This will fail the test if
+// there is an error.
+func WaitUntilEfsFileSystemAvailable(t testing.TestingT, region string, fileSystemID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilEfsFileSystemAvailableE(t, region, fileSystemID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilEfsFileSystemAvailableE waits until the EFS file system with the given ID is available, retrying the check
+// for the specified amount of times, sleeping for the provided duration between each try. This stops waiting if the
+// file system is being deleted or is in an error state.
+func WaitUntilEfsFileSystemAvailableE(t testing.TestingT, region string, fileSystemID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for EFS file system %s to be available.", fileSystemID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			fileSystem, err := GetEfsFileSystemE(t, region, fileSystemID)
+			if err != nil {
+				return "", err
+			}
+			if err := checkEfsLifeCycleStateAvailable("file system", fileSystemID, aws.StringValue(fileSystem.LifeCycleState)); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("EFS file system %s is now available", fileSystemID), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// WaitUntilEfsFileSystemDeleted waits until the EFS file system with the given ID no longer exists, retrying the check
+// for the specified amount of times, sleeping for the provided duration between each try. This will fail the test if
+// there is an error.
+func WaitUntilEfsFileSystemDeleted(t testing.TestingT, region string, fileSystemID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilEfsFileSystemDeletedE(t, region, fileSystemID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilEfsFileSystemDeletedE waits until the EFS file system with the given ID no longer exists, retrying the check
+// for the specified amount of times, sleeping for the provided duration between each try. This is useful to check that
+// destroying a module really deletes its file system.
+func WaitUntilEfsFileSystemDeletedE(t testing.TestingT, region string, fileSystemID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for EFS file system %s to be deleted.", fileSystemID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			fileSystem, err := GetEfsFileSystemE(t, region, fileSystemID)
+			if _, isNotFound := err.(NotFoundError); isNotFound {
+				return fmt.Sprintf("EFS file system %s is now deleted", fileSystemID), nil
+			}
+			if err != nil {
+				return "", err
+			}
+			state := aws.StringValue(fileSystem.LifeCycleState)
+			if state == efs.LifeCycleStateDeleted {
+				return fmt.Sprintf("EFS file system %s is now deleted", fileSystemID), nil
+			}
+			return "", NewEfsLifeCycleStateError("file system", fileSystemID, efs.LifeCycleStateDeleted, state)
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// GetEfsMountTargets returns the mount targets of the EFS file system with the given ID. This will fail the test if
+// there is an error.
+func GetEfsMountTargets(t testing.TestingT, region string, fileSystemID string) []*efs.MountTargetDescription {
+	mountTargets, err := GetEfsMountTargetsE(t, region, fileSystemID)
+	require.NoError(t, err)
+	return mountTargets
+}
+
+// GetEfsMountTargetsE returns the mount targets of the EFS file system with the given ID.
+func GetEfsMountTargetsE(t testing.TestingT, region string, fileSystemID string) ([]*efs.MountTargetDescription, error) {
+	client, err := NewEfsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	mountTargets := []*efs.MountTargetDescription{}
+	input := &efs.DescribeMountTargetsInput{FileSystemId: aws.String(fileSystemID)}
+	for {
+		output, err := client.DescribeMountTargets(input)
+		if err != nil {
+			return nil, err
+		}
+		mountTargets = append(mountTargets, output.MountTargets...)
+		if output.NextMarker == nil {
+			return mountTargets, nil
+		}
+		input.Marker = output.NextMarker
+	}
+}
+
+// WaitUntilEfsMountTargetsAvailable waits until the EFS file system with the given ID has an available mount target in
+// each of the given subnets, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. This will fail the test if there is an error.
+func WaitUntilEfsMountTargetsAvailable(t testing.TestingT, region string, fileSystemID string, subnetIDs []string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilEfsMountTargetsAvailableE(t, region, fileSystemID, subnetIDs, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilEfsMountTargetsAvailableE waits until the EFS file system with the given ID has an available mount target in
+// each of the given subnets, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. The error lists the subnets without an available mount target.
+func WaitUntilEfsMountTargetsAvailableE(t testing.TestingT, region string, fileSystemID string, subnetIDs []string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the mount targets of EFS file system %s to be available in %d subnets.", fileSystemID, len(subnetIDs)),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			mountTargets, err := GetEfsMountTargetsE(t, region, fileSystemID)
+			if err != nil {
+				return "", err
+			}
+			if err := checkEfsMountTargetsAvailable(fileSystemID, mountTargets, subnetIDs); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("The mount targets of EFS file system %s are now available", fileSystemID), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkEfsMountTargetsAvailable returns an error if any of the given subnets doesn't have an available mount target
+// among the given mount targets.
+func checkEfsMountTargetsAvailable(fileSystemID string, mountTargets []*efs.MountTargetDescription, subnetIDs []string) error {
+	statesBySubnet := map[string]string{}
+	for _, mountTarget := range mountTargets {
+		statesBySubnet[aws.StringValue(mountTarget.SubnetId)] = aws.StringValue(mountTarget.LifeCycleState)
+	}
+
+	unavailable := []string{}
+	for _, subnetID := range subnetIDs {
+		state, hasMountTarget := statesBySubnet[subnetID]
+		switch {
+		case !hasMountTarget:
+			unavailable = append(unavailable, fmt.Sprintf("%s (no mount target)", subnetID))
+		case state != efs.LifeCycleStateAvailable:
+			unavailable = append(unavailable, fmt.Sprintf("%s (%s)", subnetID, state))
+		}
+	}
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		return NewEfsMountTargetsNotAvailableError(fileSystemID, unavailable)
+	}
+	return nil
+}
+
+// EfsAccessPointConfig is the configuration of an EFS access point, to compare with AssertEfsAccessPoint.
+type EfsAccessPointConfig struct {
+	FileSystemID      string
+	RootDirectoryPath string
+	// The POSIX user the access point enforces for all requests, or nil if it doesn't enforce one.
+	PosixUser *EfsPosixUser
+	// The owner and permissions the access point creates the root directory with, or nil if it doesn't create it.
+	CreationInfo *EfsCreationInfo
+}
+
+// EfsPosixUser is the POSIX user an EFS access point enforces.
+type EfsPosixUser struct {
+	Uid           int64
+	Gid           int64
+	SecondaryGids []int64
+}
+
+// EfsCreationInfo is the owner and permissions an EFS access point creates its root directory with.
+type EfsCreationInfo struct {
+	OwnerUid    int64
+	OwnerGid    int64
+	Permissions string // In octal, e.g., 0755
+}
+
+func (config EfsAccessPointConfig) String() string {
+	parts := []string{fmt.Sprintf("file system %s, root directory %s", config.FileSystemID, config.RootDirectoryPath)}
+	if config.PosixUser != nil {
+		parts = append(parts, fmt.Sprintf("POSIX user %d:%d (secondary groups %v)", config.PosixUser.Uid, config.PosixUser.Gid, config.PosixUser.SecondaryGids))
+	}
+	if config.CreationInfo != nil {
+		parts = append(parts, fmt.Sprintf("created as %d:%d with permissions %s", config.CreationInfo.OwnerUid, config.CreationInfo.OwnerGid, config.CreationInfo.Permissions))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GetEfsAccessPoint returns the description of the EFS access point with the given ID. This will fail the test if
+// there is an error.
+func GetEfsAccessPoint(t testing.TestingT, region string, accessPointID string) *efs.AccessPointDescription {
+	accessPoint, err := GetEfsAccessPointE(t, region, accessPointID)
+	require.NoError(t, err)
+	return accessPoint
+}
+
+// GetEfsAccessPointE returns the description of the EFS access point with the given ID, or a NotFoundError if it
+// doesn't exist.
+func GetEfsAccessPointE(t testing.TestingT, region string, accessPointID string) (*efs.AccessPointDescription, error) {
+	client, err := NewEfsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeAccessPoints(&efs.DescribeAccessPointsInput{AccessPointId: aws.String(accessPointID)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == efs.ErrCodeAccessPointNotFound {
+			return nil, NewNotFoundError("EFS access point", accessPointID, region)
+		}
+		return nil, err
+	}
+	if len(output.AccessPoints) == 0 {
+		return nil, NewNotFoundError("EFS access point", accessPointID, region)
+	}
+	return output.AccessPoints[0], nil
+}
+
+// AssertEfsAccessPoint checks that the EFS access point with the given ID is available and has the expected
+// configuration. This will fail the test if it doesn't.
+func AssertEfsAccessPoint(t testing.TestingT, region string, accessPointID string, expected EfsAccessPointConfig) {
+	err := AssertEfsAccessPointE(t, region, accessPointID, expected)
+	require.NoError(t, err)
+}
+
+// AssertEfsAccessPointE checks that the EFS access point with the given ID is available and has the expected
+// configuration, i.e., that it belongs to the expected file system, exposes the expected root directory, and enforces
+// the expected POSIX user and root directory ownership:
+//
+//	aws.AssertEfsAccessPoint(t, region, accessPointID, aws.EfsAccessPointConfig{
+//		FileSystemID:      fileSystemID,
+//		RootDirectoryPath: "/app",
+//		PosixUser:         &aws.EfsPosixUser{Uid: 1000, Gid: 1000},
+//		CreationInfo:      &aws.EfsCreationInfo{OwnerUid: 1000, OwnerGid: 1000, Permissions: "0755"},
+//	})
+func AssertEfsAccessPointE(t testing.TestingT, region string, accessPointID string, expected EfsAccessPointConfig) error {
+	accessPoint, err := GetEfsAccessPointE(t, region, accessPointID)
+	if err != nil {
+		return err
+	}
+	if err := checkEfsLifeCycleStateAvailable("access point", accessPointID, aws.StringValue(accessPoint.LifeCycleState)); err != nil {
+		return err
+	}
+	return checkEfsAccessPointConfig(accessPointID, newEfsAccessPointConfig(accessPoint), expected)
+}
+
+// newEfsAccessPointConfig returns the configuration of the given access point.
+func newEfsAccessPointConfig(accessPoint *efs.AccessPointDescription) EfsAccessPointConfig {
+	config := EfsAccessPointConfig{
+		FileSystemID:      aws.StringValue(accessPoint.FileSystemId),
+		RootDirectoryPath: "/",
+	}
+	if accessPoint.PosixUser != nil {
+		config.PosixUser = &EfsPosixUser{
+			Uid:           aws.Int64Value(accessPoint.PosixUser.Uid),
+			Gid:           aws.Int64Value(accessPoint.PosixUser.Gid),
+			SecondaryGids: aws.Int64ValueSlice(accessPoint.PosixUser.SecondaryGids),
+		}
+	}
+	if rootDirectory := accessPoint.RootDirectory; rootDirectory != nil {
+		if path := aws.StringValue(rootDirectory.Path); path != "" {
+			config.RootDirectoryPath = path
+		}
+		if rootDirectory.CreationInfo != nil {
+			config.CreationInfo = &EfsCreationInfo{
+				OwnerUid:    aws.Int64Value(rootDirectory.CreationInfo.OwnerUid),
+				OwnerGid:    aws.Int64Value(rootDirectory.CreationInfo.OwnerGid),
+				Permissions: aws.StringValue(rootDirectory.CreationInfo.Permissions),
+			}
+		}
+	}
+	return config
+}
+
+// checkEfsAccessPointConfig returns an error if the given actual configuration of an access point is not the expected
+// one. Empty secondary groups match no secondary groups.
+func checkEfsAccessPointConfig(accessPointID string, actual EfsAccessPointConfig, expected EfsAccessPointConfig) error {
+	if actual.String() != expected.String() {
+		return NewEfsAccessPointMismatchError(accessPointID, expected, actual)
+	}
+	return nil
+}
+
+// checkEfsLifeCycleStateAvailable returns an error if the given life cycle state of an EFS resource is not available,
+// wrapped in a retry.FatalError if the resource will never become available.
+func checkEfsLifeCycleStateAvailable(resourceType string, resourceID string, state string) error {
+	switch state {
+	case efs.LifeCycleStateAvailable:
+		return nil
+	case efs.LifeCycleStateDeleting, efs.LifeCycleStateDeleted, efs.LifeCycleStateError:
+		return retry.FatalError{Underlying: NewEfsLifeCycleStateError(resourceType, resourceID, efs.LifeCycleStateAvailable, state)}
+	default:
+		return NewEfsLifeCycleStateError(resourceType, resourceID, efs.LifeCycleStateAvailable, state)
+	}
+}
+
+// CheckEfsMountAndWriteFromInstance mounts the EFS file system with the given ID on the given EC2 instance, then writes
+// and reads back a file to prove the instance can use it. This will fail the test if there is an error.
+func CheckEfsMountAndWriteFromInstance(t testing.TestingT, region string, instanceID string, fileSystemID string, timeout time.Duration) {
+	err := CheckEfsMountAndWriteFromInstanceE(t, region, instanceID, fileSystemID, timeout)
+	require.NoError(t, err)
+}
+
+// CheckEfsMountAndWriteFromInstanceE mounts the EFS file system with the given ID on the given EC2 instance, then
+// writes and reads back a file to prove the instance can use it, through the security groups of the mount targets. The
+// script runs through SSM (see RunSsmCommandOnInstanceE), so the instance needs the SSM agent and an NFS client, and
+// root access to mount the file system. The file and the mount are removed afterwards.
+func CheckEfsMountAndWriteFromInstanceE(t testing.TestingT, region string, instanceID string, fileSystemID string, timeout time.Duration) error {
+	content := "terratest-" + random.UniqueId()
+	script := newEfsMountAndWriteScript(getEfsFileSystemDnsName(region, fileSystemID), fileSystemID, content)
+
+	logger.Logf(t, "Checking that EC2 instance %s can mount and write to EFS file system %s", instanceID, fileSystemID)
+	output, err := RunSsmCommandOnInstanceE(t, region, instanceID, script, timeout)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(output.Stdout) != content {
+		return NewEfsWriteCheckFailedError(fileSystemID, instanceID, content, output.Stdout)
+	}
+	return nil
+}
+
+// newEfsMountAndWriteScript returns a shell script that mounts the EFS file system with the given DNS name, writes the
+// given content to a file, prints it back, and cleans up.
+func newEfsMountAndWriteScript(dnsName string, fileSystemID string, content string) string {
+	mountDir := fmt.Sprintf("/mnt/terratest-%s", fileSystemID)
+	file := fmt.Sprintf("%s/%s", mountDir, content)
+	return strings.Join([]string{
+		"set -e",
+		fmt.Sprintf("mkdir -p %s", mountDir),
+		fmt.Sprintf("mount -t nfs4 -o nfsvers=4.1,rsize=1048576,wsize=1048576,hard,timeo=600,retrans=2,noresvport %s:/ %s", dnsName, mountDir),
+		fmt.Sprintf("trap 'rm -f %s; umount %s; rmdir %s' EXIT", file, mountDir, mountDir),
+		fmt.Sprintf("echo '%s' > %s", content, file),
+		"sync",
+		fmt.Sprintf("cat %s", file),
+	}, "\n")
+}
+
+// getEfsFileSystemDnsName returns the DNS name of the EFS file system with the given ID, in the DNS domain of the
+// partition of the given region.
+func getEfsFileSystemDnsName(region string, fileSystemID string) string {
+	dnsSuffix := "amazonaws.com"
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		dnsSuffix = partition.DNSSuffix()
+	}
+	return fmt.Sprintf("%s.efs.%s.%s", fileSystemID, region, dnsSuffix)
+}
+
+// EfsWriteCheckRequest is the payload CheckEfsWriteFromLambda invokes the lambda function with.
+type EfsWriteCheckRequest struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// CheckEfsWriteFromLambda invokes the given lambda function to write and read back a file on the EFS file system it
+// mounts at the given local mount path. This will fail the test if there is an error.
+func CheckEfsWriteFromLambda(t testing.TestingT, region string, functionName string, localMountPath string) {
+	err := CheckEfsWriteFromLambdaE(t, region, functionName, localMountPath)
+	require.NoError(t, err)
+}
+
+// CheckEfsWriteFromLambdaE invokes the given lambda function to write and read back a file on the EFS file system it
+// mounts at the given local mount path (e.g., /mnt/efs), to prove the function can use it. The function is invoked with
+// an EfsWriteCheckRequest, and must write the content to the path, then return the content it reads back from the path
+// as a JSON string. A minimal Python handler:
+//
+//	def handler(event, context):
+//	    with open(event["path"], "w") as f:
+//	        f.write(event["content"])
+//	    with open(event["path"]) as f:
+//	        return f.read()
+func CheckEfsWriteFromLambdaE(t testing.TestingT, region string, functionName string, localMountPath string) error {
+	content := "terratest-" + random.UniqueId()
+	request := EfsWriteCheckRequest{
+		Path:    strings.TrimSuffix(localMountPath, "/") + "/" + content,
+		Content: content,
+	}
+
+	logger.Logf(t, "Checking that lambda function %s can write to EFS at %s", functionName, localMountPath)
+	result, err := InvokeLambdaWithPayloadE(t, region, functionName, request)
+	if err != nil {
+		return err
+	}
+	if result.FunctionError != "" {
+		return NewEfsWriteCheckFailedError(localMountPath, functionName, content, string(result.Payload))
+	}
+	var readBack string
+	if err := result.DecodePayload(&readBack); err != nil || readBack != content {
+		return NewEfsWriteCheckFailedError(localMountPath, functionName, content, string(result.Payload))
+	}
+	return nil
+}
+
+// NewEfsClient creates an EFS client.
+func NewEfsClient(t testing.TestingT, region string) *efs.EFS {
+	client, err := NewEfsClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewEfsClientE creates an EFS client.
+func NewEfsClientE(t testing.TestingT, region string) (*efs.EFS, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return efs.New(sess), nil
+}
@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/efs"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckEfsLifeCycleStateAvailable(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkEfsLifeCycleStateAvailable("file system", "fs-123", efs.LifeCycleStateAvailable))
+
+	err := checkEfsLifeCycleStateAvailable("file system", "fs-123", efs.LifeCycleStateCreating)
+	assert.IsType(t, EfsLifeCycleStateError{}, err)
+
+	err = checkEfsLifeCycleStateAvailable("file system", "fs-123", efs.LifeCycleStateError)
+	require.IsType(t, retry.FatalError{}, err)
+	assert.IsType(t, EfsLifeCycleStateError{}, err.(retry.FatalError).Underlying)
+}
+
+func TestCheckEfsMountTargetsAvailable(t *testing.T) {
+	t.Parallel()
+
+	mountTargets := []*efs.MountTargetDescription{
+		{SubnetId: aws.String("subnet-a"), LifeCycleState: aws.String(efs.LifeCycleStateAvailable)},
+		{SubnetId: aws.String("subnet-b"), LifeCycleState: aws.String(efs.LifeCycleStateCreating)},
+	}
+
+	assert.NoError(t, checkEfsMountTargetsAvailable("fs-123", mountTargets, []string{"subnet-a"}))
+
+	err := checkEfsMountTargetsAvailable("fs-123", mountTargets, []string{"subnet-a", "subnet-b", "subnet-c"})
+	require.IsType(t, EfsMountTargetsNotAvailableError{}, err)
+	assert.Equal(t, []string{"subnet-b (creating)", "subnet-c (no mount target)"}, err.(EfsMountTargetsNotAvailableError).subnets)
+}
+
+func TestNewEfsAccessPointConfig(t *testing.T) {
+	t.Parallel()
+
+	accessPoint := &efs.AccessPointDescription{
+		FileSystemId: aws.String("fs-123"),
+		PosixUser:    &efs.PosixUser{Uid: aws.Int64(1000), Gid: aws.Int64(1000)},
+		RootDirectory: &efs.RootDirectory{
+			Path:         aws.String("/app"),
+			CreationInfo: &efs.CreationInfo{OwnerUid: aws.Int64(1000), OwnerGid: aws.Int64(1000), Permissions: aws.String("0755")},
+		},
+	}
+	expected := EfsAccessPointConfig{
+		FileSystemID:      "fs-123",
+		RootDirectoryPath: "/app",
+		PosixUser:         &EfsPosixUser{Uid: 1000, Gid: 1000},
+		CreationInfo:      &EfsCreationInfo{OwnerUid: 1000, OwnerGid: 1000, Permissions: "0755"},
+	}
+
+	actual := newEfsAccessPointConfig(accessPoint)
+	assert.NoError(t, checkEfsAccessPointConfig("fsap-123", actual, expected))
+
+	expected.PosixUser = nil
+	assert.IsType(t, EfsAccessPointMismatchError{}, checkEfsAccessPointConfig("fsap-123", actual, expected))
+
+	assert.Equal(t, EfsAccessPointConfig{FileSystemID: "fs-123", RootDirectoryPath: "/"}, newEfsAccessPointConfig(&efs.AccessPointDescription{FileSystemId: aws.String("fs-123")}))
+}
+
+func TestGetEfsFileSystemDnsName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "fs-123.efs.us-east-1.amazonaws.com", getEfsFileSystemDnsName("us-east-1", "fs-123"))
+	assert.Equal(t, "fs-123.efs.cn-north-1.amazonaws.com.cn", getEfsFileSystemDnsName("cn-north-1", "fs-123"))
+}
+
+func TestNewEfsMountAndWriteScript(t *testing.T) {
+	t.Parallel()
+
+	script := newEfsMountAndWriteScript("fs-123.efs.us-east-1.amazonaws.com", "fs-123", "terratest-abc")
+	assert.True(t, strings.HasPrefix(script, "set -e\n"))
+	assert.Contains(t, script, "fs-123.efs.us-east-1.amazonaws.com:/ /mnt/terratest-fs-123")
+	assert.Contains(t, script, "echo 'terratest-abc' > /mnt/terratest-fs-123/terratest-abc")
+	assert.Contains(t, script, "umount /mnt/terratest-fs-123")
+}
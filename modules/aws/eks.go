@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	eksTokenPrefix        = "k8s-aws-v1."
+	eksClusterIDHeader    = "x-k8s-aws-id"
+	eksTokenPresignExpiry = 60 * time.Second
+)
+
+// GetEksCluster fetches information about the EKS cluster with the given name.
+func GetEksCluster(t testing.TestingT, region string, clusterName string) *eks.Cluster {
+	cluster, err := GetEksClusterE(t, region, clusterName)
+	require.NoError(t, err)
+	return cluster
+}
+
+// GetEksClusterE fetches information about the EKS cluster with the given name.
+func GetEksClusterE(t testing.TestingT, region string, clusterName string) (*eks.Cluster, error) {
+	client, err := NewEksClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	if err != nil {
+		return nil, err
+	}
+	return output.Cluster, nil
+}
+
+// GetEksToken returns a token that can be used to authenticate to the Kubernetes API of the EKS cluster with the given
+// name, in the same way as aws eks get-token. The token is valid for 15 minutes.
+func GetEksToken(t testing.TestingT, region string, clusterName string) string {
+	token, err := GetEksTokenE(t, region, clusterName)
+	require.NoError(t, err)
+	return token
+}
+
+// GetEksTokenE returns a token that can be used to authenticate to the Kubernetes API of the EKS cluster with the given
+// name, in the same way as aws eks get-token. The token is valid for 15 minutes.
+func GetEksTokenE(t testing.TestingT, region string, clusterName string) (string, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return "", err
+	}
+	return getEksTokenWithSession(sess, clusterName)
+}
+
+// getEksTokenWithSession returns a token for the EKS cluster with the given name, which is a presigned STS
+// GetCallerIdentity request that the cluster uses to find out which IAM identity is authenticating.
+func getEksTokenWithSession(sess *session.Session, clusterName string) (string, error) {
+	request, _ := sts.New(sess).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	request.HTTPRequest.Header.Add(eksClusterIDHeader, clusterName)
+	presignedURL, err := request.Presign(eksTokenPresignExpiry)
+	if err != nil {
+		return "", err
+	}
+	return eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presignedURL)), nil
+}
+
+// WaitUntilEksNodeGroupActive waits until the given node group of the EKS cluster is active, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try.
+func WaitUntilEksNodeGroupActive(
+	t testing.TestingT,
+	region string,
+	clusterName string,
+	nodeGroupName string,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) {
+	err := WaitUntilEksNodeGroupActiveE(t, region, clusterName, nodeGroupName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilEksNodeGroupActiveE waits until the given node group of the EKS cluster is active, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try. This stops waiting early if the
+// node group fails to be created or is being deleted.
+func WaitUntilEksNodeGroupActiveE(
+	t testing.TestingT,
+	region string,
+	clusterName string,
+	nodeGroupName string,
+	maxRetries int,
+	sleepBetweenRetries time.Duration,
+) error {
+	client, err := NewEksClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for node group %s of EKS cluster %s to be active.", nodeGroupName, clusterName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			output, err := client.DescribeNodegroup(&eks.DescribeNodegroupInput{
+				ClusterName:   aws.String(clusterName),
+				NodegroupName: aws.String(nodeGroupName),
+			})
+			if err != nil {
+				return "", err
+			}
+
+			status := aws.StringValue(output.Nodegroup.Status)
+			switch status {
+			case eks.NodegroupStatusActive:
+				return fmt.Sprintf("Node group %s of EKS cluster %s is now active", nodeGroupName, clusterName), nil
+			case eks.NodegroupStatusCreateFailed, eks.NodegroupStatusDeleting, eks.NodegroupStatusDeleteFailed:
+				return "", retry.FatalError{Underlying: NewEksNodeGroupNotActiveError(clusterName, nodeGroupName, status)}
+			default:
+				return "", NewEksNodeGroupNotActiveError(clusterName, nodeGroupName, status)
+			}
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// NewEksClient creates an EKS client.
+func NewEksClient(t testing.TestingT, region string) *eks.EKS {
+	client, err := NewEksClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewEksClientE creates an EKS client.
+func NewEksClientE(t testing.TestingT, region string) (*eks.EKS, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return eks.New(sess), nil
+}
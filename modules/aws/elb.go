@@ -0,0 +1,400 @@
+package aws
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// targetsHealthyMaxRetries and targetsHealthySleepBetweenRetries give targets 10 minutes to pass their health checks,
+	// which is enough for the default health check settings and a slow booting instance.
+	targetsHealthyMaxRetries          = 60
+	targetsHealthySleepBetweenRetries = 10 * time.Second
+)
+
+// GetLoadBalancer fetches information about the application or network load balancer with the given name.
+func GetLoadBalancer(t testing.TestingT, region string, name string) *elbv2.LoadBalancer {
+	loadBalancer, err := GetLoadBalancerE(t, region, name)
+	require.NoError(t, err)
+	return loadBalancer
+}
+
+// GetLoadBalancerE fetches information about the application or network load balancer with the given name.
+func GetLoadBalancerE(t testing.TestingT, region string, name string) (*elbv2.LoadBalancer, error) {
+	client, err := NewElbV2ClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{Names: aws.StringSlice([]string{name})})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.LoadBalancers) == 0 {
+		return nil, NewNotFoundError("load balancer", name, region)
+	}
+	return output.LoadBalancers[0], nil
+}
+
+// GetTargetHealth fetches the health of the targets registered with the given target group.
+func GetTargetHealth(t testing.TestingT, region string, targetGroupArn string) []*elbv2.TargetHealthDescription {
+	targets, err := GetTargetHealthE(t, region, targetGroupArn)
+	require.NoError(t, err)
+	return targets
+}
+
+// GetTargetHealthE fetches the health of the targets registered with the given target group.
+func GetTargetHealthE(t testing.TestingT, region string, targetGroupArn string) ([]*elbv2.TargetHealthDescription, error) {
+	client, err := NewElbV2ClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: aws.String(targetGroupArn)})
+	if err != nil {
+		return nil, err
+	}
+	return output.TargetHealthDescriptions, nil
+}
+
+// WaitUntilTargetsHealthy waits for up to 10 minutes until at least one target is registered with the given target
+// group, and all of its targets are healthy.
+func WaitUntilTargetsHealthy(t testing.TestingT, region string, targetGroupArn string) {
+	err := WaitUntilTargetsHealthyE(t, region, targetGroupArn)
+	require.NoError(t, err)
+}
+
+// WaitUntilTargetsHealthyE waits for up to 10 minutes until at least one target is registered with the given target
+// group, and all of its targets are healthy. Targets that are being deregistered (draining) are ignored.
+func WaitUntilTargetsHealthyE(t testing.TestingT, region string, targetGroupArn string) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for targets of target group %s to be healthy.", targetGroupArn),
+		targetsHealthyMaxRetries,
+		targetsHealthySleepBetweenRetries,
+		func() (string, error) {
+			targets, err := GetTargetHealthE(t, region, targetGroupArn)
+			if err != nil {
+				return "", err
+			}
+			healthyTargets, err := checkTargetsHealthy(targetGroupArn, targets)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("All %d targets of target group %s are now healthy", healthyTargets, targetGroupArn), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkTargetsHealthy returns the number of healthy targets, or an error if there are none or a target that is not
+// draining is not healthy.
+func checkTargetsHealthy(targetGroupArn string, targets []*elbv2.TargetHealthDescription) (int, error) {
+	healthyTargets := 0
+	for _, target := range targets {
+		if target.TargetHealth == nil {
+			continue
+		}
+		state := aws.StringValue(target.TargetHealth.State)
+		switch state {
+		case elbv2.TargetHealthStateEnumHealthy:
+			healthyTargets++
+		case elbv2.TargetHealthStateEnumDraining:
+		default:
+			targetID := aws.StringValue(target.Target.Id)
+			if target.Target.Port != nil {
+				targetID = fmt.Sprintf("%s:%d", targetID, aws.Int64Value(target.Target.Port))
+			}
+			return 0, NewTargetNotHealthyError(targetGroupArn, targetID, state, aws.StringValue(target.TargetHealth.Description))
+		}
+	}
+	if healthyTargets == 0 {
+		return 0, NewTargetNotHealthyError(targetGroupArn, "", "", "no targets are registered")
+	}
+	return healthyTargets, nil
+}
+
+// GetLoadBalancerListeners fetches the listeners of the given load balancer.
+func GetLoadBalancerListeners(t testing.TestingT, region string, loadBalancerArn string) []*elbv2.Listener {
+	listeners, err := GetLoadBalancerListenersE(t, region, loadBalancerArn)
+	require.NoError(t, err)
+	return listeners
+}
+
+// GetLoadBalancerListenersE fetches the listeners of the given load balancer.
+func GetLoadBalancerListenersE(t testing.TestingT, region string, loadBalancerArn string) ([]*elbv2.Listener, error) {
+	client, err := NewElbV2ClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	listeners := []*elbv2.Listener{}
+	input := &elbv2.DescribeListenersInput{LoadBalancerArn: aws.String(loadBalancerArn)}
+	err = client.DescribeListenersPages(input, func(output *elbv2.DescribeListenersOutput, lastPage bool) bool {
+		listeners = append(listeners, output.Listeners...)
+		return true
+	})
+	return listeners, err
+}
+
+// GetLoadBalancerListenerForPort fetches the listener of the given load balancer on the given port.
+func GetLoadBalancerListenerForPort(t testing.TestingT, region string, loadBalancerArn string, port int64) *elbv2.Listener {
+	listener, err := GetLoadBalancerListenerForPortE(t, region, loadBalancerArn, port)
+	require.NoError(t, err)
+	return listener
+}
+
+// GetLoadBalancerListenerForPortE fetches the listener of the given load balancer on the given port.
+func GetLoadBalancerListenerForPortE(t testing.TestingT, region string, loadBalancerArn string, port int64) (*elbv2.Listener, error) {
+	listeners, err := GetLoadBalancerListenersE(t, region, loadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, listener := range listeners {
+		if aws.Int64Value(listener.Port) == port {
+			return listener, nil
+		}
+	}
+	return nil, NewNotFoundError("listener on port "+strconv.FormatInt(port, 10), loadBalancerArn, region)
+}
+
+// GetListenerRules fetches the rules of the given listener, sorted by priority, with the default rule last.
+func GetListenerRules(t testing.TestingT, region string, listenerArn string) []*elbv2.Rule {
+	rules, err := GetListenerRulesE(t, region, listenerArn)
+	require.NoError(t, err)
+	return rules
+}
+
+// GetListenerRulesE fetches the rules of the given listener, sorted by priority, with the default rule last.
+func GetListenerRulesE(t testing.TestingT, region string, listenerArn string) ([]*elbv2.Rule, error) {
+	client, err := NewElbV2ClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []*elbv2.Rule{}
+	input := &elbv2.DescribeRulesInput{ListenerArn: aws.String(listenerArn)}
+	for {
+		output, err := client.DescribeRules(input)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, output.Rules...)
+		if output.NextMarker == nil {
+			break
+		}
+		input.Marker = output.NextMarker
+	}
+
+	sortListenerRules(rules)
+	return rules, nil
+}
+
+// sortListenerRules sorts the given listener rules in the order the load balancer evaluates them: by priority, with the
+// default rule last.
+func sortListenerRules(rules []*elbv2.Rule) {
+	priority := func(rule *elbv2.Rule) int {
+		if aws.BoolValue(rule.IsDefault) {
+			return math.MaxInt32
+		}
+		value, err := strconv.Atoi(aws.StringValue(rule.Priority))
+		if err != nil {
+			return math.MaxInt32
+		}
+		return value
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return priority(rules[i]) < priority(rules[j])
+	})
+}
+
+// AssertListenerRoutesToTargetGroup checks that the rules of the given listener forward requests with the given host
+// and path to the given target group, and fails the test if they do not.
+func AssertListenerRoutesToTargetGroup(t testing.TestingT, region string, listenerArn string, host string, path string, expectedTargetGroupArn string) {
+	err := AssertListenerRoutesToTargetGroupE(t, region, listenerArn, host, path, expectedTargetGroupArn)
+	require.NoError(t, err)
+}
+
+// AssertListenerRoutesToTargetGroupE checks that the rules of the given listener forward requests with the given host
+// and path to the given target group, and returns an error if they do not. The first rule, by priority, whose
+// host-header and path-pattern conditions match the request is used, falling back to the default rule. Rules with
+// other conditions (e.g., on HTTP headers or the source IP) are skipped, as they can't be evaluated from the host and
+// path alone.
+func AssertListenerRoutesToTargetGroupE(t testing.TestingT, region string, listenerArn string, host string, path string, expectedTargetGroupArn string) error {
+	rules, err := GetListenerRulesE(t, region, listenerArn)
+	if err != nil {
+		return err
+	}
+	rule := findMatchingListenerRule(rules, host, path)
+	if rule == nil {
+		return NewListenerRoutingMismatchError(listenerArn, host, path, expectedTargetGroupArn, "no matching rule")
+	}
+
+	targetGroupArns := getForwardTargetGroupArns(rule)
+	for _, targetGroupArn := range targetGroupArns {
+		if targetGroupArn == expectedTargetGroupArn {
+			return nil
+		}
+	}
+	actual := fmt.Sprintf("rule %s, which forwards to %v", aws.StringValue(rule.RuleArn), targetGroupArns)
+	return NewListenerRoutingMismatchError(listenerArn, host, path, expectedTargetGroupArn, actual)
+}
+
+// findMatchingListenerRule returns the first of the given rules, sorted by priority, that matches a request with the
+// given host and path, or nil if none of them do.
+func findMatchingListenerRule(rules []*elbv2.Rule, host string, path string) *elbv2.Rule {
+	for _, rule := range rules {
+		if listenerRuleMatches(rule, host, path) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// listenerRuleMatches returns true if the given rule matches a request with the given host and path.
+func listenerRuleMatches(rule *elbv2.Rule, host string, path string) bool {
+	for _, condition := range rule.Conditions {
+		switch aws.StringValue(condition.Field) {
+		case "host-header":
+			values := aws.StringValueSlice(condition.Values)
+			if condition.HostHeaderConfig != nil {
+				values = append(values, aws.StringValueSlice(condition.HostHeaderConfig.Values)...)
+			}
+			if !matchesAnyWildcardPattern(values, strings.ToLower(host), true) {
+				return false
+			}
+		case "path-pattern":
+			values := aws.StringValueSlice(condition.Values)
+			if condition.PathPatternConfig != nil {
+				values = append(values, aws.StringValueSlice(condition.PathPatternConfig.Values)...)
+			}
+			if !matchesAnyWildcardPattern(values, path, false) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAnyWildcardPattern returns true if the given value matches one of the given patterns, in which * matches any
+// number of characters, and ? matches exactly one character, as in listener rules and IAM policies.
+func matchesAnyWildcardPattern(patterns []string, value string, caseInsensitive bool) bool {
+	for _, pattern := range patterns {
+		expression := regexp.QuoteMeta(pattern)
+		expression = strings.ReplaceAll(expression, `\*`, ".*")
+		expression = strings.ReplaceAll(expression, `\?`, ".")
+		if caseInsensitive {
+			expression = "(?i)" + expression
+		}
+		if regexp.MustCompile("^" + expression + "$").MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// getForwardTargetGroupArns returns the ARNs of the target groups the given rule forwards requests to.
+func getForwardTargetGroupArns(rule *elbv2.Rule) []string {
+	targetGroupArns := []string{}
+	for _, action := range rule.Actions {
+		if aws.StringValue(action.Type) != elbv2.ActionTypeEnumForward {
+			continue
+		}
+		if action.TargetGroupArn != nil {
+			targetGroupArns = append(targetGroupArns, aws.StringValue(action.TargetGroupArn))
+			continue
+		}
+		if action.ForwardConfig != nil {
+			for _, targetGroup := range action.ForwardConfig.TargetGroups {
+				targetGroupArns = append(targetGroupArns, aws.StringValue(targetGroup.TargetGroupArn))
+			}
+		}
+	}
+	return targetGroupArns
+}
+
+// VerifyRequestThroughLoadBalancer sends HTTP GET requests with the given host and path to the given load balancer
+// URL (e.g., "http://" + its DNS name) until one returns the expected status code and a body that contains the
+// expected text, retrying for the specified amount of times, sleeping for the provided duration between each try. This
+// will fail the test if no request does.
+func VerifyRequestThroughLoadBalancer(t testing.TestingT, loadBalancerURL string, host string, path string, expectedStatusCode int, expectedBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := VerifyRequestThroughLoadBalancerE(t, loadBalancerURL, host, path, expectedStatusCode, expectedBodySubstring, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// VerifyRequestThroughLoadBalancerE sends HTTP GET requests with the given host and path to the given load balancer
+// URL (e.g., "http://" + its DNS name) until one returns the expected status code and a body that contains the
+// expected text, retrying for the specified amount of times, sleeping for the provided duration between each try.
+//
+// The host is sent in the Host header (and as the TLS server name for HTTPS), so this verifies host-based routing
+// without a DNS record for the host. Use this with a body that identifies the target (e.g., the name of the service),
+// to check that the load balancer routes the request to the right one.
+func VerifyRequestThroughLoadBalancerE(t testing.TestingT, loadBalancerURL string, host string, path string, expectedStatusCode int, expectedBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{ServerName: host}},
+	}
+	url := strings.TrimSuffix(loadBalancerURL, "/") + path
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Sending request for %s%s to %s.", host, path, loadBalancerURL),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				return "", retry.FatalError{Underlying: err}
+			}
+			request.Host = host
+
+			response, err := client.Do(request)
+			if err != nil {
+				return "", err
+			}
+			defer response.Body.Close()
+			body, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				return "", err
+			}
+
+			if response.StatusCode != expectedStatusCode || !strings.Contains(string(body), expectedBodySubstring) {
+				return "", NewLoadBalancerResponseMismatchError(host, path, expectedStatusCode, expectedBodySubstring, response.StatusCode, string(body))
+			}
+			return fmt.Sprintf("Request for %s%s through %s returned the expected response", host, path, loadBalancerURL), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// NewElbV2Client creates a client for application and network load balancers.
+func NewElbV2Client(t testing.TestingT, region string) *elbv2.ELBV2 {
+	client, err := NewElbV2ClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewElbV2ClientE creates a client for application and network load balancers.
+func NewElbV2ClientE(t testing.TestingT, region string) (*elbv2.ELBV2, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return elbv2.New(sess), nil
+}
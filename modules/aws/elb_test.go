@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTargetsHealthy(t *testing.T) {
+	t.Parallel()
+
+	target := func(id string, state string) *elbv2.TargetHealthDescription {
+		return &elbv2.TargetHealthDescription{
+			Target:       &elbv2.TargetDescription{Id: aws.String(id), Port: aws.Int64(80)},
+			TargetHealth: &elbv2.TargetHealth{State: aws.String(state)},
+		}
+	}
+
+	healthy, err := checkTargetsHealthy("tg", []*elbv2.TargetHealthDescription{
+		target("i-1", elbv2.TargetHealthStateEnumHealthy),
+		target("i-2", elbv2.TargetHealthStateEnumDraining),
+		target("i-3", elbv2.TargetHealthStateEnumHealthy),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, healthy)
+
+	_, err = checkTargetsHealthy("tg", []*elbv2.TargetHealthDescription{
+		target("i-1", elbv2.TargetHealthStateEnumHealthy),
+		target("i-2", elbv2.TargetHealthStateEnumUnhealthy),
+	})
+	assert.Equal(t, NewTargetNotHealthyError("tg", "i-2:80", elbv2.TargetHealthStateEnumUnhealthy, ""), err)
+
+	_, err = checkTargetsHealthy("tg", nil)
+	assert.Error(t, err)
+}
+
+func TestFindMatchingListenerRule(t *testing.T) {
+	t.Parallel()
+
+	forwardTo := func(targetGroupArn string) []*elbv2.Action {
+		return []*elbv2.Action{{Type: aws.String(elbv2.ActionTypeEnumForward), TargetGroupArn: aws.String(targetGroupArn)}}
+	}
+	rules := []*elbv2.Rule{
+		{RuleArn: aws.String("default"), IsDefault: aws.Bool(true), Priority: aws.String("default"), Actions: forwardTo("tg-default")},
+		{
+			RuleArn:  aws.String("api"),
+			Priority: aws.String("20"),
+			Conditions: []*elbv2.RuleCondition{
+				{Field: aws.String("host-header"), HostHeaderConfig: &elbv2.HostHeaderConditionConfig{Values: aws.StringSlice([]string{"api.example.com"})}},
+				{Field: aws.String("path-pattern"), PathPatternConfig: &elbv2.PathPatternConditionConfig{Values: aws.StringSlice([]string{"/v?/*"})}},
+			},
+			Actions: forwardTo("tg-api"),
+		},
+		{
+			RuleArn:    aws.String("header"),
+			Priority:   aws.String("5"),
+			Conditions: []*elbv2.RuleCondition{{Field: aws.String("http-header")}},
+			Actions:    forwardTo("tg-header"),
+		},
+		{
+			RuleArn:    aws.String("static"),
+			Priority:   aws.String("10"),
+			Conditions: []*elbv2.RuleCondition{{Field: aws.String("path-pattern"), Values: aws.StringSlice([]string{"/static/*"})}},
+			Actions: []*elbv2.Action{{
+				Type:          aws.String(elbv2.ActionTypeEnumForward),
+				ForwardConfig: &elbv2.ForwardActionConfig{TargetGroups: []*elbv2.TargetGroupTuple{{TargetGroupArn: aws.String("tg-static")}}},
+			}},
+		},
+	}
+	sortListenerRules(rules)
+
+	testCases := []struct {
+		host              string
+		path              string
+		expectedRule      string
+		expectedForwardTo []string
+	}{
+		{"API.example.com", "/v1/users/1", "api", []string{"tg-api"}},
+		{"api.example.com", "/static/css/site.css", "static", []string{"tg-static"}},
+		{"api.example.com", "/v10/users", "default", []string{"tg-default"}},
+		{"www.example.com", "/v1/users", "default", []string{"tg-default"}},
+	}
+	for _, testCase := range testCases {
+		rule := findMatchingListenerRule(rules, testCase.host, testCase.path)
+		require.NotNil(t, rule, "%s%s", testCase.host, testCase.path)
+		assert.Equal(t, testCase.expectedRule, aws.StringValue(rule.RuleArn), "%s%s", testCase.host, testCase.path)
+		assert.Equal(t, testCase.expectedForwardTo, getForwardTargetGroupArns(rule), "%s%s", testCase.host, testCase.path)
+	}
+}
+
+func TestVerifyRequestThroughLoadBalancer(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "host=%s path=%s", r.Host, r.URL.Path)
+	}))
+	defer server.Close()
+
+	err := VerifyRequestThroughLoadBalancerE(t, server.URL, "api.example.com", "/v1/users", http.StatusOK, "host=api.example.com path=/v1/users", 1, 0)
+	assert.NoError(t, err)
+
+	err = VerifyRequestThroughLoadBalancerE(t, server.URL, "www.example.com", "/v1/users", http.StatusOK, "host=api.example.com", 1, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// You can set this environment variable to the URL of a LocalStack instance (e.g., http://localhost:4566) to make all
+// the helpers of this package talk to it instead of AWS, without changing the tests.
+const LocalStackURLEnvVar = "TERRATEST_LOCALSTACK_URL"
+
+// LocalStack accepts any credentials, so these are used when running against LocalStack without a profile, so that the
+// tests don't need AWS credentials at all.
+const (
+	localStackAccessKeyID     = "test"
+	localStackSecretAccessKey = "test"
+)
+
+var (
+	endpointsMutex sync.RWMutex
+	// endpointResolver overrides the endpoints of all the sessions created by this package, if set.
+	endpointResolver endpoints.Resolver
+	// localStackURL is the URL of the LocalStack instance endpointResolver resolves to, if it was set by UseLocalStack.
+	localStackURL string
+)
+
+// SetEndpointResolver makes all the sessions created by this package, and so all the helpers of this package, resolve
+// the endpoints of AWS services with the given resolver (e.g., to test against a VPC endpoint or a mock of an AWS API).
+// Use nil to go back to the default AWS endpoints. As this applies to the whole package, tests that set it must not run
+// in parallel with tests that expect the real AWS endpoints.
+func SetEndpointResolver(resolver endpoints.Resolver) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+
+	endpointResolver = resolver
+	localStackURL = ""
+}
+
+// UseLocalStack makes all the helpers of this package talk to the LocalStack instance at the given URL (e.g.,
+// http://localhost:4566) instead of AWS. This also uses path-style S3 URLs, which LocalStack requires, and dummy
+// credentials unless a profile is used, so the tests don't need AWS credentials. Call ResetEndpoints to go back to AWS.
+// Setting the TERRATEST_LOCALSTACK_URL environment variable has the same effect without changing the tests:
+//
+//	func TestMain(m *testing.M) {
+//		aws.UseLocalStack("http://localhost:4566")
+//		os.Exit(m.Run())
+//	}
+//
+// Helpers that don't go through the AWS SDK, such as OpenSsmTunnel or PushImageToEcr, still talk to AWS.
+func UseLocalStack(url string) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+
+	endpointResolver = newStaticEndpointResolver(url)
+	localStackURL = url
+}
+
+// ResetEndpoints undoes SetEndpointResolver and UseLocalStack, so that the helpers of this package talk to the default
+// AWS endpoints again, unless the TERRATEST_LOCALSTACK_URL environment variable is set.
+func ResetEndpoints() {
+	SetEndpointResolver(nil)
+}
+
+// GetLocalStackURL returns the URL of the LocalStack instance the helpers of this package talk to, or an empty string if
+// they talk to AWS.
+func GetLocalStackURL() string {
+	endpointsMutex.RLock()
+	defer endpointsMutex.RUnlock()
+
+	if endpointResolver != nil {
+		return localStackURL
+	}
+	return os.Getenv(LocalStackURLEnvVar)
+}
+
+// getEndpointResolver returns the resolver that overrides the endpoints of the sessions created by this package, or nil
+// to use the default AWS endpoints.
+func getEndpointResolver() endpoints.Resolver {
+	endpointsMutex.RLock()
+	resolver := endpointResolver
+	endpointsMutex.RUnlock()
+
+	if resolver != nil {
+		return resolver
+	}
+	if url := os.Getenv(LocalStackURLEnvVar); url != "" {
+		return newStaticEndpointResolver(url)
+	}
+	return nil
+}
+
+// newStaticEndpointResolver returns a resolver that resolves the endpoints of all services in all regions to the given
+// URL, signing requests for the region of the session.
+func newStaticEndpointResolver(url string) endpoints.Resolver {
+	url = strings.TrimSuffix(url, "/")
+	return endpoints.ResolverFunc(func(service string, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		return endpoints.ResolvedEndpoint{URL: url, SigningRegion: region}, nil
+	})
+}
+
+// newAwsConfig returns the config of a session in the given region, with the endpoint overrides and the retryer of this
+// package.
+func newAwsConfig(region string) *aws.Config {
+	config := request.WithRetryer(aws.NewConfig().WithRegion(region), getRetryer())
+	if resolver := getEndpointResolver(); resolver != nil {
+		config = config.WithEndpointResolver(resolver)
+	}
+	if GetLocalStackURL() != "" {
+		config = config.WithS3ForcePathStyle(true)
+	}
+	return config
+}
+
+// newLocalStackCredentials returns the dummy credentials used to talk to LocalStack.
+func newLocalStackCredentials() *credentials.Credentials {
+	return CreateAwsCredentials(localStackAccessKeyID, localStackSecretAccessKey)
+}
@@ -0,0 +1,46 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStaticEndpointResolver(t *testing.T) {
+	t.Parallel()
+
+	resolver := newStaticEndpointResolver("http://localhost:4566/")
+	for _, service := range []string{"s3", "iam", "dynamodb"} {
+		endpoint, err := resolver.EndpointFor(service, "eu-west-1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:4566", endpoint.URL)
+		assert.Equal(t, "eu-west-1", endpoint.SigningRegion)
+	}
+}
+
+// This test changes the endpoints of the whole package, so it must not run in parallel with the other tests.
+func TestUseLocalStack(t *testing.T) {
+	defer ResetEndpoints()
+
+	UseLocalStack("http://localhost:4566")
+	assert.Equal(t, "http://localhost:4566", GetLocalStackURL())
+
+	sess, err := NewAuthenticatedSessionWithOptions("us-west-2", AuthOptions{})
+	require.NoError(t, err)
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, localStackAccessKeyID, creds.AccessKeyID)
+	assert.True(t, aws.BoolValue(sess.Config.S3ForcePathStyle))
+
+	endpoint, err := sess.Config.EndpointResolver.EndpointFor(s3.EndpointsID, "us-west-2")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:4566", endpoint.URL)
+
+	ResetEndpoints()
+	if GetLocalStackURL() == "" {
+		assert.Nil(t, newAwsConfig("us-west-2").EndpointResolver)
+	}
+}
@@ -2,6 +2,10 @@ package aws
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
 // IpForEc2InstanceNotFound is an error that occurs when the IP for an EC2 instance is not found.
@@ -61,6 +65,65 @@ func NewAsgCapacityNotMetError(asgName string, desiredCapacity int64, currentCap
 	return AsgCapacityNotMetError{asgName, desiredCapacity, currentCapacity}
 }
 
+// AsgInstanceRefreshNotCompleteError is returned when the instance refresh of an ASG has not completed successfully.
+type AsgInstanceRefreshNotCompleteError struct {
+	asgName           string
+	instanceRefreshID string
+	status            string
+	percentComplete   int64
+	statusReason      string
+}
+
+func (err AsgInstanceRefreshNotCompleteError) Error() string {
+	msg := fmt.Sprintf(
+		"Instance refresh %s of ASG %s has not completed successfully (status %s, %d%% complete)",
+		err.instanceRefreshID,
+		err.asgName,
+		err.status,
+		err.percentComplete,
+	)
+	if err.statusReason != "" {
+		msg += ": " + err.statusReason
+	}
+	return msg
+}
+
+func NewAsgInstanceRefreshNotCompleteError(asgName string, instanceRefreshID string, status string, percentComplete int64, statusReason string) AsgInstanceRefreshNotCompleteError {
+	return AsgInstanceRefreshNotCompleteError{asgName, instanceRefreshID, status, percentComplete, statusReason}
+}
+
+// AsgMixedInstancesPolicyMismatchError is returned when the mixed instances policy of an ASG is not the expected one.
+type AsgMixedInstancesPolicyMismatchError struct {
+	asgName  string
+	expected AsgMixedInstancesPolicy
+	actual   AsgMixedInstancesPolicy
+}
+
+func (err AsgMixedInstancesPolicyMismatchError) Error() string {
+	return fmt.Sprintf("Expected ASG %s to have mixed instances policy %+v, but it has %+v", err.asgName, err.expected, err.actual)
+}
+
+func NewAsgMixedInstancesPolicyMismatchError(asgName string, expected AsgMixedInstancesPolicy, actual AsgMixedInstancesPolicy) AsgMixedInstancesPolicyMismatchError {
+	return AsgMixedInstancesPolicyMismatchError{asgName, expected, actual}
+}
+
+// AsgLifecycleHookTransitionMismatchError is returned when a lifecycle hook of an ASG is not for the expected
+// transition.
+type AsgLifecycleHookTransitionMismatchError struct {
+	asgName  string
+	hookName string
+	expected string
+	actual   string
+}
+
+func (err AsgLifecycleHookTransitionMismatchError) Error() string {
+	return fmt.Sprintf("Expected lifecycle hook %s of ASG %s to be for transition %s, but it is for %s", err.hookName, err.asgName, err.expected, err.actual)
+}
+
+func NewAsgLifecycleHookTransitionMismatchError(asgName string, hookName string, expected string, actual string) AsgLifecycleHookTransitionMismatchError {
+	return AsgLifecycleHookTransitionMismatchError{asgName, hookName, expected, actual}
+}
+
 // BucketVersioningNotEnabledError is returned when an S3 bucket that should have versioning does not have it applied
 type BucketVersioningNotEnabledError struct {
 	s3BucketName     string
@@ -129,3 +192,879 @@ func (err NoRdsInstanceTypeError) Error() string {
 		err.DatabaseEngineVersion,
 	)
 }
+
+// EksNodeGroupNotActiveError is returned when an EKS node group is not yet active.
+type EksNodeGroupNotActiveError struct {
+	clusterName   string
+	nodeGroupName string
+	status        string
+}
+
+func (err EksNodeGroupNotActiveError) Error() string {
+	return fmt.Sprintf(
+		"Node group %s of EKS cluster %s is not active (status %s)",
+		err.nodeGroupName,
+		err.clusterName,
+		err.status,
+	)
+}
+
+func NewEksNodeGroupNotActiveError(clusterName string, nodeGroupName string, status string) EksNodeGroupNotActiveError {
+	return EksNodeGroupNotActiveError{clusterName, nodeGroupName, status}
+}
+
+// EcsServiceNotStableError is returned when an ECS service is not yet stable.
+type EcsServiceNotStableError struct {
+	clusterName string
+	serviceName string
+	reason      string
+}
+
+func (err EcsServiceNotStableError) Error() string {
+	return fmt.Sprintf("ECS service %s in cluster %s is not stable: %s", err.serviceName, err.clusterName, err.reason)
+}
+
+func NewEcsServiceNotStableError(clusterName string, serviceName string, reason string) EcsServiceNotStableError {
+	return EcsServiceNotStableError{clusterName, serviceName, reason}
+}
+
+// EcsTaskNotStoppedError is returned when an ECS task has not stopped yet.
+type EcsTaskNotStoppedError struct {
+	taskArn string
+	status  string
+}
+
+func (err EcsTaskNotStoppedError) Error() string {
+	return fmt.Sprintf("ECS task %s has not stopped yet (status %s)", err.taskArn, err.status)
+}
+
+func NewEcsTaskNotStoppedError(taskArn string, status string) EcsTaskNotStoppedError {
+	return EcsTaskNotStoppedError{taskArn, status}
+}
+
+// EcsContainerDefinitionNotFoundError is returned when an ECS task definition has no container with the given name.
+type EcsContainerDefinitionNotFoundError struct {
+	taskDefinition string
+	containerName  string
+}
+
+func (err EcsContainerDefinitionNotFoundError) Error() string {
+	return fmt.Sprintf("ECS task definition %s has no container named %s", err.taskDefinition, err.containerName)
+}
+
+func NewEcsContainerDefinitionNotFoundError(taskDefinition string, containerName string) EcsContainerDefinitionNotFoundError {
+	return EcsContainerDefinitionNotFoundError{taskDefinition, containerName}
+}
+
+// EcsContainerDefinitionMismatchError is returned when a container of an ECS task definition is not configured as
+// expected.
+type EcsContainerDefinitionMismatchError struct {
+	taskDefinition string
+	containerName  string
+	field          string
+	expected       string
+	actual         string
+}
+
+func (err EcsContainerDefinitionMismatchError) Error() string {
+	return fmt.Sprintf(
+		"Expected %s of container %s in ECS task definition %s to be %s, but got %s",
+		err.field,
+		err.containerName,
+		err.taskDefinition,
+		err.expected,
+		err.actual,
+	)
+}
+
+func NewEcsContainerDefinitionMismatchError(taskDefinition string, containerName string, field string, expected string, actual string) EcsContainerDefinitionMismatchError {
+	return EcsContainerDefinitionMismatchError{taskDefinition, containerName, field, expected, actual}
+}
+
+// LambdaNotUpdatedError is returned when the latest update of a lambda function has not been applied.
+type LambdaNotUpdatedError struct {
+	functionName     string
+	state            string
+	lastUpdateStatus string
+	reason           string
+}
+
+func (err LambdaNotUpdatedError) Error() string {
+	msg := fmt.Sprintf(
+		"Lambda function %s is not updated (state %s, last update status %s)",
+		err.functionName,
+		err.state,
+		err.lastUpdateStatus,
+	)
+	if err.reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, err.reason)
+	}
+	return msg
+}
+
+func NewLambdaNotUpdatedError(functionName string, state string, lastUpdateStatus string, reason string) LambdaNotUpdatedError {
+	return LambdaNotUpdatedError{functionName, state, lastUpdateStatus, reason}
+}
+
+// DynamoDBTableNotActiveError is returned when a dynamoDB table or one of its global secondary indexes is not active.
+type DynamoDBTableNotActiveError struct {
+	tableName string
+	indexName string
+	status    string
+}
+
+func (err DynamoDBTableNotActiveError) Error() string {
+	if err.indexName != "" {
+		return fmt.Sprintf("Global secondary index %s of dynamoDB table %s is not active (status %s)", err.indexName, err.tableName, err.status)
+	}
+	return fmt.Sprintf("DynamoDB table %s is not active (status %s)", err.tableName, err.status)
+}
+
+func NewDynamoDBTableNotActiveError(tableName string, indexName string, status string) DynamoDBTableNotActiveError {
+	return DynamoDBTableNotActiveError{tableName, indexName, status}
+}
+
+// DynamoDBItemNotFoundError is returned when a dynamoDB table has no item with the given key.
+type DynamoDBItemNotFoundError struct {
+	tableName string
+	key       map[string]*dynamodb.AttributeValue
+}
+
+func (err DynamoDBItemNotFoundError) Error() string {
+	return fmt.Sprintf("No item with key %v found in dynamoDB table %s", err.key, err.tableName)
+}
+
+func NewDynamoDBItemNotFoundError(tableName string, key map[string]*dynamodb.AttributeValue) DynamoDBItemNotFoundError {
+	return DynamoDBItemNotFoundError{tableName, key}
+}
+
+// DynamoDBTableConfigMismatchError is returned when a dynamoDB table is not configured as expected.
+type DynamoDBTableConfigMismatchError struct {
+	tableName string
+	field     string
+	expected  string
+	actual    string
+}
+
+func (err DynamoDBTableConfigMismatchError) Error() string {
+	return fmt.Sprintf("Expected %s of dynamoDB table %s to be %s, but got %s", err.field, err.tableName, err.expected, err.actual)
+}
+
+func NewDynamoDBTableConfigMismatchError(tableName string, field string, expected string, actual string) DynamoDBTableConfigMismatchError {
+	return DynamoDBTableConfigMismatchError{tableName, field, expected, actual}
+}
+
+// RdsClusterNotAvailableError is returned when an RDS cluster or one of its DB instances is not yet available.
+type RdsClusterNotAvailableError struct {
+	dbClusterID  string
+	dbInstanceID string
+	status       string
+}
+
+func (err RdsClusterNotAvailableError) Error() string {
+	if err.dbInstanceID == "" {
+		return fmt.Sprintf("RDS cluster %s is not yet available (status: %s)", err.dbClusterID, err.status)
+	}
+	return fmt.Sprintf("DB instance %s of RDS cluster %s is not yet available (status: %s)", err.dbInstanceID, err.dbClusterID, err.status)
+}
+
+// NewRdsClusterNotAvailableError creates a new RdsClusterNotAvailableError. The DB instance ID is empty if the cluster
+// itself is not available.
+func NewRdsClusterNotAvailableError(dbClusterID string, dbInstanceID string, status string) RdsClusterNotAvailableError {
+	return RdsClusterNotAvailableError{dbClusterID, dbInstanceID, status}
+}
+
+// RdsClusterSnapshotNotAvailableError is returned when an RDS cluster snapshot is not yet available.
+type RdsClusterSnapshotNotAvailableError struct {
+	snapshotID string
+	status     string
+}
+
+func (err RdsClusterSnapshotNotAvailableError) Error() string {
+	return fmt.Sprintf("RDS cluster snapshot %s is not yet available (status: %s)", err.snapshotID, err.status)
+}
+
+// NewRdsClusterSnapshotNotAvailableError creates a new RdsClusterSnapshotNotAvailableError.
+func NewRdsClusterSnapshotNotAvailableError(snapshotID string, status string) RdsClusterSnapshotNotAvailableError {
+	return RdsClusterSnapshotNotAvailableError{snapshotID, status}
+}
+
+// TargetNotHealthyError is returned when a target of a load balancer target group is not healthy.
+type TargetNotHealthyError struct {
+	targetGroupArn string
+	targetID       string
+	state          string
+	description    string
+}
+
+func (err TargetNotHealthyError) Error() string {
+	if err.targetID == "" {
+		return fmt.Sprintf("Target group %s has no healthy targets: %s", err.targetGroupArn, err.description)
+	}
+	return fmt.Sprintf("Target %s of target group %s is not healthy (state: %s): %s", err.targetID, err.targetGroupArn, err.state, err.description)
+}
+
+// NewTargetNotHealthyError creates a new TargetNotHealthyError. The target ID is empty if the target group has no
+// targets.
+func NewTargetNotHealthyError(targetGroupArn string, targetID string, state string, description string) TargetNotHealthyError {
+	return TargetNotHealthyError{targetGroupArn, targetID, state, description}
+}
+
+// ListenerRoutingMismatchError is returned when a load balancer listener doesn't route a request to the expected
+// target group.
+type ListenerRoutingMismatchError struct {
+	listenerArn            string
+	host                   string
+	path                   string
+	expectedTargetGroupArn string
+	actual                 string
+}
+
+func (err ListenerRoutingMismatchError) Error() string {
+	return fmt.Sprintf(
+		"Expected listener %s to route requests for %s%s to target group %s, but found %s",
+		err.listenerArn,
+		err.host,
+		err.path,
+		err.expectedTargetGroupArn,
+		err.actual,
+	)
+}
+
+// NewListenerRoutingMismatchError creates a new ListenerRoutingMismatchError.
+func NewListenerRoutingMismatchError(listenerArn string, host string, path string, expectedTargetGroupArn string, actual string) ListenerRoutingMismatchError {
+	return ListenerRoutingMismatchError{listenerArn, host, path, expectedTargetGroupArn, actual}
+}
+
+// LoadBalancerResponseMismatchError is returned when a request through a load balancer doesn't return the expected
+// response.
+type LoadBalancerResponseMismatchError struct {
+	host                  string
+	path                  string
+	expectedStatusCode    int
+	expectedBodySubstring string
+	statusCode            int
+	body                  string
+}
+
+func (err LoadBalancerResponseMismatchError) Error() string {
+	return fmt.Sprintf(
+		"Expected request for %s%s to return status code %d and a body containing %q, but got status code %d and body %q",
+		err.host,
+		err.path,
+		err.expectedStatusCode,
+		err.expectedBodySubstring,
+		err.statusCode,
+		err.body,
+	)
+}
+
+// NewLoadBalancerResponseMismatchError creates a new LoadBalancerResponseMismatchError.
+func NewLoadBalancerResponseMismatchError(host string, path string, expectedStatusCode int, expectedBodySubstring string, statusCode int, body string) LoadBalancerResponseMismatchError {
+	return LoadBalancerResponseMismatchError{host, path, expectedStatusCode, expectedBodySubstring, statusCode, body}
+}
+
+// Route53AliasTargetMismatchError is returned when a Route 53 record is not an alias for the expected DNS name.
+type Route53AliasTargetMismatchError struct {
+	recordName      string
+	expectedDNSName string
+	actualDNSName   string
+}
+
+func (err Route53AliasTargetMismatchError) Error() string {
+	if err.actualDNSName == "" {
+		return fmt.Sprintf("Expected Route 53 record %s to be an alias for %s, but it is not an alias", err.recordName, err.expectedDNSName)
+	}
+	return fmt.Sprintf("Expected Route 53 record %s to be an alias for %s, but it is an alias for %s", err.recordName, err.expectedDNSName, err.actualDNSName)
+}
+
+// NewRoute53AliasTargetMismatchError creates a new Route53AliasTargetMismatchError. The actual DNS name is empty if the
+// record is not an alias.
+func NewRoute53AliasTargetMismatchError(recordName string, expectedDNSName string, actualDNSName string) Route53AliasTargetMismatchError {
+	return Route53AliasTargetMismatchError{recordName, expectedDNSName, actualDNSName}
+}
+
+// DnsNotResolvedError is returned when a domain name does not yet resolve to the expected values.
+type DnsNotResolvedError struct {
+	fqdn         string
+	resolverAddr string
+	expected     []string
+	answers      []string
+}
+
+func (err DnsNotResolvedError) Error() string {
+	resolver := err.resolverAddr
+	if resolver == "" {
+		resolver = "the system resolver"
+	}
+	return fmt.Sprintf("Expected %s to resolve to %v via %s, but it resolves to %v", err.fqdn, err.expected, resolver, err.answers)
+}
+
+// NewDnsNotResolvedError creates a new DnsNotResolvedError. The resolver address is empty for the system resolver.
+func NewDnsNotResolvedError(fqdn string, resolverAddr string, expected []string, answers []string) DnsNotResolvedError {
+	return DnsNotResolvedError{fqdn, resolverAddr, expected, answers}
+}
+
+// KmsRoundtripMismatchError is returned when data decrypted with a KMS key is different from the data that was
+// encrypted.
+type KmsRoundtripMismatchError struct {
+	cmkID string
+	what  string
+}
+
+func (err KmsRoundtripMismatchError) Error() string {
+	return fmt.Sprintf("The %s of KMS key %s is different from the original", err.what, err.cmkID)
+}
+
+// NewKmsRoundtripMismatchError creates a new KmsRoundtripMismatchError.
+func NewKmsRoundtripMismatchError(cmkID string, what string) KmsRoundtripMismatchError {
+	return KmsRoundtripMismatchError{cmkID, what}
+}
+
+// KmsKeyConfigMismatchError is returned when a KMS key is not configured as expected.
+type KmsKeyConfigMismatchError struct {
+	cmkID    string
+	field    string
+	expected string
+	actual   string
+}
+
+func (err KmsKeyConfigMismatchError) Error() string {
+	return fmt.Sprintf("Expected %s of KMS key %s to be %s, but got %s", err.field, err.cmkID, err.expected, err.actual)
+}
+
+// NewKmsKeyConfigMismatchError creates a new KmsKeyConfigMismatchError.
+func NewKmsKeyConfigMismatchError(cmkID string, field string, expected string, actual string) KmsKeyConfigMismatchError {
+	return KmsKeyConfigMismatchError{cmkID, field, expected, actual}
+}
+
+// SubnetRouteMismatchError is returned when a subnet does not route a destination to the expected target.
+type SubnetRouteMismatchError struct {
+	subnetID    string
+	destination string
+	expected    string
+	actual      string
+}
+
+func (err SubnetRouteMismatchError) Error() string {
+	return fmt.Sprintf("Expected subnet %s to route %s to %s, but found %s", err.subnetID, err.destination, err.expected, err.actual)
+}
+
+// NewSubnetRouteMismatchError creates a new SubnetRouteMismatchError.
+func NewSubnetRouteMismatchError(subnetID string, destination string, expected string, actual string) SubnetRouteMismatchError {
+	return SubnetRouteMismatchError{subnetID, destination, expected, actual}
+}
+
+// VpcEndpointNotFoundError is returned when a VPC has no available endpoint for a service.
+type VpcEndpointNotFoundError struct {
+	vpcID       string
+	serviceName string
+	states      []string
+}
+
+func (err VpcEndpointNotFoundError) Error() string {
+	if len(err.states) == 0 {
+		return fmt.Sprintf("VPC %s has no endpoint for service %s", err.vpcID, err.serviceName)
+	}
+	return fmt.Sprintf("VPC %s has no available endpoint for service %s (found endpoints in states %v)", err.vpcID, err.serviceName, err.states)
+}
+
+// NewVpcEndpointNotFoundError creates a new VpcEndpointNotFoundError. The states are the states of the endpoints for
+// the service that are not available.
+func NewVpcEndpointNotFoundError(vpcID string, serviceName string, states []string) VpcEndpointNotFoundError {
+	return VpcEndpointNotFoundError{vpcID, serviceName, states}
+}
+
+// SfnExecutionStatusMismatchError is returned when an execution of a Step Functions state machine doesn't have the
+// expected status.
+type SfnExecutionStatusMismatchError struct {
+	executionArn   string
+	expectedStatus string
+	status         string
+}
+
+func (err SfnExecutionStatusMismatchError) Error() string {
+	return fmt.Sprintf("Expected execution %s to have status %s, but it has status %s", err.executionArn, err.expectedStatus, err.status)
+}
+
+// NewSfnExecutionStatusMismatchError creates a new SfnExecutionStatusMismatchError.
+func NewSfnExecutionStatusMismatchError(executionArn string, expectedStatus string, status string) SfnExecutionStatusMismatchError {
+	return SfnExecutionStatusMismatchError{executionArn, expectedStatus, status}
+}
+
+// ECRLifecyclePolicyMismatchError is returned when the lifecycle policy of an ECR repository is not the expected one.
+type ECRLifecyclePolicyMismatchError struct {
+	repoName string
+	expected string
+	actual   string
+}
+
+func (err ECRLifecyclePolicyMismatchError) Error() string {
+	return fmt.Sprintf("Expected ECR repository %s to have lifecycle policy %s, but it has %s", err.repoName, err.expected, err.actual)
+}
+
+// NewECRLifecyclePolicyMismatchError creates a new ECRLifecyclePolicyMismatchError.
+func NewECRLifecyclePolicyMismatchError(repoName string, expected string, actual string) ECRLifecyclePolicyMismatchError {
+	return ECRLifecyclePolicyMismatchError{repoName, expected, actual}
+}
+
+// ECRImageScanNotCompleteError is returned when the scan of an image in an ECR repository is not complete.
+type ECRImageScanNotCompleteError struct {
+	repoName    string
+	tag         string
+	status      string
+	description string
+}
+
+func (err ECRImageScanNotCompleteError) Error() string {
+	msg := fmt.Sprintf("Scan of image %s:%s is not complete (status %q)", err.repoName, err.tag, err.status)
+	if err.description != "" {
+		msg += ": " + err.description
+	}
+	return msg
+}
+
+// NewECRImageScanNotCompleteError creates a new ECRImageScanNotCompleteError.
+func NewECRImageScanNotCompleteError(repoName string, tag string, status string, description string) ECRImageScanNotCompleteError {
+	return ECRImageScanNotCompleteError{repoName, tag, status, description}
+}
+
+// CloudFrontNotDeployedError is returned when the latest changes of a CloudFront distribution are not deployed yet.
+type CloudFrontNotDeployedError struct {
+	distributionID string
+	status         string
+}
+
+func (err CloudFrontNotDeployedError) Error() string {
+	return fmt.Sprintf("CloudFront distribution %s is not deployed yet (status %s)", err.distributionID, err.status)
+}
+
+// NewCloudFrontNotDeployedError creates a new CloudFrontNotDeployedError.
+func NewCloudFrontNotDeployedError(distributionID string, status string) CloudFrontNotDeployedError {
+	return CloudFrontNotDeployedError{distributionID, status}
+}
+
+// CloudFrontInvalidationNotCompleteError is returned when an invalidation of a CloudFront distribution is not complete.
+type CloudFrontInvalidationNotCompleteError struct {
+	distributionID string
+	invalidationID string
+	status         string
+}
+
+func (err CloudFrontInvalidationNotCompleteError) Error() string {
+	return fmt.Sprintf("Invalidation %s of CloudFront distribution %s is not complete yet (status %s)", err.invalidationID, err.distributionID, err.status)
+}
+
+// NewCloudFrontInvalidationNotCompleteError creates a new CloudFrontInvalidationNotCompleteError.
+func NewCloudFrontInvalidationNotCompleteError(distributionID string, invalidationID string, status string) CloudFrontInvalidationNotCompleteError {
+	return CloudFrontInvalidationNotCompleteError{distributionID, invalidationID, status}
+}
+
+// CloudFrontResponseMismatchError is returned when the response to a request through a CloudFront distribution doesn't
+// meet the expectation.
+type CloudFrontResponseMismatchError struct {
+	url      string
+	property string
+	expected string
+	actual   string
+}
+
+func (err CloudFrontResponseMismatchError) Error() string {
+	return fmt.Sprintf("Expected response to %s to have %s %q, but got %q", err.url, err.property, err.expected, err.actual)
+}
+
+// NewCloudFrontResponseMismatchError creates a new CloudFrontResponseMismatchError.
+func NewCloudFrontResponseMismatchError(url string, property string, expected string, actual string) CloudFrontResponseMismatchError {
+	return CloudFrontResponseMismatchError{url, property, expected, actual}
+}
+
+// ApiGatewayResponseMismatchError is returned when a request to an API Gateway API doesn't return the expected
+// response.
+type ApiGatewayResponseMismatchError struct {
+	path                  string
+	expectedStatusCode    int
+	expectedBodySubstring string
+	statusCode            int
+	body                  string
+	log                   string
+}
+
+func (err ApiGatewayResponseMismatchError) Error() string {
+	msg := fmt.Sprintf(
+		"Expected request for %s to return status code %d and a body containing %q, but got status code %d and body %q",
+		err.path,
+		err.expectedStatusCode,
+		err.expectedBodySubstring,
+		err.statusCode,
+		err.body,
+	)
+	if err.log != "" {
+		msg += fmt.Sprintf("\nExecution log:\n%s", err.log)
+	}
+	return msg
+}
+
+// NewApiGatewayResponseMismatchError creates a new ApiGatewayResponseMismatchError. The log is the execution log of the
+// request, if any.
+func NewApiGatewayResponseMismatchError(path string, expectedStatusCode int, expectedBodySubstring string, statusCode int, body string, log string) ApiGatewayResponseMismatchError {
+	return ApiGatewayResponseMismatchError{path, expectedStatusCode, expectedBodySubstring, statusCode, body, log}
+}
+
+// KinesisStreamNotActiveError is returned when a Kinesis data stream is not active.
+type KinesisStreamNotActiveError struct {
+	streamName string
+	status     string
+}
+
+func (err KinesisStreamNotActiveError) Error() string {
+	return fmt.Sprintf("Kinesis stream %s is not active (status %s)", err.streamName, err.status)
+}
+
+// NewKinesisStreamNotActiveError creates a new KinesisStreamNotActiveError.
+func NewKinesisStreamNotActiveError(streamName string, status string) KinesisStreamNotActiveError {
+	return KinesisStreamNotActiveError{streamName, status}
+}
+
+// KinesisConsumerNotActiveError is returned when an enhanced fan-out consumer of a Kinesis data stream is not active.
+type KinesisConsumerNotActiveError struct {
+	streamName   string
+	consumerName string
+	status       string
+}
+
+func (err KinesisConsumerNotActiveError) Error() string {
+	return fmt.Sprintf("Consumer %s of Kinesis stream %s is not active (status %s)", err.consumerName, err.streamName, err.status)
+}
+
+// NewKinesisConsumerNotActiveError creates a new KinesisConsumerNotActiveError.
+func NewKinesisConsumerNotActiveError(streamName string, consumerName string, status string) KinesisConsumerNotActiveError {
+	return KinesisConsumerNotActiveError{streamName, consumerName, status}
+}
+
+// KinesisRecordsNotFoundError is returned when a Kinesis data stream doesn't have the expected number of records.
+type KinesisRecordsNotFoundError struct {
+	streamName string
+	expected   int
+	actual     int
+}
+
+func (err KinesisRecordsNotFoundError) Error() string {
+	return fmt.Sprintf("Expected Kinesis stream %s to have at least %d records, but found %d", err.streamName, err.expected, err.actual)
+}
+
+// NewKinesisRecordsNotFoundError creates a new KinesisRecordsNotFoundError.
+func NewKinesisRecordsNotFoundError(streamName string, expected int, actual int) KinesisRecordsNotFoundError {
+	return KinesisRecordsNotFoundError{streamName, expected, actual}
+}
+
+// AthenaQueryNotSucceededError is returned when an Athena query hasn't succeeded.
+type AthenaQueryNotSucceededError struct {
+	queryExecutionID string
+	state            string
+	reason           string
+}
+
+func (err AthenaQueryNotSucceededError) Error() string {
+	msg := fmt.Sprintf("Athena query %s has not succeeded (state %s)", err.queryExecutionID, err.state)
+	if err.reason != "" {
+		msg += ": " + err.reason
+	}
+	return msg
+}
+
+// NewAthenaQueryNotSucceededError creates a new AthenaQueryNotSucceededError.
+func NewAthenaQueryNotSucceededError(queryExecutionID string, state string, reason string) AthenaQueryNotSucceededError {
+	return AthenaQueryNotSucceededError{queryExecutionID, state, reason}
+}
+
+// Ec2InstanceNotSpotError is returned when an EC2 instance is not a spot instance.
+type Ec2InstanceNotSpotError struct {
+	instanceID string
+}
+
+func (err Ec2InstanceNotSpotError) Error() string {
+	return fmt.Sprintf("EC2 instance %s is not a spot instance", err.instanceID)
+}
+
+// NewEc2InstanceNotSpotError creates a new Ec2InstanceNotSpotError.
+func NewEc2InstanceNotSpotError(instanceID string) Ec2InstanceNotSpotError {
+	return Ec2InstanceNotSpotError{instanceID}
+}
+
+// SpotRequestNotFulfilledError is returned when a spot instance request is not fulfilled.
+type SpotRequestNotFulfilledError struct {
+	requestID string
+	state     string
+	code      string
+	message   string
+}
+
+func (err SpotRequestNotFulfilledError) Error() string {
+	return fmt.Sprintf("Spot request %s is not fulfilled (state %s, status %s): %s", err.requestID, err.state, err.code, err.message)
+}
+
+// NewSpotRequestNotFulfilledError creates a new SpotRequestNotFulfilledError.
+func NewSpotRequestNotFulfilledError(requestID string, state string, code string, message string) SpotRequestNotFulfilledError {
+	return SpotRequestNotFulfilledError{requestID, state, code, message}
+}
+
+// FisExperimentNotCompletedError is returned when a Fault Injection Simulator experiment has not completed.
+type FisExperimentNotCompletedError struct {
+	experimentID string
+	status       string
+	reason       string
+}
+
+func (err FisExperimentNotCompletedError) Error() string {
+	msg := fmt.Sprintf("FIS experiment %s has not completed (status %s)", err.experimentID, err.status)
+	if err.reason != "" {
+		msg += ": " + err.reason
+	}
+	return msg
+}
+
+// NewFisExperimentNotCompletedError creates a new FisExperimentNotCompletedError.
+func NewFisExperimentNotCompletedError(experimentID string, status string, reason string) FisExperimentNotCompletedError {
+	return FisExperimentNotCompletedError{experimentID, status, reason}
+}
+
+// EfsLifeCycleStateError is returned when an EFS resource is not in the expected life cycle state.
+type EfsLifeCycleStateError struct {
+	resourceType  string
+	resourceID    string
+	expectedState string
+	actualState   string
+}
+
+func (err EfsLifeCycleStateError) Error() string {
+	return fmt.Sprintf("EFS %s %s is %s instead of %s", err.resourceType, err.resourceID, err.actualState, err.expectedState)
+}
+
+// NewEfsLifeCycleStateError creates a new EfsLifeCycleStateError.
+func NewEfsLifeCycleStateError(resourceType string, resourceID string, expectedState string, actualState string) EfsLifeCycleStateError {
+	return EfsLifeCycleStateError{resourceType, resourceID, expectedState, actualState}
+}
+
+// EfsMountTargetsNotAvailableError is returned when an EFS file system doesn't have an available mount target in some
+// subnets.
+type EfsMountTargetsNotAvailableError struct {
+	fileSystemID string
+	subnets      []string
+}
+
+func (err EfsMountTargetsNotAvailableError) Error() string {
+	return fmt.Sprintf("EFS file system %s has no available mount target in subnets %s", err.fileSystemID, strings.Join(err.subnets, ", "))
+}
+
+// NewEfsMountTargetsNotAvailableError creates a new EfsMountTargetsNotAvailableError.
+func NewEfsMountTargetsNotAvailableError(fileSystemID string, subnets []string) EfsMountTargetsNotAvailableError {
+	return EfsMountTargetsNotAvailableError{fileSystemID, subnets}
+}
+
+// EfsAccessPointMismatchError is returned when an EFS access point doesn't have the expected configuration.
+type EfsAccessPointMismatchError struct {
+	accessPointID string
+	expected      EfsAccessPointConfig
+	actual        EfsAccessPointConfig
+}
+
+func (err EfsAccessPointMismatchError) Error() string {
+	return fmt.Sprintf("EFS access point %s has configuration %s instead of %s", err.accessPointID, err.actual, err.expected)
+}
+
+// NewEfsAccessPointMismatchError creates a new EfsAccessPointMismatchError.
+func NewEfsAccessPointMismatchError(accessPointID string, expected EfsAccessPointConfig, actual EfsAccessPointConfig) EfsAccessPointMismatchError {
+	return EfsAccessPointMismatchError{accessPointID, expected, actual}
+}
+
+// EfsWriteCheckFailedError is returned when a file written to an EFS file system can't be read back.
+type EfsWriteCheckFailedError struct {
+	fileSystem string
+	writer     string
+	expected   string
+	output     string
+}
+
+func (err EfsWriteCheckFailedError) Error() string {
+	return fmt.Sprintf("%s could not write %q to EFS %s and read it back: got %q", err.writer, err.expected, err.fileSystem, err.output)
+}
+
+// NewEfsWriteCheckFailedError creates a new EfsWriteCheckFailedError.
+func NewEfsWriteCheckFailedError(fileSystem string, writer string, expected string, output string) EfsWriteCheckFailedError {
+	return EfsWriteCheckFailedError{fileSystem, writer, expected, output}
+}
+
+// MskClusterNotActiveError is returned when an MSK cluster is not active.
+type MskClusterNotActiveError struct {
+	clusterArn string
+	state      string
+	reason     string
+}
+
+func (err MskClusterNotActiveError) Error() string {
+	msg := fmt.Sprintf("MSK cluster %s is not active (state %s)", err.clusterArn, err.state)
+	if err.reason != "" {
+		msg += ": " + err.reason
+	}
+	return msg
+}
+
+// NewMskClusterNotActiveError creates a new MskClusterNotActiveError.
+func NewMskClusterNotActiveError(clusterArn string, state string, reason string) MskClusterNotActiveError {
+	return MskClusterNotActiveError{clusterArn, state, reason}
+}
+
+// MskSmokeTestFailedError is returned when a message produced to an MSK cluster can't be consumed back.
+type MskSmokeTestFailedError struct {
+	clusterArn string
+	topic      string
+	message    string
+	stdout     string
+	stderr     string
+}
+
+func (err MskSmokeTestFailedError) Error() string {
+	return fmt.Sprintf("Message %s produced to topic %s of MSK cluster %s was not consumed back.\nstdout: %s\nstderr: %s", err.message, err.topic, err.clusterArn, err.stdout, err.stderr)
+}
+
+// NewMskSmokeTestFailedError creates a new MskSmokeTestFailedError.
+func NewMskSmokeTestFailedError(clusterArn string, topic string, message string, stdout string, stderr string) MskSmokeTestFailedError {
+	return MskSmokeTestFailedError{clusterArn, topic, message, stdout, stderr}
+}
+
+// OpenSearchDomainNotReadyError is returned when an OpenSearch domain is not ready.
+type OpenSearchDomainNotReadyError struct {
+	domainName string
+	reason     string
+}
+
+func (err OpenSearchDomainNotReadyError) Error() string {
+	return fmt.Sprintf("OpenSearch domain %s is not ready: %s", err.domainName, err.reason)
+}
+
+// NewOpenSearchDomainNotReadyError creates a new OpenSearchDomainNotReadyError.
+func NewOpenSearchDomainNotReadyError(domainName string, reason string) OpenSearchDomainNotReadyError {
+	return OpenSearchDomainNotReadyError{domainName, reason}
+}
+
+// OpenSearchRequestFailedError is returned when a request to an OpenSearch domain doesn't have the expected response.
+type OpenSearchRequestFailedError struct {
+	method     string
+	path       string
+	statusCode int
+	body       string
+}
+
+func (err OpenSearchRequestFailedError) Error() string {
+	return fmt.Sprintf("OpenSearch request %s %s got unexpected response with status %d: %s", err.method, err.path, err.statusCode, err.body)
+}
+
+// NewOpenSearchRequestFailedError creates a new OpenSearchRequestFailedError.
+func NewOpenSearchRequestFailedError(method string, path string, statusCode int, body string) OpenSearchRequestFailedError {
+	return OpenSearchRequestFailedError{method, path, statusCode, body}
+}
+
+// CloudTrailEventNamesMismatchError is returned when the events recorded by CloudTrail are not the expected ones.
+type CloudTrailEventNamesMismatchError struct {
+	expected []string
+	actual   []string
+}
+
+func (err CloudTrailEventNamesMismatchError) Error() string {
+	return fmt.Sprintf("CloudTrail recorded events %v instead of %v", err.actual, err.expected)
+}
+
+// NewCloudTrailEventNamesMismatchError creates a new CloudTrailEventNamesMismatchError.
+func NewCloudTrailEventNamesMismatchError(expected []string, actual []string) CloudTrailEventNamesMismatchError {
+	return CloudTrailEventNamesMismatchError{expected, actual}
+}
+
+// ConfigRuleEvaluationFailedError is returned when an AWS Config rule has not been evaluated successfully.
+type ConfigRuleEvaluationFailedError struct {
+	ruleName  string
+	errorCode string
+	message   string
+}
+
+func (err ConfigRuleEvaluationFailedError) Error() string {
+	if err.errorCode != "" {
+		return fmt.Sprintf("AWS Config rule %s has not been evaluated successfully (%s): %s", err.ruleName, err.errorCode, err.message)
+	}
+	return fmt.Sprintf("AWS Config rule %s has not been evaluated successfully: %s", err.ruleName, err.message)
+}
+
+// NewConfigRuleEvaluationFailedError creates a new ConfigRuleEvaluationFailedError.
+func NewConfigRuleEvaluationFailedError(ruleName string, errorCode string, message string) ConfigRuleEvaluationFailedError {
+	return ConfigRuleEvaluationFailedError{ruleName, errorCode, message}
+}
+
+// ConfigResourceComplianceMismatchError is returned when an AWS Config rule doesn't evaluate a resource as expected.
+type ConfigResourceComplianceMismatchError struct {
+	ruleName           string
+	resourceID         string
+	expectedCompliance string
+	actualCompliance   string
+}
+
+func (err ConfigResourceComplianceMismatchError) Error() string {
+	return fmt.Sprintf("AWS Config rule %s evaluated resource %s as %s instead of %s", err.ruleName, err.resourceID, err.actualCompliance, err.expectedCompliance)
+}
+
+// NewConfigResourceComplianceMismatchError creates a new ConfigResourceComplianceMismatchError.
+func NewConfigResourceComplianceMismatchError(ruleName string, resourceID string, expectedCompliance string, actualCompliance string) ConfigResourceComplianceMismatchError {
+	return ConfigResourceComplianceMismatchError{ruleName, resourceID, expectedCompliance, actualCompliance}
+}
+
+// AcmCertificateNotIssuedError is returned when an ACM certificate is not issued.
+type AcmCertificateNotIssuedError struct {
+	certArn string
+	status  string
+	reason  string
+}
+
+func (err AcmCertificateNotIssuedError) Error() string {
+	msg := fmt.Sprintf("ACM certificate %s is not issued (status %s)", err.certArn, err.status)
+	if err.reason != "" {
+		msg += ": " + err.reason
+	}
+	return msg
+}
+
+// NewAcmCertificateNotIssuedError creates a new AcmCertificateNotIssuedError.
+func NewAcmCertificateNotIssuedError(certArn string, status string, reason string) AcmCertificateNotIssuedError {
+	return AcmCertificateNotIssuedError{certArn, status, reason}
+}
+
+// S3EventNotificationNotConfiguredError is returned when an S3 bucket doesn't send the event of an object upload to
+// any target of the expected type.
+type S3EventNotificationNotConfiguredError struct {
+	bucket string
+	key    string
+	target string
+}
+
+func (err S3EventNotificationNotConfiguredError) Error() string {
+	return fmt.Sprintf("Bucket %s does not notify any %s target of the upload of object %s", err.bucket, err.target, err.key)
+}
+
+// NewS3EventNotificationNotConfiguredError creates a new S3EventNotificationNotConfiguredError.
+func NewS3EventNotificationNotConfiguredError(bucket string, key string, target string) S3EventNotificationNotConfiguredError {
+	return S3EventNotificationNotConfiguredError{bucket, key, target}
+}
+
+// S3EventNotificationNotReceivedError is returned when the target of an S3 event notification doesn't receive it.
+type S3EventNotificationNotReceivedError struct {
+	bucket  string
+	key     string
+	target  string
+	timeout time.Duration
+}
+
+func (err S3EventNotificationNotReceivedError) Error() string {
+	return fmt.Sprintf("%s target of bucket %s did not receive the event of object %s within %s", err.target, err.bucket, err.key, err.timeout)
+}
+
+// NewS3EventNotificationNotReceivedError creates a new S3EventNotificationNotReceivedError.
+func NewS3EventNotificationNotReceivedError(bucket string, key string, target string, timeout time.Duration) S3EventNotificationNotReceivedError {
+	return S3EventNotificationNotReceivedError{bucket, key, target, timeout}
+}
@@ -0,0 +1,531 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// maxEventBridgePutEventsBatchSize is the maximum number of events that can be put in a single EventBridge API call.
+const maxEventBridgePutEventsBatchSize = 10
+
+// eventBridgeDeliveryPollSeconds is how long AssertRuleDeliversEvent waits for a delivered event after each put.
+const eventBridgeDeliveryPollSeconds = 10
+
+// EventBridgeEvent is a custom event to put on an EventBridge event bus.
+type EventBridgeEvent struct {
+	Source     string   // The source of the event (e.g., com.example.orders)
+	DetailType string   // The detail type of the event (e.g., OrderPlaced)
+	Detail     string   // The JSON detail of the event
+	Resources  []string // The ARNs of the resources the event is about, if any
+}
+
+// PutEventBridgeEvents puts the given events on the event bus with the given name, or on the default event bus if the
+// name is empty, and returns the IDs of the events.
+func PutEventBridgeEvents(t testing.TestingT, region string, eventBusName string, events []EventBridgeEvent) []string {
+	eventIDs, err := PutEventBridgeEventsE(t, region, eventBusName, events)
+	require.NoError(t, err)
+	return eventIDs
+}
+
+// PutEventBridgeEventsE puts the given events on the event bus with the given name, or on the default event bus if the
+// name is empty, and returns the IDs of the events. This returns an error if any of the events could not be put.
+func PutEventBridgeEventsE(t testing.TestingT, region string, eventBusName string, events []EventBridgeEvent) ([]string, error) {
+	logger.Logf(t, "Putting %d events on event bus %s", len(events), eventBusDisplayName(eventBusName))
+
+	client, err := NewEventBridgeClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	eventIDs := []string{}
+	for start := 0; start < len(events); start += maxEventBridgePutEventsBatchSize {
+		end := start + maxEventBridgePutEventsBatchSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		output, err := client.PutEvents(&eventbridge.PutEventsInput{
+			Entries: newPutEventsRequestEntries(eventBusName, events[start:end]),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		failures := map[string]string{}
+		for i, entry := range output.Entries {
+			if entry.ErrorCode != nil {
+				failures[strconv.Itoa(start+i)] = fmt.Sprintf("%s: %s", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage))
+				continue
+			}
+			eventIDs = append(eventIDs, aws.StringValue(entry.EventId))
+		}
+		if len(failures) > 0 {
+			return nil, EventBridgePutEventsFailed{EventBusName: eventBusDisplayName(eventBusName), Failures: failures}
+		}
+	}
+	return eventIDs, nil
+}
+
+func newPutEventsRequestEntries(eventBusName string, events []EventBridgeEvent) []*eventbridge.PutEventsRequestEntry {
+	entries := []*eventbridge.PutEventsRequestEntry{}
+	for _, event := range events {
+		entry := &eventbridge.PutEventsRequestEntry{
+			EventBusName: eventBusNameParam(eventBusName),
+			Source:       aws.String(event.Source),
+			DetailType:   aws.String(event.DetailType),
+			Detail:       aws.String(event.Detail),
+		}
+		if len(event.Resources) > 0 {
+			entry.Resources = aws.StringSlice(event.Resources)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// AssertEventMatchesRule checks that the event pattern of the given rule on the given event bus (or on the default event
+// bus if the name is empty) matches the given event, and fails the test if it does not.
+func AssertEventMatchesRule(t testing.TestingT, region string, eventBusName string, ruleName string, event EventBridgeEvent) {
+	err := AssertEventMatchesRuleE(t, region, eventBusName, ruleName, event)
+	require.NoError(t, err)
+}
+
+// AssertEventMatchesRuleE checks that the event pattern of the given rule on the given event bus (or on the default event
+// bus if the name is empty) matches the given event, and returns an error if it does not. This only tests the pattern,
+// so no event is put on the bus and no target is invoked.
+func AssertEventMatchesRuleE(t testing.TestingT, region string, eventBusName string, ruleName string, event EventBridgeEvent) error {
+	client, err := NewEventBridgeClientE(t, region)
+	if err != nil {
+		return err
+	}
+	rule, err := client.DescribeRule(&eventbridge.DescribeRuleInput{
+		Name:         aws.String(ruleName),
+		EventBusName: eventBusNameParam(eventBusName),
+	})
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(rule.EventPattern) == "" {
+		return EventBridgeRuleHasNoEventPattern{RuleName: ruleName}
+	}
+
+	accountID, err := GetAccountIdE(t)
+	if err != nil {
+		return err
+	}
+	testEvent, err := newTestEventJSON(event, accountID, region, time.Now())
+	if err != nil {
+		return err
+	}
+	output, err := client.TestEventPattern(&eventbridge.TestEventPatternInput{
+		Event:        aws.String(testEvent),
+		EventPattern: rule.EventPattern,
+	})
+	if err != nil {
+		return err
+	}
+	if !aws.BoolValue(output.Result) {
+		return EventBridgeRuleDoesNotMatchEvent{RuleName: ruleName, EventPattern: aws.StringValue(rule.EventPattern), Event: testEvent}
+	}
+	return nil
+}
+
+// newTestEventJSON returns the given event as the full JSON event EventBridge delivers to targets, which is the format
+// the TestEventPattern API expects.
+func newTestEventJSON(event EventBridgeEvent, accountID string, region string, eventTime time.Time) (string, error) {
+	detail := json.RawMessage("{}")
+	if event.Detail != "" {
+		detail = json.RawMessage(event.Detail)
+	}
+	resources := event.Resources
+	if resources == nil {
+		resources = []string{}
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	eventJSON, err := json.Marshal(map[string]interface{}{
+		"version":     "0",
+		"id":          id.String(),
+		"detail-type": event.DetailType,
+		"source":      event.Source,
+		"account":     accountID,
+		"time":        eventTime.UTC().Format(time.RFC3339),
+		"region":      region,
+		"resources":   resources,
+		"detail":      detail,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(eventJSON), nil
+}
+
+// AssertRuleDeliversEvent checks that the given event, when put on the given event bus (or on the default event bus if
+// the name is empty), is delivered by the given rule within the given timeout, and returns the delivered event as JSON.
+// This will fail the test if it is not.
+func AssertRuleDeliversEvent(t testing.TestingT, region string, eventBusName string, ruleName string, event EventBridgeEvent, timeout time.Duration) string {
+	delivered, err := AssertRuleDeliversEventE(t, region, eventBusName, ruleName, event, timeout)
+	require.NoError(t, err)
+	return delivered
+}
+
+// AssertRuleDeliversEventE checks that the given event, when put on the given event bus (or on the default event bus if
+// the name is empty), is delivered by the given rule within the given timeout, and returns the delivered event as JSON.
+//
+// To observe the delivery, this attaches a temporary SQS queue as an additional target of the rule, and removes the
+// target and deletes the queue before returning. As the new target can take a while to become active, the event is put
+// again until it is delivered, so the other targets of the rule may receive it several times.
+func AssertRuleDeliversEventE(t testing.TestingT, region string, eventBusName string, ruleName string, event EventBridgeEvent, timeout time.Duration) (string, error) {
+	client, err := NewEventBridgeClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	sqsClient, err := NewSqsClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+
+	rule, err := client.DescribeRule(&eventbridge.DescribeRuleInput{
+		Name:         aws.String(ruleName),
+		EventBusName: eventBusNameParam(eventBusName),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	queueURL, err := CreateRandomQueueE(t, region, "terratest-eventbridge")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := DeleteQueueE(t, region, queueURL); err != nil {
+			logger.Logf(t, "Failed to delete temporary queue %s: %v", queueURL, err)
+		}
+	}()
+
+	attributes, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return "", err
+	}
+	queueArn := aws.StringValue(attributes.Attributes[sqs.QueueAttributeNameQueueArn])
+	policy, err := newEventBridgeQueuePolicy(queueArn, aws.StringValue(rule.Arn))
+	if err != nil {
+		return "", err
+	}
+	_, err = sqsClient.SetQueueAttributes(&sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]*string{sqs.QueueAttributeNamePolicy: aws.String(policy)},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	targetID := "terratest-" + queueURL[strings.LastIndex(queueURL, "-")+1:]
+	logger.Logf(t, "Attaching temporary target %s for queue %s to rule %s", targetID, queueURL, ruleName)
+	targets, err := client.PutTargets(&eventbridge.PutTargetsInput{
+		Rule:         aws.String(ruleName),
+		EventBusName: eventBusNameParam(eventBusName),
+		Targets:      []*eventbridge.Target{{Id: aws.String(targetID), Arn: aws.String(queueArn)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if aws.Int64Value(targets.FailedEntryCount) > 0 {
+		failure := targets.FailedEntries[0]
+		return "", fmt.Errorf("Failed to attach temporary target to rule %s: %s: %s", ruleName, aws.StringValue(failure.ErrorCode), aws.StringValue(failure.ErrorMessage))
+	}
+	defer func() {
+		_, err := client.RemoveTargets(&eventbridge.RemoveTargetsInput{
+			Rule:         aws.String(ruleName),
+			EventBusName: eventBusNameParam(eventBusName),
+			Ids:          aws.StringSlice([]string{targetID}),
+		})
+		if err != nil {
+			logger.Logf(t, "Failed to remove temporary target %s from rule %s: %v", targetID, ruleName, err)
+		}
+	}()
+
+	sleepBetweenRetries := time.Duration(eventBridgeDeliveryPollSeconds) * time.Second
+	maxRetries := int(timeout/sleepBetweenRetries) + 1
+
+	var delivered string
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for rule %s to deliver event from %s.", ruleName, event.Source),
+		maxRetries,
+		0,
+		func() (string, error) {
+			if _, err := PutEventBridgeEventsE(t, region, eventBusName, []EventBridgeEvent{event}); err != nil {
+				return "", err
+			}
+			result, err := sqsClient.ReceiveMessage(&sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(queueURL),
+				MaxNumberOfMessages: aws.Int64(maxSqsBatchSize),
+				WaitTimeSeconds:     aws.Int64(eventBridgeDeliveryPollSeconds),
+			})
+			if err != nil {
+				return "", err
+			}
+			for _, message := range result.Messages {
+				if isDeliveredEvent(aws.StringValue(message.Body), event) {
+					delivered = aws.StringValue(message.Body)
+					return fmt.Sprintf("Rule %s delivered event from %s", ruleName, event.Source), nil
+				}
+			}
+			return "", EventBridgeEventNotDelivered{RuleName: ruleName, EventBusName: eventBusDisplayName(eventBusName)}
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return "", err
+	}
+	return delivered, nil
+}
+
+// newEventBridgeQueuePolicy returns an SQS queue policy that allows the given EventBridge rule to send messages to the
+// given queue.
+func newEventBridgeQueuePolicy(queueArn string, ruleArn string) (string, error) {
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":    "Allow",
+				"Principal": map[string]string{"Service": "events.amazonaws.com"},
+				"Action":    "sqs:SendMessage",
+				"Resource":  queueArn,
+				"Condition": map[string]interface{}{
+					"ArnEquals": map[string]string{"aws:SourceArn": ruleArn},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
+
+// isDeliveredEvent returns true if the given message body is an event with the source and detail type of the given
+// event.
+func isDeliveredEvent(body string, event EventBridgeEvent) bool {
+	var delivered struct {
+		Source     string `json:"source"`
+		DetailType string `json:"detail-type"`
+	}
+	if err := json.Unmarshal([]byte(body), &delivered); err != nil {
+		return false
+	}
+	return delivered.Source == event.Source && delivered.DetailType == event.DetailType
+}
+
+// ValidateScheduleExpression checks that the given expression is a valid EventBridge schedule expression (e.g.,
+// "rate(5 minutes)" or "cron(0 12 * * ? *)"), and fails the test if it is not.
+func ValidateScheduleExpression(t testing.TestingT, expression string) {
+	err := ValidateScheduleExpressionE(t, expression)
+	require.NoError(t, err)
+}
+
+// ValidateScheduleExpressionE checks that the given expression is a valid EventBridge schedule expression (e.g.,
+// "rate(5 minutes)" or "cron(0 12 * * ? *)"), and returns an error if it is not. This catches mistakes EventBridge
+// only reports when the rule is created, such as a cron expression with five fields, or "rate(1 minutes)".
+func ValidateScheduleExpressionE(t testing.TestingT, expression string) error {
+	return validateScheduleExpression(expression)
+}
+
+var (
+	rateExpressionRegexp = regexp.MustCompile(`^rate\((\d+) (minute|minutes|hour|hours|day|days)\)$`)
+	cronExpressionRegexp = regexp.MustCompile(`^cron\((.*)\)$`)
+	cronNumberRegexp     = regexp.MustCompile(`\d+`)
+)
+
+// cronField describes a field of an EventBridge cron expression.
+type cronField struct {
+	name     string
+	chars    *regexp.Regexp
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minutes", regexp.MustCompile(`^[0-9,\-*/]+$`), 0, 59},
+	{"hours", regexp.MustCompile(`^[0-9,\-*/]+$`), 0, 23},
+	{"day-of-month", regexp.MustCompile(`^[0-9,\-*?/LW]+$`), 1, 31},
+	{"month", regexp.MustCompile(`^[0-9A-Za-z,\-*/]+$`), 1, 12},
+	{"day-of-week", regexp.MustCompile(`^[0-9A-Za-z,\-*?/L#]+$`), 1, 7},
+	{"year", regexp.MustCompile(`^[0-9,\-*/]+$`), 1970, 2199},
+}
+
+func validateScheduleExpression(expression string) error {
+	if match := rateExpressionRegexp.FindStringSubmatch(expression); match != nil {
+		value, err := strconv.Atoi(match[1])
+		if err != nil || value <= 0 {
+			return ScheduleExpressionInvalid{Expression: expression, Reason: "the rate value must be a positive integer"}
+		}
+		if singular := !strings.HasSuffix(match[2], "s"); singular != (value == 1) {
+			return ScheduleExpressionInvalid{Expression: expression, Reason: "the rate unit must be singular if the value is 1, and plural otherwise"}
+		}
+		return nil
+	}
+
+	match := cronExpressionRegexp.FindStringSubmatch(expression)
+	if match == nil {
+		return ScheduleExpressionInvalid{Expression: expression, Reason: "the expression must be rate(value unit) or cron(fields)"}
+	}
+	fields := strings.Fields(match[1])
+	if len(fields) != len(cronFields) {
+		return ScheduleExpressionInvalid{Expression: expression, Reason: fmt.Sprintf("a cron expression must have %d fields, but it has %d", len(cronFields), len(fields))}
+	}
+	for i, field := range cronFields {
+		if !field.chars.MatchString(fields[i]) {
+			return ScheduleExpressionInvalid{Expression: expression, Reason: fmt.Sprintf("invalid %s field %q", field.name, fields[i])}
+		}
+		// Months and days of the week can also be given by name (e.g., JAN or MON), which the character check allows.
+		for _, number := range cronNumberRegexp.FindAllString(fields[i], -1) {
+			value, _ := strconv.Atoi(number)
+			if value < field.min || value > field.max {
+				return ScheduleExpressionInvalid{Expression: expression, Reason: fmt.Sprintf("%s value %d is not between %d and %d", field.name, value, field.min, field.max)}
+			}
+		}
+	}
+	if (fields[2] == "?") == (fields[4] == "?") {
+		return ScheduleExpressionInvalid{Expression: expression, Reason: "exactly one of the day-of-month and day-of-week fields must be ?"}
+	}
+	return nil
+}
+
+// AssertRuleScheduleExpression checks that the given rule on the default event bus has the given schedule expression,
+// and fails the test if it does not.
+func AssertRuleScheduleExpression(t testing.TestingT, region string, ruleName string, expectedExpression string) {
+	err := AssertRuleScheduleExpressionE(t, region, ruleName, expectedExpression)
+	require.NoError(t, err)
+}
+
+// AssertRuleScheduleExpressionE checks that the given rule on the default event bus has the given schedule expression,
+// and returns an error if it does not. Scheduled rules can only be created on the default event bus.
+func AssertRuleScheduleExpressionE(t testing.TestingT, region string, ruleName string, expectedExpression string) error {
+	if err := validateScheduleExpression(expectedExpression); err != nil {
+		return err
+	}
+	client, err := NewEventBridgeClientE(t, region)
+	if err != nil {
+		return err
+	}
+	rule, err := client.DescribeRule(&eventbridge.DescribeRuleInput{Name: aws.String(ruleName)})
+	if err != nil {
+		return err
+	}
+	if actual := aws.StringValue(rule.ScheduleExpression); actual != expectedExpression {
+		return ScheduleExpressionMismatch{RuleName: ruleName, Expected: expectedExpression, Actual: actual}
+	}
+	return nil
+}
+
+// eventBusNameParam returns the given event bus name as an API parameter, which is nil for the default event bus.
+func eventBusNameParam(eventBusName string) *string {
+	if eventBusName == "" {
+		return nil
+	}
+	return aws.String(eventBusName)
+}
+
+func eventBusDisplayName(eventBusName string) string {
+	if eventBusName == "" {
+		return "default"
+	}
+	return eventBusName
+}
+
+// NewEventBridgeClient creates an EventBridge client.
+func NewEventBridgeClient(t testing.TestingT, region string) *eventbridge.EventBridge {
+	client, err := NewEventBridgeClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewEventBridgeClientE creates an EventBridge client.
+func NewEventBridgeClientE(t testing.TestingT, region string) (*eventbridge.EventBridge, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return eventbridge.New(sess), nil
+}
+
+// EventBridgePutEventsFailed is an error that occurs if some of the events could not be put on an event bus.
+type EventBridgePutEventsFailed struct {
+	EventBusName string
+	Failures     map[string]string
+}
+
+func (err EventBridgePutEventsFailed) Error() string {
+	return fmt.Sprintf("Failed to put %d events on event bus %s: %v", len(err.Failures), err.EventBusName, err.Failures)
+}
+
+// EventBridgeRuleHasNoEventPattern is an error that occurs if a rule has no event pattern, such as a scheduled rule.
+type EventBridgeRuleHasNoEventPattern struct {
+	RuleName string
+}
+
+func (err EventBridgeRuleHasNoEventPattern) Error() string {
+	return fmt.Sprintf("Rule %s has no event pattern", err.RuleName)
+}
+
+// EventBridgeRuleDoesNotMatchEvent is an error that occurs if the event pattern of a rule does not match an event.
+type EventBridgeRuleDoesNotMatchEvent struct {
+	RuleName     string
+	EventPattern string
+	Event        string
+}
+
+func (err EventBridgeRuleDoesNotMatchEvent) Error() string {
+	return fmt.Sprintf("Event pattern %s of rule %s does not match event %s", err.EventPattern, err.RuleName, err.Event)
+}
+
+// EventBridgeEventNotDelivered is an error that occurs if a rule did not deliver an event.
+type EventBridgeEventNotDelivered struct {
+	RuleName     string
+	EventBusName string
+}
+
+func (err EventBridgeEventNotDelivered) Error() string {
+	return fmt.Sprintf("Rule %s on event bus %s did not deliver the event yet", err.RuleName, err.EventBusName)
+}
+
+// ScheduleExpressionInvalid is an error that occurs if a schedule expression is not valid.
+type ScheduleExpressionInvalid struct {
+	Expression string
+	Reason     string
+}
+
+func (err ScheduleExpressionInvalid) Error() string {
+	return fmt.Sprintf("Invalid schedule expression %q: %s", err.Expression, err.Reason)
+}
+
+// ScheduleExpressionMismatch is an error that occurs if a rule does not have the expected schedule expression.
+type ScheduleExpressionMismatch struct {
+	RuleName string
+	Expected string
+	Actual   string
+}
+
+func (err ScheduleExpressionMismatch) Error() string {
+	return fmt.Sprintf("Expected rule %s to have schedule expression %q, but it has %q", err.RuleName, err.Expected, err.Actual)
+}
@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateScheduleExpression(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expression string
+		valid      bool
+	}{
+		{"rate(1 minute)", true},
+		{"rate(5 minutes)", true},
+		{"rate(12 hours)", true},
+		{"rate(1 day)", true},
+		{"rate(1 minutes)", false},
+		{"rate(5 minute)", false},
+		{"rate(0 minutes)", false},
+		{"rate(5 weeks)", false},
+		{"cron(0 12 * * ? *)", true},
+		{"cron(0/15 8-17 ? * MON-FRI *)", true},
+		{"cron(0 10 L * ? 2030)", true},
+		{"cron(0 9 ? * 2#1 *)", true},
+		{"cron(0 12 * * *)", false},
+		{"cron(0 12 * * * *)", false},
+		{"cron(0 12 ? * ? *)", false},
+		{"cron(60 12 * * ? *)", false},
+		{"cron(0 24 * * ? *)", false},
+		{"cron(0 12 ? 13 MON *)", false},
+		{"cron(0 ? * * MON *)", false},
+		{"0 12 * * ? *", false},
+	}
+
+	for _, testCase := range testCases {
+		err := validateScheduleExpression(testCase.expression)
+		if testCase.valid {
+			assert.NoError(t, err, testCase.expression)
+		} else {
+			assert.IsType(t, ScheduleExpressionInvalid{}, err, testCase.expression)
+		}
+	}
+}
+
+func TestNewEventBridgeQueuePolicy(t *testing.T) {
+	t.Parallel()
+
+	queueArn := "arn:aws:sqs:us-east-1:123456789012:terratest-eventbridge"
+	ruleArn := "arn:aws:events:us-east-1:123456789012:rule/orders"
+	policy, err := newEventBridgeQueuePolicy(queueArn, ruleArn)
+	require.NoError(t, err)
+
+	parsed, err := parseS3BucketPolicy(policy)
+	require.NoError(t, err)
+	require.Len(t, parsed.Statement, 1)
+	statement := parsed.Statement[0]
+	assert.Equal(t, "Allow", statement.Effect)
+	assert.Equal(t, map[string]interface{}{"Service": "events.amazonaws.com"}, statement.Principal)
+	assert.Equal(t, policyStringList{"sqs:SendMessage"}, statement.Action)
+	assert.Equal(t, policyStringList{queueArn}, statement.Resource)
+	assert.Contains(t, policy, ruleArn)
+}
+
+func TestIsDeliveredEvent(t *testing.T) {
+	t.Parallel()
+
+	event := EventBridgeEvent{Source: "com.example.orders", DetailType: "OrderPlaced"}
+	assert.True(t, isDeliveredEvent(`{"source":"com.example.orders","detail-type":"OrderPlaced","detail":{}}`, event))
+	assert.False(t, isDeliveredEvent(`{"source":"com.example.orders","detail-type":"OrderShipped","detail":{}}`, event))
+	assert.False(t, isDeliveredEvent(`{"source":"aws.ec2","detail-type":"OrderPlaced"}`, event))
+	assert.False(t, isDeliveredEvent("not json", event))
+}
+
+func TestNewTestEventJSON(t *testing.T) {
+	t.Parallel()
+
+	eventTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	event := EventBridgeEvent{Source: "com.example.orders", DetailType: "OrderPlaced", Detail: `{"orderId":"42"}`}
+	eventJSON, err := newTestEventJSON(event, "123456789012", "us-east-1", eventTime)
+	require.NoError(t, err)
+
+	var parsed map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(eventJSON), &parsed))
+	assert.Equal(t, "com.example.orders", parsed["source"])
+	assert.Equal(t, "OrderPlaced", parsed["detail-type"])
+	assert.Equal(t, "123456789012", parsed["account"])
+	assert.Equal(t, "us-east-1", parsed["region"])
+	assert.Equal(t, "2021-06-01T12:00:00Z", parsed["time"])
+	assert.Equal(t, []interface{}{}, parsed["resources"])
+	assert.Equal(t, map[string]interface{}{"orderId": "42"}, parsed["detail"])
+	assert.NotEmpty(t, parsed["id"])
+}
+
+func TestNewPutEventsRequestEntries(t *testing.T) {
+	t.Parallel()
+
+	events := []EventBridgeEvent{
+		{Source: "com.example.orders", DetailType: "OrderPlaced", Detail: "{}"},
+		{Source: "com.example.orders", DetailType: "OrderShipped", Detail: "{}", Resources: []string{"arn:aws:s3:::bucket"}},
+	}
+
+	entries := newPutEventsRequestEntries("", events)
+	require.Len(t, entries, 2)
+	assert.Nil(t, entries[0].EventBusName)
+	assert.Nil(t, entries[0].Resources)
+	assert.Equal(t, []string{"arn:aws:s3:::bucket"}, aws.StringValueSlice(entries[1].Resources))
+
+	entries = newPutEventsRequestEntries("orders", events)
+	assert.Equal(t, "orders", aws.StringValue(entries[0].EventBusName))
+}
@@ -20,7 +20,7 @@ func GetIamCurrentUserName(t testing.TestingT) string {
 
 // GetIamCurrentUserNameE gets the username for the current IAM user.
 func GetIamCurrentUserNameE(t testing.TestingT) (string, error) {
-	iamClient, err := NewIamClientE(t, defaultRegion)
+	iamClient, err := NewIamClientE(t, getDefaultRegion())
 	if err != nil {
 		return "", err
 	}
@@ -44,7 +44,7 @@ func GetIamCurrentUserArn(t testing.TestingT) string {
 
 // GetIamCurrentUserArnE gets the ARN for the current IAM user.
 func GetIamCurrentUserArnE(t testing.TestingT) (string, error) {
-	iamClient, err := NewIamClientE(t, defaultRegion)
+	iamClient, err := NewIamClientE(t, getDefaultRegion())
 	if err != nil {
 		return "", err
 	}
@@ -144,7 +144,7 @@ func NewIamClient(t testing.TestingT, region string) *iam.IAM {
 
 // NewIamClientE creates a new IAM client.
 func NewIamClientE(t testing.TestingT, region string) (*iam.IAM, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,346 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// kinesisRecordsPollInterval is how often WaitForKinesisRecords reads new records from a stream.
+const kinesisRecordsPollInterval = 5 * time.Second
+
+// KinesisRecord is a record of a Kinesis data stream.
+type KinesisRecord struct {
+	ShardID        string
+	SequenceNumber string
+	PartitionKey   string
+	Data           []byte
+}
+
+// PutKinesisRecord puts a record with the given partition key and data on the given Kinesis data stream, and returns
+// the record with the shard and sequence number it was assigned.
+func PutKinesisRecord(t testing.TestingT, region string, streamName string, partitionKey string, data []byte) KinesisRecord {
+	record, err := PutKinesisRecordE(t, region, streamName, partitionKey, data)
+	require.NoError(t, err)
+	return record
+}
+
+// PutKinesisRecordE puts a record with the given partition key and data on the given Kinesis data stream, and returns
+// the record with the shard and sequence number it was assigned.
+func PutKinesisRecordE(t testing.TestingT, region string, streamName string, partitionKey string, data []byte) (KinesisRecord, error) {
+	logger.Logf(t, "Putting record with partition key %s on Kinesis stream %s", partitionKey, streamName)
+
+	client, err := NewKinesisClientE(t, region)
+	if err != nil {
+		return KinesisRecord{}, err
+	}
+	output, err := client.PutRecord(&kinesis.PutRecordInput{
+		StreamName:   aws.String(streamName),
+		PartitionKey: aws.String(partitionKey),
+		Data:         data,
+	})
+	if err != nil {
+		return KinesisRecord{}, err
+	}
+	return KinesisRecord{
+		ShardID:        aws.StringValue(output.ShardId),
+		SequenceNumber: aws.StringValue(output.SequenceNumber),
+		PartitionKey:   partitionKey,
+		Data:           data,
+	}, nil
+}
+
+// GetKinesisRecords reads the records of all the shards of the given Kinesis data stream, starting at the position
+// given by the shard iterator type (e.g., kinesis.ShardIteratorTypeTrimHorizon), until it catches up with the tip of
+// each shard.
+func GetKinesisRecords(t testing.TestingT, region string, streamName string, shardIteratorType string) []KinesisRecord {
+	records, err := GetKinesisRecordsE(t, region, streamName, shardIteratorType)
+	require.NoError(t, err)
+	return records
+}
+
+// GetKinesisRecordsE reads the records of all the shards of the given Kinesis data stream, starting at the position
+// given by the shard iterator type (e.g., kinesis.ShardIteratorTypeTrimHorizon), until it catches up with the tip of
+// each shard. The records are returned grouped by shard, in the order of the shards, and in order within each shard.
+func GetKinesisRecordsE(t testing.TestingT, region string, streamName string, shardIteratorType string) ([]KinesisRecord, error) {
+	client, err := NewKinesisClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newKinesisStreamReader(client, streamName, shardIteratorType)
+	if err != nil {
+		return nil, err
+	}
+	return reader.readAvailable()
+}
+
+// WaitForKinesisRecords waits until the given Kinesis data stream has at least the given number of records, reading
+// all the shards from their oldest record, and returns the records. This will fail the test if there are not enough
+// records within the given timeout.
+func WaitForKinesisRecords(t testing.TestingT, region string, streamName string, count int, timeout time.Duration) []KinesisRecord {
+	records, err := WaitForKinesisRecordsE(t, region, streamName, count, timeout)
+	require.NoError(t, err)
+	return records
+}
+
+// WaitForKinesisRecordsE waits until the given Kinesis data stream has at least the given number of records, reading
+// all the shards from their oldest record, and returns the records. This is useful to check that a producer (e.g., a
+// Lambda function or a CloudWatch Logs subscription) writes to the stream. Each shard is read from where the previous
+// try stopped, so records are only read once.
+func WaitForKinesisRecordsE(t testing.TestingT, region string, streamName string, count int, timeout time.Duration) ([]KinesisRecord, error) {
+	client, err := NewKinesisClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newKinesisStreamReader(client, streamName, kinesis.ShardIteratorTypeTrimHorizon)
+	if err != nil {
+		return nil, err
+	}
+
+	sleepBetweenRetries := kinesisRecordsPollInterval
+	if timeout < sleepBetweenRetries {
+		sleepBetweenRetries = timeout
+	}
+	maxRetries := 1
+	if sleepBetweenRetries > 0 {
+		maxRetries = int(timeout/sleepBetweenRetries) + 1
+	}
+
+	records := []KinesisRecord{}
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %d records on Kinesis stream %s.", count, streamName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			newRecords, err := reader.readAvailable()
+			if err != nil {
+				return "", err
+			}
+			records = append(records, newRecords...)
+			if len(records) < count {
+				return "", NewKinesisRecordsNotFoundError(streamName, count, len(records))
+			}
+			return fmt.Sprintf("Read %d records from Kinesis stream %s", len(records), streamName), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// kinesisStreamReader reads the records of all the shards of a Kinesis data stream, keeping track of the shard
+// iterator of each shard.
+type kinesisStreamReader struct {
+	client    *kinesis.Kinesis
+	shardIDs  []string
+	iterators map[string]*string
+}
+
+func newKinesisStreamReader(client *kinesis.Kinesis, streamName string, shardIteratorType string) (*kinesisStreamReader, error) {
+	shardIDs, err := listKinesisShardIDs(client, streamName)
+	if err != nil {
+		return nil, err
+	}
+	reader := &kinesisStreamReader{client: client, shardIDs: shardIDs, iterators: map[string]*string{}}
+	for _, shardID := range shardIDs {
+		output, err := client.GetShardIterator(&kinesis.GetShardIteratorInput{
+			StreamName:        aws.String(streamName),
+			ShardId:           aws.String(shardID),
+			ShardIteratorType: aws.String(shardIteratorType),
+		})
+		if err != nil {
+			return nil, err
+		}
+		reader.iterators[shardID] = output.ShardIterator
+	}
+	return reader, nil
+}
+
+func listKinesisShardIDs(client *kinesis.Kinesis, streamName string) ([]string, error) {
+	shardIDs := []string{}
+	input := &kinesis.ListShardsInput{StreamName: aws.String(streamName)}
+	for {
+		output, err := client.ListShards(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, shard := range output.Shards {
+			shardIDs = append(shardIDs, aws.StringValue(shard.ShardId))
+		}
+		if output.NextToken == nil {
+			return shardIDs, nil
+		}
+		// The stream name can't be given together with a next token.
+		input = &kinesis.ListShardsInput{NextToken: output.NextToken}
+	}
+}
+
+// readAvailable reads the records of each shard until it catches up with the tip of the shard, or until the end of the
+// shard if it is closed, and returns the records that were not read before.
+func (reader *kinesisStreamReader) readAvailable() ([]KinesisRecord, error) {
+	records := []KinesisRecord{}
+	for _, shardID := range reader.shardIDs {
+		for reader.iterators[shardID] != nil {
+			output, err := reader.client.GetRecords(&kinesis.GetRecordsInput{ShardIterator: reader.iterators[shardID]})
+			if err != nil {
+				return nil, err
+			}
+			reader.iterators[shardID] = output.NextShardIterator
+			for _, record := range output.Records {
+				records = append(records, KinesisRecord{
+					ShardID:        shardID,
+					SequenceNumber: aws.StringValue(record.SequenceNumber),
+					PartitionKey:   aws.StringValue(record.PartitionKey),
+					Data:           record.Data,
+				})
+			}
+			if aws.Int64Value(output.MillisBehindLatest) == 0 && len(output.Records) == 0 {
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+// WaitUntilKinesisStreamActive waits until the given Kinesis data stream is active, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. This will fail the test if the
+// stream doesn't become active in time.
+func WaitUntilKinesisStreamActive(t testing.TestingT, region string, streamName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilKinesisStreamActiveE(t, region, streamName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilKinesisStreamActiveE waits until the given Kinesis data stream is active, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. This stops waiting early if the
+// stream is being deleted.
+func WaitUntilKinesisStreamActiveE(t testing.TestingT, region string, streamName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	client, err := NewKinesisClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Kinesis stream %s to be active.", streamName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			output, err := client.DescribeStreamSummary(&kinesis.DescribeStreamSummaryInput{StreamName: aws.String(streamName)})
+			if err != nil {
+				return "", err
+			}
+			status := aws.StringValue(output.StreamDescriptionSummary.StreamStatus)
+			if err := checkKinesisStatusActive(status, kinesis.StreamStatusDeleting, NewKinesisStreamNotActiveError(streamName, status)); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Kinesis stream %s is now active", streamName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// GetKinesisStreamConsumer fetches the enhanced fan-out consumer with the given name of the given Kinesis data stream.
+func GetKinesisStreamConsumer(t testing.TestingT, region string, streamName string, consumerName string) *kinesis.ConsumerDescription {
+	consumer, err := GetKinesisStreamConsumerE(t, region, streamName, consumerName)
+	require.NoError(t, err)
+	return consumer
+}
+
+// GetKinesisStreamConsumerE fetches the enhanced fan-out consumer with the given name of the given Kinesis data stream.
+// An error of type NotFoundError is returned if the stream has no consumer with the name.
+func GetKinesisStreamConsumerE(t testing.TestingT, region string, streamName string, consumerName string) (*kinesis.ConsumerDescription, error) {
+	client, err := NewKinesisClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := client.DescribeStreamSummary(&kinesis.DescribeStreamSummaryInput{StreamName: aws.String(streamName)})
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeStreamConsumer(&kinesis.DescribeStreamConsumerInput{
+		StreamARN:    stream.StreamDescriptionSummary.StreamARN,
+		ConsumerName: aws.String(consumerName),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == kinesis.ErrCodeResourceNotFoundException {
+			return nil, NewNotFoundError("Kinesis stream consumer", consumerName, region)
+		}
+		return nil, err
+	}
+	return output.ConsumerDescription, nil
+}
+
+// WaitUntilKinesisStreamConsumerActive waits until the enhanced fan-out consumer with the given name of the given
+// Kinesis data stream is registered and active, retrying the check for the specified amount of times, sleeping for the
+// provided duration between each try. This will fail the test if the consumer doesn't become active in time.
+func WaitUntilKinesisStreamConsumerActive(t testing.TestingT, region string, streamName string, consumerName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilKinesisStreamConsumerActiveE(t, region, streamName, consumerName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilKinesisStreamConsumerActiveE waits until the enhanced fan-out consumer with the given name of the given
+// Kinesis data stream is registered and active, retrying the check for the specified amount of times, sleeping for the
+// provided duration between each try. Consumers can only subscribe to shards once they are active. This stops waiting
+// early if the consumer is being deregistered.
+func WaitUntilKinesisStreamConsumerActiveE(t testing.TestingT, region string, streamName string, consumerName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for consumer %s of Kinesis stream %s to be active.", consumerName, streamName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			consumer, err := GetKinesisStreamConsumerE(t, region, streamName, consumerName)
+			if err != nil {
+				return "", err
+			}
+			status := aws.StringValue(consumer.ConsumerStatus)
+			if err := checkKinesisStatusActive(status, kinesis.ConsumerStatusDeleting, NewKinesisConsumerNotActiveError(streamName, consumerName, status)); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Consumer %s of Kinesis stream %s is now active", consumerName, streamName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkKinesisStatusActive returns the given error if the given status of a stream or consumer is not active. The error
+// is wrapped in a retry.FatalError if the status is the deleting status, as the stream or consumer won't become active
+// anymore.
+func checkKinesisStatusActive(status string, deletingStatus string, notActiveErr error) error {
+	switch status {
+	case kinesis.StreamStatusActive:
+		return nil
+	case deletingStatus:
+		return retry.FatalError{Underlying: notActiveErr}
+	default:
+		return notActiveErr
+	}
+}
+
+// NewKinesisClient creates a Kinesis client.
+func NewKinesisClient(t testing.TestingT, region string) *kinesis.Kinesis {
+	client, err := NewKinesisClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewKinesisClientE creates a Kinesis client.
+func NewKinesisClientE(t testing.TestingT, region string) (*kinesis.Kinesis, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return kinesis.New(sess), nil
+}
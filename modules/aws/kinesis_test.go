@@ -0,0 +1,21 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckKinesisStatusActive(t *testing.T) {
+	t.Parallel()
+
+	notActiveErr := NewKinesisStreamNotActiveError("orders", "")
+
+	assert.NoError(t, checkKinesisStatusActive(kinesis.StreamStatusActive, kinesis.StreamStatusDeleting, notActiveErr))
+	assert.NoError(t, checkKinesisStatusActive(kinesis.ConsumerStatusActive, kinesis.ConsumerStatusDeleting, notActiveErr))
+	assert.Equal(t, notActiveErr, checkKinesisStatusActive(kinesis.StreamStatusCreating, kinesis.StreamStatusDeleting, notActiveErr))
+	assert.Equal(t, notActiveErr, checkKinesisStatusActive(kinesis.StreamStatusUpdating, kinesis.StreamStatusDeleting, notActiveErr))
+	assert.Equal(t, retry.FatalError{Underlying: notActiveErr}, checkKinesisStatusActive(kinesis.StreamStatusDeleting, kinesis.StreamStatusDeleting, notActiveErr))
+}
@@ -1,9 +1,16 @@
 package aws
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
 )
 
 // GetCmkArn gets the ARN of a KMS Customer Master Key (CMK) in the given region with the given ID. The ID can be an alias, such
@@ -35,6 +42,324 @@ func GetCmkArnE(t testing.TestingT, region string, cmkID string) (string, error)
 	return *result.KeyMetadata.Arn, nil
 }
 
+// EncryptWithKms encrypts the given plaintext with the given KMS key and returns the ciphertext.
+func EncryptWithKms(t testing.TestingT, region string, cmkID string, plaintext []byte) []byte {
+	ciphertext, err := EncryptWithKmsE(t, region, cmkID, plaintext)
+	require.NoError(t, err)
+	return ciphertext
+}
+
+// EncryptWithKmsE encrypts the given plaintext with the given KMS key and returns the ciphertext.
+func EncryptWithKmsE(t testing.TestingT, region string, cmkID string, plaintext []byte) ([]byte, error) {
+	kmsClient, err := NewKmsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	result, err := kmsClient.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(cmkID),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.CiphertextBlob, nil
+}
+
+// DecryptWithKms decrypts the given ciphertext, which must have been encrypted with a symmetric KMS key, and returns
+// the plaintext.
+func DecryptWithKms(t testing.TestingT, region string, ciphertext []byte) []byte {
+	plaintext, err := DecryptWithKmsE(t, region, ciphertext)
+	require.NoError(t, err)
+	return plaintext
+}
+
+// DecryptWithKmsE decrypts the given ciphertext, which must have been encrypted with a symmetric KMS key, and returns
+// the plaintext.
+func DecryptWithKmsE(t testing.TestingT, region string, ciphertext []byte) ([]byte, error) {
+	kmsClient, err := NewKmsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	result, err := kmsClient.Decrypt(&kms.DecryptInput{CiphertextBlob: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	return result.Plaintext, nil
+}
+
+// AssertKmsEncryptDecryptRoundtrip encrypts random plaintext with the given KMS key, decrypts it again, and fails the
+// test if that fails or the decrypted plaintext is different.
+func AssertKmsEncryptDecryptRoundtrip(t testing.TestingT, region string, cmkID string) {
+	err := AssertKmsEncryptDecryptRoundtripE(t, region, cmkID)
+	require.NoError(t, err)
+}
+
+// AssertKmsEncryptDecryptRoundtripE encrypts random plaintext with the given KMS key, decrypts it again, and returns an
+// error if that fails or the decrypted plaintext is different. This checks that the current IAM identity may both
+// encrypt and decrypt with the key.
+func AssertKmsEncryptDecryptRoundtripE(t testing.TestingT, region string, cmkID string) error {
+	logger.Logf(t, "Encrypting and decrypting random plaintext with KMS key %s", cmkID)
+
+	plaintext := []byte("terratest-" + random.UniqueId())
+	ciphertext, err := EncryptWithKmsE(t, region, cmkID, plaintext)
+	if err != nil {
+		return err
+	}
+	decrypted, err := DecryptWithKmsE(t, region, ciphertext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		return NewKmsRoundtripMismatchError(cmkID, "decrypted plaintext")
+	}
+	return nil
+}
+
+// GenerateKmsDataKey generates an AES-256 data key with the given KMS key, and returns the plaintext key and the key
+// encrypted with the KMS key.
+func GenerateKmsDataKey(t testing.TestingT, region string, cmkID string) ([]byte, []byte) {
+	plaintextKey, encryptedKey, err := GenerateKmsDataKeyE(t, region, cmkID)
+	require.NoError(t, err)
+	return plaintextKey, encryptedKey
+}
+
+// GenerateKmsDataKeyE generates an AES-256 data key with the given KMS key, and returns the plaintext key and the key
+// encrypted with the KMS key.
+func GenerateKmsDataKeyE(t testing.TestingT, region string, cmkID string) ([]byte, []byte, error) {
+	kmsClient, err := NewKmsClientE(t, region)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := kmsClient.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(cmkID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Plaintext, result.CiphertextBlob, nil
+}
+
+// AssertKmsDataKeyRoundtrip generates a data key with the given KMS key, decrypts the encrypted copy of the data key,
+// and fails the test if that fails or the decrypted data key is different.
+func AssertKmsDataKeyRoundtrip(t testing.TestingT, region string, cmkID string) {
+	err := AssertKmsDataKeyRoundtripE(t, region, cmkID)
+	require.NoError(t, err)
+}
+
+// AssertKmsDataKeyRoundtripE generates a data key with the given KMS key, decrypts the encrypted copy of the data key,
+// and returns an error if that fails or the decrypted data key is different. This is how envelope encryption (e.g., by
+// S3 or EBS) uses the key.
+func AssertKmsDataKeyRoundtripE(t testing.TestingT, region string, cmkID string) error {
+	logger.Logf(t, "Generating and decrypting a data key with KMS key %s", cmkID)
+
+	plaintextKey, encryptedKey, err := GenerateKmsDataKeyE(t, region, cmkID)
+	if err != nil {
+		return err
+	}
+	decryptedKey, err := DecryptWithKmsE(t, region, encryptedKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintextKey, decryptedKey) {
+		return NewKmsRoundtripMismatchError(cmkID, "decrypted data key")
+	}
+	return nil
+}
+
+// GetKmsKeyGrants gets the grants of the given KMS key.
+func GetKmsKeyGrants(t testing.TestingT, region string, cmkID string) []*kms.GrantListEntry {
+	grants, err := GetKmsKeyGrantsE(t, region, cmkID)
+	require.NoError(t, err)
+	return grants
+}
+
+// GetKmsKeyGrantsE gets the grants of the given KMS key.
+func GetKmsKeyGrantsE(t testing.TestingT, region string, cmkID string) ([]*kms.GrantListEntry, error) {
+	kmsClient, err := NewKmsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	grants := []*kms.GrantListEntry{}
+	err = kmsClient.ListGrantsPages(&kms.ListGrantsInput{KeyId: aws.String(cmkID)}, func(output *kms.ListGrantsResponse, lastPage bool) bool {
+		grants = append(grants, output.Grants...)
+		return true
+	})
+	return grants, err
+}
+
+// AssertKmsKeyHasGrant checks that the given KMS key has a grant for the given grantee principal that allows all of the
+// given operations (e.g., kms.GrantOperationDecrypt), and fails the test if it does not.
+func AssertKmsKeyHasGrant(t testing.TestingT, region string, cmkID string, granteePrincipal string, operations []string) {
+	err := AssertKmsKeyHasGrantE(t, region, cmkID, granteePrincipal, operations)
+	require.NoError(t, err)
+}
+
+// AssertKmsKeyHasGrantE checks that the given KMS key has a grant for the given grantee principal that allows all of
+// the given operations (e.g., kms.GrantOperationDecrypt), and returns an error if it does not.
+func AssertKmsKeyHasGrantE(t testing.TestingT, region string, cmkID string, granteePrincipal string, operations []string) error {
+	grants, err := GetKmsKeyGrantsE(t, region, cmkID)
+	if err != nil {
+		return err
+	}
+	if findKmsGrant(grants, granteePrincipal, operations) == nil {
+		return NewKmsKeyConfigMismatchError(cmkID, "grants", fmt.Sprintf("a grant of %v to %s", operations, granteePrincipal), "none")
+	}
+	return nil
+}
+
+// findKmsGrant returns the first of the given grants for the given grantee principal that allows all of the given
+// operations, or nil if there is none.
+func findKmsGrant(grants []*kms.GrantListEntry, granteePrincipal string, operations []string) *kms.GrantListEntry {
+	for _, grant := range grants {
+		if aws.StringValue(grant.GranteePrincipal) != granteePrincipal {
+			continue
+		}
+		allowed := map[string]bool{}
+		for _, operation := range grant.Operations {
+			allowed[aws.StringValue(operation)] = true
+		}
+		allowsAll := true
+		for _, operation := range operations {
+			allowsAll = allowsAll && allowed[operation]
+		}
+		if allowsAll {
+			return grant
+		}
+	}
+	return nil
+}
+
+// AssertKmsKeyRotationEnabled checks that automatic rotation is enabled for the given KMS key, and fails the test if it
+// is not.
+func AssertKmsKeyRotationEnabled(t testing.TestingT, region string, cmkID string) {
+	err := AssertKmsKeyRotationEnabledE(t, region, cmkID)
+	require.NoError(t, err)
+}
+
+// AssertKmsKeyRotationEnabledE checks that automatic rotation is enabled for the given KMS key, and returns an error if
+// it is not. The key must be a symmetric customer managed key, and cmkID must be a key ID or ARN rather than an alias, as
+// required by the KMS API.
+func AssertKmsKeyRotationEnabledE(t testing.TestingT, region string, cmkID string) error {
+	kmsClient, err := NewKmsClientE(t, region)
+	if err != nil {
+		return err
+	}
+	result, err := kmsClient.GetKeyRotationStatus(&kms.GetKeyRotationStatusInput{KeyId: aws.String(cmkID)})
+	if err != nil {
+		return err
+	}
+	if !aws.BoolValue(result.KeyRotationEnabled) {
+		return NewKmsKeyConfigMismatchError(cmkID, "key rotation", "enabled", "disabled")
+	}
+	return nil
+}
+
+// GetKmsKeyPolicy gets the key policy of the given KMS key.
+func GetKmsKeyPolicy(t testing.TestingT, region string, cmkID string) string {
+	policy, err := GetKmsKeyPolicyE(t, region, cmkID)
+	require.NoError(t, err)
+	return policy
+}
+
+// GetKmsKeyPolicyE gets the key policy of the given KMS key.
+func GetKmsKeyPolicyE(t testing.TestingT, region string, cmkID string) (string, error) {
+	kmsClient, err := NewKmsClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	// The default policy is the only key policy a KMS key can have.
+	result, err := kmsClient.GetKeyPolicy(&kms.GetKeyPolicyInput{
+		KeyId:      aws.String(cmkID),
+		PolicyName: aws.String("default"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(result.Policy), nil
+}
+
+// AssertKmsKeyPolicyAllows checks that the key policy of the given KMS key has a statement that allows the given AWS
+// principal (e.g., the ARN of an IAM role) to perform the given action (e.g., "kms:Decrypt"), and fails the test if it
+// does not.
+func AssertKmsKeyPolicyAllows(t testing.TestingT, region string, cmkID string, principal string, action string) {
+	err := AssertKmsKeyPolicyAllowsE(t, region, cmkID, principal, action)
+	require.NoError(t, err)
+}
+
+// AssertKmsKeyPolicyAllowsE checks that the key policy of the given KMS key has a statement that allows the given AWS
+// principal (e.g., the ARN of an IAM role) to perform the given action (e.g., "kms:Decrypt"), and returns an error if it
+// does not. Statements match if they list the principal (or "*") and an action pattern that matches the action (e.g.,
+// "kms:*"). Conditions are ignored, and this doesn't check whether another statement denies the action.
+func AssertKmsKeyPolicyAllowsE(t testing.TestingT, region string, cmkID string, principal string, action string) error {
+	policy, err := GetKmsKeyPolicyE(t, region, cmkID)
+	if err != nil {
+		return err
+	}
+	allows, err := kmsKeyPolicyAllows(policy, principal, action)
+	if err != nil {
+		return err
+	}
+	if !allows {
+		return NewKmsKeyConfigMismatchError(cmkID, "key policy", fmt.Sprintf("a statement that allows %s to %s", principal, action), "none")
+	}
+	return nil
+}
+
+// kmsKeyPolicyStatement is a statement of a KMS key policy.
+type kmsKeyPolicyStatement struct {
+	Effect    string
+	Principal interface{}
+	Action    policyStringList
+}
+
+// kmsKeyPolicyAllows returns true if the given key policy has a statement that allows the given AWS principal to
+// perform the given action.
+func kmsKeyPolicyAllows(policyJSON string, principal string, action string) (bool, error) {
+	policy := struct {
+		Statement []kmsKeyPolicyStatement
+	}{}
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return false, err
+	}
+
+	for _, statement := range policy.Statement {
+		if statement.Effect != "Allow" || !matchesAnyWildcardPattern(statement.Action, action, true) {
+			continue
+		}
+		for _, statementPrincipal := range getAwsPolicyPrincipals(statement.Principal) {
+			if statementPrincipal == "*" || statementPrincipal == principal {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// getAwsPolicyPrincipals returns the AWS principals of the given policy statement principal, which is either "*" or an
+// object that maps principal types to a principal or a list of them.
+func getAwsPolicyPrincipals(principal interface{}) []string {
+	switch value := principal.(type) {
+	case string:
+		return []string{value}
+	case map[string]interface{}:
+		principals := []string{}
+		switch awsPrincipals := value["AWS"].(type) {
+		case string:
+			principals = append(principals, awsPrincipals)
+		case []interface{}:
+			for _, awsPrincipal := range awsPrincipals {
+				if awsPrincipalString, isString := awsPrincipal.(string); isString {
+					principals = append(principals, awsPrincipalString)
+				}
+			}
+		}
+		return principals
+	default:
+		return nil
+	}
+}
+
 // NewKmsClient creates a KMS client.
 func NewKmsClient(t testing.TestingT, region string) *kms.KMS {
 	client, err := NewKmsClientE(t, region)
@@ -46,7 +371,7 @@ func NewKmsClient(t testing.TestingT, region string) *kms.KMS {
 
 // NewKmsClientE creates a KMS client.
 func NewKmsClientE(t testing.TestingT, region string) (*kms.KMS, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKmsKeyPolicyAllows(t *testing.T) {
+	t.Parallel()
+
+	policy := `{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Sid": "Root", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::111111111111:root"}, "Action": "kms:*", "Resource": "*"},
+			{"Sid": "App", "Effect": "Allow", "Principal": {"AWS": ["arn:aws:iam::111111111111:role/app"]}, "Action": ["kms:Decrypt", "kms:GenerateDataKey*"], "Resource": "*"},
+			{"Sid": "Deny", "Effect": "Deny", "Principal": "*", "Action": "kms:ScheduleKeyDeletion", "Resource": "*"},
+			{"Sid": "Service", "Effect": "Allow", "Principal": {"Service": "logs.amazonaws.com"}, "Action": "kms:Encrypt", "Resource": "*"}
+		]
+	}`
+
+	testCases := []struct {
+		principal string
+		action    string
+		expected  bool
+	}{
+		{"arn:aws:iam::111111111111:root", "kms:PutKeyPolicy", true},
+		{"arn:aws:iam::111111111111:role/app", "kms:decrypt", true},
+		{"arn:aws:iam::111111111111:role/app", "kms:GenerateDataKeyWithoutPlaintext", true},
+		{"arn:aws:iam::111111111111:role/app", "kms:Encrypt", false},
+		{"arn:aws:iam::111111111111:role/other", "kms:ScheduleKeyDeletion", false},
+		{"logs.amazonaws.com", "kms:Encrypt", false},
+	}
+	for _, testCase := range testCases {
+		allows, err := kmsKeyPolicyAllows(policy, testCase.principal, testCase.action)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, allows, "%s %s", testCase.principal, testCase.action)
+	}
+}
+
+func TestFindKmsGrant(t *testing.T) {
+	t.Parallel()
+
+	grants := []*kms.GrantListEntry{
+		{GrantId: aws.String("1"), GranteePrincipal: aws.String("role-a"), Operations: aws.StringSlice([]string{kms.GrantOperationEncrypt})},
+		{GrantId: aws.String("2"), GranteePrincipal: aws.String("role-b"), Operations: aws.StringSlice([]string{kms.GrantOperationEncrypt, kms.GrantOperationDecrypt})},
+	}
+
+	grant := findKmsGrant(grants, "role-b", []string{kms.GrantOperationDecrypt, kms.GrantOperationEncrypt})
+	require.NotNil(t, grant)
+	assert.Equal(t, "2", aws.StringValue(grant.GrantId))
+	assert.Nil(t, findKmsGrant(grants, "role-a", []string{kms.GrantOperationDecrypt}))
+	assert.Nil(t, findKmsGrant(grants, "role-c", nil))
+}
@@ -1,11 +1,17 @@
 package aws
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -173,6 +179,176 @@ func (err *FunctionError) Error() string {
 	return fmt.Sprintf("%q error with status code %d invoking lambda function: %q", err.Message, err.StatusCode, err.Payload)
 }
 
+// LambdaInvocationResult contains the result of invoking a lambda function with InvokeLambdaWithPayload.
+type LambdaInvocationResult struct {
+	// The HTTP status code of the invocation, which is 200 for a successful synchronous invocation, even if the
+	// function itself returned an error.
+	StatusCode int64
+
+	// The type of error returned by the function (e.g., "Unhandled"), or empty if the function succeeded.
+	FunctionError string
+
+	// The version of the function that was invoked.
+	ExecutedVersion string
+
+	// The response from the function, or an error object if FunctionError is set.
+	Payload []byte
+
+	// The last 4 KB of the logs of the invocation.
+	LogTail string
+}
+
+// DecodePayload unmarshals the JSON payload returned by the function into the given value.
+func (result *LambdaInvocationResult) DecodePayload(value interface{}) error {
+	return json.Unmarshal(result.Payload, value)
+}
+
+// InvokeLambdaWithPayload synchronously invokes a lambda function with the given payload (which is converted to JSON)
+// and returns the result of the invocation, including the tail of its logs. Unlike InvokeFunction, this does not fail
+// the test if the function returns an error, so that you can make assertions about the FunctionError and Payload of the
+// result. This will fail the test if the function can't be invoked.
+func InvokeLambdaWithPayload(t testing.TestingT, region string, functionName string, payload interface{}) *LambdaInvocationResult {
+	result, err := InvokeLambdaWithPayloadE(t, region, functionName, payload)
+	require.NoError(t, err)
+	return result
+}
+
+// InvokeLambdaWithPayloadE synchronously invokes a lambda function with the given payload (which is converted to JSON)
+// and returns the result of the invocation, including the tail of its logs. Errors returned by the function itself are
+// reported in the FunctionError of the result rather than as an error.
+func InvokeLambdaWithPayloadE(t testing.TestingT, region string, functionName string, payload interface{}) (*LambdaInvocationResult, error) {
+	lambdaClient, err := NewLambdaClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	invokeInput := &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		InvocationType: aws.String(lambda.InvocationTypeRequestResponse),
+		LogType:        aws.String(lambda.LogTypeTail),
+	}
+	if payload != nil {
+		payloadJson, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		invokeInput.Payload = payloadJson
+	}
+
+	out, err := lambdaClient.Invoke(invokeInput)
+	if err != nil {
+		return nil, err
+	}
+	return newLambdaInvocationResult(out)
+}
+
+// newLambdaInvocationResult converts the output of invoking a lambda function into a LambdaInvocationResult.
+func newLambdaInvocationResult(out *lambda.InvokeOutput) (*LambdaInvocationResult, error) {
+	logTail, err := base64.StdEncoding.DecodeString(aws.StringValue(out.LogResult))
+	if err != nil {
+		return nil, err
+	}
+	return &LambdaInvocationResult{
+		StatusCode:      aws.Int64Value(out.StatusCode),
+		FunctionError:   aws.StringValue(out.FunctionError),
+		ExecutedVersion: aws.StringValue(out.ExecutedVersion),
+		Payload:         out.Payload,
+		LogTail:         string(logTail),
+	}, nil
+}
+
+// WaitUntilLambdaUpdated waits until the latest code or configuration update of the given lambda function has been
+// applied, so that it can be invoked, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try.
+func WaitUntilLambdaUpdated(t testing.TestingT, region string, functionName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilLambdaUpdatedE(t, region, functionName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilLambdaUpdatedE waits until the latest code or configuration update of the given lambda function has been
+// applied, so that it can be invoked, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. This stops waiting early if the update failed.
+func WaitUntilLambdaUpdatedE(t testing.TestingT, region string, functionName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	lambdaClient, err := NewLambdaClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for lambda function %s to be updated.", functionName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			configuration, err := lambdaClient.GetFunctionConfiguration(&lambda.GetFunctionConfigurationInput{
+				FunctionName: aws.String(functionName),
+			})
+			if err != nil {
+				return "", err
+			}
+			if err := checkLambdaUpdated(functionName, configuration); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Lambda function %s is updated", functionName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkLambdaUpdated returns an error if the latest update of the given lambda function configuration has not been
+// applied yet. If the update failed, the error is wrapped in a retry.FatalError, as it will never be applied.
+func checkLambdaUpdated(functionName string, configuration *lambda.FunctionConfiguration) error {
+	state := aws.StringValue(configuration.State)
+	lastUpdateStatus := aws.StringValue(configuration.LastUpdateStatus)
+
+	if state == lambda.StateFailed {
+		return retry.FatalError{Underlying: NewLambdaNotUpdatedError(functionName, state, lastUpdateStatus, aws.StringValue(configuration.StateReason))}
+	}
+	if lastUpdateStatus == lambda.LastUpdateStatusFailed {
+		return retry.FatalError{Underlying: NewLambdaNotUpdatedError(functionName, state, lastUpdateStatus, aws.StringValue(configuration.LastUpdateStatusReason))}
+	}
+	// Functions that were never updated may not report a state or update status.
+	if (state != "" && state != lambda.StateActive) || (lastUpdateStatus != "" && lastUpdateStatus != lambda.LastUpdateStatusSuccessful) {
+		return NewLambdaNotUpdatedError(functionName, state, lastUpdateStatus, "")
+	}
+	return nil
+}
+
+// GetLambdaRecentLogEvents returns the messages the given lambda function logged to its CloudWatch log group
+// (/aws/lambda/<functionName>) within the given duration before now, across all its log streams.
+func GetLambdaRecentLogEvents(t testing.TestingT, region string, functionName string, since time.Duration) []string {
+	messages, err := GetLambdaRecentLogEventsE(t, region, functionName, since)
+	require.NoError(t, err)
+	return messages
+}
+
+// GetLambdaRecentLogEventsE returns the messages the given lambda function logged to its CloudWatch log group
+// (/aws/lambda/<functionName>) within the given duration before now, across all its log streams. Note that log events
+// may take a few seconds to show up in CloudWatch after the function has run.
+func GetLambdaRecentLogEventsE(t testing.TestingT, region string, functionName string, since time.Duration) ([]string, error) {
+	client, err := NewCloudWatchLogsClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(fmt.Sprintf("/aws/lambda/%s", functionName)),
+		StartTime:    aws.Int64(time.Now().Add(-since).UnixNano() / int64(time.Millisecond)),
+	}
+	messages := []string{}
+	err = client.FilterLogEventsPages(input, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+		for _, event := range page.Events {
+			messages = append(messages, aws.StringValue(event.Message))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
 // NewLambdaClient creates a new Lambda client.
 func NewLambdaClient(t testing.TestingT, region string) *lambda.Lambda {
 	client, err := NewLambdaClientE(t, region)
@@ -182,7 +358,7 @@ func NewLambdaClient(t testing.TestingT, region string) *lambda.Lambda {
 
 // NewLambdaClientE creates a new Lambda client.
 func NewLambdaClientE(t testing.TestingT, region string) (*lambda.Lambda, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
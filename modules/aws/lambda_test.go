@@ -1,8 +1,13 @@
 package aws
 
 import (
+	"encoding/base64"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,3 +20,60 @@ func TestFunctionError(t *testing.T) {
 	require.Contains(t, err.Error(), "123")
 	require.Contains(t, err.Error(), "payload")
 }
+
+func TestNewLambdaInvocationResult(t *testing.T) {
+	t.Parallel()
+
+	result, err := newLambdaInvocationResult(&lambda.InvokeOutput{
+		StatusCode:      aws.Int64(200),
+		FunctionError:   aws.String("Unhandled"),
+		ExecutedVersion: aws.String("$LATEST"),
+		Payload:         []byte(`{"errorMessage": "boom"}`),
+		LogResult:       aws.String(base64.StdEncoding.EncodeToString([]byte("START RequestId: 123\nboom\n"))),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), result.StatusCode)
+	assert.Equal(t, "Unhandled", result.FunctionError)
+	assert.Equal(t, "$LATEST", result.ExecutedVersion)
+	assert.Equal(t, "START RequestId: 123\nboom\n", result.LogTail)
+
+	var payload struct {
+		ErrorMessage string `json:"errorMessage"`
+	}
+	require.NoError(t, result.DecodePayload(&payload))
+	assert.Equal(t, "boom", payload.ErrorMessage)
+}
+
+func TestCheckLambdaUpdated(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		state            string
+		lastUpdateStatus string
+		expectedErr      interface{}
+	}{
+		{"active", lambda.StateActive, lambda.LastUpdateStatusSuccessful, nil},
+		{"never updated", "", "", nil},
+		{"pending", lambda.StatePending, "", LambdaNotUpdatedError{}},
+		{"in progress", lambda.StateActive, lambda.LastUpdateStatusInProgress, LambdaNotUpdatedError{}},
+		{"update failed", lambda.StateActive, lambda.LastUpdateStatusFailed, retry.FatalError{}},
+		{"failed", lambda.StateFailed, "", retry.FatalError{}},
+	}
+
+	for _, testCase := range testCases {
+		configuration := &lambda.FunctionConfiguration{}
+		if testCase.state != "" {
+			configuration.State = aws.String(testCase.state)
+		}
+		if testCase.lastUpdateStatus != "" {
+			configuration.LastUpdateStatus = aws.String(testCase.lastUpdateStatus)
+		}
+		err := checkLambdaUpdated("test-function", configuration)
+		if testCase.expectedErr == nil {
+			assert.NoError(t, err, testCase.name)
+		} else {
+			assert.IsType(t, testCase.expectedErr, err, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kafka"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// MskBrokerAuth is the authentication of the brokers to connect to, which determines their bootstrap brokers.
+type MskBrokerAuth string
+
+const (
+	MskBrokerAuthPlaintext       MskBrokerAuth = "PLAINTEXT"
+	MskBrokerAuthTls             MskBrokerAuth = "TLS"
+	MskBrokerAuthSaslScram       MskBrokerAuth = "SASL_SCRAM"
+	MskBrokerAuthSaslIam         MskBrokerAuth = "SASL_IAM"
+	MskBrokerAuthPublicTls       MskBrokerAuth = "PUBLIC_TLS"
+	MskBrokerAuthPublicSaslScram MskBrokerAuth = "PUBLIC_SASL_SCRAM"
+	MskBrokerAuthPublicSaslIam   MskBrokerAuth = "PUBLIC_SASL_IAM"
+)
+
+// The directory of the Kafka CLI tools on the client instance of RunMskProduceConsumeSmokeTest, as installed by the
+// MSK client setup guide.
+const defaultKafkaBinDir = "/opt/kafka/bin"
+
+// MskSmokeTestOptions configures RunMskProduceConsumeSmokeTest.
+type MskSmokeTestOptions struct {
+	// The EC2 instance in the VPC of the cluster to produce and consume from. It needs the SSM agent, Java, the Kafka CLI
+	// tools, the aws-msk-iam-auth library in the classpath of the tools (e.g., in their libs directory), and an instance
+	// profile allowed to connect to the cluster, create topics, and write and read data.
+	ClientInstanceID string
+	// The topic to produce to and consume from, which is created if it doesn't exist. Defaults to a random topic.
+	Topic string
+	// The directory of the Kafka CLI tools on the instance. Defaults to /opt/kafka/bin.
+	KafkaBinDir string
+	// How long to wait for the smoke test to complete.
+	Timeout time.Duration
+}
+
+// GetMskCluster returns the description of the MSK cluster with the given ARN. This will fail the test if there is an
+// error.
+func GetMskCluster(t testing.TestingT, region string, clusterArn string) *kafka.ClusterInfo {
+	cluster, err := GetMskClusterE(t, region, clusterArn)
+	require.NoError(t, err)
+	return cluster
+}
+
+// GetMskClusterE returns the description of the MSK cluster with the given ARN, or a NotFoundError if it doesn't exist.
+func GetMskClusterE(t testing.TestingT, region string, clusterArn string) (*kafka.ClusterInfo, error) {
+	client, err := NewMskClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeCluster(&kafka.DescribeClusterInput{ClusterArn: aws.String(clusterArn)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == kafka.ErrCodeNotFoundException {
+			return nil, NewNotFoundError("MSK cluster", clusterArn, region)
+		}
+		return nil, err
+	}
+	return output.ClusterInfo, nil
+}
+
+// WaitUntilMskClusterActive waits until the MSK cluster with the given ARN is active, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. This will fail the test if there is
+// an error.
+func WaitUntilMskClusterActive(t testing.TestingT, region string, clusterArn string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilMskClusterActiveE(t, region, clusterArn, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilMskClusterActiveE waits until the MSK cluster with the given ARN is active, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. Creating a cluster usually takes
+// 15 to 30 minutes. This stops waiting if the cluster failed or is being deleted.
+func WaitUntilMskClusterActiveE(t testing.TestingT, region string, clusterArn string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for MSK cluster %s to be active.", clusterArn),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			cluster, err := GetMskClusterE(t, region, clusterArn)
+			if err != nil {
+				return "", err
+			}
+			if err := checkMskClusterActive(cluster); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("MSK cluster %s is now active", clusterArn), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkMskClusterActive returns an error if the given cluster is not active, wrapped in a retry.FatalError if it will
+// never become active.
+func checkMskClusterActive(cluster *kafka.ClusterInfo) error {
+	clusterArn := aws.StringValue(cluster.ClusterArn)
+	state := aws.StringValue(cluster.State)
+	reason := ""
+	if cluster.StateInfo != nil {
+		reason = aws.StringValue(cluster.StateInfo.Message)
+	}
+	switch state {
+	case kafka.ClusterStateActive:
+		return nil
+	case kafka.ClusterStateFailed, kafka.ClusterStateDeleting:
+		return retry.FatalError{Underlying: NewMskClusterNotActiveError(clusterArn, state, reason)}
+	default:
+		return NewMskClusterNotActiveError(clusterArn, state, reason)
+	}
+}
+
+// GetMskBootstrapBrokers returns the bootstrap brokers of the MSK cluster with the given ARN for the given
+// authentication, as a comma-separated list of host:port. This will fail the test if there is an error.
+func GetMskBootstrapBrokers(t testing.TestingT, region string, clusterArn string, auth MskBrokerAuth) string {
+	brokers, err := GetMskBootstrapBrokersE(t, region, clusterArn, auth)
+	require.NoError(t, err)
+	return brokers
+}
+
+// GetMskBootstrapBrokersE returns the bootstrap brokers of the MSK cluster with the given ARN for the given
+// authentication, as a comma-separated list of host:port that Kafka clients accept. This returns an error if the
+// cluster doesn't have brokers for the given authentication, e.g., if IAM authentication is not enabled.
+func GetMskBootstrapBrokersE(t testing.TestingT, region string, clusterArn string, auth MskBrokerAuth) (string, error) {
+	client, err := NewMskClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	output, err := client.GetBootstrapBrokers(&kafka.GetBootstrapBrokersInput{ClusterArn: aws.String(clusterArn)})
+	if err != nil {
+		return "", err
+	}
+	return selectMskBootstrapBrokers(region, clusterArn, output, auth)
+}
+
+// selectMskBootstrapBrokers returns the bootstrap brokers for the given authentication from the given output.
+func selectMskBootstrapBrokers(region string, clusterArn string, output *kafka.GetBootstrapBrokersOutput, auth MskBrokerAuth) (string, error) {
+	brokersByAuth := map[MskBrokerAuth]*string{
+		MskBrokerAuthPlaintext:       output.BootstrapBrokerString,
+		MskBrokerAuthTls:             output.BootstrapBrokerStringTls,
+		MskBrokerAuthSaslScram:       output.BootstrapBrokerStringSaslScram,
+		MskBrokerAuthSaslIam:         output.BootstrapBrokerStringSaslIam,
+		MskBrokerAuthPublicTls:       output.BootstrapBrokerStringPublicTls,
+		MskBrokerAuthPublicSaslScram: output.BootstrapBrokerStringPublicSaslScram,
+		MskBrokerAuthPublicSaslIam:   output.BootstrapBrokerStringPublicSaslIam,
+	}
+	brokers, isKnownAuth := brokersByAuth[auth]
+	if !isKnownAuth {
+		return "", fmt.Errorf("unknown MSK broker authentication %s", auth)
+	}
+	if aws.StringValue(brokers) == "" {
+		return "", NewNotFoundError(fmt.Sprintf("%s bootstrap brokers of MSK cluster", auth), clusterArn, region)
+	}
+	return aws.StringValue(brokers), nil
+}
+
+// RunMskProduceConsumeSmokeTest produces a message to the MSK cluster with the given ARN and consumes it back, using
+// IAM authentication, from the client instance of the given options. This will fail the test if there is an error.
+func RunMskProduceConsumeSmokeTest(t testing.TestingT, region string, clusterArn string, options MskSmokeTestOptions) {
+	err := RunMskProduceConsumeSmokeTestE(t, region, clusterArn, options)
+	require.NoError(t, err)
+}
+
+// RunMskProduceConsumeSmokeTestE produces a random message to the MSK cluster with the given ARN and consumes it back,
+// using SASL/IAM authentication, to prove that the cluster works and that clients in its VPC can reach it. The brokers
+// of MSK clusters are usually private, so the Kafka CLI tools run on the client instance of the given options through
+// SSM (see RunSsmCommandOnInstanceE).
+func RunMskProduceConsumeSmokeTestE(t testing.TestingT, region string, clusterArn string, options MskSmokeTestOptions) error {
+	brokers, err := GetMskBootstrapBrokersE(t, region, clusterArn, MskBrokerAuthSaslIam)
+	if err != nil {
+		return err
+	}
+
+	topic := options.Topic
+	if topic == "" {
+		topic = "terratest-" + strings.ToLower(random.UniqueId())
+	}
+	kafkaBinDir := options.KafkaBinDir
+	if kafkaBinDir == "" {
+		kafkaBinDir = defaultKafkaBinDir
+	}
+	message := "terratest-" + random.UniqueId()
+	script := newMskSmokeTestScript(kafkaBinDir, brokers, topic, message, options.Timeout)
+
+	logger.Logf(t, "Producing to and consuming from topic %s of MSK cluster %s on %s", topic, clusterArn, options.ClientInstanceID)
+	output, err := RunSsmCommandOnInstanceE(t, region, options.ClientInstanceID, script, options.Timeout)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(output.Stdout, message) {
+		return NewMskSmokeTestFailedError(clusterArn, topic, message, output.Stdout, output.Stderr)
+	}
+	return nil
+}
+
+// mskIamClientProperties are the properties of Kafka clients to authenticate to MSK with IAM.
+var mskIamClientProperties = []string{
+	"security.protocol=SASL_SSL",
+	"sasl.mechanism=AWS_MSK_IAM",
+	"sasl.jaas.config=software.amazon.msk.auth.iam.IAMLoginModule required;",
+	"sasl.client.callback.handler.class=software.amazon.msk.auth.iam.IAMClientCallbackHandler",
+}
+
+// newMskSmokeTestScript returns a shell script that creates the given topic if needed, produces the given message to
+// it, and consumes it back, printing the consumed messages.
+func newMskSmokeTestScript(kafkaBinDir string, brokers string, topic string, message string, timeout time.Duration) string {
+	consumeTimeoutMs := (timeout / 2).Milliseconds()
+	return strings.Join([]string{
+		"set -e",
+		"config=$(mktemp)",
+		"trap 'rm -f $config' EXIT",
+		fmt.Sprintf("cat > $config <<'EOF'\n%s\nEOF", strings.Join(mskIamClientProperties, "\n")),
+		fmt.Sprintf("%s/kafka-topics.sh --bootstrap-server %s --command-config $config --create --if-not-exists --topic %s --partitions 1 --replication-factor 2", kafkaBinDir, brokers, topic),
+		fmt.Sprintf("echo '%s' | %s/kafka-console-producer.sh --bootstrap-server %s --producer.config $config --topic %s", message, kafkaBinDir, brokers, topic),
+		fmt.Sprintf("%s/kafka-console-consumer.sh --bootstrap-server %s --consumer.config $config --topic %s --from-beginning --max-messages 1000 --timeout-ms %d || true", kafkaBinDir, brokers, topic, consumeTimeoutMs),
+	}, "\n")
+}
+
+// NewMskClient creates an MSK client.
+func NewMskClient(t testing.TestingT, region string) *kafka.Kafka {
+	client, err := NewMskClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewMskClientE creates an MSK client.
+func NewMskClientE(t testing.TestingT, region string) (*kafka.Kafka, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return kafka.New(sess), nil
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kafka"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMskClusterActive(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkMskClusterActive(&kafka.ClusterInfo{State: aws.String(kafka.ClusterStateActive)}))
+
+	err := checkMskClusterActive(&kafka.ClusterInfo{State: aws.String(kafka.ClusterStateCreating)})
+	assert.IsType(t, MskClusterNotActiveError{}, err)
+
+	err = checkMskClusterActive(&kafka.ClusterInfo{
+		State:     aws.String(kafka.ClusterStateFailed),
+		StateInfo: &kafka.StateInfo{Message: aws.String("insufficient capacity")},
+	})
+	require.IsType(t, retry.FatalError{}, err)
+	assert.Contains(t, err.(retry.FatalError).Underlying.Error(), "insufficient capacity")
+}
+
+func TestSelectMskBootstrapBrokers(t *testing.T) {
+	t.Parallel()
+
+	output := &kafka.GetBootstrapBrokersOutput{
+		BootstrapBrokerStringTls:     aws.String("b-1.example:9094,b-2.example:9094"),
+		BootstrapBrokerStringSaslIam: aws.String("b-1.example:9098,b-2.example:9098"),
+	}
+
+	brokers, err := selectMskBootstrapBrokers("us-east-1", "arn", output, MskBrokerAuthSaslIam)
+	require.NoError(t, err)
+	assert.Equal(t, "b-1.example:9098,b-2.example:9098", brokers)
+
+	_, err = selectMskBootstrapBrokers("us-east-1", "arn", output, MskBrokerAuthSaslScram)
+	assert.IsType(t, NotFoundError{}, err)
+
+	_, err = selectMskBootstrapBrokers("us-east-1", "arn", output, MskBrokerAuth("KERBEROS"))
+	assert.Error(t, err)
+}
+
+func TestNewMskSmokeTestScript(t *testing.T) {
+	t.Parallel()
+
+	script := newMskSmokeTestScript("/opt/kafka/bin", "b-1.example:9098", "terratest-topic", "terratest-message", time.Minute)
+	assert.True(t, strings.HasPrefix(script, "set -e\n"))
+	assert.Contains(t, script, "sasl.mechanism=AWS_MSK_IAM")
+	assert.Contains(t, script, "/opt/kafka/bin/kafka-topics.sh --bootstrap-server b-1.example:9098 --command-config $config --create --if-not-exists --topic terratest-topic")
+	assert.Contains(t, script, "echo 'terratest-message' | /opt/kafka/bin/kafka-console-producer.sh")
+	assert.Contains(t, script, "--timeout-ms 30000")
+}
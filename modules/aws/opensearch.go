@@ -0,0 +1,303 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/service/opensearchservice"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// openSearchSigningName is the service name to sign requests to OpenSearch and Elasticsearch domains with.
+const openSearchSigningName = "es"
+
+// OpenSearchAuth configures how RunOpenSearchSmokeTest authenticates to a domain. If Username is set, requests use
+// HTTP basic authentication as that user of the internal user database of fine-grained access control (e.g., the master
+// user). Otherwise, requests are signed with SigV4 using the AWS credentials of the test, which works with IAM-based
+// access policies and with fine-grained access control mapped to IAM roles.
+type OpenSearchAuth struct {
+	Username string
+	Password string
+}
+
+// GetOpenSearchDomain returns the status of the OpenSearch (or Elasticsearch) domain with the given name. This will
+// fail the test if there is an error.
+func GetOpenSearchDomain(t testing.TestingT, region string, domainName string) *opensearchservice.DomainStatus {
+	domain, err := GetOpenSearchDomainE(t, region, domainName)
+	require.NoError(t, err)
+	return domain
+}
+
+// GetOpenSearchDomainE returns the status of the OpenSearch (or Elasticsearch) domain with the given name, or a
+// NotFoundError if it doesn't exist.
+func GetOpenSearchDomainE(t testing.TestingT, region string, domainName string) (*opensearchservice.DomainStatus, error) {
+	client, err := NewOpenSearchClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.DescribeDomain(&opensearchservice.DescribeDomainInput{DomainName: aws.String(domainName)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == opensearchservice.ErrCodeResourceNotFoundException {
+			return nil, NewNotFoundError("OpenSearch domain", domainName, region)
+		}
+		return nil, err
+	}
+	return output.DomainStatus, nil
+}
+
+// WaitUntilOpenSearchDomainReady waits until the OpenSearch (or Elasticsearch) domain with the given name is created
+// and done processing changes, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. This will fail the test if there is an error.
+func WaitUntilOpenSearchDomainReady(t testing.TestingT, region string, domainName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilOpenSearchDomainReadyE(t, region, domainName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilOpenSearchDomainReadyE waits until the OpenSearch (or Elasticsearch) domain with the given name is created
+// and done processing changes (including blue/green deployments and version upgrades), and has an endpoint, retrying
+// the check for the specified amount of times, sleeping for the provided duration between each try. Creating or
+// updating a domain often takes 15 to 30 minutes. This stops waiting if the domain is being deleted.
+func WaitUntilOpenSearchDomainReadyE(t testing.TestingT, region string, domainName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for OpenSearch domain %s to be ready.", domainName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			domain, err := GetOpenSearchDomainE(t, region, domainName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkOpenSearchDomainReady(domain); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("OpenSearch domain %s is now ready", domainName), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkOpenSearchDomainReady returns an error if the given domain is not ready, wrapped in a retry.FatalError if it is
+// being deleted.
+func checkOpenSearchDomainReady(domain *opensearchservice.DomainStatus) error {
+	domainName := aws.StringValue(domain.DomainName)
+	switch {
+	case aws.BoolValue(domain.Deleted):
+		return retry.FatalError{Underlying: NewOpenSearchDomainNotReadyError(domainName, "deleted")}
+	case !aws.BoolValue(domain.Created):
+		return NewOpenSearchDomainNotReadyError(domainName, "being created")
+	case aws.BoolValue(domain.Processing):
+		return NewOpenSearchDomainNotReadyError(domainName, "processing changes")
+	case aws.BoolValue(domain.UpgradeProcessing):
+		return NewOpenSearchDomainNotReadyError(domainName, "upgrading")
+	}
+	if _, err := getOpenSearchDomainEndpoint(domain); err != nil {
+		return NewOpenSearchDomainNotReadyError(domainName, "waiting for an endpoint")
+	}
+	return nil
+}
+
+// GetOpenSearchDomainEndpoint returns the endpoint of the OpenSearch (or Elasticsearch) domain with the given name
+// (e.g., https://search-logs-abc123.us-east-1.es.amazonaws.com). This will fail the test if there is an error.
+func GetOpenSearchDomainEndpoint(t testing.TestingT, region string, domainName string) string {
+	endpoint, err := GetOpenSearchDomainEndpointE(t, region, domainName)
+	require.NoError(t, err)
+	return endpoint
+}
+
+// GetOpenSearchDomainEndpointE returns the endpoint of the OpenSearch (or Elasticsearch) domain with the given name
+// (e.g., https://search-logs-abc123.us-east-1.es.amazonaws.com). This is the VPC endpoint for domains in a VPC, and the
+// custom endpoint if the domain has one enabled.
+func GetOpenSearchDomainEndpointE(t testing.TestingT, region string, domainName string) (string, error) {
+	domain, err := GetOpenSearchDomainE(t, region, domainName)
+	if err != nil {
+		return "", err
+	}
+	return getOpenSearchDomainEndpoint(domain)
+}
+
+// getOpenSearchDomainEndpoint returns the HTTPS endpoint of the given domain.
+func getOpenSearchDomainEndpoint(domain *opensearchservice.DomainStatus) (string, error) {
+	endpoint := aws.StringValue(domain.Endpoint)
+	if vpcEndpoint, ok := domain.Endpoints["vpc"]; ok && endpoint == "" {
+		endpoint = aws.StringValue(vpcEndpoint)
+	}
+	if options := domain.DomainEndpointOptions; options != nil && aws.BoolValue(options.CustomEndpointEnabled) && aws.StringValue(options.CustomEndpoint) != "" {
+		endpoint = aws.StringValue(options.CustomEndpoint)
+	}
+	if endpoint == "" {
+		return "", fmt.Errorf("OpenSearch domain %s has no endpoint", aws.StringValue(domain.DomainName))
+	}
+	return "https://" + endpoint, nil
+}
+
+// RunOpenSearchSmokeTest creates an index in the OpenSearch (or Elasticsearch) domain with the given name, writes a
+// document to it, and searches for it, authenticating with the given auth. This will fail the test if there is an
+// error.
+func RunOpenSearchSmokeTest(t testing.TestingT, region string, domainName string, auth OpenSearchAuth) {
+	err := RunOpenSearchSmokeTestE(t, region, domainName, auth)
+	require.NoError(t, err)
+}
+
+// RunOpenSearchSmokeTestE creates a random index in the OpenSearch (or Elasticsearch) domain with the given name,
+// writes a document to it, and searches for it, authenticating with the given auth, to prove that the domain accepts
+// writes and serves searches for clients with those credentials. The index is deleted afterwards. The test runner must
+// be able to reach the endpoint of the domain, so domains in a VPC need a runner with access to the VPC.
+func RunOpenSearchSmokeTestE(t testing.TestingT, region string, domainName string, auth OpenSearchAuth) error {
+	endpoint, err := GetOpenSearchDomainEndpointE(t, region, domainName)
+	if err != nil {
+		return err
+	}
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return err
+	}
+	client := openSearchClient{endpoint: endpoint, region: region, auth: auth, credentials: sess.Config.Credentials}
+
+	index := "terratest-" + strings.ToLower(random.UniqueId())
+	message := "terratest-" + random.UniqueId()
+	logger.Logf(t, "Running smoke test against index %s of OpenSearch domain %s", index, domainName)
+
+	if err := client.expect(t, http.MethodPut, "/"+index, "", http.StatusOK); err != nil {
+		return err
+	}
+	defer func() {
+		if err := client.expect(t, http.MethodDelete, "/"+index, "", http.StatusOK); err != nil {
+			logger.Logf(t, "Failed to delete OpenSearch index %s: %v", index, err)
+		}
+	}()
+
+	document := fmt.Sprintf(`{"message": %q}`, message)
+	if err := client.expect(t, http.MethodPut, "/"+index+"/_doc/1?refresh=true", document, http.StatusCreated); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`{"query": {"match": {"message": %q}}}`, message)
+	status, body, err := client.send(t, http.MethodPost, "/"+index+"/_search", query)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return NewOpenSearchRequestFailedError(http.MethodPost, "/"+index+"/_search", status, body)
+	}
+	found, err := openSearchResponseHasMessage(body, message)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return NewOpenSearchRequestFailedError(http.MethodPost, "/"+index+"/_search", status, body)
+	}
+	return nil
+}
+
+// openSearchClient sends requests to the endpoint of an OpenSearch domain.
+type openSearchClient struct {
+	endpoint    string
+	region      string
+	auth        OpenSearchAuth
+	credentials *credentials.Credentials
+}
+
+// expect sends the given request, and returns an error if the response doesn't have the expected status.
+func (client openSearchClient) expect(t testing.TestingT, method string, path string, body string, expectedStatus int) error {
+	status, responseBody, err := client.send(t, method, path, body)
+	if err != nil {
+		return err
+	}
+	if status != expectedStatus {
+		return NewOpenSearchRequestFailedError(method, path, status, responseBody)
+	}
+	return nil
+}
+
+// send sends the given request, and returns the status and body of the response.
+func (client openSearchClient) send(t testing.TestingT, method string, path string, body string) (int, string, error) {
+	request, err := client.newRequest(method, path, body)
+	if err != nil {
+		return 0, "", err
+	}
+	logger.Logf(t, "Sending %s request to %s", method, request.URL)
+
+	response, err := (&http.Client{Timeout: 30 * time.Second}).Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, "", err
+	}
+	return response.StatusCode, string(responseBody), nil
+}
+
+// newRequest returns the given request, authenticated with basic authentication or signed with SigV4.
+func (client openSearchClient) newRequest(method string, path string, body string) (*http.Request, error) {
+	request, err := http.NewRequest(method, strings.TrimSuffix(client.endpoint, "/")+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if client.auth.Username != "" {
+		request.SetBasicAuth(client.auth.Username, client.auth.Password)
+		return request, nil
+	}
+	signer := v4.NewSigner(client.credentials)
+	if _, err := signer.Sign(request, bytes.NewReader([]byte(body)), openSearchSigningName, client.region, time.Now()); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// openSearchResponseHasMessage returns true if the given search response has a hit whose message is the given one.
+func openSearchResponseHasMessage(body string, message string) (bool, error) {
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source struct {
+					Message string `json:"message"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return false, err
+	}
+	for _, hit := range response.Hits.Hits {
+		if hit.Source.Message == message {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NewOpenSearchClient creates an OpenSearch Service client, which also manages Elasticsearch domains.
+func NewOpenSearchClient(t testing.TestingT, region string) *opensearchservice.OpenSearchService {
+	client, err := NewOpenSearchClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewOpenSearchClientE creates an OpenSearch Service client, which also manages Elasticsearch domains.
+func NewOpenSearchClientE(t testing.TestingT, region string) (*opensearchservice.OpenSearchService, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return opensearchservice.New(sess), nil
+}
@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/opensearchservice"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOpenSearchDomainReady(t *testing.T) {
+	t.Parallel()
+
+	ready := &opensearchservice.DomainStatus{
+		DomainName: aws.String("logs"),
+		Created:    aws.Bool(true),
+		Endpoint:   aws.String("search-logs-abc123.us-east-1.es.amazonaws.com"),
+	}
+	assert.NoError(t, checkOpenSearchDomainReady(ready))
+
+	processing := *ready
+	processing.Processing = aws.Bool(true)
+	assert.IsType(t, OpenSearchDomainNotReadyError{}, checkOpenSearchDomainReady(&processing))
+
+	noEndpoint := *ready
+	noEndpoint.Endpoint = nil
+	assert.IsType(t, OpenSearchDomainNotReadyError{}, checkOpenSearchDomainReady(&noEndpoint))
+
+	deleted := *ready
+	deleted.Deleted = aws.Bool(true)
+	assert.IsType(t, retry.FatalError{}, checkOpenSearchDomainReady(&deleted))
+}
+
+func TestGetOpenSearchDomainEndpoint(t *testing.T) {
+	t.Parallel()
+
+	endpoint, err := getOpenSearchDomainEndpoint(&opensearchservice.DomainStatus{Endpoint: aws.String("search-logs.us-east-1.es.amazonaws.com")})
+	require.NoError(t, err)
+	assert.Equal(t, "https://search-logs.us-east-1.es.amazonaws.com", endpoint)
+
+	endpoint, err = getOpenSearchDomainEndpoint(&opensearchservice.DomainStatus{Endpoints: map[string]*string{"vpc": aws.String("vpc-logs.us-east-1.es.amazonaws.com")}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://vpc-logs.us-east-1.es.amazonaws.com", endpoint)
+
+	endpoint, err = getOpenSearchDomainEndpoint(&opensearchservice.DomainStatus{
+		Endpoint:              aws.String("search-logs.us-east-1.es.amazonaws.com"),
+		DomainEndpointOptions: &opensearchservice.DomainEndpointOptions{CustomEndpointEnabled: aws.Bool(true), CustomEndpoint: aws.String("logs.example.com")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://logs.example.com", endpoint)
+
+	_, err = getOpenSearchDomainEndpoint(&opensearchservice.DomainStatus{DomainName: aws.String("logs")})
+	assert.Error(t, err)
+}
+
+func TestOpenSearchClientNewRequestUsesBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	client := openSearchClient{endpoint: "https://logs.example.com/", region: "us-east-1", auth: OpenSearchAuth{Username: "admin", Password: "secret"}}
+	request, err := client.newRequest(http.MethodPut, "/index", `{}`)
+	require.NoError(t, err)
+
+	username, password, ok := request.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", username)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "https://logs.example.com/index", request.URL.String())
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+}
+
+func TestOpenSearchResponseHasMessage(t *testing.T) {
+	t.Parallel()
+
+	body := `{"hits": {"total": {"value": 1}, "hits": [{"_id": "1", "_source": {"message": "terratest-abc"}}]}}`
+
+	found, err := openSearchResponseHasMessage(body, "terratest-abc")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = openSearchResponseHasMessage(body, "terratest-xyz")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = openSearchResponseHasMessage("not json", "terratest-abc")
+	assert.Error(t, err)
+}
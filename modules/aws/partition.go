@@ -0,0 +1,139 @@
+package aws
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// The default region of each partition, used for the API calls that require a region but don't depend on it (e.g., for
+// global services such as IAM).
+var defaultRegionsByPartition = map[string]string{
+	endpoints.AwsPartitionID:      defaultRegion,
+	endpoints.AwsCnPartitionID:    "cn-north-1",
+	endpoints.AwsUsGovPartitionID: "us-gov-west-1",
+	endpoints.AwsIsoPartitionID:   "us-iso-east-1",
+	endpoints.AwsIsoBPartitionID:  "us-isob-east-1",
+}
+
+// The region prefixes of the partitions other than the public one, ordered so that longer prefixes come first.
+var partitionRegionPrefixes = []struct {
+	prefix    string
+	partition string
+}{
+	{"us-isob-", endpoints.AwsIsoBPartitionID},
+	{"us-iso-", endpoints.AwsIsoPartitionID},
+	{"us-gov-", endpoints.AwsUsGovPartitionID},
+	{"cn-", endpoints.AwsCnPartitionID},
+}
+
+// GetPartitionForRegion returns the partition the given region is in (e.g., aws for us-east-1, aws-us-gov for
+// us-gov-west-1, or aws-cn for cn-north-1). Regions unknown to the AWS SDK are matched by prefix, and default to the
+// public partition.
+func GetPartitionForRegion(region string) string {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition.ID()
+	}
+	for _, prefix := range partitionRegionPrefixes {
+		if strings.HasPrefix(region, prefix.prefix) {
+			return prefix.partition
+		}
+	}
+	return endpoints.AwsPartitionID
+}
+
+// GetDefaultRegionForPartition returns the region used by this package for API calls that don't depend on a region in
+// the given partition, such as calls to IAM. This returns us-east-1 for unknown partitions.
+func GetDefaultRegionForPartition(partition string) string {
+	if region, ok := defaultRegionsByPartition[partition]; ok {
+		return region
+	}
+	return defaultRegion
+}
+
+// getDefaultRegion returns the region to use for API calls that don't depend on a region, in the partition of the
+// region the tests run in: the region of the TERRATEST_REGION environment variable if set, or else the region of the
+// AWS CLI and SDK environment variables. This is us-east-1 if none of them is set.
+func getDefaultRegion() string {
+	for _, envVar := range []string{regionOverrideEnvVarName, "AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(envVar); region != "" {
+			return GetDefaultRegionForPartition(GetPartitionForRegion(region))
+		}
+	}
+	return defaultRegion
+}
+
+// GetPartition returns the partition of the credentials the tests run with (e.g., aws-us-gov for GovCloud credentials).
+// This will fail the test if there is an error.
+func GetPartition(t testing.TestingT) string {
+	partition, err := GetPartitionE(t)
+	require.NoError(t, err)
+	return partition
+}
+
+// GetPartitionE returns the partition of the credentials the tests run with (e.g., aws-us-gov for GovCloud
+// credentials), from the ARN of the caller identity.
+func GetPartitionE(t testing.TestingT) (string, error) {
+	callerArn, err := GetCallerArnE(t)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := arn.Parse(callerArn)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Partition, nil
+}
+
+// GetCallerArn returns the ARN of the identity the tests run as. This will fail the test if there is an error.
+func GetCallerArn(t testing.TestingT) string {
+	callerArn, err := GetCallerArnE(t)
+	require.NoError(t, err)
+	return callerArn
+}
+
+// GetCallerArnE returns the ARN of the identity the tests run as.
+func GetCallerArnE(t testing.TestingT) (string, error) {
+	stsClient, err := NewStsClientE(t, getDefaultRegion())
+	if err != nil {
+		return "", err
+	}
+	identity, err := stsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(identity.Arn), nil
+}
+
+// NewArnForRegion returns the ARN of the given resource of the given service in the given region and account, in the
+// partition of the region, e.g.:
+//
+//	aws.NewArnForRegion("ec2", "us-gov-west-1", "123456789012", "instance/i-0123456789abcdef0")
+//	// arn:aws-us-gov:ec2:us-gov-west-1:123456789012:instance/i-0123456789abcdef0
+func NewArnForRegion(service string, region string, accountID string, resource string) string {
+	return arn.ARN{
+		Partition: GetPartitionForRegion(region),
+		Service:   service,
+		Region:    region,
+		AccountID: accountID,
+		Resource:  resource,
+	}.String()
+}
+
+// IsRegionInPartition returns true if the given region is a region of the given partition known to the AWS SDK.
+func IsRegionInPartition(region string, partition string) bool {
+	for _, p := range endpoints.DefaultPartitions() {
+		if p.ID() != partition {
+			continue
+		}
+		_, ok := p.Regions()[region]
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPartitionForRegion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		region   string
+		expected string
+	}{
+		{"us-east-1", "aws"},
+		{"eu-west-1", "aws"},
+		{"us-gov-west-1", "aws-us-gov"},
+		{"us-gov-east-1", "aws-us-gov"},
+		{"cn-north-1", "aws-cn"},
+		{"cn-northwest-1", "aws-cn"},
+		{"us-iso-east-1", "aws-iso"},
+		{"us-isob-east-1", "aws-iso-b"},
+		{"cn-south-9", "aws-cn"},
+		{"xx-nowhere-1", "aws"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, GetPartitionForRegion(testCase.region), testCase.region)
+	}
+}
+
+func TestGetDefaultRegionForPartition(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "us-east-1", GetDefaultRegionForPartition("aws"))
+	assert.Equal(t, "us-gov-west-1", GetDefaultRegionForPartition("aws-us-gov"))
+	assert.Equal(t, "cn-north-1", GetDefaultRegionForPartition("aws-cn"))
+	assert.Equal(t, "us-east-1", GetDefaultRegionForPartition("unknown"))
+}
+
+func TestNewArnForRegion(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0", NewArnForRegion("ec2", "us-east-1", "123456789012", "instance/i-0123456789abcdef0"))
+	assert.Equal(t, "arn:aws-us-gov:ec2:us-gov-west-1:123456789012:instance/i-0123456789abcdef0", NewArnForRegion("ec2", "us-gov-west-1", "123456789012", "instance/i-0123456789abcdef0"))
+	assert.Equal(t, "arn:aws-cn:sqs:cn-north-1:123456789012:queue", NewArnForRegion("sqs", "cn-north-1", "123456789012", "queue"))
+}
+
+func TestIsRegionInPartition(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsRegionInPartition("us-gov-west-1", "aws-us-gov"))
+	assert.False(t, IsRegionInPartition("us-gov-west-1", "aws"))
+	assert.True(t, IsRegionInPartition("eu-west-1", "aws"))
+	assert.False(t, IsRegionInPartition("eu-west-1", "unknown"))
+}
+
+func TestGetStableRegionsWithApprovedRegionsIncludesAllPartitions(t *testing.T) {
+	t.Parallel()
+
+	regions := getStableRegions([]string{"us-gov-west-1"})
+	assert.Contains(t, regions, "us-gov-west-1")
+	assert.Contains(t, regions, "cn-north-1")
+	assert.Contains(t, regions, "us-east-1")
+}
@@ -3,10 +3,13 @@ package aws
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rds"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -210,7 +213,7 @@ func NewRdsClient(t testing.TestingT, region string) *rds.RDS {
 
 // NewRdsClientE creates an RDS client.
 func NewRdsClientE(t testing.TestingT, region string) (*rds.RDS, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -302,6 +305,240 @@ func GetValidEngineVersionE(t testing.TestingT, region string, engine string, ma
 	return *out.DBEngineVersions[0].EngineVersion, nil
 }
 
+// rdsAvailableStatus is the status of an RDS cluster, instance, or snapshot that is ready to use.
+const rdsAvailableStatus = "available"
+
+// rdsClusterFailedStatuses are the statuses an RDS cluster or instance can't become available from without
+// intervention.
+var rdsClusterFailedStatuses = []string{
+	"failed",
+	"deleting",
+	"inaccessible-encryption-credentials",
+	"inaccessible-encryption-credentials-recoverable",
+	"incompatible-parameters",
+	"incompatible-restore",
+}
+
+// GetRdsClusterDetails gets the details of the RDS (e.g., Aurora) cluster with the given identifier.
+func GetRdsClusterDetails(t testing.TestingT, dbClusterID string, awsRegion string) *rds.DBCluster {
+	cluster, err := GetRdsClusterDetailsE(t, dbClusterID, awsRegion)
+	require.NoError(t, err)
+	return cluster
+}
+
+// GetRdsClusterDetailsE gets the details of the RDS (e.g., Aurora) cluster with the given identifier.
+func GetRdsClusterDetailsE(t testing.TestingT, dbClusterID string, awsRegion string) (*rds.DBCluster, error) {
+	rdsClient, err := NewRdsClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	output, err := rdsClient.DescribeDBClusters(&rds.DescribeDBClustersInput{DBClusterIdentifier: aws.String(dbClusterID)})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.DBClusters) == 0 {
+		return nil, NewNotFoundError("RDS cluster", dbClusterID, awsRegion)
+	}
+	return output.DBClusters[0], nil
+}
+
+// GetRdsClusterInstances gets the details of the DB instances that are members of the given RDS cluster.
+func GetRdsClusterInstances(t testing.TestingT, dbClusterID string, awsRegion string) []*rds.DBInstance {
+	instances, err := GetRdsClusterInstancesE(t, dbClusterID, awsRegion)
+	require.NoError(t, err)
+	return instances
+}
+
+// GetRdsClusterInstancesE gets the details of the DB instances that are members of the given RDS cluster.
+func GetRdsClusterInstancesE(t testing.TestingT, dbClusterID string, awsRegion string) ([]*rds.DBInstance, error) {
+	rdsClient, err := NewRdsClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	instances := []*rds.DBInstance{}
+	input := &rds.DescribeDBInstancesInput{
+		Filters: []*rds.Filter{{Name: aws.String("db-cluster-id"), Values: aws.StringSlice([]string{dbClusterID})}},
+	}
+	err = rdsClient.DescribeDBInstancesPages(input, func(output *rds.DescribeDBInstancesOutput, lastPage bool) bool {
+		instances = append(instances, output.DBInstances...)
+		return true
+	})
+	return instances, err
+}
+
+// WaitUntilRdsClusterAvailable waits until the given RDS cluster and all of its DB instances are available, retrying
+// the check for the specified amount of times, sleeping for the provided duration between each try.
+func WaitUntilRdsClusterAvailable(t testing.TestingT, dbClusterID string, awsRegion string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilRdsClusterAvailableE(t, dbClusterID, awsRegion, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilRdsClusterAvailableE waits until the given RDS cluster and all of its DB instances are available, retrying
+// the check for the specified amount of times, sleeping for the provided duration between each try. This stops waiting
+// early if the cluster or one of its instances is in a state it can't become available from (e.g., failed or deleting).
+func WaitUntilRdsClusterAvailableE(t testing.TestingT, dbClusterID string, awsRegion string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for RDS cluster %s to be available.", dbClusterID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			cluster, err := GetRdsClusterDetailsE(t, dbClusterID, awsRegion)
+			if err != nil {
+				return "", err
+			}
+			instances, err := GetRdsClusterInstancesE(t, dbClusterID, awsRegion)
+			if err != nil {
+				return "", err
+			}
+			if err := checkRdsClusterAvailable(cluster, instances); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("RDS cluster %s is now available", dbClusterID), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// checkRdsClusterAvailable returns an error if the given RDS cluster or one of its instances is not available. The
+// error is wrapped in a retry.FatalError if it can't become available without intervention.
+func checkRdsClusterAvailable(cluster *rds.DBCluster, instances []*rds.DBInstance) error {
+	clusterID := aws.StringValue(cluster.DBClusterIdentifier)
+	if err := checkRdsStatusAvailable(clusterID, "", aws.StringValue(cluster.Status)); err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if err := checkRdsStatusAvailable(clusterID, aws.StringValue(instance.DBInstanceIdentifier), aws.StringValue(instance.DBInstanceStatus)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRdsStatusAvailable(dbClusterID string, dbInstanceID string, status string) error {
+	if status == rdsAvailableStatus {
+		return nil
+	}
+	err := NewRdsClusterNotAvailableError(dbClusterID, dbInstanceID, status)
+	for _, failedStatus := range rdsClusterFailedStatuses {
+		if status == failedStatus {
+			return retry.FatalError{Underlying: err}
+		}
+	}
+	return err
+}
+
+// CreateRdsClusterSnapshot creates a manual snapshot with the given identifier of the given RDS cluster, and waits
+// until it is available, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try.
+func CreateRdsClusterSnapshot(t testing.TestingT, dbClusterID string, snapshotID string, awsRegion string, maxRetries int, sleepBetweenRetries time.Duration) *rds.DBClusterSnapshot {
+	snapshot, err := CreateRdsClusterSnapshotE(t, dbClusterID, snapshotID, awsRegion, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return snapshot
+}
+
+// CreateRdsClusterSnapshotE creates a manual snapshot with the given identifier of the given RDS cluster, and waits
+// until it is available, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. Manual snapshots are not deleted with the cluster, so use DeleteRdsClusterSnapshot to clean it up.
+func CreateRdsClusterSnapshotE(t testing.TestingT, dbClusterID string, snapshotID string, awsRegion string, maxRetries int, sleepBetweenRetries time.Duration) (*rds.DBClusterSnapshot, error) {
+	logger.Logf(t, "Creating snapshot %s of RDS cluster %s", snapshotID, dbClusterID)
+
+	rdsClient, err := NewRdsClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	_, err = rdsClient.CreateDBClusterSnapshot(&rds.CreateDBClusterSnapshotInput{
+		DBClusterIdentifier:         aws.String(dbClusterID),
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot *rds.DBClusterSnapshot
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for snapshot %s of RDS cluster %s to be available.", snapshotID, dbClusterID),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			output, err := rdsClient.DescribeDBClusterSnapshots(&rds.DescribeDBClusterSnapshotsInput{
+				DBClusterSnapshotIdentifier: aws.String(snapshotID),
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(output.DBClusterSnapshots) == 0 {
+				return "", NewNotFoundError("RDS cluster snapshot", snapshotID, awsRegion)
+			}
+			snapshot = output.DBClusterSnapshots[0]
+			status := aws.StringValue(snapshot.Status)
+			if status != rdsAvailableStatus {
+				return "", NewRdsClusterSnapshotNotAvailableError(snapshotID, status)
+			}
+			return fmt.Sprintf("Snapshot %s of RDS cluster %s is now available", snapshotID, dbClusterID), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// DeleteRdsClusterSnapshot deletes the manual RDS cluster snapshot with the given identifier.
+func DeleteRdsClusterSnapshot(t testing.TestingT, snapshotID string, awsRegion string) {
+	err := DeleteRdsClusterSnapshotE(t, snapshotID, awsRegion)
+	require.NoError(t, err)
+}
+
+// DeleteRdsClusterSnapshotE deletes the manual RDS cluster snapshot with the given identifier.
+func DeleteRdsClusterSnapshotE(t testing.TestingT, snapshotID string, awsRegion string) error {
+	logger.Logf(t, "Deleting RDS cluster snapshot %s", snapshotID)
+
+	rdsClient, err := NewRdsClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+	_, err = rdsClient.DeleteDBClusterSnapshot(&rds.DeleteDBClusterSnapshotInput{
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+	})
+	return err
+}
+
+// RestoreRdsClusterFromSnapshot restores a new RDS cluster from a snapshot, as configured by the given input, and
+// waits until it is available, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try.
+func RestoreRdsClusterFromSnapshot(t testing.TestingT, input *rds.RestoreDBClusterFromSnapshotInput, awsRegion string, maxRetries int, sleepBetweenRetries time.Duration) *rds.DBCluster {
+	cluster, err := RestoreRdsClusterFromSnapshotE(t, input, awsRegion, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return cluster
+}
+
+// RestoreRdsClusterFromSnapshotE restores a new RDS cluster from a snapshot, as configured by the given input (which
+// must set at least DBClusterIdentifier, SnapshotIdentifier, and Engine), and waits until it is available, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try.
+//
+// Note that the restored cluster has no DB instances: to connect to it, create an instance in the cluster (e.g., with
+// terraform), and then use WaitUntilRdsClusterAvailable to wait for it.
+func RestoreRdsClusterFromSnapshotE(t testing.TestingT, input *rds.RestoreDBClusterFromSnapshotInput, awsRegion string, maxRetries int, sleepBetweenRetries time.Duration) (*rds.DBCluster, error) {
+	dbClusterID := aws.StringValue(input.DBClusterIdentifier)
+	logger.Logf(t, "Restoring RDS cluster %s from snapshot %s", dbClusterID, aws.StringValue(input.SnapshotIdentifier))
+
+	rdsClient, err := NewRdsClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rdsClient.RestoreDBClusterFromSnapshot(input); err != nil {
+		return nil, err
+	}
+	if err := WaitUntilRdsClusterAvailableE(t, dbClusterID, awsRegion, maxRetries, sleepBetweenRetries); err != nil {
+		return nil, err
+	}
+	return GetRdsClusterDetailsE(t, dbClusterID, awsRegion)
+}
+
 // ParameterForDbInstanceNotFound is an error that occurs when the parameter group specified is not found for the DB instance
 type ParameterForDbInstanceNotFound struct {
 	ParameterName string
@@ -0,0 +1,353 @@
+package aws
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const (
+	// RdsEngineMySql is the engine of RDS MySQL and Aurora MySQL databases, for use in RdsConnectionOptions.
+	RdsEngineMySql = "mysql"
+	// RdsEnginePostgres is the engine of RDS PostgreSQL and Aurora PostgreSQL databases, for use in
+	// RdsConnectionOptions.
+	RdsEnginePostgres = "postgres"
+
+	// rdsTunnelTimeout is how long to wait for a tunnel to the database to accept connections.
+	rdsTunnelTimeout = 30 * time.Second
+)
+
+// RdsConnectionOptions are the options to connect to an RDS database with ConnectAndQuery. Set SshBastion or
+// SsmBastionInstanceID to connect to a database in a private subnet through a bastion host.
+type RdsConnectionOptions struct {
+	Engine   string // The engine of the database: RdsEngineMySql or RdsEnginePostgres
+	Host     string // The address of the database (e.g., the endpoint of an RDS cluster)
+	Port     int64  // The port of the database
+	Username string
+	Password string
+	Database string // The name of the database to connect to (optional)
+
+	// The name of the database/sql driver to use. Defaults to the engine. Terratest only registers a MySQL driver, so to
+	// connect to a Postgres database, import a driver in your test that registers as "postgres" (e.g.,
+	// github.com/lib/pq), or set this to the name of the driver you import (e.g., "pgx").
+	DriverName string
+
+	SshBastion *ssh.Host // Connect through an SSH tunnel via this host (optional)
+
+	// Connect through an SSM port forwarding session via the EC2 instance with this ID (optional). This requires the AWS
+	// CLI and the Session Manager plugin to be installed.
+	SsmBastionInstanceID string
+	AwsRegion            string // The region of the SSM bastion instance
+}
+
+// ConnectAndQuery connects to the database with the given options, runs the given query, and returns the rows it
+// returns, with each column formatted as a string. This will fail the test if there is an error.
+func ConnectAndQuery(t testing.TestingT, options RdsConnectionOptions, query string, args ...interface{}) [][]string {
+	rows, err := ConnectAndQueryE(t, options, query, args...)
+	require.NoError(t, err)
+	return rows
+}
+
+// ConnectAndQueryE connects to the database with the given options, runs the given query, and returns the rows it
+// returns, with each column formatted as a string (NULL values are returned as empty strings). If the options set a
+// bastion, the connection goes through a tunnel that is closed once the query is done.
+func ConnectAndQueryE(t testing.TestingT, options RdsConnectionOptions, query string, args ...interface{}) ([][]string, error) {
+	host, port := options.Host, options.Port
+	if options.SshBastion != nil || options.SsmBastionInstanceID != "" {
+		tunnel, err := openRdsTunnel(t, options)
+		if err != nil {
+			return nil, err
+		}
+		defer tunnel.Close()
+		host, port = "127.0.0.1", int64(tunnel.LocalPort())
+	}
+
+	driverName := options.DriverName
+	if driverName == "" {
+		driverName = options.Engine
+	}
+	dataSourceName, err := formatRdsDataSourceName(options.Engine, host, port, options.Username, options.Password, options.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logf(t, "Running query on %s database %s at %s:%d", options.Engine, options.Database, options.Host, options.Port)
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRowsAsStrings(rows)
+}
+
+// formatRdsDataSourceName returns the data source name to connect to the given database with the driver of the given
+// engine.
+func formatRdsDataSourceName(engine string, host string, port int64, username string, password string, database string) (string, error) {
+	address := net.JoinHostPort(host, strconv.FormatInt(port, 10))
+	switch engine {
+	case RdsEngineMySql:
+		config := mysql.NewConfig()
+		config.User = username
+		config.Passwd = password
+		config.Net = "tcp"
+		config.Addr = address
+		config.DBName = database
+		return config.FormatDSN(), nil
+	case RdsEnginePostgres:
+		dataSourceURL := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(username, password),
+			Host:     address,
+			Path:     "/" + database,
+			RawQuery: "sslmode=require",
+		}
+		return dataSourceURL.String(), nil
+	default:
+		return "", UnsupportedRdsEngine{Engine: engine}
+	}
+}
+
+// scanRowsAsStrings reads all the given rows, formatting each column as a string.
+func scanRowsAsStrings(rows *sql.Rows) ([][]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := [][]string{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make([]string, len(columns))
+		for i, value := range values {
+			row[i] = value.String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// rdsTunnel is a tunnel from a local port to a database.
+type rdsTunnel interface {
+	LocalPort() int
+	Close() error
+}
+
+// openRdsTunnel opens a tunnel to the database with the given options through the bastion they set.
+func openRdsTunnel(t testing.TestingT, options RdsConnectionOptions) (rdsTunnel, error) {
+	remoteAddress := net.JoinHostPort(options.Host, strconv.FormatInt(options.Port, 10))
+	if options.SshBastion != nil {
+		return openSshRdsTunnel(t, *options.SshBastion, remoteAddress)
+	}
+	return OpenSsmTunnelToRemoteHostE(t, options.AwsRegion, options.SsmBastionInstanceID, options.Host, int(options.Port))
+}
+
+// sshRdsTunnel forwards connections to a local port to a database through an SSH connection to a bastion host.
+type sshRdsTunnel struct {
+	client     *gossh.Client
+	forwarder  *localPortForwarder
+	agentConns []net.Conn
+}
+
+func (tunnel *sshRdsTunnel) LocalPort() int {
+	return tunnel.forwarder.LocalPort()
+}
+
+func (tunnel *sshRdsTunnel) Close() error {
+	tunnel.forwarder.Close()
+	err := tunnel.client.Close()
+	closeAgentConns(tunnel.agentConns)
+	return err
+}
+
+// openSshRdsTunnel opens an SSH connection to the given bastion host, and forwards connections to a local port to the
+// given remote address through it.
+func openSshRdsTunnel(t testing.TestingT, bastion ssh.Host, remoteAddress string) (*sshRdsTunnel, error) {
+	authMethods, agentConns, err := createRdsTunnelAuthMethods(bastion)
+	if err != nil {
+		return nil, err
+	}
+	port := bastion.CustomPort
+	if port == 0 {
+		port = 22
+	}
+
+	logger.Logf(t, "Opening SSH tunnel to %s through %s", remoteAddress, bastion.Hostname)
+	client, err := gossh.Dial("tcp", net.JoinHostPort(bastion.Hostname, strconv.Itoa(port)), &gossh.ClientConfig{
+		User: bastion.SshUserName,
+		Auth: authMethods,
+		// Do not do a host key check, as Terratest is only used for testing, not prod
+		HostKeyCallback: ssh.NoOpHostKeyCallback,
+		Timeout:         rdsTunnelTimeout,
+	})
+	if err != nil {
+		closeAgentConns(agentConns)
+		return nil, err
+	}
+
+	forwarder, err := newLocalPortForwarder(func() (net.Conn, error) {
+		return client.Dial("tcp", remoteAddress)
+	})
+	if err != nil {
+		client.Close()
+		closeAgentConns(agentConns)
+		return nil, err
+	}
+	return &sshRdsTunnel{client: client, forwarder: forwarder, agentConns: agentConns}, nil
+}
+
+// createRdsTunnelAuthMethods returns the SSH authentication methods the given host is configured with, along with the
+// connections to the SSH agents they use, which the caller must close once it no longer needs the methods.
+func createRdsTunnelAuthMethods(host ssh.Host) ([]gossh.AuthMethod, []net.Conn, error) {
+	methods := []gossh.AuthMethod{}
+
+	agentSockets := []string{}
+	if host.OverrideSshAgent != nil {
+		agentSockets = append(agentSockets, host.OverrideSshAgent.SocketFile())
+	}
+	if host.SshAgent {
+		agentSockets = append(agentSockets, os.Getenv("SSH_AUTH_SOCK"))
+	}
+	agentConns := []net.Conn{}
+	for _, socket := range agentSockets {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			closeAgentConns(agentConns)
+			return nil, nil, err
+		}
+		agentConns = append(agentConns, conn)
+		methods = append(methods, gossh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	if host.SshKeyPair != nil {
+		signer, err := gossh.ParsePrivateKey([]byte(host.SshKeyPair.PrivateKey))
+		if err != nil {
+			closeAgentConns(agentConns)
+			return nil, nil, err
+		}
+		methods = append(methods, gossh.PublicKeys(signer))
+	}
+	if host.Password != "" {
+		methods = append(methods, gossh.Password(host.Password))
+	}
+
+	if len(methods) == 0 {
+		return nil, nil, NoSshAuthMethodForBastion{Hostname: host.Hostname}
+	}
+	return methods, agentConns, nil
+}
+
+// closeAgentConns closes the given connections to SSH agents.
+func closeAgentConns(conns []net.Conn) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// localPortForwarder listens on a random local port, and copies the data of each connection it accepts to and from a
+// connection opened with its dial function.
+type localPortForwarder struct {
+	listener net.Listener
+	dial     func() (net.Conn, error)
+	wg       sync.WaitGroup
+}
+
+// newLocalPortForwarder starts forwarding connections to a random local port to connections opened with the given
+// dial function.
+func newLocalPortForwarder(dial func() (net.Conn, error)) (*localPortForwarder, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	forwarder := &localPortForwarder{listener: listener, dial: dial}
+	forwarder.wg.Add(1)
+	go forwarder.acceptConnections()
+	return forwarder, nil
+}
+
+func (forwarder *localPortForwarder) LocalPort() int {
+	return forwarder.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops accepting connections. Connections that were already accepted are closed once either end closes them.
+func (forwarder *localPortForwarder) Close() {
+	forwarder.listener.Close()
+	forwarder.wg.Wait()
+}
+
+func (forwarder *localPortForwarder) acceptConnections() {
+	defer forwarder.wg.Done()
+	for {
+		localConn, err := forwarder.listener.Accept()
+		if err != nil {
+			return
+		}
+		go forwarder.forward(localConn)
+	}
+}
+
+func (forwarder *localPortForwarder) forward(localConn net.Conn) {
+	defer localConn.Close()
+	remoteConn, err := forwarder.dial()
+	if err != nil {
+		return
+	}
+	defer remoteConn.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remoteConn, localConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(localConn, remoteConn)
+		done <- struct{}{}
+	}()
+	// Close both connections as soon as either direction is done.
+	<-done
+}
+
+// UnsupportedRdsEngine is an error that occurs when ConnectAndQuery is called with an engine it doesn't support.
+type UnsupportedRdsEngine struct {
+	Engine string
+}
+
+func (err UnsupportedRdsEngine) Error() string {
+	return fmt.Sprintf("Unsupported RDS engine %q: expected %q or %q", err.Engine, RdsEngineMySql, RdsEnginePostgres)
+}
+
+// NoSshAuthMethodForBastion is an error that occurs when a bastion host has no SSH authentication method configured.
+type NoSshAuthMethodForBastion struct {
+	Hostname string
+}
+
+func (err NoSshAuthMethodForBastion) Error() string {
+	return fmt.Sprintf("No SSH authentication method defined for bastion host %s", err.Hostname)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -145,3 +148,19 @@ func TestGetRecommendedRdsInstanceTypeErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckRdsClusterAvailable(t *testing.T) {
+	t.Parallel()
+
+	cluster := &rds.DBCluster{DBClusterIdentifier: aws.String("cluster"), Status: aws.String("available")}
+	instance := &rds.DBInstance{DBInstanceIdentifier: aws.String("instance-1"), DBInstanceStatus: aws.String("available")}
+	assert.NoError(t, checkRdsClusterAvailable(cluster, []*rds.DBInstance{instance}))
+
+	instance.DBInstanceStatus = aws.String("creating")
+	err := checkRdsClusterAvailable(cluster, []*rds.DBInstance{instance})
+	assert.Equal(t, NewRdsClusterNotAvailableError("cluster", "instance-1", "creating"), err)
+
+	cluster.Status = aws.String("failed")
+	err = checkRdsClusterAvailable(cluster, []*rds.DBInstance{instance})
+	assert.Equal(t, retry.FatalError{Underlying: NewRdsClusterNotAvailableError("cluster", "", "failed")}, err)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/gruntwork-io/terratest/modules/collections"
@@ -42,12 +43,39 @@ var stableRegions = []string{
 	"eu-north-1",     // Launched 2018
 }
 
+// Stable regions by partition. Partitions without stable regions pick from all the regions of the account.
+var stableRegionsByPartition = map[string][]string{
+	endpoints.AwsPartitionID: stableRegions,
+	endpoints.AwsUsGovPartitionID: {
+		"us-gov-west-1", // Launched 2011
+		"us-gov-east-1", // Launched 2018
+	},
+	endpoints.AwsCnPartitionID: {
+		"cn-north-1",     // Launched 2014
+		"cn-northwest-1", // Launched 2017
+	},
+}
+
+// getStableRegions returns the stable regions to pick from: those of all partitions if approvedRegions is not empty, or
+// else those of the partition the tests run in.
+func getStableRegions(approvedRegions []string) []string {
+	if len(approvedRegions) == 0 {
+		return stableRegionsByPartition[GetPartitionForRegion(getDefaultRegion())]
+	}
+	regions := []string{}
+	for _, partitionRegions := range stableRegionsByPartition {
+		regions = append(regions, partitionRegions...)
+	}
+	return regions
+}
+
 // GetRandomStableRegion gets a randomly chosen AWS region that is considered stable. Like GetRandomRegion, you can
 // further restrict the stable region list using approvedRegions and forbiddenRegions. We consider stable regions to be
-// those that have been around for at least 1 year.
+// those that have been around for at least 1 year. The regions are those of the partition of the TERRATEST_REGION or
+// AWS_REGION environment variables (e.g., GovCloud regions), unless approvedRegions is given.
 // Note that regions in the approvedRegions list that are not considered stable are ignored.
 func GetRandomStableRegion(t testing.TestingT, approvedRegions []string, forbiddenRegions []string) string {
-	regionsToPickFrom := stableRegions
+	regionsToPickFrom := getStableRegions(approvedRegions)
 	if len(approvedRegions) > 0 {
 		regionsToPickFrom = collections.ListIntersection(regionsToPickFrom, approvedRegions)
 	}
@@ -108,7 +136,7 @@ func GetAllAwsRegions(t testing.TestingT) []string {
 func GetAllAwsRegionsE(t testing.TestingT) ([]string, error) {
 	logger.Log(t, "Looking up all AWS regions available in this account")
 
-	ec2Client, err := NewEc2ClientE(t, defaultRegion)
+	ec2Client, err := NewEc2ClientE(t, getDefaultRegion())
 	if err != nil {
 		return nil, err
 	}
@@ -171,8 +199,8 @@ func GetRegionsForService(t testing.TestingT, serviceName string) []string {
 // GetRegionsForService gets all AWS regions in which a service is available and returns errors.
 // See https://docs.aws.amazon.com/systems-manager/latest/userguide/parameter-store-public-parameters-global-infrastructure.html
 func GetRegionsForServiceE(t testing.TestingT, serviceName string) ([]string, error) {
-	// These values are available in any region, defaulting to us-east-1 since it's the oldest
-	ssmClient, err := NewSsmClientE(t, "us-east-1")
+	// These values are available in any region, defaulting to the oldest region of the partition
+	ssmClient, err := NewSsmClientE(t, getDefaultRegion())
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,278 @@
+package aws
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// RetryOptions configures how the sessions created by this package, and so all the helpers of this package, retry the
+// requests that fail because they are throttled, because of an eventual consistency error or because of a transient
+// error. Retries are delayed with a jittered exponential backoff between the min and max delays.
+//
+// The defaults are those of the default retryer of the AWS SDK, so the sessions retry as many times as they would
+// without this package. Raise MaxRetries and MaxThrottleDelay with SetRetryOptions for tests that run many requests in
+// parallel and keep being throttled.
+type RetryOptions struct {
+	// MaxRetries is the max number of times a request is retried.
+	MaxRetries int
+	// MinRetryDelay and MaxRetryDelay bound the delay before retrying eventual consistency and transient errors.
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+	// MinThrottleDelay and MaxThrottleDelay bound the delay before retrying throttled requests.
+	MinThrottleDelay time.Duration
+	MaxThrottleDelay time.Duration
+	// RetryBudget is the max number of retries the requests of each test can make per minute, so that a test that keeps
+	// being throttled stops retrying, and fails, rather than making the throttling worse for the tests running in
+	// parallel. Each run of a test has its own budget, which applies to the clients created with the New*Client helpers
+	// of this package, which take the test. Zero means no budget, which is the default.
+	RetryBudget int
+}
+
+// DefaultRetryOptions returns the retry options the sessions created by this package use unless SetRetryOptions is
+// called.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries:       client.DefaultRetryerMaxNumRetries,
+		MinRetryDelay:    client.DefaultRetryerMinRetryDelay,
+		MaxRetryDelay:    client.DefaultRetryerMaxRetryDelay,
+		MinThrottleDelay: client.DefaultRetryerMinThrottleDelay,
+		MaxThrottleDelay: client.DefaultRetryerMaxThrottleDelay,
+	}
+}
+
+// The error codes that are returned when requests are throttled, in addition to the ones the AWS SDK knows about, by
+// service name.
+var serviceThrottleErrorCodes = map[string][]string{
+	"kinesis": {"LimitExceededException"},
+	"s3":      {"SlowDown"},
+	"ssm":     {"TooManyUpdates"},
+}
+
+// eventualConsistencyError is an error that is returned when a request uses a resource that was just created or
+// updated, as it will eventually succeed once the change has propagated. The message is matched as a substring, and an
+// empty message matches any error with the code.
+type eventualConsistencyError struct {
+	code    string
+	message string
+}
+
+// The eventual consistency errors, by service name. This doesn't include the NotFound errors of newly created resources,
+// as the helpers that check resources are deleted expect them.
+var serviceEventualConsistencyErrors = map[string][]eventualConsistencyError{
+	"autoscaling": {{"ValidationError", "Invalid IamInstanceProfile"}},
+	"ec2":         {{"InvalidParameterValue", "Invalid IAM Instance Profile"}},
+	"iam":         {{"ConcurrentModification", ""}},
+	"lambda": {
+		{"InvalidParameterValueException", "cannot be assumed by Lambda"},
+		{"ResourceConflictException", "The operation cannot be performed at this time"},
+	},
+	"s3": {{"OperationAborted", ""}},
+}
+
+var (
+	retryerMutex sync.RWMutex
+	// sessionRetryer is the retryer of all the sessions created by this package.
+	sessionRetryer = newThrottlingRetryer(DefaultRetryOptions())
+)
+
+// SetRetryOptions makes all the sessions created from now on by this package, and so all the helpers of this package,
+// retry failed requests with the given options. Sessions that were already created keep their options. As this applies
+// to the whole package, call it before the tests run, e.g., from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		options := aws.DefaultRetryOptions()
+//		options.MaxRetries = 10
+//		options.RetryBudget = 100
+//		aws.SetRetryOptions(options)
+//		os.Exit(m.Run())
+//	}
+func SetRetryOptions(options RetryOptions) {
+	retryerMutex.Lock()
+	defer retryerMutex.Unlock()
+
+	sessionRetryer = newThrottlingRetryer(options)
+}
+
+// ResetRetryOptions undoes SetRetryOptions, so that the sessions created from now on use DefaultRetryOptions again.
+func ResetRetryOptions() {
+	SetRetryOptions(DefaultRetryOptions())
+}
+
+// getRetryer returns the retryer of the sessions created by this package.
+func getRetryer() *throttlingRetryer {
+	retryerMutex.RLock()
+	defer retryerMutex.RUnlock()
+
+	return sessionRetryer
+}
+
+// throttlingRetryer is a request.Retryer that retries throttled requests with longer delays than the other errors, and
+// adapts to the throttling of each service: once a request to a service is throttled, the retries of all the requests
+// to that service in the same region wait for at least the same delay, so that tests running in parallel back off
+// together instead of throttling each other.
+type throttlingRetryer struct {
+	options RetryOptions
+
+	mutex sync.Mutex
+	// throttledUntil is the time until which the requests to each service in each region are delayed.
+	throttledUntil map[string]time.Time
+	// budgets are the retry budgets of the running tests.
+	budgets *perTestValues
+}
+
+func newThrottlingRetryer(options RetryOptions) *throttlingRetryer {
+	return &throttlingRetryer{
+		options:        options,
+		throttledUntil: map[string]time.Time{},
+		budgets:        newPerTestValues(),
+	}
+}
+
+// forTest returns a retryer that backs off together with this retryer, and that stops retrying once the given test has
+// used its retry budget. Each run of a test, e.g., with go test -count, gets a full budget.
+func (retryer *throttlingRetryer) forTest(t testing.TestingT) request.Retryer {
+	if retryer.options.RetryBudget <= 0 {
+		return retryer
+	}
+
+	budget := retryer.budgets.getOrCreate(t, func() interface{} {
+		return newRetryBudget(retryer.options.RetryBudget, time.Minute)
+	}).(*retryBudget)
+	return &testRetryer{throttlingRetryer: retryer, budget: budget}
+}
+
+// MaxRetries returns the max number of times a request is retried.
+func (retryer *throttlingRetryer) MaxRetries() int {
+	return retryer.options.MaxRetries
+}
+
+// ShouldRetry returns true if the failed request is retryable.
+func (retryer *throttlingRetryer) ShouldRetry(req *request.Request) bool {
+	if req.RetryCount >= retryer.options.MaxRetries {
+		return false
+	}
+	return isThrottleError(req) || isEventualConsistencyError(req) || req.IsErrorRetryable()
+}
+
+// RetryRules returns how long to wait before retrying the failed request.
+func (retryer *throttlingRetryer) RetryRules(req *request.Request) time.Duration {
+	if !isThrottleError(req) {
+		return getJitteredBackoff(req.RetryCount, retryer.options.MinRetryDelay, retryer.options.MaxRetryDelay)
+	}
+
+	delay := getJitteredBackoff(req.RetryCount, retryer.options.MinThrottleDelay, retryer.options.MaxThrottleDelay)
+	key := req.ClientInfo.ServiceName + "/" + aws.StringValue(req.Config.Region)
+	now := time.Now()
+
+	retryer.mutex.Lock()
+	defer retryer.mutex.Unlock()
+
+	if until := retryer.throttledUntil[key]; until.After(now.Add(delay)) {
+		return until.Sub(now)
+	}
+	retryer.throttledUntil[key] = now.Add(delay)
+	return delay
+}
+
+// testRetryer is the throttlingRetryer of the clients of a test, which limits its retries to the retry budget of the
+// test.
+type testRetryer struct {
+	*throttlingRetryer
+	budget *retryBudget
+}
+
+// ShouldRetry returns true if the failed request is retryable and the retry budget of the test allows it.
+func (retryer *testRetryer) ShouldRetry(req *request.Request) bool {
+	return retryer.throttlingRetryer.ShouldRetry(req) && retryer.budget.take(time.Now())
+}
+
+// isThrottleError returns true if the given request failed because it was throttled.
+func isThrottleError(req *request.Request) bool {
+	if req.IsErrorThrottle() {
+		return true
+	}
+	awsErr, ok := req.Error.(awserr.Error)
+	if !ok {
+		return false
+	}
+	for _, code := range serviceThrottleErrorCodes[req.ClientInfo.ServiceName] {
+		if awsErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isEventualConsistencyError returns true if the given request failed because of an eventual consistency error of its
+// service.
+func isEventualConsistencyError(req *request.Request) bool {
+	awsErr, ok := req.Error.(awserr.Error)
+	if !ok {
+		return false
+	}
+	for _, consistencyErr := range serviceEventualConsistencyErrors[req.ClientInfo.ServiceName] {
+		if awsErr.Code() == consistencyErr.code && strings.Contains(awsErr.Message(), consistencyErr.message) {
+			return true
+		}
+	}
+	return false
+}
+
+// getJitteredBackoff returns a random delay between half and all of the exponential backoff of the given retry, which
+// doubles from the min delay with each retry up to the max delay.
+func getJitteredBackoff(retryCount int, minDelay time.Duration, maxDelay time.Duration) time.Duration {
+	delay := minDelay
+	for i := 0; i < retryCount && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay < 2 {
+		return delay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// retryBudget is a token bucket that limits the number of retries per interval. Each retry takes a token, and the
+// bucket is refilled at the next interval.
+type retryBudget struct {
+	mutex    sync.Mutex
+	size     int
+	interval time.Duration
+	tokens   int
+	refillAt time.Time
+}
+
+// newRetryBudget returns a budget of the given number of retries per interval, or of unlimited retries if it is zero.
+func newRetryBudget(size int, interval time.Duration) *retryBudget {
+	return &retryBudget{size: size, interval: interval, tokens: size}
+}
+
+// take returns true if the budget allows another retry at the given time.
+func (budget *retryBudget) take(now time.Time) bool {
+	if budget.size <= 0 {
+		return true
+	}
+
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	if !now.Before(budget.refillAt) {
+		budget.tokens = budget.size
+		budget.refillAt = now.Add(budget.interval)
+	}
+	if budget.tokens == 0 {
+		return false
+	}
+	budget.tokens--
+	return true
+}
@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFailedRequest(serviceName string, statusCode int, err error) *request.Request {
+	return &request.Request{
+		ClientInfo:   metadata.ClientInfo{ServiceName: serviceName},
+		Config:       aws.Config{Region: aws.String("us-east-1")},
+		HTTPResponse: &http.Response{StatusCode: statusCode},
+		Error:        err,
+	}
+}
+
+func TestThrottlingRetryerShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	retryer := newThrottlingRetryer(DefaultRetryOptions())
+
+	testCases := []struct {
+		name     string
+		req      *request.Request
+		expected bool
+	}{
+		{"throttling", newFailedRequest("ec2", 400, awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)), true},
+		{"too many requests", newFailedRequest("ecs", 429, awserr.New("SomethingElse", "", nil)), true},
+		{"service throttling", newFailedRequest("s3", 400, awserr.New("SlowDown", "Please reduce your request rate.", nil)), true},
+		{"other service throttling code", newFailedRequest("ec2", 400, awserr.New("SlowDown", "", nil)), false},
+		{"eventual consistency", newFailedRequest("lambda", 400, awserr.New("InvalidParameterValueException", "The role defined for the function cannot be assumed by Lambda.", nil)), true},
+		{"other message", newFailedRequest("lambda", 400, awserr.New("InvalidParameterValueException", "Unsupported runtime.", nil)), false},
+		{"internal error", newFailedRequest("iam", 500, awserr.New("ServiceUnavailable", "", nil)), true},
+		{"not found", newFailedRequest("ec2", 400, awserr.New("InvalidInstanceID.NotFound", "", nil)), false},
+		{"access denied", newFailedRequest("s3", 403, awserr.New("AccessDenied", "Access Denied", nil)), false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, retryer.ShouldRetry(testCase.req), testCase.name)
+	}
+
+	exhausted := newFailedRequest("ec2", 400, awserr.New("RequestLimitExceeded", "", nil))
+	exhausted.RetryCount = DefaultRetryOptions().MaxRetries
+	assert.False(t, retryer.ShouldRetry(exhausted))
+}
+
+func TestThrottlingRetryerRetryRules(t *testing.T) {
+	t.Parallel()
+
+	options := RetryOptions{
+		MaxRetries:       10,
+		MinRetryDelay:    200 * time.Millisecond,
+		MaxRetryDelay:    5 * time.Second,
+		MinThrottleDelay: 1 * time.Second,
+		MaxThrottleDelay: 60 * time.Second,
+	}
+	retryer := newThrottlingRetryer(options)
+
+	transient := newFailedRequest("iam", 500, awserr.New("InternalFailure", "", nil))
+	delay := retryer.RetryRules(transient)
+	assert.True(t, delay >= options.MinRetryDelay/2 && delay <= options.MinRetryDelay, "delay %s", delay)
+
+	throttled := newFailedRequest("ec2", 400, awserr.New("RequestLimitExceeded", "", nil))
+	throttled.RetryCount = 3
+	first := retryer.RetryRules(throttled)
+	assert.True(t, first >= 4*time.Second && first <= 8*time.Second, "delay %s", first)
+
+	// Once the service is throttled, the retries of the other requests to it wait for at least as long.
+	other := newFailedRequest("ec2", 400, awserr.New("RequestLimitExceeded", "", nil))
+	assert.True(t, retryer.RetryRules(other) > first-time.Second)
+
+	otherRegion := newFailedRequest("ec2", 400, awserr.New("RequestLimitExceeded", "", nil))
+	otherRegion.Config.Region = aws.String("eu-west-1")
+	assert.True(t, retryer.RetryRules(otherRegion) <= options.MinThrottleDelay)
+}
+
+func TestThrottlingRetryerForTest(t *testing.T) {
+	t.Parallel()
+
+	unlimited := newThrottlingRetryer(DefaultRetryOptions())
+	assert.Equal(t, unlimited, unlimited.forTest(t))
+
+	options := DefaultRetryOptions()
+	options.RetryBudget = 1
+	retryer := newThrottlingRetryer(options)
+
+	throttled := func() *request.Request {
+		return newFailedRequest("ec2", 400, awserr.New("RequestLimitExceeded", "", nil))
+	}
+
+	// A rerun of a test, with the same name, gets a full budget.
+	for run := 0; run < 2; run++ {
+		t.Run("first", func(t *testing.T) {
+			first := retryer.forTest(t)
+			assert.True(t, first.ShouldRetry(throttled()))
+			assert.False(t, first.ShouldRetry(throttled()))
+			assert.False(t, retryer.forTest(t).ShouldRetry(throttled()))
+		})
+	}
+
+	// The budget of a test is not used up by the retries of the other tests, nor by the errors that are not retried.
+	t.Run("second", func(t *testing.T) {
+		second := retryer.forTest(t)
+		assert.False(t, second.ShouldRetry(newFailedRequest("s3", 403, awserr.New("AccessDenied", "Access Denied", nil))))
+		assert.True(t, second.ShouldRetry(throttled()))
+	})
+}
+
+func TestGetJitteredBackoff(t *testing.T) {
+	t.Parallel()
+
+	for retryCount := 0; retryCount < 10; retryCount++ {
+		expected := time.Second << uint(retryCount)
+		if expected > 30*time.Second {
+			expected = 30 * time.Second
+		}
+		delay := getJitteredBackoff(retryCount, time.Second, 30*time.Second)
+		assert.True(t, delay >= expected/2 && delay <= expected, "retry %d: delay %s", retryCount, delay)
+	}
+	assert.Equal(t, time.Duration(0), getJitteredBackoff(3, 0, 0))
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	budget := newRetryBudget(2, time.Minute)
+	assert.True(t, budget.take(now))
+	assert.True(t, budget.take(now))
+	assert.False(t, budget.take(now.Add(30*time.Second)))
+	assert.True(t, budget.take(now.Add(time.Minute)))
+
+	unlimited := newRetryBudget(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		assert.True(t, unlimited.take(now))
+	}
+}
@@ -0,0 +1,204 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// dnsResolvePollInterval is how often WaitUntilDnsResolvesTo resolves a name.
+const dnsResolvePollInterval = 10 * time.Second
+
+// GetRoute53Record fetches the record with the given name and type (e.g., route53.RRTypeA) from the given hosted zone.
+func GetRoute53Record(t testing.TestingT, hostedZoneID string, recordName string, recordType string, awsRegion string) *route53.ResourceRecordSet {
+	record, err := GetRoute53RecordE(t, hostedZoneID, recordName, recordType, awsRegion)
+	require.NoError(t, err)
+	return record
+}
+
+// GetRoute53RecordE fetches the record with the given name and type (e.g., route53.RRTypeA) from the given hosted zone.
+// If the hosted zone has several records with the name and type (e.g., weighted records), the first one is returned.
+func GetRoute53RecordE(t testing.TestingT, hostedZoneID string, recordName string, recordType string, awsRegion string) (*route53.ResourceRecordSet, error) {
+	client, err := NewRoute53ClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(hostedZoneID),
+		StartRecordName: aws.String(recordName),
+		StartRecordType: aws.String(recordType),
+		MaxItems:        aws.String("1"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The records are listed starting from the given name and type, so the first record is a different one if there
+	// is no record with them.
+	if len(output.ResourceRecordSets) > 0 {
+		record := output.ResourceRecordSets[0]
+		if normalizeDnsName(aws.StringValue(record.Name)) == normalizeDnsName(recordName) && aws.StringValue(record.Type) == recordType {
+			return record, nil
+		}
+	}
+	return nil, NewNotFoundError("Route 53 "+recordType+" record", recordName, hostedZoneID)
+}
+
+// AssertRoute53RecordAliasTarget checks that the record with the given name and type in the given hosted zone is an
+// alias for the given DNS name (e.g., the DNS name of a load balancer), and fails the test if it is not.
+func AssertRoute53RecordAliasTarget(t testing.TestingT, hostedZoneID string, recordName string, recordType string, expectedDNSName string, awsRegion string) {
+	err := AssertRoute53RecordAliasTargetE(t, hostedZoneID, recordName, recordType, expectedDNSName, awsRegion)
+	require.NoError(t, err)
+}
+
+// AssertRoute53RecordAliasTargetE checks that the record with the given name and type in the given hosted zone is an
+// alias for the given DNS name (e.g., the DNS name of a load balancer), and returns an error if it is not. The names are
+// compared ignoring case, the trailing dot, and the dualstack. prefix Route 53 adds to load balancer aliases.
+func AssertRoute53RecordAliasTargetE(t testing.TestingT, hostedZoneID string, recordName string, recordType string, expectedDNSName string, awsRegion string) error {
+	record, err := GetRoute53RecordE(t, hostedZoneID, recordName, recordType, awsRegion)
+	if err != nil {
+		return err
+	}
+	return checkRoute53RecordAliasTarget(record, expectedDNSName)
+}
+
+func checkRoute53RecordAliasTarget(record *route53.ResourceRecordSet, expectedDNSName string) error {
+	recordName := aws.StringValue(record.Name)
+	if record.AliasTarget == nil {
+		return NewRoute53AliasTargetMismatchError(recordName, expectedDNSName, "")
+	}
+	actualDNSName := aws.StringValue(record.AliasTarget.DNSName)
+	normalizeAliasName := func(name string) string {
+		return strings.TrimPrefix(normalizeDnsName(name), "dualstack.")
+	}
+	if normalizeAliasName(actualDNSName) != normalizeAliasName(expectedDNSName) {
+		return NewRoute53AliasTargetMismatchError(recordName, expectedDNSName, actualDNSName)
+	}
+	return nil
+}
+
+// normalizeDnsName normalizes the given DNS name, so that names that refer to the same domain are equal. Route 53
+// returns names with a trailing dot, and with * escaped as \052.
+func normalizeDnsName(name string) string {
+	name = strings.Replace(name, `\052`, "*", -1)
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// WaitUntilDnsResolvesTo waits until the given fully qualified domain name resolves to all of the expected values via
+// every given resolver (e.g., "8.8.8.8:53"), checking every 10 seconds until the given timeout. This will fail the test
+// if it doesn't in time.
+func WaitUntilDnsResolvesTo(t testing.TestingT, fqdn string, expected []string, resolverAddrs []string, timeout time.Duration) {
+	err := WaitUntilDnsResolvesToE(t, fqdn, expected, resolverAddrs, timeout)
+	require.NoError(t, err)
+}
+
+// WaitUntilDnsResolvesToE waits until the given fully qualified domain name resolves to all of the expected values via
+// every given resolver (e.g., "8.8.8.8:53"), checking every 10 seconds until the given timeout. If no resolvers are
+// given, the system resolver is used. This is useful to check that a record created with terraform has propagated.
+//
+// The expected values can be IP addresses or the canonical name of the domain (e.g., the target of a CNAME record).
+// The name may resolve to other addresses too, as names such as load balancer aliases resolve to several addresses
+// that change over time.
+func WaitUntilDnsResolvesToE(t testing.TestingT, fqdn string, expected []string, resolverAddrs []string, timeout time.Duration) error {
+	sleepBetweenRetries := dnsResolvePollInterval
+	if timeout < sleepBetweenRetries {
+		sleepBetweenRetries = timeout
+	}
+	maxRetries := 1
+	if sleepBetweenRetries > 0 {
+		maxRetries = int(timeout/sleepBetweenRetries) + 1
+	}
+
+	resolvers := resolverAddrs
+	if len(resolvers) == 0 {
+		resolvers = []string{""}
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %s to resolve to %v.", fqdn, expected),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			for _, resolverAddr := range resolvers {
+				answers, err := resolveDnsName(fqdn, resolverAddr)
+				if err != nil {
+					return "", err
+				}
+				if missing := getMissingDnsAnswers(answers, expected); len(missing) > 0 {
+					return "", NewDnsNotResolvedError(fqdn, resolverAddr, expected, answers)
+				}
+			}
+			return fmt.Sprintf("%s now resolves to %v", fqdn, expected), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// resolveDnsName returns the addresses and the canonical name the given name resolves to via the given resolver, or
+// via the system resolver if the resolver is empty.
+func resolveDnsName(fqdn string, resolverAddr string) ([]string, error) {
+	resolver := net.DefaultResolver
+	if resolverAddr != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsResolvePollInterval)
+	defer cancel()
+
+	answers, err := resolver.LookupHost(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	if canonicalName, err := resolver.LookupCNAME(ctx, fqdn); err == nil {
+		answers = append(answers, canonicalName)
+	}
+	return answers, nil
+}
+
+// getMissingDnsAnswers returns the expected values that are not in the given answers.
+func getMissingDnsAnswers(answers []string, expected []string) []string {
+	found := map[string]bool{}
+	for _, answer := range answers {
+		found[normalizeDnsName(answer)] = true
+	}
+	missing := []string{}
+	for _, value := range expected {
+		if !found[normalizeDnsName(value)] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// NewRoute53Client creates a Route 53 client.
+func NewRoute53Client(t testing.TestingT, region string) *route53.Route53 {
+	client, err := NewRoute53ClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewRoute53ClientE creates a Route 53 client.
+func NewRoute53ClientE(t testing.TestingT, region string) (*route53.Route53, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return route53.New(sess), nil
+}
@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRoute53RecordAliasTarget(t *testing.T) {
+	t.Parallel()
+
+	record := &route53.ResourceRecordSet{
+		Name: aws.String("app.example.com."),
+		Type: aws.String(route53.RRTypeA),
+		AliasTarget: &route53.AliasTarget{
+			DNSName: aws.String("dualstack.my-alb-123456.us-east-1.elb.amazonaws.com."),
+		},
+	}
+	assert.NoError(t, checkRoute53RecordAliasTarget(record, "my-alb-123456.us-east-1.elb.amazonaws.com"))
+	assert.NoError(t, checkRoute53RecordAliasTarget(record, "My-ALB-123456.us-east-1.elb.amazonaws.com."))
+	assert.Error(t, checkRoute53RecordAliasTarget(record, "other-alb-123456.us-east-1.elb.amazonaws.com"))
+
+	record.AliasTarget = nil
+	assert.Equal(t, NewRoute53AliasTargetMismatchError("app.example.com.", "my-alb", ""), checkRoute53RecordAliasTarget(record, "my-alb"))
+}
+
+func TestNormalizeDnsName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "*.example.com", normalizeDnsName(`\052.Example.com.`))
+	assert.Equal(t, "example.com", normalizeDnsName("example.com"))
+}
+
+func TestGetMissingDnsAnswers(t *testing.T) {
+	t.Parallel()
+
+	answers := []string{"10.0.0.1", "10.0.0.2", "target.example.com."}
+	assert.Empty(t, getMissingDnsAnswers(answers, []string{"10.0.0.2", "Target.example.com"}))
+	assert.Equal(t, []string{"10.0.0.3"}, getMissingDnsAnswers(answers, []string{"10.0.0.1", "10.0.0.3"}))
+}
+
+func TestWaitUntilDnsResolvesToLocalhost(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, WaitUntilDnsResolvesToE(t, "localhost", []string{"127.0.0.1"}, nil, 0))
+}
@@ -2,8 +2,13 @@ package aws
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -470,6 +475,353 @@ func AssertS3BucketPolicyExistsE(t testing.TestingT, region string, bucketName s
 	return nil
 }
 
+// GetS3ObjectPresignedURL returns a presigned URL that can be used to download the given object from the given bucket
+// without AWS credentials until the given expiry.
+func GetS3ObjectPresignedURL(t testing.TestingT, awsRegion string, bucket string, key string, expiry time.Duration) string {
+	url, err := GetS3ObjectPresignedURLE(t, awsRegion, bucket, key, expiry)
+	require.NoError(t, err)
+	return url
+}
+
+// GetS3ObjectPresignedURLE returns a presigned URL that can be used to download the given object from the given bucket
+// without AWS credentials until the given expiry.
+func GetS3ObjectPresignedURLE(t testing.TestingT, awsRegion string, bucket string, key string, expiry time.Duration) (string, error) {
+	s3Client, err := NewS3ClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	req, _ := s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+// PutS3ObjectPresignedURL returns a presigned URL that can be used to upload the given object to the given bucket with
+// an HTTP PUT request without AWS credentials until the given expiry.
+func PutS3ObjectPresignedURL(t testing.TestingT, awsRegion string, bucket string, key string, expiry time.Duration) string {
+	url, err := PutS3ObjectPresignedURLE(t, awsRegion, bucket, key, expiry)
+	require.NoError(t, err)
+	return url
+}
+
+// PutS3ObjectPresignedURLE returns a presigned URL that can be used to upload the given object to the given bucket with
+// an HTTP PUT request without AWS credentials until the given expiry.
+func PutS3ObjectPresignedURLE(t testing.TestingT, awsRegion string, bucket string, key string, expiry time.Duration) (string, error) {
+	s3Client, err := NewS3ClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	req, _ := s3Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+// VerifyS3PresignedURLContents downloads the object at the given presigned URL and checks that its contents are the
+// expected contents. This will fail the test if the download fails or the contents differ.
+func VerifyS3PresignedURLContents(t testing.TestingT, presignedURL string, expectedContents string) {
+	err := VerifyS3PresignedURLContentsE(t, presignedURL, expectedContents)
+	require.NoError(t, err)
+}
+
+// VerifyS3PresignedURLContentsE downloads the object at the given presigned URL and checks that its contents are the
+// expected contents. This is useful to test that a presigned URL handed out by your code actually grants access.
+func VerifyS3PresignedURLContentsE(t testing.TestingT, presignedURL string, expectedContents string) error {
+	logger.Logf(t, "Downloading object from presigned URL")
+
+	resp, err := http.Get(presignedURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return S3PresignedURLErr{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if string(body) != expectedContents {
+		return S3PresignedURLErr{StatusCode: resp.StatusCode, Body: string(body), UnexpectedContents: true}
+	}
+	return nil
+}
+
+// UploadLargeS3Object uploads the contents of the given reader to the given object in the given bucket using a multipart
+// upload with parts of the given size, and returns the URL of the object.
+func UploadLargeS3Object(t testing.TestingT, awsRegion string, bucket string, key string, body io.Reader, partSize int64) string {
+	location, err := UploadLargeS3ObjectE(t, awsRegion, bucket, key, body, partSize)
+	require.NoError(t, err)
+	return location
+}
+
+// UploadLargeS3ObjectE uploads the contents of the given reader to the given object in the given bucket using a
+// multipart upload with parts of the given size (at least 5 MB, or s3manager.DefaultUploadPartSize if 0), and returns
+// the URL of the object. Objects smaller than the part size are uploaded in a single request.
+func UploadLargeS3ObjectE(t testing.TestingT, awsRegion string, bucket string, key string, body io.Reader, partSize int64) (string, error) {
+	logger.Logf(t, "Uploading object %s to bucket %s", key, bucket)
+
+	uploader, err := NewS3UploaderE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	if partSize > 0 {
+		uploader.PartSize = partSize
+	}
+
+	output, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	if err != nil {
+		return "", err
+	}
+	return output.Location, nil
+}
+
+// S3BucketPolicy is the parsed policy of an S3 bucket.
+type S3BucketPolicy struct {
+	Version   string
+	Statement []S3BucketPolicyStatement
+}
+
+// S3BucketPolicyStatement is a statement of the policy of an S3 bucket. Action and Resource are always lists, even if
+// the policy sets them to a single string.
+type S3BucketPolicyStatement struct {
+	Sid       string
+	Effect    string
+	Principal interface{}
+	Action    policyStringList
+	Resource  policyStringList
+	Condition map[string]map[string]interface{}
+}
+
+// policyStringList is a list of strings in an IAM policy, which may be written as a single string.
+type policyStringList []string
+
+func (list *policyStringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*list = []string{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*list = multiple
+	return nil
+}
+
+// GetS3BucketPolicyDocument fetches the given bucket's resource policy and parses it.
+func GetS3BucketPolicyDocument(t testing.TestingT, awsRegion string, bucket string) *S3BucketPolicy {
+	policy, err := GetS3BucketPolicyDocumentE(t, awsRegion, bucket)
+	require.NoError(t, err)
+	return policy
+}
+
+// GetS3BucketPolicyDocumentE fetches the given bucket's resource policy and parses it.
+func GetS3BucketPolicyDocumentE(t testing.TestingT, awsRegion string, bucket string) (*S3BucketPolicy, error) {
+	policyJSON, err := GetS3BucketPolicyE(t, awsRegion, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return parseS3BucketPolicy(policyJSON)
+}
+
+func parseS3BucketPolicy(policyJSON string) (*S3BucketPolicy, error) {
+	policy := &S3BucketPolicy{}
+	if err := json.Unmarshal([]byte(policyJSON), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// AssertS3BucketPolicyDeniesInsecureTransport checks that the given bucket's policy denies requests that don't use TLS,
+// and fails the test if it does not.
+func AssertS3BucketPolicyDeniesInsecureTransport(t testing.TestingT, awsRegion string, bucket string) {
+	err := AssertS3BucketPolicyDeniesInsecureTransportE(t, awsRegion, bucket)
+	require.NoError(t, err)
+}
+
+// AssertS3BucketPolicyDeniesInsecureTransportE checks that the given bucket's policy denies requests that don't use TLS
+// (with a Deny statement on the aws:SecureTransport condition key), and returns an error if it does not.
+func AssertS3BucketPolicyDeniesInsecureTransportE(t testing.TestingT, awsRegion string, bucket string) error {
+	policy, err := GetS3BucketPolicyDocumentE(t, awsRegion, bucket)
+	if err != nil {
+		return err
+	}
+	if !policyDeniesInsecureTransport(policy) {
+		return S3BucketConfigMismatchErr{Bucket: bucket, Region: awsRegion, Setting: "policy", Expected: "a statement that denies insecure transport", Actual: "none"}
+	}
+	return nil
+}
+
+func policyDeniesInsecureTransport(policy *S3BucketPolicy) bool {
+	for _, statement := range policy.Statement {
+		if statement.Effect != "Deny" {
+			continue
+		}
+		for operator, conditions := range statement.Condition {
+			if operator != "Bool" {
+				continue
+			}
+			if value, hasKey := conditions["aws:SecureTransport"]; hasKey && fmt.Sprintf("%v", value) == "false" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetS3BucketLifecycleRules fetches the given bucket's lifecycle rules.
+func GetS3BucketLifecycleRules(t testing.TestingT, awsRegion string, bucket string) []*s3.LifecycleRule {
+	rules, err := GetS3BucketLifecycleRulesE(t, awsRegion, bucket)
+	require.NoError(t, err)
+	return rules
+}
+
+// GetS3BucketLifecycleRulesE fetches the given bucket's lifecycle rules.
+func GetS3BucketLifecycleRulesE(t testing.TestingT, awsRegion string, bucket string) ([]*s3.LifecycleRule, error) {
+	s3Client, err := NewS3ClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s3Client.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Rules, nil
+}
+
+// AssertS3BucketLifecycleRuleEnabled checks that the given bucket has an enabled lifecycle rule with the given ID, and
+// fails the test if it does not.
+func AssertS3BucketLifecycleRuleEnabled(t testing.TestingT, awsRegion string, bucket string, ruleID string) {
+	err := AssertS3BucketLifecycleRuleEnabledE(t, awsRegion, bucket, ruleID)
+	require.NoError(t, err)
+}
+
+// AssertS3BucketLifecycleRuleEnabledE checks that the given bucket has an enabled lifecycle rule with the given ID, and
+// returns an error if it does not.
+func AssertS3BucketLifecycleRuleEnabledE(t testing.TestingT, awsRegion string, bucket string, ruleID string) error {
+	rules, err := GetS3BucketLifecycleRulesE(t, awsRegion, bucket)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if aws.StringValue(rule.ID) != ruleID {
+			continue
+		}
+		if status := aws.StringValue(rule.Status); status != s3.ExpirationStatusEnabled {
+			return S3BucketConfigMismatchErr{Bucket: bucket, Region: awsRegion, Setting: "status of lifecycle rule " + ruleID, Expected: s3.ExpirationStatusEnabled, Actual: status}
+		}
+		return nil
+	}
+	return S3BucketConfigMismatchErr{Bucket: bucket, Region: awsRegion, Setting: "lifecycle rule " + ruleID, Expected: "a rule", Actual: "none"}
+}
+
+// GetS3BucketEncryptionAlgorithm fetches the server-side encryption algorithm (e.g., s3.ServerSideEncryptionAwsKms) of
+// the default encryption rule of the given bucket.
+func GetS3BucketEncryptionAlgorithm(t testing.TestingT, awsRegion string, bucket string) string {
+	algorithm, err := GetS3BucketEncryptionAlgorithmE(t, awsRegion, bucket)
+	require.NoError(t, err)
+	return algorithm
+}
+
+// GetS3BucketEncryptionAlgorithmE fetches the server-side encryption algorithm (e.g., s3.ServerSideEncryptionAwsKms) of
+// the default encryption rule of the given bucket.
+func GetS3BucketEncryptionAlgorithmE(t testing.TestingT, awsRegion string, bucket string) (string, error) {
+	s3Client, err := NewS3ClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+	res, err := s3Client.GetBucketEncryption(&s3.GetBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return "", err
+	}
+	if res.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range res.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault != nil {
+				return aws.StringValue(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// AssertS3BucketEncryption checks that the given bucket encrypts objects by default with the given algorithm (e.g.,
+// s3.ServerSideEncryptionAwsKms), and fails the test if it does not.
+func AssertS3BucketEncryption(t testing.TestingT, awsRegion string, bucket string, expectedAlgorithm string) {
+	err := AssertS3BucketEncryptionE(t, awsRegion, bucket, expectedAlgorithm)
+	require.NoError(t, err)
+}
+
+// AssertS3BucketEncryptionE checks that the given bucket encrypts objects by default with the given algorithm (e.g.,
+// s3.ServerSideEncryptionAwsKms), and returns an error if it does not.
+func AssertS3BucketEncryptionE(t testing.TestingT, awsRegion string, bucket string, expectedAlgorithm string) error {
+	algorithm, err := GetS3BucketEncryptionAlgorithmE(t, awsRegion, bucket)
+	if err != nil {
+		return err
+	}
+	if algorithm != expectedAlgorithm {
+		return S3BucketConfigMismatchErr{Bucket: bucket, Region: awsRegion, Setting: "default encryption algorithm", Expected: expectedAlgorithm, Actual: algorithm}
+	}
+	return nil
+}
+
+// AssertS3BucketPublicAccessBlocked checks that all four settings of the public access block of the given bucket are
+// enabled, and fails the test if they are not.
+func AssertS3BucketPublicAccessBlocked(t testing.TestingT, awsRegion string, bucket string) {
+	err := AssertS3BucketPublicAccessBlockedE(t, awsRegion, bucket)
+	require.NoError(t, err)
+}
+
+// AssertS3BucketPublicAccessBlockedE checks that all four settings of the public access block of the given bucket
+// (BlockPublicAcls, BlockPublicPolicy, IgnorePublicAcls, and RestrictPublicBuckets) are enabled, and returns an error if
+// they are not.
+func AssertS3BucketPublicAccessBlockedE(t testing.TestingT, awsRegion string, bucket string) error {
+	s3Client, err := NewS3ClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+	res, err := s3Client.GetPublicAccessBlock(&s3.GetPublicAccessBlockInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return err
+	}
+	return checkS3PublicAccessBlock(bucket, awsRegion, res.PublicAccessBlockConfiguration)
+}
+
+func checkS3PublicAccessBlock(bucket string, awsRegion string, config *s3.PublicAccessBlockConfiguration) error {
+	if config == nil {
+		config = &s3.PublicAccessBlockConfiguration{}
+	}
+	settings := []struct {
+		name  string
+		value *bool
+	}{
+		{"BlockPublicAcls", config.BlockPublicAcls},
+		{"BlockPublicPolicy", config.BlockPublicPolicy},
+		{"IgnorePublicAcls", config.IgnorePublicAcls},
+		{"RestrictPublicBuckets", config.RestrictPublicBuckets},
+	}
+	for _, setting := range settings {
+		if !aws.BoolValue(setting.value) {
+			return S3BucketConfigMismatchErr{Bucket: bucket, Region: awsRegion, Setting: "public access block setting " + setting.name, Expected: "true", Actual: "false"}
+		}
+	}
+	return nil
+}
+
 // NewS3Client creates an S3 client.
 func NewS3Client(t testing.TestingT, region string) *s3.S3 {
 	client, err := NewS3ClientE(t, region)
@@ -480,7 +832,7 @@ func NewS3Client(t testing.TestingT, region string) *s3.S3 {
 
 // NewS3ClientE creates an S3 client.
 func NewS3ClientE(t testing.TestingT, region string) (*s3.S3, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -497,7 +849,7 @@ func NewS3Uploader(t testing.TestingT, region string) *s3manager.Uploader {
 
 // NewS3UploaderE creates an S3 Uploader.
 func NewS3UploaderE(t testing.TestingT, region string) (*s3manager.Uploader, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -514,3 +866,31 @@ type S3AccessLoggingNotEnabledErr struct {
 func (err S3AccessLoggingNotEnabledErr) Error() string {
 	return fmt.Sprintf("Server Acess Logging hasn't been enabled for S3 Bucket %s in region %s", err.OriginBucket, err.Region)
 }
+
+// S3PresignedURLErr is a custom error that occurs when an object can't be downloaded from a presigned URL, or has
+// unexpected contents.
+type S3PresignedURLErr struct {
+	StatusCode         int
+	Body               string
+	UnexpectedContents bool
+}
+
+func (err S3PresignedURLErr) Error() string {
+	if err.UnexpectedContents {
+		return fmt.Sprintf("Object downloaded from presigned URL has unexpected contents: %s", err.Body)
+	}
+	return fmt.Sprintf("Failed to download object from presigned URL: status code %d: %s", err.StatusCode, err.Body)
+}
+
+// S3BucketConfigMismatchErr is a custom error that occurs when an S3 bucket is not configured as expected
+type S3BucketConfigMismatchErr struct {
+	Bucket   string
+	Region   string
+	Setting  string
+	Expected string
+	Actual   string
+}
+
+func (err S3BucketConfigMismatchErr) Error() string {
+	return fmt.Sprintf("Expected %s of S3 Bucket %s in region %s to be %s, but got %s", err.Setting, err.Bucket, err.Region, err.Expected, err.Actual)
+}
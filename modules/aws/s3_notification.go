@@ -0,0 +1,426 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// S3NotificationTarget is the type of destination an S3 bucket sends event notifications to.
+type S3NotificationTarget string
+
+const (
+	S3NotificationTargetSqs         S3NotificationTarget = "SQS"
+	S3NotificationTargetLambda      S3NotificationTarget = "Lambda"
+	S3NotificationTargetEventBridge S3NotificationTarget = "EventBridge"
+)
+
+// The S3 event types an upload with PutObject is notified as.
+var s3ObjectCreatedByPutEvents = []string{
+	"s3:ObjectCreated:*",
+	s3.EventS3ObjectCreatedPut,
+}
+
+// VerifyS3EventNotification uploads an object with the given key to the given bucket, and checks that the notification
+// target of the given type the bucket is configured with receives the corresponding event within the given timeout. This
+// returns the received event and will fail the test if it is not received.
+func VerifyS3EventNotification(t testing.TestingT, region string, bucket string, key string, target S3NotificationTarget, timeout time.Duration) string {
+	event, err := VerifyS3EventNotificationE(t, region, bucket, key, target, timeout)
+	require.NoError(t, err)
+	return event
+}
+
+// VerifyS3EventNotificationE uploads an object with the given key to the given bucket, and checks that the notification
+// target of the given type the bucket is configured with receives the corresponding event within the given timeout. The
+// key must match the prefix and suffix filters of the notification configuration. The object is deleted before
+// returning, which the target may be notified of too. This returns the received event, which is:
+//
+//   - For SQS, the body of the message the queue received. The message is deleted from the queue.
+//   - For Lambda, the log event of the function that contains the key, so the function must log the events it handles.
+//     As keys are URL encoded in the events, use a key without characters that need encoding.
+//   - For EventBridge, the event delivered to a temporary rule on the default event bus, which is created and deleted by
+//     this function. As the rule can take a while to become active, the object is uploaded again until it is delivered.
+func VerifyS3EventNotificationE(t testing.TestingT, region string, bucket string, key string, target S3NotificationTarget, timeout time.Duration) (string, error) {
+	client, err := NewS3ClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	config, err := client.GetBucketNotificationConfiguration(&s3.GetBucketNotificationConfigurationRequest{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return "", err
+	}
+	targetArn, err := findS3NotificationTargetArn(config, bucket, key, target)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_, err := client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err != nil {
+			logger.Logf(t, "Failed to delete test object %s from bucket %s: %v", key, bucket, err)
+		}
+	}()
+
+	switch target {
+	case S3NotificationTargetSqs:
+		return verifyS3EventNotificationToQueueE(t, region, bucket, key, targetArn, timeout)
+	case S3NotificationTargetLambda:
+		return verifyS3EventNotificationToLambdaE(t, region, bucket, key, targetArn, timeout)
+	default:
+		return verifyS3EventNotificationToEventBridgeE(t, region, bucket, key, timeout)
+	}
+}
+
+// findS3NotificationTargetArn returns the ARN of the first target of the given type in the given notification
+// configuration that is notified of the upload of an object with the given key, which is empty for EventBridge as it
+// always receives all the events of the bucket.
+func findS3NotificationTargetArn(config *s3.NotificationConfiguration, bucket string, key string, target S3NotificationTarget) (string, error) {
+	switch target {
+	case S3NotificationTargetSqs:
+		for _, queue := range config.QueueConfigurations {
+			if s3NotificationMatches(queue.Events, queue.Filter, key) {
+				return aws.StringValue(queue.QueueArn), nil
+			}
+		}
+	case S3NotificationTargetLambda:
+		for _, function := range config.LambdaFunctionConfigurations {
+			if s3NotificationMatches(function.Events, function.Filter, key) {
+				return aws.StringValue(function.LambdaFunctionArn), nil
+			}
+		}
+	case S3NotificationTargetEventBridge:
+		if config.EventBridgeConfiguration != nil {
+			return "", nil
+		}
+	default:
+		return "", fmt.Errorf("unknown S3 notification target %s", target)
+	}
+	return "", NewS3EventNotificationNotConfiguredError(bucket, key, string(target))
+}
+
+// s3NotificationMatches returns true if a notification for the given events and filter is sent when an object with the
+// given key is uploaded.
+func s3NotificationMatches(events []*string, filter *s3.NotificationConfigurationFilter, key string) bool {
+	if filter != nil && filter.Key != nil {
+		for _, rule := range filter.Key.FilterRules {
+			value := aws.StringValue(rule.Value)
+			switch strings.ToLower(aws.StringValue(rule.Name)) {
+			case s3.FilterRuleNamePrefix:
+				if !strings.HasPrefix(key, value) {
+					return false
+				}
+			case s3.FilterRuleNameSuffix:
+				if !strings.HasSuffix(key, value) {
+					return false
+				}
+			}
+		}
+	}
+	for _, event := range aws.StringValueSlice(events) {
+		for _, putEvent := range s3ObjectCreatedByPutEvents {
+			if event == putEvent {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// putS3NotificationTestObjectE uploads an object with the given key to the given bucket to trigger its notifications.
+func putS3NotificationTestObjectE(t testing.TestingT, client *s3.S3, bucket string, key string) error {
+	logger.Logf(t, "Uploading test object %s to bucket %s", key, bucket)
+	_, err := client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   strings.NewReader("terratest-" + random.UniqueId()),
+	})
+	return err
+}
+
+// verifyS3EventNotificationToQueueE uploads the object and waits for the given queue to receive the event.
+func verifyS3EventNotificationToQueueE(t testing.TestingT, region string, bucket string, key string, queueArn string, timeout time.Duration) (string, error) {
+	s3Client, err := NewS3ClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	sqsClient, err := NewSqsClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	parsedArn, err := arn.Parse(queueArn)
+	if err != nil {
+		return "", err
+	}
+	queue, err := sqsClient.GetQueueUrl(&sqs.GetQueueUrlInput{
+		QueueName:              aws.String(parsedArn.Resource),
+		QueueOwnerAWSAccountId: aws.String(parsedArn.AccountID),
+	})
+	if err != nil {
+		return "", err
+	}
+	queueURL := aws.StringValue(queue.QueueUrl)
+
+	if err := putS3NotificationTestObjectE(t, s3Client, bucket, key); err != nil {
+		return "", err
+	}
+	return receiveS3EventNotificationE(t, sqsClient, queueURL, bucket, key, string(S3NotificationTargetSqs), timeout, nil, isS3EventNotificationMessage)
+}
+
+// verifyS3EventNotificationToLambdaE uploads the object and waits for the given function to log the key.
+func verifyS3EventNotificationToLambdaE(t testing.TestingT, region string, bucket string, key string, functionArn string, timeout time.Duration) (string, error) {
+	s3Client, err := NewS3ClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	parsedArn, err := arn.Parse(functionArn)
+	if err != nil {
+		return "", err
+	}
+	// The resource of a function ARN is function:<name>, optionally followed by :<qualifier>.
+	functionName := strings.Split(strings.TrimPrefix(parsedArn.Resource, "function:"), ":")[0]
+
+	if err := putS3NotificationTestObjectE(t, s3Client, bucket, key); err != nil {
+		return "", err
+	}
+	message, err := WaitForLogEventMatchingE(t, region, "/aws/lambda/"+functionName, fmt.Sprintf("%q", key), timeout)
+	if _, ok := err.(LogEventNotFound); ok {
+		return "", NewS3EventNotificationNotReceivedError(bucket, key, string(S3NotificationTargetLambda), timeout)
+	}
+	return message, err
+}
+
+// verifyS3EventNotificationToEventBridgeE creates a temporary rule on the default event bus that matches the event and
+// sends it to a temporary queue, then uploads the object until the event is delivered to the queue.
+func verifyS3EventNotificationToEventBridgeE(t testing.TestingT, region string, bucket string, key string, timeout time.Duration) (string, error) {
+	s3Client, err := NewS3ClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	sqsClient, err := NewSqsClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	client, err := NewEventBridgeClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+
+	queueURL, err := CreateRandomQueueE(t, region, "terratest-s3-notification")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := DeleteQueueE(t, region, queueURL); err != nil {
+			logger.Logf(t, "Failed to delete temporary queue %s: %v", queueURL, err)
+		}
+	}()
+
+	pattern, err := newS3EventBridgeEventPattern(bucket, key)
+	if err != nil {
+		return "", err
+	}
+	ruleName := "terratest-s3-notification-" + random.UniqueId()
+	logger.Logf(t, "Creating temporary rule %s for the events of bucket %s", ruleName, bucket)
+	rule, err := client.PutRule(&eventbridge.PutRuleInput{
+		Name:         aws.String(ruleName),
+		EventPattern: aws.String(pattern),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := deleteS3NotificationRuleE(client, ruleName); err != nil {
+			logger.Logf(t, "Failed to delete temporary rule %s: %v", ruleName, err)
+		}
+	}()
+
+	attributes, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return "", err
+	}
+	queueArn := aws.StringValue(attributes.Attributes[sqs.QueueAttributeNameQueueArn])
+	policy, err := newEventBridgeQueuePolicy(queueArn, aws.StringValue(rule.RuleArn))
+	if err != nil {
+		return "", err
+	}
+	_, err = sqsClient.SetQueueAttributes(&sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]*string{sqs.QueueAttributeNamePolicy: aws.String(policy)},
+	})
+	if err != nil {
+		return "", err
+	}
+	targets, err := client.PutTargets(&eventbridge.PutTargetsInput{
+		Rule:    aws.String(ruleName),
+		Targets: []*eventbridge.Target{{Id: aws.String("terratest"), Arn: aws.String(queueArn)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if aws.Int64Value(targets.FailedEntryCount) > 0 {
+		failure := targets.FailedEntries[0]
+		return "", fmt.Errorf("Failed to attach temporary queue to rule %s: %s: %s", ruleName, aws.StringValue(failure.ErrorCode), aws.StringValue(failure.ErrorMessage))
+	}
+
+	upload := func() error {
+		return putS3NotificationTestObjectE(t, s3Client, bucket, key)
+	}
+	return receiveS3EventNotificationE(t, sqsClient, queueURL, bucket, key, string(S3NotificationTargetEventBridge), timeout, upload, isS3EventBridgeEvent)
+}
+
+// receiveS3EventNotificationE polls the given queue until it receives a message for which isEvent returns true, calling
+// beforeEachPoll first if it is not nil, deletes that message and returns its body.
+func receiveS3EventNotificationE(
+	t testing.TestingT,
+	client *sqs.SQS,
+	queueURL string,
+	bucket string,
+	key string,
+	target string,
+	timeout time.Duration,
+	beforeEachPoll func() error,
+	isEvent func(body string, bucket string, key string) bool,
+) (string, error) {
+	sleepBetweenRetries := time.Duration(eventBridgeDeliveryPollSeconds) * time.Second
+	maxRetries := int(timeout/sleepBetweenRetries) + 1
+
+	var received string
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %s to receive the event of object %s in bucket %s.", target, key, bucket),
+		maxRetries,
+		0,
+		func() (string, error) {
+			if beforeEachPoll != nil {
+				if err := beforeEachPoll(); err != nil {
+					return "", err
+				}
+			}
+			result, err := client.ReceiveMessage(&sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(queueURL),
+				MaxNumberOfMessages: aws.Int64(maxSqsBatchSize),
+				WaitTimeSeconds:     aws.Int64(eventBridgeDeliveryPollSeconds),
+			})
+			if err != nil {
+				return "", err
+			}
+			for _, message := range result.Messages {
+				if !isEvent(aws.StringValue(message.Body), bucket, key) {
+					continue
+				}
+				received = aws.StringValue(message.Body)
+				_, err := client.DeleteMessage(&sqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: message.ReceiptHandle})
+				if err != nil {
+					logger.Logf(t, "Failed to delete message from queue %s: %v", queueURL, err)
+				}
+				return fmt.Sprintf("%s received the event of object %s in bucket %s", target, key, bucket), nil
+			}
+			return "", NewS3EventNotificationNotReceivedError(bucket, key, target, timeout)
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return "", err
+	}
+	return received, nil
+}
+
+// isS3EventNotificationMessage returns true if the given message body is an S3 event notification for the creation of
+// an object with the given key in the given bucket.
+func isS3EventNotificationMessage(body string, bucket string, key string) bool {
+	var notification struct {
+		Records []struct {
+			EventName string `json:"eventName"`
+			S3        struct {
+				Bucket struct {
+					Name string `json:"name"`
+				} `json:"bucket"`
+				Object struct {
+					Key string `json:"key"`
+				} `json:"object"`
+			} `json:"s3"`
+		} `json:"Records"`
+	}
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return false
+	}
+	for _, record := range notification.Records {
+		// Object keys are URL encoded in S3 event notifications, with spaces encoded as plus signs.
+		recordKey, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(record.EventName, "ObjectCreated:") && record.S3.Bucket.Name == bucket && recordKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isS3EventBridgeEvent returns true if the given message body is an EventBridge event for the creation of an object
+// with the given key in the given bucket.
+func isS3EventBridgeEvent(body string, bucket string, key string) bool {
+	var event struct {
+		Source     string `json:"source"`
+		DetailType string `json:"detail-type"`
+		Detail     struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"detail"`
+	}
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return false
+	}
+	return event.Source == "aws.s3" && event.DetailType == "Object Created" && event.Detail.Bucket.Name == bucket && event.Detail.Object.Key == key
+}
+
+// newS3EventBridgeEventPattern returns an EventBridge event pattern that matches the creation of an object with the
+// given key in the given bucket.
+func newS3EventBridgeEventPattern(bucket string, key string) (string, error) {
+	pattern, err := json.Marshal(map[string]interface{}{
+		"source":      []string{"aws.s3"},
+		"detail-type": []string{"Object Created"},
+		"detail": map[string]interface{}{
+			"bucket": map[string][]string{"name": {bucket}},
+			"object": map[string][]string{"key": {key}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(pattern), nil
+}
+
+// deleteS3NotificationRuleE removes the targets of the given rule on the default event bus and deletes it.
+func deleteS3NotificationRuleE(client *eventbridge.EventBridge, ruleName string) error {
+	_, err := client.RemoveTargets(&eventbridge.RemoveTargetsInput{
+		Rule: aws.String(ruleName),
+		Ids:  aws.StringSlice([]string{"terratest"}),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteRule(&eventbridge.DeleteRuleInput{Name: aws.String(ruleName)})
+	return err
+}
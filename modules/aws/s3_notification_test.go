@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindS3NotificationTargetArn(t *testing.T) {
+	t.Parallel()
+
+	config := &s3.NotificationConfiguration{
+		QueueConfigurations: []*s3.QueueConfiguration{
+			{
+				QueueArn: aws.String("arn:aws:sqs:us-east-1:123456789012:images"),
+				Events:   aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+				Filter: &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{FilterRules: []*s3.FilterRule{
+					{Name: aws.String("Prefix"), Value: aws.String("images/")},
+					{Name: aws.String("Suffix"), Value: aws.String(".png")},
+				}}},
+			},
+			{
+				QueueArn: aws.String("arn:aws:sqs:us-east-1:123456789012:deletions"),
+				Events:   aws.StringSlice([]string{"s3:ObjectRemoved:*"}),
+			},
+		},
+		LambdaFunctionConfigurations: []*s3.LambdaFunctionConfiguration{
+			{
+				LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:thumbnail"),
+				Events:            aws.StringSlice([]string{s3.EventS3ObjectCreatedCopy, s3.EventS3ObjectCreatedPut}),
+			},
+		},
+	}
+
+	queueArn, err := findS3NotificationTargetArn(config, "bucket", "images/cat.png", S3NotificationTargetSqs)
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:sqs:us-east-1:123456789012:images", queueArn)
+
+	_, err = findS3NotificationTargetArn(config, "bucket", "images/cat.jpg", S3NotificationTargetSqs)
+	assert.IsType(t, S3EventNotificationNotConfiguredError{}, err)
+
+	functionArn, err := findS3NotificationTargetArn(config, "bucket", "anything", S3NotificationTargetLambda)
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:lambda:us-east-1:123456789012:function:thumbnail", functionArn)
+
+	_, err = findS3NotificationTargetArn(config, "bucket", "anything", S3NotificationTargetEventBridge)
+	assert.IsType(t, S3EventNotificationNotConfiguredError{}, err)
+
+	config.EventBridgeConfiguration = &s3.EventBridgeConfiguration{}
+	_, err = findS3NotificationTargetArn(config, "bucket", "anything", S3NotificationTargetEventBridge)
+	assert.NoError(t, err)
+
+	_, err = findS3NotificationTargetArn(config, "bucket", "anything", S3NotificationTarget("SNS"))
+	assert.Error(t, err)
+}
+
+func TestIsS3EventNotificationMessage(t *testing.T) {
+	t.Parallel()
+
+	body := `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"uploads/my+file%281%29.txt"}}}]}`
+	assert.True(t, isS3EventNotificationMessage(body, "bucket", "uploads/my file(1).txt"))
+	assert.False(t, isS3EventNotificationMessage(body, "other-bucket", "uploads/my file(1).txt"))
+	assert.False(t, isS3EventNotificationMessage(body, "bucket", "uploads/other.txt"))
+
+	removed := `{"Records":[{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"bucket"},"object":{"key":"file.txt"}}}]}`
+	assert.False(t, isS3EventNotificationMessage(removed, "bucket", "file.txt"))
+
+	testEvent := `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"bucket"}`
+	assert.False(t, isS3EventNotificationMessage(testEvent, "bucket", "file.txt"))
+}
+
+func TestIsS3EventBridgeEvent(t *testing.T) {
+	t.Parallel()
+
+	body := `{"source":"aws.s3","detail-type":"Object Created","detail":{"bucket":{"name":"bucket"},"object":{"key":"file.txt"}}}`
+	assert.True(t, isS3EventBridgeEvent(body, "bucket", "file.txt"))
+	assert.False(t, isS3EventBridgeEvent(body, "bucket", "other.txt"))
+	assert.False(t, isS3EventBridgeEvent(`not json`, "bucket", "file.txt"))
+}
+
+func TestNewS3EventBridgeEventPattern(t *testing.T) {
+	t.Parallel()
+
+	pattern, err := newS3EventBridgeEventPattern("bucket", "file.txt")
+	require.NoError(t, err)
+
+	var parsed map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(pattern), &parsed))
+	assert.Equal(t, []interface{}{"aws.s3"}, parsed["source"])
+	assert.Equal(t, map[string]interface{}{
+		"bucket": map[string]interface{}{"name": []interface{}{"bucket"}},
+		"object": map[string]interface{}{"key": []interface{}{"file.txt"}},
+	}, parsed["detail"])
+}
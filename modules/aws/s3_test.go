@@ -267,3 +267,43 @@ func testEmptyBucket(t *testing.T, s3Client *s3.S3, region string, s3BucketName
 	}
 	require.Equal(t, 0, len((*bucketObjects).Contents))
 }
+
+func TestParseS3BucketPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := parseS3BucketPolicy(`{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Sid": "Read", "Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"},
+			{"Sid": "TLS", "Effect": "Deny", "Principal": {"AWS": "*"}, "Action": ["s3:*"], "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"],
+			 "Condition": {"Bool": {"aws:SecureTransport": "false"}}}
+		]
+	}`)
+	require.NoError(t, err)
+	require.Len(t, policy.Statement, 2)
+	assert.Equal(t, []string{"s3:GetObject"}, []string(policy.Statement[0].Action))
+	assert.Equal(t, []string{"arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"}, []string(policy.Statement[1].Resource))
+	assert.True(t, policyDeniesInsecureTransport(policy))
+
+	policy.Statement = policy.Statement[:1]
+	assert.False(t, policyDeniesInsecureTransport(policy))
+}
+
+func TestCheckS3PublicAccessBlock(t *testing.T) {
+	t.Parallel()
+
+	config := &s3.PublicAccessBlockConfiguration{
+		BlockPublicAcls:       aws.Bool(true),
+		BlockPublicPolicy:     aws.Bool(true),
+		IgnorePublicAcls:      aws.Bool(true),
+		RestrictPublicBuckets: aws.Bool(true),
+	}
+	assert.NoError(t, checkS3PublicAccessBlock("bucket", "us-east-1", config))
+
+	config.RestrictPublicBuckets = aws.Bool(false)
+	err := checkS3PublicAccessBlock("bucket", "us-east-1", config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "RestrictPublicBuckets")
+
+	assert.Error(t, checkS3PublicAccessBlock("bucket", "us-east-1", nil))
+}
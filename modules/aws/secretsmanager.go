@@ -1,9 +1,13 @@
 package aws
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -77,6 +81,234 @@ func DeleteSecretE(t testing.TestingT, awsRegion, id string, forceDelete bool) e
 	return err
 }
 
+// SecretVersionStageCurrent is the staging label of the current version of a secret.
+const SecretVersionStageCurrent = "AWSCURRENT"
+
+// CreateSecretStringWithKmsKey creates a new secret in Secrets Manager encrypted with the given KMS key and returns the secret ARN
+func CreateSecretStringWithKmsKey(t testing.TestingT, awsRegion, description, name, secretString, kmsKeyID string) string {
+	arn, err := CreateSecretStringWithKmsKeyE(t, awsRegion, description, name, secretString, kmsKeyID)
+	require.NoError(t, err)
+	return arn
+}
+
+// CreateSecretStringWithKmsKeyE creates a new secret in Secrets Manager encrypted with the given KMS key and returns the secret ARN
+func CreateSecretStringWithKmsKeyE(t testing.TestingT, awsRegion, description, name, secretString, kmsKeyID string) (string, error) {
+	logger.Logf(t, "Creating new secret in secrets manager named %s encrypted with KMS key %s", name, kmsKeyID)
+
+	client, err := NewSecretsManagerClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := client.CreateSecret(&secretsmanager.CreateSecretInput{
+		Description:  aws.String(description),
+		Name:         aws.String(name),
+		SecretString: aws.String(secretString),
+		KmsKeyId:     aws.String(kmsKeyID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(secret.ARN), nil
+}
+
+// PutSecretString stores the given value as a new version of a secret, which becomes the current version, and returns the ID of the version
+func PutSecretString(t testing.TestingT, awsRegion, id, secretString string) string {
+	versionID, err := PutSecretStringE(t, awsRegion, id, secretString)
+	require.NoError(t, err)
+	return versionID
+}
+
+// PutSecretStringE stores the given value as a new version of a secret, which becomes the current version, and returns the ID of the version
+func PutSecretStringE(t testing.TestingT, awsRegion, id, secretString string) (string, error) {
+	logger.Logf(t, "Putting new value of secret with ID %s", id)
+
+	client, err := NewSecretsManagerClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := client.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(id),
+		SecretString: aws.String(secretString),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.VersionId), nil
+}
+
+// GetSecretValueForVersionStage takes the friendly name or ARN of a secret and returns the plaintext value of the version with the given staging label (e.g., "AWSPENDING")
+func GetSecretValueForVersionStage(t testing.TestingT, awsRegion, id, versionStage string) string {
+	secret, err := GetSecretValueForVersionStageE(t, awsRegion, id, versionStage)
+	require.NoError(t, err)
+	return secret
+}
+
+// GetSecretValueForVersionStageE takes the friendly name or ARN of a secret and returns the plaintext value of the version with the given staging label (e.g., "AWSPENDING")
+func GetSecretValueForVersionStageE(t testing.TestingT, awsRegion, id, versionStage string) (string, error) {
+	logger.Logf(t, "Getting value of version %s of secret with ID %s", versionStage, id)
+
+	client, err := NewSecretsManagerClientE(t, awsRegion)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(id),
+		VersionStage: aws.String(versionStage),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(secret.SecretString), nil
+}
+
+// GetSecretVersionStages returns the staging labels of each version of a secret, by version ID
+func GetSecretVersionStages(t testing.TestingT, awsRegion, id string) map[string][]string {
+	stages, err := GetSecretVersionStagesE(t, awsRegion, id)
+	require.NoError(t, err)
+	return stages
+}
+
+// GetSecretVersionStagesE returns the staging labels of each version of a secret, by version ID. Versions without a staging label are not included, as Secrets Manager considers them deprecated.
+func GetSecretVersionStagesE(t testing.TestingT, awsRegion, id string) (map[string][]string, error) {
+	client, err := NewSecretsManagerClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	stages := map[string][]string{}
+	input := &secretsmanager.ListSecretVersionIdsInput{SecretId: aws.String(id)}
+	err = client.ListSecretVersionIdsPages(input, func(output *secretsmanager.ListSecretVersionIdsOutput, lastPage bool) bool {
+		for _, version := range output.Versions {
+			if len(version.VersionStages) > 0 {
+				stages[aws.StringValue(version.VersionId)] = aws.StringValueSlice(version.VersionStages)
+			}
+		}
+		return true
+	})
+	return stages, err
+}
+
+// GetSecretVersionIDForStage returns the ID of the version of a secret with the given staging label (e.g., "AWSCURRENT")
+func GetSecretVersionIDForStage(t testing.TestingT, awsRegion, id, versionStage string) string {
+	versionID, err := GetSecretVersionIDForStageE(t, awsRegion, id, versionStage)
+	require.NoError(t, err)
+	return versionID
+}
+
+// GetSecretVersionIDForStageE returns the ID of the version of a secret with the given staging label (e.g., "AWSCURRENT")
+func GetSecretVersionIDForStageE(t testing.TestingT, awsRegion, id, versionStage string) (string, error) {
+	stages, err := GetSecretVersionStagesE(t, awsRegion, id)
+	if err != nil {
+		return "", err
+	}
+	versionID := findSecretVersionWithStage(stages, versionStage)
+	if versionID == "" {
+		return "", NewNotFoundError(fmt.Sprintf("version with stage %s of secret", versionStage), id, awsRegion)
+	}
+	return versionID, nil
+}
+
+// findSecretVersionWithStage returns the ID of the version with the given staging label, or an empty string if there is none.
+func findSecretVersionWithStage(stages map[string][]string, versionStage string) string {
+	for versionID, versionStages := range stages {
+		for _, stage := range versionStages {
+			if stage == versionStage {
+				return versionID
+			}
+		}
+	}
+	return ""
+}
+
+// AssertSecretVersionHasStage checks that the given version of a secret has the given staging label, and fails the test if it does not
+func AssertSecretVersionHasStage(t testing.TestingT, awsRegion, id, versionID, versionStage string) {
+	err := AssertSecretVersionHasStageE(t, awsRegion, id, versionID, versionStage)
+	require.NoError(t, err)
+}
+
+// AssertSecretVersionHasStageE checks that the given version of a secret has the given staging label, and returns an error if it does not
+func AssertSecretVersionHasStageE(t testing.TestingT, awsRegion, id, versionID, versionStage string) error {
+	stages, err := GetSecretVersionStagesE(t, awsRegion, id)
+	if err != nil {
+		return err
+	}
+	for _, stage := range stages[versionID] {
+		if stage == versionStage {
+			return nil
+		}
+	}
+	return SecretVersionStageMismatch{SecretID: id, VersionID: versionID, ExpectedStage: versionStage, ActualStages: stages[versionID]}
+}
+
+// RotateSecret starts an immediate rotation of a secret with its rotation Lambda function. If rotationLambdaArn is not empty, rotation is first enabled with the given function.
+func RotateSecret(t testing.TestingT, awsRegion, id, rotationLambdaArn string) {
+	err := RotateSecretE(t, awsRegion, id, rotationLambdaArn)
+	require.NoError(t, err)
+}
+
+// RotateSecretE starts an immediate rotation of a secret with its rotation Lambda function. If rotationLambdaArn is not empty, rotation is first enabled with the given function.
+// The rotation runs asynchronously: use WaitUntilSecretRotated to wait for it to finish.
+func RotateSecretE(t testing.TestingT, awsRegion, id, rotationLambdaArn string) error {
+	logger.Logf(t, "Rotating secret with ID %s", id)
+
+	client, err := NewSecretsManagerClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	input := &secretsmanager.RotateSecretInput{SecretId: aws.String(id)}
+	if rotationLambdaArn != "" {
+		input.RotationLambdaARN = aws.String(rotationLambdaArn)
+	}
+	_, err = client.RotateSecret(input)
+	return err
+}
+
+// WaitUntilSecretRotated waits until the current version of a secret is different from the given previous version,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try, and returns the ID of the new current version.
+func WaitUntilSecretRotated(t testing.TestingT, awsRegion, id, previousVersionID string, maxRetries int, sleepBetweenRetries time.Duration) string {
+	versionID, err := WaitUntilSecretRotatedE(t, awsRegion, id, previousVersionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return versionID
+}
+
+// WaitUntilSecretRotatedE waits until the current version of a secret is different from the given previous version,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try, and returns the ID of the new current version.
+// This is useful to test a rotation Lambda function:
+//
+//	previousVersionID := aws.GetSecretVersionIDForStage(t, awsRegion, secretID, aws.SecretVersionStageCurrent)
+//	aws.RotateSecret(t, awsRegion, secretID, "")
+//	newVersionID := aws.WaitUntilSecretRotated(t, awsRegion, secretID, previousVersionID, 30, 10*time.Second)
+func WaitUntilSecretRotatedE(t testing.TestingT, awsRegion, id, previousVersionID string, maxRetries int, sleepBetweenRetries time.Duration) (string, error) {
+	versionID, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for secret %s to be rotated.", id),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			currentVersionID, err := GetSecretVersionIDForStageE(t, awsRegion, id, SecretVersionStageCurrent)
+			if err != nil {
+				return "", err
+			}
+			if currentVersionID == previousVersionID {
+				return "", SecretNotRotated{SecretID: id, VersionID: currentVersionID}
+			}
+			return currentVersionID, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	logger.Logf(t, "Secret %s was rotated to version %s", id, versionID)
+	return versionID, nil
+}
+
 // NewSecretsManagerClient creates a new SecretsManager client.
 func NewSecretsManagerClient(t testing.TestingT, region string) *secretsmanager.SecretsManager {
 	client, err := NewSecretsManagerClientE(t, region)
@@ -86,10 +318,32 @@ func NewSecretsManagerClient(t testing.TestingT, region string) *secretsmanager.
 
 // NewSecretsManagerClientE creates a new SecretsManager client.
 func NewSecretsManagerClientE(t testing.TestingT, region string) (*secretsmanager.SecretsManager, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
 
 	return secretsmanager.New(sess), nil
 }
+
+// SecretVersionStageMismatch is an error that occurs when a version of a secret doesn't have the expected staging label.
+type SecretVersionStageMismatch struct {
+	SecretID      string
+	VersionID     string
+	ExpectedStage string
+	ActualStages  []string
+}
+
+func (err SecretVersionStageMismatch) Error() string {
+	return fmt.Sprintf("Expected version %s of secret %s to have stage %s, but it has stages %v", err.VersionID, err.SecretID, err.ExpectedStage, err.ActualStages)
+}
+
+// SecretNotRotated is an error that occurs when a secret has not been rotated yet.
+type SecretNotRotated struct {
+	SecretID  string
+	VersionID string
+}
+
+func (err SecretNotRotated) Error() string {
+	return fmt.Sprintf("Secret %s has not been rotated yet: the current version is still %s", err.SecretID, err.VersionID)
+}
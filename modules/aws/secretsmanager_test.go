@@ -29,3 +29,34 @@ func deleteSecret(t *testing.T, region, id string) {
 	_, err := GetSecretValueE(t, region, id)
 	require.Error(t, err)
 }
+
+func TestSecretsManagerVersionStages(t *testing.T) {
+	t.Parallel()
+
+	region := GetRandomStableRegion(t, nil, nil)
+	name := random.UniqueId()
+
+	secretARN := CreateSecretStringWithDefaultKey(t, region, "This is just a secrets manager test description.", name, "first value")
+	defer deleteSecret(t, region, secretARN)
+
+	firstVersionID := GetSecretVersionIDForStage(t, region, secretARN, SecretVersionStageCurrent)
+	secondVersionID := PutSecretString(t, region, secretARN, "second value")
+	assert.NotEqual(t, firstVersionID, secondVersionID)
+
+	AssertSecretVersionHasStage(t, region, secretARN, secondVersionID, SecretVersionStageCurrent)
+	AssertSecretVersionHasStage(t, region, secretARN, firstVersionID, "AWSPREVIOUS")
+	assert.Equal(t, "first value", GetSecretValueForVersionStage(t, region, secretARN, "AWSPREVIOUS"))
+	assert.Equal(t, secondVersionID, WaitUntilSecretRotated(t, region, secretARN, firstVersionID, 1, 0))
+}
+
+func TestFindSecretVersionWithStage(t *testing.T) {
+	t.Parallel()
+
+	stages := map[string][]string{
+		"v1": {"AWSPREVIOUS"},
+		"v2": {"AWSCURRENT", "custom"},
+	}
+	assert.Equal(t, "v2", findSecretVersionWithStage(stages, SecretVersionStageCurrent))
+	assert.Equal(t, "v1", findSecretVersionWithStage(stages, "AWSPREVIOUS"))
+	assert.Equal(t, "", findSecretVersionWithStage(stages, "AWSPENDING"))
+}
@@ -0,0 +1,248 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// SfnStateExecution is a run of a state during the execution of a Step Functions state machine, parsed from the
+// execution history.
+type SfnStateExecution struct {
+	Name   string // The name of the state
+	Type   string // The type of the state (e.g., Task, Choice, Pass, Parallel, Map)
+	Input  string // The JSON input of the state
+	Output string // The JSON output of the state, if it exited
+	Exited bool   // If the state exited, rather than failing the execution or still running
+	Error  string // The error of the last failure of the state (e.g., States.TaskFailed), if it failed, even if it was retried
+	Cause  string // The cause of the last failure of the state
+}
+
+// SfnExecutionHistory is the list of states that ran during the execution of a Step Functions state machine, in the order
+// they were entered.
+type SfnExecutionHistory []SfnStateExecution
+
+// StateNames returns the names of the states that ran, in the order they were entered.
+func (history SfnExecutionHistory) StateNames() []string {
+	names := []string{}
+	for _, state := range history {
+		names = append(names, state.Name)
+	}
+	return names
+}
+
+// State returns the last run of the state with the given name, or nil if the state didn't run.
+func (history SfnExecutionHistory) State(name string) *SfnStateExecution {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Name == name {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// StartStateMachineExecution starts an execution of the given Step Functions state machine with the given JSON input,
+// and returns the ARN of the execution.
+func StartStateMachineExecution(t testing.TestingT, region string, stateMachineArn string, input string) string {
+	executionArn, err := StartStateMachineExecutionE(t, region, stateMachineArn, input)
+	require.NoError(t, err)
+	return executionArn
+}
+
+// StartStateMachineExecutionE starts an execution of the given Step Functions state machine with the given JSON input,
+// and returns the ARN of the execution.
+func StartStateMachineExecutionE(t testing.TestingT, region string, stateMachineArn string, input string) (string, error) {
+	logger.Logf(t, "Starting execution of state machine %s", stateMachineArn)
+
+	client, err := NewSfnClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+	output, err := client.StartExecution(&sfn.StartExecutionInput{
+		StateMachineArn: aws.String(stateMachineArn),
+		Input:           aws.String(input),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.ExecutionArn), nil
+}
+
+// WaitUntilExecutionStatus waits until the given execution of a Step Functions state machine has the given status (e.g.,
+// sfn.ExecutionStatusSucceeded), retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try, and returns the description of the execution.
+func WaitUntilExecutionStatus(t testing.TestingT, region string, executionArn string, expectedStatus string, maxRetries int, sleepBetweenRetries time.Duration) *sfn.DescribeExecutionOutput {
+	execution, err := WaitUntilExecutionStatusE(t, region, executionArn, expectedStatus, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return execution
+}
+
+// WaitUntilExecutionStatusE waits until the given execution of a Step Functions state machine has the given status (e.g.,
+// sfn.ExecutionStatusSucceeded), retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try, and returns the description of the execution. This stops waiting early if the execution finishes
+// with a different status.
+func WaitUntilExecutionStatusE(t testing.TestingT, region string, executionArn string, expectedStatus string, maxRetries int, sleepBetweenRetries time.Duration) (*sfn.DescribeExecutionOutput, error) {
+	client, err := NewSfnClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var execution *sfn.DescribeExecutionOutput
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for execution %s to have status %s.", executionArn, expectedStatus),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			execution, err = client.DescribeExecution(&sfn.DescribeExecutionInput{ExecutionArn: aws.String(executionArn)})
+			if err != nil {
+				return "", err
+			}
+			if err := checkExecutionStatus(execution, expectedStatus); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Execution %s now has status %s", executionArn, expectedStatus), nil
+		},
+	)
+	logger.Log(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return execution, nil
+}
+
+// checkExecutionStatus returns an error if the given execution doesn't have the expected status. The error is wrapped in
+// a retry.FatalError if the execution finished, as its status can't change anymore.
+func checkExecutionStatus(execution *sfn.DescribeExecutionOutput, expectedStatus string) error {
+	status := aws.StringValue(execution.Status)
+	if status == expectedStatus {
+		return nil
+	}
+	err := NewSfnExecutionStatusMismatchError(aws.StringValue(execution.ExecutionArn), expectedStatus, status)
+	if status != sfn.ExecutionStatusRunning {
+		return retry.FatalError{Underlying: err}
+	}
+	return err
+}
+
+// GetExecutionHistory fetches the history of the given execution of a Step Functions state machine, and returns the
+// states that ran, with their inputs and outputs.
+func GetExecutionHistory(t testing.TestingT, region string, executionArn string) SfnExecutionHistory {
+	history, err := GetExecutionHistoryE(t, region, executionArn)
+	require.NoError(t, err)
+	return history
+}
+
+// GetExecutionHistoryE fetches the history of the given execution of a Step Functions state machine, and returns the
+// states that ran, with their inputs and outputs. This is useful to check which branch of a Choice state ran, or that a
+// Catch sent a failure to the right state:
+//
+//	history := aws.GetExecutionHistory(t, region, executionArn)
+//	assert.Equal(t, []string{"Process", "HandleFailure"}, history.StateNames())
+//	assert.Equal(t, "States.TaskFailed", history.State("Process").Error)
+func GetExecutionHistoryE(t testing.TestingT, region string, executionArn string) (SfnExecutionHistory, error) {
+	client, err := NewSfnClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	events := []*sfn.HistoryEvent{}
+	input := &sfn.GetExecutionHistoryInput{ExecutionArn: aws.String(executionArn)}
+	err = client.GetExecutionHistoryPages(input, func(output *sfn.GetExecutionHistoryOutput, lastPage bool) bool {
+		events = append(events, output.Events...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseExecutionHistory(events), nil
+}
+
+// parseExecutionHistory returns the states that ran according to the given history events. Failures are attributed to
+// the most recently entered state that hasn't exited yet.
+func parseExecutionHistory(events []*sfn.HistoryEvent) SfnExecutionHistory {
+	history := SfnExecutionHistory{}
+	lastOpenState := func(name string) *SfnStateExecution {
+		for i := len(history) - 1; i >= 0; i-- {
+			if !history[i].Exited && (name == "" || history[i].Name == name) {
+				return &history[i]
+			}
+		}
+		return nil
+	}
+
+	for _, event := range events {
+		eventType := aws.StringValue(event.Type)
+		switch {
+		case strings.HasSuffix(eventType, "StateEntered") && event.StateEnteredEventDetails != nil:
+			history = append(history, SfnStateExecution{
+				Name:  aws.StringValue(event.StateEnteredEventDetails.Name),
+				Type:  strings.TrimSuffix(eventType, "StateEntered"),
+				Input: aws.StringValue(event.StateEnteredEventDetails.Input),
+			})
+		case strings.HasSuffix(eventType, "StateExited") && event.StateExitedEventDetails != nil:
+			if state := lastOpenState(aws.StringValue(event.StateExitedEventDetails.Name)); state != nil {
+				state.Output = aws.StringValue(event.StateExitedEventDetails.Output)
+				state.Exited = true
+			}
+		default:
+			errorName, cause, isFailure := getHistoryEventFailure(event)
+			if !isFailure {
+				continue
+			}
+			if state := lastOpenState(""); state != nil {
+				state.Error = errorName
+				state.Cause = cause
+			}
+		}
+	}
+	return history
+}
+
+// getHistoryEventFailure returns the error and cause of the given history event, if it is a failure of a task.
+func getHistoryEventFailure(event *sfn.HistoryEvent) (string, string, bool) {
+	switch {
+	case event.TaskFailedEventDetails != nil:
+		return aws.StringValue(event.TaskFailedEventDetails.Error), aws.StringValue(event.TaskFailedEventDetails.Cause), true
+	case event.TaskTimedOutEventDetails != nil:
+		return aws.StringValue(event.TaskTimedOutEventDetails.Error), aws.StringValue(event.TaskTimedOutEventDetails.Cause), true
+	case event.TaskStartFailedEventDetails != nil:
+		return aws.StringValue(event.TaskStartFailedEventDetails.Error), aws.StringValue(event.TaskStartFailedEventDetails.Cause), true
+	case event.TaskSubmitFailedEventDetails != nil:
+		return aws.StringValue(event.TaskSubmitFailedEventDetails.Error), aws.StringValue(event.TaskSubmitFailedEventDetails.Cause), true
+	case event.LambdaFunctionFailedEventDetails != nil:
+		return aws.StringValue(event.LambdaFunctionFailedEventDetails.Error), aws.StringValue(event.LambdaFunctionFailedEventDetails.Cause), true
+	case event.LambdaFunctionTimedOutEventDetails != nil:
+		return aws.StringValue(event.LambdaFunctionTimedOutEventDetails.Error), aws.StringValue(event.LambdaFunctionTimedOutEventDetails.Cause), true
+	case event.ActivityFailedEventDetails != nil:
+		return aws.StringValue(event.ActivityFailedEventDetails.Error), aws.StringValue(event.ActivityFailedEventDetails.Cause), true
+	case event.ActivityTimedOutEventDetails != nil:
+		return aws.StringValue(event.ActivityTimedOutEventDetails.Error), aws.StringValue(event.ActivityTimedOutEventDetails.Cause), true
+	case event.ExecutionFailedEventDetails != nil:
+		return aws.StringValue(event.ExecutionFailedEventDetails.Error), aws.StringValue(event.ExecutionFailedEventDetails.Cause), true
+	default:
+		return "", "", false
+	}
+}
+
+// NewSfnClient creates a Step Functions client.
+func NewSfnClient(t testing.TestingT, region string) *sfn.SFN {
+	client, err := NewSfnClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewSfnClientE creates a Step Functions client.
+func NewSfnClientE(t testing.TestingT, region string) (*sfn.SFN, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return sfn.New(sess), nil
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExecutionHistory(t *testing.T) {
+	t.Parallel()
+
+	entered := func(eventType string, name string, input string) *sfn.HistoryEvent {
+		return &sfn.HistoryEvent{Type: aws.String(eventType), StateEnteredEventDetails: &sfn.StateEnteredEventDetails{Name: aws.String(name), Input: aws.String(input)}}
+	}
+	exited := func(eventType string, name string, output string) *sfn.HistoryEvent {
+		return &sfn.HistoryEvent{Type: aws.String(eventType), StateExitedEventDetails: &sfn.StateExitedEventDetails{Name: aws.String(name), Output: aws.String(output)}}
+	}
+
+	events := []*sfn.HistoryEvent{
+		{Type: aws.String(sfn.HistoryEventTypeExecutionStarted)},
+		entered(sfn.HistoryEventTypeChoiceStateEntered, "Route", `{"kind":"a"}`),
+		exited(sfn.HistoryEventTypeChoiceStateExited, "Route", `{"kind":"a"}`),
+		entered(sfn.HistoryEventTypeTaskStateEntered, "Process", `{"kind":"a"}`),
+		{Type: aws.String(sfn.HistoryEventTypeLambdaFunctionScheduled)},
+		{
+			Type:                             aws.String(sfn.HistoryEventTypeLambdaFunctionFailed),
+			LambdaFunctionFailedEventDetails: &sfn.LambdaFunctionFailedEventDetails{Error: aws.String("BadInput"), Cause: aws.String("boom")},
+		},
+		exited(sfn.HistoryEventTypeTaskStateExited, "Process", `{"error":"BadInput"}`),
+		entered(sfn.HistoryEventTypeFailStateEntered, "HandleFailure", `{"error":"BadInput"}`),
+		{
+			Type:                        aws.String(sfn.HistoryEventTypeExecutionFailed),
+			ExecutionFailedEventDetails: &sfn.ExecutionFailedEventDetails{Error: aws.String("Failed"), Cause: aws.String("handled")},
+		},
+	}
+
+	history := parseExecutionHistory(events)
+	assert.Equal(t, []string{"Route", "Process", "HandleFailure"}, history.StateNames())
+
+	process := history.State("Process")
+	require.NotNil(t, process)
+	assert.Equal(t, SfnStateExecution{Name: "Process", Type: "Task", Input: `{"kind":"a"}`, Output: `{"error":"BadInput"}`, Exited: true, Error: "BadInput", Cause: "boom"}, *process)
+
+	failure := history.State("HandleFailure")
+	require.NotNil(t, failure)
+	assert.Equal(t, "Fail", failure.Type)
+	assert.False(t, failure.Exited)
+	assert.Equal(t, "Failed", failure.Error)
+
+	assert.Nil(t, history.State("Missing"))
+}
+
+func TestCheckExecutionStatus(t *testing.T) {
+	t.Parallel()
+
+	execution := &sfn.DescribeExecutionOutput{ExecutionArn: aws.String("arn"), Status: aws.String(sfn.ExecutionStatusRunning)}
+	assert.NoError(t, checkExecutionStatus(execution, sfn.ExecutionStatusRunning))
+	assert.IsType(t, SfnExecutionStatusMismatchError{}, checkExecutionStatus(execution, sfn.ExecutionStatusSucceeded))
+
+	execution.Status = aws.String(sfn.ExecutionStatusFailed)
+	assert.IsType(t, retry.FatalError{}, checkExecutionStatus(execution, sfn.ExecutionStatusSucceeded))
+}
@@ -1,8 +1,14 @@
 package aws
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
@@ -62,6 +68,267 @@ func DeleteSNSTopicE(t testing.TestingT, region string, snsTopicArn string) erro
 	return err
 }
 
+// SnsTestSubscription is a temporary SQS queue subscribed to an SNS topic, which can be used to verify which messages
+// are delivered to the subscribers of the topic.
+type SnsTestSubscription struct {
+	Region          string
+	TopicArn        string
+	QueueURL        string
+	QueueArn        string
+	SubscriptionArn string
+}
+
+// SubscribeTestQueueToSnsTopic creates a temporary SQS queue and subscribes it to the given SNS topic with the given
+// filter policy (or none, if it's empty). Use DeleteSnsTestSubscription to clean up the queue and subscription.
+func SubscribeTestQueueToSnsTopic(t testing.TestingT, region string, topicArn string, filterPolicy string) *SnsTestSubscription {
+	subscription, err := SubscribeTestQueueToSnsTopicE(t, region, topicArn, filterPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subscription
+}
+
+// SubscribeTestQueueToSnsTopicE creates a temporary SQS queue and subscribes it to the given SNS topic with the given
+// filter policy (or none, if it's empty). The subscription uses raw message delivery, so the body of each message in
+// the queue is the published message. Note that SNS may take up to a minute to apply a new filter policy. Use
+// DeleteSnsTestSubscriptionE to clean up the queue and subscription.
+func SubscribeTestQueueToSnsTopicE(t testing.TestingT, region string, topicArn string, filterPolicy string) (*SnsTestSubscription, error) {
+	// SNS FIFO topics can only deliver to SQS FIFO queues.
+	var queueURL string
+	var err error
+	if strings.HasSuffix(topicArn, ".fifo") {
+		queueURL, err = CreateRandomFifoQueueE(t, region, "terratest-sns")
+	} else {
+		queueURL, err = CreateRandomQueueE(t, region, "terratest-sns")
+	}
+	if err != nil {
+		return nil, err
+	}
+	subscription := &SnsTestSubscription{Region: region, TopicArn: topicArn, QueueURL: queueURL}
+
+	sqsClient, err := NewSqsClientE(t, region)
+	if err != nil {
+		return subscription, err
+	}
+	attributes, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err != nil {
+		return subscription, err
+	}
+	subscription.QueueArn = aws.StringValue(attributes.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	policy, err := newSnsToSqsQueuePolicy(topicArn, subscription.QueueArn)
+	if err != nil {
+		return subscription, err
+	}
+	_, err = sqsClient.SetQueueAttributes(&sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]*string{sqs.QueueAttributeNamePolicy: aws.String(policy)},
+	})
+	if err != nil {
+		return subscription, err
+	}
+
+	logger.Logf(t, "Subscribing SQS queue %s to SNS topic %s", queueURL, topicArn)
+	snsClient, err := NewSnsClientE(t, region)
+	if err != nil {
+		return subscription, err
+	}
+	subscriptionAttributes := map[string]*string{"RawMessageDelivery": aws.String("true")}
+	if filterPolicy != "" {
+		subscriptionAttributes["FilterPolicy"] = aws.String(filterPolicy)
+	}
+	output, err := snsClient.Subscribe(&sns.SubscribeInput{
+		TopicArn:              aws.String(topicArn),
+		Protocol:              aws.String("sqs"),
+		Endpoint:              aws.String(subscription.QueueArn),
+		Attributes:            subscriptionAttributes,
+		ReturnSubscriptionArn: aws.Bool(true),
+	})
+	if err != nil {
+		return subscription, err
+	}
+	subscription.SubscriptionArn = aws.StringValue(output.SubscriptionArn)
+	return subscription, nil
+}
+
+// newSnsToSqsQueuePolicy returns a queue policy that allows the given SNS topic to send messages to the given queue.
+func newSnsToSqsQueuePolicy(topicArn string, queueArn string) (string, error) {
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "sns.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueArn,
+			"Condition": map[string]interface{}{
+				"ArnEquals": map[string]string{"aws:SourceArn": topicArn},
+			},
+		}},
+	}
+	bytes, err := json.Marshal(policy)
+	return string(bytes), err
+}
+
+// DeleteSnsTestSubscription unsubscribes the temporary SQS queue from the SNS topic and deletes the queue.
+func DeleteSnsTestSubscription(t testing.TestingT, subscription *SnsTestSubscription) {
+	err := DeleteSnsTestSubscriptionE(t, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// DeleteSnsTestSubscriptionE unsubscribes the temporary SQS queue from the SNS topic and deletes the queue. This can be
+// called with a partially created subscription returned alongside an error by SubscribeTestQueueToSnsTopicE.
+func DeleteSnsTestSubscriptionE(t testing.TestingT, subscription *SnsTestSubscription) error {
+	if subscription == nil {
+		return nil
+	}
+	if subscription.SubscriptionArn != "" {
+		logger.Logf(t, "Unsubscribing SQS queue %s from SNS topic %s", subscription.QueueURL, subscription.TopicArn)
+		snsClient, err := NewSnsClientE(t, subscription.Region)
+		if err != nil {
+			return err
+		}
+		_, err = snsClient.Unsubscribe(&sns.UnsubscribeInput{SubscriptionArn: aws.String(subscription.SubscriptionArn)})
+		if err != nil {
+			return err
+		}
+	}
+	if subscription.QueueURL != "" {
+		return DeleteQueueE(t, subscription.Region, subscription.QueueURL)
+	}
+	return nil
+}
+
+// PublishSnsMessage publishes the given message with the given string attributes to the SNS topic and returns the
+// message ID.
+func PublishSnsMessage(t testing.TestingT, region string, topicArn string, message string, attributes map[string]string) string {
+	messageID, err := PublishSnsMessageE(t, region, topicArn, message, attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messageID
+}
+
+// PublishSnsMessageE publishes the given message with the given string attributes to the SNS topic and returns the
+// message ID. The attributes are what subscription filter policies match against.
+func PublishSnsMessageE(t testing.TestingT, region string, topicArn string, message string, attributes map[string]string) (string, error) {
+	logger.Logf(t, "Publishing message %s to SNS topic %s", message, topicArn)
+
+	snsClient, err := NewSnsClientE(t, region)
+	if err != nil {
+		return "", err
+	}
+
+	input := &sns.PublishInput{
+		TopicArn:          aws.String(topicArn),
+		Message:           aws.String(message),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{},
+	}
+	for name, value := range attributes {
+		input.MessageAttributes[name] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	// SNS FIFO topics require a message group, and content-based deduplication is not always enabled on them.
+	if strings.HasSuffix(topicArn, ".fifo") {
+		input.MessageGroupId = aws.String("terratest")
+		input.MessageDeduplicationId = aws.String(fmt.Sprintf("%d", time.Now().UnixNano()))
+	}
+
+	output, err := snsClient.Publish(input)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.MessageId), nil
+}
+
+// AssertSnsMessageDelivered checks that a message with the given body is delivered to the temporary SQS queue of the
+// subscription within the given timeout, and fails the test if it is not.
+func AssertSnsMessageDelivered(t testing.TestingT, subscription *SnsTestSubscription, message string, timeout time.Duration) {
+	err := AssertSnsMessageDeliveredE(t, subscription, message, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertSnsMessageDeliveredE checks that a message with the given body is delivered to the temporary SQS queue of the
+// subscription within the given timeout, and returns an error if it is not. The message is deleted from the queue.
+func AssertSnsMessageDeliveredE(t testing.TestingT, subscription *SnsTestSubscription, message string, timeout time.Duration) error {
+	delivered, err := waitForSnsTestMessage(t, subscription, message, timeout)
+	if err != nil {
+		return err
+	}
+	if !delivered {
+		return SnsMessageNotDelivered{TopicArn: subscription.TopicArn, QueueUrl: subscription.QueueURL, Message: message}
+	}
+	return nil
+}
+
+// AssertSnsMessageNotDelivered checks that no message with the given body is delivered to the temporary SQS queue of
+// the subscription within the given duration (e.g., because the filter policy of the subscription doesn't match it),
+// and fails the test if one is.
+func AssertSnsMessageNotDelivered(t testing.TestingT, subscription *SnsTestSubscription, message string, wait time.Duration) {
+	err := AssertSnsMessageNotDeliveredE(t, subscription, message, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertSnsMessageNotDeliveredE checks that no message with the given body is delivered to the temporary SQS queue of
+// the subscription within the given duration (e.g., because the filter policy of the subscription doesn't match it),
+// and returns an error if one is.
+func AssertSnsMessageNotDeliveredE(t testing.TestingT, subscription *SnsTestSubscription, message string, wait time.Duration) error {
+	delivered, err := waitForSnsTestMessage(t, subscription, message, wait)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		return SnsMessageDelivered{TopicArn: subscription.TopicArn, QueueUrl: subscription.QueueURL, Message: message}
+	}
+	return nil
+}
+
+// waitForSnsTestMessage receives messages from the temporary SQS queue of the subscription until one with the given body
+// arrives or the timeout expires, and returns whether it arrived. All received messages are deleted from the queue.
+func waitForSnsTestMessage(t testing.TestingT, subscription *SnsTestSubscription, message string, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		// The API only allows us to wait a max 20 seconds for a new message to arrive.
+		waitTimeSeconds := int(time.Until(deadline).Seconds())
+		if waitTimeSeconds > 20 {
+			waitTimeSeconds = 20
+		}
+		if waitTimeSeconds < 0 {
+			waitTimeSeconds = 0
+		}
+
+		messages, err := ReceiveMessageBatchFromQueueE(t, subscription.Region, subscription.QueueURL, maxSqsBatchSize, waitTimeSeconds)
+		if err != nil {
+			return false, err
+		}
+		found := false
+		for _, received := range messages {
+			if err := DeleteMessageFromQueueE(t, subscription.Region, subscription.QueueURL, received.ReceiptHandle); err != nil {
+				return false, err
+			}
+			if received.MessageBody == message {
+				found = true
+			}
+		}
+		if found {
+			return true, nil
+		}
+		if !time.Now().Before(deadline) {
+			return false, nil
+		}
+	}
+}
+
 // NewSnsClient creates a new SNS client.
 func NewSnsClient(t testing.TestingT, region string) *sns.SNS {
 	client, err := NewSnsClientE(t, region)
@@ -73,10 +340,34 @@ func NewSnsClient(t testing.TestingT, region string) *sns.SNS {
 
 // NewSnsClientE creates a new SNS client.
 func NewSnsClientE(t testing.TestingT, region string) (*sns.SNS, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
 
 	return sns.New(sess), nil
 }
+
+// SnsMessageNotDelivered is an error that occurs if a message published to an SNS topic is not delivered to a
+// subscription.
+type SnsMessageNotDelivered struct {
+	TopicArn string
+	QueueUrl string
+	Message  string
+}
+
+func (err SnsMessageNotDelivered) Error() string {
+	return fmt.Sprintf("Message %s published to SNS topic %s was not delivered to queue %s", err.Message, err.TopicArn, err.QueueUrl)
+}
+
+// SnsMessageDelivered is an error that occurs if a message published to an SNS topic is delivered to a subscription
+// that should have filtered it out.
+type SnsMessageDelivered struct {
+	TopicArn string
+	QueueUrl string
+	Message  string
+}
+
+func (err SnsMessageDelivered) Error() string {
+	return fmt.Sprintf("Message %s published to SNS topic %s was unexpectedly delivered to queue %s", err.Message, err.TopicArn, err.QueueUrl)
+}
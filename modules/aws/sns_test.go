@@ -1,14 +1,17 @@
 package aws
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateAndDeleteSnsTopic(t *testing.T) {
@@ -24,6 +27,43 @@ func TestCreateAndDeleteSnsTopic(t *testing.T) {
 	assert.True(t, snsTopicExists(t, region, arn))
 }
 
+func TestSnsTestSubscriptionDelivery(t *testing.T) {
+	t.Parallel()
+
+	region := GetRandomStableRegion(t, nil, nil)
+	uniqueID := random.UniqueId()
+	arn := CreateSnsTopic(t, region, fmt.Sprintf("test-sns-delivery-%s", uniqueID))
+	defer deleteTopic(t, region, arn)
+
+	subscription, err := SubscribeTestQueueToSnsTopicE(t, region, arn, "")
+	defer DeleteSnsTestSubscription(t, subscription)
+	require.NoError(t, err)
+
+	message := fmt.Sprintf("test-message-%s", uniqueID)
+	PublishSnsMessage(t, region, arn, message, map[string]string{"event": "created"})
+	AssertSnsMessageDelivered(t, subscription, message, 2*time.Minute)
+}
+
+func TestNewSnsToSqsQueuePolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := newSnsToSqsQueuePolicy("arn:aws:sns:us-east-1:123456789012:topic", "arn:aws:sqs:us-east-1:123456789012:queue")
+	require.NoError(t, err)
+
+	var parsed struct {
+		Statement []struct {
+			Action    string
+			Resource  string
+			Condition map[string]map[string]string
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(policy), &parsed))
+	require.Len(t, parsed.Statement, 1)
+	assert.Equal(t, "sqs:SendMessage", parsed.Statement[0].Action)
+	assert.Equal(t, "arn:aws:sqs:us-east-1:123456789012:queue", parsed.Statement[0].Resource)
+	assert.Equal(t, "arn:aws:sns:us-east-1:123456789012:topic", parsed.Statement[0].Condition["ArnEquals"]["aws:SourceArn"])
+}
+
 func snsTopicExists(t *testing.T, region string, arn string) bool {
 	snsClient := NewSnsClient(t, region)
 
@@ -1,14 +1,17 @@
 package aws
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/google/uuid"
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
 
@@ -210,9 +213,12 @@ func SendMessageToFifoQueueE(t testing.TestingT, awsRegion string, queueURL stri
 
 // QueueMessageResponse contains a queue message.
 type QueueMessageResponse struct {
-	ReceiptHandle string
-	MessageBody   string
-	Error         error
+	ReceiptHandle          string
+	MessageBody            string
+	MessageID              string
+	MessageGroupID         string // Only set for messages received from FIFO queues
+	MessageDeduplicationID string // Only set for messages received from FIFO queues
+	Error                  error
 }
 
 // WaitForQueueMessage waits to receive a message from on the queueURL. Since the API only allows us to wait a max 20 seconds for a new
@@ -253,6 +259,286 @@ func WaitForQueueMessage(t testing.TestingT, awsRegion string, queueURL string,
 	return QueueMessageResponse{Error: ReceiveMessageTimeout{QueueUrl: queueURL, TimeoutSec: timeout}}
 }
 
+// maxSqsBatchSize is the maximum number of messages that can be sent or received in a single SQS API call.
+const maxSqsBatchSize = 10
+
+// QueueMessage is a message to send to an SQS queue with SendMessageBatchToQueue.
+type QueueMessage struct {
+	MessageBody            string
+	MessageGroupID         string // Required for FIFO queues
+	MessageDeduplicationID string // Only for FIFO queues, and optional if content-based deduplication is enabled
+}
+
+// SendMessageToFifoQueueWithDeduplicationID sends the given message to the FIFO SQS queue with the given URL, using the
+// given deduplication ID rather than content-based deduplication.
+func SendMessageToFifoQueueWithDeduplicationID(t testing.TestingT, awsRegion string, queueURL string, message string, messageGroupID string, deduplicationID string) {
+	err := SendMessageToFifoQueueWithDeduplicationIDE(t, awsRegion, queueURL, message, messageGroupID, deduplicationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SendMessageToFifoQueueWithDeduplicationIDE sends the given message to the FIFO SQS queue with the given URL, using
+// the given deduplication ID rather than content-based deduplication.
+func SendMessageToFifoQueueWithDeduplicationIDE(t testing.TestingT, awsRegion string, queueURL string, message string, messageGroupID string, deduplicationID string) error {
+	logger.Logf(t, "Sending message %s with deduplication id %s to queue %s", message, deduplicationID, queueURL)
+
+	sqsClient, err := NewSqsClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	res, err := sqsClient.SendMessage(&sqs.SendMessageInput{
+		MessageBody:            aws.String(message),
+		QueueUrl:               aws.String(queueURL),
+		MessageGroupId:         aws.String(messageGroupID),
+		MessageDeduplicationId: aws.String(deduplicationID),
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Logf(t, "Message id %s sent to queue %s", aws.StringValue(res.MessageId), queueURL)
+	return nil
+}
+
+// SendMessageBatchToQueue sends the given messages to the SQS queue with the given URL, in batches of up to 10 messages.
+func SendMessageBatchToQueue(t testing.TestingT, awsRegion string, queueURL string, messages []QueueMessage) {
+	err := SendMessageBatchToQueueE(t, awsRegion, queueURL, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SendMessageBatchToQueueE sends the given messages to the SQS queue with the given URL, in batches of up to 10
+// messages. Returns a SendMessageBatchFailed error if any of the messages could not be sent.
+func SendMessageBatchToQueueE(t testing.TestingT, awsRegion string, queueURL string, messages []QueueMessage) error {
+	logger.Logf(t, "Sending %d messages to queue %s", len(messages), queueURL)
+
+	sqsClient, err := NewSqsClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	for _, entries := range newSqsSendMessageBatches(messages) {
+		res, err := sqsClient.SendMessageBatch(&sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(res.Failed) > 0 {
+			failures := map[string]string{}
+			for _, failure := range res.Failed {
+				failures[aws.StringValue(failure.Id)] = fmt.Sprintf("%s: %s", aws.StringValue(failure.Code), aws.StringValue(failure.Message))
+			}
+			return SendMessageBatchFailed{QueueUrl: queueURL, Failures: failures}
+		}
+	}
+	return nil
+}
+
+// newSqsSendMessageBatches converts the given messages into batches of send message entries, with the index of each
+// message as its entry ID.
+func newSqsSendMessageBatches(messages []QueueMessage) [][]*sqs.SendMessageBatchRequestEntry {
+	batches := [][]*sqs.SendMessageBatchRequestEntry{}
+	for start := 0; start < len(messages); start += maxSqsBatchSize {
+		end := start + maxSqsBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		entries := []*sqs.SendMessageBatchRequestEntry{}
+		for index, message := range messages[start:end] {
+			entry := &sqs.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(start + index)),
+				MessageBody: aws.String(message.MessageBody),
+			}
+			if message.MessageGroupID != "" {
+				entry.MessageGroupId = aws.String(message.MessageGroupID)
+			}
+			if message.MessageDeduplicationID != "" {
+				entry.MessageDeduplicationId = aws.String(message.MessageDeduplicationID)
+			}
+			entries = append(entries, entry)
+		}
+		batches = append(batches, entries)
+	}
+	return batches
+}
+
+// ReceiveMessageBatchFromQueue receives up to maxMessages messages (at most 10) from the SQS queue with the given URL,
+// waiting up to waitTimeSeconds (at most 20) for messages to arrive. The messages are not deleted from the queue.
+func ReceiveMessageBatchFromQueue(t testing.TestingT, awsRegion string, queueURL string, maxMessages int, waitTimeSeconds int) []QueueMessageResponse {
+	messages, err := ReceiveMessageBatchFromQueueE(t, awsRegion, queueURL, maxMessages, waitTimeSeconds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+// ReceiveMessageBatchFromQueueE receives up to maxMessages messages (at most 10) from the SQS queue with the given URL,
+// waiting up to waitTimeSeconds (at most 20) for messages to arrive. The messages are not deleted from the queue.
+func ReceiveMessageBatchFromQueueE(t testing.TestingT, awsRegion string, queueURL string, maxMessages int, waitTimeSeconds int) ([]QueueMessageResponse, error) {
+	sqsClient, err := NewSqsClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := sqsClient.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:              aws.String(queueURL),
+		AttributeNames:        aws.StringSlice([]string{sqs.MessageSystemAttributeNameMessageGroupId, sqs.MessageSystemAttributeNameMessageDeduplicationId}),
+		MaxNumberOfMessages:   aws.Int64(int64(maxMessages)),
+		MessageAttributeNames: aws.StringSlice([]string{"All"}),
+		WaitTimeSeconds:       aws.Int64(int64(waitTimeSeconds)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []QueueMessageResponse{}
+	for _, message := range result.Messages {
+		messages = append(messages, QueueMessageResponse{
+			ReceiptHandle:          aws.StringValue(message.ReceiptHandle),
+			MessageBody:            aws.StringValue(message.Body),
+			MessageID:              aws.StringValue(message.MessageId),
+			MessageGroupID:         aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]),
+			MessageDeduplicationID: aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameMessageDeduplicationId]),
+		})
+	}
+	logger.Logf(t, "Received %d messages on %s", len(messages), queueURL)
+	return messages, nil
+}
+
+// PurgeQueue deletes all the messages in the SQS queue with the given URL.
+func PurgeQueue(t testing.TestingT, awsRegion string, queueURL string) {
+	err := PurgeQueueE(t, awsRegion, queueURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// PurgeQueueE deletes all the messages in the SQS queue with the given URL. Note that SQS only allows purging a queue
+// once every 60 seconds, and that it may take up to 60 seconds for the messages to be deleted.
+func PurgeQueueE(t testing.TestingT, awsRegion string, queueURL string) error {
+	logger.Logf(t, "Purging SQS Queue %s", queueURL)
+
+	sqsClient, err := NewSqsClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.PurgeQueue(&sqs.PurgeQueueInput{
+		QueueUrl: aws.String(queueURL),
+	})
+	return err
+}
+
+// AssertMessagesLandInDLQ checks that messages land in the dead-letter queue configured in the redrive policy of the
+// SQS queue with the given URL within the given duration, and fails the test if they do not.
+func AssertMessagesLandInDLQ(t testing.TestingT, awsRegion string, queueURL string, within time.Duration) {
+	err := AssertMessagesLandInDLQE(t, awsRegion, queueURL, within)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertMessagesLandInDLQE checks that messages land in the dead-letter queue configured in the redrive policy of the
+// SQS queue with the given URL within the given duration, and returns an error if they do not. This is useful to test
+// that messages that fail to be processed are moved to the dead-letter queue. The messages are not received from the
+// dead-letter queue, so they can still be inspected afterwards.
+func AssertMessagesLandInDLQE(t testing.TestingT, awsRegion string, queueURL string, within time.Duration) error {
+	sqsClient, err := NewSqsClientE(t, awsRegion)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameRedrivePolicy}),
+	})
+	if err != nil {
+		return err
+	}
+	redrivePolicy, hasRedrivePolicy := attributes.Attributes[sqs.QueueAttributeNameRedrivePolicy]
+	if !hasRedrivePolicy {
+		return NoRedrivePolicy{QueueUrl: queueURL}
+	}
+	accountID, queueName, err := parseSqsDeadLetterTarget(aws.StringValue(redrivePolicy))
+	if err != nil {
+		return err
+	}
+	dlq, err := sqsClient.GetQueueUrl(&sqs.GetQueueUrlInput{
+		QueueName:              aws.String(queueName),
+		QueueOwnerAWSAccountId: aws.String(accountID),
+	})
+	if err != nil {
+		return err
+	}
+	dlqURL := aws.StringValue(dlq.QueueUrl)
+
+	sleepBetweenRetries := 5 * time.Second
+	if within < sleepBetweenRetries {
+		sleepBetweenRetries = within
+	}
+	maxRetries := 1
+	if sleepBetweenRetries > 0 {
+		maxRetries = int(within/sleepBetweenRetries) + 1
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for messages to land in dead-letter queue %s of queue %s.", dlqURL, queueURL),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			dlqAttributes, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+				QueueUrl: aws.String(dlqURL),
+				AttributeNames: aws.StringSlice([]string{
+					sqs.QueueAttributeNameApproximateNumberOfMessages,
+					sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+				}),
+			})
+			if err != nil {
+				return "", err
+			}
+			count := 0
+			for _, value := range dlqAttributes.Attributes {
+				number, err := strconv.Atoi(aws.StringValue(value))
+				if err != nil {
+					return "", err
+				}
+				count += number
+			}
+			if count == 0 {
+				return "", NoMessagesInDLQ{QueueUrl: queueURL, DLQUrl: dlqURL}
+			}
+			return fmt.Sprintf("%d messages landed in dead-letter queue %s", count, dlqURL), nil
+		},
+	)
+	logger.Log(t, msg)
+	return err
+}
+
+// parseSqsDeadLetterTarget returns the account ID and name of the dead-letter queue configured in the given redrive
+// policy of an SQS queue.
+func parseSqsDeadLetterTarget(redrivePolicy string) (string, string, error) {
+	var policy struct {
+		DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	}
+	if err := json.Unmarshal([]byte(redrivePolicy), &policy); err != nil {
+		return "", "", err
+	}
+
+	// SQS queue ARNs look like arn:aws:sqs:<region>:<account-id>:<queue-name>
+	parts := strings.Split(policy.DeadLetterTargetArn, ":")
+	if len(parts) != 6 || parts[2] != "sqs" {
+		return "", "", fmt.Errorf("Invalid dead-letter queue ARN %q in redrive policy", policy.DeadLetterTargetArn)
+	}
+	return parts[4], parts[5], nil
+}
+
 // NewSqsClient creates a new SQS client.
 func NewSqsClient(t testing.TestingT, region string) *sqs.SQS {
 	client, err := NewSqsClientE(t, region)
@@ -264,7 +550,7 @@ func NewSqsClient(t testing.TestingT, region string) *sqs.SQS {
 
 // NewSqsClientE creates a new SQS client.
 func NewSqsClientE(t testing.TestingT, region string) (*sqs.SQS, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -281,3 +567,32 @@ type ReceiveMessageTimeout struct {
 func (err ReceiveMessageTimeout) Error() string {
 	return fmt.Sprintf("Failed to receive messages on %s within %s seconds", err.QueueUrl, strconv.Itoa(err.TimeoutSec))
 }
+
+// SendMessageBatchFailed is an error that occurs if some of the messages of a batch could not be sent.
+type SendMessageBatchFailed struct {
+	QueueUrl string
+	Failures map[string]string
+}
+
+func (err SendMessageBatchFailed) Error() string {
+	return fmt.Sprintf("Failed to send %d messages to %s: %v", len(err.Failures), err.QueueUrl, err.Failures)
+}
+
+// NoRedrivePolicy is an error that occurs if a queue has no redrive policy, and so no dead-letter queue.
+type NoRedrivePolicy struct {
+	QueueUrl string
+}
+
+func (err NoRedrivePolicy) Error() string {
+	return fmt.Sprintf("Queue %s has no redrive policy, so it has no dead-letter queue", err.QueueUrl)
+}
+
+// NoMessagesInDLQ is an error that occurs if no messages landed in the dead-letter queue of a queue.
+type NoMessagesInDLQ struct {
+	QueueUrl string
+	DLQUrl   string
+}
+
+func (err NoMessagesInDLQ) Error() string {
+	return fmt.Sprintf("No messages landed in dead-letter queue %s of queue %s", err.DLQUrl, err.QueueUrl)
+}
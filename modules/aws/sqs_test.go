@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSqsQueueMethods(t *testing.T) {
@@ -66,6 +67,40 @@ func TestFifoSqsQueueMethods(t *testing.T) {
 	assert.Error(t, secondResponse.Error, ReceiveMessageTimeout{QueueUrl: url, TimeoutSec: timeoutSec})
 }
 
+func TestNewSqsSendMessageBatches(t *testing.T) {
+	t.Parallel()
+
+	messages := []QueueMessage{}
+	for i := 0; i < 23; i++ {
+		messages = append(messages, QueueMessage{MessageBody: fmt.Sprintf("message-%d", i), MessageGroupID: "group"})
+	}
+	messages[22].MessageDeduplicationID = "dedup"
+
+	batches := newSqsSendMessageBatches(messages)
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], 10)
+	assert.Len(t, batches[2], 3)
+
+	lastEntry := batches[2][2]
+	assert.Equal(t, "22", aws.StringValue(lastEntry.Id))
+	assert.Equal(t, "message-22", aws.StringValue(lastEntry.MessageBody))
+	assert.Equal(t, "group", aws.StringValue(lastEntry.MessageGroupId))
+	assert.Equal(t, "dedup", aws.StringValue(lastEntry.MessageDeduplicationId))
+	assert.Nil(t, batches[0][0].MessageDeduplicationId)
+}
+
+func TestParseSqsDeadLetterTarget(t *testing.T) {
+	t.Parallel()
+
+	accountID, queueName, err := parseSqsDeadLetterTarget(`{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123456789012:test-dlq","maxReceiveCount":3}`)
+	require.NoError(t, err)
+	assert.Equal(t, "123456789012", accountID)
+	assert.Equal(t, "test-dlq", queueName)
+
+	_, _, err = parseSqsDeadLetterTarget(`{"maxReceiveCount":3}`)
+	assert.Error(t, err)
+}
+
 func queueExists(t *testing.T, region string, url string) bool {
 	sqsClient := NewSqsClient(t, region)
 
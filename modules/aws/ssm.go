@@ -66,6 +66,72 @@ func PutParameterWithClientE(t testing.TestingT, client *ssm.SSM, keyName string
 	return *resp.Version, nil
 }
 
+// PutParameterWithType creates or overwrites SSM Parameter at keyName with keyValue as the given type (e.g., ssm.ParameterTypeString),
+// encrypted with the given KMS key if it is a SecureString, and returns the version of the parameter.
+func PutParameterWithType(t testing.TestingT, awsRegion string, keyName string, keyDescription string, keyValue string, parameterType string, kmsKeyID string) int64 {
+	version, err := PutParameterWithTypeE(t, awsRegion, keyName, keyDescription, keyValue, parameterType, kmsKeyID)
+	require.NoError(t, err)
+	return version
+}
+
+// PutParameterWithTypeE creates or overwrites SSM Parameter at keyName with keyValue as the given type (e.g., ssm.ParameterTypeString),
+// encrypted with the given KMS key if it is a SecureString, and returns the version of the parameter. If kmsKeyID is empty, SecureString
+// parameters are encrypted with the default "aws/ssm" KMS key.
+func PutParameterWithTypeE(t testing.TestingT, awsRegion string, keyName string, keyDescription string, keyValue string, parameterType string, kmsKeyID string) (int64, error) {
+	ssmClient, err := NewSsmClientE(t, awsRegion)
+	if err != nil {
+		return 0, err
+	}
+
+	input := &ssm.PutParameterInput{
+		Name:        aws.String(keyName),
+		Description: aws.String(keyDescription),
+		Value:       aws.String(keyValue),
+		Type:        aws.String(parameterType),
+		Overwrite:   aws.Bool(true),
+	}
+	if kmsKeyID != "" {
+		input.KeyId = aws.String(kmsKeyID)
+	}
+	resp, err := ssmClient.PutParameter(input)
+	if err != nil {
+		return 0, err
+	}
+
+	return aws.Int64Value(resp.Version), nil
+}
+
+// GetParametersByPath retrieves the latest version of all SSM Parameters under the given path (e.g., "/my-app/"), recursively, with decryption.
+// The parameters are returned by name.
+func GetParametersByPath(t testing.TestingT, awsRegion string, path string) map[string]string {
+	parameters, err := GetParametersByPathE(t, awsRegion, path)
+	require.NoError(t, err)
+	return parameters
+}
+
+// GetParametersByPathE retrieves the latest version of all SSM Parameters under the given path (e.g., "/my-app/"), recursively, with decryption.
+// The parameters are returned by name.
+func GetParametersByPathE(t testing.TestingT, awsRegion string, path string) (map[string]string, error) {
+	ssmClient, err := NewSsmClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := map[string]string{}
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}
+	err = ssmClient.GetParametersByPathPages(input, func(output *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range output.Parameters {
+			parameters[aws.StringValue(parameter.Name)] = aws.StringValue(parameter.Value)
+		}
+		return true
+	})
+	return parameters, err
+}
+
 // DeleteParameter deletes all versions of SSM Parameter at keyName.
 func DeleteParameter(t testing.TestingT, awsRegion string, keyName string) {
 	err := DeleteParameterE(t, awsRegion, keyName)
@@ -100,7 +166,7 @@ func NewSsmClient(t testing.TestingT, region string) *ssm.SSM {
 
 // NewSsmClientE creates an SSM client.
 func NewSsmClientE(t testing.TestingT, region string) (*ssm.SSM, error) {
-	sess, err := NewAuthenticatedSession(region)
+	sess, err := newAuthenticatedSessionForTest(t, region)
 	if err != nil {
 		return nil, err
 	}
@@ -260,3 +326,106 @@ func CheckSSMCommandWithClientWithDocumentE(t testing.TestingT, client *ssm.SSM,
 
 	return result, nil
 }
+
+// ssmCommandPollInterval is how often RunSsmCommandOnInstance checks if the command is done.
+const ssmCommandPollInterval = 2 * time.Second
+
+// RunSsmCommandOnInstance runs the given shell script on the given instance through AWS SSM, waiting for up to the given timeout for it to
+// finish, and returns its output. This will fail the test if the script can't be run or exits with a non-zero exit code.
+func RunSsmCommandOnInstance(t testing.TestingT, awsRegion string, instanceID string, script string, timeout time.Duration) *CommandOutput {
+	result, err := RunSsmCommandOnInstanceE(t, awsRegion, instanceID, script, timeout)
+	require.NoError(t, err)
+	return result
+}
+
+// RunSsmCommandOnInstanceE runs the given shell script on the given instance through AWS SSM, waiting for up to the given timeout for it to
+// finish, and returns its output. This is an alternative to SSH for instances without public access, as it only requires the instance to
+// run the SSM agent with an instance profile that allows it to use SSM.
+//
+// Unlike CheckSsmCommandE, the script may span several lines, the command is cancelled on the instance if it times out, and the output is
+// returned with an SsmCommandFailed error if the script exits with a non-zero exit code. SSM truncates the stdout and stderr it returns to
+// 24000 characters each.
+func RunSsmCommandOnInstanceE(t testing.TestingT, awsRegion string, instanceID string, script string, timeout time.Duration) (*CommandOutput, error) {
+	logger.Logf(t, "Running script on EC2 instance with ID '%s' through SSM", instanceID)
+
+	client, err := NewSsmClientE(t, awsRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSM requires an execution timeout of at least 1 second.
+	executionTimeout := int64(timeout.Seconds())
+	if executionTimeout < 1 {
+		executionTimeout = 1
+	}
+	resp, err := client.SendCommand(&ssm.SendCommandInput{
+		Comment:      aws.String("Terratest SSM"),
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  aws.StringSlice([]string{instanceID}),
+		Parameters: map[string][]*string{
+			"commands":         aws.StringSlice([]string{script}),
+			"executionTimeout": aws.StringSlice([]string{fmt.Sprintf("%d", executionTimeout)}),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	commandID := resp.Command.CommandId
+
+	maxRetries := int(timeout/ssmCommandPollInterval) + 1
+	result := &CommandOutput{}
+	_, err = retry.DoWithRetryE(t, fmt.Sprintf("Waiting for the script to finish on %s", instanceID), maxRetries, ssmCommandPollInterval, func() (string, error) {
+		invocation, err := client.GetCommandInvocation(&ssm.GetCommandInvocationInput{
+			CommandId:  commandID,
+			InstanceId: aws.String(instanceID),
+		})
+		if err != nil {
+			// The invocation may not exist for a few seconds after the command is sent.
+			return "", err
+		}
+		return "", checkSsmCommandInvocation(instanceID, invocation, result)
+	})
+
+	if err == nil {
+		return result, nil
+	}
+	if fatalErr, isFatalErr := err.(retry.FatalError); isFatalErr {
+		return result, fatalErr.Underlying
+	}
+
+	// The command didn't finish in time, so don't leave it running on the instance.
+	if _, cancelErr := client.CancelCommand(&ssm.CancelCommandInput{CommandId: commandID}); cancelErr != nil {
+		logger.Logf(t, "Failed to cancel SSM command %s: %v", aws.StringValue(commandID), cancelErr)
+	}
+	return result, err
+}
+
+// checkSsmCommandInvocation records the output of the given invocation in the result, and returns nil if it succeeded, a retry.FatalError
+// if it finished without succeeding, or an error if it hasn't finished yet.
+func checkSsmCommandInvocation(instanceID string, invocation *ssm.GetCommandInvocationOutput, result *CommandOutput) error {
+	result.Stdout = aws.StringValue(invocation.StandardOutputContent)
+	result.Stderr = aws.StringValue(invocation.StandardErrorContent)
+	result.ExitCode = aws.Int64Value(invocation.ResponseCode)
+
+	status := aws.StringValue(invocation.Status)
+	switch status {
+	case ssm.CommandInvocationStatusSuccess:
+		return nil
+	case ssm.CommandInvocationStatusFailed, ssm.CommandInvocationStatusCancelled, ssm.CommandInvocationStatusTimedOut:
+		return retry.FatalError{Underlying: SsmCommandFailed{InstanceID: instanceID, Status: status, StatusDetails: aws.StringValue(invocation.StatusDetails), ExitCode: result.ExitCode}}
+	default:
+		return fmt.Errorf("bad status: %s", status)
+	}
+}
+
+// SsmCommandFailed is an error that occurs when a command run through SSM fails or doesn't finish in time.
+type SsmCommandFailed struct {
+	InstanceID    string
+	Status        string
+	StatusDetails string
+	ExitCode      int64
+}
+
+func (err SsmCommandFailed) Error() string {
+	return fmt.Sprintf("SSM command on %s finished with status %s (%s) and exit code %d", err.InstanceID, err.Status, err.StatusDetails, err.ExitCode)
+}
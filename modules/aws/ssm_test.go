@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +41,46 @@ func TestParameterIsDeleted(t *testing.T) {
 	assert.Equal(t, actualValue, "")
 	assert.Error(t, err)
 }
+
+func TestGetParametersByPath(t *testing.T) {
+	t.Parallel()
+
+	awsRegion := GetRandomStableRegion(t, nil, nil)
+	path := fmt.Sprintf("/terratest/%s", random.UniqueId())
+	stringName := path + "/plain"
+	secureName := path + "/nested/secret"
+
+	PutParameterWithType(t, awsRegion, stringName, "This is a test key", "plain value", ssm.ParameterTypeString, "")
+	defer DeleteParameter(t, awsRegion, stringName)
+	PutParameterWithType(t, awsRegion, secureName, "This is a test key", "secret value", ssm.ParameterTypeSecureString, "")
+	defer DeleteParameter(t, awsRegion, secureName)
+
+	parameters := GetParametersByPath(t, awsRegion, path)
+	assert.Equal(t, map[string]string{stringName: "plain value", secureName: "secret value"}, parameters)
+}
+
+func TestCheckSsmCommandInvocation(t *testing.T) {
+	t.Parallel()
+
+	invocation := &ssm.GetCommandInvocationOutput{
+		Status:                aws.String(ssm.CommandInvocationStatusInProgress),
+		StandardOutputContent: aws.String("partial"),
+		ResponseCode:          aws.Int64(-1),
+	}
+	result := &CommandOutput{}
+	err := checkSsmCommandInvocation("i-1", invocation, result)
+	assert.EqualError(t, err, "bad status: InProgress")
+	assert.Equal(t, "partial", result.Stdout)
+
+	invocation.Status = aws.String(ssm.CommandInvocationStatusSuccess)
+	invocation.ResponseCode = aws.Int64(0)
+	assert.NoError(t, checkSsmCommandInvocation("i-1", invocation, result))
+
+	invocation.Status = aws.String(ssm.CommandInvocationStatusFailed)
+	invocation.StandardErrorContent = aws.String("boom")
+	invocation.ResponseCode = aws.Int64(2)
+	err = checkSsmCommandInvocation("i-1", invocation, result)
+	assert.IsType(t, retry.FatalError{}, err)
+	assert.Equal(t, int64(2), result.ExitCode)
+	assert.Equal(t, "boom", result.Stderr)
+}
@@ -0,0 +1,159 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// ssmInstancePortForwardingDocument is the SSM document that forwards a local port to a port of an instance.
+	ssmInstancePortForwardingDocument = "AWS-StartPortForwardingSession"
+	// ssmPortForwardingDocument is the SSM document that forwards a local port to a host reachable from an instance.
+	ssmPortForwardingDocument = "AWS-StartPortForwardingSessionToRemoteHost"
+	// ssmTunnelTimeout is how long to wait for an SSM tunnel to accept connections.
+	ssmTunnelTimeout = 30 * time.Second
+)
+
+// SsmTunnel forwards connections to a local port to a port of an EC2 instance, or to a host reachable from it, through
+// an SSM Session Manager port forwarding session.
+type SsmTunnel struct {
+	cmd       *exec.Cmd
+	localPort int
+}
+
+// LocalPort returns the local port the tunnel listens on.
+func (tunnel *SsmTunnel) LocalPort() int {
+	return tunnel.localPort
+}
+
+// LocalAddress returns the local address the tunnel listens on (e.g., 127.0.0.1:54321), to use in place of the remote
+// address with the http-helper or database helpers.
+func (tunnel *SsmTunnel) LocalAddress() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(tunnel.localPort))
+}
+
+// Close ends the port forwarding session.
+func (tunnel *SsmTunnel) Close() error {
+	if err := tunnel.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	// The session is killed, so the error it exits with is expected.
+	tunnel.cmd.Wait()
+	return nil
+}
+
+// OpenSsmTunnel opens an SSM port forwarding session from a random local port to the given port of the given EC2
+// instance. This will fail the test if there is an error.
+func OpenSsmTunnel(t testing.TestingT, awsRegion string, instanceID string, port int) *SsmTunnel {
+	tunnel, err := OpenSsmTunnelE(t, awsRegion, instanceID, port)
+	require.NoError(t, err)
+	return tunnel
+}
+
+// OpenSsmTunnelE opens an SSM port forwarding session from a random local port to the given port of the given EC2
+// instance, and waits until the local port accepts connections. The instance needs neither a public IP nor a bastion
+// host, only the SSM agent and a route to the SSM endpoints. This requires the AWS CLI and its Session Manager plugin.
+// Close the tunnel when done:
+//
+//	tunnel := aws.OpenSsmTunnel(t, awsRegion, instanceID, 8080)
+//	defer tunnel.Close()
+//	http_helper.HttpGetWithRetry(t, "http://"+tunnel.LocalAddress()+"/health", nil, 200, "OK", 30, 5*time.Second)
+func OpenSsmTunnelE(t testing.TestingT, awsRegion string, instanceID string, port int) (*SsmTunnel, error) {
+	logger.Logf(t, "Opening SSM tunnel to port %d of %s", port, instanceID)
+	return openSsmTunnel(t, awsRegion, instanceID, ssmInstancePortForwardingDocument, map[string][]string{
+		"portNumber": {strconv.Itoa(port)},
+	})
+}
+
+// OpenSsmTunnelToRemoteHost opens an SSM port forwarding session from a random local port to the given host and port
+// via the given EC2 instance. This will fail the test if there is an error.
+func OpenSsmTunnelToRemoteHost(t testing.TestingT, awsRegion string, instanceID string, host string, port int) *SsmTunnel {
+	tunnel, err := OpenSsmTunnelToRemoteHostE(t, awsRegion, instanceID, host, port)
+	require.NoError(t, err)
+	return tunnel
+}
+
+// OpenSsmTunnelToRemoteHostE opens an SSM port forwarding session from a random local port to the given host and port
+// via the given EC2 instance, and waits until the local port accepts connections. This is useful to reach private
+// resources the instance can reach, such as a database or an internal load balancer. This requires the AWS CLI and its
+// Session Manager plugin. Close the tunnel when done.
+func OpenSsmTunnelToRemoteHostE(t testing.TestingT, awsRegion string, instanceID string, host string, port int) (*SsmTunnel, error) {
+	logger.Logf(t, "Opening SSM tunnel to %s:%d through %s", host, port, instanceID)
+	return openSsmTunnel(t, awsRegion, instanceID, ssmPortForwardingDocument, map[string][]string{
+		"host":       {host},
+		"portNumber": {strconv.Itoa(port)},
+	})
+}
+
+// openSsmTunnel starts an SSM session with the given port forwarding document and parameters from a random local port
+// using the AWS CLI, and waits until the local port accepts connections.
+func openSsmTunnel(t testing.TestingT, awsRegion string, instanceID string, document string, parameters map[string][]string) (*SsmTunnel, error) {
+	localPort, err := getFreeLocalPort()
+	if err != nil {
+		return nil, err
+	}
+	args, err := ssmPortForwardingArgs(awsRegion, instanceID, document, parameters, localPort)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("aws", args...)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	tunnel := &SsmTunnel{cmd: cmd, localPort: localPort}
+
+	localAddress := tunnel.LocalAddress()
+	_, err = retry.DoWithRetryE(t, fmt.Sprintf("Waiting for SSM tunnel on %s", localAddress), int(ssmTunnelTimeout/time.Second), time.Second, func() (string, error) {
+		conn, err := net.Dial("tcp", localAddress)
+		if err != nil {
+			return "", err
+		}
+		conn.Close()
+		return "", nil
+	})
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// ssmPortForwardingArgs returns the arguments of the AWS CLI to start an SSM session with the given port forwarding
+// document and parameters, forwarding the given local port.
+func ssmPortForwardingArgs(awsRegion string, instanceID string, document string, parameters map[string][]string, localPort int) ([]string, error) {
+	allParameters := map[string][]string{"localPortNumber": {strconv.Itoa(localPort)}}
+	for name, values := range parameters {
+		allParameters[name] = values
+	}
+	parametersJSON, err := json.Marshal(allParameters)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		"ssm", "start-session",
+		"--region", awsRegion,
+		"--target", instanceID,
+		"--document-name", document,
+		"--parameters", string(parametersJSON),
+	}, nil
+}
+
+// getFreeLocalPort returns a local port that is not in use.
+func getFreeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
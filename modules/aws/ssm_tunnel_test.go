@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSsmPortForwardingArgs(t *testing.T) {
+	t.Parallel()
+
+	args, err := ssmPortForwardingArgs("us-east-1", "i-0123456789abcdef0", ssmPortForwardingDocument, map[string][]string{"host": {"db.example.com"}, "portNumber": {"5432"}}, 15432)
+	require.NoError(t, err)
+	require.Len(t, args, 10)
+	assert.Equal(t, []string{"ssm", "start-session", "--region", "us-east-1", "--target", "i-0123456789abcdef0", "--document-name", ssmPortForwardingDocument, "--parameters"}, args[:9])
+
+	parameters := map[string][]string{}
+	require.NoError(t, json.Unmarshal([]byte(args[9]), &parameters))
+	assert.Equal(t, map[string][]string{"host": {"db.example.com"}, "portNumber": {"5432"}, "localPortNumber": {"15432"}}, parameters)
+
+	args, err = ssmPortForwardingArgs("us-east-1", "i-0123456789abcdef0", ssmInstancePortForwardingDocument, map[string][]string{"portNumber": {"8080"}}, 18080)
+	require.NoError(t, err)
+	assert.Equal(t, ssmInstancePortForwardingDocument, args[7])
+	parameters = map[string][]string{}
+	require.NoError(t, json.Unmarshal([]byte(args[9]), &parameters))
+	assert.Equal(t, map[string][]string{"portNumber": {"8080"}, "localPortNumber": {"18080"}}, parameters)
+}
+
+func TestSsmTunnelLocalAddress(t *testing.T) {
+	t.Parallel()
+
+	tunnel := &SsmTunnel{cmd: exec.Command("true"), localPort: 15432}
+	assert.Equal(t, 15432, tunnel.LocalPort())
+	assert.Equal(t, "127.0.0.1:15432", tunnel.LocalAddress())
+}
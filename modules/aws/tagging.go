@@ -0,0 +1,208 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// maxTaggingResourceARNs is the maximum number of ARNs that can be given in a single Resource Groups Tagging API call.
+const maxTaggingResourceARNs = 100
+
+// RequireResourcesTagged checks that each of the resources with the given ARNs has the required tags, and fails the
+// test if any of them does not. An empty required value means the tag can have any value.
+func RequireResourcesTagged(t testing.TestingT, region string, resourceARNs []string, requiredTags map[string]string) {
+	err := RequireResourcesTaggedE(t, region, resourceARNs, requiredTags)
+	require.NoError(t, err)
+}
+
+// RequireResourcesTaggedE checks that each of the resources with the given ARNs has the required tags, and returns an
+// error listing every missing or mismatched tag if any of them does not. An empty required value means the tag can
+// have any value. Global resources (e.g., IAM roles or CloudFront distributions) must be checked in us-east-1.
+func RequireResourcesTaggedE(t testing.TestingT, region string, resourceARNs []string, requiredTags map[string]string) error {
+	client, err := NewResourceGroupsTaggingClientE(t, region)
+	if err != nil {
+		return err
+	}
+
+	mappings := []*resourcegroupstaggingapi.ResourceTagMapping{}
+	for start := 0; start < len(resourceARNs); start += maxTaggingResourceARNs {
+		end := start + maxTaggingResourceARNs
+		if end > len(resourceARNs) {
+			end = len(resourceARNs)
+		}
+		input := &resourcegroupstaggingapi.GetResourcesInput{ResourceARNList: aws.StringSlice(resourceARNs[start:end])}
+		err := client.GetResourcesPages(input, func(output *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+			mappings = append(mappings, output.ResourceTagMappingList...)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return checkResourcesTagged(resourceARNs, mappings, requiredTags)
+}
+
+// RequireTestRunResourcesTagged discovers the resources tagged with the given run ID tag (e.g., a tag with a unique ID
+// that the module under test adds to every resource it creates), and checks that each of them has the required tags.
+// This will fail the test if any of them does not.
+func RequireTestRunResourcesTagged(t testing.TestingT, region string, runIDTagKey string, runID string, requiredTags map[string]string) {
+	err := RequireTestRunResourcesTaggedE(t, region, runIDTagKey, runID, requiredTags)
+	require.NoError(t, err)
+}
+
+// RequireTestRunResourcesTaggedE discovers the resources tagged with the given run ID tag (e.g., a tag with a unique ID
+// that the module under test adds to every resource it creates), and checks that each of them has the required tags.
+// Resources the module forgets to tag with the run ID can't be discovered, so check them with RequireResourcesTaggedE.
+func RequireTestRunResourcesTaggedE(t testing.TestingT, region string, runIDTagKey string, runID string, requiredTags map[string]string) error {
+	mappings, err := getTestRunResourceTagMappingsE(t, region, runIDTagKey, runID, false)
+	if err != nil {
+		return err
+	}
+	resourceARNs := []string{}
+	for _, mapping := range mappings {
+		resourceARNs = append(resourceARNs, aws.StringValue(mapping.ResourceARN))
+	}
+	return checkResourcesTagged(resourceARNs, mappings, requiredTags)
+}
+
+// RequireTestRunResourcesCompliant discovers the resources tagged with the given run ID tag, and checks that each of
+// them complies with the tag policies of the AWS organization. This will fail the test if any of them does not.
+func RequireTestRunResourcesCompliant(t testing.TestingT, region string, runIDTagKey string, runID string) {
+	err := RequireTestRunResourcesCompliantE(t, region, runIDTagKey, runID)
+	require.NoError(t, err)
+}
+
+// RequireTestRunResourcesCompliantE discovers the resources tagged with the given run ID tag, and checks that each of
+// them complies with the tag policies of the AWS organization, returning an error listing the noncompliant keys of
+// each resource if any of them does not. The account must be a member of an organization with tag policies.
+func RequireTestRunResourcesCompliantE(t testing.TestingT, region string, runIDTagKey string, runID string) error {
+	mappings, err := getTestRunResourceTagMappingsE(t, region, runIDTagKey, runID, true)
+	if err != nil {
+		return err
+	}
+	return checkResourcesCompliant(mappings)
+}
+
+func getTestRunResourceTagMappingsE(t testing.TestingT, region string, runIDTagKey string, runID string, includeComplianceDetails bool) ([]*resourcegroupstaggingapi.ResourceTagMapping, error) {
+	client, err := NewResourceGroupsTaggingClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	input := &resourcegroupstaggingapi.GetResourcesInput{
+		TagFilters: []*resourcegroupstaggingapi.TagFilter{{Key: aws.String(runIDTagKey), Values: aws.StringSlice([]string{runID})}},
+	}
+	if includeComplianceDetails {
+		input.IncludeComplianceDetails = aws.Bool(true)
+	}
+	mappings := []*resourcegroupstaggingapi.ResourceTagMapping{}
+	err = client.GetResourcesPages(input, func(output *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+		mappings = append(mappings, output.ResourceTagMappingList...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) == 0 {
+		return nil, NewNotFoundError("resources tagged with "+runIDTagKey, runID, region)
+	}
+	return mappings, nil
+}
+
+// checkResourcesTagged returns an error if any of the resources with the given ARNs doesn't have the required tags
+// according to the given tag mappings. Resources without a tag mapping have no tags.
+func checkResourcesTagged(resourceARNs []string, mappings []*resourcegroupstaggingapi.ResourceTagMapping, requiredTags map[string]string) error {
+	tagsByARN := map[string]map[string]string{}
+	for _, mapping := range mappings {
+		tags := map[string]string{}
+		for _, tag := range mapping.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		tagsByARN[aws.StringValue(mapping.ResourceARN)] = tags
+	}
+
+	problems := map[string][]string{}
+	for _, resourceARN := range resourceARNs {
+		tags := tagsByARN[resourceARN]
+		for key, expected := range requiredTags {
+			actual, hasTag := tags[key]
+			switch {
+			case !hasTag:
+				problems[resourceARN] = append(problems[resourceARN], fmt.Sprintf("missing tag %s", key))
+			case expected != "" && actual != expected:
+				problems[resourceARN] = append(problems[resourceARN], fmt.Sprintf("tag %s is %q instead of %q", key, actual, expected))
+			}
+		}
+		sort.Strings(problems[resourceARN])
+	}
+	if len(problems) > 0 {
+		return ResourcesNotTagged{Problems: problems}
+	}
+	return nil
+}
+
+// checkResourcesCompliant returns an error if any of the given tag mappings is not compliant with the tag policies.
+func checkResourcesCompliant(mappings []*resourcegroupstaggingapi.ResourceTagMapping) error {
+	problems := map[string][]string{}
+	for _, mapping := range mappings {
+		details := mapping.ComplianceDetails
+		if details == nil || aws.BoolValue(details.ComplianceStatus) {
+			continue
+		}
+		resourceARN := aws.StringValue(mapping.ResourceARN)
+		for _, key := range details.NoncompliantKeys {
+			problems[resourceARN] = append(problems[resourceARN], fmt.Sprintf("noncompliant key %s", aws.StringValue(key)))
+		}
+		for _, key := range details.KeysWithNoncompliantValues {
+			problems[resourceARN] = append(problems[resourceARN], fmt.Sprintf("noncompliant value of key %s", aws.StringValue(key)))
+		}
+		if len(problems[resourceARN]) == 0 {
+			problems[resourceARN] = []string{"noncompliant"}
+		}
+	}
+	if len(problems) > 0 {
+		return ResourcesNotTagged{Problems: problems}
+	}
+	return nil
+}
+
+// NewResourceGroupsTaggingClient creates a Resource Groups Tagging API client.
+func NewResourceGroupsTaggingClient(t testing.TestingT, region string) *resourcegroupstaggingapi.ResourceGroupsTaggingAPI {
+	client, err := NewResourceGroupsTaggingClientE(t, region)
+	require.NoError(t, err)
+	return client
+}
+
+// NewResourceGroupsTaggingClientE creates a Resource Groups Tagging API client.
+func NewResourceGroupsTaggingClientE(t testing.TestingT, region string) (*resourcegroupstaggingapi.ResourceGroupsTaggingAPI, error) {
+	sess, err := newAuthenticatedSessionForTest(t, region)
+	if err != nil {
+		return nil, err
+	}
+	return resourcegroupstaggingapi.New(sess), nil
+}
+
+// ResourcesNotTagged is an error that occurs if resources don't have the required tags, or don't comply with tag
+// policies.
+type ResourcesNotTagged struct {
+	Problems map[string][]string // The problems of each resource, by ARN
+}
+
+func (err ResourcesNotTagged) Error() string {
+	resourceARNs := []string{}
+	for resourceARN := range err.Problems {
+		resourceARNs = append(resourceARNs, resourceARN)
+	}
+	sort.Strings(resourceARNs)
+
+	lines := []string{fmt.Sprintf("%d resources are not tagged as required:", len(resourceARNs))}
+	for _, resourceARN := range resourceARNs {
+		lines = append(lines, fmt.Sprintf("  %s: %s", resourceARN, strings.Join(err.Problems[resourceARN], ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
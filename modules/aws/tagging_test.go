@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResourceTagMapping(resourceARN string, tags map[string]string) *resourcegroupstaggingapi.ResourceTagMapping {
+	mapping := &resourcegroupstaggingapi.ResourceTagMapping{ResourceARN: aws.String(resourceARN)}
+	for key, value := range tags {
+		mapping.Tags = append(mapping.Tags, &resourcegroupstaggingapi.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return mapping
+}
+
+func TestCheckResourcesTagged(t *testing.T) {
+	t.Parallel()
+
+	bucketARN := "arn:aws:s3:::terratest-bucket"
+	queueARN := "arn:aws:sqs:us-east-1:123456789012:terratest-queue"
+	roleARN := "arn:aws:iam::123456789012:role/terratest"
+	mappings := []*resourcegroupstaggingapi.ResourceTagMapping{
+		newTestResourceTagMapping(bucketARN, map[string]string{"Team": "platform", "CostCenter": "1234"}),
+		newTestResourceTagMapping(queueARN, map[string]string{"Team": "data"}),
+	}
+	requiredTags := map[string]string{"Team": "platform", "CostCenter": ""}
+
+	assert.NoError(t, checkResourcesTagged([]string{bucketARN}, mappings, requiredTags))
+
+	err := checkResourcesTagged([]string{bucketARN, queueARN, roleARN}, mappings, requiredTags)
+	require.IsType(t, ResourcesNotTagged{}, err)
+	problems := err.(ResourcesNotTagged).Problems
+	assert.NotContains(t, problems, bucketARN)
+	assert.Equal(t, []string{"missing tag CostCenter", `tag Team is "data" instead of "platform"`}, problems[queueARN])
+	assert.Equal(t, []string{"missing tag CostCenter", "missing tag Team"}, problems[roleARN])
+	assert.Contains(t, err.Error(), "2 resources are not tagged as required")
+}
+
+func TestCheckResourcesCompliant(t *testing.T) {
+	t.Parallel()
+
+	compliant := newTestResourceTagMapping("arn:aws:s3:::compliant", nil)
+	compliant.ComplianceDetails = &resourcegroupstaggingapi.ComplianceDetails{ComplianceStatus: aws.Bool(true)}
+	noncompliant := newTestResourceTagMapping("arn:aws:s3:::noncompliant", nil)
+	noncompliant.ComplianceDetails = &resourcegroupstaggingapi.ComplianceDetails{
+		ComplianceStatus:           aws.Bool(false),
+		NoncompliantKeys:           aws.StringSlice([]string{"costcenter"}),
+		KeysWithNoncompliantValues: aws.StringSlice([]string{"Environment"}),
+	}
+
+	assert.NoError(t, checkResourcesCompliant([]*resourcegroupstaggingapi.ResourceTagMapping{compliant}))
+
+	err := checkResourcesCompliant([]*resourcegroupstaggingapi.ResourceTagMapping{compliant, noncompliant})
+	require.IsType(t, ResourcesNotTagged{}, err)
+	assert.Equal(t, map[string][]string{
+		"arn:aws:s3:::noncompliant": {"noncompliant key costcenter", "noncompliant value of key Environment"},
+	}, err.(ResourcesNotTagged).Problems)
+}
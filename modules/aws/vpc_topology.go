@@ -0,0 +1,387 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultRouteDestination is the destination of the default IPv4 route.
+const defaultRouteDestination = "0.0.0.0/0"
+
+// VpcTopology is the network topology of a VPC: its subnets, how they are routed, and what the VPC is connected to.
+type VpcTopology struct {
+	VpcId                     string                     // The ID of the VPC
+	Subnets                   []Subnet                   // The subnets in the VPC
+	RouteTables               []RouteTable               // The route tables of the VPC
+	NatGateways               []NatGateway               // The NAT gateways in the VPC
+	InternetGatewayIds        []string                   // The IDs of the internet gateways attached to the VPC
+	VpcEndpoints              []VpcEndpoint              // The VPC endpoints in the VPC
+	PeeringConnections        []VpcPeeringConnection     // The peering connections the VPC is the requester or accepter of
+	TransitGatewayAttachments []TransitGatewayAttachment // The attachments of the VPC to transit gateways
+}
+
+// RouteTable is a route table of a VPC.
+type RouteTable struct {
+	Id        string   // The ID of the route table
+	Main      bool     // If the route table is the main route table of the VPC
+	SubnetIds []string // The IDs of the subnets explicitly associated with the route table
+	Routes    []Route  // The routes in the route table
+}
+
+// Route is a route in a route table.
+type Route struct {
+	Destination string // The destination IPv4 or IPv6 CIDR block, or prefix list ID
+	Target      string // The ID of the target (e.g., igw-123, nat-123, tgw-123, or local)
+	State       string // The state of the route (active or blackhole)
+}
+
+// NatGateway is a NAT gateway.
+type NatGateway struct {
+	Id       string // The ID of the NAT gateway
+	SubnetId string // The ID of the subnet the NAT gateway is in
+	State    string // The state of the NAT gateway (e.g., available)
+	PublicIp string // The Elastic IP address of the NAT gateway, if it is public
+}
+
+// VpcEndpoint is a VPC endpoint.
+type VpcEndpoint struct {
+	Id            string   // The ID of the VPC endpoint
+	ServiceName   string   // The name of the service (e.g., com.amazonaws.us-east-1.s3)
+	Type          string   // The type of the endpoint (Gateway, Interface, or GatewayLoadBalancer)
+	State         string   // The state of the endpoint (e.g., available)
+	RouteTableIds []string // The IDs of the route tables of a gateway endpoint
+	SubnetIds     []string // The IDs of the subnets of an interface endpoint
+}
+
+// VpcPeeringConnection is a peering connection between two VPCs.
+type VpcPeeringConnection struct {
+	Id             string // The ID of the peering connection
+	RequesterVpcId string // The ID of the VPC that requested the peering connection
+	AccepterVpcId  string // The ID of the VPC that accepted the peering connection
+	Status         string // The status of the peering connection (e.g., active)
+}
+
+// TransitGatewayAttachment is an attachment of a VPC to a transit gateway.
+type TransitGatewayAttachment struct {
+	Id               string   // The ID of the attachment
+	TransitGatewayId string   // The ID of the transit gateway
+	State            string   // The state of the attachment (e.g., available)
+	SubnetIds        []string // The IDs of the subnets the attachment is in
+}
+
+// GetVpcTopology fetches the network topology of the VPC with the given ID.
+func GetVpcTopology(t testing.TestingT, vpcID string, region string) *VpcTopology {
+	topology, err := GetVpcTopologyE(t, vpcID, region)
+	require.NoError(t, err)
+	return topology
+}
+
+// GetVpcTopologyE fetches the network topology of the VPC with the given ID.
+func GetVpcTopologyE(t testing.TestingT, vpcID string, region string) (*VpcTopology, error) {
+	client, err := NewEc2ClientE(t, region)
+	if err != nil {
+		return nil, err
+	}
+	vpcFilter := generateVpcIdFilter(vpcID)
+	filters := []*ec2.Filter{&vpcFilter}
+
+	topology := &VpcTopology{VpcId: vpcID}
+	if topology.Subnets, err = GetSubnetsForVpcE(t, region, filters); err != nil {
+		return nil, err
+	}
+
+	err = client.DescribeRouteTablesPages(&ec2.DescribeRouteTablesInput{Filters: filters}, func(output *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		for _, routeTable := range output.RouteTables {
+			topology.RouteTables = append(topology.RouteTables, newRouteTable(routeTable))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.DescribeNatGatewaysPages(&ec2.DescribeNatGatewaysInput{Filter: filters}, func(output *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, natGateway := range output.NatGateways {
+			publicIP := ""
+			for _, address := range natGateway.NatGatewayAddresses {
+				if address.PublicIp != nil {
+					publicIP = aws.StringValue(address.PublicIp)
+				}
+			}
+			topology.NatGateways = append(topology.NatGateways, NatGateway{
+				Id:       aws.StringValue(natGateway.NatGatewayId),
+				SubnetId: aws.StringValue(natGateway.SubnetId),
+				State:    aws.StringValue(natGateway.State),
+				PublicIp: publicIP,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	internetGatewayFilters := []*ec2.Filter{{Name: aws.String("attachment.vpc-id"), Values: aws.StringSlice([]string{vpcID})}}
+	err = client.DescribeInternetGatewaysPages(&ec2.DescribeInternetGatewaysInput{Filters: internetGatewayFilters}, func(output *ec2.DescribeInternetGatewaysOutput, lastPage bool) bool {
+		for _, internetGateway := range output.InternetGateways {
+			topology.InternetGatewayIds = append(topology.InternetGatewayIds, aws.StringValue(internetGateway.InternetGatewayId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.DescribeVpcEndpointsPages(&ec2.DescribeVpcEndpointsInput{Filters: filters}, func(output *ec2.DescribeVpcEndpointsOutput, lastPage bool) bool {
+		for _, endpoint := range output.VpcEndpoints {
+			topology.VpcEndpoints = append(topology.VpcEndpoints, VpcEndpoint{
+				Id:            aws.StringValue(endpoint.VpcEndpointId),
+				ServiceName:   aws.StringValue(endpoint.ServiceName),
+				Type:          aws.StringValue(endpoint.VpcEndpointType),
+				State:         aws.StringValue(endpoint.State),
+				RouteTableIds: aws.StringValueSlice(endpoint.RouteTableIds),
+				SubnetIds:     aws.StringValueSlice(endpoint.SubnetIds),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The VPC may be either side of a peering connection, and the API can't filter on either side in one request.
+	peeringConnectionIDs := map[string]bool{}
+	for _, filterName := range []string{"requester-vpc-info.vpc-id", "accepter-vpc-info.vpc-id"} {
+		peeringFilters := []*ec2.Filter{{Name: aws.String(filterName), Values: aws.StringSlice([]string{vpcID})}}
+		err = client.DescribeVpcPeeringConnectionsPages(&ec2.DescribeVpcPeeringConnectionsInput{Filters: peeringFilters}, func(output *ec2.DescribeVpcPeeringConnectionsOutput, lastPage bool) bool {
+			for _, connection := range output.VpcPeeringConnections {
+				connectionID := aws.StringValue(connection.VpcPeeringConnectionId)
+				if peeringConnectionIDs[connectionID] {
+					continue
+				}
+				peeringConnectionIDs[connectionID] = true
+				topology.PeeringConnections = append(topology.PeeringConnections, newVpcPeeringConnection(connection))
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = client.DescribeTransitGatewayVpcAttachmentsPages(&ec2.DescribeTransitGatewayVpcAttachmentsInput{Filters: filters}, func(output *ec2.DescribeTransitGatewayVpcAttachmentsOutput, lastPage bool) bool {
+		for _, attachment := range output.TransitGatewayVpcAttachments {
+			topology.TransitGatewayAttachments = append(topology.TransitGatewayAttachments, TransitGatewayAttachment{
+				Id:               aws.StringValue(attachment.TransitGatewayAttachmentId),
+				TransitGatewayId: aws.StringValue(attachment.TransitGatewayId),
+				State:            aws.StringValue(attachment.State),
+				SubnetIds:        aws.StringValueSlice(attachment.SubnetIds),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return topology, nil
+}
+
+func newRouteTable(routeTable *ec2.RouteTable) RouteTable {
+	result := RouteTable{Id: aws.StringValue(routeTable.RouteTableId)}
+	for _, association := range routeTable.Associations {
+		if aws.BoolValue(association.Main) {
+			result.Main = true
+		}
+		if association.SubnetId != nil {
+			result.SubnetIds = append(result.SubnetIds, aws.StringValue(association.SubnetId))
+		}
+	}
+	for _, route := range routeTable.Routes {
+		result.Routes = append(result.Routes, newRoute(route))
+	}
+	return result
+}
+
+func newRoute(route *ec2.Route) Route {
+	destination := aws.StringValue(route.DestinationCidrBlock)
+	if destination == "" {
+		destination = aws.StringValue(route.DestinationIpv6CidrBlock)
+	}
+	if destination == "" {
+		destination = aws.StringValue(route.DestinationPrefixListId)
+	}
+
+	target := ""
+	for _, targetID := range []*string{
+		route.GatewayId,
+		route.NatGatewayId,
+		route.TransitGatewayId,
+		route.VpcPeeringConnectionId,
+		route.EgressOnlyInternetGatewayId,
+		route.NetworkInterfaceId,
+		route.InstanceId,
+		route.CarrierGatewayId,
+		route.LocalGatewayId,
+		route.CoreNetworkArn,
+	} {
+		if targetID != nil {
+			target = aws.StringValue(targetID)
+			break
+		}
+	}
+
+	return Route{Destination: destination, Target: target, State: aws.StringValue(route.State)}
+}
+
+func newVpcPeeringConnection(connection *ec2.VpcPeeringConnection) VpcPeeringConnection {
+	result := VpcPeeringConnection{Id: aws.StringValue(connection.VpcPeeringConnectionId)}
+	if connection.RequesterVpcInfo != nil {
+		result.RequesterVpcId = aws.StringValue(connection.RequesterVpcInfo.VpcId)
+	}
+	if connection.AccepterVpcInfo != nil {
+		result.AccepterVpcId = aws.StringValue(connection.AccepterVpcInfo.VpcId)
+	}
+	if connection.Status != nil {
+		result.Status = aws.StringValue(connection.Status.Code)
+	}
+	return result
+}
+
+// RouteTableForSubnet returns the route table of the subnet with the given ID: the route table it is explicitly
+// associated with, or else the main route table of the VPC. Returns nil if there is neither.
+func (topology *VpcTopology) RouteTableForSubnet(subnetID string) *RouteTable {
+	var mainRouteTable *RouteTable
+	for i := range topology.RouteTables {
+		routeTable := &topology.RouteTables[i]
+		for _, associatedSubnetID := range routeTable.SubnetIds {
+			if associatedSubnetID == subnetID {
+				return routeTable
+			}
+		}
+		if routeTable.Main {
+			mainRouteTable = routeTable
+		}
+	}
+	return mainRouteTable
+}
+
+// RouteForSubnet returns the route for the given destination (e.g., 0.0.0.0/0) in the route table of the subnet with
+// the given ID, or nil if there is none.
+func (topology *VpcTopology) RouteForSubnet(subnetID string, destination string) *Route {
+	routeTable := topology.RouteTableForSubnet(subnetID)
+	if routeTable == nil {
+		return nil
+	}
+	for i := range routeTable.Routes {
+		if routeTable.Routes[i].Destination == destination {
+			return &routeTable.Routes[i]
+		}
+	}
+	return nil
+}
+
+// RequirePrivateSubnetRoutesThroughNat checks that the default route of the subnet with the given ID goes through an
+// available NAT gateway in the VPC, and fails the test if it does not.
+func RequirePrivateSubnetRoutesThroughNat(t testing.TestingT, topology *VpcTopology, subnetID string) {
+	err := RequirePrivateSubnetRoutesThroughNatE(t, topology, subnetID)
+	require.NoError(t, err)
+}
+
+// RequirePrivateSubnetRoutesThroughNatE checks that the default route of the subnet with the given ID goes through an
+// available NAT gateway in the VPC, and returns an error if it does not. As the subnet has a single default route, this
+// also checks that it doesn't route through an internet gateway.
+func RequirePrivateSubnetRoutesThroughNatE(t testing.TestingT, topology *VpcTopology, subnetID string) error {
+	route, err := getActiveDefaultRoute(topology, subnetID, "a NAT gateway")
+	if err != nil {
+		return err
+	}
+	for _, natGateway := range topology.NatGateways {
+		if natGateway.Id == route.Target && natGateway.State == ec2.NatGatewayStateAvailable {
+			return nil
+		}
+	}
+	return NewSubnetRouteMismatchError(subnetID, defaultRouteDestination, "an available NAT gateway in the VPC", route.Target)
+}
+
+// RequirePublicSubnetRoutesThroughIgw checks that the default route of the subnet with the given ID goes through an
+// internet gateway attached to the VPC, and fails the test if it does not.
+func RequirePublicSubnetRoutesThroughIgw(t testing.TestingT, topology *VpcTopology, subnetID string) {
+	err := RequirePublicSubnetRoutesThroughIgwE(t, topology, subnetID)
+	require.NoError(t, err)
+}
+
+// RequirePublicSubnetRoutesThroughIgwE checks that the default route of the subnet with the given ID goes through an
+// internet gateway attached to the VPC, and returns an error if it does not.
+func RequirePublicSubnetRoutesThroughIgwE(t testing.TestingT, topology *VpcTopology, subnetID string) error {
+	route, err := getActiveDefaultRoute(topology, subnetID, "an internet gateway")
+	if err != nil {
+		return err
+	}
+	for _, internetGatewayID := range topology.InternetGatewayIds {
+		if internetGatewayID == route.Target {
+			return nil
+		}
+	}
+	return NewSubnetRouteMismatchError(subnetID, defaultRouteDestination, "an internet gateway attached to the VPC", route.Target)
+}
+
+// getActiveDefaultRoute returns the default route of the given subnet, or an error if it has none or it is not active.
+func getActiveDefaultRoute(topology *VpcTopology, subnetID string, expectedTarget string) (*Route, error) {
+	route := topology.RouteForSubnet(subnetID, defaultRouteDestination)
+	if route == nil {
+		return nil, NewSubnetRouteMismatchError(subnetID, defaultRouteDestination, expectedTarget, "no route")
+	}
+	if route.State != ec2.RouteStateActive {
+		return nil, NewSubnetRouteMismatchError(subnetID, defaultRouteDestination, expectedTarget, fmt.Sprintf("%s (%s)", route.Target, route.State))
+	}
+	return route, nil
+}
+
+// RequireSubnetRoutesTo checks that the subnet with the given ID has an active route for the given destination to the
+// given target (e.g., the ID of a transit gateway or peering connection), and fails the test if it does not.
+func RequireSubnetRoutesTo(t testing.TestingT, topology *VpcTopology, subnetID string, destination string, target string) {
+	err := RequireSubnetRoutesToE(t, topology, subnetID, destination, target)
+	require.NoError(t, err)
+}
+
+// RequireSubnetRoutesToE checks that the subnet with the given ID has an active route for the given destination to the
+// given target (e.g., the ID of a transit gateway or peering connection), and returns an error if it does not.
+func RequireSubnetRoutesToE(t testing.TestingT, topology *VpcTopology, subnetID string, destination string, target string) error {
+	route := topology.RouteForSubnet(subnetID, destination)
+	if route == nil {
+		return NewSubnetRouteMismatchError(subnetID, destination, target, "no route")
+	}
+	if route.Target != target || route.State != ec2.RouteStateActive {
+		return NewSubnetRouteMismatchError(subnetID, destination, target, fmt.Sprintf("%s (%s)", route.Target, route.State))
+	}
+	return nil
+}
+
+// RequireVpcEndpointForService checks that the VPC has an available endpoint for the given service (e.g.,
+// com.amazonaws.us-east-1.s3), and fails the test if it does not.
+func RequireVpcEndpointForService(t testing.TestingT, topology *VpcTopology, serviceName string) {
+	err := RequireVpcEndpointForServiceE(t, topology, serviceName)
+	require.NoError(t, err)
+}
+
+// RequireVpcEndpointForServiceE checks that the VPC has an available endpoint for the given service (e.g.,
+// com.amazonaws.us-east-1.s3), and returns an error if it does not.
+func RequireVpcEndpointForServiceE(t testing.TestingT, topology *VpcTopology, serviceName string) error {
+	states := []string{}
+	for _, endpoint := range topology.VpcEndpoints {
+		if endpoint.ServiceName != serviceName {
+			continue
+		}
+		if strings.EqualFold(endpoint.State, "available") {
+			return nil
+		}
+		states = append(states, endpoint.State)
+	}
+	return NewVpcEndpointNotFoundError(topology.VpcId, serviceName, states)
+}
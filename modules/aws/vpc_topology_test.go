@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVpcTopology() *VpcTopology {
+	return &VpcTopology{
+		VpcId: "vpc-1",
+		RouteTables: []RouteTable{
+			{
+				Id:        "rtb-public",
+				Main:      true,
+				SubnetIds: []string{"subnet-public"},
+				Routes: []Route{
+					{Destination: "10.0.0.0/16", Target: "local", State: ec2.RouteStateActive},
+					{Destination: defaultRouteDestination, Target: "igw-1", State: ec2.RouteStateActive},
+				},
+			},
+			{
+				Id:        "rtb-private",
+				SubnetIds: []string{"subnet-private"},
+				Routes: []Route{
+					{Destination: "10.0.0.0/16", Target: "local", State: ec2.RouteStateActive},
+					{Destination: defaultRouteDestination, Target: "nat-1", State: ec2.RouteStateActive},
+					{Destination: "10.1.0.0/16", Target: "tgw-1", State: ec2.RouteStateActive},
+				},
+			},
+			{
+				Id:        "rtb-broken",
+				SubnetIds: []string{"subnet-broken"},
+				Routes: []Route{
+					{Destination: defaultRouteDestination, Target: "nat-2", State: ec2.RouteStateBlackhole},
+				},
+			},
+		},
+		NatGateways:        []NatGateway{{Id: "nat-1", SubnetId: "subnet-public", State: ec2.NatGatewayStateAvailable}},
+		InternetGatewayIds: []string{"igw-1"},
+		VpcEndpoints:       []VpcEndpoint{{Id: "vpce-1", ServiceName: "com.amazonaws.us-east-1.s3", Type: "Gateway", State: "available"}},
+	}
+}
+
+func TestVpcTopologyRouteTableForSubnet(t *testing.T) {
+	t.Parallel()
+
+	topology := newTestVpcTopology()
+	assert.Equal(t, "rtb-private", topology.RouteTableForSubnet("subnet-private").Id)
+	// Subnets without an explicit association use the main route table.
+	assert.Equal(t, "rtb-public", topology.RouteTableForSubnet("subnet-other").Id)
+	assert.Nil(t, topology.RouteForSubnet("subnet-private", "192.168.0.0/16"))
+}
+
+func TestRequireSubnetRoutes(t *testing.T) {
+	t.Parallel()
+
+	topology := newTestVpcTopology()
+	assert.NoError(t, RequirePrivateSubnetRoutesThroughNatE(t, topology, "subnet-private"))
+	assert.Error(t, RequirePrivateSubnetRoutesThroughNatE(t, topology, "subnet-public"))
+	assert.Error(t, RequirePrivateSubnetRoutesThroughNatE(t, topology, "subnet-broken"))
+
+	assert.NoError(t, RequirePublicSubnetRoutesThroughIgwE(t, topology, "subnet-public"))
+	assert.Equal(t, NewSubnetRouteMismatchError("subnet-private", defaultRouteDestination, "an internet gateway attached to the VPC", "nat-1"), RequirePublicSubnetRoutesThroughIgwE(t, topology, "subnet-private"))
+
+	assert.NoError(t, RequireSubnetRoutesToE(t, topology, "subnet-private", "10.1.0.0/16", "tgw-1"))
+	assert.Error(t, RequireSubnetRoutesToE(t, topology, "subnet-public", "10.1.0.0/16", "tgw-1"))
+
+	assert.NoError(t, RequireVpcEndpointForServiceE(t, topology, "com.amazonaws.us-east-1.s3"))
+	assert.Error(t, RequireVpcEndpointForServiceE(t, topology, "com.amazonaws.us-east-1.dynamodb"))
+}
+
+func TestNewRouteTable(t *testing.T) {
+	t.Parallel()
+
+	routeTable := newRouteTable(&ec2.RouteTable{
+		RouteTableId: aws.String("rtb-1"),
+		Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}, {SubnetId: aws.String("subnet-1")}},
+		Routes: []*ec2.Route{
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1"), State: aws.String(ec2.RouteStateActive)},
+			{DestinationPrefixListId: aws.String("pl-1"), GatewayId: aws.String("vpce-1"), State: aws.String(ec2.RouteStateActive)},
+		},
+	})
+	require.Len(t, routeTable.Routes, 2)
+	assert.True(t, routeTable.Main)
+	assert.Equal(t, []string{"subnet-1"}, routeTable.SubnetIds)
+	assert.Equal(t, Route{Destination: "0.0.0.0/0", Target: "nat-1", State: ec2.RouteStateActive}, routeTable.Routes[0])
+	assert.Equal(t, Route{Destination: "pl-1", Target: "vpce-1", State: ec2.RouteStateActive}, routeTable.Routes[1])
+}
+
+func TestGetVpcTopologyForDefaultVpc(t *testing.T) {
+	t.Parallel()
+
+	region := GetRandomStableRegion(t, nil, nil)
+	vpc := GetDefaultVpc(t, region)
+	topology := GetVpcTopology(t, vpc.Id, region)
+
+	require.NotEmpty(t, topology.Subnets)
+	for _, subnet := range topology.Subnets {
+		RequirePublicSubnetRoutesThroughIgw(t, topology, subnet.Id)
+	}
+}
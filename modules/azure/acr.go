@@ -0,0 +1,761 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2019-05-01/containerregistry"
+	acrtasks "github.com/Azure/azure-sdk-for-go/services/preview/containerregistry/mgmt/2019-06-01-preview/containerregistry"
+	"github.com/Azure/azure-sdk-for-go/services/preview/security/mgmt/v3.0/security"
+	"github.com/Azure/go-autorest/autorest"
+	autorestAzure "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/ghodss/yaml"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// containerRegistryTokenUsername is the user name of the docker logins with an ACR refresh token as the password.
+const containerRegistryTokenUsername = "00000000-0000-0000-0000-000000000000"
+
+// containerRegistryVulnerabilityAssessmentNames are the keys of the Microsoft Defender for Cloud assessments whose
+// sub-assessments are the vulnerabilities found in container registry images, by Microsoft Defender Vulnerability
+// Management and by the former Qualys scanner.
+var containerRegistryVulnerabilityAssessmentNames = []string{
+	"c0b7cfc6-3172-465a-b378-53c7ff2cc0d5",
+	"dbd0cb49-b563-45e7-9724-889e799fa648",
+}
+
+// GetContainerRegistryLoginServer gets the login server of the container registry, e.g., myregistry.azurecr.io.
+// This function would fail the test if there is an error.
+func GetContainerRegistryLoginServer(t testing.TestingT, registryName string, resourceGroupName string, subscriptionID string) string {
+	loginServer, err := GetContainerRegistryLoginServerE(registryName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return loginServer
+}
+
+// GetContainerRegistryLoginServerE gets the login server of the container registry, e.g., myregistry.azurecr.io.
+func GetContainerRegistryLoginServerE(registryName string, resourceGroupName string, subscriptionID string) (string, error) {
+	registry, err := GetContainerRegistryE(registryName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+	if registry.RegistryProperties == nil || registry.LoginServer == nil {
+		return "", fmt.Errorf("container registry %s has no login server", registryName)
+	}
+	return *registry.LoginServer, nil
+}
+
+// GetContainerRegistryRefreshToken exchanges an Azure AD access token for a refresh token of the container registry
+// at the given login server. This function would fail the test if there is an error.
+func GetContainerRegistryRefreshToken(t testing.TestingT, loginServer string) string {
+	token, err := GetContainerRegistryRefreshTokenE(loginServer)
+	require.NoError(t, err)
+	return token
+}
+
+// GetContainerRegistryRefreshTokenE exchanges an Azure AD access token, from the credentials of NewAuthorizer, for a
+// refresh token of the container registry at the given login server, as `az acr login --expose-token` does. The
+// refresh token is the docker password of the user 00000000-0000-0000-0000-000000000000, so no admin user is needed.
+func GetContainerRegistryRefreshTokenE(loginServer string) (string, error) {
+	accessToken, err := getAccessTokenE(AuthOptions{}, "")
+	if err != nil {
+		return "", err
+	}
+	return exchangeContainerRegistryRefreshTokenE("https://"+loginServer, loginServer, accessToken)
+}
+
+// exchangeContainerRegistryRefreshTokenE exchanges the Azure AD access token for a refresh token of the container
+// registry at the given URL, which is issued for the given service, i.e., the login server.
+func exchangeContainerRegistryRefreshTokenE(registryURL string, service string, accessToken string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", service)
+	form.Set("access_token", accessToken)
+	resp, err := http.PostForm(strings.TrimSuffix(registryURL, "/")+"/oauth2/exchange", form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("container registry %s refused the token exchange with status %d: %s", service, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	if token.RefreshToken == "" {
+		return "", fmt.Errorf("container registry %s returned no refresh token", service)
+	}
+	return token.RefreshToken, nil
+}
+
+// DockerLoginToContainerRegistry logs in docker to the container registry at the given login server with a refresh
+// token, and returns the temporary Docker config directory that holds the login.
+// This function would fail the test if there is an error.
+func DockerLoginToContainerRegistry(t testing.TestingT, loginServer string) string {
+	configDir, err := DockerLoginToContainerRegistryE(t, loginServer)
+	require.NoError(t, err)
+	return configDir
+}
+
+// DockerLoginToContainerRegistryE logs in docker to the container registry at the given login server with a refresh
+// token (see GetContainerRegistryRefreshTokenE), and returns the temporary Docker config directory that holds the
+// login. The Docker config of the user is left untouched: pass the directory to docker with --config, and remove it
+// when done. The login lasts as long as the refresh token, i.e., about 3 hours.
+func DockerLoginToContainerRegistryE(t testing.TestingT, loginServer string) (string, error) {
+	refreshToken, err := GetContainerRegistryRefreshTokenE(loginServer)
+	if err != nil {
+		return "", err
+	}
+
+	configDir, err := ioutil.TempDir("", "terratest-acr-")
+	if err != nil {
+		return "", err
+	}
+	dockerConfig, err := newContainerRegistryDockerConfig(loginServer, refreshToken)
+	if err != nil {
+		os.RemoveAll(configDir)
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(configDir, "config.json"), dockerConfig, 0600); err != nil {
+		os.RemoveAll(configDir)
+		return "", err
+	}
+
+	logger.Logf(t, "Logged in to container registry %s in Docker config %s", loginServer, configDir)
+	return configDir, nil
+}
+
+// newContainerRegistryDockerConfig returns a Docker config file that logs in to the container registry at the given
+// login server with the given refresh token.
+func newContainerRegistryDockerConfig(loginServer string, refreshToken string) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(containerRegistryTokenUsername + ":" + refreshToken))
+	config := map[string]interface{}{
+		"auths": map[string]interface{}{
+			loginServer: map[string]string{"auth": auth},
+		},
+	}
+	return json.Marshal(config)
+}
+
+// PushImageToContainerRegistry tags the given local Docker image with the given tag in the given repository of the
+// container registry at the given login server and pushes it, and returns the URI of the pushed image.
+// This function would fail the test if there is an error.
+func PushImageToContainerRegistry(t testing.TestingT, loginServer string, localImage string, repository string, tag string) string {
+	imageURI, err := PushImageToContainerRegistryE(t, loginServer, localImage, repository, tag)
+	require.NoError(t, err)
+	return imageURI
+}
+
+// PushImageToContainerRegistryE tags the given local Docker image with the given tag in the given repository of the
+// container registry at the given login server and pushes it, and returns the URI of the pushed image. This requires
+// the docker CLI. The login is only used for the push (see DockerLoginToContainerRegistryE).
+func PushImageToContainerRegistryE(t testing.TestingT, loginServer string, localImage string, repository string, tag string) (string, error) {
+	configDir, err := DockerLoginToContainerRegistryE(t, loginServer)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(configDir)
+
+	imageURI := fmt.Sprintf("%s/%s:%s", loginServer, repository, tag)
+	logger.Logf(t, "Pushing image %s to %s", localImage, imageURI)
+	if err := shell.RunCommandE(t, shell.Command{Command: "docker", Args: []string{"tag", localImage, imageURI}}); err != nil {
+		return "", err
+	}
+	if err := shell.RunCommandE(t, shell.Command{Command: "docker", Args: []string{"--config", configDir, "push", imageURI}}); err != nil {
+		return "", err
+	}
+	return imageURI, nil
+}
+
+// PullImageFromContainerRegistry pulls the image with the given tag in the given repository of the container registry
+// at the given login server, and returns its URI. This function would fail the test if there is an error.
+func PullImageFromContainerRegistry(t testing.TestingT, loginServer string, repository string, tag string) string {
+	imageURI, err := PullImageFromContainerRegistryE(t, loginServer, repository, tag)
+	require.NoError(t, err)
+	return imageURI
+}
+
+// PullImageFromContainerRegistryE pulls the image with the given tag in the given repository of the container registry
+// at the given login server, and returns its URI. This requires the docker CLI. The login is only used for the pull
+// (see DockerLoginToContainerRegistryE).
+func PullImageFromContainerRegistryE(t testing.TestingT, loginServer string, repository string, tag string) (string, error) {
+	configDir, err := DockerLoginToContainerRegistryE(t, loginServer)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(configDir)
+
+	imageURI := fmt.Sprintf("%s/%s:%s", loginServer, repository, tag)
+	logger.Logf(t, "Pulling image %s", imageURI)
+	if err := shell.RunCommandE(t, shell.Command{Command: "docker", Args: []string{"--config", configDir, "pull", imageURI}}); err != nil {
+		return "", err
+	}
+	return imageURI, nil
+}
+
+// GetContainerRegistryImageDigest gets the digest of the local Docker image with the given URI in a container
+// registry, as pushed or pulled. This function would fail the test if there is an error.
+func GetContainerRegistryImageDigest(t testing.TestingT, imageURI string) string {
+	digest, err := GetContainerRegistryImageDigestE(t, imageURI)
+	require.NoError(t, err)
+	return digest
+}
+
+// GetContainerRegistryImageDigestE gets the digest of the local Docker image with the given URI in a container
+// registry, as pushed or pulled, e.g., sha256:2a3b.... Scan findings are reported by digest rather than by tag.
+func GetContainerRegistryImageDigestE(t testing.TestingT, imageURI string) (string, error) {
+	output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{
+		Command: "docker",
+		Args:    []string{"image", "inspect", "--format", "{{json .RepoDigests}}", imageURI},
+	})
+	if err != nil {
+		return "", err
+	}
+	var repoDigests []string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &repoDigests); err != nil {
+		return "", err
+	}
+	return findRepoDigest(imageURI, repoDigests)
+}
+
+// findRepoDigest returns the digest of the image with the given URI among the repo digests of a local Docker image,
+// which are of the form <registry>/<repository>@<digest>, one per registry it was pushed to or pulled from.
+func findRepoDigest(imageURI string, repoDigests []string) (string, error) {
+	repository := imageURI
+	if index := strings.LastIndex(repository, ":"); index > strings.LastIndex(repository, "/") {
+		repository = repository[:index]
+	}
+	for _, repoDigest := range repoDigests {
+		if strings.HasPrefix(repoDigest, repository+"@") {
+			return strings.TrimPrefix(repoDigest, repository+"@"), nil
+		}
+	}
+	return "", fmt.Errorf("image %s has no digest in repository %s, was it pushed?", imageURI, repository)
+}
+
+// AssertContainerRegistryRetentionPolicy checks that the retention policy of the container registry is enabled and
+// purges untagged manifests after the given number of days.
+// This function would fail the test if there is an error or it does not.
+func AssertContainerRegistryRetentionPolicy(t testing.TestingT, expectedDays int32, registryName string, resourceGroupName string, subscriptionID string) {
+	err := AssertContainerRegistryRetentionPolicyE(expectedDays, registryName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertContainerRegistryRetentionPolicyE checks that the retention policy of the container registry is enabled and
+// purges untagged manifests after the given number of days. Retention policies are only available in the Premium SKU.
+func AssertContainerRegistryRetentionPolicyE(expectedDays int32, registryName string, resourceGroupName string, subscriptionID string) error {
+	registry, err := GetContainerRegistryE(registryName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkContainerRegistryRetentionPolicy(registry, expectedDays)
+}
+
+// checkContainerRegistryRetentionPolicy returns an error if the retention policy of the container registry is not
+// enabled with the given number of days.
+func checkContainerRegistryRetentionPolicy(registry *containerregistry.Registry, expectedDays int32) error {
+	name := to.String(registry.Name)
+	if registry.RegistryProperties == nil || registry.Policies == nil || registry.Policies.RetentionPolicy == nil {
+		return fmt.Errorf("container registry %s has no retention policy", name)
+	}
+	policy := registry.Policies.RetentionPolicy
+	if policy.Status != containerregistry.Enabled {
+		return fmt.Errorf("retention policy of container registry %s is %s", name, policy.Status)
+	}
+	if to.Int32(policy.Days) != expectedDays {
+		return fmt.Errorf("retention policy of container registry %s retains untagged manifests for %d days, not %d", name, to.Int32(policy.Days), expectedDays)
+	}
+	return nil
+}
+
+// GetContainerRegistryTasksClientE is a helper function that will setup an ACR Tasks client on your behalf.
+func GetContainerRegistryTasksClientE(subscriptionID string) (*acrtasks.TasksClient, error) {
+	client, err := CreateContainerRegistryTasksClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return client, nil
+}
+
+// ContainerRegistryPurgeTask is an ACR task that runs `acr purge` on a schedule, to delete old images.
+type ContainerRegistryPurgeTask struct {
+	Enabled   bool     // Whether the task and at least one of its timer triggers are enabled
+	Schedules []string // The CRON expressions of the enabled timer triggers
+	Filters   []string // The --filter arguments of acr purge, i.e., <repository regex>:<tag regex>
+	Ago       string   // The --ago argument of acr purge, i.e., the age of the images to delete, e.g., 30d
+	Keep      int      // The --keep argument of acr purge, i.e., the number of latest images to keep
+	Untagged  bool     // Whether acr purge also deletes untagged manifests
+	DryRun    bool     // Whether acr purge only lists the images it would delete
+}
+
+// GetContainerRegistryPurgeTask gets the purge command and schedule of the ACR task.
+// This function would fail the test if there is an error.
+func GetContainerRegistryPurgeTask(t testing.TestingT, taskName string, registryName string, resourceGroupName string, subscriptionID string) *ContainerRegistryPurgeTask {
+	task, err := GetContainerRegistryPurgeTaskE(taskName, registryName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return task
+}
+
+// GetContainerRegistryPurgeTaskE gets the purge command and schedule of the ACR task, e.g., as created by
+// `az acr task create --cmd "acr purge ..." --schedule ...` or the cmd step of an encoded task.
+func GetContainerRegistryPurgeTaskE(taskName string, registryName string, resourceGroupName string, subscriptionID string) (*ContainerRegistryPurgeTask, error) {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetContainerRegistryTasksClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := client.Get(context.Background(), resourceGroupName, registryName, taskName)
+	if err != nil {
+		return nil, err
+	}
+	return parseContainerRegistryPurgeTask(&task)
+}
+
+// parseContainerRegistryPurgeTask returns the purge command and schedule of the ACR task, from the first acr purge
+// command of its encoded task content.
+func parseContainerRegistryPurgeTask(task *acrtasks.Task) (*ContainerRegistryPurgeTask, error) {
+	name := to.String(task.Name)
+	if task.TaskProperties == nil || task.Step == nil {
+		return nil, fmt.Errorf("ACR task %s has no step", name)
+	}
+	step, ok := task.Step.AsEncodedTaskStep()
+	if !ok {
+		return nil, fmt.Errorf("ACR task %s is not an encoded task", name)
+	}
+	content, err := base64.StdEncoding.DecodeString(to.String(step.EncodedTaskContent))
+	if err != nil {
+		return nil, err
+	}
+	var definition struct {
+		Steps []struct {
+			Cmd string `json:"cmd"`
+		} `json:"steps"`
+	}
+	if err := yaml.Unmarshal(content, &definition); err != nil {
+		return nil, err
+	}
+
+	for _, taskStep := range definition.Steps {
+		args := splitCommandLine(taskStep.Cmd)
+		if len(args) < 2 || args[0] != "acr" || args[1] != "purge" {
+			continue
+		}
+		purgeTask, err := parseAcrPurgeArgs(args[2:])
+		if err != nil {
+			return nil, fmt.Errorf("ACR task %s: %s", name, err)
+		}
+		if task.Trigger != nil && task.Trigger.TimerTriggers != nil {
+			for _, trigger := range *task.Trigger.TimerTriggers {
+				if trigger.Status == acrtasks.TriggerStatusEnabled {
+					purgeTask.Schedules = append(purgeTask.Schedules, to.String(trigger.Schedule))
+				}
+			}
+		}
+		purgeTask.Enabled = task.Status == acrtasks.TaskStatusEnabled && len(purgeTask.Schedules) > 0
+		return purgeTask, nil
+	}
+	return nil, fmt.Errorf("ACR task %s runs no acr purge command", name)
+}
+
+// parseAcrPurgeArgs returns the purge task for the given arguments of acr purge, in the --flag value or --flag=value
+// forms.
+func parseAcrPurgeArgs(args []string) (*ContainerRegistryPurgeTask, error) {
+	task := &ContainerRegistryPurgeTask{}
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := args[i], "", false
+		if index := strings.Index(flag, "="); index >= 0 {
+			flag, value, hasValue = flag[:index], flag[index+1:], true
+		}
+		switch flag {
+		case "--untagged":
+			task.Untagged = true
+			continue
+		case "--dry-run":
+			task.DryRun = true
+			continue
+		case "--filter", "--ago", "--keep":
+		default:
+			// Other flags, e.g., --timeout or --concurrency, do not change what is purged
+			if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				i++
+			}
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("acr purge flag %s has no value", flag)
+			}
+			i++
+			value = args[i]
+		}
+		switch flag {
+		case "--filter":
+			task.Filters = append(task.Filters, value)
+		case "--ago":
+			task.Ago = value
+		case "--keep":
+			keep, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid acr purge --keep %q", value)
+			}
+			task.Keep = keep
+		}
+	}
+	return task, nil
+}
+
+// splitCommandLine splits the command line into arguments at spaces, except in single or double quotes, which are
+// removed.
+func splitCommandLine(commandLine string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, char := range commandLine {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArg = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(char)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// AssertContainerRegistryPurgeTask checks that the ACR task is enabled on a schedule and purges the images matching
+// exactly the given filters that are older than the given age.
+// This function would fail the test if there is an error or it does not.
+func AssertContainerRegistryPurgeTask(t testing.TestingT, expectedFilters []string, expectedAgo string, taskName string, registryName string, resourceGroupName string, subscriptionID string) {
+	err := AssertContainerRegistryPurgeTaskE(expectedFilters, expectedAgo, taskName, registryName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertContainerRegistryPurgeTaskE checks that the ACR task is enabled on a schedule and purges the images matching
+// exactly the given filters, in any order, that are older than the given age, e.g., []string{"app:.*"} and "30d".
+// Tasks that only do a dry run do not purge anything, so they fail the check.
+func AssertContainerRegistryPurgeTaskE(expectedFilters []string, expectedAgo string, taskName string, registryName string, resourceGroupName string, subscriptionID string) error {
+	task, err := GetContainerRegistryPurgeTaskE(taskName, registryName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkContainerRegistryPurgeTask(taskName, task, expectedFilters, expectedAgo)
+}
+
+// checkContainerRegistryPurgeTask returns an error if the purge task is not enabled, only does a dry run, or does not
+// purge the images matching exactly the given filters that are older than the given age.
+func checkContainerRegistryPurgeTask(taskName string, task *ContainerRegistryPurgeTask, expectedFilters []string, expectedAgo string) error {
+	if !task.Enabled {
+		return fmt.Errorf("ACR task %s is not enabled on a schedule", taskName)
+	}
+	if task.DryRun {
+		return fmt.Errorf("ACR task %s only does a dry run of acr purge", taskName)
+	}
+	filters := append([]string{}, task.Filters...)
+	expected := append([]string{}, expectedFilters...)
+	sort.Strings(filters)
+	sort.Strings(expected)
+	if strings.Join(filters, "\n") != strings.Join(expected, "\n") {
+		return fmt.Errorf("ACR task %s purges filters %v, expected %v", taskName, task.Filters, expectedFilters)
+	}
+	if task.Ago != expectedAgo {
+		return fmt.Errorf("ACR task %s purges images older than %q, expected %q", taskName, task.Ago, expectedAgo)
+	}
+	return nil
+}
+
+// ContainerRegistryScanFinding is a vulnerability that Microsoft Defender for Cloud found in a container registry
+// image.
+type ContainerRegistryScanFinding struct {
+	ID          string   // The ID of the vulnerability in the scanner, e.g., the CVE
+	DisplayName string   // The title of the vulnerability
+	Severity    string   // The severity of the vulnerability: Critical, High, Medium or Low
+	CVEs        []string // The CVEs of the vulnerability
+}
+
+// ContainerRegistryVulnerabilityCounts is the number of vulnerabilities of each severity that Microsoft Defender for
+// Cloud found in a container registry image.
+type ContainerRegistryVulnerabilityCounts struct {
+	Critical int64
+	High     int64
+	Medium   int64
+	Low      int64
+}
+
+// Total returns the total number of vulnerabilities found.
+func (counts ContainerRegistryVulnerabilityCounts) Total() int64 {
+	return counts.Critical + counts.High + counts.Medium + counts.Low
+}
+
+// newContainerRegistryVulnerabilityCounts returns the number of the given findings of each severity.
+func newContainerRegistryVulnerabilityCounts(findings []ContainerRegistryScanFinding) ContainerRegistryVulnerabilityCounts {
+	counts := ContainerRegistryVulnerabilityCounts{}
+	for _, finding := range findings {
+		switch strings.ToLower(finding.Severity) {
+		case "critical":
+			counts.Critical++
+		case "high":
+			counts.High++
+		case "medium":
+			counts.Medium++
+		default:
+			counts.Low++
+		}
+	}
+	return counts
+}
+
+// containerRegistrySubAssessmentList is a page of the sub-assessments of a container registry vulnerability
+// assessment. The additional data of the sub-assessments of Microsoft Defender Vulnerability Management are not
+// modelled by the SDK, so they are decoded here along with those of the former Qualys scanner.
+type containerRegistrySubAssessmentList struct {
+	Value    []containerRegistrySubAssessment `json:"value"`
+	NextLink string                           `json:"nextLink"`
+}
+
+// containerRegistrySubAssessment is a vulnerability found in a container registry image.
+type containerRegistrySubAssessment struct {
+	Properties struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+		Status      struct {
+			Code     string `json:"code"`
+			Severity string `json:"severity"`
+		} `json:"status"`
+		AdditionalData struct {
+			// Qualys
+			RepositoryName string `json:"repositoryName"`
+			ImageDigest    string `json:"imageDigest"`
+			Cve            []struct {
+				Title string `json:"title"`
+			} `json:"cve"`
+			// Microsoft Defender Vulnerability Management
+			ArtifactDetails struct {
+				RepositoryName string `json:"repositoryName"`
+				Digest         string `json:"digest"`
+			} `json:"artifactDetails"`
+			VulnerabilityDetails struct {
+				CveID    string `json:"cveId"`
+				Severity string `json:"severity"`
+			} `json:"vulnerabilityDetails"`
+		} `json:"additionalData"`
+	} `json:"properties"`
+}
+
+// GetContainerRegistryScanFindings gets the vulnerabilities that Microsoft Defender for Cloud found in the image with
+// the given digest in the given repository of the container registry.
+// This function would fail the test if there is an error.
+func GetContainerRegistryScanFindings(t testing.TestingT, repository string, imageDigest string, registryName string, resourceGroupName string, subscriptionID string) []ContainerRegistryScanFinding {
+	findings, err := GetContainerRegistryScanFindingsE(repository, imageDigest, registryName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return findings
+}
+
+// GetContainerRegistryScanFindingsE gets the vulnerabilities that Microsoft Defender for Cloud found in the image with
+// the given digest in the given repository of the container registry. This requires the Defender for Containers plan
+// on the subscription. Images are scanned a few minutes after they are pushed, and images without vulnerabilities
+// have no findings.
+func GetContainerRegistryScanFindingsE(repository string, imageDigest string, registryName string, resourceGroupName string, subscriptionID string) ([]ContainerRegistryScanFinding, error) {
+	registry, err := GetContainerRegistryE(registryName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetSecuritySubAssessmentsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []ContainerRegistryScanFinding{}
+	for _, assessmentName := range containerRegistryVulnerabilityAssessmentNames {
+		subAssessments, err := listContainerRegistrySubAssessmentsE(client, to.String(registry.ID), assessmentName)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, filterContainerRegistryScanFindings(subAssessments, repository, imageDigest)...)
+	}
+	return findings, nil
+}
+
+// GetSecuritySubAssessmentsClientE is a helper function that will setup a Microsoft Defender for Cloud
+// sub-assessments client on your behalf.
+func GetSecuritySubAssessmentsClientE(subscriptionID string) (*security.SubAssessmentsClient, error) {
+	client, err := CreateSecuritySubAssessmentsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return client, nil
+}
+
+// listContainerRegistrySubAssessmentsE lists all the sub-assessments of the given assessment of the resource with the
+// given ID, following the next links. An assessment that does not apply to the resource has no sub-assessments.
+func listContainerRegistrySubAssessmentsE(client *security.SubAssessmentsClient, resourceID string, assessmentName string) ([]containerRegistrySubAssessment, error) {
+	ctx := context.Background()
+	req, err := client.ListPreparer(ctx, resourceID, assessmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	subAssessments := []containerRegistrySubAssessment{}
+	for {
+		resp, err := client.ListSender(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return subAssessments, nil
+		}
+		var page containerRegistrySubAssessmentList
+		err = autorest.Respond(resp, autorestAzure.WithErrorUnlessStatusCode(http.StatusOK), autorest.ByUnmarshallingJSON(&page), autorest.ByClosing())
+		if err != nil {
+			return nil, err
+		}
+		subAssessments = append(subAssessments, page.Value...)
+		if page.NextLink == "" {
+			return subAssessments, nil
+		}
+		req, err = autorest.Prepare((&http.Request{}).WithContext(ctx), autorest.AsJSON(), autorest.AsGet(), autorest.WithBaseURL(page.NextLink))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// filterContainerRegistryScanFindings returns the unhealthy sub-assessments of the image with the given digest in the
+// given repository as scan findings.
+func filterContainerRegistryScanFindings(subAssessments []containerRegistrySubAssessment, repository string, imageDigest string) []ContainerRegistryScanFinding {
+	findings := []ContainerRegistryScanFinding{}
+	for _, subAssessment := range subAssessments {
+		props := subAssessment.Properties
+		if !strings.EqualFold(props.Status.Code, string(security.SubAssessmentStatusCodeUnhealthy)) {
+			continue
+		}
+		data := props.AdditionalData
+		subAssessmentRepository, subAssessmentDigest := data.RepositoryName, data.ImageDigest
+		if subAssessmentRepository == "" {
+			subAssessmentRepository, subAssessmentDigest = data.ArtifactDetails.RepositoryName, data.ArtifactDetails.Digest
+		}
+		if subAssessmentRepository != repository || subAssessmentDigest != imageDigest {
+			continue
+		}
+
+		finding := ContainerRegistryScanFinding{ID: props.ID, DisplayName: props.DisplayName, Severity: props.Status.Severity, CVEs: []string{}}
+		if data.VulnerabilityDetails.Severity != "" {
+			finding.Severity = data.VulnerabilityDetails.Severity
+		}
+		for _, cve := range data.Cve {
+			finding.CVEs = append(finding.CVEs, cve.Title)
+		}
+		if data.VulnerabilityDetails.CveID != "" {
+			finding.CVEs = append(finding.CVEs, data.VulnerabilityDetails.CveID)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// WaitForContainerRegistryScanFindings waits until Microsoft Defender for Cloud reports vulnerabilities in the image
+// with the given digest in the given repository of the container registry, retrying the check for the specified amount
+// of times, sleeping for the provided duration between each try, and returns the number of vulnerabilities found.
+// This function would fail the test if there is an error or no vulnerabilities are reported in time.
+func WaitForContainerRegistryScanFindings(t testing.TestingT, repository string, imageDigest string, registryName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) ContainerRegistryVulnerabilityCounts {
+	counts, err := WaitForContainerRegistryScanFindingsE(t, repository, imageDigest, registryName, resourceGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return counts
+}
+
+// WaitForContainerRegistryScanFindingsE waits until Microsoft Defender for Cloud reports vulnerabilities in the image
+// with the given digest in the given repository of the container registry, retrying the check for the specified amount
+// of times, sleeping for the provided duration between each try, and returns the number of vulnerabilities found.
+// Images without vulnerabilities never get findings, so this is meant to check that the scan catches an image with
+// known vulnerabilities, e.g.:
+//
+//	imageURI := azure.PushImageToContainerRegistry(t, loginServer, "vulnerable:latest", "app", "v1")
+//	digest := azure.GetContainerRegistryImageDigest(t, imageURI)
+//	counts := azure.WaitForContainerRegistryScanFindings(t, "app", digest, registryName, resourceGroupName, "", 30, time.Minute)
+//	assert.NotZero(t, counts.Critical)
+func WaitForContainerRegistryScanFindingsE(t testing.TestingT, repository string, imageDigest string, registryName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) (ContainerRegistryVulnerabilityCounts, error) {
+	var counts ContainerRegistryVulnerabilityCounts
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for scan findings of image %s@%s.", repository, imageDigest),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			findings, err := GetContainerRegistryScanFindingsE(repository, imageDigest, registryName, resourceGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			if len(findings) == 0 {
+				return "", fmt.Errorf("no scan findings for image %s@%s yet", repository, imageDigest)
+			}
+			counts = newContainerRegistryVulnerabilityCounts(findings)
+			return fmt.Sprintf("Scan of image %s@%s found %d vulnerabilities", repository, imageDigest, counts.Total()), nil
+		},
+	)
+	logger.Logf(t, msg)
+	if err != nil {
+		return ContainerRegistryVulnerabilityCounts{}, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,243 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2019-05-01/containerregistry"
+	acrtasks "github.com/Azure/azure-sdk-for-go/services/preview/containerregistry/mgmt/2019-06-01-preview/containerregistry"
+	"github.com/Azure/azure-sdk-for-go/services/preview/security/mgmt/v3.0/security"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when methods can be mocked or Create/Delete APIs are added, these tests can be extended.
+*/
+
+func TestGetContainerRegistryLoginServerE(t *testing.T) {
+	t.Parallel()
+
+	registryName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := GetContainerRegistryLoginServerE(registryName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestGetContainerRegistryPurgeTaskE(t *testing.T) {
+	t.Parallel()
+
+	taskName := ""
+	registryName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := GetContainerRegistryPurgeTaskE(taskName, registryName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestGetContainerRegistryScanFindingsE(t *testing.T) {
+	t.Parallel()
+
+	registryName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := GetContainerRegistryScanFindingsE("app", "sha256:0", registryName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestExchangeContainerRegistryRefreshTokenE(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.URL.Path != "/oauth2/exchange" || r.PostForm.Get("grant_type") != "access_token" || r.PostForm.Get("access_token") != "aad-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"refresh_token": "refresh-for-%s"}`, r.PostForm.Get("service"))
+	}))
+	defer server.Close()
+
+	token, err := exchangeContainerRegistryRefreshTokenE(server.URL, "myregistry.azurecr.io", "aad-token")
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-for-myregistry.azurecr.io", token)
+
+	_, err = exchangeContainerRegistryRefreshTokenE(server.URL, "myregistry.azurecr.io", "expired-token")
+	assert.Error(t, err)
+}
+
+func TestNewContainerRegistryDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	dockerConfig, err := newContainerRegistryDockerConfig("myregistry.azurecr.io", "refresh-token")
+	require.NoError(t, err)
+
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	require.NoError(t, json.Unmarshal(dockerConfig, &config))
+	auth, err := base64.StdEncoding.DecodeString(config.Auths["myregistry.azurecr.io"].Auth)
+	require.NoError(t, err)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000000:refresh-token", string(auth))
+}
+
+func TestFindRepoDigest(t *testing.T) {
+	t.Parallel()
+
+	repoDigests := []string{"docker.io/library/app@sha256:aaa", "myregistry.azurecr.io/team/app@sha256:bbb"}
+
+	digest, err := findRepoDigest("myregistry.azurecr.io/team/app:v1", repoDigests)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:bbb", digest)
+
+	_, err = findRepoDigest("otherregistry.azurecr.io:443/team/app", repoDigests)
+	assert.Error(t, err)
+}
+
+func TestCheckContainerRegistryRetentionPolicy(t *testing.T) {
+	t.Parallel()
+
+	registry := &containerregistry.Registry{
+		Name: to.StringPtr("myregistry"),
+		RegistryProperties: &containerregistry.RegistryProperties{Policies: &containerregistry.Policies{
+			RetentionPolicy: &containerregistry.RetentionPolicy{Days: to.Int32Ptr(7), Status: containerregistry.Enabled},
+		}},
+	}
+	assert.NoError(t, checkContainerRegistryRetentionPolicy(registry, 7))
+	assert.Error(t, checkContainerRegistryRetentionPolicy(registry, 30))
+
+	registry.Policies.RetentionPolicy.Status = containerregistry.Disabled
+	assert.Error(t, checkContainerRegistryRetentionPolicy(registry, 7))
+
+	assert.Error(t, checkContainerRegistryRetentionPolicy(&containerregistry.Registry{}, 7))
+}
+
+func TestParseContainerRegistryPurgeTask(t *testing.T) {
+	t.Parallel()
+
+	content := "version: v1.1.0\nsteps:\n  - cmd: acr purge --filter 'app:.*' --filter \"web:^pr-\" --ago 30d --untagged --keep=3 --timeout 3600\n    disableWorkingDirectoryOverride: true\n    timeout: 3600\n"
+	task := &acrtasks.Task{
+		Name: to.StringPtr("purge"),
+		TaskProperties: &acrtasks.TaskProperties{
+			Status: acrtasks.TaskStatusEnabled,
+			Step:   acrtasks.EncodedTaskStep{EncodedTaskContent: to.StringPtr(base64.StdEncoding.EncodeToString([]byte(content)))},
+			Trigger: &acrtasks.TriggerProperties{TimerTriggers: &[]acrtasks.TimerTrigger{
+				{Name: to.StringPtr("daily"), Schedule: to.StringPtr("0 1 * * *"), Status: acrtasks.TriggerStatusEnabled},
+				{Name: to.StringPtr("weekly"), Schedule: to.StringPtr("0 2 * * Sun"), Status: acrtasks.TriggerStatusDisabled},
+			}},
+		},
+	}
+
+	purgeTask, err := parseContainerRegistryPurgeTask(task)
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerRegistryPurgeTask{
+		Enabled:   true,
+		Schedules: []string{"0 1 * * *"},
+		Filters:   []string{"app:.*", "web:^pr-"},
+		Ago:       "30d",
+		Keep:      3,
+		Untagged:  true,
+	}, purgeTask)
+
+	assert.NoError(t, checkContainerRegistryPurgeTask("purge", purgeTask, []string{"web:^pr-", "app:.*"}, "30d"))
+	assert.Error(t, checkContainerRegistryPurgeTask("purge", purgeTask, []string{"app:.*"}, "30d"))
+	assert.Error(t, checkContainerRegistryPurgeTask("purge", purgeTask, []string{"app:.*", "web:^pr-"}, "7d"))
+
+	purgeTask.DryRun = true
+	assert.Error(t, checkContainerRegistryPurgeTask("purge", purgeTask, []string{"app:.*", "web:^pr-"}, "30d"))
+
+	task.Step = acrtasks.EncodedTaskStep{EncodedTaskContent: to.StringPtr(base64.StdEncoding.EncodeToString([]byte("steps:\n  - build: -t app .\n")))}
+	_, err = parseContainerRegistryPurgeTask(task)
+	assert.Error(t, err)
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"acr", "purge", "--filter", "app:.*", "--ago", "1d"}, splitCommandLine("acr purge  --filter 'app:.*' --ago 1d"))
+	assert.Equal(t, []string{"--filter", "my app:v 1"}, splitCommandLine(`--filter "my app:v 1"`))
+	assert.Equal(t, []string{}, splitCommandLine(""))
+}
+
+// testContainerRegistrySubAssessments are sub-assessments of the Microsoft Defender Vulnerability Management and Qualys
+// schemas, as returned by the Microsoft Defender for Cloud API.
+const testContainerRegistrySubAssessments = `{"value": [
+	{"properties": {"id": "CVE-2023-0001", "displayName": "openssl: buffer overflow", "status": {"code": "Unhealthy", "severity": "High"},
+		"additionalData": {"assessedResourceType": "AzureContainerRegistryVulnerability", "vulnerabilityDetails": {"cveId": "CVE-2023-0001", "severity": "Critical"},
+			"artifactDetails": {"repositoryName": "app", "digest": "sha256:aaa"}}}},
+	{"properties": {"id": "CVE-2023-0002", "displayName": "zlib: heap overflow", "status": {"code": "Unhealthy", "severity": "Medium"},
+		"additionalData": {"assessedResourceType": "AzureContainerRegistryVulnerability", "vulnerabilityDetails": {"cveId": "CVE-2023-0002", "severity": "Medium"},
+			"artifactDetails": {"repositoryName": "other", "digest": "sha256:bbb"}}}},
+	{"properties": {"id": "176249", "displayName": "Debian Security Update for curl", "status": {"code": "Unhealthy", "severity": "High"},
+		"additionalData": {"assessedResourceType": "ContainerRegistryVulnerability", "repositoryName": "app", "imageDigest": "sha256:aaa",
+			"cve": [{"title": "CVE-2023-0003"}, {"title": "CVE-2023-0004"}]}}},
+	{"properties": {"id": "176250", "displayName": "Fixed", "status": {"code": "Healthy", "severity": "Low"},
+		"additionalData": {"repositoryName": "app", "imageDigest": "sha256:aaa"}}}
+]}`
+
+func TestFilterContainerRegistryScanFindings(t *testing.T) {
+	t.Parallel()
+
+	var page containerRegistrySubAssessmentList
+	require.NoError(t, json.Unmarshal([]byte(testContainerRegistrySubAssessments), &page))
+
+	findings := filterContainerRegistryScanFindings(page.Value, "app", "sha256:aaa")
+	assert.Equal(t, []ContainerRegistryScanFinding{
+		{ID: "CVE-2023-0001", DisplayName: "openssl: buffer overflow", Severity: "Critical", CVEs: []string{"CVE-2023-0001"}},
+		{ID: "176249", DisplayName: "Debian Security Update for curl", Severity: "High", CVEs: []string{"CVE-2023-0003", "CVE-2023-0004"}},
+	}, findings)
+
+	counts := newContainerRegistryVulnerabilityCounts(findings)
+	assert.Equal(t, ContainerRegistryVulnerabilityCounts{Critical: 1, High: 1}, counts)
+	assert.Equal(t, int64(2), counts.Total())
+}
+
+func TestListContainerRegistrySubAssessmentsE(t *testing.T) {
+	t.Parallel()
+
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/registry/providers/Microsoft.Security/assessments/page/subAssessments":
+			fmt.Fprintf(w, `{"value": [{"properties": {"id": "1"}}], "nextLink": "%s/next?page=2"}`, serverURL)
+		case "/next":
+			fmt.Fprint(w, `{"value": [{"properties": {"id": "2"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": "NotFound"}}`)
+		}
+	}))
+	defer server.Close()
+	serverURL = server.URL
+
+	client := security.NewSubAssessmentsClientWithBaseURI(server.URL, "subscription", "")
+
+	subAssessments, err := listContainerRegistrySubAssessmentsE(&client, "registry", "page")
+	require.NoError(t, err)
+	require.Len(t, subAssessments, 2)
+	assert.Equal(t, "1", subAssessments[0].Properties.ID)
+	assert.Equal(t, "2", subAssessments[1].Properties.ID)
+
+	subAssessments, err = listContainerRegistrySubAssessmentsE(&client, "registry", "missing")
+	require.NoError(t, err)
+	assert.Empty(t, subAssessments)
+}
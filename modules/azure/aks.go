@@ -2,9 +2,14 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-11-01/containerservice"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
 )
 
 // GetManagedClustersClientE is a helper function that will setup an Azure ManagedClusters client on your behalf
@@ -41,3 +46,142 @@ func GetManagedClusterE(t testing.TestingT, resourceGroupName, clusterName, subs
 	}
 	return &managedCluster, nil
 }
+
+// GetAksCluster returns the AKS cluster with the given name in the given resource group.
+// This function would fail the test if there is an error.
+func GetAksCluster(t testing.TestingT, resourceGroupName, clusterName, subscriptionID string) *containerservice.ManagedCluster {
+	cluster, err := GetAksClusterE(t, resourceGroupName, clusterName, subscriptionID)
+	require.NoError(t, err)
+	return cluster
+}
+
+// GetAksClusterE returns the AKS cluster with the given name in the given resource group.
+func GetAksClusterE(t testing.TestingT, resourceGroupName, clusterName, subscriptionID string) (*containerservice.ManagedCluster, error) {
+	return GetManagedClusterE(t, resourceGroupName, clusterName, subscriptionID)
+}
+
+// WaitUntilAksClusterProvisioned waits until the given AKS cluster has been provisioned, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the provisioning fails.
+func WaitUntilAksClusterProvisioned(t testing.TestingT, resourceGroupName, clusterName, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilAksClusterProvisionedE(t, resourceGroupName, clusterName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilAksClusterProvisionedE waits until the given AKS cluster has been provisioned (i.e., its provisioning state
+// is Succeeded), retrying the check for the specified amount of times, sleeping for the provided duration between each
+// try. This is useful after an upgrade or a scale operation that terraform doesn't wait for.
+func WaitUntilAksClusterProvisionedE(t testing.TestingT, resourceGroupName, clusterName, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for AKS cluster %s to be provisioned.", clusterName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			cluster, err := GetAksClusterE(t, resourceGroupName, clusterName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			var state *string
+			if cluster.ManagedClusterProperties != nil {
+				state = cluster.ManagedClusterProperties.ProvisioningState
+			}
+			if err := checkAksProvisioningState("AKS cluster "+clusterName, state); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("AKS cluster %s is provisioned", clusterName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// GetAgentPoolsClientE is a helper function that will setup an Azure AgentPools client on your behalf, which manages
+// the node pools of AKS clusters.
+func GetAgentPoolsClientE(subscriptionID string) (*containerservice.AgentPoolsClient, error) {
+	client, err := CreateAgentPoolsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return &client, nil
+}
+
+// GetAksNodePool returns the node pool with the given name of the given AKS cluster.
+// This function would fail the test if there is an error.
+func GetAksNodePool(t testing.TestingT, resourceGroupName, clusterName, nodePoolName, subscriptionID string) *containerservice.AgentPool {
+	nodePool, err := GetAksNodePoolE(t, resourceGroupName, clusterName, nodePoolName, subscriptionID)
+	require.NoError(t, err)
+	return nodePool
+}
+
+// GetAksNodePoolE returns the node pool with the given name of the given AKS cluster.
+func GetAksNodePoolE(t testing.TestingT, resourceGroupName, clusterName, nodePoolName, subscriptionID string) (*containerservice.AgentPool, error) {
+	client, err := GetAgentPoolsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	nodePool, err := client.Get(context.Background(), resourceGroupName, clusterName, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+	return &nodePool, nil
+}
+
+// WaitUntilAksNodePoolProvisioned waits until the given node pool of the given AKS cluster has been provisioned,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the provisioning fails.
+func WaitUntilAksNodePoolProvisioned(t testing.TestingT, resourceGroupName, clusterName, nodePoolName, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilAksNodePoolProvisionedE(t, resourceGroupName, clusterName, nodePoolName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilAksNodePoolProvisionedE waits until the given node pool of the given AKS cluster has been provisioned (i.e.,
+// its provisioning state is Succeeded), retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. Node pools are provisioned again when they are scaled or upgraded.
+func WaitUntilAksNodePoolProvisionedE(t testing.TestingT, resourceGroupName, clusterName, nodePoolName, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for node pool %s of AKS cluster %s to be provisioned.", nodePoolName, clusterName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			nodePool, err := GetAksNodePoolE(t, resourceGroupName, clusterName, nodePoolName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			var state *string
+			if nodePool.ManagedClusterAgentPoolProfileProperties != nil {
+				state = nodePool.ManagedClusterAgentPoolProfileProperties.ProvisioningState
+			}
+			if err := checkAksProvisioningState(fmt.Sprintf("Node pool %s of AKS cluster %s", nodePoolName, clusterName), state); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Node pool %s of AKS cluster %s is provisioned", nodePoolName, clusterName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkAksProvisioningState returns an error if the given provisioning state of an AKS resource is not Succeeded,
+// which is a retry.FatalError if the provisioning has failed or was canceled.
+func checkAksProvisioningState(resource string, state *string) error {
+	if state == nil {
+		return fmt.Errorf("%s has no provisioning state yet", resource)
+	}
+	switch *state {
+	case "Succeeded":
+		return nil
+	case "Failed", "Canceled":
+		return retry.FatalError{Underlying: fmt.Errorf("%s provisioning state is %s", resource, *state)}
+	default:
+		return fmt.Errorf("%s provisioning state is %s", resource, *state)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAksCluster(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetAksClusterE(t, "fakeResourceGroupName", "fakeClusterName", "")
+	require.Error(t, err)
+}
+
+func TestGetAksNodePool(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetAksNodePoolE(t, "fakeResourceGroupName", "fakeClusterName", "fakeNodePoolName", "")
+	require.Error(t, err)
+}
+
+func TestCheckAksProvisioningState(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkAksProvisioningState("AKS cluster test", to.StringPtr("Succeeded")))
+	assert.Error(t, checkAksProvisioningState("AKS cluster test", nil))
+
+	err := checkAksProvisioningState("AKS cluster test", to.StringPtr("Updating"))
+	require.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+
+	err = checkAksProvisioningState("AKS cluster test", to.StringPtr("Failed"))
+	require.Error(t, err)
+	assert.IsType(t, retry.FatalError{}, err)
+}
@@ -2,9 +2,17 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2019-08-01/web"
+	http_helper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,3 +83,291 @@ func GetAppServiceClientE(subscriptionID string) (*web.AppsClient, error) {
 	appsClient.Authorizer = *authorizer
 	return appsClient, nil
 }
+
+// GetAppServiceSlot gets the deployment slot with the given name of the specified application.
+// This function would fail the test if there is an error.
+func GetAppServiceSlot(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string) *web.Site {
+	slot, err := GetAppServiceSlotE(appName, slotName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+
+	return slot
+}
+
+// GetAppServiceSlotE gets the deployment slot with the given name of the specified application. An empty slot name
+// refers to the production slot, i.e., the application itself.
+func GetAppServiceSlotE(appName string, slotName string, resGroupName string, subscriptionID string) (*web.Site, error) {
+	if slotName == "" {
+		return GetAppServiceE(appName, resGroupName, subscriptionID)
+	}
+
+	rgName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetAppServiceClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := client.GetSlot(context.Background(), rgName, appName, slotName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resource, nil
+}
+
+// WaitUntilAppServiceRunning waits until the specified application (or one of its deployment slots) is in the Running
+// state, retrying the check for the specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the application is not running in time.
+func WaitUntilAppServiceRunning(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilAppServiceRunningE(t, appName, slotName, resGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilAppServiceRunningE waits until the specified application (or one of its deployment slots, if the slot name
+// is not empty) is in the Running state, retrying the check for the specified amount of times, sleeping for the
+// provided duration between each try. This works for both web apps and Function Apps.
+func WaitUntilAppServiceRunningE(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	description := fmt.Sprintf("Waiting for app %s to be running.", appServiceDisplayName(appName, slotName))
+	msg, err := retry.DoWithRetryE(t, description, maxRetries, sleepBetweenRetries, func() (string, error) {
+		site, err := GetAppServiceSlotE(appName, slotName, resGroupName, subscriptionID)
+		if err != nil {
+			return "", err
+		}
+		if err := checkAppServiceRunning(site, appServiceDisplayName(appName, slotName)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("App %s is running", appServiceDisplayName(appName, slotName)), nil
+	})
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkAppServiceRunning returns an error if the given application is not in the Running state.
+func checkAppServiceRunning(site *web.Site, displayName string) error {
+	if site.SiteProperties == nil || site.SiteProperties.State == nil {
+		return fmt.Errorf("App %s has no state yet", displayName)
+	}
+	if *site.SiteProperties.State != "Running" {
+		return fmt.Errorf("App %s is in state %s rather than Running", displayName, *site.SiteProperties.State)
+	}
+	return nil
+}
+
+// GetAppServiceAppSettings gets the application settings of the specified application (or one of its deployment slots).
+// This function would fail the test if there is an error.
+func GetAppServiceAppSettings(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string) map[string]string {
+	settings, err := GetAppServiceAppSettingsE(appName, slotName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+
+	return settings
+}
+
+// GetAppServiceAppSettingsE gets the application settings of the specified application (or one of its deployment slots,
+// if the slot name is not empty). Settings that reference a key vault secret are returned as the reference.
+func GetAppServiceAppSettingsE(appName string, slotName string, resGroupName string, subscriptionID string) (map[string]string, error) {
+	rgName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetAppServiceClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result web.StringDictionary
+	if slotName == "" {
+		result, err = client.ListApplicationSettings(context.Background(), rgName, appName)
+	} else {
+		result, err = client.ListApplicationSettingsSlot(context.Background(), rgName, appName, slotName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{}
+	for name, value := range result.Properties {
+		if value != nil {
+			settings[name] = *value
+		}
+	}
+	return settings, nil
+}
+
+// AssertAppServiceAppSettings checks that the specified application (or one of its deployment slots) has the expected
+// application settings. This function would fail the test if it does not.
+func AssertAppServiceAppSettings(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, expectedSettings map[string]string) {
+	err := AssertAppServiceAppSettingsE(appName, slotName, resGroupName, subscriptionID, expectedSettings)
+	require.NoError(t, err)
+}
+
+// AssertAppServiceAppSettingsE checks that the specified application (or one of its deployment slots, if the slot name
+// is not empty) has the expected application settings. Other settings, such as those the platform adds, are ignored.
+func AssertAppServiceAppSettingsE(appName string, slotName string, resGroupName string, subscriptionID string, expectedSettings map[string]string) error {
+	settings, err := GetAppServiceAppSettingsE(appName, slotName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkAppServiceAppSettings(settings, expectedSettings, appServiceDisplayName(appName, slotName))
+}
+
+// checkAppServiceAppSettings returns an error listing the expected settings that are missing or have another value.
+func checkAppServiceAppSettings(settings map[string]string, expectedSettings map[string]string, displayName string) error {
+	mismatches := []string{}
+	for name, expectedValue := range expectedSettings {
+		value, exists := settings[name]
+		if !exists {
+			mismatches = append(mismatches, fmt.Sprintf("%s is not set", name))
+		} else if value != expectedValue {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %q rather than %q", name, value, expectedValue))
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("App %s has unexpected app settings: %s", displayName, strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// GetAppServiceSlotNames gets the names of the deployment slots of the specified application, besides production.
+// This function would fail the test if there is an error.
+func GetAppServiceSlotNames(t *testing.T, appName string, resGroupName string, subscriptionID string) []string {
+	slotNames, err := GetAppServiceSlotNamesE(appName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+
+	return slotNames
+}
+
+// GetAppServiceSlotNamesE gets the names of the deployment slots of the specified application, besides production.
+func GetAppServiceSlotNamesE(appName string, resGroupName string, subscriptionID string) ([]string, error) {
+	rgName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetAppServiceClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := client.ListSlotsComplete(context.Background(), rgName, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	slotNames := []string{}
+	for ; iterator.NotDone(); err = iterator.NextWithContext(context.Background()) {
+		if err != nil {
+			return nil, err
+		}
+		if name := iterator.Value().Name; name != nil {
+			// Slots are named <app>/<slot>.
+			slotNames = append(slotNames, (*name)[strings.LastIndex(*name, "/")+1:])
+		}
+	}
+	return slotNames, err
+}
+
+// AppServiceSlotExists indicates whether the specified application has a deployment slot with the given name.
+// This function would fail the test if there is an error.
+func AppServiceSlotExists(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string) bool {
+	exists, err := AppServiceSlotExistsE(appName, slotName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+
+	return exists
+}
+
+// AppServiceSlotExistsE indicates whether the specified application has a deployment slot with the given name.
+func AppServiceSlotExistsE(appName string, slotName string, resGroupName string, subscriptionID string) (bool, error) {
+	_, err := GetAppServiceSlotE(appName, slotName, resGroupName, subscriptionID)
+	if err != nil {
+		if ResourceNotFoundErrorExists(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetAppServiceURL gets the default URL of the specified application (or one of its deployment slots).
+// This function would fail the test if there is an error.
+func GetAppServiceURL(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string) string {
+	url, err := GetAppServiceURLE(appName, slotName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+
+	return url
+}
+
+// GetAppServiceURLE gets the default URL of the specified application (or one of its deployment slots, if the slot name
+// is not empty), e.g., https://myapp.azurewebsites.net or https://myapp-staging.azurewebsites.net.
+func GetAppServiceURLE(appName string, slotName string, resGroupName string, subscriptionID string) (string, error) {
+	site, err := GetAppServiceSlotE(appName, slotName, resGroupName, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+	if site.SiteProperties == nil || site.SiteProperties.DefaultHostName == nil {
+		return "", fmt.Errorf("App %s has no default host name", appServiceDisplayName(appName, slotName))
+	}
+	return "https://" + *site.SiteProperties.DefaultHostName, nil
+}
+
+// HttpGetAppServiceWithRetry makes HTTP GET requests to the given path of the specified application (or one of its
+// deployment slots) until it responds with the expected status and a body that contains the expected text.
+// This function would fail the test if there is an error or the application doesn't respond as expected in time.
+func HttpGetAppServiceWithRetry(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, path string, expectedStatus int, expectedBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := HttpGetAppServiceWithRetryE(t, appName, slotName, resGroupName, subscriptionID, path, expectedStatus, expectedBodySubstring, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// HttpGetAppServiceWithRetryE makes HTTP GET requests to the given path of the specified application (or one of its
+// deployment slots, if the slot name is not empty) until it responds with the expected status and a body that contains
+// the expected text, retrying for the specified amount of times, sleeping for the provided duration between each try.
+// This rides out the errors applications return while they start or warm up after a deployment, a restart or a scale
+// out. For Function Apps, the path includes the route prefix, e.g., /api/my-function.
+func HttpGetAppServiceWithRetryE(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, path string, expectedStatus int, expectedBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	url, err := GetAppServiceURLE(appName, slotName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return http_helper.HttpGetWithRetryWithCustomValidationE(t, url+path, nil, maxRetries, sleepBetweenRetries, func(status int, body string) bool {
+		return status == expectedStatus && strings.Contains(body, expectedBodySubstring)
+	})
+}
+
+// AssertAppServiceSlotSwap checks that the deployment slots of the specified application have been swapped, i.e., that
+// production serves what was deployed to the given slot and vice versa.
+// This function would fail the test if there is an error or the slots don't serve the expected content in time.
+func AssertAppServiceSlotSwap(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, path string, expectedProductionBodySubstring string, expectedSlotBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := AssertAppServiceSlotSwapE(t, appName, slotName, resGroupName, subscriptionID, path, expectedProductionBodySubstring, expectedSlotBodySubstring, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// AssertAppServiceSlotSwapE checks that the deployment slots of the specified application have been swapped, by
+// waiting until the given path of production responds with a body that contains the expected production text (e.g., the
+// version that was deployed to the slot) and the same path of the given slot with the expected slot text (e.g., the
+// version that was in production), retrying for the specified amount of times, sleeping for the provided duration
+// between each try. Both must respond with status 200. For example:
+//
+//	azure.HttpGetAppServiceWithRetry(t, appName, "staging", resGroupName, "", "/version", 200, "v2", 30, 10*time.Second)
+//	// swap the staging slot into production, e.g., with terraform or the Azure CLI
+//	azure.AssertAppServiceSlotSwap(t, appName, "staging", resGroupName, "", "/version", "v2", "v1", 30, 10*time.Second)
+func AssertAppServiceSlotSwapE(t *testing.T, appName string, slotName string, resGroupName string, subscriptionID string, path string, expectedProductionBodySubstring string, expectedSlotBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	if err := HttpGetAppServiceWithRetryE(t, appName, "", resGroupName, subscriptionID, path, http.StatusOK, expectedProductionBodySubstring, maxRetries, sleepBetweenRetries); err != nil {
+		return fmt.Errorf("Production of app %s does not serve the swapped in content: %v", appName, err)
+	}
+	if err := HttpGetAppServiceWithRetryE(t, appName, slotName, resGroupName, subscriptionID, path, http.StatusOK, expectedSlotBodySubstring, maxRetries, sleepBetweenRetries); err != nil {
+		return fmt.Errorf("Slot %s of app %s does not serve the swapped out content: %v", slotName, appName, err)
+	}
+	return nil
+}
+
+// appServiceDisplayName returns the name of the given application slot to show in messages.
+func appServiceDisplayName(appName string, slotName string) string {
+	if slotName == "" {
+		return appName
+	}
+	return appName + "/" + slotName
+}
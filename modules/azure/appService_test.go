@@ -9,6 +9,8 @@ package azure
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2019-08-01/web"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,3 +49,38 @@ func TestGetAppServiceClientE(t *testing.T) {
 	_, err := GetAppServiceClientE(subscriptionID)
 	require.NoError(t, err)
 }
+
+func TestGetAppServiceSlotE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetAppServiceSlotE("", "staging", "", "")
+	require.Error(t, err)
+}
+
+func TestGetAppServiceAppSettingsE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetAppServiceAppSettingsE("", "", "", "")
+	require.Error(t, err)
+}
+
+func TestCheckAppServiceRunning(t *testing.T) {
+	t.Parallel()
+
+	running := "Running"
+	stopped := "Stopped"
+	assert.NoError(t, checkAppServiceRunning(&web.Site{SiteProperties: &web.SiteProperties{State: &running}}, "app"))
+	assert.Error(t, checkAppServiceRunning(&web.Site{SiteProperties: &web.SiteProperties{State: &stopped}}, "app"))
+	assert.Error(t, checkAppServiceRunning(&web.Site{}, "app"))
+}
+
+func TestCheckAppServiceAppSettings(t *testing.T) {
+	t.Parallel()
+
+	settings := map[string]string{"FUNCTIONS_WORKER_RUNTIME": "node", "WEBSITE_RUN_FROM_PACKAGE": "1"}
+	assert.NoError(t, checkAppServiceAppSettings(settings, map[string]string{"FUNCTIONS_WORKER_RUNTIME": "node"}, "app"))
+
+	err := checkAppServiceAppSettings(settings, map[string]string{"FUNCTIONS_WORKER_RUNTIME": "python", "API_URL": "https://example.com"}, "app/staging")
+	require.Error(t, err)
+	assert.Equal(t, `App app/staging has unexpected app settings: API_URL is not set, FUNCTIONS_WORKER_RUNTIME is "node" rather than "python"`, err.Error())
+}
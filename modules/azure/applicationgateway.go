@@ -0,0 +1,449 @@
+package azure
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// applicationGatewayMaliciousQuery is a query string with SQL injection and cross-site scripting payloads that the OWASP
+// core rule set of the web application firewall blocks.
+const applicationGatewayMaliciousQuery = "?id=1%27%20OR%20%271%27%3D%271&q=%3Cscript%3Ealert%28document.cookie%29%3C%2Fscript%3E"
+
+// GetApplicationGatewayClientE gets a new Application Gateway client in the specified Azure Subscription.
+func GetApplicationGatewayClientE(subscriptionID string) (*network.ApplicationGatewaysClient, error) {
+	// Get the Application Gateway client
+	client, err := CreateApplicationGatewaysClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an authorizer
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetApplicationGateway gets an Application Gateway network resource in the specified Azure Resource Group.
+// This function would fail the test if there is an error.
+func GetApplicationGateway(t testing.TestingT, gatewayName string, resourceGroupName string, subscriptionID string) *network.ApplicationGateway {
+	gateway, err := GetApplicationGatewayE(gatewayName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return gateway
+}
+
+// GetApplicationGatewayE gets an Application Gateway network resource in the specified Azure Resource Group.
+func GetApplicationGatewayE(gatewayName string, resourceGroupName string, subscriptionID string) (*network.ApplicationGateway, error) {
+	// Validate Azure Resource Group Name
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the client reference
+	client, err := GetApplicationGatewayClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the Application Gateway
+	gateway, err := client.Get(context.Background(), resourceGroupName, gatewayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gateway, nil
+}
+
+// GetApplicationGatewayBackendHealth gets the health of the servers in the backend pools of the Application Gateway.
+// This function would fail the test if there is an error.
+func GetApplicationGatewayBackendHealth(t testing.TestingT, gatewayName string, resourceGroupName string, subscriptionID string) *network.ApplicationGatewayBackendHealth {
+	health, err := GetApplicationGatewayBackendHealthE(gatewayName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return health
+}
+
+// GetApplicationGatewayBackendHealthE gets the health of the servers in the backend pools of the Application Gateway,
+// as seen by its health probes. This is a long running operation that can take a minute.
+func GetApplicationGatewayBackendHealthE(gatewayName string, resourceGroupName string, subscriptionID string) (*network.ApplicationGatewayBackendHealth, error) {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetApplicationGatewayClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	future, err := client.BackendHealth(context.Background(), resourceGroupName, gatewayName, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := future.WaitForCompletionRef(context.Background(), client.Client); err != nil {
+		return nil, err
+	}
+	health, err := future.Result(*client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
+// WaitUntilApplicationGatewayBackendsHealthy waits until all the servers in the backend pools of the Application
+// Gateway are healthy, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. This function would fail the test if there is an error or the backends are not healthy in time.
+func WaitUntilApplicationGatewayBackendsHealthy(t testing.TestingT, gatewayName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilApplicationGatewayBackendsHealthyE(t, gatewayName, resourceGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilApplicationGatewayBackendsHealthyE waits until all the servers in the backend pools of the Application
+// Gateway are healthy (i.e., up), retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. Backends are unknown until the first probes run after they are added.
+func WaitUntilApplicationGatewayBackendsHealthyE(t testing.TestingT, gatewayName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for backends of Application Gateway %s to be healthy.", gatewayName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			health, err := GetApplicationGatewayBackendHealthE(gatewayName, resourceGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			if err := checkApplicationGatewayBackendHealth(health, gatewayName); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Backends of Application Gateway %s are healthy", gatewayName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkApplicationGatewayBackendHealth returns an error if the given backend health has no servers or a server that is
+// not up, with the logs of the health probes of these servers.
+func checkApplicationGatewayBackendHealth(health *network.ApplicationGatewayBackendHealth, gatewayName string) error {
+	unhealthy := []string{}
+	servers := 0
+	if health.BackendAddressPools != nil {
+		for _, pool := range *health.BackendAddressPools {
+			if pool.BackendHTTPSettingsCollection == nil {
+				continue
+			}
+			for _, settings := range *pool.BackendHTTPSettingsCollection {
+				if settings.Servers == nil {
+					continue
+				}
+				for _, server := range *settings.Servers {
+					servers++
+					if server.Health != network.Up {
+						unhealthy = append(unhealthy, fmt.Sprintf("%s is %s (%s)", to.String(server.Address), server.Health, to.String(server.HealthProbeLog)))
+					}
+				}
+			}
+		}
+	}
+
+	if servers == 0 {
+		return fmt.Errorf("Application Gateway %s has no backend servers", gatewayName)
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("Application Gateway %s has unhealthy backend servers: %s", gatewayName, strings.Join(unhealthy, "; "))
+	}
+	return nil
+}
+
+// AssertApplicationGatewayRoutingRule checks that the routing rule of the Application Gateway routes requests from the
+// given listener to the given backend pool. This function would fail the test if there is an error or it does not.
+func AssertApplicationGatewayRoutingRule(t testing.TestingT, ruleName string, listenerName string, backendPoolName string, gatewayName string, resourceGroupName string, subscriptionID string) {
+	err := AssertApplicationGatewayRoutingRuleE(ruleName, listenerName, backendPoolName, gatewayName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertApplicationGatewayRoutingRuleE checks that the routing rule of the Application Gateway routes requests from the
+// given listener to the given backend pool. For path based rules, the backend pool may be the default one of the URL
+// path map or the one of any of its path rules.
+func AssertApplicationGatewayRoutingRuleE(ruleName string, listenerName string, backendPoolName string, gatewayName string, resourceGroupName string, subscriptionID string) error {
+	gateway, err := GetApplicationGatewayE(gatewayName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkApplicationGatewayRoutingRule(gateway, ruleName, listenerName, backendPoolName)
+}
+
+// checkApplicationGatewayRoutingRule returns an error if the given Application Gateway has no routing rule with the
+// given name, or if it does not route requests from the given listener to the given backend pool.
+func checkApplicationGatewayRoutingRule(gateway *network.ApplicationGateway, ruleName string, listenerName string, backendPoolName string) error {
+	gatewayName := to.String(gateway.Name)
+	if gateway.ApplicationGatewayPropertiesFormat == nil || gateway.RequestRoutingRules == nil {
+		return NewNotFoundError("Application Gateway routing rule", ruleName, gatewayName)
+	}
+
+	for _, rule := range *gateway.RequestRoutingRules {
+		if to.String(rule.Name) != ruleName || rule.ApplicationGatewayRequestRoutingRulePropertiesFormat == nil {
+			continue
+		}
+		if actual := subResourceName(rule.HTTPListener); actual != listenerName {
+			return fmt.Errorf("Routing rule %s of Application Gateway %s has listener %q, not %q", ruleName, gatewayName, actual, listenerName)
+		}
+
+		backendPoolNames := []string{subResourceName(rule.BackendAddressPool)}
+		if rule.URLPathMap != nil {
+			backendPoolNames = getApplicationGatewayURLPathMapBackendPoolNames(gateway, subResourceName(rule.URLPathMap))
+		}
+		for _, name := range backendPoolNames {
+			if name == backendPoolName {
+				return nil
+			}
+		}
+		return fmt.Errorf("Routing rule %s of Application Gateway %s routes to backend pools %v, not %q", ruleName, gatewayName, backendPoolNames, backendPoolName)
+	}
+	return NewNotFoundError("Application Gateway routing rule", ruleName, gatewayName)
+}
+
+// getApplicationGatewayURLPathMapBackendPoolNames returns the names of the backend pools the URL path map with the
+// given name of the Application Gateway routes to.
+func getApplicationGatewayURLPathMapBackendPoolNames(gateway *network.ApplicationGateway, pathMapName string) []string {
+	names := []string{}
+	if gateway.URLPathMaps == nil {
+		return names
+	}
+	for _, pathMap := range *gateway.URLPathMaps {
+		if to.String(pathMap.Name) != pathMapName || pathMap.ApplicationGatewayURLPathMapPropertiesFormat == nil {
+			continue
+		}
+		if pathMap.DefaultBackendAddressPool != nil {
+			names = append(names, subResourceName(pathMap.DefaultBackendAddressPool))
+		}
+		if pathMap.PathRules != nil {
+			for _, pathRule := range *pathMap.PathRules {
+				if pathRule.ApplicationGatewayPathRulePropertiesFormat != nil && pathRule.BackendAddressPool != nil {
+					names = append(names, subResourceName(pathRule.BackendAddressPool))
+				}
+			}
+		}
+	}
+	return names
+}
+
+// AssertApplicationGatewayFirewallMode checks that the web application firewall of the Application Gateway is enabled
+// in the given mode. This function would fail the test if there is an error or it is not.
+func AssertApplicationGatewayFirewallMode(t testing.TestingT, expectedMode WebApplicationFirewallMode, gatewayName string, resourceGroupName string, subscriptionID string) {
+	err := AssertApplicationGatewayFirewallModeE(expectedMode, gatewayName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertApplicationGatewayFirewallModeE checks that the web application firewall of the Application Gateway is enabled
+// in the given mode (WAFPreventionMode or WAFDetectionMode). The mode is
+// read from the firewall policy associated with the gateway if there is one, or else from its WAF configuration.
+func AssertApplicationGatewayFirewallModeE(expectedMode WebApplicationFirewallMode, gatewayName string, resourceGroupName string, subscriptionID string) error {
+	gateway, err := GetApplicationGatewayE(gatewayName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if gateway.ApplicationGatewayPropertiesFormat == nil {
+		return fmt.Errorf("Application Gateway %s has no properties", gatewayName)
+	}
+
+	var enabled bool
+	var mode WebApplicationFirewallMode
+	if gateway.FirewallPolicy != nil && gateway.FirewallPolicy.ID != nil {
+		policy, err := getWebApplicationFirewallPolicyE(to.String(gateway.FirewallPolicy.ID), subscriptionID)
+		if err != nil {
+			return err
+		}
+		if policy.WebApplicationFirewallPolicyPropertiesFormat != nil && policy.PolicySettings != nil {
+			enabled = policy.PolicySettings.State == network.WebApplicationFirewallEnabledStateEnabled
+			mode = WebApplicationFirewallMode(policy.PolicySettings.Mode)
+		}
+	} else if config := gateway.WebApplicationFirewallConfiguration; config != nil {
+		enabled = to.Bool(config.Enabled)
+		mode = WebApplicationFirewallMode(config.FirewallMode)
+	}
+
+	return checkApplicationGatewayFirewallMode(gatewayName, enabled, mode, expectedMode)
+}
+
+// checkApplicationGatewayFirewallMode returns an error if the web application firewall is not enabled in the expected
+// mode.
+func checkApplicationGatewayFirewallMode(gatewayName string, enabled bool, mode WebApplicationFirewallMode, expectedMode WebApplicationFirewallMode) error {
+	if !enabled {
+		return fmt.Errorf("Web application firewall of Application Gateway %s is not enabled", gatewayName)
+	}
+	if !strings.EqualFold(string(mode), string(expectedMode)) {
+		return fmt.Errorf("Web application firewall of Application Gateway %s is in %s mode, not %s", gatewayName, mode, expectedMode)
+	}
+	return nil
+}
+
+// getWebApplicationFirewallPolicyE gets the web application firewall policy with the given resource ID.
+func getWebApplicationFirewallPolicyE(policyID string, subscriptionID string) (*network.WebApplicationFirewallPolicy, error) {
+	resourceGroupName, err := getResourceGroupNameFromResourceIDE(policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := CreateWebApplicationFirewallPoliciesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	policy, err := client.Get(context.Background(), resourceGroupName, GetNameFromResourceID(policyID))
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetApplicationGatewayPublicIP gets the public IP address of the frontend of the Application Gateway.
+// This function would fail the test if there is an error.
+func GetApplicationGatewayPublicIP(t testing.TestingT, gatewayName string, resourceGroupName string, subscriptionID string) string {
+	ipAddress, err := GetApplicationGatewayPublicIPE(gatewayName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return ipAddress
+}
+
+// GetApplicationGatewayPublicIPE gets the public IP address of the frontend of the Application Gateway, to send
+// requests through the gateway with HttpGetApplicationGatewayWithRetryE.
+func GetApplicationGatewayPublicIPE(gatewayName string, resourceGroupName string, subscriptionID string) (string, error) {
+	gateway, err := GetApplicationGatewayE(gatewayName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	if gateway.ApplicationGatewayPropertiesFormat != nil && gateway.FrontendIPConfigurations != nil {
+		for _, config := range *gateway.FrontendIPConfigurations {
+			if config.ApplicationGatewayFrontendIPConfigurationPropertiesFormat == nil || config.PublicIPAddress == nil {
+				continue
+			}
+			publicIPID := to.String(config.PublicIPAddress.ID)
+			publicIPResourceGroupName, err := getResourceGroupNameFromResourceIDE(publicIPID)
+			if err != nil {
+				return "", err
+			}
+			return GetIPOfPublicIPAddressByNameE(GetNameFromResourceID(publicIPID), publicIPResourceGroupName, subscriptionID)
+		}
+	}
+	return "", NewNotFoundError("Public frontend IP configuration", "Any", gatewayName)
+}
+
+// HttpGetApplicationGatewayWithRetry makes HTTP GET requests to the given URL through the Application Gateway until it
+// responds with the expected status and a body that contains the expected text.
+// This function would fail the test if there is an error or the gateway doesn't respond as expected in time.
+func HttpGetApplicationGatewayWithRetry(t testing.TestingT, url string, hostHeader string, expectedStatus int, expectedBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := HttpGetApplicationGatewayWithRetryE(t, url, hostHeader, expectedStatus, expectedBodySubstring, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// HttpGetApplicationGatewayWithRetryE makes HTTP GET requests to the given URL through the Application Gateway (e.g.,
+// http://<public IP>/path) until it responds with the expected status and a body that contains the expected text,
+// retrying for the specified amount of times, sleeping for the provided duration between each try. If the host header
+// is not empty, it is sent instead of the host of the URL, to test multi-site listeners before DNS is set up.
+func HttpGetApplicationGatewayWithRetryE(t testing.TestingT, url string, hostHeader string, expectedStatus int, expectedBodySubstring string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	_, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("HTTP GET to %s through Application Gateway", url),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			status, body, err := httpGetApplicationGatewayE(url, hostHeader)
+			if err != nil {
+				return "", err
+			}
+			if status != expectedStatus || !strings.Contains(body, expectedBodySubstring) {
+				return "", fmt.Errorf("Application Gateway responded with status %d and body %q", status, body)
+			}
+			return "", nil
+		},
+	)
+	return err
+}
+
+// AssertApplicationGatewayBlocksMaliciousRequest checks that the web application firewall of the Application Gateway
+// blocks a request with SQL injection and cross-site scripting payloads to the given URL.
+// This function would fail the test if there is an error or the request is not blocked in time.
+func AssertApplicationGatewayBlocksMaliciousRequest(t testing.TestingT, url string, hostHeader string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := AssertApplicationGatewayBlocksMaliciousRequestE(t, url, hostHeader, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// AssertApplicationGatewayBlocksMaliciousRequestE checks that the web application firewall of the Application Gateway
+// blocks (i.e., responds with 403 Forbidden to) a request with SQL injection and cross-site scripting payloads in the
+// query string of the given URL, retrying for the specified amount of times, sleeping for the provided duration between
+// each try, as firewall changes take a few minutes to apply. This only holds in Prevention mode: in Detection mode,
+// malicious requests are only logged.
+func AssertApplicationGatewayBlocksMaliciousRequestE(t testing.TestingT, url string, hostHeader string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	maliciousURL := url + applicationGatewayMaliciousQuery
+	if strings.Contains(url, "?") {
+		maliciousURL = url + "&" + strings.TrimPrefix(applicationGatewayMaliciousQuery, "?")
+	}
+	return HttpGetApplicationGatewayWithRetryE(t, maliciousURL, hostHeader, http.StatusForbidden, "", maxRetries, sleepBetweenRetries)
+}
+
+// httpGetApplicationGatewayE makes an HTTP GET request to the given URL with the given host header (if not empty), and
+// returns the status and the body of the response.
+func httpGetApplicationGatewayE(url string, hostHeader string) (int, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	if hostHeader != "" {
+		req.Host = hostHeader
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{ServerName: hostHeader}}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, strings.TrimSpace(string(body)), nil
+}
+
+// subResourceName returns the name of the resource the given reference points to, or an empty string if it is nil.
+func subResourceName(subResource *network.SubResource) string {
+	if subResource == nil {
+		return ""
+	}
+	return GetNameFromResourceID(to.String(subResource.ID))
+}
+
+// getResourceGroupNameFromResourceIDE returns the name of the resource group of the resource with the given ID.
+func getResourceGroupNameFromResourceIDE(resourceID string) (string, error) {
+	parts := strings.Split(resourceID, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1], nil
+		}
+	}
+	return "", NewFailedToParseError("Resource ID", resourceID)
+}
@@ -0,0 +1,152 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when methods can be mocked or Create/Delete APIs are added, these tests can be extended.
+*/
+
+func TestGetApplicationGatewayE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetApplicationGatewayE("", "", "")
+
+	require.Error(t, err)
+}
+
+func TestGetApplicationGatewayBackendHealthE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetApplicationGatewayBackendHealthE("", "", "")
+
+	require.Error(t, err)
+}
+
+func TestAssertApplicationGatewayFirewallModeE(t *testing.T) {
+	t.Parallel()
+
+	err := AssertApplicationGatewayFirewallModeE(WAFPreventionMode, "", "", "")
+
+	require.Error(t, err)
+}
+
+func TestCheckApplicationGatewayBackendHealth(t *testing.T) {
+	t.Parallel()
+
+	newHealth := func(servers ...network.ApplicationGatewayBackendHealthServer) *network.ApplicationGatewayBackendHealth {
+		return &network.ApplicationGatewayBackendHealth{BackendAddressPools: &[]network.ApplicationGatewayBackendHealthPool{{
+			BackendHTTPSettingsCollection: &[]network.ApplicationGatewayBackendHealthHTTPSettings{{Servers: &servers}},
+		}}}
+	}
+	up := network.ApplicationGatewayBackendHealthServer{Address: to.StringPtr("10.0.1.4"), Health: network.Up}
+	down := network.ApplicationGatewayBackendHealthServer{Address: to.StringPtr("10.0.1.5"), Health: network.Down, HealthProbeLog: to.StringPtr("Received invalid status code: 500")}
+
+	assert.NoError(t, checkApplicationGatewayBackendHealth(newHealth(up), "agw"))
+	err := checkApplicationGatewayBackendHealth(newHealth(up, down), "agw")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Received invalid status code: 500")
+	assert.Error(t, checkApplicationGatewayBackendHealth(newHealth(), "agw"))
+}
+
+func TestCheckApplicationGatewayRoutingRule(t *testing.T) {
+	t.Parallel()
+
+	gatewayID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/applicationGateways/agw"
+	subResource := func(path string) *network.SubResource {
+		return &network.SubResource{ID: to.StringPtr(gatewayID + path)}
+	}
+	gateway := &network.ApplicationGateway{
+		Name: to.StringPtr("agw"),
+		ApplicationGatewayPropertiesFormat: &network.ApplicationGatewayPropertiesFormat{
+			RequestRoutingRules: &[]network.ApplicationGatewayRequestRoutingRule{
+				{
+					Name: to.StringPtr("basic"),
+					ApplicationGatewayRequestRoutingRulePropertiesFormat: &network.ApplicationGatewayRequestRoutingRulePropertiesFormat{
+						HTTPListener:       subResource("/httpListeners/http"),
+						BackendAddressPool: subResource("/backendAddressPools/web"),
+					},
+				},
+				{
+					Name: to.StringPtr("path-based"),
+					ApplicationGatewayRequestRoutingRulePropertiesFormat: &network.ApplicationGatewayRequestRoutingRulePropertiesFormat{
+						HTTPListener: subResource("/httpListeners/https"),
+						URLPathMap:   subResource("/urlPathMaps/paths"),
+					},
+				},
+			},
+			URLPathMaps: &[]network.ApplicationGatewayURLPathMap{{
+				Name: to.StringPtr("paths"),
+				ApplicationGatewayURLPathMapPropertiesFormat: &network.ApplicationGatewayURLPathMapPropertiesFormat{
+					DefaultBackendAddressPool: subResource("/backendAddressPools/web"),
+					PathRules: &[]network.ApplicationGatewayPathRule{{
+						ApplicationGatewayPathRulePropertiesFormat: &network.ApplicationGatewayPathRulePropertiesFormat{
+							BackendAddressPool: subResource("/backendAddressPools/api"),
+						},
+					}},
+				},
+			}},
+		},
+	}
+
+	assert.NoError(t, checkApplicationGatewayRoutingRule(gateway, "basic", "http", "web"))
+	assert.Error(t, checkApplicationGatewayRoutingRule(gateway, "basic", "https", "web"))
+	assert.Error(t, checkApplicationGatewayRoutingRule(gateway, "basic", "http", "api"))
+	assert.NoError(t, checkApplicationGatewayRoutingRule(gateway, "path-based", "https", "web"))
+	assert.NoError(t, checkApplicationGatewayRoutingRule(gateway, "path-based", "https", "api"))
+	assert.Error(t, checkApplicationGatewayRoutingRule(gateway, "path-based", "https", "admin"))
+	assert.IsType(t, NotFoundError{}, checkApplicationGatewayRoutingRule(gateway, "missing", "http", "web"))
+}
+
+func TestCheckApplicationGatewayFirewallMode(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkApplicationGatewayFirewallMode("agw", true, WAFPreventionMode, WAFPreventionMode))
+	assert.Error(t, checkApplicationGatewayFirewallMode("agw", true, WAFDetectionMode, WAFPreventionMode))
+	assert.Error(t, checkApplicationGatewayFirewallMode("agw", false, WAFPreventionMode, WAFPreventionMode))
+}
+
+func TestGetResourceGroupNameFromResourceIDE(t *testing.T) {
+	t.Parallel()
+
+	name, err := getResourceGroupNameFromResourceIDE("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/waf-rg/providers/Microsoft.Network/ApplicationGatewayWebApplicationFirewallPolicies/waf")
+	require.NoError(t, err)
+	assert.Equal(t, "waf-rg", name)
+
+	_, err = getResourceGroupNameFromResourceIDE("waf")
+	assert.Error(t, err)
+}
+
+func TestAssertApplicationGatewayBlocksMaliciousRequestE(t *testing.T) {
+	t.Parallel()
+
+	// Emulate a web application firewall that blocks requests with scripts in their query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, "Hello from %s", r.Host)
+	}))
+	defer server.Close()
+
+	require.NoError(t, HttpGetApplicationGatewayWithRetryE(t, server.URL, "www.example.com", http.StatusOK, "Hello from www.example.com", 1, 0))
+	require.NoError(t, AssertApplicationGatewayBlocksMaliciousRequestE(t, server.URL+"/?page=1", "", 1, 0))
+	assert.Error(t, HttpGetApplicationGatewayWithRetryE(t, server.URL, "", http.StatusForbidden, "", 1, 0))
+}
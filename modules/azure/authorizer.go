@@ -1,9 +1,17 @@
 package azure
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
 	az "github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 )
@@ -17,25 +25,323 @@ const (
 
 	// AuthFromFile is an env variable supported by the Azure SDK
 	AuthFromFile = "AZURE_AUTH_LOCATION"
+
+	// AuthFromEnvClientSecret is an env variable supported by the Azure SDK
+	AuthFromEnvClientSecret = "AZURE_CLIENT_SECRET"
+
+	// AuthFromEnvFederatedTokenFile is an env variable set by Azure AD workload identity (e.g., in AKS pods) to the path
+	// of a federated token
+	AuthFromEnvFederatedTokenFile = "AZURE_FEDERATED_TOKEN_FILE"
+
+	// AuthFromArmClient, AuthFromArmTenant, AuthFromArmClientSecret, AuthFromArmOIDCToken and AuthFromArmOIDCTokenFile
+	// are the env variables supported by the `azurerm` Terraform provider for the same settings
+	AuthFromArmClient        = "ARM_CLIENT_ID"
+	AuthFromArmTenant        = "ARM_TENANT_ID"
+	AuthFromArmClientSecret  = "ARM_CLIENT_SECRET"
+	AuthFromArmOIDCToken     = "ARM_OIDC_TOKEN"
+	AuthFromArmOIDCTokenFile = "ARM_OIDC_TOKEN_FILE_PATH"
+
+	// AuthFromArmUseOIDC is an env variable supported by the `azurerm` Terraform provider to authenticate with workload
+	// identity federation, e.g., with the OIDC token of a GitHub Actions job
+	AuthFromArmUseOIDC = "ARM_USE_OIDC"
+
+	// AuthFromArmUseMSI is an env variable supported by the `azurerm` Terraform provider to authenticate with a managed
+	// identity
+	AuthFromArmUseMSI = "ARM_USE_MSI"
+
+	// federatedTokenAudience is the audience of the federated tokens Azure AD exchanges for access tokens.
+	federatedTokenAudience = "api://AzureADTokenExchange"
+
+	// clientAssertionType is the OAuth client assertion type of federated tokens.
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// The env variables GitHub Actions jobs with the id-token permission (and the `azurerm` Terraform provider) use to
+// request an OIDC token, and that managed identity environments other than Azure VMs set.
+var (
+	oidcRequestURLEnvNames      = []string{"ARM_OIDC_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_URL"}
+	oidcRequestTokenEnvNames    = []string{"ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN"}
+	managedIdentityEndpointEnvs = []string{"IDENTITY_ENDPOINT", "MSI_ENDPOINT"}
 )
 
+// AuthMethod is a way to authenticate to Azure, for use in AuthOptions.
+type AuthMethod string
+
+// AuthMethod values
+const (
+	// AuthMethodDefault picks the first method of the credential chain the settings are available for (see NewAuthorizer)
+	AuthMethodDefault AuthMethod = ""
+	// AuthMethodWorkloadIdentity exchanges a federated token (e.g., of a Kubernetes service account or a CI job) for an
+	// access token of the service principal or managed identity with the client ID
+	AuthMethodWorkloadIdentity AuthMethod = "WorkloadIdentity"
+	// AuthMethodClientSecret authenticates as the service principal with the client ID and secret
+	AuthMethodClientSecret AuthMethod = "ClientSecret"
+	// AuthMethodEnvironment authenticates with the AZURE_* env variables supported by the Azure SDK
+	AuthMethodEnvironment AuthMethod = "Environment"
+	// AuthMethodFile authenticates with the file at AZURE_AUTH_LOCATION
+	AuthMethodFile AuthMethod = "File"
+	// AuthMethodManagedIdentity authenticates as the managed identity of the Azure resource the test runs on (the user
+	// assigned identity with the client ID, if set)
+	AuthMethodManagedIdentity AuthMethod = "ManagedIdentity"
+	// AuthMethodCLI authenticates as the user or service principal logged in with the Azure CLI
+	AuthMethodCLI AuthMethod = "CLI"
+)
+
+// AuthOptions are the options to authenticate to Azure with NewAuthorizerWithOptions. The settings that are empty are
+// read from the AZURE_* env variables supported by the Azure SDK, or else the ARM_* env variables supported by the
+// `azurerm` Terraform provider, so tests authenticate like the Terraform code they test.
+type AuthOptions struct {
+	Method       AuthMethod // The way to authenticate, or AuthMethodDefault for the credential chain
+	TenantID     string
+	ClientID     string // The client ID of the service principal, or of the user assigned managed identity
+	ClientSecret string
+
+	// The federated token for workload identity, or the path of a file with it (re-read whenever the access token is
+	// refreshed, as Kubernetes rotates projected service account tokens). If neither is set, the token is requested from
+	// GitHub Actions.
+	FederatedToken     string
+	FederatedTokenFile string
+}
+
 // NewAuthorizer creates an Azure authorizer adhering to standard auth mechanisms provided by the Azure Go SDK
 // See Azure Go Auth docs here: https://docs.microsoft.com/en-us/go/azure/azure-sdk-go-authorization
+//
+// It uses the first method of this credential chain the env variables are set for:
+//  1. Workload identity, if a federated token (file) is set, or with ARM_USE_OIDC=true in GitHub Actions
+//  2. Client secret
+//  3. The AZURE_* env variables (client certificate, username and password, or managed identity)
+//  4. The file at AZURE_AUTH_LOCATION
+//  5. Managed identity, with ARM_USE_MSI=true or in App Service, Functions, Container Apps, Cloud Shell or Arc
+//  6. Azure CLI
+//
+// This allows tests to run without secrets in CI, e.g., in GitHub Actions with workload identity federation, in Azure
+// DevOps with a workload identity service connection and the Azure CLI task, or on a self-hosted agent with a managed
+// identity.
 func NewAuthorizer() (*autorest.Authorizer, error) {
-	// Carry out env var lookups
+	return NewAuthorizerWithOptions(AuthOptions{})
+}
+
+// NewAuthorizerWithOptions creates an Azure authorizer that authenticates with the given options.
+func NewAuthorizerWithOptions(options AuthOptions) (*autorest.Authorizer, error) {
+	return newAuthorizerWithResourceE(options, "")
+}
+
+// newAuthorizerWithResourceE creates an Azure authorizer for the given resource (e.g., a data plane API), or for the
+// resource manager if it is empty, that authenticates with the given options.
+func newAuthorizerWithResourceE(options AuthOptions, resource string) (*autorest.Authorizer, error) {
+	options = resolveAuthOptions(options)
+
+	env, err := az.EnvironmentFromName(getDefaultEnvironmentName())
+	if err != nil {
+		return nil, err
+	}
+	tokenResource := resource
+	if tokenResource == "" {
+		tokenResource = env.ResourceManagerEndpoint
+	}
+
+	var authorizer autorest.Authorizer
+	switch method := getAuthMethod(options); method {
+	case AuthMethodWorkloadIdentity:
+		authorizer, err = newWorkloadIdentityAuthorizerE(options, env, tokenResource)
+	case AuthMethodClientSecret:
+		config := auth.NewClientCredentialsConfig(options.ClientID, options.ClientSecret, options.TenantID)
+		config.AADEndpoint = env.ActiveDirectoryEndpoint
+		config.Resource = tokenResource
+		authorizer, err = config.Authorizer()
+	case AuthMethodEnvironment:
+		if resource == "" {
+			authorizer, err = auth.NewAuthorizerFromEnvironment()
+		} else {
+			authorizer, err = auth.NewAuthorizerFromEnvironmentWithResource(resource)
+		}
+	case AuthMethodFile:
+		if resource == "" {
+			authorizer, err = auth.NewAuthorizerFromFile(az.PublicCloud.ResourceManagerEndpoint)
+		} else {
+			authorizer, err = auth.NewAuthorizerFromFileWithResource(resource)
+		}
+	case AuthMethodManagedIdentity:
+		config := auth.NewMSIConfig()
+		config.ClientID = options.ClientID
+		config.Resource = tokenResource
+		authorizer, err = config.Authorizer()
+	case AuthMethodCLI:
+		if resource == "" {
+			authorizer, err = auth.NewAuthorizerFromCLI()
+		} else {
+			authorizer, err = auth.NewAuthorizerFromCLIWithResource(resource)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported Azure auth method %q", method)
+	}
+
+	return &authorizer, err
+}
+
+// resolveAuthOptions returns the given options, with the settings that are empty read from the env variables.
+func resolveAuthOptions(options AuthOptions) AuthOptions {
+	if options.TenantID == "" {
+		options.TenantID = lookupFirstEnv(AuthFromEnvTenant, AuthFromArmTenant)
+	}
+	if options.ClientID == "" {
+		options.ClientID = lookupFirstEnv(AuthFromEnvClient, AuthFromArmClient)
+	}
+	if options.ClientSecret == "" {
+		options.ClientSecret = lookupFirstEnv(AuthFromEnvClientSecret, AuthFromArmClientSecret)
+	}
+	if options.FederatedToken == "" {
+		options.FederatedToken = lookupFirstEnv(AuthFromArmOIDCToken)
+	}
+	if options.FederatedTokenFile == "" {
+		options.FederatedTokenFile = lookupFirstEnv(AuthFromEnvFederatedTokenFile, AuthFromArmOIDCTokenFile)
+	}
+	return options
+}
+
+// getAuthMethod returns the method of the given options, or else the first method of the credential chain they (and
+// the env variables) have the settings for.
+func getAuthMethod(options AuthOptions) AuthMethod {
+	if options.Method != AuthMethodDefault {
+		return options.Method
+	}
+
+	hasServicePrincipal := options.TenantID != "" && options.ClientID != ""
+	hasFederatedToken := options.FederatedToken != "" || options.FederatedTokenFile != "" || lookupEnvBool(AuthFromArmUseOIDC)
 	_, clientIDExists := os.LookupEnv(AuthFromEnvClient)
 	_, tenantIDExists := os.LookupEnv(AuthFromEnvTenant)
 	_, fileAuthSet := os.LookupEnv(AuthFromFile)
 
-	// Execute logic to return an authorizer from the correct method
-	if clientIDExists && tenantIDExists {
-		authorizer, err := auth.NewAuthorizerFromEnvironment()
-		return &authorizer, err
-	} else if fileAuthSet {
-		authorizer, err := auth.NewAuthorizerFromFile(az.PublicCloud.ResourceManagerEndpoint)
-		return &authorizer, err
-	} else {
-		authorizer, err := auth.NewAuthorizerFromCLI()
-		return &authorizer, err
+	switch {
+	case hasServicePrincipal && hasFederatedToken && options.ClientSecret == "":
+		return AuthMethodWorkloadIdentity
+	case hasServicePrincipal && options.ClientSecret != "":
+		return AuthMethodClientSecret
+	case clientIDExists && tenantIDExists:
+		return AuthMethodEnvironment
+	case fileAuthSet:
+		return AuthMethodFile
+	case lookupEnvBool(AuthFromArmUseMSI) || lookupFirstEnv(managedIdentityEndpointEnvs...) != "":
+		return AuthMethodManagedIdentity
+	default:
+		return AuthMethodCLI
+	}
+}
+
+// newWorkloadIdentityAuthorizerE creates an authorizer that exchanges the federated token of the given options for
+// access tokens of the given resource.
+func newWorkloadIdentityAuthorizerE(options AuthOptions, env az.Environment, resource string) (autorest.Authorizer, error) {
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, options.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	secret := &federatedTokenSecret{token: options.FederatedToken, tokenFile: options.FederatedTokenFile}
+	token, err := adal.NewServicePrincipalTokenWithSecret(*oauthConfig, options.ClientID, resource, secret)
+	if err != nil {
+		return nil, err
+	}
+	return autorest.NewBearerAuthorizer(token), nil
+}
+
+// federatedTokenSecret is an adal secret that authenticates with a federated token as the client assertion, which is
+// read from the file or requested from GitHub Actions again on each refresh, as these tokens are short lived.
+type federatedTokenSecret struct {
+	token     string
+	tokenFile string
+}
+
+// SetAuthenticationValues is a method of the interface adal.ServicePrincipalSecret.
+func (secret *federatedTokenSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, values *url.Values) error {
+	token, err := secret.getFederatedTokenE()
+	if err != nil {
+		return err
+	}
+	values.Set("client_assertion_type", clientAssertionType)
+	values.Set("client_assertion", token)
+	return nil
+}
+
+// getFederatedTokenE returns the federated token of the secret.
+func (secret *federatedTokenSecret) getFederatedTokenE() (string, error) {
+	if secret.token != "" {
+		return secret.token, nil
+	}
+	if secret.tokenFile != "" {
+		token, err := ioutil.ReadFile(secret.tokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+	return requestOIDCTokenE(lookupFirstEnv(oidcRequestURLEnvNames...), lookupFirstEnv(oidcRequestTokenEnvNames...))
+}
+
+// requestOIDCTokenE requests an OIDC token for Azure AD from the given endpoint with the given bearer token, as for the
+// id-token permission of GitHub Actions jobs.
+func requestOIDCTokenE(requestURL string, requestToken string) (string, error) {
+	if requestURL == "" || requestToken == "" {
+		return "", fmt.Errorf("No federated token is set and no OIDC token can be requested: set %s, %s or, in GitHub Actions, the id-token: write permission", AuthFromEnvFederatedTokenFile, AuthFromArmOIDCToken)
+	}
+
+	tokenURL, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	query.Set("audience", federatedTokenAudience)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC token request failed with status %s", resp.Status)
+	}
+
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	return token.Value, nil
+}
+
+// lookupFirstEnv returns the value of the first of the given env variables that is set and not empty.
+func lookupFirstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// lookupEnvBool returns whether the given env variable is set to true.
+func lookupEnvBool(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && value
+}
+
+// getAccessTokenE returns an Azure AD access token for the given resource, authenticating with the given options, for
+// the APIs and protocols that take a raw token rather than an authorizer.
+func getAccessTokenE(options AuthOptions, resource string) (string, error) {
+	authorizer, err := newAuthorizerWithResourceE(options, resource)
+	if err != nil {
+		return "", err
+	}
+
+	// The authorizer only adds the token to requests, so get it from a request that is never sent.
+	req, err := autorest.Prepare(&http.Request{Header: http.Header{}}, (*authorizer).WithAuthorization())
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), nil
 }
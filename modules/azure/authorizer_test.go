@@ -0,0 +1,121 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authEnvNames are the env variables that select the auth method, which the tests below unset.
+var authEnvNames = []string{
+	AuthFromEnvClient, AuthFromEnvTenant, AuthFromFile, AuthFromEnvClientSecret, AuthFromEnvFederatedTokenFile,
+	AuthFromArmClient, AuthFromArmTenant, AuthFromArmClientSecret, AuthFromArmOIDCToken, AuthFromArmOIDCTokenFile,
+	AuthFromArmUseOIDC, AuthFromArmUseMSI, "IDENTITY_ENDPOINT", "MSI_ENDPOINT",
+}
+
+// unsetAuthEnv unsets the env variables that select the auth method for the duration of the test, as getAuthMethod
+// tells unset variables apart from empty ones like the Azure SDK does.
+func unsetAuthEnv(t *testing.T) {
+	for _, name := range authEnvNames {
+		// Setenv registers the cleanup that restores the variable
+		t.Setenv(name, "")
+		require.NoError(t, os.Unsetenv(name))
+	}
+}
+
+func TestGetAuthMethod(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		options  AuthOptions
+		expected AuthMethod
+	}{
+		{"CLI", map[string]string{}, AuthOptions{}, AuthMethodCLI},
+		{"Explicit", map[string]string{AuthFromArmUseMSI: "true"}, AuthOptions{Method: AuthMethodCLI}, AuthMethodCLI},
+		{"AksWorkloadIdentity", map[string]string{AuthFromEnvClient: "client", AuthFromEnvTenant: "tenant", AuthFromEnvFederatedTokenFile: "/var/run/secrets/azure/tokens/azure-identity-token"}, AuthOptions{}, AuthMethodWorkloadIdentity},
+		{"GitHubActionsOIDC", map[string]string{AuthFromArmClient: "client", AuthFromArmTenant: "tenant", AuthFromArmUseOIDC: "true"}, AuthOptions{}, AuthMethodWorkloadIdentity},
+		{"ArmClientSecret", map[string]string{AuthFromArmClient: "client", AuthFromArmTenant: "tenant", AuthFromArmClientSecret: "secret"}, AuthOptions{}, AuthMethodClientSecret},
+		{"OptionsClientSecret", map[string]string{}, AuthOptions{TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}, AuthMethodClientSecret},
+		{"AzureSdkEnvironment", map[string]string{AuthFromEnvClient: "client", AuthFromEnvTenant: "tenant"}, AuthOptions{}, AuthMethodEnvironment},
+		{"File", map[string]string{AuthFromFile: "/tmp/azure.auth"}, AuthOptions{}, AuthMethodFile},
+		{"ArmUseMSI", map[string]string{AuthFromArmUseMSI: "true", AuthFromArmClient: "identity"}, AuthOptions{}, AuthMethodManagedIdentity},
+		{"AppServiceManagedIdentity", map[string]string{"IDENTITY_ENDPOINT": "http://localhost:42356/msi/token"}, AuthOptions{}, AuthMethodManagedIdentity},
+		{"ArmUseMSIFalse", map[string]string{AuthFromArmUseMSI: "false"}, AuthOptions{}, AuthMethodCLI},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			unsetAuthEnv(t)
+			for name, value := range testCase.env {
+				t.Setenv(name, value)
+			}
+			assert.Equal(t, testCase.expected, getAuthMethod(resolveAuthOptions(testCase.options)))
+		})
+	}
+}
+
+func TestResolveAuthOptions(t *testing.T) {
+	unsetAuthEnv(t)
+	t.Setenv(AuthFromArmClient, "arm-client")
+	t.Setenv(AuthFromEnvTenant, "azure-tenant")
+	t.Setenv(AuthFromArmTenant, "arm-tenant")
+
+	options := resolveAuthOptions(AuthOptions{ClientSecret: "secret"})
+	assert.Equal(t, AuthOptions{TenantID: "azure-tenant", ClientID: "arm-client", ClientSecret: "secret"}, options)
+}
+
+func TestFederatedTokenSecretSetAuthenticationValues(t *testing.T) {
+	t.Parallel()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("first-token\n"), 0600))
+	secret := &federatedTokenSecret{tokenFile: tokenFile}
+
+	values := url.Values{}
+	require.NoError(t, secret.SetAuthenticationValues(nil, &values))
+	assert.Equal(t, clientAssertionType, values.Get("client_assertion_type"))
+	assert.Equal(t, "first-token", values.Get("client_assertion"))
+
+	// The token file is read again on refresh, as it is rotated
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("second-token"), 0600))
+	require.NoError(t, secret.SetAuthenticationValues(nil, &values))
+	assert.Equal(t, "second-token", values.Get("client_assertion"))
+}
+
+func TestRequestOIDCTokenE(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("audience") != federatedTokenAudience {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"count": 1, "value": "github-token"}`)
+	}))
+	defer server.Close()
+
+	token, err := requestOIDCTokenE(server.URL+"/token?api-version=2.0", "request-token")
+	require.NoError(t, err)
+	assert.Equal(t, "github-token", token)
+
+	_, err = requestOIDCTokenE(server.URL+"/token?api-version=2.0", "wrong-token")
+	assert.Error(t, err)
+
+	_, err = requestOIDCTokenE("", "")
+	assert.Error(t, err)
+}
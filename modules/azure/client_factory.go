@@ -11,6 +11,7 @@ package azure
 // snippet-tag-start::client_factory_example.imports
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 
@@ -20,12 +21,20 @@ import (
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/sql/mgmt/sql"
 	"github.com/Azure/azure-sdk-for-go/profiles/preview/cosmos-db/mgmt/documentdb"
 	"github.com/Azure/azure-sdk-for-go/profiles/preview/preview/monitor/mgmt/insights"
+	"github.com/Azure/azure-sdk-for-go/services/authorization/mgmt/2015-07-01/authorization"
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/containerinstance/mgmt/2018-10-01/containerinstance"
 	"github.com/Azure/azure-sdk-for-go/services/containerregistry/mgmt/2019-05-01/containerregistry"
 	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-11-01/containerservice"
-	kvmng "github.com/Azure/azure-sdk-for-go/services/keyvault/mgmt/2016-10-01/keyvault"
+	"github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+	kvmng "github.com/Azure/azure-sdk-for-go/services/keyvault/mgmt/2019-09-01/keyvault"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	loganalytics "github.com/Azure/azure-sdk-for-go/services/operationalinsights/v1/operationalinsights"
+	acrtasks "github.com/Azure/azure-sdk-for-go/services/preview/containerregistry/mgmt/2019-06-01-preview/containerregistry"
+	"github.com/Azure/azure-sdk-for-go/services/preview/policyinsights/mgmt/2019-10-01-preview/policyinsights"
+	"github.com/Azure/azure-sdk-for-go/services/preview/postgresql/mgmt/2020-11-05-preview/postgresqlflexibleservers"
+	"github.com/Azure/azure-sdk-for-go/services/preview/security/mgmt/v3.0/security"
+	"github.com/Azure/azure-sdk-for-go/services/privatedns/mgmt/2018-09-01/privatedns"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-06-01/subscriptions"
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
 	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2019-08-01/web"
@@ -108,6 +117,25 @@ func CreateManagedClustersClientE(subscriptionID string) (containerservice.Manag
 	return containerservice.NewManagedClustersClientWithBaseURI(baseURI, subscriptionID), nil
 }
 
+// CreateAgentPoolsClientE returns an AKS agent pools (node pools) client instance configured with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateAgentPoolsClientE(subscriptionID string) (containerservice.AgentPoolsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return containerservice.AgentPoolsClient{}, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return containerservice.AgentPoolsClient{}, err
+	}
+
+	// Create correct client based on type passed
+	return containerservice.NewAgentPoolsClientWithBaseURI(baseURI, subscriptionID), nil
+}
+
 // CreateCosmosDBAccountClientE is a helper function that will setup a CosmosDB account client with the correct BaseURI depending on
 // the Azure environment that is currently setup (or "Public", if none is setup).
 func CreateCosmosDBAccountClientE(subscriptionID string) (*documentdb.DatabaseAccountsClient, error) {
@@ -150,6 +178,46 @@ func CreateCosmosDBSQLClientE(subscriptionID string) (*documentdb.SQLResourcesCl
 	return &cosmosClient, nil
 }
 
+// CreateRoleAssignmentsClientE is a helper function that will setup a role assignments client with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateRoleAssignmentsClientE(subscriptionID string) (*authorization.RoleAssignmentsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	client := authorization.NewRoleAssignmentsClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
+// CreateRoleDefinitionsClientE is a helper function that will setup a role definitions client with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateRoleDefinitionsClientE(subscriptionID string) (*authorization.RoleDefinitionsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	client := authorization.NewRoleDefinitionsClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
 // CreateKeyVaultManagementClientE is a helper function that will setup a key vault management client with the correct BaseURI depending on
 // the Azure environment that is currently setup (or "Public", if none is setup).
 func CreateKeyVaultManagementClientE(subscriptionID string) (*kvmng.VaultsClient, error) {
@@ -267,6 +335,69 @@ func CreateAvailabilitySetClientE(subscriptionID string) (*compute.AvailabilityS
 	return &client, nil
 }
 
+// CreateVirtualMachineScaleSetsClientE returns a virtual machine scale sets client instance configured with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateVirtualMachineScaleSetsClientE(subscriptionID string) (*compute.VirtualMachineScaleSetsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := compute.NewVirtualMachineScaleSetsClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
+// CreateVirtualMachineScaleSetVMsClientE returns a virtual machine scale set instances client instance configured with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateVirtualMachineScaleSetVMsClientE(subscriptionID string) (*compute.VirtualMachineScaleSetVMsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := compute.NewVirtualMachineScaleSetVMsClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
+// CreateVirtualMachineScaleSetRollingUpgradesClientE returns a virtual machine scale set rolling upgrades client instance configured with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateVirtualMachineScaleSetRollingUpgradesClientE(subscriptionID string) (*compute.VirtualMachineScaleSetRollingUpgradesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := compute.NewVirtualMachineScaleSetRollingUpgradesClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
 // CreateResourceGroupClientE gets a resource group client in a subscription
 func CreateResourceGroupClientE(subscriptionID string) (*resources.GroupsClient, error) {
 	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
@@ -348,6 +479,90 @@ func CreateDatabaseClient(subscriptionID string) (*sql.DatabasesClient, error) {
 	return &sqlDBClient, nil
 }
 
+// CreateSQLServerFirewallRulesClientE returns a sql server firewall rules client instance configured with the correct BaseURI depending on the
+// Azure environment that is currently setup (or "Public", if none is setup).
+func CreateSQLServerFirewallRulesClientE(subscriptionID string) (*sql.FirewallRulesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := sql.NewFirewallRulesClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
+// CreatePostgreSQLFlexibleServersClientE returns a PostgreSQL flexible servers client instance configured with the correct BaseURI depending on the
+// Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePostgreSQLFlexibleServersClientE(subscriptionID string) (*postgresqlflexibleservers.ServersClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := postgresqlflexibleservers.NewServersClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
+// CreatePostgreSQLFlexibleDatabasesClientE returns a PostgreSQL flexible server databases client instance configured with the correct BaseURI depending on the
+// Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePostgreSQLFlexibleDatabasesClientE(subscriptionID string) (*postgresqlflexibleservers.DatabasesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := postgresqlflexibleservers.NewDatabasesClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
+// CreatePostgreSQLFlexibleFirewallRulesClientE returns a PostgreSQL flexible server firewall rules client instance configured with the correct BaseURI depending on the
+// Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePostgreSQLFlexibleFirewallRulesClientE(subscriptionID string) (*postgresqlflexibleservers.FirewallRulesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := postgresqlflexibleservers.NewFirewallRulesClientWithBaseURI(baseURI, subscriptionID)
+
+	return &client, nil
+}
+
 // CreateMySQLServerClientE is a helper function that will setup a mysql server client.
 func CreateMySQLServerClientE(subscriptionID string) (*mysql.ServersClient, error) {
 	// Validate Azure subscription ID
@@ -629,6 +844,66 @@ func CreateLoadBalancerClientE(subscriptionID string) (*network.LoadBalancersCli
 	return &client, nil
 }
 
+// CreateApplicationGatewaysClientE returns an application gateways client instance configured with the correct BaseURI depending on
+// the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateApplicationGatewaysClientE(subscriptionID string) (*network.ApplicationGatewaysClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := network.NewApplicationGatewaysClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreateWebApplicationFirewallPoliciesClientE returns a web application firewall policies client instance configured with the correct BaseURI depending on
+// the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateWebApplicationFirewallPoliciesClientE(subscriptionID string) (*network.WebApplicationFirewallPoliciesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := network.NewWebApplicationFirewallPoliciesClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreateMetricsClientE returns an Azure Monitor metrics client instance configured with the correct BaseURI depending on
+// the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateMetricsClientE(subscriptionID string) (*insights.MetricsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getBaseURI()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create correct client based on type passed
+	client := insights.NewMetricsClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
 // CreateNewSubnetClientE returns a Subnet client instance configured with the
 // correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
 func CreateNewSubnetClientE(subscriptionID string) (*network.SubnetsClient, error) {
@@ -649,6 +924,26 @@ func CreateNewSubnetClientE(subscriptionID string) (*network.SubnetsClient, erro
 	return &subnetClient, nil
 }
 
+// CreateRouteTablesClientE returns a Route Tables client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateRouteTablesClientE(subscriptionID string) (*network.RouteTablesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := network.NewRouteTablesClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
 // CreateNewVirtualNetworkClientE returns a Virtual Network client instance configured with the
 // correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
 func CreateNewVirtualNetworkClientE(subscriptionID string) (*network.VirtualNetworksClient, error) {
@@ -711,6 +1006,46 @@ func CreateContainerRegistryClientE(subscriptionID string) (*containerregistry.R
 	return &registryClient, nil
 }
 
+// CreateContainerRegistryTasksClientE returns an ACR Tasks client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateContainerRegistryTasksClientE(subscriptionID string) (*acrtasks.TasksClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := acrtasks.NewTasksClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreateSecuritySubAssessmentsClientE returns a Microsoft Defender for Cloud sub-assessments client instance configured
+// with the correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateSecuritySubAssessmentsClientE(subscriptionID string) (*security.SubAssessmentsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client, sub-assessments are listed by scope so they need no Defender for Cloud location
+	client := security.NewSubAssessmentsClientWithBaseURI(baseURI, subscriptionID, "")
+	return &client, nil
+}
+
 // CreateContainerInstanceClientE returns an ACI client instance configured with the
 // correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
 func CreateContainerInstanceClientE(subscriptionID string) (*containerinstance.ContainerGroupsClient, error) {
@@ -771,6 +1106,147 @@ func CreateFrontDoorFrontendEndpointClientE(subscriptionID string) (*frontdoor.F
 	return &client, nil
 }
 
+// CreateDNSZonesClientE returns a DNS Zones client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateDNSZonesClientE(subscriptionID string) (*dns.ZonesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := dns.NewZonesClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreateDNSRecordSetsClientE returns a DNS Record Sets client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateDNSRecordSetsClientE(subscriptionID string) (*dns.RecordSetsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := dns.NewRecordSetsClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreatePrivateDNSZonesClientE returns a Private DNS Zones client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePrivateDNSZonesClientE(subscriptionID string) (*privatedns.PrivateZonesClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := privatedns.NewPrivateZonesClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreatePrivateDNSRecordSetsClientE returns a Private DNS Record Sets client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePrivateDNSRecordSetsClientE(subscriptionID string) (*privatedns.RecordSetsClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := privatedns.NewRecordSetsClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreatePrivateDNSVirtualNetworkLinksClientE returns a Private DNS Virtual Network Links client instance configured with the
+// correct BaseURI depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePrivateDNSVirtualNetworkLinksClientE(subscriptionID string) (*privatedns.VirtualNetworkLinksClient, error) {
+	// Validate Azure subscription ID
+	subscriptionID, err := getTargetAzureSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := privatedns.NewVirtualNetworkLinksClientWithBaseURI(baseURI, subscriptionID)
+	return &client, nil
+}
+
+// CreatePolicyStatesClientE returns an Azure Policy states client instance configured with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreatePolicyStatesClientE() (*policyinsights.PolicyStatesClient, error) {
+	// Lookup environment URI
+	baseURI, err := getEnvironmentEndpointE(ResourceManagerEndpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := policyinsights.NewPolicyStatesClientWithBaseURI(baseURI)
+	return &client, nil
+}
+
+// CreateLogAnalyticsQueryClientE returns a Log Analytics query client instance configured with the correct BaseURI
+// depending on the Azure environment that is currently setup (or "Public", if none is setup).
+func CreateLogAnalyticsQueryClientE() (*loganalytics.QueryClient, error) {
+	// Lookup environment URI
+	baseURI, err := getLogAnalyticsResourceE()
+	if err != nil {
+		return nil, err
+	}
+
+	// create client
+	client := loganalytics.NewQueryClientWithBaseURI(baseURI + "/v1")
+	return &client, nil
+}
+
+// getLogAnalyticsResourceE returns the Log Analytics API of the configured Azure environment, which is also the
+// resource of its access tokens.
+func getLogAnalyticsResourceE() (string, error) {
+	env, err := autorestAzure.EnvironmentFromName(getDefaultEnvironmentName())
+	if err != nil {
+		return "", err
+	}
+	if env.ResourceIdentifiers.OperationalInsights == autorestAzure.NotAvailable {
+		return "", fmt.Errorf("Log Analytics is not available in the %s environment", env.Name)
+	}
+	return env.ResourceIdentifiers.OperationalInsights, nil
+}
+
 // GetKeyVaultURISuffixE returns the proper KeyVault URI suffix for the configured Azure environment.
 // This function would fail the test if there is an error.
 func GetKeyVaultURISuffixE() (string, error) {
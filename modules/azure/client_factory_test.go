@@ -156,6 +156,123 @@ func TestManagedClustersClientBaseURISetCorrectly(t *testing.T) {
 	}
 }
 
+func TestAgentPoolsClientBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/AgentPoolsClient", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/AgentPoolsClient", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/AgentPoolsClient", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/AgentPoolsClient", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get an agent pools client
+			client, err := CreateAgentPoolsClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, client.BaseURI)
+		})
+	}
+}
+
+func TestVirtualMachineScaleSetClientsBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/VirtualMachineScaleSetClients", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/VirtualMachineScaleSetClients", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/VirtualMachineScaleSetClients", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/VirtualMachineScaleSetClients", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the scale set, instances and rolling upgrades clients
+			scaleSetsClient, err := CreateVirtualMachineScaleSetsClientE("")
+			require.NoError(t, err)
+			vmsClient, err := CreateVirtualMachineScaleSetVMsClientE("")
+			require.NoError(t, err)
+			rollingUpgradesClient, err := CreateVirtualMachineScaleSetRollingUpgradesClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, scaleSetsClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, vmsClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, rollingUpgradesClient.BaseURI)
+		})
+	}
+}
+
+func TestDatabaseServerFirewallClientsBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/DatabaseServerClients", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/DatabaseServerClients", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/DatabaseServerClients", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/DatabaseServerClients", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the sql server firewall and PostgreSQL flexible server clients
+			sqlFirewallClient, err := CreateSQLServerFirewallRulesClientE("")
+			require.NoError(t, err)
+			serversClient, err := CreatePostgreSQLFlexibleServersClientE("")
+			require.NoError(t, err)
+			databasesClient, err := CreatePostgreSQLFlexibleDatabasesClientE("")
+			require.NoError(t, err)
+			firewallClient, err := CreatePostgreSQLFlexibleFirewallRulesClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, sqlFirewallClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, serversClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, databasesClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, firewallClient.BaseURI)
+		})
+	}
+}
+
 func TestCosmosDBAccountClientBaseURISetCorrectly(t *testing.T) {
 	var cases = []struct {
 		CaseName        string
@@ -290,6 +407,234 @@ func TestLoadBalancerClientBaseURISetCorrectly(t *testing.T) {
 	}
 }
 
+func TestApplicationGatewayClientsBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/ApplicationGatewayClients", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/ApplicationGatewayClients", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/ApplicationGatewayClients", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/ApplicationGatewayClients", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the application gateway, WAF policy and metrics clients
+			gatewaysClient, err := CreateApplicationGatewaysClientE("")
+			require.NoError(t, err)
+			policiesClient, err := CreateWebApplicationFirewallPoliciesClientE("")
+			require.NoError(t, err)
+			metricsClient, err := CreateMetricsClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, gatewaysClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, policiesClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, metricsClient.BaseURI)
+		})
+	}
+}
+
+func TestDNSClientsBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/DNSClients", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/DNSClients", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/DNSClients", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/DNSClients", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the public and private DNS clients
+			zonesClient, err := CreateDNSZonesClientE("")
+			require.NoError(t, err)
+			recordSetsClient, err := CreateDNSRecordSetsClientE("")
+			require.NoError(t, err)
+			privateZonesClient, err := CreatePrivateDNSZonesClientE("")
+			require.NoError(t, err)
+			privateRecordSetsClient, err := CreatePrivateDNSRecordSetsClientE("")
+			require.NoError(t, err)
+			linksClient, err := CreatePrivateDNSVirtualNetworkLinksClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, zonesClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, recordSetsClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, privateZonesClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, privateRecordSetsClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, linksClient.BaseURI)
+		})
+	}
+}
+
+func TestLogAnalyticsQueryClientBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/LogAnalyticsQueryClient", govCloudEnvName, "https://api.loganalytics.us/v1"},
+		{"PublicCloud/LogAnalyticsQueryClient", publicCloudEnvName, "https://api.loganalytics.io/v1"},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get a Log Analytics query client
+			client, err := CreateLogAnalyticsQueryClientE()
+			require.NoError(t, err)
+
+			// Check for correct Log Analytics URI
+			assert.Equal(t, tt.ExpectedBaseURI, client.BaseURI)
+		})
+	}
+
+	// Log Analytics queries are not available in the China and German clouds
+	os.Setenv(AzureEnvironmentEnvName, chinaCloudEnvName)
+	_, err := CreateLogAnalyticsQueryClientE()
+	assert.Error(t, err)
+}
+
+func TestContainerRegistryTasksAndSecurityClientsBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/ContainerRegistryTasksAndSecurityClients", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/ContainerRegistryTasksAndSecurityClients", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/ContainerRegistryTasksAndSecurityClients", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/ContainerRegistryTasksAndSecurityClients", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the ACR Tasks and Defender for Cloud sub-assessments clients
+			tasksClient, err := CreateContainerRegistryTasksClientE("")
+			require.NoError(t, err)
+			subAssessmentsClient, err := CreateSecuritySubAssessmentsClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, tasksClient.BaseURI)
+			assert.Equal(t, tt.ExpectedBaseURI, subAssessmentsClient.BaseURI)
+		})
+	}
+}
+
+func TestPolicyStatesClientBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/PolicyStatesClient", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/PolicyStatesClient", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/PolicyStatesClient", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/PolicyStatesClient", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the policy states client
+			client, err := CreatePolicyStatesClientE()
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, client.BaseURI)
+		})
+	}
+}
+
+func TestRouteTablesClientBaseURISetCorrectly(t *testing.T) {
+	var cases = []struct {
+		CaseName        string
+		EnvironmentName string
+		ExpectedBaseURI string
+	}{
+		{"GovCloud/RouteTablesClient", govCloudEnvName, autorest.USGovernmentCloud.ResourceManagerEndpoint},
+		{"PublicCloud/RouteTablesClient", publicCloudEnvName, autorest.PublicCloud.ResourceManagerEndpoint},
+		{"ChinaCloud/RouteTablesClient", chinaCloudEnvName, autorest.ChinaCloud.ResourceManagerEndpoint},
+		{"GermanCloud/RouteTablesClient", germanyCloudEnvName, autorest.GermanCloud.ResourceManagerEndpoint},
+	}
+
+	// save any current env value and restore on exit
+	currentEnv := os.Getenv(AzureEnvironmentEnvName)
+	defer os.Setenv(AzureEnvironmentEnvName, currentEnv)
+
+	for _, tt := range cases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			// Override env setting
+			os.Setenv(AzureEnvironmentEnvName, tt.EnvironmentName)
+
+			// Get the route tables client
+			client, err := CreateRouteTablesClientE("")
+			require.NoError(t, err)
+
+			// Check for correct ARM URI
+			assert.Equal(t, tt.ExpectedBaseURI, client.BaseURI)
+		})
+	}
+}
+
 func TestFrontDoorClientBaseURISetCorrectly(t *testing.T) {
 	var cases = []struct {
 		CaseName        string
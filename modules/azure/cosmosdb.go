@@ -1,13 +1,30 @@
 package azure
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/preview/cosmos-db/mgmt/documentdb"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
 
+// cosmosDBAPIVersion is the version of the Cosmos DB SQL API REST interface used for the document roundtrip.
+const cosmosDBAPIVersion = "2018-12-31"
+
 // GetCosmosDBAccountClientE is a helper function that will setup a CosmosDB account client.
 func GetCosmosDBAccountClientE(subscriptionID string) (*documentdb.DatabaseAccountsClient, error) {
 
@@ -195,3 +212,306 @@ func GetCosmosDBSQLContainerThroughputE(t testing.TestingT, subscriptionID strin
 	//Return throughput config
 	return &cosmosSQLCtrThroughput, nil
 }
+
+// AssertCosmosDBConsistencyLevel checks that the default consistency level of a database account is the expected one.
+// This function would fail the test if it is not.
+func AssertCosmosDBConsistencyLevel(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, expectedLevel documentdb.DefaultConsistencyLevel) {
+	err := AssertCosmosDBConsistencyLevelE(t, subscriptionID, resourceGroupName, accountName, expectedLevel)
+	require.NoError(t, err)
+}
+
+// AssertCosmosDBConsistencyLevelE checks that the default consistency level of a database account is the expected one.
+func AssertCosmosDBConsistencyLevelE(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, expectedLevel documentdb.DefaultConsistencyLevel) error {
+	cosmosDBAccount, err := GetCosmosDBAccountE(t, subscriptionID, resourceGroupName, accountName)
+	if err != nil {
+		return err
+	}
+	return checkCosmosDBConsistencyLevel(cosmosDBAccount, expectedLevel)
+}
+
+// checkCosmosDBConsistencyLevel returns an error if the default consistency level of the given database account is not
+// the expected one.
+func checkCosmosDBConsistencyLevel(cosmosDBAccount *documentdb.DatabaseAccountGetResults, expectedLevel documentdb.DefaultConsistencyLevel) error {
+	var level documentdb.DefaultConsistencyLevel
+	if cosmosDBAccount.DatabaseAccountGetProperties != nil && cosmosDBAccount.ConsistencyPolicy != nil {
+		level = cosmosDBAccount.ConsistencyPolicy.DefaultConsistencyLevel
+	}
+	if level != expectedLevel {
+		return fmt.Errorf("database account %s has consistency level %q rather than %q", to.String(cosmosDBAccount.Name), level, expectedLevel)
+	}
+	return nil
+}
+
+// AssertCosmosDBReplication checks that a database account is replicated to exactly the expected locations, and that
+// it accepts writes in all of them or only in the primary one. This function would fail the test if it does not.
+func AssertCosmosDBReplication(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, expectedLocations []string, multipleWriteLocations bool) {
+	err := AssertCosmosDBReplicationE(t, subscriptionID, resourceGroupName, accountName, expectedLocations, multipleWriteLocations)
+	require.NoError(t, err)
+}
+
+// AssertCosmosDBReplicationE checks that a database account is replicated to exactly the expected locations (e.g.,
+// "East US" or eastus), and that multi-region writes are enabled or not. The first expected location must be the
+// write location of the account, or the one with failover priority 0 if multi-region writes are enabled.
+func AssertCosmosDBReplicationE(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, expectedLocations []string, multipleWriteLocations bool) error {
+	cosmosDBAccount, err := GetCosmosDBAccountE(t, subscriptionID, resourceGroupName, accountName)
+	if err != nil {
+		return err
+	}
+	return checkCosmosDBReplication(cosmosDBAccount, expectedLocations, multipleWriteLocations)
+}
+
+// checkCosmosDBReplication returns an error if the given database account is not replicated to exactly the expected
+// locations, with the first one as primary, or if multi-region writes are not enabled as expected.
+func checkCosmosDBReplication(cosmosDBAccount *documentdb.DatabaseAccountGetResults, expectedLocations []string, multipleWriteLocations bool) error {
+	name := to.String(cosmosDBAccount.Name)
+	if cosmosDBAccount.DatabaseAccountGetProperties == nil {
+		return fmt.Errorf("database account %s has no properties", name)
+	}
+	if to.Bool(cosmosDBAccount.EnableMultipleWriteLocations) != multipleWriteLocations {
+		return fmt.Errorf("database account %s has multiple write locations set to %t rather than %t", name, to.Bool(cosmosDBAccount.EnableMultipleWriteLocations), multipleWriteLocations)
+	}
+
+	actual := []string{}
+	primary := ""
+	if cosmosDBAccount.Locations != nil {
+		for _, location := range *cosmosDBAccount.Locations {
+			actual = append(actual, normalizeCosmosDBLocation(to.String(location.LocationName)))
+			if to.Int32(location.FailoverPriority) == 0 {
+				primary = normalizeCosmosDBLocation(to.String(location.LocationName))
+			}
+		}
+	}
+	expected := []string{}
+	for _, location := range expectedLocations {
+		expected = append(expected, normalizeCosmosDBLocation(location))
+	}
+	sort.Strings(actual)
+	sort.Strings(expected)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		return fmt.Errorf("database account %s is replicated to %v rather than %v", name, actual, expected)
+	}
+	if len(expected) > 0 && primary != normalizeCosmosDBLocation(expectedLocations[0]) {
+		return fmt.Errorf("database account %s has primary location %s rather than %s", name, primary, normalizeCosmosDBLocation(expectedLocations[0]))
+	}
+	return nil
+}
+
+// normalizeCosmosDBLocation returns the given location as a name such as eastus, as database accounts return display
+// names such as East US.
+func normalizeCosmosDBLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+// AssertCosmosDBSQLContainer checks that a SQL container is partitioned with the expected partition key path and has
+// the expected throughput. This function would fail the test if it does not.
+func AssertCosmosDBSQLContainer(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, databaseName string, containerName string, partitionKeyPath string, throughput int32) {
+	err := AssertCosmosDBSQLContainerE(t, subscriptionID, resourceGroupName, accountName, databaseName, containerName, partitionKeyPath, throughput)
+	require.NoError(t, err)
+}
+
+// AssertCosmosDBSQLContainerE checks that a SQL container is partitioned with the expected partition key path (e.g.,
+// /tenantId) and has the expected provisioned throughput in RU/s. The throughput is not checked if it is 0, e.g., for
+// containers that share the throughput of their database.
+func AssertCosmosDBSQLContainerE(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, databaseName string, containerName string, partitionKeyPath string, throughput int32) error {
+	cosmosSQLContainer, err := GetCosmosDBSQLContainerE(t, subscriptionID, resourceGroupName, accountName, databaseName, containerName)
+	if err != nil {
+		return err
+	}
+	if err := checkCosmosDBSQLContainerPartitionKey(cosmosSQLContainer, partitionKeyPath); err != nil {
+		return err
+	}
+	if throughput == 0 {
+		return nil
+	}
+
+	cosmosSQLCtrThroughput, err := GetCosmosDBSQLContainerThroughputE(t, subscriptionID, resourceGroupName, accountName, databaseName, containerName)
+	if err != nil {
+		return err
+	}
+	return checkCosmosDBThroughput(cosmosSQLCtrThroughput, containerName, throughput)
+}
+
+// checkCosmosDBSQLContainerPartitionKey returns an error if the given SQL container is not partitioned with the
+// expected partition key path.
+func checkCosmosDBSQLContainerPartitionKey(cosmosSQLContainer *documentdb.SQLContainerGetResults, partitionKeyPath string) error {
+	paths := []string{}
+	if cosmosSQLContainer.SQLContainerGetProperties != nil && cosmosSQLContainer.Resource != nil && cosmosSQLContainer.Resource.PartitionKey != nil && cosmosSQLContainer.Resource.PartitionKey.Paths != nil {
+		paths = *cosmosSQLContainer.Resource.PartitionKey.Paths
+	}
+	if len(paths) != 1 || paths[0] != partitionKeyPath {
+		return fmt.Errorf("SQL container %s has partition key paths %v rather than [%s]", to.String(cosmosSQLContainer.Name), paths, partitionKeyPath)
+	}
+	return nil
+}
+
+// checkCosmosDBThroughput returns an error if the given throughput settings don't have the expected throughput.
+func checkCosmosDBThroughput(throughputSettings *documentdb.ThroughputSettingsGetResults, resourceName string, throughput int32) error {
+	var actual int32
+	if throughputSettings.ThroughputSettingsGetProperties != nil && throughputSettings.Resource != nil {
+		actual = to.Int32(throughputSettings.Resource.Throughput)
+	}
+	if actual != throughput {
+		return fmt.Errorf("%s has a throughput of %d RU/s rather than %d RU/s", resourceName, actual, throughput)
+	}
+	return nil
+}
+
+// AssertCosmosDBSQLDocumentRoundtrip checks that a document can be written to a SQL container and read back.
+// This function would fail the test if it can't.
+func AssertCosmosDBSQLDocumentRoundtrip(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, databaseName string, containerName string, partitionKeyPath string, useAAD bool) {
+	err := AssertCosmosDBSQLDocumentRoundtripE(t, subscriptionID, resourceGroupName, accountName, databaseName, containerName, partitionKeyPath, useAAD)
+	require.NoError(t, err)
+}
+
+// AssertCosmosDBSQLDocumentRoundtripE writes a document with a random ID and partition key value (under the given
+// partition key path, e.g., /tenantId) to a SQL container, reads it back, and deletes it. If useAAD is true, the
+// requests authenticate with Azure AD, which checks that the caller has a Cosmos DB data plane role assignment (e.g.,
+// Cosmos DB Built-in Data Contributor) on the account; otherwise they authenticate with the primary key of the account.
+func AssertCosmosDBSQLDocumentRoundtripE(t testing.TestingT, subscriptionID string, resourceGroupName string, accountName string, databaseName string, containerName string, partitionKeyPath string, useAAD bool) error {
+	cosmosDBAccount, err := GetCosmosDBAccountE(t, subscriptionID, resourceGroupName, accountName)
+	if err != nil {
+		return err
+	}
+	if cosmosDBAccount.DatabaseAccountGetProperties == nil || cosmosDBAccount.DocumentEndpoint == nil {
+		return fmt.Errorf("database account %s has no document endpoint", accountName)
+	}
+	endpoint := strings.TrimSuffix(*cosmosDBAccount.DocumentEndpoint, "/")
+
+	var authorize func(verb, resourceType, resourceLink, date string) string
+	if useAAD {
+		token, err := getCosmosDBAADTokenE(endpoint)
+		if err != nil {
+			return err
+		}
+		authorize = func(verb, resourceType, resourceLink, date string) string {
+			return url.QueryEscape("type=aad&ver=1.0&sig=" + token)
+		}
+	} else {
+		cosmosClient, err := GetCosmosDBAccountClientE(subscriptionID)
+		if err != nil {
+			return err
+		}
+		keys, err := cosmosClient.ListKeys(context.Background(), resourceGroupName, accountName)
+		if err != nil {
+			return err
+		}
+		masterKey := to.String(keys.PrimaryMasterKey)
+		authorize = func(verb, resourceType, resourceLink, date string) string {
+			return newCosmosDBMasterKeyAuthorization(masterKey, verb, resourceType, resourceLink, date)
+		}
+	}
+
+	id := random.UniqueId()
+	partitionKeyValue := random.UniqueId()
+	document, err := newCosmosDBDocument(id, partitionKeyPath, partitionKeyValue)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	containerLink := fmt.Sprintf("dbs/%s/colls/%s", databaseName, containerName)
+	documentLink := fmt.Sprintf("%s/docs/%s", containerLink, id)
+	request := func(verb, resourceType, resourceLink, path string, body []byte, expectedStatus int) ([]byte, error) {
+		return doCosmosDBRequestE(endpoint, verb, resourceType, resourceLink, path, body, partitionKeyValue, authorize, expectedStatus)
+	}
+
+	if _, err := request(http.MethodPost, "docs", containerLink, containerLink+"/docs", body, http.StatusCreated); err != nil {
+		return err
+	}
+	readBody, err := request(http.MethodGet, "docs", documentLink, documentLink, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var readDocument map[string]interface{}
+	if err := json.Unmarshal(readBody, &readDocument); err != nil {
+		return err
+	}
+	if readDocument["id"] != id {
+		return fmt.Errorf("read document %v from SQL container %s rather than the written document %s", readDocument["id"], containerName, id)
+	}
+	_, err = request(http.MethodDelete, "docs", documentLink, documentLink, nil, http.StatusNoContent)
+	return err
+}
+
+// newCosmosDBDocument returns a document with the given ID and the given partition key value at the given partition key
+// path, which may be nested (e.g., /address/zipCode).
+func newCosmosDBDocument(id string, partitionKeyPath string, partitionKeyValue string) (map[string]interface{}, error) {
+	document := map[string]interface{}{"id": id}
+	fields := strings.Split(strings.TrimPrefix(partitionKeyPath, "/"), "/")
+	if len(fields) == 0 || fields[0] == "" {
+		return nil, fmt.Errorf("invalid partition key path %q", partitionKeyPath)
+	}
+	if partitionKeyPath == "/id" {
+		return nil, fmt.Errorf("partition key path /id is not supported, as the partition key value must be random")
+	}
+
+	current := document
+	for _, field := range fields[:len(fields)-1] {
+		next := map[string]interface{}{}
+		current[field] = next
+		current = next
+	}
+	current[fields[len(fields)-1]] = partitionKeyValue
+	return document, nil
+}
+
+// newCosmosDBMasterKeyAuthorization returns the value of the authorization header of a Cosmos DB SQL API request signed
+// with the given account key.
+// See https://docs.microsoft.com/en-us/rest/api/cosmos-db/access-control-on-cosmosdb-resources
+func newCosmosDBMasterKeyAuthorization(masterKey string, verb string, resourceType string, resourceLink string, date string) string {
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		// An invalid key can't sign requests, so let the service reject them.
+		key = []byte(masterKey)
+	}
+	payload := strings.ToLower(verb) + "\n" + strings.ToLower(resourceType) + "\n" + resourceLink + "\n" + strings.ToLower(date) + "\n\n"
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return url.QueryEscape("type=master&ver=1.0&sig=" + signature)
+}
+
+// getCosmosDBAADTokenE returns an Azure AD access token for the database account with the given document endpoint.
+func getCosmosDBAADTokenE(endpoint string) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	return getAccessTokenE(AuthOptions{}, endpointURL.Scheme+"://"+endpointURL.Hostname())
+}
+
+// doCosmosDBRequestE sends a request for a resource of a document in the given partition to the Cosmos DB SQL API, and
+// returns the body of the response if it has the expected status.
+func doCosmosDBRequestE(endpoint string, verb string, resourceType string, resourceLink string, path string, body []byte, partitionKeyValue string, authorize func(verb, resourceType, resourceLink, date string) string, expectedStatus int) ([]byte, error) {
+	req, err := http.NewRequest(verb, endpoint+"/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	partitionKey, err := json.Marshal([]string{partitionKeyValue})
+	if err != nil {
+		return nil, err
+	}
+	date := time.Now().UTC().Format(http.TimeFormat)
+	req.Header.Set("Authorization", authorize(verb, resourceType, resourceLink, date))
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("x-ms-version", cosmosDBAPIVersion)
+	req.Header.Set("x-ms-documentdb-partitionkey", string(partitionKey))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != expectedStatus {
+		return nil, fmt.Errorf("%s %s returned status %d rather than %d: %s", verb, resourceLink, resp.StatusCode, expectedStatus, string(respBody))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,98 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/preview/cosmos-db/mgmt/documentdb"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCosmosDBConsistencyLevel(t *testing.T) {
+	t.Parallel()
+
+	account := &documentdb.DatabaseAccountGetResults{
+		Name: to.StringPtr("account"),
+		DatabaseAccountGetProperties: &documentdb.DatabaseAccountGetProperties{
+			ConsistencyPolicy: &documentdb.ConsistencyPolicy{DefaultConsistencyLevel: documentdb.Session},
+		},
+	}
+	assert.NoError(t, checkCosmosDBConsistencyLevel(account, documentdb.Session))
+	assert.Error(t, checkCosmosDBConsistencyLevel(account, documentdb.Strong))
+}
+
+func TestCheckCosmosDBReplication(t *testing.T) {
+	t.Parallel()
+
+	account := &documentdb.DatabaseAccountGetResults{
+		Name: to.StringPtr("account"),
+		DatabaseAccountGetProperties: &documentdb.DatabaseAccountGetProperties{
+			Locations: &[]documentdb.Location{
+				{LocationName: to.StringPtr("West US"), FailoverPriority: to.Int32Ptr(1)},
+				{LocationName: to.StringPtr("East US"), FailoverPriority: to.Int32Ptr(0)},
+			},
+		},
+	}
+	assert.NoError(t, checkCosmosDBReplication(account, []string{"eastus", "West US"}, false))
+	assert.Error(t, checkCosmosDBReplication(account, []string{"westus", "eastus"}, false))
+	assert.Error(t, checkCosmosDBReplication(account, []string{"eastus"}, false))
+	assert.Error(t, checkCosmosDBReplication(account, []string{"eastus", "westus"}, true))
+}
+
+func TestCheckCosmosDBSQLContainerPartitionKey(t *testing.T) {
+	t.Parallel()
+
+	container := &documentdb.SQLContainerGetResults{
+		Name: to.StringPtr("container"),
+		SQLContainerGetProperties: &documentdb.SQLContainerGetProperties{
+			Resource: &documentdb.SQLContainerGetPropertiesResource{
+				PartitionKey: &documentdb.ContainerPartitionKey{Paths: &[]string{"/tenantId"}},
+			},
+		},
+	}
+	assert.NoError(t, checkCosmosDBSQLContainerPartitionKey(container, "/tenantId"))
+	assert.Error(t, checkCosmosDBSQLContainerPartitionKey(container, "/userId"))
+
+	throughput := &documentdb.ThroughputSettingsGetResults{
+		ThroughputSettingsGetProperties: &documentdb.ThroughputSettingsGetProperties{
+			Resource: &documentdb.ThroughputSettingsGetPropertiesResource{Throughput: to.Int32Ptr(400)},
+		},
+	}
+	assert.NoError(t, checkCosmosDBThroughput(throughput, "container", 400))
+	assert.Error(t, checkCosmosDBThroughput(throughput, "container", 1000))
+}
+
+func TestNewCosmosDBDocument(t *testing.T) {
+	t.Parallel()
+
+	document, err := newCosmosDBDocument("doc", "/address/zipCode", "12345")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "doc", "address": map[string]interface{}{"zipCode": "12345"}}, document)
+
+	_, err = newCosmosDBDocument("doc", "", "12345")
+	assert.Error(t, err)
+}
+
+func TestNewCosmosDBMasterKeyAuthorization(t *testing.T) {
+	t.Parallel()
+
+	// Example from the Cosmos DB REST API documentation
+	authorization := newCosmosDBMasterKeyAuthorization(
+		"dsZQi3KtZmCv1ljt3VNWNm7sQUF1y5rJfC6kv5JiwvW0EndXdDku/dkKBp8/ufDToSxLzR4y+O/0H/t4bQtVNw==",
+		"GET",
+		"dbs",
+		"dbs/ToDoList",
+		"Thu, 27 Apr 2017 00:51:12 GMT",
+	)
+	decoded, err := url.QueryUnescape(authorization)
+	require.NoError(t, err)
+	assert.Equal(t, "type=master&ver=1.0&sig=c09PEVJrgp2uQRkr934kFbTqhByc7TVr3OHyqlu+c+c=", decoded)
+}
@@ -0,0 +1,274 @@
+package azure
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	autorestAzure "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// DatabaseEngineSQLServer is the engine of Azure SQL databases, for use in DatabaseConnectionOptions.
+	DatabaseEngineSQLServer = "sqlserver"
+	// DatabaseEnginePostgres is the engine of Azure Database for PostgreSQL servers, for use in
+	// DatabaseConnectionOptions.
+	DatabaseEnginePostgres = "postgres"
+	// DatabaseEngineMySQL is the engine of Azure Database for MySQL servers, for use in DatabaseConnectionOptions.
+	DatabaseEngineMySQL = "mysql"
+
+	// sqlServerAADDriverName is the name the Azure AD aware driver of github.com/denisenkom/go-mssqldb/azuread
+	// registers as.
+	sqlServerAADDriverName = "azuresql"
+)
+
+// DatabaseConnectionOptions are the options to connect to an Azure database with ConnectAndQuery. Set UseAAD to
+// authenticate with Azure AD, with the same credentials as the other functions of this package, rather than a password.
+type DatabaseConnectionOptions struct {
+	Engine   string // The engine of the database: DatabaseEngineSQLServer, DatabaseEnginePostgres or DatabaseEngineMySQL
+	Host     string // The fully qualified domain name of the server
+	Port     int    // The port of the server
+	Username string // The login, or the name of the Azure AD user, group or managed identity if UseAAD is set
+	Password string // The password of the login, ignored if UseAAD is set
+	Database string // The name of the database to connect to (optional)
+	UseAAD   bool   // Authenticate with an Azure AD access token rather than the password
+
+	// How to get the Azure AD access token for PostgreSQL and MySQL if UseAAD is set. Defaults to the credential chain
+	// of NewAuthorizer. The SQL Server driver gets the token itself, with the DefaultAzureCredential of the Azure SDK.
+	AuthOptions AuthOptions
+
+	// The name of the database/sql driver to use. Defaults to the engine, or "azuresql" for SQL Server with UseAAD set.
+	// Terratest only registers a MySQL driver, so import a driver in your test that registers as this name (e.g.,
+	// github.com/lib/pq for PostgreSQL, github.com/denisenkom/go-mssqldb for SQL Server, or
+	// github.com/denisenkom/go-mssqldb/azuread for SQL Server with UseAAD set).
+	DriverName string
+}
+
+// ConnectAndQuery connects to the database with the given options, runs the given query, and returns the rows it
+// returns, with each column formatted as a string. This will fail the test if there is an error.
+func ConnectAndQuery(t testing.TestingT, options DatabaseConnectionOptions, query string, args ...interface{}) [][]string {
+	rows, err := ConnectAndQueryE(t, options, query, args...)
+	require.NoError(t, err)
+	return rows
+}
+
+// ConnectAndQueryE connects to the database with the given options, runs the given query, and returns the rows it
+// returns, with each column formatted as a string (NULL values are returned as empty strings). The firewall of the
+// server must allow the test runner (see AssertSQLServerFirewallAllowsIPE), or it must run in the virtual network of a
+// private server.
+func ConnectAndQueryE(t testing.TestingT, options DatabaseConnectionOptions, query string, args ...interface{}) ([][]string, error) {
+	password := options.Password
+	if options.UseAAD && options.Engine != DatabaseEngineSQLServer {
+		// PostgreSQL and MySQL take the Azure AD access token as the password, while the SQL Server driver gets it
+		// itself.
+		token, err := getOSSRDBMSAccessTokenE(options.AuthOptions)
+		if err != nil {
+			return nil, err
+		}
+		password = token
+	}
+
+	driverName := options.DriverName
+	if driverName == "" {
+		driverName = options.Engine
+		if options.UseAAD && options.Engine == DatabaseEngineSQLServer {
+			driverName = sqlServerAADDriverName
+		}
+	}
+	dataSourceName, err := formatDatabaseDataSourceName(options, password)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logf(t, "Running query on %s database %s at %s:%d", options.Engine, options.Database, options.Host, options.Port)
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRowsAsStrings(rows)
+}
+
+// getOSSRDBMSAccessTokenE returns an Azure AD access token for Azure Database for PostgreSQL and MySQL servers in the
+// configured Azure environment, authenticating with the given options.
+func getOSSRDBMSAccessTokenE(authOptions AuthOptions) (string, error) {
+	env, err := autorestAzure.EnvironmentFromName(getDefaultEnvironmentName())
+	if err != nil {
+		return "", err
+	}
+	return getAccessTokenE(authOptions, env.ResourceIdentifiers.OSSRDBMS)
+}
+
+// formatDatabaseDataSourceName returns the data source name to connect to the database with the given options and
+// password with the driver of their engine. Connections are always encrypted, as Azure requires it by default.
+func formatDatabaseDataSourceName(options DatabaseConnectionOptions, password string) (string, error) {
+	address := net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+	switch options.Engine {
+	case DatabaseEngineSQLServer:
+		query := url.Values{}
+		query.Set("encrypt", "true")
+		if options.Database != "" {
+			query.Set("database", options.Database)
+		}
+		dataSourceURL := url.URL{Scheme: "sqlserver", Host: address}
+		if options.UseAAD {
+			query.Set("fedauth", "ActiveDirectoryDefault")
+		} else {
+			dataSourceURL.User = url.UserPassword(options.Username, password)
+		}
+		dataSourceURL.RawQuery = query.Encode()
+		return dataSourceURL.String(), nil
+	case DatabaseEnginePostgres:
+		dataSourceURL := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(options.Username, password),
+			Host:     address,
+			Path:     "/" + options.Database,
+			RawQuery: "sslmode=require",
+		}
+		return dataSourceURL.String(), nil
+	case DatabaseEngineMySQL:
+		config := mysql.NewConfig()
+		config.User = options.Username
+		config.Passwd = password
+		config.Net = "tcp"
+		config.Addr = address
+		config.DBName = options.Database
+		config.TLSConfig = "true"
+		// Azure AD access tokens are sent as cleartext passwords, which is safe over TLS.
+		config.AllowCleartextPasswords = options.UseAAD
+		return config.FormatDSN(), nil
+	default:
+		return "", UnsupportedDatabaseEngine{Engine: options.Engine}
+	}
+}
+
+// scanRowsAsStrings reads all the given rows, formatting each column as a string.
+func scanRowsAsStrings(rows *sql.Rows) ([][]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := [][]string{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make([]string, len(columns))
+		for i, value := range values {
+			row[i] = value.String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// firewallRangeAllowsIP returns whether the given IP address is in the range of a firewall rule with the given start
+// and end IP addresses.
+func firewallRangeAllowsIP(startIPAddress string, endIPAddress string, ipAddress string) (bool, error) {
+	start, end, ip := net.ParseIP(startIPAddress).To4(), net.ParseIP(endIPAddress).To4(), net.ParseIP(ipAddress).To4()
+	if start == nil || end == nil {
+		return false, fmt.Errorf("invalid firewall rule range %s - %s", startIPAddress, endIPAddress)
+	}
+	if ip == nil {
+		return false, fmt.Errorf("invalid IPv4 address %q", ipAddress)
+	}
+	for i := range ip {
+		if ip[i] != start[i] {
+			if ip[i] < start[i] {
+				return false, nil
+			}
+			break
+		}
+	}
+	for i := range ip {
+		if ip[i] != end[i] {
+			if ip[i] > end[i] {
+				return false, nil
+			}
+			break
+		}
+	}
+	return true, nil
+}
+
+// AssertDatabaseHostResolvesToPrivateIP checks that the given database host name resolves to private IP addresses,
+// i.e., through the private DNS zone of a private endpoint or a private server. This function would fail the test if
+// there is an error or it does not.
+func AssertDatabaseHostResolvesToPrivateIP(t testing.TestingT, host string) {
+	err := AssertDatabaseHostResolvesToPrivateIPE(t, host)
+	require.NoError(t, err)
+}
+
+// AssertDatabaseHostResolvesToPrivateIPE checks that the given database host name resolves to private IP addresses,
+// i.e., through the private DNS zone of a private endpoint or a private server. This only holds when the test runs in
+// (or is peered with) the virtual network linked to the zone.
+func AssertDatabaseHostResolvesToPrivateIPE(t testing.TestingT, host string) error {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+	logger.Logf(t, "Database host %s resolves to %v", host, ips)
+	return checkIPsArePrivate(host, ips)
+}
+
+// checkIPsArePrivate returns an error if the given host name resolved to no IP address or to a public one.
+func checkIPsArePrivate(host string, ips []net.IP) error {
+	if len(ips) == 0 {
+		return fmt.Errorf("database host %s resolves to no IP address", host)
+	}
+	for _, ip := range ips {
+		if !ip.IsPrivate() {
+			return fmt.Errorf("database host %s resolves to public IP address %s", host, ip)
+		}
+	}
+	return nil
+}
+
+// AssertDatabasePortReachable checks that a TCP connection can be opened to the given port of the given database host
+// within the given timeout. This function would fail the test if there is an error.
+func AssertDatabasePortReachable(t testing.TestingT, host string, port int, timeout time.Duration) {
+	err := AssertDatabasePortReachableE(t, host, port, timeout)
+	require.NoError(t, err)
+}
+
+// AssertDatabasePortReachableE checks that a TCP connection can be opened to the given port of the given database host
+// within the given timeout, which tells network issues apart from authentication issues when ConnectAndQueryE fails.
+func AssertDatabasePortReachableE(t testing.TestingT, host string, port int, timeout time.Duration) error {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	logger.Logf(t, "Connected to database at %s", address)
+	return conn.Close()
+}
+
+// UnsupportedDatabaseEngine is an error that occurs when DatabaseConnectionOptions has an unknown engine.
+type UnsupportedDatabaseEngine struct {
+	Engine string
+}
+
+func (err UnsupportedDatabaseEngine) Error() string {
+	return fmt.Sprintf("Unsupported database engine %q: expected %q, %q or %q", err.Engine, DatabaseEngineSQLServer, DatabaseEnginePostgres, DatabaseEngineMySQL)
+}
@@ -0,0 +1,853 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+	"github.com/Azure/azure-sdk-for-go/services/privatedns/mgmt/2018-09-01/privatedns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// runShellScriptCommandID is the ID of the run command that runs a shell script on a Linux virtual machine.
+const runShellScriptCommandID = "RunShellScript"
+
+// dnsNamePattern matches the DNS names and name servers that can be safely passed to a shell script.
+var dnsNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.:-]*$`)
+
+// dnsRecordTypePattern matches the DNS record types that can be safely passed to a shell script.
+var dnsRecordTypePattern = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// GetDNSZonesClientE is a helper function that will setup a DNS zones client.
+func GetDNSZonesClientE(subscriptionID string) (*dns.ZonesClient, error) {
+	// Create a DNS zones client
+	client, err := CreateDNSZonesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an authorizer
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetDNSRecordSetsClientE is a helper function that will setup a DNS record sets client.
+func GetDNSRecordSetsClientE(subscriptionID string) (*dns.RecordSetsClient, error) {
+	client, err := CreateDNSRecordSetsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetPrivateDNSZonesClientE is a helper function that will setup a private DNS zones client.
+func GetPrivateDNSZonesClientE(subscriptionID string) (*privatedns.PrivateZonesClient, error) {
+	client, err := CreatePrivateDNSZonesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetPrivateDNSRecordSetsClientE is a helper function that will setup a private DNS record sets client.
+func GetPrivateDNSRecordSetsClientE(subscriptionID string) (*privatedns.RecordSetsClient, error) {
+	client, err := CreatePrivateDNSRecordSetsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetPrivateDNSVirtualNetworkLinksClientE is a helper function that will setup a private DNS virtual network links
+// client.
+func GetPrivateDNSVirtualNetworkLinksClientE(subscriptionID string) (*privatedns.VirtualNetworkLinksClient, error) {
+	client, err := CreatePrivateDNSVirtualNetworkLinksClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// DNSZoneExists indicates whether the specified public DNS zone exists.
+// This function would fail the test if there is an error.
+func DNSZoneExists(t testing.TestingT, zoneName string, resourceGroupName string, subscriptionID string) bool {
+	exists, err := DNSZoneExistsE(zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return exists
+}
+
+// DNSZoneExistsE indicates whether the specified public DNS zone exists.
+func DNSZoneExistsE(zoneName string, resourceGroupName string, subscriptionID string) (bool, error) {
+	_, err := GetDNSZoneE(zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		if ResourceNotFoundErrorExists(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDNSZone gets the public DNS zone.
+// This function would fail the test if there is an error.
+func GetDNSZone(t testing.TestingT, zoneName string, resourceGroupName string, subscriptionID string) *dns.Zone {
+	zone, err := GetDNSZoneE(zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return zone
+}
+
+// GetDNSZoneE gets the public DNS zone.
+func GetDNSZoneE(zoneName string, resourceGroupName string, subscriptionID string) (*dns.Zone, error) {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetDNSZonesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := client.Get(context.Background(), resourceGroupName, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+// GetDNSZoneNameServers gets the Azure name servers of the public DNS zone, which its parent domain must delegate to.
+// This function would fail the test if there is an error.
+func GetDNSZoneNameServers(t testing.TestingT, zoneName string, resourceGroupName string, subscriptionID string) []string {
+	nameServers, err := GetDNSZoneNameServersE(zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return nameServers
+}
+
+// GetDNSZoneNameServersE gets the Azure name servers of the public DNS zone, which its parent domain must delegate to.
+func GetDNSZoneNameServersE(zoneName string, resourceGroupName string, subscriptionID string) ([]string, error) {
+	zone, err := GetDNSZoneE(zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if zone.ZoneProperties == nil || zone.NameServers == nil || len(*zone.NameServers) == 0 {
+		return nil, fmt.Errorf("DNS zone %s has no name servers", zoneName)
+	}
+	return *zone.NameServers, nil
+}
+
+// DNSRecordSetExists indicates whether the record set of the given name and type exists in the public DNS zone.
+// This function would fail the test if there is an error.
+func DNSRecordSetExists(t testing.TestingT, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) bool {
+	exists, err := DNSRecordSetExistsE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return exists
+}
+
+// DNSRecordSetExistsE indicates whether the record set of the given name and type exists in the public DNS zone.
+func DNSRecordSetExistsE(recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) (bool, error) {
+	_, err := GetDNSRecordSetE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		if ResourceNotFoundErrorExists(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDNSRecordSet gets the record set of the given name and type in the public DNS zone. Use "@" as the name of the
+// record set at the apex of the zone.
+// This function would fail the test if there is an error.
+func GetDNSRecordSet(t testing.TestingT, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) *dns.RecordSet {
+	recordSet, err := GetDNSRecordSetE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return recordSet
+}
+
+// GetDNSRecordSetE gets the record set of the given name and type in the public DNS zone. Use "@" as the name of the
+// record set at the apex of the zone.
+func GetDNSRecordSetE(recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) (*dns.RecordSet, error) {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetDNSRecordSetsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordSet, err := client.Get(context.Background(), resourceGroupName, zoneName, recordSetName, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordSet, nil
+}
+
+// GetDNSRecordSetValues gets the values of the records in the record set of the public DNS zone, formatted as
+// documented in getDNSRecordSetValues.
+// This function would fail the test if there is an error.
+func GetDNSRecordSetValues(t testing.TestingT, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) []string {
+	values, err := GetDNSRecordSetValuesE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return values
+}
+
+// GetDNSRecordSetValuesE gets the values of the records in the record set of the public DNS zone, formatted as
+// documented in getDNSRecordSetValues.
+func GetDNSRecordSetValuesE(recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) ([]string, error) {
+	recordSet, err := GetDNSRecordSetE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return getDNSRecordSetValues(recordSet.RecordSetProperties), nil
+}
+
+// AssertDNSRecordSetValues checks that the record set of the public DNS zone has exactly the given values, in any
+// order. This function would fail the test if there is an error or it does not.
+func AssertDNSRecordSetValues(t testing.TestingT, expectedValues []string, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) {
+	err := AssertDNSRecordSetValuesE(expectedValues, recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertDNSRecordSetValuesE checks that the record set of the public DNS zone has exactly the given values, in any
+// order, e.g., []string{"10 mail.example.com"} for an MX record set. Domain names are compared case insensitively
+// and regardless of the trailing dot.
+func AssertDNSRecordSetValuesE(expectedValues []string, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) error {
+	values, err := GetDNSRecordSetValuesE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkDNSRecordValues(fmt.Sprintf("%s record set %s of DNS zone %s", recordType, recordSetName, zoneName), values, expectedValues)
+}
+
+// WaitUntilDNSRecordSetResolves waits until the record set of the public DNS zone resolves to its values on the name
+// servers of the zone, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. This function would fail the test if there is an error or it does not resolve in time.
+func WaitUntilDNSRecordSetResolves(t testing.TestingT, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilDNSRecordSetResolvesE(t, recordSetName, recordType, zoneName, resourceGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilDNSRecordSetResolvesE waits until the record set of the public DNS zone resolves to its values on the name
+// servers of the zone, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. The name servers are queried directly, so this holds before the parent domain delegates the zone.
+func WaitUntilDNSRecordSetResolvesE(t testing.TestingT, recordSetName string, recordType dns.RecordType, zoneName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	recordSet, err := GetDNSRecordSetE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	nameServers, err := GetDNSZoneNameServersE(zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if recordSet.RecordSetProperties == nil || recordSet.Fqdn == nil {
+		return fmt.Errorf("%s record set %s of DNS zone %s has no FQDN", recordType, recordSetName, zoneName)
+	}
+	return AssertDNSNameResolvesE(t, getDNSRecordSetValues(recordSet.RecordSetProperties), to.String(recordSet.Fqdn), string(recordType), nameServers[0], maxRetries, sleepBetweenRetries)
+}
+
+// PrivateDNSZoneExists indicates whether the specified private DNS zone exists.
+// This function would fail the test if there is an error.
+func PrivateDNSZoneExists(t testing.TestingT, zoneName string, resourceGroupName string, subscriptionID string) bool {
+	exists, err := PrivateDNSZoneExistsE(zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return exists
+}
+
+// PrivateDNSZoneExistsE indicates whether the specified private DNS zone exists.
+func PrivateDNSZoneExistsE(zoneName string, resourceGroupName string, subscriptionID string) (bool, error) {
+	_, err := GetPrivateDNSZoneE(zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		if ResourceNotFoundErrorExists(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPrivateDNSZone gets the private DNS zone.
+// This function would fail the test if there is an error.
+func GetPrivateDNSZone(t testing.TestingT, zoneName string, resourceGroupName string, subscriptionID string) *privatedns.PrivateZone {
+	zone, err := GetPrivateDNSZoneE(zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return zone
+}
+
+// GetPrivateDNSZoneE gets the private DNS zone.
+func GetPrivateDNSZoneE(zoneName string, resourceGroupName string, subscriptionID string) (*privatedns.PrivateZone, error) {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetPrivateDNSZonesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := client.Get(context.Background(), resourceGroupName, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+// GetPrivateDNSRecordSet gets the record set of the given name and type in the private DNS zone. Use "@" as the name
+// of the record set at the apex of the zone.
+// This function would fail the test if there is an error.
+func GetPrivateDNSRecordSet(t testing.TestingT, recordSetName string, recordType privatedns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) *privatedns.RecordSet {
+	recordSet, err := GetPrivateDNSRecordSetE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return recordSet
+}
+
+// GetPrivateDNSRecordSetE gets the record set of the given name and type in the private DNS zone. Use "@" as the name
+// of the record set at the apex of the zone.
+func GetPrivateDNSRecordSetE(recordSetName string, recordType privatedns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) (*privatedns.RecordSet, error) {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetPrivateDNSRecordSetsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordSet, err := client.Get(context.Background(), resourceGroupName, zoneName, recordType, recordSetName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordSet, nil
+}
+
+// GetPrivateDNSRecordSetValues gets the values of the records in the record set of the private DNS zone, formatted as
+// documented in getDNSRecordSetValues.
+// This function would fail the test if there is an error.
+func GetPrivateDNSRecordSetValues(t testing.TestingT, recordSetName string, recordType privatedns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) []string {
+	values, err := GetPrivateDNSRecordSetValuesE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return values
+}
+
+// GetPrivateDNSRecordSetValuesE gets the values of the records in the record set of the private DNS zone, formatted
+// as documented in getDNSRecordSetValues.
+func GetPrivateDNSRecordSetValuesE(recordSetName string, recordType privatedns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) ([]string, error) {
+	recordSet, err := GetPrivateDNSRecordSetE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return getPrivateDNSRecordSetValues(recordSet.RecordSetProperties), nil
+}
+
+// AssertPrivateDNSRecordSetValues checks that the record set of the private DNS zone has exactly the given values, in
+// any order. This function would fail the test if there is an error or it does not.
+func AssertPrivateDNSRecordSetValues(t testing.TestingT, expectedValues []string, recordSetName string, recordType privatedns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) {
+	err := AssertPrivateDNSRecordSetValuesE(expectedValues, recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertPrivateDNSRecordSetValuesE checks that the record set of the private DNS zone has exactly the given values,
+// in any order, e.g., the private IP address of a private endpoint for an A record set.
+func AssertPrivateDNSRecordSetValuesE(expectedValues []string, recordSetName string, recordType privatedns.RecordType, zoneName string, resourceGroupName string, subscriptionID string) error {
+	values, err := GetPrivateDNSRecordSetValuesE(recordSetName, recordType, zoneName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkDNSRecordValues(fmt.Sprintf("%s record set %s of private DNS zone %s", recordType, recordSetName, zoneName), values, expectedValues)
+}
+
+// AssertPrivateDNSZoneLinkedToVirtualNetwork checks that the private DNS zone is linked to the given virtual network,
+// with auto registration of virtual machine records enabled or not as given.
+// This function would fail the test if there is an error or it is not.
+func AssertPrivateDNSZoneLinkedToVirtualNetwork(t testing.TestingT, virtualNetworkID string, registrationEnabled bool, zoneName string, resourceGroupName string, subscriptionID string) {
+	err := AssertPrivateDNSZoneLinkedToVirtualNetworkE(virtualNetworkID, registrationEnabled, zoneName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertPrivateDNSZoneLinkedToVirtualNetworkE checks that the private DNS zone is linked to the given virtual network,
+// with auto registration of virtual machine records enabled or not as given, and that the link is done, i.e., that
+// the virtual network resolves the records of the zone.
+func AssertPrivateDNSZoneLinkedToVirtualNetworkE(virtualNetworkID string, registrationEnabled bool, zoneName string, resourceGroupName string, subscriptionID string) error {
+	resourceGroupName, err := getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return err
+	}
+
+	client, err := GetPrivateDNSVirtualNetworkLinksClientE(subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	iterator, err := client.ListComplete(context.Background(), resourceGroupName, zoneName, nil)
+	if err != nil {
+		return err
+	}
+	links := []privatedns.VirtualNetworkLink{}
+	for ; iterator.NotDone(); err = iterator.NextWithContext(context.Background()) {
+		if err != nil {
+			return err
+		}
+		links = append(links, iterator.Value())
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkPrivateDNSZoneVirtualNetworkLink(zoneName, links, virtualNetworkID, registrationEnabled)
+}
+
+// checkPrivateDNSZoneVirtualNetworkLink returns an error if none of the given links of the private DNS zone is a
+// completed link to the given virtual network with the given auto registration. Resource IDs are compared case
+// insensitively, as Azure does.
+func checkPrivateDNSZoneVirtualNetworkLink(zoneName string, links []privatedns.VirtualNetworkLink, virtualNetworkID string, registrationEnabled bool) error {
+	for _, link := range links {
+		props := link.VirtualNetworkLinkProperties
+		if props == nil || props.VirtualNetwork == nil || !strings.EqualFold(to.String(props.VirtualNetwork.ID), virtualNetworkID) {
+			continue
+		}
+		if to.Bool(props.RegistrationEnabled) != registrationEnabled {
+			return fmt.Errorf("link %s of private DNS zone %s has registration enabled %t, not %t", to.String(link.Name), zoneName, to.Bool(props.RegistrationEnabled), registrationEnabled)
+		}
+		if props.VirtualNetworkLinkState != privatedns.Completed {
+			return fmt.Errorf("link %s of private DNS zone %s is in state %q", to.String(link.Name), zoneName, props.VirtualNetworkLinkState)
+		}
+		return nil
+	}
+	return fmt.Errorf("private DNS zone %s is not linked to virtual network %s", zoneName, virtualNetworkID)
+}
+
+// getDNSRecordSetValues returns the values of the records in the public DNS record set, formatted like the short
+// output of dig:
+//   - A, AAAA: the IP address
+//   - CNAME, NS, PTR: the domain name
+//   - MX: "<preference> <exchange>"
+//   - SRV: "<priority> <weight> <port> <target>"
+//   - TXT: the strings of the record, concatenated
+//   - CAA: "<flags> <tag> "<value>""
+//   - SOA: "<host> <email> <serial> <refresh> <retry> <expire> <minimum TTL>"
+func getDNSRecordSetValues(props *dns.RecordSetProperties) []string {
+	values := []string{}
+	if props == nil {
+		return values
+	}
+	if props.ARecords != nil {
+		for _, record := range *props.ARecords {
+			values = append(values, to.String(record.Ipv4Address))
+		}
+	}
+	if props.AaaaRecords != nil {
+		for _, record := range *props.AaaaRecords {
+			values = append(values, to.String(record.Ipv6Address))
+		}
+	}
+	if props.CnameRecord != nil {
+		values = append(values, to.String(props.CnameRecord.Cname))
+	}
+	if props.NsRecords != nil {
+		for _, record := range *props.NsRecords {
+			values = append(values, to.String(record.Nsdname))
+		}
+	}
+	if props.PtrRecords != nil {
+		for _, record := range *props.PtrRecords {
+			values = append(values, to.String(record.Ptrdname))
+		}
+	}
+	if props.MxRecords != nil {
+		for _, record := range *props.MxRecords {
+			values = append(values, fmt.Sprintf("%d %s", to.Int32(record.Preference), to.String(record.Exchange)))
+		}
+	}
+	if props.SrvRecords != nil {
+		for _, record := range *props.SrvRecords {
+			values = append(values, fmt.Sprintf("%d %d %d %s", to.Int32(record.Priority), to.Int32(record.Weight), to.Int32(record.Port), to.String(record.Target)))
+		}
+	}
+	if props.TxtRecords != nil {
+		for _, record := range *props.TxtRecords {
+			values = append(values, strings.Join(to.StringSlice(record.Value), ""))
+		}
+	}
+	if props.CaaRecords != nil {
+		for _, record := range *props.CaaRecords {
+			values = append(values, fmt.Sprintf("%d %s %q", to.Int32(record.Flags), to.String(record.Tag), to.String(record.Value)))
+		}
+	}
+	if record := props.SoaRecord; record != nil {
+		values = append(values, fmt.Sprintf("%s %s %d %d %d %d %d", to.String(record.Host), to.String(record.Email), to.Int64(record.SerialNumber), to.Int64(record.RefreshTime), to.Int64(record.RetryTime), to.Int64(record.ExpireTime), to.Int64(record.MinimumTTL)))
+	}
+	return values
+}
+
+// getPrivateDNSRecordSetValues returns the values of the records in the private DNS record set, formatted as
+// documented in getDNSRecordSetValues.
+func getPrivateDNSRecordSetValues(props *privatedns.RecordSetProperties) []string {
+	values := []string{}
+	if props == nil {
+		return values
+	}
+	if props.ARecords != nil {
+		for _, record := range *props.ARecords {
+			values = append(values, to.String(record.Ipv4Address))
+		}
+	}
+	if props.AaaaRecords != nil {
+		for _, record := range *props.AaaaRecords {
+			values = append(values, to.String(record.Ipv6Address))
+		}
+	}
+	if props.CnameRecord != nil {
+		values = append(values, to.String(props.CnameRecord.Cname))
+	}
+	if props.PtrRecords != nil {
+		for _, record := range *props.PtrRecords {
+			values = append(values, to.String(record.Ptrdname))
+		}
+	}
+	if props.MxRecords != nil {
+		for _, record := range *props.MxRecords {
+			values = append(values, fmt.Sprintf("%d %s", to.Int32(record.Preference), to.String(record.Exchange)))
+		}
+	}
+	if props.SrvRecords != nil {
+		for _, record := range *props.SrvRecords {
+			values = append(values, fmt.Sprintf("%d %d %d %s", to.Int32(record.Priority), to.Int32(record.Weight), to.Int32(record.Port), to.String(record.Target)))
+		}
+	}
+	if props.TxtRecords != nil {
+		for _, record := range *props.TxtRecords {
+			values = append(values, strings.Join(to.StringSlice(record.Value), ""))
+		}
+	}
+	if record := props.SoaRecord; record != nil {
+		values = append(values, fmt.Sprintf("%s %s %d %d %d %d %d", to.String(record.Host), to.String(record.Email), to.Int64(record.SerialNumber), to.Int64(record.RefreshTime), to.Int64(record.RetryTime), to.Int64(record.ExpireTime), to.Int64(record.MinimumTTL)))
+	}
+	return values
+}
+
+// checkDNSRecordValues returns an error if the actual values of the described DNS records are not the expected ones,
+// in any order. Domain names are compared case insensitively and regardless of the trailing dot.
+func checkDNSRecordValues(description string, actualValues []string, expectedValues []string) error {
+	actual := normalizeDNSRecordValues(actualValues)
+	expected := normalizeDNSRecordValues(expectedValues)
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		return fmt.Errorf("%s has values %v, expected %v", description, actualValues, expectedValues)
+	}
+	return nil
+}
+
+// normalizeDNSRecordValues returns the sorted DNS record values in lower case, without the trailing dots of domain
+// names.
+func normalizeDNSRecordValues(values []string) []string {
+	normalized := make([]string, len(values))
+	for i, value := range values {
+		normalized[i] = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), ".")
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// ResolveDNSName resolves the records of the given type for the DNS name from the test runner, formatted as
+// documented in getDNSRecordSetValues, on the given name server or on the system resolver if it is empty.
+// This function would fail the test if there is an error.
+func ResolveDNSName(t testing.TestingT, dnsName string, recordType string, nameServer string) []string {
+	values, err := ResolveDNSNameE(dnsName, recordType, nameServer)
+	require.NoError(t, err)
+	return values
+}
+
+// ResolveDNSNameE resolves the records of the given type for the DNS name from the test runner, formatted as
+// documented in getDNSRecordSetValues, on the given name server or on the system resolver if it is empty. The A,
+// AAAA, CNAME, MX, NS, SRV and TXT record types are supported. A and AAAA records follow CNAME records.
+func ResolveDNSNameE(dnsName string, recordType string, nameServer string) ([]string, error) {
+	resolver := net.DefaultResolver
+	if nameServer != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(strings.TrimSuffix(nameServer, "."), "53"))
+			},
+		}
+	}
+
+	ctx := context.Background()
+	values := []string{}
+	switch strings.ToUpper(recordType) {
+	case "A", "AAAA":
+		addresses, err := resolver.LookupIPAddr(ctx, dnsName)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			if (address.IP.To4() != nil) == strings.EqualFold(recordType, "A") {
+				values = append(values, address.IP.String())
+			}
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, dnsName)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, dnsName)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			values = append(values, fmt.Sprintf("%d %s", record.Pref, record.Host))
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, dnsName)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			values = append(values, record.Host)
+		}
+	case "SRV":
+		_, records, err := resolver.LookupSRV(ctx, "", "", dnsName)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			values = append(values, fmt.Sprintf("%d %d %d %s", record.Priority, record.Weight, record.Port, record.Target))
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, dnsName)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, records...)
+	default:
+		return nil, fmt.Errorf("unsupported DNS record type %q", recordType)
+	}
+	return values, nil
+}
+
+// AssertDNSNameResolves checks that the DNS name resolves to exactly the given values from the test runner, on the
+// given name server or on the system resolver if it is empty, retrying the check for the specified amount of times,
+// sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or it does not resolve in time.
+func AssertDNSNameResolves(t testing.TestingT, expectedValues []string, dnsName string, recordType string, nameServer string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := AssertDNSNameResolvesE(t, expectedValues, dnsName, recordType, nameServer, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// AssertDNSNameResolvesE checks that the DNS name resolves to exactly the given values from the test runner, on the
+// given name server or on the system resolver if it is empty, retrying the check for the specified amount of times,
+// sleeping for the provided duration between each try, as DNS changes take a while to propagate.
+func AssertDNSNameResolvesE(t testing.TestingT, expectedValues []string, dnsName string, recordType string, nameServer string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %s records of %s to resolve to %v.", recordType, dnsName, expectedValues),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			values, err := ResolveDNSNameE(dnsName, recordType, nameServer)
+			if err != nil {
+				return "", err
+			}
+			if err := checkDNSRecordValues(fmt.Sprintf("%s records of %s", recordType, dnsName), values, expectedValues); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s records of %s resolve to %v", recordType, dnsName, values), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// ResolveDNSNameFromVirtualMachine resolves the records of the given type for the DNS name from the given Linux
+// virtual machine, formatted as documented in getDNSRecordSetValues.
+// This function would fail the test if there is an error.
+func ResolveDNSNameFromVirtualMachine(t testing.TestingT, dnsName string, recordType string, nameServer string, vmName string, resourceGroupName string, subscriptionID string) []string {
+	values, err := ResolveDNSNameFromVirtualMachineE(t, dnsName, recordType, nameServer, vmName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return values
+}
+
+// ResolveDNSNameFromVirtualMachineE resolves the records of the given type for the DNS name from the given Linux
+// virtual machine, formatted as documented in getDNSRecordSetValues. This runs dig on the virtual machine with a run
+// command, so it sees the private DNS zones linked to its virtual network and the DNS servers of the virtual network,
+// e.g., the inbound endpoint of a private resolver. Pass a name server to query it rather than the DNS servers of the
+// virtual machine. Run commands take about half a minute, and the virtual machine must have dig installed, as the
+// Azure Ubuntu images do.
+func ResolveDNSNameFromVirtualMachineE(t testing.TestingT, dnsName string, recordType string, nameServer string, vmName string, resourceGroupName string, subscriptionID string) ([]string, error) {
+	script, err := formatDigScript(dnsName, recordType, nameServer)
+	if err != nil {
+		return nil, err
+	}
+	resourceGroupName, err = getTargetAzureResourceGroupName(resourceGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetVirtualMachineClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logf(t, "Running %q on virtual machine %s", script, vmName)
+	input := compute.RunCommandInput{CommandID: to.StringPtr(runShellScriptCommandID), Script: &[]string{script}}
+	future, err := client.RunCommand(context.Background(), resourceGroupName, vmName, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := future.WaitForCompletionRef(context.Background(), client.Client); err != nil {
+		return nil, err
+	}
+	result, err := future.Result(*client)
+	if err != nil {
+		return nil, err
+	}
+	if result.Value == nil || len(*result.Value) == 0 {
+		return nil, fmt.Errorf("run command on virtual machine %s returned no output", vmName)
+	}
+
+	stdout, stderr := parseRunShellScriptOutput(to.String((*result.Value)[0].Message))
+	if strings.TrimSpace(stderr) != "" {
+		return nil, fmt.Errorf("dig on virtual machine %s failed: %s", vmName, strings.TrimSpace(stderr))
+	}
+	return parseDigShortOutput(recordType, stdout), nil
+}
+
+// AssertDNSNameResolvesFromVirtualMachine checks that the DNS name resolves to exactly the given values from the given
+// Linux virtual machine, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. This function would fail the test if there is an error or it does not resolve in time.
+func AssertDNSNameResolvesFromVirtualMachine(t testing.TestingT, expectedValues []string, dnsName string, recordType string, nameServer string, vmName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := AssertDNSNameResolvesFromVirtualMachineE(t, expectedValues, dnsName, recordType, nameServer, vmName, resourceGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// AssertDNSNameResolvesFromVirtualMachineE checks that the DNS name resolves to exactly the given values from the
+// given Linux virtual machine, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. See ResolveDNSNameFromVirtualMachineE for how the name is resolved.
+func AssertDNSNameResolvesFromVirtualMachineE(t testing.TestingT, expectedValues []string, dnsName string, recordType string, nameServer string, vmName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %s records of %s to resolve to %v on virtual machine %s.", recordType, dnsName, expectedValues, vmName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			values, err := ResolveDNSNameFromVirtualMachineE(t, dnsName, recordType, nameServer, vmName, resourceGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			description := fmt.Sprintf("%s records of %s on virtual machine %s", recordType, dnsName, vmName)
+			if err := checkDNSRecordValues(description, values, expectedValues); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s resolve to %v", description, values), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// formatDigScript returns the shell script that resolves the records of the given type for the DNS name with dig, on
+// the given name server if it is not empty. The arguments are validated, as they are passed to a shell.
+func formatDigScript(dnsName string, recordType string, nameServer string) (string, error) {
+	if !dnsNamePattern.MatchString(dnsName) {
+		return "", fmt.Errorf("invalid DNS name %q", dnsName)
+	}
+	if !dnsRecordTypePattern.MatchString(recordType) {
+		return "", fmt.Errorf("invalid DNS record type %q", recordType)
+	}
+	script := fmt.Sprintf("dig +short %s %s", dnsName, strings.ToUpper(recordType))
+	if nameServer != "" {
+		if !dnsNamePattern.MatchString(nameServer) {
+			return "", fmt.Errorf("invalid name server %q", nameServer)
+		}
+		script += " @" + nameServer
+	}
+	return script, nil
+}
+
+// parseRunShellScriptOutput returns the standard output and error of a shell script from the message of its run
+// command, which has the form "Enable succeeded: \n[stdout]\n...\n[stderr]\n...".
+func parseRunShellScriptOutput(message string) (string, string) {
+	stdout := message
+	if index := strings.Index(stdout, "[stdout]\n"); index >= 0 {
+		stdout = stdout[index+len("[stdout]\n"):]
+	}
+	stderr := ""
+	if index := strings.Index(stdout, "[stderr]\n"); index >= 0 {
+		stdout, stderr = stdout[:index], stdout[index+len("[stderr]\n"):]
+	}
+	return stdout, stderr
+}
+
+// parseDigShortOutput returns the values of the records of the given type in the short output of dig, formatted as
+// documented in getDNSRecordSetValues. The CNAME records that dig follows to A and AAAA records are skipped.
+func parseDigShortOutput(recordType string, output string) []string {
+	values := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		switch strings.ToUpper(recordType) {
+		case "A", "AAAA":
+			if net.ParseIP(line) == nil {
+				continue
+			}
+		case "TXT":
+			// dig quotes each string of the record
+			line = strings.Trim(strings.ReplaceAll(line, `" "`, ""), `"`)
+		}
+		values = append(values, line)
+	}
+	return values
+}
@@ -0,0 +1,172 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/dns/mgmt/2018-05-01/dns"
+	"github.com/Azure/azure-sdk-for-go/services/privatedns/mgmt/2018-09-01/privatedns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when methods can be mocked or Create/Delete APIs are added, these tests can be extended.
+*/
+
+func TestGetDNSZoneE(t *testing.T) {
+	t.Parallel()
+
+	zoneName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := GetDNSZoneE(zoneName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestGetDNSRecordSetE(t *testing.T) {
+	t.Parallel()
+
+	recordSetName := ""
+	zoneName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := GetDNSRecordSetE(recordSetName, dns.A, zoneName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestGetPrivateDNSRecordSetE(t *testing.T) {
+	t.Parallel()
+
+	recordSetName := ""
+	zoneName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := GetPrivateDNSRecordSetE(recordSetName, privatedns.A, zoneName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestAssertPrivateDNSZoneLinkedToVirtualNetworkE(t *testing.T) {
+	t.Parallel()
+
+	virtualNetworkID := ""
+	zoneName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	err := AssertPrivateDNSZoneLinkedToVirtualNetworkE(virtualNetworkID, true, zoneName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestResolveDNSNameFromVirtualMachineE(t *testing.T) {
+	t.Parallel()
+
+	vmName := ""
+	resourceGroupName := ""
+	subscriptionID := ""
+
+	_, err := ResolveDNSNameFromVirtualMachineE(t, "db.privatelink.database.windows.net", "A", "", vmName, resourceGroupName, subscriptionID)
+	require.Error(t, err)
+}
+
+func TestGetDNSRecordSetValues(t *testing.T) {
+	t.Parallel()
+
+	mx := &dns.RecordSetProperties{MxRecords: &[]dns.MxRecord{
+		{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("mail1.example.com")},
+		{Preference: to.Int32Ptr(20), Exchange: to.StringPtr("mail2.example.com")},
+	}}
+	assert.Equal(t, []string{"10 mail1.example.com", "20 mail2.example.com"}, getDNSRecordSetValues(mx))
+
+	txt := &dns.RecordSetProperties{TxtRecords: &[]dns.TxtRecord{{Value: &[]string{"v=spf1 ", "-all"}}}}
+	assert.Equal(t, []string{"v=spf1 -all"}, getDNSRecordSetValues(txt))
+
+	caa := &dns.RecordSetProperties{CaaRecords: &[]dns.CaaRecord{{Flags: to.Int32Ptr(0), Tag: to.StringPtr("issue"), Value: to.StringPtr("letsencrypt.org")}}}
+	assert.Equal(t, []string{`0 issue "letsencrypt.org"`}, getDNSRecordSetValues(caa))
+
+	assert.Empty(t, getDNSRecordSetValues(nil))
+
+	private := &privatedns.RecordSetProperties{ARecords: &[]privatedns.ARecord{{Ipv4Address: to.StringPtr("10.0.1.4")}}}
+	assert.Equal(t, []string{"10.0.1.4"}, getPrivateDNSRecordSetValues(private))
+}
+
+func TestCheckDNSRecordValues(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkDNSRecordValues("records", []string{"10.0.0.5", "10.0.0.4"}, []string{"10.0.0.4", "10.0.0.5"}))
+	assert.NoError(t, checkDNSRecordValues("records", []string{"App.Example.com."}, []string{"app.example.com"}))
+	assert.Error(t, checkDNSRecordValues("records", []string{"10.0.0.4"}, []string{"10.0.0.4", "10.0.0.5"}))
+	assert.Error(t, checkDNSRecordValues("records", []string{"10.0.0.4", "10.0.0.4"}, []string{"10.0.0.4"}))
+}
+
+func TestCheckPrivateDNSZoneVirtualNetworkLink(t *testing.T) {
+	t.Parallel()
+
+	vnetID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+	link := func(id string, registrationEnabled bool, state privatedns.VirtualNetworkLinkState) privatedns.VirtualNetworkLink {
+		return privatedns.VirtualNetworkLink{
+			Name: to.StringPtr("link"),
+			VirtualNetworkLinkProperties: &privatedns.VirtualNetworkLinkProperties{
+				VirtualNetwork:          &privatedns.SubResource{ID: to.StringPtr(id)},
+				RegistrationEnabled:     to.BoolPtr(registrationEnabled),
+				VirtualNetworkLinkState: state,
+			},
+		}
+	}
+
+	links := []privatedns.VirtualNetworkLink{link("/subscriptions/sub/other", false, privatedns.Completed), link(vnetID, false, privatedns.Completed)}
+	assert.NoError(t, checkPrivateDNSZoneVirtualNetworkLink("zone", links, vnetID, false))
+	assert.NoError(t, checkPrivateDNSZoneVirtualNetworkLink("zone", links, "/SUBSCRIPTIONS/sub/resourcegroups/rg/providers/Microsoft.Network/virtualNetworks/vnet", false))
+	assert.Error(t, checkPrivateDNSZoneVirtualNetworkLink("zone", links, vnetID, true))
+	assert.Error(t, checkPrivateDNSZoneVirtualNetworkLink("zone", links[:1], vnetID, false))
+	assert.Error(t, checkPrivateDNSZoneVirtualNetworkLink("zone", []privatedns.VirtualNetworkLink{link(vnetID, false, privatedns.InProgress)}, vnetID, false))
+}
+
+func TestFormatDigScript(t *testing.T) {
+	t.Parallel()
+
+	script, err := formatDigScript("db.privatelink.database.windows.net", "a", "")
+	require.NoError(t, err)
+	assert.Equal(t, "dig +short db.privatelink.database.windows.net A", script)
+
+	script, err = formatDigScript("app.contoso.internal", "CNAME", "10.0.0.4")
+	require.NoError(t, err)
+	assert.Equal(t, "dig +short app.contoso.internal CNAME @10.0.0.4", script)
+
+	_, err = formatDigScript("example.com; rm -rf /", "A", "")
+	assert.Error(t, err)
+	_, err = formatDigScript("example.com", "A", "$(id)")
+	assert.Error(t, err)
+	_, err = formatDigScript("example.com", "A;", "")
+	assert.Error(t, err)
+}
+
+func TestParseRunShellScriptOutput(t *testing.T) {
+	t.Parallel()
+
+	stdout, stderr := parseRunShellScriptOutput("Enable succeeded: \n[stdout]\ndb.privatelink.database.windows.net.\n10.0.1.4\n\n[stderr]\n")
+	assert.Equal(t, "db.privatelink.database.windows.net.\n10.0.1.4\n\n", stdout)
+	assert.Empty(t, stderr)
+
+	_, stderr = parseRunShellScriptOutput("Enable succeeded: \n[stdout]\n\n[stderr]\n/var/lib/waagent/run-command/download/0/script.sh: 1: dig: not found\n")
+	assert.Contains(t, stderr, "dig: not found")
+}
+
+func TestParseDigShortOutput(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"10.0.1.4"}, parseDigShortOutput("A", "db.privatelink.database.windows.net.\n10.0.1.4\n\n"))
+	assert.Equal(t, []string{"v=spf1 -all", "verification"}, parseDigShortOutput("TXT", "\"v=spf1 \" \"-all\"\n\"verification\"\n"))
+	assert.Equal(t, []string{"10 mail.example.com."}, parseDigShortOutput("MX", "10 mail.example.com.\n"))
+	assert.Empty(t, parseDigShortOutput("A", ";; connection timed out; no servers could be reached\n"))
+}
@@ -9,3 +9,13 @@ const (
 	PrivateIP LoadBalancerIPType = "PrivateIP"
 	NoIP      LoadBalancerIPType = "NoIP"
 )
+
+// WebApplicationFirewallMode enumerator for the Prevention or Detection modes of the web application firewall of an
+// Application Gateway.
+type WebApplicationFirewallMode string
+
+// WebApplicationFirewallMode values
+const (
+	WAFPreventionMode WebApplicationFirewallMode = "Prevention"
+	WAFDetectionMode  WebApplicationFirewallMode = "Detection"
+)
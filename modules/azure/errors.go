@@ -2,6 +2,7 @@ package azure
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -69,3 +70,12 @@ func ResourceNotFoundErrorExists(err error) bool {
 	}
 	return false
 }
+
+// isBadRequestError checks whether the error is a 400 Bad Request response, e.g., for an invalid query, which is not
+// worth retrying.
+func isBadRequestError(err error) bool {
+	if autorestError, ok := err.(autorest.DetailedError); ok {
+		return autorestError.StatusCode == http.StatusBadRequest
+	}
+	return false
+}
@@ -0,0 +1,361 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/eventhub/mgmt/2017-04-01/eventhub"
+	blobstorage "github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// eventHubPartitionAPIVersion is the version of the Event Hubs REST API used to get the runtime information of
+// partitions.
+const eventHubPartitionAPIVersion = "2014-01"
+
+// EventHubPartitionInfo is the runtime information of a partition of an event hub.
+type EventHubPartitionInfo struct {
+	PartitionID         string
+	BeginSequenceNumber int64  `xml:"BeginSequenceNumber"`
+	EndSequenceNumber   int64  `xml:"EndSequenceNumber"`
+	LastEnqueuedOffset  string `xml:"LastEnqueuedOffset"`
+	LastEnqueuedTimeUtc string `xml:"LastEnqueuedTimeUtc"`
+}
+
+// EventHubCheckpointStore is the blob container where the consumers of an event hub store their checkpoints, using the
+// layout of the checkpoint stores of the Azure SDKs (e.g., BlobCheckpointStore).
+type EventHubCheckpointStore struct {
+	StorageAccountName string
+	ResourceGroupName  string
+	ContainerName      string
+}
+
+// EventHubCheckpoint is the position of a consumer group in a partition of an event hub, i.e., the last event it has
+// processed.
+type EventHubCheckpoint struct {
+	PartitionID    string
+	Offset         string
+	SequenceNumber int64
+}
+
+func eventHubNamespaceClientE(subscriptionID string) (*eventhub.NamespacesClient, error) {
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	nsClient := eventhub.NewNamespacesClient(subscriptionID)
+	nsClient.Authorizer = *authorizer
+	return &nsClient, nil
+}
+
+func eventHubClientE(subscriptionID string) (*eventhub.EventHubsClient, error) {
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	ehClient := eventhub.NewEventHubsClient(subscriptionID)
+	ehClient.Authorizer = *authorizer
+	return &ehClient, nil
+}
+
+// getEventHubNamespaceEndpointE returns the Service Bus endpoint of the given Event Hubs namespace.
+func getEventHubNamespaceEndpointE(subscriptionID string, namespace string, resourceGroup string) (string, error) {
+	nsClient, err := eventHubNamespaceClientE(subscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	ehNamespace, err := nsClient.Get(context.Background(), resourceGroup, namespace)
+	if err != nil {
+		return "", err
+	}
+	if ehNamespace.EHNamespaceProperties == nil || ehNamespace.ServiceBusEndpoint == nil {
+		return "", fmt.Errorf("namespace %s has no Service Bus endpoint", namespace)
+	}
+	return *ehNamespace.ServiceBusEndpoint, nil
+}
+
+// getEventHubSASEndpointE returns the endpoint of the given Event Hubs namespace, with the primary key of its
+// RootManageSharedAccessKey authorization rule.
+func getEventHubSASEndpointE(subscriptionID string, namespace string, resourceGroup string) (*sasEndpoint, error) {
+	serviceBusEndpoint, err := getEventHubNamespaceEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	nsClient, err := eventHubNamespaceClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := nsClient.ListKeys(context.Background(), resourceGroup, namespace, serviceBusRootAuthRuleName)
+	if err != nil {
+		return nil, err
+	}
+	return newSASEndpoint(serviceBusEndpoint, serviceBusRootAuthRuleName, to.String(keys.PrimaryKey))
+}
+
+// SendEventHubEventE - send an event to an event hub of the given namespace, authenticating with a key of its
+// RootManageSharedAccessKey authorization rule. Events with the same partition key are sent to the same partition; if
+// it is empty, the event is sent to any partition.
+func SendEventHubEventE(subscriptionID string, namespace string, resourceGroup string, eventHubName string, body string, partitionKey string) error {
+	endpoint, err := getEventHubSASEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/atom+xml;type=entry;charset=utf-8"}
+	if partitionKey != "" {
+		brokerProperties, err := json.Marshal(serviceBusBrokerProperties{PartitionKey: partitionKey})
+		if err != nil {
+			return err
+		}
+		headers["BrokerProperties"] = string(brokerProperties)
+	}
+
+	_, _, err = endpoint.do(http.MethodPost, eventHubName+"/messages", []byte(body), headers, http.StatusCreated)
+	return err
+}
+
+// SendEventHubEvent - send an event to an event hub of the given namespace. This function would fail the test if there
+// is an error.
+func SendEventHubEvent(t *testing.T, subscriptionID string, namespace string, resourceGroup string, eventHubName string, body string, partitionKey string) {
+	err := SendEventHubEventE(subscriptionID, namespace, resourceGroup, eventHubName, body, partitionKey)
+	require.NoError(t, err)
+}
+
+// GetEventHubPartitionIDsE - get the IDs of the partitions of the given event hub.
+func GetEventHubPartitionIDsE(subscriptionID string, namespace string, resourceGroup string, eventHubName string) ([]string, error) {
+	ehClient, err := eventHubClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := ehClient.Get(context.Background(), resourceGroup, namespace, eventHubName)
+	if err != nil {
+		return nil, err
+	}
+	if model.Properties == nil || model.PartitionIds == nil {
+		return []string{}, nil
+	}
+	return *model.PartitionIds, nil
+}
+
+// GetEventHubPartitionIDs - get the IDs of the partitions of the given event hub. This function would fail the test if
+// there is an error.
+func GetEventHubPartitionIDs(t *testing.T, subscriptionID string, namespace string, resourceGroup string, eventHubName string) []string {
+	partitionIDs, err := GetEventHubPartitionIDsE(subscriptionID, namespace, resourceGroup, eventHubName)
+	require.NoError(t, err)
+	return partitionIDs
+}
+
+// GetEventHubPartitionInfosE - get the runtime information of all the partitions of the given event hub, including the
+// sequence number of the last event enqueued in each of them.
+func GetEventHubPartitionInfosE(subscriptionID string, namespace string, resourceGroup string, eventHubName string) ([]EventHubPartitionInfo, error) {
+	partitionIDs, err := GetEventHubPartitionIDsE(subscriptionID, namespace, resourceGroup, eventHubName)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := getEventHubSASEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := []EventHubPartitionInfo{}
+	for _, partitionID := range partitionIDs {
+		path := fmt.Sprintf("%s/partitions/%s?api-version=%s", eventHubName, partitionID, eventHubPartitionAPIVersion)
+		_, body, err := endpoint.do(http.MethodGet, path, nil, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		partition, err := parseEventHubPartitionInfo(partitionID, body)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, *partition)
+	}
+	return partitions, nil
+}
+
+// GetEventHubPartitionInfos - get the runtime information of all the partitions of the given event hub. This function
+// would fail the test if there is an error.
+func GetEventHubPartitionInfos(t *testing.T, subscriptionID string, namespace string, resourceGroup string, eventHubName string) []EventHubPartitionInfo {
+	partitions, err := GetEventHubPartitionInfosE(subscriptionID, namespace, resourceGroup, eventHubName)
+	require.NoError(t, err)
+	return partitions
+}
+
+// parseEventHubPartitionInfo parses the Atom entry with the description of a partition returned by the Event Hubs REST
+// API.
+func parseEventHubPartitionInfo(partitionID string, body []byte) (*EventHubPartitionInfo, error) {
+	var entry struct {
+		Content struct {
+			PartitionDescription EventHubPartitionInfo `xml:"PartitionDescription"`
+		} `xml:"content"`
+	}
+	if err := xml.Unmarshal(body, &entry); err != nil {
+		return nil, fmt.Errorf("invalid description of partition %s: %v", partitionID, err)
+	}
+	partition := entry.Content.PartitionDescription
+	partition.PartitionID = partitionID
+	return &partition, nil
+}
+
+// GetEventHubCheckpointsE - get the checkpoints of the given consumer group of an event hub in the given checkpoint
+// store, by partition ID. Partitions that the consumer group has not processed any event from yet have no checkpoint.
+func GetEventHubCheckpointsE(subscriptionID string, namespace string, resourceGroup string, eventHubName string, consumerGroup string, store EventHubCheckpointStore) (map[string]EventHubCheckpoint, error) {
+	serviceBusEndpoint, err := getEventHubNamespaceEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	endpointURL, err := url.Parse(serviceBusEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	storageClient, err := GetStorageAccountClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := storageClient.ListKeys(context.Background(), store.ResourceGroupName, store.StorageAccountName, "")
+	if err != nil {
+		return nil, err
+	}
+	if keys.Keys == nil || len(*keys.Keys) == 0 {
+		return nil, fmt.Errorf("storage account %s has no keys", store.StorageAccountName)
+	}
+	blobClient, err := blobstorage.NewBasicClient(store.StorageAccountName, to.String((*keys.Keys)[0].Value))
+	if err != nil {
+		return nil, err
+	}
+	blobService := blobClient.GetBlobService()
+	container := blobService.GetContainerReference(store.ContainerName)
+
+	prefix := getEventHubCheckpointPrefix(endpointURL.Hostname(), eventHubName, consumerGroup)
+	checkpoints := map[string]EventHubCheckpoint{}
+	params := blobstorage.ListBlobsParameters{Prefix: prefix, Include: &blobstorage.IncludeBlobDataset{Metadata: true}}
+	for {
+		blobs, err := container.ListBlobs(params)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range blobs.Blobs {
+			checkpoint, err := parseEventHubCheckpoint(strings.TrimPrefix(blob.Name, prefix), blob.Metadata)
+			if err != nil {
+				return nil, err
+			}
+			checkpoints[checkpoint.PartitionID] = *checkpoint
+		}
+		if blobs.NextMarker == "" {
+			return checkpoints, nil
+		}
+		params.Marker = blobs.NextMarker
+	}
+}
+
+// GetEventHubCheckpoints - get the checkpoints of the given consumer group of an event hub in the given checkpoint
+// store, by partition ID. This function would fail the test if there is an error.
+func GetEventHubCheckpoints(t *testing.T, subscriptionID string, namespace string, resourceGroup string, eventHubName string, consumerGroup string, store EventHubCheckpointStore) map[string]EventHubCheckpoint {
+	checkpoints, err := GetEventHubCheckpointsE(subscriptionID, namespace, resourceGroup, eventHubName, consumerGroup, store)
+	require.NoError(t, err)
+	return checkpoints
+}
+
+// getEventHubCheckpointPrefix returns the prefix of the names of the checkpoint blobs of the given consumer group.
+func getEventHubCheckpointPrefix(fullyQualifiedNamespace string, eventHubName string, consumerGroup string) string {
+	return strings.ToLower(fmt.Sprintf("%s/%s/%s/checkpoint/", fullyQualifiedNamespace, eventHubName, consumerGroup))
+}
+
+// parseEventHubCheckpoint returns the checkpoint of the given partition stored in a blob with the given metadata.
+func parseEventHubCheckpoint(partitionID string, metadata map[string]string) (*EventHubCheckpoint, error) {
+	checkpoint := &EventHubCheckpoint{PartitionID: partitionID}
+	sequenceNumber := ""
+	for name, value := range metadata {
+		switch strings.ToLower(name) {
+		case "sequencenumber":
+			sequenceNumber = value
+		case "offset":
+			checkpoint.Offset = value
+		}
+	}
+
+	parsed, err := strconv.ParseInt(sequenceNumber, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint of partition %s has invalid sequence number %q", partitionID, sequenceNumber)
+	}
+	checkpoint.SequenceNumber = parsed
+	return checkpoint, nil
+}
+
+// WaitUntilEventHubConsumerGroupCaughtUpE - wait until the given consumer group has checkpointed all the events
+// enqueued in the given event hub, e.g., after sending events with SendEventHubEventE, to check that the consumer
+// processes them. This retries the check for the specified amount of times, sleeping for the provided duration between
+// each try.
+func WaitUntilEventHubConsumerGroupCaughtUpE(t *testing.T, subscriptionID string, namespace string, resourceGroup string, eventHubName string, consumerGroup string, store EventHubCheckpointStore, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for consumer group %s of event hub %s to catch up.", consumerGroup, eventHubName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			partitions, err := GetEventHubPartitionInfosE(subscriptionID, namespace, resourceGroup, eventHubName)
+			if err != nil {
+				return "", err
+			}
+			checkpoints, err := GetEventHubCheckpointsE(subscriptionID, namespace, resourceGroup, eventHubName, consumerGroup, store)
+			if err != nil {
+				return "", err
+			}
+			if err := checkEventHubCheckpointsCaughtUp(partitions, checkpoints); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Consumer group %s of event hub %s caught up", consumerGroup, eventHubName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// WaitUntilEventHubConsumerGroupCaughtUp - wait until the given consumer group has checkpointed all the events enqueued
+// in the given event hub. This function would fail the test if there is an error or the consumer group doesn't catch up
+// in time.
+func WaitUntilEventHubConsumerGroupCaughtUp(t *testing.T, subscriptionID string, namespace string, resourceGroup string, eventHubName string, consumerGroup string, store EventHubCheckpointStore, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilEventHubConsumerGroupCaughtUpE(t, subscriptionID, namespace, resourceGroup, eventHubName, consumerGroup, store, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// checkEventHubCheckpointsCaughtUp returns an error if the given checkpoints are behind the last event enqueued in any
+// of the given partitions.
+func checkEventHubCheckpointsCaughtUp(partitions []EventHubPartitionInfo, checkpoints map[string]EventHubCheckpoint) error {
+	behind := []string{}
+	for _, partition := range partitions {
+		// Partitions that have no events have an end sequence number of -1.
+		if partition.EndSequenceNumber < 0 || partition.EndSequenceNumber < partition.BeginSequenceNumber {
+			continue
+		}
+		checkpoint, exists := checkpoints[partition.PartitionID]
+		if !exists {
+			behind = append(behind, fmt.Sprintf("partition %s has no checkpoint", partition.PartitionID))
+		} else if checkpoint.SequenceNumber < partition.EndSequenceNumber {
+			behind = append(behind, fmt.Sprintf("partition %s is at sequence number %d of %d", partition.PartitionID, checkpoint.SequenceNumber, partition.EndSequenceNumber))
+		}
+	}
+	if len(behind) > 0 {
+		sort.Strings(behind)
+		return fmt.Errorf("consumer group is behind: %s", strings.Join(behind, "; "))
+	}
+	return nil
+}
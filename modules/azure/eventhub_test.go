@@ -0,0 +1,83 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors. These tests can be extended.
+*/
+func TestSendEventHubEventE(t *testing.T) {
+	t.Parallel()
+
+	subscriptionID := ""
+	resourceGroup := ""
+	namespace := ""
+
+	err := SendEventHubEventE(subscriptionID, namespace, resourceGroup, "events", "test", "")
+	require.Error(t, err)
+}
+
+func TestParseEventHubPartitionInfo(t *testing.T) {
+	t.Parallel()
+
+	body := `<entry xmlns="http://www.w3.org/2005/Atom">
+  <content type="application/xml">
+    <PartitionDescription xmlns="http://schemas.microsoft.com/netservices/2010/10/servicebus/connect" xmlns:i="http://www.w3.org/2001/XMLSchema-instance">
+      <SizeInBytes>1024</SizeInBytes>
+      <BeginSequenceNumber>0</BeginSequenceNumber>
+      <EndSequenceNumber>41</EndSequenceNumber>
+      <IncomingBytesPerSecond>0</IncomingBytesPerSecond>
+      <OutgoingBytesPerSecond>0</OutgoingBytesPerSecond>
+      <LastEnqueuedOffset>8400</LastEnqueuedOffset>
+      <LastEnqueuedTimeUtc>2021-01-01T00:00:00Z</LastEnqueuedTimeUtc>
+    </PartitionDescription>
+  </content>
+</entry>`
+	partition, err := parseEventHubPartitionInfo("1", []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, &EventHubPartitionInfo{
+		PartitionID:         "1",
+		BeginSequenceNumber: 0,
+		EndSequenceNumber:   41,
+		LastEnqueuedOffset:  "8400",
+		LastEnqueuedTimeUtc: "2021-01-01T00:00:00Z",
+	}, partition)
+}
+
+func TestParseEventHubCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "my-namespace.servicebus.windows.net/events/$default/checkpoint/", getEventHubCheckpointPrefix("my-namespace.servicebus.windows.net", "Events", "$Default"))
+
+	checkpoint, err := parseEventHubCheckpoint("0", map[string]string{"SequenceNumber": "41", "offset": "8400"})
+	require.NoError(t, err)
+	assert.Equal(t, &EventHubCheckpoint{PartitionID: "0", Offset: "8400", SequenceNumber: 41}, checkpoint)
+
+	_, err = parseEventHubCheckpoint("0", map[string]string{})
+	assert.Error(t, err)
+}
+
+func TestCheckEventHubCheckpointsCaughtUp(t *testing.T) {
+	t.Parallel()
+
+	partitions := []EventHubPartitionInfo{
+		{PartitionID: "0", BeginSequenceNumber: 0, EndSequenceNumber: 41},
+		{PartitionID: "1", BeginSequenceNumber: 0, EndSequenceNumber: -1},
+	}
+	assert.NoError(t, checkEventHubCheckpointsCaughtUp(partitions, map[string]EventHubCheckpoint{"0": {PartitionID: "0", SequenceNumber: 41}}))
+
+	err := checkEventHubCheckpointsCaughtUp(partitions, map[string]EventHubCheckpoint{"0": {PartitionID: "0", SequenceNumber: 40}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partition 0 is at sequence number 40 of 41")
+	assert.Error(t, checkEventHubCheckpointsCaughtUp(partitions, map[string]EventHubCheckpoint{}))
+}
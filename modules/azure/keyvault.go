@@ -2,14 +2,22 @@ package azure
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"fmt"
+	"hash"
 	"os"
+	"sort"
+	"strings"
 	"testing"
 
-	kvauth "github.com/Azure/azure-sdk-for-go/services/keyvault/auth"
-	kvmng "github.com/Azure/azure-sdk-for-go/services/keyvault/mgmt/2016-10-01/keyvault"
+	kvmng "github.com/Azure/azure-sdk-for-go/services/keyvault/mgmt/2019-09-01/keyvault"
 	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
 	"github.com/Azure/go-autorest/autorest"
+	autorestAzure "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/require"
 )
 
@@ -123,24 +131,19 @@ func GetKeyVaultClientE() (*keyvault.BaseClient, error) {
 	return &kvClient, nil
 }
 
-// NewKeyVaultAuthorizerE will return dataplane Authorizer for KeyVault.
+// NewKeyVaultAuthorizerE will return dataplane Authorizer for KeyVault, adhering to the same auth mechanisms as
+// NewAuthorizer.
 func NewKeyVaultAuthorizerE() (*autorest.Authorizer, error) {
-	// Carry out env var lookups
-	_, clientIDExists := os.LookupEnv(AuthFromEnvClient)
-	_, tenantIDExists := os.LookupEnv(AuthFromEnvTenant)
-	_, fileAuthSet := os.LookupEnv(AuthFromFile)
-
-	// Execute logic to return an authorizer from the correct method
-	if clientIDExists && tenantIDExists {
-		authorizer, err := kvauth.NewAuthorizerFromEnvironment()
-		return &authorizer, err
-	} else if fileAuthSet {
-		authorizer, err := kvauth.NewAuthorizerFromFile()
-		return &authorizer, err
-	} else {
-		authorizer, err := kvauth.NewAuthorizerFromCLI()
-		return &authorizer, err
+	// The resource can be overridden like in the Azure SDK, e.g., for Azure Stack
+	resource := os.Getenv("AZURE_KEYVAULT_RESOURCE")
+	if resource == "" {
+		env, err := autorestAzure.EnvironmentFromName(getDefaultEnvironmentName())
+		if err != nil {
+			return nil, err
+		}
+		resource = env.ResourceIdentifiers.KeyVault
 	}
+	return newAuthorizerWithResourceE(AuthOptions{}, resource)
 }
 
 // GetKeyVault is a helper function that gets the keyvault management object.
@@ -189,3 +192,459 @@ func GetKeyVaultManagementClientE(subscriptionID string) (*kvmng.VaultsClient, e
 
 	return vaultClient, nil
 }
+
+// KeyVaultPermissionModel is the model a key vault authorizes data plane operations with.
+type KeyVaultPermissionModel string
+
+const (
+	// KeyVaultAccessPolicyModel authorizes operations with the access policies of the vault.
+	KeyVaultAccessPolicyModel KeyVaultPermissionModel = "AccessPolicy"
+	// KeyVaultRbacModel authorizes operations with Azure role assignments (e.g., Key Vault Secrets User).
+	KeyVaultRbacModel KeyVaultPermissionModel = "RBAC"
+)
+
+// getKeyVaultURLE returns the data plane URL of the given key vault.
+func getKeyVaultURLE(keyVaultName string) (string, error) {
+	keyVaultSuffix, err := GetKeyVaultURISuffixE()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://%s.%s", keyVaultName, keyVaultSuffix), nil
+}
+
+// GetKeyVaultSecretValue gets the value of the current version of a key vault secret.
+// This function would fail the test if there is an error.
+func GetKeyVaultSecretValue(t *testing.T, keyVaultName string, secretName string) string {
+	value, err := GetKeyVaultSecretValueE(keyVaultName, secretName)
+	require.NoError(t, err)
+	return value
+}
+
+// GetKeyVaultSecretValueE gets the value of the current version of a key vault secret.
+func GetKeyVaultSecretValueE(keyVaultName, secretName string) (string, error) {
+	client, err := GetKeyVaultClientE()
+	if err != nil {
+		return "", err
+	}
+	vaultURL, err := getKeyVaultURLE(keyVaultName)
+	if err != nil {
+		return "", err
+	}
+	secret, err := client.GetSecret(context.Background(), vaultURL, secretName, "")
+	if err != nil {
+		return "", err
+	}
+	if secret.Value == nil {
+		return "", fmt.Errorf("secret %s of key vault %s has no value", secretName, keyVaultName)
+	}
+	return *secret.Value, nil
+}
+
+// SetKeyVaultSecret sets the value of a key vault secret, creating a new version of it, and returns the ID of that
+// version. This function would fail the test if there is an error.
+func SetKeyVaultSecret(t *testing.T, keyVaultName string, secretName string, value string) string {
+	id, err := SetKeyVaultSecretE(keyVaultName, secretName, value)
+	require.NoError(t, err)
+	return id
+}
+
+// SetKeyVaultSecretE sets the value of a key vault secret, creating a new version of it, and returns the ID of that
+// version. This is useful to test that an application picks up rotated secrets.
+func SetKeyVaultSecretE(keyVaultName, secretName, value string) (string, error) {
+	client, err := GetKeyVaultClientE()
+	if err != nil {
+		return "", err
+	}
+	vaultURL, err := getKeyVaultURLE(keyVaultName)
+	if err != nil {
+		return "", err
+	}
+	secret, err := client.SetSecret(context.Background(), vaultURL, secretName, keyvault.SecretSetParameters{Value: &value})
+	if err != nil {
+		return "", err
+	}
+	if secret.ID == nil {
+		return "", nil
+	}
+	return *secret.ID, nil
+}
+
+// GetKeyVaultKey gets the current version of a key vault key.
+// This function would fail the test if there is an error.
+func GetKeyVaultKey(t *testing.T, keyVaultName string, keyName string) *keyvault.KeyBundle {
+	key, err := GetKeyVaultKeyE(keyVaultName, keyName)
+	require.NoError(t, err)
+	return key
+}
+
+// GetKeyVaultKeyE gets the current version of a key vault key, including its public part and the operations it allows.
+func GetKeyVaultKeyE(keyVaultName, keyName string) (*keyvault.KeyBundle, error) {
+	client, err := GetKeyVaultClientE()
+	if err != nil {
+		return nil, err
+	}
+	vaultURL, err := getKeyVaultURLE(keyVaultName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := client.GetKey(context.Background(), vaultURL, keyName, "")
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetKeyVaultCertificate gets the current version of a key vault certificate.
+// This function would fail the test if there is an error.
+func GetKeyVaultCertificate(t *testing.T, keyVaultName string, certificateName string) *keyvault.CertificateBundle {
+	certificate, err := GetKeyVaultCertificateE(keyVaultName, certificateName)
+	require.NoError(t, err)
+	return certificate
+}
+
+// GetKeyVaultCertificateE gets the current version of a key vault certificate, including its policy and its public
+// certificate.
+func GetKeyVaultCertificateE(keyVaultName, certificateName string) (*keyvault.CertificateBundle, error) {
+	client, err := GetKeyVaultClientE()
+	if err != nil {
+		return nil, err
+	}
+	vaultURL, err := getKeyVaultURLE(keyVaultName)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := client.GetCertificate(context.Background(), vaultURL, certificateName, "")
+	if err != nil {
+		return nil, err
+	}
+	return &certificate, nil
+}
+
+// AssertKeyVaultSoftDeleteEnabled checks that soft delete is enabled for a key vault, with the given retention period
+// unless it is 0. This function would fail the test if it is not.
+func AssertKeyVaultSoftDeleteEnabled(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string, retentionDays int32) {
+	err := AssertKeyVaultSoftDeleteEnabledE(t, resGroupName, keyVaultName, subscriptionID, retentionDays)
+	require.NoError(t, err)
+}
+
+// AssertKeyVaultSoftDeleteEnabledE checks that soft delete is enabled for a key vault, with the given retention period
+// unless it is 0.
+func AssertKeyVaultSoftDeleteEnabledE(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string, retentionDays int32) error {
+	vault, err := GetKeyVaultE(t, resGroupName, keyVaultName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkKeyVaultSoftDelete(vault, retentionDays)
+}
+
+// checkKeyVaultSoftDelete returns an error if soft delete is not enabled for the given key vault with the given
+// retention period (unless it is 0). Soft delete is enabled if it is not specified, as it can no longer be disabled.
+func checkKeyVaultSoftDelete(vault *kvmng.Vault, retentionDays int32) error {
+	if vault.Properties == nil {
+		return fmt.Errorf("key vault %s has no properties", to.String(vault.Name))
+	}
+	if vault.Properties.EnableSoftDelete != nil && !*vault.Properties.EnableSoftDelete {
+		return fmt.Errorf("key vault %s does not have soft delete enabled", to.String(vault.Name))
+	}
+	if retentionDays != 0 && to.Int32(vault.Properties.SoftDeleteRetentionInDays) != retentionDays {
+		return fmt.Errorf("key vault %s retains deleted objects for %d days rather than %d", to.String(vault.Name), to.Int32(vault.Properties.SoftDeleteRetentionInDays), retentionDays)
+	}
+	return nil
+}
+
+// AssertKeyVaultPurgeProtectionEnabled checks that purge protection is enabled for a key vault.
+// This function would fail the test if it is not.
+func AssertKeyVaultPurgeProtectionEnabled(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string) {
+	err := AssertKeyVaultPurgeProtectionEnabledE(t, resGroupName, keyVaultName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertKeyVaultPurgeProtectionEnabledE checks that purge protection is enabled for a key vault, so that deleted
+// objects can't be purged before the end of their retention period.
+func AssertKeyVaultPurgeProtectionEnabledE(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string) error {
+	vault, err := GetKeyVaultE(t, resGroupName, keyVaultName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if vault.Properties == nil || !to.Bool(vault.Properties.EnablePurgeProtection) {
+		return fmt.Errorf("key vault %s does not have purge protection enabled", keyVaultName)
+	}
+	return nil
+}
+
+// GetKeyVaultPermissionModel gets the permission model of a key vault.
+// This function would fail the test if there is an error.
+func GetKeyVaultPermissionModel(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string) KeyVaultPermissionModel {
+	model, err := GetKeyVaultPermissionModelE(t, resGroupName, keyVaultName, subscriptionID)
+	require.NoError(t, err)
+	return model
+}
+
+// GetKeyVaultPermissionModelE gets the permission model of a key vault, i.e., whether it authorizes data plane
+// operations with its access policies or with Azure role assignments.
+func GetKeyVaultPermissionModelE(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string) (KeyVaultPermissionModel, error) {
+	vault, err := GetKeyVaultE(t, resGroupName, keyVaultName, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+	return getKeyVaultPermissionModel(vault), nil
+}
+
+// getKeyVaultPermissionModel returns the permission model of the given key vault.
+func getKeyVaultPermissionModel(vault *kvmng.Vault) KeyVaultPermissionModel {
+	if vault.Properties != nil && to.Bool(vault.Properties.EnableRbacAuthorization) {
+		return KeyVaultRbacModel
+	}
+	return KeyVaultAccessPolicyModel
+}
+
+// AssertKeyVaultAccessPolicy checks that an access policy of a key vault grants the given principal the given
+// permissions. This function would fail the test if it does not.
+func AssertKeyVaultAccessPolicy(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string, objectID string, secretPermissions []string, keyPermissions []string, certificatePermissions []string) {
+	err := AssertKeyVaultAccessPolicyE(t, resGroupName, keyVaultName, subscriptionID, objectID, secretPermissions, keyPermissions, certificatePermissions)
+	require.NoError(t, err)
+}
+
+// AssertKeyVaultAccessPolicyE checks that the access policies of a key vault grant the principal with the given object
+// ID the given secret, key and certificate permissions (e.g., get and list), compared case-insensitively. This only
+// applies to vaults that use the access policy permission model: use AssertKeyVaultRoleAssignmentE for the others.
+func AssertKeyVaultAccessPolicyE(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string, objectID string, secretPermissions []string, keyPermissions []string, certificatePermissions []string) error {
+	vault, err := GetKeyVaultE(t, resGroupName, keyVaultName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkKeyVaultAccessPolicy(vault, objectID, secretPermissions, keyPermissions, certificatePermissions)
+}
+
+// checkKeyVaultAccessPolicy returns an error if the access policies of the given key vault don't grant the given
+// permissions to the given principal.
+func checkKeyVaultAccessPolicy(vault *kvmng.Vault, objectID string, secretPermissions []string, keyPermissions []string, certificatePermissions []string) error {
+	if getKeyVaultPermissionModel(vault) == KeyVaultRbacModel {
+		return fmt.Errorf("key vault %s uses the RBAC permission model, so its access policies are ignored", to.String(vault.Name))
+	}
+
+	granted := map[string][]string{}
+	if vault.Properties != nil && vault.Properties.AccessPolicies != nil {
+		for _, policy := range *vault.Properties.AccessPolicies {
+			if !strings.EqualFold(to.String(policy.ObjectID), objectID) || policy.Permissions == nil {
+				continue
+			}
+			if policy.Permissions.Secrets != nil {
+				for _, permission := range *policy.Permissions.Secrets {
+					granted["secret"] = append(granted["secret"], string(permission))
+				}
+			}
+			if policy.Permissions.Keys != nil {
+				for _, permission := range *policy.Permissions.Keys {
+					granted["key"] = append(granted["key"], string(permission))
+				}
+			}
+			if policy.Permissions.Certificates != nil {
+				for _, permission := range *policy.Permissions.Certificates {
+					granted["certificate"] = append(granted["certificate"], string(permission))
+				}
+			}
+		}
+	}
+
+	missing := []string{}
+	for kind, expected := range map[string][]string{"secret": secretPermissions, "key": keyPermissions, "certificate": certificatePermissions} {
+		for _, permission := range expected {
+			if !keyVaultPermissionGranted(granted[kind], permission) {
+				missing = append(missing, kind+" "+permission)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("key vault %s does not grant %s permissions to %s", to.String(vault.Name), strings.Join(missing, ", "), objectID)
+	}
+	return nil
+}
+
+// keyVaultPermissionGranted returns true if the given permission is one of the granted permissions, or if all
+// permissions are granted.
+func keyVaultPermissionGranted(granted []string, permission string) bool {
+	for _, grantedPermission := range granted {
+		if strings.EqualFold(grantedPermission, permission) || strings.EqualFold(grantedPermission, "all") {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertKeyVaultRoleAssignment checks that a principal is assigned a role on a key vault or one of its parent scopes.
+// This function would fail the test if it is not.
+func AssertKeyVaultRoleAssignment(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string, principalID string, roleName string) {
+	err := AssertKeyVaultRoleAssignmentE(t, resGroupName, keyVaultName, subscriptionID, principalID, roleName)
+	require.NoError(t, err)
+}
+
+// AssertKeyVaultRoleAssignmentE checks that the principal with the given object ID is assigned the role with the given
+// name (e.g., Key Vault Secrets User) on a key vault, or on its resource group or subscription. This only applies to
+// vaults that use the RBAC permission model: use AssertKeyVaultAccessPolicyE for the others.
+func AssertKeyVaultRoleAssignmentE(t *testing.T, resGroupName string, keyVaultName string, subscriptionID string, principalID string, roleName string) error {
+	vault, err := GetKeyVaultE(t, resGroupName, keyVaultName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if getKeyVaultPermissionModel(vault) != KeyVaultRbacModel {
+		return fmt.Errorf("key vault %s uses the access policy permission model, so role assignments don't grant data plane access", keyVaultName)
+	}
+
+	assignmentsClient, err := CreateRoleAssignmentsClientE(subscriptionID)
+	if err != nil {
+		return err
+	}
+	definitionsClient, err := CreateRoleDefinitionsClientE(subscriptionID)
+	if err != nil {
+		return err
+	}
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return err
+	}
+	assignmentsClient.Authorizer = *authorizer
+	definitionsClient.Authorizer = *authorizer
+
+	vaultID := to.String(vault.ID)
+	filter := fmt.Sprintf("principalId eq '%s'", principalID)
+	assignments, err := assignmentsClient.ListForScopeComplete(context.Background(), vaultID, filter)
+	if err != nil {
+		return err
+	}
+	for ; assignments.NotDone(); err = assignments.NextWithContext(context.Background()) {
+		if err != nil {
+			return err
+		}
+		properties := assignments.Value().Properties
+		if properties == nil || !roleAssignmentScopeApplies(to.String(properties.Scope), vaultID) {
+			continue
+		}
+		definition, err := definitionsClient.GetByID(context.Background(), to.String(properties.RoleDefinitionID))
+		if err != nil {
+			return err
+		}
+		if definition.RoleDefinitionProperties != nil && strings.EqualFold(to.String(definition.RoleDefinitionProperties.RoleName), roleName) {
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s is not assigned role %s on key vault %s", principalID, roleName, keyVaultName)
+}
+
+// roleAssignmentScopeApplies returns true if a role assignment with the given scope applies to the resource with the
+// given ID, i.e., if the scope is the resource or one of its parents.
+func roleAssignmentScopeApplies(scope string, resourceID string) bool {
+	scope = strings.ToLower(strings.TrimSuffix(scope, "/"))
+	resourceID = strings.ToLower(resourceID)
+	return scope == "" || resourceID == scope || strings.HasPrefix(resourceID, scope+"/")
+}
+
+// AssertKeyVaultKeySignVerify checks that a key vault key can sign a digest and verify the signature.
+// This function would fail the test if it can't.
+func AssertKeyVaultKeySignVerify(t *testing.T, keyVaultName string, keyName string) {
+	err := AssertKeyVaultKeySignVerifyE(keyVaultName, keyName)
+	require.NoError(t, err)
+}
+
+// AssertKeyVaultKeySignVerifyE checks that a key vault key can sign the digest of a random message and verify the
+// signature, with an algorithm that suits the type of the key (RS256 for RSA keys and ECDSA with the curve of EC
+// keys). This checks that the key allows the sign and verify operations, and that the caller is authorized to perform
+// them, with either permission model.
+func AssertKeyVaultKeySignVerifyE(keyVaultName, keyName string) error {
+	key, err := GetKeyVaultKeyE(keyVaultName, keyName)
+	if err != nil {
+		return err
+	}
+	algorithm, hasher, err := getKeyVaultSignatureAlgorithm(key.Key)
+	if err != nil {
+		return err
+	}
+
+	client, err := GetKeyVaultClientE()
+	if err != nil {
+		return err
+	}
+	vaultURL, err := getKeyVaultURLE(keyVaultName)
+	if err != nil {
+		return err
+	}
+
+	hasher.Write([]byte(random.UniqueId()))
+	digest := base64.RawURLEncoding.EncodeToString(hasher.Sum(nil))
+	signature, err := client.Sign(context.Background(), vaultURL, keyName, "", keyvault.KeySignParameters{Algorithm: algorithm, Value: &digest})
+	if err != nil {
+		return err
+	}
+	result, err := client.Verify(context.Background(), vaultURL, keyName, "", keyvault.KeyVerifyParameters{Algorithm: algorithm, Digest: &digest, Signature: signature.Result})
+	if err != nil {
+		return err
+	}
+	if !to.Bool(result.Value) {
+		return fmt.Errorf("key %s of key vault %s could not verify its own %s signature", keyName, keyVaultName, algorithm)
+	}
+	return nil
+}
+
+// getKeyVaultSignatureAlgorithm returns the signature algorithm to test the given key with, and the hash to compute
+// the digests to sign with.
+func getKeyVaultSignatureAlgorithm(key *keyvault.JSONWebKey) (keyvault.JSONWebKeySignatureAlgorithm, hash.Hash, error) {
+	if key == nil {
+		return "", nil, fmt.Errorf("key has no public part")
+	}
+	switch key.Kty {
+	case keyvault.RSA, keyvault.RSAHSM:
+		return keyvault.RS256, sha256.New(), nil
+	case keyvault.EC, keyvault.ECHSM:
+		switch key.Crv {
+		case keyvault.P256:
+			return keyvault.ES256, sha256.New(), nil
+		case keyvault.P256K:
+			return keyvault.ES256K, sha256.New(), nil
+		case keyvault.P384:
+			return keyvault.ES384, sha512.New384(), nil
+		case keyvault.P521:
+			return keyvault.ES512, sha512.New(), nil
+		}
+		return "", nil, fmt.Errorf("unsupported elliptic curve %s", key.Crv)
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %s", key.Kty)
+	}
+}
+
+// AssertKeyVaultKeyEncryptDecrypt checks that a key vault key can encrypt data and decrypt it back.
+// This function would fail the test if it can't.
+func AssertKeyVaultKeyEncryptDecrypt(t *testing.T, keyVaultName string, keyName string) {
+	err := AssertKeyVaultKeyEncryptDecryptE(keyVaultName, keyName)
+	require.NoError(t, err)
+}
+
+// AssertKeyVaultKeyEncryptDecryptE checks that a key vault key can encrypt a random message with RSA-OAEP and decrypt
+// it back, e.g., to test that a key used for customer-managed encryption works. Only RSA keys support encryption.
+func AssertKeyVaultKeyEncryptDecryptE(keyVaultName, keyName string) error {
+	client, err := GetKeyVaultClientE()
+	if err != nil {
+		return err
+	}
+	vaultURL, err := getKeyVaultURLE(keyVaultName)
+	if err != nil {
+		return err
+	}
+
+	plaintext := base64.RawURLEncoding.EncodeToString([]byte(random.UniqueId()))
+	encrypted, err := client.Encrypt(context.Background(), vaultURL, keyName, "", keyvault.KeyOperationsParameters{Algorithm: keyvault.RSAOAEP, Value: &plaintext})
+	if err != nil {
+		return err
+	}
+	decrypted, err := client.Decrypt(context.Background(), vaultURL, keyName, "", keyvault.KeyOperationsParameters{Algorithm: keyvault.RSAOAEP, Value: encrypted.Result})
+	if err != nil {
+		return err
+	}
+	if to.String(decrypted.Result) != plaintext {
+		return fmt.Errorf("key %s of key vault %s did not decrypt what it encrypted back", keyName, keyVaultName)
+	}
+	return nil
+}
@@ -3,6 +3,10 @@ package azure
 import (
 	"testing"
 
+	kvmng "github.com/Azure/azure-sdk-for-go/services/keyvault/mgmt/2019-09-01/keyvault"
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.0/keyvault"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,3 +52,85 @@ func TestGetKeyVault(t *testing.T) {
 	_, err := GetKeyVaultE(t, resGroupName, keyVaultName, subscriptionID)
 	require.Error(t, err)
 }
+
+func TestGetKeyVaultSecretValue(t *testing.T) {
+	t.Parallel()
+
+	testKeyVaultName := "fakeKeyVault"
+	testKeyVaultSecretName := "fakeSecretName"
+	_, err := GetKeyVaultSecretValueE(testKeyVaultName, testKeyVaultSecretName)
+	require.Error(t, err)
+}
+
+func TestGetKeyVaultKey(t *testing.T) {
+	t.Parallel()
+
+	testKeyVaultName := "fakeKeyVault"
+	testKeyVaultKeyName := "fakeKeyName"
+	_, err := GetKeyVaultKeyE(testKeyVaultName, testKeyVaultKeyName)
+	require.Error(t, err)
+}
+
+func TestCheckKeyVaultSoftDelete(t *testing.T) {
+	t.Parallel()
+
+	vault := &kvmng.Vault{Name: to.StringPtr("kv"), Properties: &kvmng.VaultProperties{SoftDeleteRetentionInDays: to.Int32Ptr(90)}}
+	assert.NoError(t, checkKeyVaultSoftDelete(vault, 0))
+	assert.NoError(t, checkKeyVaultSoftDelete(vault, 90))
+	assert.Error(t, checkKeyVaultSoftDelete(vault, 7))
+
+	vault.Properties.EnableSoftDelete = to.BoolPtr(false)
+	assert.Error(t, checkKeyVaultSoftDelete(vault, 0))
+}
+
+func TestCheckKeyVaultAccessPolicy(t *testing.T) {
+	t.Parallel()
+
+	vault := &kvmng.Vault{Name: to.StringPtr("kv"), Properties: &kvmng.VaultProperties{AccessPolicies: &[]kvmng.AccessPolicyEntry{
+		{
+			ObjectID: to.StringPtr("00000000-0000-0000-0000-000000000001"),
+			Permissions: &kvmng.Permissions{
+				Secrets: &[]kvmng.SecretPermissions{kvmng.SecretPermissionsGet, kvmng.SecretPermissionsList},
+				Keys:    &[]kvmng.KeyPermissions{kvmng.KeyPermissionsAll},
+			},
+		},
+	}}}
+	objectID := "00000000-0000-0000-0000-000000000001"
+
+	assert.NoError(t, checkKeyVaultAccessPolicy(vault, objectID, []string{"Get", "list"}, []string{"sign", "verify"}, nil))
+	err := checkKeyVaultAccessPolicy(vault, objectID, []string{"set"}, nil, []string{"get"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate get, secret set")
+	assert.Error(t, checkKeyVaultAccessPolicy(vault, "00000000-0000-0000-0000-000000000002", []string{"get"}, nil, nil))
+
+	vault.Properties.EnableRbacAuthorization = to.BoolPtr(true)
+	assert.Equal(t, KeyVaultRbacModel, getKeyVaultPermissionModel(vault))
+	assert.Error(t, checkKeyVaultAccessPolicy(vault, objectID, []string{"get"}, nil, nil))
+}
+
+func TestRoleAssignmentScopeApplies(t *testing.T) {
+	t.Parallel()
+
+	vaultID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv"
+	assert.True(t, roleAssignmentScopeApplies(vaultID, vaultID))
+	assert.True(t, roleAssignmentScopeApplies("/subscriptions/sub/resourcegroups/RG", vaultID))
+	assert.True(t, roleAssignmentScopeApplies("/", vaultID))
+	assert.False(t, roleAssignmentScopeApplies("/subscriptions/sub/resourceGroups/rg2", vaultID))
+	assert.False(t, roleAssignmentScopeApplies("/subscriptions/sub/resourceGroups/r", vaultID))
+}
+
+func TestGetKeyVaultSignatureAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	algorithm, _, err := getKeyVaultSignatureAlgorithm(&keyvault.JSONWebKey{Kty: keyvault.RSAHSM})
+	require.NoError(t, err)
+	assert.Equal(t, keyvault.RS256, algorithm)
+
+	algorithm, hasher, err := getKeyVaultSignatureAlgorithm(&keyvault.JSONWebKey{Kty: keyvault.EC, Crv: keyvault.P384})
+	require.NoError(t, err)
+	assert.Equal(t, keyvault.ES384, algorithm)
+	assert.Equal(t, 48, hasher.Size())
+
+	_, _, err = getKeyVaultSignatureAlgorithm(&keyvault.JSONWebKey{Kty: keyvault.Oct})
+	assert.Error(t, err)
+}
@@ -2,12 +2,22 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/profiles/preview/preview/monitor/mgmt/insights"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
 
+// loadBalancerHealthProbeMetric is the Azure Monitor metric with the health probe status of the backends of a Standard
+// Load Balancer, i.e., the percentage of successful probes.
+const loadBalancerHealthProbeMetric = "DipAvailability"
+
 // LoadBalancerExists indicates whether the specified Load Balancer exists.
 // This function would fail the test if there is an error.
 func LoadBalancerExists(t testing.TestingT, loadBalancerName string, resourceGroupName string, subscriptionID string) bool {
@@ -198,3 +208,167 @@ func GetLoadBalancerClientE(subscriptionID string) (*network.LoadBalancersClient
 
 	return client, nil
 }
+
+// AssertLoadBalancerRule checks that the load balancing rule of the Load Balancer forwards the given frontend port to the
+// given backend port of the given backend pool, checked with the given health probe.
+// This function would fail the test if there is an error or it does not.
+func AssertLoadBalancerRule(t testing.TestingT, ruleName string, frontendPort int32, backendPort int32, backendPoolName string, probeName string, loadBalancerName string, resourceGroupName string, subscriptionID string) {
+	err := AssertLoadBalancerRuleE(ruleName, frontendPort, backendPort, backendPoolName, probeName, loadBalancerName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertLoadBalancerRuleE checks that the load balancing rule of the Load Balancer forwards the given frontend port to
+// the given backend port of the given backend pool, checked with the given health probe (ignored if empty).
+func AssertLoadBalancerRuleE(ruleName string, frontendPort int32, backendPort int32, backendPoolName string, probeName string, loadBalancerName string, resourceGroupName string, subscriptionID string) error {
+	lb, err := GetLoadBalancerE(loadBalancerName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkLoadBalancerRule(lb, ruleName, frontendPort, backendPort, backendPoolName, probeName)
+}
+
+// checkLoadBalancerRule returns an error if the given Load Balancer has no load balancing rule with the given name, or
+// if its ports, backend pool or health probe are not the given ones.
+func checkLoadBalancerRule(lb *network.LoadBalancer, ruleName string, frontendPort int32, backendPort int32, backendPoolName string, probeName string) error {
+	lbName := to.String(lb.Name)
+	if lb.LoadBalancerPropertiesFormat == nil || lb.LoadBalancingRules == nil {
+		return NewNotFoundError("Load balancing rule", ruleName, lbName)
+	}
+
+	for _, rule := range *lb.LoadBalancingRules {
+		if to.String(rule.Name) != ruleName || rule.LoadBalancingRulePropertiesFormat == nil {
+			continue
+		}
+		if actual := to.Int32(rule.FrontendPort); actual != frontendPort {
+			return fmt.Errorf("Load balancing rule %s of Load Balancer %s has frontend port %d, not %d", ruleName, lbName, actual, frontendPort)
+		}
+		if actual := to.Int32(rule.BackendPort); actual != backendPort {
+			return fmt.Errorf("Load balancing rule %s of Load Balancer %s has backend port %d, not %d", ruleName, lbName, actual, backendPort)
+		}
+		if actual := subResourceName(rule.BackendAddressPool); actual != backendPoolName {
+			return fmt.Errorf("Load balancing rule %s of Load Balancer %s has backend pool %q, not %q", ruleName, lbName, actual, backendPoolName)
+		}
+		if actual := subResourceName(rule.Probe); probeName != "" && actual != probeName {
+			return fmt.Errorf("Load balancing rule %s of Load Balancer %s has health probe %q, not %q", ruleName, lbName, actual, probeName)
+		}
+		return nil
+	}
+	return NewNotFoundError("Load balancing rule", ruleName, lbName)
+}
+
+// GetLoadBalancerBackendHealth gets the health probe status of the backends of the Load Balancer, by IP address.
+// This function would fail the test if there is an error.
+func GetLoadBalancerBackendHealth(t testing.TestingT, loadBalancerName string, resourceGroupName string, subscriptionID string) map[string]float64 {
+	health, err := GetLoadBalancerBackendHealthE(loadBalancerName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return health
+}
+
+// GetLoadBalancerBackendHealthE gets the health probe status of the backends of the Load Balancer, by IP address: the
+// percentage of successful health probes in the last minute that has data. Azure only reports this metric for Standard
+// Load Balancers, a few minutes after the probes start.
+func GetLoadBalancerBackendHealthE(loadBalancerName string, resourceGroupName string, subscriptionID string) (map[string]float64, error) {
+	lb, err := GetLoadBalancerE(loadBalancerName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := CreateMetricsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	end := time.Now().UTC()
+	timespan := fmt.Sprintf("%s/%s", end.Add(-10*time.Minute).Format(time.RFC3339), end.Format(time.RFC3339))
+	metrics, err := client.List(context.Background(), to.String(lb.ID), timespan, to.StringPtr("PT1M"), loadBalancerHealthProbeMetric, "Average", nil, "", "BackendIPAddress eq '*'", insights.Data, "Microsoft.Network/loadBalancers")
+	if err != nil {
+		return nil, err
+	}
+	return parseLoadBalancerBackendHealth(metrics), nil
+}
+
+// parseLoadBalancerBackendHealth returns the latest average of each time series of the given health probe status
+// metrics, by the backend IP address of the time series.
+func parseLoadBalancerBackendHealth(metrics insights.Response) map[string]float64 {
+	health := map[string]float64{}
+	if metrics.Value == nil {
+		return health
+	}
+	for _, metric := range *metrics.Value {
+		if metric.Timeseries == nil {
+			continue
+		}
+		for _, series := range *metric.Timeseries {
+			backendIPAddress := ""
+			if series.Metadatavalues != nil {
+				for _, metadata := range *series.Metadatavalues {
+					if metadata.Name != nil && to.String(metadata.Name.Value) == "backendipaddress" {
+						backendIPAddress = to.String(metadata.Value)
+					}
+				}
+			}
+			if backendIPAddress == "" || series.Data == nil {
+				continue
+			}
+			// The latest minutes may not have been aggregated yet
+			for i := len(*series.Data) - 1; i >= 0; i-- {
+				if average := (*series.Data)[i].Average; average != nil {
+					health[backendIPAddress] = *average
+					break
+				}
+			}
+		}
+	}
+	return health
+}
+
+// WaitUntilLoadBalancerBackendsHealthy waits until at least the given number of backends of the Load Balancer are
+// healthy, retrying the check for the specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the backends are not healthy in time.
+func WaitUntilLoadBalancerBackendsHealthy(t testing.TestingT, expectedBackends int, loadBalancerName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilLoadBalancerBackendsHealthyE(t, expectedBackends, loadBalancerName, resourceGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilLoadBalancerBackendsHealthyE waits until at least the given number of backends of the Load Balancer are
+// healthy (i.e., all their health probes succeed) and no backend is unhealthy, retrying the check for the specified
+// amount of times, sleeping for the provided duration between each try.
+func WaitUntilLoadBalancerBackendsHealthyE(t testing.TestingT, expectedBackends int, loadBalancerName string, resourceGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %d backends of Load Balancer %s to be healthy.", expectedBackends, loadBalancerName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			health, err := GetLoadBalancerBackendHealthE(loadBalancerName, resourceGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			if err := checkLoadBalancerBackendHealth(health, expectedBackends, loadBalancerName); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Backends of Load Balancer %s are healthy", loadBalancerName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkLoadBalancerBackendHealth returns an error if the given health probe status has fewer than the expected number
+// of backends or a backend for which some probes failed.
+func checkLoadBalancerBackendHealth(health map[string]float64, expectedBackends int, loadBalancerName string) error {
+	for backendIPAddress, availability := range health {
+		if availability < 100 {
+			return fmt.Errorf("Backend %s of Load Balancer %s has a health probe status of %.0f%%", backendIPAddress, loadBalancerName, availability)
+		}
+	}
+	if len(health) < expectedBackends {
+		return fmt.Errorf("Load Balancer %s has %d healthy backends, expected %d", loadBalancerName, len(health), expectedBackends)
+	}
+	return nil
+}
@@ -9,6 +9,10 @@ package azure
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/profiles/preview/preview/monitor/mgmt/insights"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,3 +44,83 @@ func TestGetLoadBalancerE(t *testing.T) {
 
 	require.Error(t, err)
 }
+
+func TestAssertLoadBalancerRuleE(t *testing.T) {
+	t.Parallel()
+
+	err := AssertLoadBalancerRuleE("", 80, 80, "", "", "", "", "")
+
+	require.Error(t, err)
+}
+
+func TestGetLoadBalancerBackendHealthE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetLoadBalancerBackendHealthE("", "", "")
+
+	require.Error(t, err)
+}
+
+func TestCheckLoadBalancerRule(t *testing.T) {
+	t.Parallel()
+
+	lbID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb"
+	lb := &network.LoadBalancer{
+		Name: to.StringPtr("lb"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			LoadBalancingRules: &[]network.LoadBalancingRule{{
+				Name: to.StringPtr("http"),
+				LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+					FrontendPort:       to.Int32Ptr(80),
+					BackendPort:        to.Int32Ptr(8080),
+					BackendAddressPool: &network.SubResource{ID: to.StringPtr(lbID + "/backendAddressPools/web")},
+					Probe:              &network.SubResource{ID: to.StringPtr(lbID + "/probes/http-probe")},
+				},
+			}},
+		},
+	}
+
+	assert.NoError(t, checkLoadBalancerRule(lb, "http", 80, 8080, "web", "http-probe"))
+	assert.NoError(t, checkLoadBalancerRule(lb, "http", 80, 8080, "web", ""))
+	assert.Error(t, checkLoadBalancerRule(lb, "http", 443, 8080, "web", ""))
+	assert.Error(t, checkLoadBalancerRule(lb, "http", 80, 80, "web", ""))
+	assert.Error(t, checkLoadBalancerRule(lb, "http", 80, 8080, "api", ""))
+	assert.Error(t, checkLoadBalancerRule(lb, "http", 80, 8080, "web", "tcp-probe"))
+	assert.IsType(t, NotFoundError{}, checkLoadBalancerRule(lb, "https", 443, 8443, "web", ""))
+}
+
+func TestParseLoadBalancerBackendHealth(t *testing.T) {
+	t.Parallel()
+
+	newSeries := func(backendIPAddress string, averages ...*float64) insights.TimeSeriesElement {
+		data := []insights.MetricValue{}
+		for _, average := range averages {
+			data = append(data, insights.MetricValue{Average: average})
+		}
+		return insights.TimeSeriesElement{
+			Metadatavalues: &[]insights.MetadataValue{{
+				Name:  &insights.LocalizableString{Value: to.StringPtr("backendipaddress")},
+				Value: to.StringPtr(backendIPAddress),
+			}},
+			Data: &data,
+		}
+	}
+	metrics := insights.Response{Value: &[]insights.Metric{{
+		Timeseries: &[]insights.TimeSeriesElement{
+			newSeries("10.0.1.4", to.Float64Ptr(0), to.Float64Ptr(100), nil),
+			newSeries("10.0.1.5", to.Float64Ptr(100), to.Float64Ptr(50)),
+		},
+	}}}
+
+	health := parseLoadBalancerBackendHealth(metrics)
+	assert.Equal(t, map[string]float64{"10.0.1.4": 100, "10.0.1.5": 50}, health)
+	assert.Empty(t, parseLoadBalancerBackendHealth(insights.Response{}))
+}
+
+func TestCheckLoadBalancerBackendHealth(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkLoadBalancerBackendHealth(map[string]float64{"10.0.1.4": 100, "10.0.1.5": 100}, 2, "lb"))
+	assert.Error(t, checkLoadBalancerBackendHealth(map[string]float64{"10.0.1.4": 100, "10.0.1.5": 50}, 2, "lb"))
+	assert.Error(t, checkLoadBalancerBackendHealth(map[string]float64{"10.0.1.4": 100}, 2, "lb"))
+}
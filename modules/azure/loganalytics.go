@@ -3,12 +3,32 @@ package azure
 import (
 	"context"
 	"fmt"
+	"time"
 
+	loganalytics "github.com/Azure/azure-sdk-for-go/services/operationalinsights/v1/operationalinsights"
 	"github.com/Azure/azure-sdk-for-go/services/preview/operationalinsights/mgmt/2020-03-01-preview/operationalinsights"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
 
+// logAnalyticsQueryRetryInterval is how long WaitForKqlResultE sleeps between queries, as Log Analytics takes minutes
+// to ingest data.
+const logAnalyticsQueryRetryInterval = 30 * time.Second
+
+// KqlResultPredicate is a condition on the rows returned by a KQL query, for WaitForKqlResultE. Each row maps the
+// column names to their values, as decoded from JSON (i.e., numbers are float64).
+type KqlResultPredicate func(rows []map[string]interface{}) bool
+
+// KqlResultHasRows returns a KqlResultPredicate that holds if the query returns at least the given number of rows.
+func KqlResultHasRows(minRows int) KqlResultPredicate {
+	return func(rows []map[string]interface{}) bool {
+		return len(rows) >= minRows
+	}
+}
+
 // LogAnalyticsWorkspaceExists indicates whether the operatonal insights workspaces exists.
 // This function would fail the test if there is an error.
 func LogAnalyticsWorkspaceExists(t testing.TestingT, workspaceName string, resourceGroupName string, subscriptionID string) bool {
@@ -70,3 +90,140 @@ func GetLogAnalyticsWorkspacesClientE(subscriptionID string) (*operationalinsigh
 	client.Authorizer = *authorizer
 	return &client, nil
 }
+
+// GetLogAnalyticsWorkspaceID gets the ID of the operational insights workspace, i.e., its customer ID, which KQL
+// queries take. This function would fail the test if there is an error.
+func GetLogAnalyticsWorkspaceID(t testing.TestingT, workspaceName string, resourceGroupName string, subscriptionID string) string {
+	workspaceID, err := GetLogAnalyticsWorkspaceIDE(workspaceName, resourceGroupName, subscriptionID)
+	require.NoError(t, err)
+	return workspaceID
+}
+
+// GetLogAnalyticsWorkspaceIDE gets the ID of the operational insights workspace, i.e., its customer ID, which KQL
+// queries take.
+func GetLogAnalyticsWorkspaceIDE(workspaceName string, resourceGroupName string, subscriptionID string) (string, error) {
+	ws, err := GetLogAnalyticsWorkspaceE(workspaceName, resourceGroupName, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+	if ws.WorkspaceProperties == nil || ws.CustomerID == nil {
+		return "", fmt.Errorf("workspace %s has no customer ID", workspaceName)
+	}
+	return *ws.CustomerID, nil
+}
+
+// GetLogAnalyticsQueryClientE returns a Log Analytics query client; otherwise error.
+func GetLogAnalyticsQueryClientE() (*loganalytics.QueryClient, error) {
+	client, err := CreateLogAnalyticsQueryClientE()
+	if err != nil {
+		return nil, err
+	}
+
+	// The query API takes access tokens for itself rather than for Azure Resource Manager
+	resource, err := getLogAnalyticsResourceE()
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := newAuthorizerWithResourceE(AuthOptions{}, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return client, nil
+}
+
+// QueryLogAnalyticsWorkspace runs the KQL query on the workspace of the given ID and returns the rows of its primary
+// result. This function would fail the test if there is an error.
+func QueryLogAnalyticsWorkspace(t testing.TestingT, workspaceID string, kqlQuery string) []map[string]interface{} {
+	rows, err := QueryLogAnalyticsWorkspaceE(workspaceID, kqlQuery)
+	require.NoError(t, err)
+	return rows
+}
+
+// QueryLogAnalyticsWorkspaceE runs the KQL query on the workspace of the given ID and returns the rows of its primary
+// result. Each row maps the column names to their values, as decoded from JSON (i.e., numbers are float64).
+func QueryLogAnalyticsWorkspaceE(workspaceID string, kqlQuery string) ([]map[string]interface{}, error) {
+	client, err := GetLogAnalyticsQueryClientE()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := client.Execute(context.Background(), workspaceID, loganalytics.QueryBody{Query: to.StringPtr(kqlQuery)})
+	if err != nil {
+		return nil, err
+	}
+	return parseLogAnalyticsQueryResults(results)
+}
+
+// WaitForKqlResult runs the KQL query on the workspace of the given ID until the rows it returns satisfy the
+// predicate, or the timeout expires, and returns these rows.
+// This function would fail the test if there is an error or the timeout expires.
+func WaitForKqlResult(t testing.TestingT, workspaceID string, kqlQuery string, predicate KqlResultPredicate, timeout time.Duration) []map[string]interface{} {
+	rows, err := WaitForKqlResultE(t, workspaceID, kqlQuery, predicate, timeout)
+	require.NoError(t, err)
+	return rows
+}
+
+// WaitForKqlResultE runs the KQL query on the workspace of the given ID until the rows it returns satisfy the
+// predicate, or the timeout expires, and returns these rows. This checks that diagnostic settings and agents actually
+// deliver data to the workspace, which takes a few minutes after they are setup, e.g.:
+//
+//	WaitForKqlResultE(t, workspaceID, "AzureDiagnostics | where ResourceId =~ '"+keyVaultID+"'", KqlResultHasRows(1), 15*time.Minute)
+//
+// Invalid queries are not retried.
+func WaitForKqlResultE(t testing.TestingT, workspaceID string, kqlQuery string, predicate KqlResultPredicate, timeout time.Duration) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	maxRetries := int(timeout/logAnalyticsQueryRetryInterval) + 1
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the result of KQL query %q on workspace %s.", kqlQuery, workspaceID),
+		maxRetries,
+		logAnalyticsQueryRetryInterval,
+		func() (string, error) {
+			var err error
+			rows, err = QueryLogAnalyticsWorkspaceE(workspaceID, kqlQuery)
+			if err != nil {
+				if isBadRequestError(err) {
+					return "", retry.FatalError{Underlying: err}
+				}
+				return "", err
+			}
+			if !predicate(rows) {
+				return "", fmt.Errorf("KQL query returned %d rows that do not satisfy the predicate", len(rows))
+			}
+			return fmt.Sprintf("KQL query returned %d rows that satisfy the predicate", len(rows)), nil
+		},
+	)
+	logger.Logf(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// parseLogAnalyticsQueryResults returns the rows of the primary result of a Log Analytics query, i.e., its first
+// table, as maps of the column names to their values.
+func parseLogAnalyticsQueryResults(results loganalytics.QueryResults) ([]map[string]interface{}, error) {
+	rows := []map[string]interface{}{}
+	if results.Tables == nil || len(*results.Tables) == 0 {
+		return rows, nil
+	}
+	table := (*results.Tables)[0]
+	if table.Columns == nil || table.Rows == nil {
+		return rows, nil
+	}
+
+	columns := *table.Columns
+	for _, values := range *table.Rows {
+		if len(values) != len(columns) {
+			return nil, fmt.Errorf("row of table %s has %d values for %d columns", to.String(table.Name), len(values), len(columns))
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[to.String(column.Name)] = values[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -1,8 +1,12 @@
 package azure
 
 import (
+	"net/http"
 	"testing"
 
+	loganalytics "github.com/Azure/azure-sdk-for-go/services/operationalinsights/v1/operationalinsights"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -28,3 +32,45 @@ func TestGetLogAnalyticsWorkspaceE(t *testing.T) {
 	_, err := GetLogAnalyticsWorkspaceE(workspaceName, resourceGroupName, subscriptionID)
 	require.Error(t, err)
 }
+
+func TestParseLogAnalyticsQueryResults(t *testing.T) {
+	t.Parallel()
+
+	results := loganalytics.QueryResults{Tables: &[]loganalytics.Table{
+		{
+			Name:    to.StringPtr("PrimaryResult"),
+			Columns: &[]loganalytics.Column{{Name: to.StringPtr("Computer"), Type: to.StringPtr("string")}, {Name: to.StringPtr("count_"), Type: to.StringPtr("long")}},
+			Rows:    &[][]interface{}{{"vm-1", float64(12)}, {"vm-2", float64(3)}},
+		},
+	}}
+
+	rows, err := parseLogAnalyticsQueryResults(results)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"Computer": "vm-1", "count_": float64(12)}, {"Computer": "vm-2", "count_": float64(3)}}, rows)
+
+	rows, err = parseLogAnalyticsQueryResults(loganalytics.QueryResults{})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	(*results.Tables)[0].Rows = &[][]interface{}{{"vm-1"}}
+	_, err = parseLogAnalyticsQueryResults(results)
+	assert.Error(t, err)
+}
+
+func TestKqlResultHasRows(t *testing.T) {
+	t.Parallel()
+
+	rows := []map[string]interface{}{{"Computer": "vm-1"}, {"Computer": "vm-2"}}
+	assert.True(t, KqlResultHasRows(1)(rows))
+	assert.True(t, KqlResultHasRows(2)(rows))
+	assert.False(t, KqlResultHasRows(3)(rows))
+	assert.False(t, KqlResultHasRows(1)(nil))
+}
+
+func TestIsBadRequestError(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isBadRequestError(autorest.DetailedError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, isBadRequestError(autorest.DetailedError{StatusCode: http.StatusForbidden}))
+	assert.False(t, isBadRequestError(assert.AnError))
+}
@@ -2,8 +2,13 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/collections"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -174,3 +179,137 @@ func GetNetworkInterfaceClientE(subscriptionID string) (*network.InterfacesClien
 
 	return client, nil
 }
+
+// EffectiveRouteSummary is a string-based (non-pointer) summary of an effective route of a Network Interface, i.e., a
+// route of the system, its subnet's route table or BGP that applies to its traffic.
+type EffectiveRouteSummary struct {
+	Name               string
+	Source             string
+	State              string
+	AddressPrefixes    []string
+	NextHopType        string
+	NextHopIPAddresses []string
+}
+
+// GetNetworkInterfaceEffectiveRoutes gets summaries of the effective routes of the Network Interface.
+// This function would fail the test if there is an error.
+func GetNetworkInterfaceEffectiveRoutes(t testing.TestingT, nicName string, resGroupName string, subscriptionID string) []EffectiveRouteSummary {
+	routes, err := GetNetworkInterfaceEffectiveRoutesE(nicName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return routes
+}
+
+// GetNetworkInterfaceEffectiveRoutesE gets summaries of the effective routes of the Network Interface. Azure only
+// computes them for Network Interfaces attached to a running Virtual Machine.
+func GetNetworkInterfaceEffectiveRoutesE(nicName string, resGroupName string, subscriptionID string) ([]EffectiveRouteSummary, error) {
+	// Validate Azure Resource Group
+	resGroupName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the client reference
+	client, err := GetNetworkInterfaceClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the effective routes, which is a long running operation
+	future, err := client.GetEffectiveRouteTable(context.Background(), resGroupName, nicName)
+	if err != nil {
+		return nil, err
+	}
+	if err := future.WaitForCompletionRef(context.Background(), client.Client); err != nil {
+		return nil, err
+	}
+	result, err := future.Result(*client)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []EffectiveRouteSummary{}
+	if result.Value == nil {
+		return routes, nil
+	}
+	for _, route := range *result.Value {
+		routes = append(routes, convertToEffectiveRouteSummary(route))
+	}
+	return routes, nil
+}
+
+// AssertEffectiveRouteNextHop checks that the traffic of the Network Interface to the given IP address is routed to
+// the given next hop. This function would fail the test if there is an error or it is not.
+func AssertEffectiveRouteNextHop(t testing.TestingT, destinationIP string, nextHopType network.RouteNextHopType, nextHopIPAddress string, nicName string, resGroupName string, subscriptionID string) {
+	err := AssertEffectiveRouteNextHopE(destinationIP, nextHopType, nextHopIPAddress, nicName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertEffectiveRouteNextHopE checks that the traffic of the Network Interface to the given IP address is routed to
+// the given next hop, i.e., by the active effective route with the longest matching address prefix. This verifies,
+// e.g., that a hub and spoke network sends internet traffic through a firewall. The next hop IP address only applies
+// to the VirtualAppliance and VirtualNetworkGateway next hop types, so leave it empty for the others.
+func AssertEffectiveRouteNextHopE(destinationIP string, nextHopType network.RouteNextHopType, nextHopIPAddress string, nicName string, resGroupName string, subscriptionID string) error {
+	routes, err := GetNetworkInterfaceEffectiveRoutesE(nicName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkEffectiveRouteNextHop(routes, destinationIP, nextHopType, nextHopIPAddress, nicName)
+}
+
+// convertToEffectiveRouteSummary converts the raw SDK effective route type into a summarized struct.
+func convertToEffectiveRouteSummary(route network.EffectiveRoute) EffectiveRouteSummary {
+	return EffectiveRouteSummary{
+		Name:               safePtrToString(route.Name),
+		Source:             string(route.Source),
+		State:              string(route.State),
+		AddressPrefixes:    to.StringSlice(route.AddressPrefix),
+		NextHopType:        string(route.NextHopType),
+		NextHopIPAddresses: to.StringSlice(route.NextHopIPAddress),
+	}
+}
+
+// findEffectiveRoute returns the active route of the given routes with the longest address prefix that contains the
+// given IP address, which is the route Azure picks for traffic to it.
+func findEffectiveRoute(routes []EffectiveRouteSummary, destinationIP string) (*EffectiveRouteSummary, error) {
+	ip := net.ParseIP(destinationIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", destinationIP)
+	}
+
+	var match *EffectiveRouteSummary
+	matchOnes := -1
+	for i, route := range routes {
+		if route.State != string(network.Active) {
+			continue
+		}
+		for _, addressPrefix := range route.AddressPrefixes {
+			_, prefixNet, err := net.ParseCIDR(addressPrefix)
+			if err != nil || !prefixNet.Contains(ip) {
+				continue
+			}
+			if ones, _ := prefixNet.Mask.Size(); ones > matchOnes {
+				match = &routes[i]
+				matchOnes = ones
+			}
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no active effective route matches %s", destinationIP)
+	}
+	return match, nil
+}
+
+// checkEffectiveRouteNextHop returns an error if the given effective routes do not route traffic to the given IP
+// address to the given next hop.
+func checkEffectiveRouteNextHop(routes []EffectiveRouteSummary, destinationIP string, nextHopType network.RouteNextHopType, nextHopIPAddress string, nicName string) error {
+	route, err := findEffectiveRoute(routes, destinationIP)
+	if err != nil {
+		return fmt.Errorf("network interface %s: %s", nicName, err)
+	}
+
+	routeNextHopIPAddress := strings.Join(route.NextHopIPAddresses, ",")
+	if !strings.EqualFold(route.NextHopType, string(nextHopType)) || (nextHopIPAddress != "" && !collections.ListContains(route.NextHopIPAddresses, nextHopIPAddress)) {
+		return fmt.Errorf("traffic of network interface %s to %s is routed to %s by %s route %s, expected %s", nicName, destinationIP, formatNextHop(route.NextHopType, routeNextHopIPAddress), route.Source, route.Name, formatNextHop(string(nextHopType), nextHopIPAddress))
+	}
+	return nil
+}
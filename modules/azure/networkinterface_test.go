@@ -9,6 +9,8 @@ package azure
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -64,3 +66,44 @@ func TestNetworkInterfaceExistsE(t *testing.T) {
 
 	require.Error(t, err)
 }
+
+func TestGetNetworkInterfaceEffectiveRoutesE(t *testing.T) {
+	t.Parallel()
+
+	nicName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetNetworkInterfaceEffectiveRoutesE(nicName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestCheckEffectiveRouteNextHop(t *testing.T) {
+	t.Parallel()
+
+	routes := []EffectiveRouteSummary{
+		{Source: "Default", State: "Active", AddressPrefixes: []string{"10.0.0.0/16"}, NextHopType: "VnetLocal"},
+		{Source: "Default", State: "Invalid", AddressPrefixes: []string{"0.0.0.0/0"}, NextHopType: "Internet"},
+		{Name: "to-firewall", Source: "User", State: "Active", AddressPrefixes: []string{"0.0.0.0/0"}, NextHopType: "VirtualAppliance", NextHopIPAddresses: []string{"10.1.0.4"}},
+		{Source: "Default", State: "Active", AddressPrefixes: []string{"10.0.0.0/8", "172.16.0.0/12"}, NextHopType: "None"},
+	}
+
+	route, err := findEffectiveRoute(routes, "10.0.1.4")
+	require.NoError(t, err)
+	assert.Equal(t, "VnetLocal", route.NextHopType)
+
+	route, err = findEffectiveRoute(routes, "172.16.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "None", route.NextHopType)
+
+	_, err = findEffectiveRoute(routes, "not-an-ip")
+	assert.Error(t, err)
+	_, err = findEffectiveRoute(routes[:1], "8.8.8.8")
+	assert.Error(t, err)
+
+	assert.NoError(t, checkEffectiveRouteNextHop(routes, "8.8.8.8", network.RouteNextHopTypeVirtualAppliance, "10.1.0.4", "terratest-nic"))
+	assert.NoError(t, checkEffectiveRouteNextHop(routes, "8.8.8.8", network.RouteNextHopTypeVirtualAppliance, "", "terratest-nic"))
+	assert.Error(t, checkEffectiveRouteNextHop(routes, "8.8.8.8", network.RouteNextHopTypeVirtualAppliance, "10.1.0.5", "terratest-nic"))
+	assert.Error(t, checkEffectiveRouteNextHop(routes, "8.8.8.8", network.RouteNextHopTypeInternet, "", "terratest-nic"))
+}
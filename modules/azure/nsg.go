@@ -3,11 +3,14 @@ package azure
 import (
 	"context"
 	"fmt"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,16 +23,20 @@ type NsgRuleSummaryList struct {
 // NsgRuleSummary is a string-based (non-pointer) summary of an NSG rule with several helper methods attached
 // to help with verification of rule configuratoin.
 type NsgRuleSummary struct {
-	Name                     string
-	Description              string
-	Protocol                 string
-	SourcePortRange          string
-	DestinationPortRange     string
-	SourceAddressPrefix      string
-	DestinationAddressPrefix string
-	Access                   string
-	Priority                 int32
-	Direction                string
+	Name                       string
+	Description                string
+	Protocol                   string
+	SourcePortRange            string
+	DestinationPortRange       string
+	SourceAddressPrefix        string
+	DestinationAddressPrefix   string
+	SourcePortRanges           []string
+	DestinationPortRanges      []string
+	SourceAddressPrefixes      []string
+	DestinationAddressPrefixes []string
+	Access                     string
+	Priority                   int32
+	Direction                  string
 }
 
 // GetDefaultNsgRulesClient returns a rules client which can be used to read the list of *default* security rules
@@ -172,6 +179,10 @@ func convertToNsgRuleSummary(name *string, rule *network.SecurityRulePropertiesF
 	summary.DestinationPortRange = safePtrToString(rule.DestinationPortRange)
 	summary.SourceAddressPrefix = safePtrToString(rule.SourceAddressPrefix)
 	summary.DestinationAddressPrefix = safePtrToString(rule.DestinationAddressPrefix)
+	summary.SourcePortRanges = to.StringSlice(rule.SourcePortRanges)
+	summary.DestinationPortRanges = to.StringSlice(rule.DestinationPortRanges)
+	summary.SourceAddressPrefixes = to.StringSlice(rule.SourceAddressPrefixes)
+	summary.DestinationAddressPrefixes = to.StringSlice(rule.DestinationAddressPrefixes)
 	summary.Access = string(rule.Access)
 	summary.Priority = safePtrToInt32(rule.Priority)
 	summary.Direction = string(rule.Direction)
@@ -218,7 +229,7 @@ func portRangeAllowsPort(portRange string, port string) (bool, error) {
 	}
 
 	// Decode user-provided port
-	portAsInt, parseErr := strconv.ParseInt(port, 10, 16)
+	portAsInt, parseErr := strconv.ParseUint(port, 10, 16)
 	if (parseErr != nil) && (port != "*") {
 		return false, parseErr
 	}
@@ -243,7 +254,7 @@ func parsePortRangeString(rangeString string) (uint16, uint16, error) {
 
 	// Check for range string that contains hyphen separator
 	if !strings.Contains(rangeString, "-") {
-		val, parseErr := strconv.ParseInt(rangeString, 10, 16)
+		val, parseErr := strconv.ParseUint(rangeString, 10, 16)
 		if parseErr != nil {
 			return 0, 0, parseErr
 		}
@@ -257,13 +268,13 @@ func parsePortRangeString(rangeString string) (uint16, uint16, error) {
 	}
 
 	// Assume the low port is listed first; parse it
-	lowVal, parseErr := strconv.ParseInt(parts[0], 10, 16)
+	lowVal, parseErr := strconv.ParseUint(parts[0], 10, 16)
 	if parseErr != nil {
 		return 0, 0, parseErr
 	}
 
 	// Assume the hi port is listed first; parse it
-	highVal, parseErr := strconv.ParseInt(parts[1], 10, 16)
+	highVal, parseErr := strconv.ParseUint(parts[1], 10, 16)
 	if parseErr != nil {
 		return 0, 0, parseErr
 	}
@@ -280,3 +291,225 @@ func parsePortRangeString(rangeString string) (uint16, uint16, error) {
 	// Return values
 	return uint16(lowVal), uint16(highVal), nil
 }
+
+// RequireInboundAllowed checks that the rules of the network security group allow inbound TCP traffic from the given
+// source (a CIDR block, an IP address or a service tag) to the given destination port.
+// This function would fail the test if there is an error or they do not.
+func RequireInboundAllowed(t *testing.T, nsg NsgRuleSummaryList, port string, sourceCIDR string) {
+	require.NoError(t, RequireInboundAllowedE(nsg, port, sourceCIDR))
+}
+
+// RequireInboundAllowedE checks that the rules of the network security group allow inbound TCP traffic from the given
+// source (a CIDR block, an IP address or a service tag) to the given destination port, i.e., that the rule with the
+// lowest priority number matching all of the source allows it.
+func RequireInboundAllowedE(nsg NsgRuleSummaryList, port string, sourceCIDR string) error {
+	return checkNsgTraffic(nsg, network.SecurityRuleDirectionInbound, port, sourceCIDR, network.SecurityRuleAccessAllow)
+}
+
+// RequireInboundDenied checks that the rules of the network security group deny inbound TCP traffic from the given
+// source (a CIDR block, an IP address or a service tag) to the given destination port.
+// This function would fail the test if there is an error or they do not.
+func RequireInboundDenied(t *testing.T, nsg NsgRuleSummaryList, port string, sourceCIDR string) {
+	require.NoError(t, RequireInboundDeniedE(nsg, port, sourceCIDR))
+}
+
+// RequireInboundDeniedE checks that the rules of the network security group deny inbound TCP traffic from the given
+// source (a CIDR block, an IP address or a service tag) to the given destination port, i.e., that no rule allows any
+// of the source before a rule denies all of it.
+func RequireInboundDeniedE(nsg NsgRuleSummaryList, port string, sourceCIDR string) error {
+	return checkNsgTraffic(nsg, network.SecurityRuleDirectionInbound, port, sourceCIDR, network.SecurityRuleAccessDeny)
+}
+
+// RequireOutboundAllowed checks that the rules of the network security group allow outbound TCP traffic to the given
+// destination (a CIDR block, an IP address or a service tag) and port.
+// This function would fail the test if there is an error or they do not.
+func RequireOutboundAllowed(t *testing.T, nsg NsgRuleSummaryList, port string, destinationCIDR string) {
+	require.NoError(t, RequireOutboundAllowedE(nsg, port, destinationCIDR))
+}
+
+// RequireOutboundAllowedE checks that the rules of the network security group allow outbound TCP traffic to the given
+// destination (a CIDR block, an IP address or a service tag) and port.
+func RequireOutboundAllowedE(nsg NsgRuleSummaryList, port string, destinationCIDR string) error {
+	return checkNsgTraffic(nsg, network.SecurityRuleDirectionOutbound, port, destinationCIDR, network.SecurityRuleAccessAllow)
+}
+
+// RequireOutboundDenied checks that the rules of the network security group deny outbound TCP traffic to the given
+// destination (a CIDR block, an IP address or a service tag) and port.
+// This function would fail the test if there is an error or they do not.
+func RequireOutboundDenied(t *testing.T, nsg NsgRuleSummaryList, port string, destinationCIDR string) {
+	require.NoError(t, RequireOutboundDeniedE(nsg, port, destinationCIDR))
+}
+
+// RequireOutboundDeniedE checks that the rules of the network security group deny outbound TCP traffic to the given
+// destination (a CIDR block, an IP address or a service tag) and port.
+func RequireOutboundDeniedE(nsg NsgRuleSummaryList, port string, destinationCIDR string) error {
+	return checkNsgTraffic(nsg, network.SecurityRuleDirectionOutbound, port, destinationCIDR, network.SecurityRuleAccessDeny)
+}
+
+// checkNsgTraffic evaluates the rules of the given direction in priority order, as Azure does, and returns an error if
+// TCP traffic on the given port from (inbound) or to (outbound) the given address does not get the expected access.
+// Rules that only match part of the address, or only some source ports or addresses on the other side (other than
+// "VirtualNetwork"), cannot grant the expected access to all of the traffic, but they do fail the check if they grant
+// the opposite access. Service tags other than "Internet" cannot be resolved, so they only match the same tag.
+func checkNsgTraffic(nsg NsgRuleSummaryList, direction network.SecurityRuleDirection, port string, address string, expectedAccess network.SecurityRuleAccess) error {
+	if strings.Contains(address, "/") && parseNsgAddress(address) == nil {
+		return fmt.Errorf("invalid CIDR block %q", address)
+	}
+
+	rules := []NsgRuleSummary{}
+	for _, rule := range nsg.SummarizedRules {
+		if strings.EqualFold(rule.Direction, string(direction)) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+
+	for _, rule := range rules {
+		if rule.Protocol != string(network.SecurityRuleProtocolAsterisk) && !strings.EqualFold(rule.Protocol, string(network.SecurityRuleProtocolTCP)) {
+			continue
+		}
+		portMatches, err := portRangesAllowPort(nsgRuleRanges(rule.DestinationPortRange, rule.DestinationPortRanges), port)
+		if err != nil {
+			return err
+		}
+		if !portMatches {
+			continue
+		}
+
+		addressPrefixes := nsgRuleRanges(rule.SourceAddressPrefix, rule.SourceAddressPrefixes)
+		otherAddressPrefixes := nsgRuleRanges(rule.DestinationAddressPrefix, rule.DestinationAddressPrefixes)
+		if direction == network.SecurityRuleDirectionOutbound {
+			addressPrefixes, otherAddressPrefixes = otherAddressPrefixes, addressPrefixes
+		}
+		covers, overlaps := addressPrefixesMatch(addressPrefixes, address)
+		if !overlaps {
+			continue
+		}
+
+		if !strings.EqualFold(rule.Access, string(expectedAccess)) {
+			return fmt.Errorf("%s TCP traffic on port %s for %s matches %s rule %s (priority %d), expected %s", direction, port, address, rule.Access, rule.Name, rule.Priority, expectedAccess)
+		}
+		sourcePortsAll, _ := portRangesAllowPort(nsgRuleRanges(rule.SourcePortRange, rule.SourcePortRanges), "*")
+		if covers && sourcePortsAll && addressPrefixesMatchAll(otherAddressPrefixes) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no %s rule matches all %s TCP traffic on port %s for %s", expectedAccess, direction, port, address)
+}
+
+// nsgRuleRanges returns the port ranges or address prefixes of a rule, which has either a single one or a list.
+func nsgRuleRanges(single string, multiple []string) []string {
+	if len(multiple) > 0 {
+		return multiple
+	}
+	return []string{single}
+}
+
+// portRangesAllowPort returns whether any of the given port ranges allows the given port.
+func portRangesAllowPort(portRanges []string, port string) (bool, error) {
+	for _, portRange := range portRanges {
+		allowed, err := portRangeAllowsPort(portRange, port)
+		if err != nil {
+			return false, err
+		}
+		if allowed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// addressPrefixesMatchAll returns whether the given address prefixes match any address in the virtual network, which
+// is the case for the other side of the rules that guard it.
+func addressPrefixesMatchAll(addressPrefixes []string) bool {
+	for _, prefix := range addressPrefixes {
+		if prefix == "*" || prefix == "0.0.0.0/0" || strings.EqualFold(prefix, "Any") || strings.EqualFold(prefix, "VirtualNetwork") {
+			return true
+		}
+	}
+	return false
+}
+
+// addressPrefixesMatch returns whether the given address prefixes of a rule cover all of the given address (a CIDR
+// block, an IP address or a service tag), and whether they overlap part of it.
+func addressPrefixesMatch(addressPrefixes []string, address string) (bool, bool) {
+	addressNet := parseNsgAddress(address)
+	overlaps := false
+	for _, prefix := range addressPrefixes {
+		if prefix == "*" || strings.EqualFold(prefix, "Any") {
+			return true, true
+		}
+		if addressNet == nil {
+			// The address is a service tag, which only the same tag matches, while all of them overlap "*"
+			if strings.EqualFold(prefix, address) {
+				return true, true
+			}
+			overlaps = overlaps || address == "*"
+			continue
+		}
+
+		if strings.EqualFold(prefix, "Internet") {
+			if !ipNetOverlapsPrivateRanges(addressNet) {
+				return true, true
+			}
+			overlaps = overlaps || !ipNetInPrivateRanges(addressNet)
+			continue
+		}
+		prefixNet := parseNsgAddress(prefix)
+		if prefixNet == nil {
+			continue
+		}
+		if ipNetContains(prefixNet, addressNet) {
+			return true, true
+		}
+		overlaps = overlaps || prefixNet.Contains(addressNet.IP) || addressNet.Contains(prefixNet.IP)
+	}
+	return false, overlaps
+}
+
+// parseNsgAddress parses the given CIDR block or IP address, or returns nil for a service tag.
+func parseNsgAddress(address string) *net.IPNet {
+	if _, ipNet, err := net.ParseCIDR(address); err == nil {
+		return ipNet
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// ipNetContains returns whether the outer network contains all of the inner network.
+func ipNetContains(outer *net.IPNet, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// privateIPRanges are the private address ranges, which the "Internet" service tag does not include.
+var privateIPRanges = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// ipNetInPrivateRanges returns whether all of the given network is in a private address range.
+func ipNetInPrivateRanges(ipNet *net.IPNet) bool {
+	for _, privateRange := range privateIPRanges {
+		_, privateNet, _ := net.ParseCIDR(privateRange)
+		if ipNetContains(privateNet, ipNet) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipNetOverlapsPrivateRanges returns whether part of the given network is in a private address range.
+func ipNetOverlapsPrivateRanges(ipNet *net.IPNet) bool {
+	for _, privateRange := range privateIPRanges {
+		_, privateNet, _ := net.ParseCIDR(privateRange)
+		if privateNet.Contains(ipNet.IP) || ipNet.Contains(privateNet.IP) {
+			return true
+		}
+	}
+	return false
+}
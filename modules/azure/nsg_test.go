@@ -31,6 +31,7 @@ func TestPortRangeParsing(t *testing.T) {
 		{"-80", 0, 0, true},
 		{"-", 0, 0, true},
 		{"80-22", 22, 80, false},
+		{"1024-65535", 1024, 65535, false},
 	}
 
 	for _, tt := range cases {
@@ -159,3 +160,57 @@ func TestFindSummarizedRule(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckNsgTraffic(t *testing.T) {
+	nsg := NsgRuleSummaryList{SummarizedRules: []NsgRuleSummary{
+		{Name: "AllowHTTPSInBound", Protocol: "Tcp", SourcePortRange: "*", DestinationPortRange: "443", SourceAddressPrefix: "Internet", DestinationAddressPrefix: "*", Access: "Allow", Priority: 100, Direction: "Inbound"},
+		{Name: "AllowSSHFromOfficeInBound", Protocol: "Tcp", SourcePortRange: "*", DestinationPortRanges: []string{"22", "2222"}, SourceAddressPrefixes: []string{"203.0.113.0/24"}, DestinationAddressPrefix: "VirtualNetwork", Access: "Allow", Priority: 110, Direction: "Inbound"},
+		{Name: "DenyRDPInBound", Protocol: "*", SourcePortRange: "*", DestinationPortRange: "3389", SourceAddressPrefix: "*", DestinationAddressPrefix: "*", Access: "Deny", Priority: 120, Direction: "Inbound"},
+		{Name: "AllowDNSInBound", Protocol: "Udp", SourcePortRange: "*", DestinationPortRange: "53", SourceAddressPrefix: "*", DestinationAddressPrefix: "*", Access: "Allow", Priority: 130, Direction: "Inbound"},
+		{Name: "AllowHTTPToWebInBound", Protocol: "Tcp", SourcePortRange: "*", DestinationPortRange: "80", SourceAddressPrefix: "*", DestinationAddressPrefix: "10.0.1.4", Access: "Allow", Priority: 140, Direction: "Inbound"},
+		{Name: "DenyInternetSMTPOutBound", Protocol: "Tcp", SourcePortRange: "*", DestinationPortRange: "25", SourceAddressPrefix: "*", DestinationAddressPrefix: "Internet", Access: "Deny", Priority: 100, Direction: "Outbound"},
+		{Name: "AllowVnetInBound", Protocol: "*", SourcePortRange: "*", DestinationPortRange: "*", SourceAddressPrefix: "VirtualNetwork", DestinationAddressPrefix: "VirtualNetwork", Access: "Allow", Priority: 65000, Direction: "Inbound"},
+		{Name: "DenyAllInBound", Protocol: "*", SourcePortRange: "*", DestinationPortRange: "*", SourceAddressPrefix: "*", DestinationAddressPrefix: "*", Access: "Deny", Priority: 65500, Direction: "Inbound"},
+		{Name: "AllowInternetOutBound", Protocol: "*", SourcePortRange: "*", DestinationPortRange: "*", SourceAddressPrefix: "*", DestinationAddressPrefix: "Internet", Access: "Allow", Priority: 65001, Direction: "Outbound"},
+		{Name: "DenyAllOutBound", Protocol: "*", SourcePortRange: "*", DestinationPortRange: "*", SourceAddressPrefix: "*", DestinationAddressPrefix: "*", Access: "Deny", Priority: 65500, Direction: "Outbound"},
+	}}
+
+	var cases = []struct {
+		CaseName  string
+		Check     func(nsg NsgRuleSummaryList, port string, address string) error
+		Port      string
+		Address   string
+		ExpectErr bool
+	}{
+		{"HTTPS allowed from the Internet tag", RequireInboundAllowedE, "443", "Internet", false},
+		{"HTTPS allowed from a public IP", RequireInboundAllowedE, "443", "198.51.100.7", false},
+		{"HTTPS not allowed from private ranges", RequireInboundAllowedE, "443", "0.0.0.0/0", true},
+		{"HTTPS not denied from a public range", RequireInboundDeniedE, "443", "198.51.100.0/24", true},
+		{"SSH allowed from the office", RequireInboundAllowedE, "2222", "203.0.113.10", false},
+		{"SSH denied from elsewhere", RequireInboundDeniedE, "22", "198.51.100.0/24", false},
+		{"SSH not denied from anywhere", RequireInboundDeniedE, "22", "0.0.0.0/0", true},
+		{"RDP denied from anywhere", RequireInboundDeniedE, "3389", "*", false},
+		{"DNS over TCP denied", RequireInboundDeniedE, "53", "198.51.100.0/24", false},
+		{"Traffic from anywhere includes the virtual network", RequireInboundDeniedE, "53", "*", true},
+		{"HTTP to a single host is not allowed to all", RequireInboundAllowedE, "80", "*", true},
+		{"HTTP to a single host is not denied to all", RequireInboundDeniedE, "80", "*", true},
+		{"Traffic allowed within the virtual network", RequireInboundAllowedE, "8080", "VirtualNetwork", false},
+		{"SMTP denied to the Internet", RequireOutboundDeniedE, "25", "Internet", false},
+		{"HTTPS allowed to a public IP", RequireOutboundAllowedE, "443", "8.8.8.8", false},
+		{"HTTPS not denied to a public IP", RequireOutboundDeniedE, "443", "8.8.8.8", true},
+		{"HTTPS denied to a private range", RequireOutboundDeniedE, "443", "10.1.0.0/16", false},
+		{"Invalid CIDR block", RequireInboundDeniedE, "22", "10.0.0.0/33", true},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.CaseName, func(t *testing.T) {
+			err := tt.Check(nsg, tt.Port, tt.Address)
+			if tt.ExpectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
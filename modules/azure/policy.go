@@ -0,0 +1,274 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/policyinsights/mgmt/2019-10-01-preview/policyinsights"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// PolicyComplianceStateCompliant is the compliance state of resources that comply with a policy assignment.
+	PolicyComplianceStateCompliant = "Compliant"
+	// PolicyComplianceStateNonCompliant is the compliance state of resources that do not comply with a policy
+	// assignment, e.g., that miss the resource an auditIfNotExists policy expects.
+	PolicyComplianceStateNonCompliant = "NonCompliant"
+
+	// policyDisallowedErrorCode is the error code of Azure Resource Manager for requests denied by a policy.
+	policyDisallowedErrorCode = "RequestDisallowedByPolicy"
+)
+
+// policyScopePattern matches the subscription and resource group scopes of policy compliance scans.
+var policyScopePattern = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)(?:/resourceGroups/([^/]+))?/?$`)
+
+// GetPolicyStatesClientE is a helper function that will setup an Azure Policy states client.
+func GetPolicyStatesClientE() (*policyinsights.PolicyStatesClient, error) {
+	client, err := CreatePolicyStatesClientE()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// TriggerPolicyComplianceScan starts a policy compliance scan of the given scope (a subscription, e.g.,
+// "/subscriptions/<id>", or a resource group, e.g., "/subscriptions/<id>/resourceGroups/<name>") and waits for it to
+// complete. This function would fail the test if there is an error.
+func TriggerPolicyComplianceScan(t testing.TestingT, scope string) {
+	err := TriggerPolicyComplianceScanE(t, scope)
+	require.NoError(t, err)
+}
+
+// TriggerPolicyComplianceScanE starts a policy compliance scan of the given scope (a subscription, e.g.,
+// "/subscriptions/<id>", or a resource group, e.g., "/subscriptions/<id>/resourceGroups/<name>") and waits for it to
+// complete, which takes several minutes. Without a scan, Azure Policy only evaluates new assignments and resources
+// within about 30 minutes, and existing ones every 24 hours.
+func TriggerPolicyComplianceScanE(t testing.TestingT, scope string) error {
+	subscriptionID, resourceGroupName, err := parsePolicyScope(scope)
+	if err != nil {
+		return err
+	}
+
+	client, err := GetPolicyStatesClientE()
+	if err != nil {
+		return err
+	}
+
+	logger.Logf(t, "Triggering policy compliance scan of %s", scope)
+	ctx := context.Background()
+	if resourceGroupName == "" {
+		future, err := client.TriggerSubscriptionEvaluation(ctx, subscriptionID)
+		if err != nil {
+			return err
+		}
+		err = future.WaitForCompletionRef(ctx, client.Client)
+	} else {
+		future, err := client.TriggerResourceGroupEvaluation(ctx, subscriptionID, resourceGroupName)
+		if err != nil {
+			return err
+		}
+		err = future.WaitForCompletionRef(ctx, client.Client)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Logf(t, "Policy compliance scan of %s completed", scope)
+	return nil
+}
+
+// GetResourcePolicyStates gets the latest policy states of the resource with the given ID, one for each policy
+// definition of each assignment that applies to it. This function would fail the test if there is an error.
+func GetResourcePolicyStates(t testing.TestingT, resourceID string) []policyinsights.PolicyState {
+	states, err := GetResourcePolicyStatesE(resourceID)
+	require.NoError(t, err)
+	return states
+}
+
+// GetResourcePolicyStatesE gets the latest policy states of the resource with the given ID, one for each policy
+// definition of each assignment that applies to it.
+func GetResourcePolicyStatesE(resourceID string) ([]policyinsights.PolicyState, error) {
+	client, err := GetPolicyStatesClientE()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	page, err := client.ListQueryResultsForResource(ctx, policyinsights.Latest, resourceID, nil, "", "", nil, nil, "", "", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	states := []policyinsights.PolicyState{}
+	for page.NotDone() {
+		states = append(states, page.Values()...)
+		if err := page.NextWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return states, nil
+}
+
+// GetResourceComplianceState gets the compliance state (e.g., PolicyComplianceStateCompliant) of the resource with the
+// given ID for the given policy assignment, which is either the ID of the assignment or its name in the given scope.
+// This function would fail the test if there is an error.
+func GetResourceComplianceState(t testing.TestingT, scope string, policyAssignment string, resourceID string) string {
+	state, err := GetResourceComplianceStateE(scope, policyAssignment, resourceID)
+	require.NoError(t, err)
+	return state
+}
+
+// GetResourceComplianceStateE gets the compliance state (e.g., PolicyComplianceStateCompliant) of the resource with
+// the given ID for the given policy assignment, which is either the ID of the assignment or its name in the given
+// scope. A resource is non-compliant with an assignment of a policy set if it is non-compliant with any of its policies.
+func GetResourceComplianceStateE(scope string, policyAssignment string, resourceID string) (string, error) {
+	states, err := GetResourcePolicyStatesE(resourceID)
+	if err != nil {
+		return "", err
+	}
+	return getResourceComplianceState(states, formatPolicyAssignmentID(scope, policyAssignment), resourceID)
+}
+
+// WaitUntilResourceCompliant waits until the resource with the given ID complies with the given policy assignment,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This
+// function would fail the test if there is an error.
+func WaitUntilResourceCompliant(t testing.TestingT, scope string, policyAssignment string, resourceID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilResourceCompliantE(t, scope, policyAssignment, resourceID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilResourceCompliantE waits until the resource with the given ID complies with the given policy assignment,
+// which is either the ID of the assignment or its name in the given scope, retrying the check for the specified amount
+// of times, sleeping for the provided duration between each try. Call TriggerPolicyComplianceScanE first to not wait
+// for the next evaluation cycle of Azure Policy.
+func WaitUntilResourceCompliantE(t testing.TestingT, scope string, policyAssignment string, resourceID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	return waitUntilResourceComplianceStateE(t, PolicyComplianceStateCompliant, scope, policyAssignment, resourceID, maxRetries, sleepBetweenRetries)
+}
+
+// WaitUntilResourceNonCompliant waits until the resource with the given ID does not comply with the given policy
+// assignment, retrying the check for the specified amount of times, sleeping for the provided duration between each
+// try. This function would fail the test if there is an error.
+func WaitUntilResourceNonCompliant(t testing.TestingT, scope string, policyAssignment string, resourceID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilResourceNonCompliantE(t, scope, policyAssignment, resourceID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilResourceNonCompliantE waits until the resource with the given ID does not comply with the given policy
+// assignment, which is either the ID of the assignment or its name in the given scope, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. This checks that audit and
+// auditIfNotExists policies flag non-compliant resources, which they do not prevent from being deployed.
+func WaitUntilResourceNonCompliantE(t testing.TestingT, scope string, policyAssignment string, resourceID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	return waitUntilResourceComplianceStateE(t, PolicyComplianceStateNonCompliant, scope, policyAssignment, resourceID, maxRetries, sleepBetweenRetries)
+}
+
+// waitUntilResourceComplianceStateE waits until the resource with the given ID has the expected compliance state for
+// the given policy assignment.
+func waitUntilResourceComplianceStateE(t testing.TestingT, expectedState string, scope string, policyAssignment string, resourceID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for resource %s to be %s with policy assignment %s.", resourceID, expectedState, policyAssignment),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			state, err := GetResourceComplianceStateE(scope, policyAssignment, resourceID)
+			if err != nil {
+				return "", err
+			}
+			if !strings.EqualFold(state, expectedState) {
+				return "", fmt.Errorf("resource %s is %s with policy assignment %s", resourceID, state, policyAssignment)
+			}
+			return fmt.Sprintf("Resource %s is %s with policy assignment %s", resourceID, state, policyAssignment), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// AssertRequestDisallowedByPolicy checks that the given error, e.g., of terraform.InitAndApplyE, is a request denied
+// by the given policy assignment. This function would fail the test if it is not.
+func AssertRequestDisallowedByPolicy(t testing.TestingT, err error, policyAssignment string) {
+	require.NoError(t, AssertRequestDisallowedByPolicyE(err, policyAssignment))
+}
+
+// AssertRequestDisallowedByPolicyE checks that the given error, e.g., of terraform.InitAndApplyE, is a request denied
+// by the given policy assignment (its name or ID), which checks that deny policies block non-compliant resources.
+// Leave the policy assignment empty to accept a denial by any assignment.
+func AssertRequestDisallowedByPolicyE(err error, policyAssignment string) error {
+	if err == nil {
+		return fmt.Errorf("expected the request to be disallowed by policy, but it succeeded")
+	}
+	return checkRequestDisallowedByPolicy(err.Error(), policyAssignment)
+}
+
+// checkRequestDisallowedByPolicy returns an error if the given error message is not a request denied by the given
+// policy assignment. Azure lists the names and IDs of the denying assignments in the message.
+func checkRequestDisallowedByPolicy(message string, policyAssignment string) error {
+	if !strings.Contains(message, policyDisallowedErrorCode) {
+		return fmt.Errorf("expected the request to be disallowed by policy, but it failed with: %s", message)
+	}
+	if policyAssignment != "" && !strings.Contains(strings.ToLower(message), strings.ToLower(policyAssignment)) {
+		return fmt.Errorf("expected the request to be disallowed by policy assignment %s, but it was disallowed with: %s", policyAssignment, message)
+	}
+	return nil
+}
+
+// parsePolicyScope returns the subscription ID and resource group name (empty for a subscription) of the given policy
+// compliance scan scope.
+func parsePolicyScope(scope string) (string, string, error) {
+	matches := policyScopePattern.FindStringSubmatch(scope)
+	if matches == nil {
+		return "", "", fmt.Errorf("invalid policy scope %q: expected /subscriptions/<id> or /subscriptions/<id>/resourceGroups/<name>", scope)
+	}
+	return matches[1], matches[2], nil
+}
+
+// formatPolicyAssignmentID returns the ID of the given policy assignment, which is either already an ID or the name of
+// an assignment in the given scope.
+func formatPolicyAssignmentID(scope string, policyAssignment string) string {
+	if strings.HasPrefix(policyAssignment, "/") {
+		return policyAssignment
+	}
+	return strings.TrimSuffix(scope, "/") + "/providers/Microsoft.Authorization/policyAssignments/" + policyAssignment
+}
+
+// getResourceComplianceState returns the aggregated compliance state of the resource with the given ID in the given
+// policy states for the policy assignment with the given ID: non-compliant if any of its policies is, and otherwise
+// the state of its first policy.
+func getResourceComplianceState(states []policyinsights.PolicyState, policyAssignmentID string, resourceID string) (string, error) {
+	complianceState := ""
+	for _, state := range states {
+		if !strings.EqualFold(to.String(state.PolicyAssignmentID), policyAssignmentID) {
+			continue
+		}
+		stateName := to.String(state.ComplianceState)
+		if stateName == "" && state.IsCompliant != nil {
+			stateName = PolicyComplianceStateNonCompliant
+			if *state.IsCompliant {
+				stateName = PolicyComplianceStateCompliant
+			}
+		}
+		if strings.EqualFold(stateName, PolicyComplianceStateNonCompliant) {
+			return PolicyComplianceStateNonCompliant, nil
+		}
+		if complianceState == "" {
+			complianceState = stateName
+		}
+	}
+	if complianceState == "" {
+		return "", fmt.Errorf("resource %s has not been evaluated against policy assignment %s", resourceID, policyAssignmentID)
+	}
+	return complianceState, nil
+}
@@ -0,0 +1,125 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/policyinsights/mgmt/2019-10-01-preview/policyinsights"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when methods can be mocked or Create/Delete APIs are added, these tests can be extended.
+*/
+
+func TestTriggerPolicyComplianceScanE(t *testing.T) {
+	t.Parallel()
+
+	scope := ""
+
+	err := TriggerPolicyComplianceScanE(t, scope)
+	require.Error(t, err)
+}
+
+func TestGetResourcePolicyStatesE(t *testing.T) {
+	t.Parallel()
+
+	resourceID := ""
+
+	_, err := GetResourcePolicyStatesE(resourceID)
+	require.Error(t, err)
+}
+
+func TestGetResourceComplianceStateE(t *testing.T) {
+	t.Parallel()
+
+	scope := ""
+	policyAssignment := ""
+	resourceID := ""
+
+	_, err := GetResourceComplianceStateE(scope, policyAssignment, resourceID)
+	require.Error(t, err)
+}
+
+func TestAssertRequestDisallowedByPolicyE(t *testing.T) {
+	t.Parallel()
+
+	err := AssertRequestDisallowedByPolicyE(nil, "")
+	require.Error(t, err)
+}
+
+func TestParsePolicyScope(t *testing.T) {
+	t.Parallel()
+
+	subscriptionID, resourceGroupName, err := parsePolicyScope("/subscriptions/00000000-0000-0000-0000-000000000000")
+	require.NoError(t, err)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000000", subscriptionID)
+	assert.Equal(t, "", resourceGroupName)
+
+	subscriptionID, resourceGroupName, err = parsePolicyScope("/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/terratest-rg/")
+	require.NoError(t, err)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000000", subscriptionID)
+	assert.Equal(t, "terratest-rg", resourceGroupName)
+
+	_, _, err = parsePolicyScope("/providers/Microsoft.Management/managementGroups/terratest")
+	require.Error(t, err)
+}
+
+func TestFormatPolicyAssignmentID(t *testing.T) {
+	t.Parallel()
+
+	scope := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/terratest-rg"
+	id := scope + "/providers/Microsoft.Authorization/policyAssignments/deny-public-ip"
+
+	assert.Equal(t, id, formatPolicyAssignmentID(scope, "deny-public-ip"))
+	assert.Equal(t, id, formatPolicyAssignmentID(scope+"/", "deny-public-ip"))
+	assert.Equal(t, id, formatPolicyAssignmentID("", id))
+}
+
+func TestGetResourceComplianceState(t *testing.T) {
+	t.Parallel()
+
+	assignmentID := "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policyAssignments/audit-tags"
+	otherAssignmentID := "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policyAssignments/other"
+	resourceID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/terratest-rg/providers/Microsoft.Storage/storageAccounts/terratest"
+
+	compliant := policyinsights.PolicyState{PolicyAssignmentID: to.StringPtr(assignmentID), ComplianceState: to.StringPtr("Compliant")}
+	nonCompliant := policyinsights.PolicyState{PolicyAssignmentID: to.StringPtr(assignmentID), ComplianceState: to.StringPtr("NonCompliant")}
+	otherNonCompliant := policyinsights.PolicyState{PolicyAssignmentID: to.StringPtr(otherAssignmentID), ComplianceState: to.StringPtr("NonCompliant")}
+	legacyCompliant := policyinsights.PolicyState{PolicyAssignmentID: to.StringPtr(assignmentID), IsCompliant: to.BoolPtr(true)}
+
+	state, err := getResourceComplianceState([]policyinsights.PolicyState{compliant, otherNonCompliant}, assignmentID, resourceID)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyComplianceStateCompliant, state)
+
+	state, err = getResourceComplianceState([]policyinsights.PolicyState{compliant, nonCompliant}, assignmentID, resourceID)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyComplianceStateNonCompliant, state)
+
+	state, err = getResourceComplianceState([]policyinsights.PolicyState{legacyCompliant}, assignmentID, resourceID)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyComplianceStateCompliant, state)
+
+	_, err = getResourceComplianceState([]policyinsights.PolicyState{otherNonCompliant}, assignmentID, resourceID)
+	require.Error(t, err)
+}
+
+func TestCheckRequestDisallowedByPolicy(t *testing.T) {
+	t.Parallel()
+
+	message := `creating Public Ip Address: (Name "pip" / Resource Group "terratest-rg"): network.PublicIPAddressesClient#CreateOrUpdate: Failure sending request: StatusCode=403 -- Original Error: Code="RequestDisallowedByPolicy" Message="Resource 'pip' was disallowed by policy. Policy identifiers: '[{"policyAssignment":{"name":"Deny public IPs","id":"/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Authorization/policyAssignments/deny-public-ip"}}]'."`
+
+	assert.NoError(t, checkRequestDisallowedByPolicy(message, ""))
+	assert.NoError(t, checkRequestDisallowedByPolicy(message, "deny-public-ip"))
+	assert.Error(t, checkRequestDisallowedByPolicy(message, "deny-storage"))
+	assert.Error(t, AssertRequestDisallowedByPolicyE(errors.New("AuthorizationFailed"), ""))
+}
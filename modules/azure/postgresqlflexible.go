@@ -0,0 +1,245 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/postgresql/mgmt/2020-11-05-preview/postgresqlflexibleservers"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// GetPostgreSQLFlexibleServersClientE is a helper function that will setup a postgresql flexible servers client.
+func GetPostgreSQLFlexibleServersClientE(subscriptionID string) (*postgresqlflexibleservers.ServersClient, error) {
+	// Create a postgresql flexible servers client
+	client, err := CreatePostgreSQLFlexibleServersClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an authorizer
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Attach authorizer to the client
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetPostgreSQLFlexibleDatabasesClientE is a helper function that will setup a postgresql flexible server databases
+// client.
+func GetPostgreSQLFlexibleDatabasesClientE(subscriptionID string) (*postgresqlflexibleservers.DatabasesClient, error) {
+	client, err := CreatePostgreSQLFlexibleDatabasesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetPostgreSQLFlexibleFirewallRulesClientE is a helper function that will setup a postgresql flexible server
+// firewall rules client.
+func GetPostgreSQLFlexibleFirewallRulesClientE(subscriptionID string) (*postgresqlflexibleservers.FirewallRulesClient, error) {
+	client, err := CreatePostgreSQLFlexibleFirewallRulesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// GetPostgreSQLFlexibleServer is a helper function that gets the postgresql flexible server.
+// This function would fail the test if there is an error.
+func GetPostgreSQLFlexibleServer(t testing.TestingT, subscriptionID string, resGroupName string, serverName string) *postgresqlflexibleservers.Server {
+	server, err := GetPostgreSQLFlexibleServerE(t, subscriptionID, resGroupName, serverName)
+	require.NoError(t, err)
+
+	return server
+}
+
+// GetPostgreSQLFlexibleServerE is a helper function that gets the postgresql flexible server.
+func GetPostgreSQLFlexibleServerE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string) (*postgresqlflexibleservers.Server, error) {
+	client, err := GetPostgreSQLFlexibleServersClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := client.Get(context.Background(), resGroupName, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &server, nil
+}
+
+// GetPostgreSQLFlexibleDatabase is a helper function that gets the database of the postgresql flexible server.
+// This function would fail the test if there is an error.
+func GetPostgreSQLFlexibleDatabase(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, dbName string) *postgresqlflexibleservers.Database {
+	database, err := GetPostgreSQLFlexibleDatabaseE(t, subscriptionID, resGroupName, serverName, dbName)
+	require.NoError(t, err)
+
+	return database
+}
+
+// GetPostgreSQLFlexibleDatabaseE is a helper function that gets the database of the postgresql flexible server.
+func GetPostgreSQLFlexibleDatabaseE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, dbName string) (*postgresqlflexibleservers.Database, error) {
+	client, err := GetPostgreSQLFlexibleDatabasesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := client.Get(context.Background(), resGroupName, serverName, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &database, nil
+}
+
+// WaitUntilPostgreSQLFlexibleServerReady waits until the postgresql flexible server is ready, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try. This function would fail the
+// test if there is an error or the server is not ready in time.
+func WaitUntilPostgreSQLFlexibleServerReady(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilPostgreSQLFlexibleServerReadyE(t, subscriptionID, resGroupName, serverName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilPostgreSQLFlexibleServerReadyE waits until the postgresql flexible server is ready, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try. It stops retrying if the server
+// is disabled or being dropped, as it will never become ready.
+func WaitUntilPostgreSQLFlexibleServerReadyE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for postgresql flexible server %s to be ready.", serverName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			server, err := GetPostgreSQLFlexibleServerE(t, subscriptionID, resGroupName, serverName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkPostgreSQLFlexibleServerReady(server); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("postgresql flexible server %s is ready", serverName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkPostgreSQLFlexibleServerReady returns an error if the postgresql flexible server is not ready, which is a
+// retry.FatalError if the server is disabled or being dropped.
+func checkPostgreSQLFlexibleServerReady(server *postgresqlflexibleservers.Server) error {
+	if server.ServerProperties == nil {
+		return fmt.Errorf("postgresql flexible server %s has no state yet", to.String(server.Name))
+	}
+	switch server.State {
+	case postgresqlflexibleservers.ServerStateReady:
+		return nil
+	case postgresqlflexibleservers.ServerStateDisabled, postgresqlflexibleservers.ServerStateDropping:
+		return retry.FatalError{Underlying: fmt.Errorf("postgresql flexible server %s is in state %s", to.String(server.Name), server.State)}
+	default:
+		return fmt.Errorf("postgresql flexible server %s is in state %s", to.String(server.Name), server.State)
+	}
+}
+
+// AssertPostgreSQLFlexibleServerFirewallAllowsIP checks that a firewall rule of the postgresql flexible server allows
+// the given IP address. This function would fail the test if there is an error or no rule allows it.
+func AssertPostgreSQLFlexibleServerFirewallAllowsIP(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, ipAddress string) {
+	err := AssertPostgreSQLFlexibleServerFirewallAllowsIPE(t, subscriptionID, resGroupName, serverName, ipAddress)
+	require.NoError(t, err)
+}
+
+// AssertPostgreSQLFlexibleServerFirewallAllowsIPE checks that a firewall rule of the postgresql flexible server allows
+// the given IP address. Servers with private access have no firewall rules.
+func AssertPostgreSQLFlexibleServerFirewallAllowsIPE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, ipAddress string) error {
+	client, err := GetPostgreSQLFlexibleFirewallRulesClientE(subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	rules, err := client.ListByServerComplete(context.Background(), resGroupName, serverName)
+	if err != nil {
+		return err
+	}
+	for ; rules.NotDone(); err = rules.NextWithContext(context.Background()) {
+		if err != nil {
+			return err
+		}
+		rule := rules.Value()
+		if rule.FirewallRuleProperties == nil {
+			continue
+		}
+		allowed, err := firewallRangeAllowsIP(to.String(rule.StartIPAddress), to.String(rule.EndIPAddress), ipAddress)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("no firewall rule of postgresql flexible server %s allows %s", serverName, ipAddress)
+}
+
+// AssertPostgreSQLFlexibleServerPrivateAccess checks that the postgresql flexible server has public network access
+// disabled and is injected in the given subnet. This function would fail the test if there is an error or it is not.
+func AssertPostgreSQLFlexibleServerPrivateAccess(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, subnetID string) {
+	err := AssertPostgreSQLFlexibleServerPrivateAccessE(t, subscriptionID, resGroupName, serverName, subnetID)
+	require.NoError(t, err)
+}
+
+// AssertPostgreSQLFlexibleServerPrivateAccessE checks that the postgresql flexible server has public network access
+// disabled and is injected in the given subnet, i.e., it is only reachable from the virtual network.
+func AssertPostgreSQLFlexibleServerPrivateAccessE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, subnetID string) error {
+	server, err := GetPostgreSQLFlexibleServerE(t, subscriptionID, resGroupName, serverName)
+	if err != nil {
+		return err
+	}
+	return checkPostgreSQLFlexibleServerPrivateAccess(server, subnetID)
+}
+
+// checkPostgreSQLFlexibleServerPrivateAccess returns an error if the postgresql flexible server allows public network
+// access or is not delegated the given subnet. Resource IDs are compared case insensitively, as Azure does.
+func checkPostgreSQLFlexibleServerPrivateAccess(server *postgresqlflexibleservers.Server, subnetID string) error {
+	name := to.String(server.Name)
+	if server.ServerProperties == nil {
+		return fmt.Errorf("postgresql flexible server %s has no properties", name)
+	}
+	if server.PublicNetworkAccess != postgresqlflexibleservers.ServerPublicNetworkAccessStateDisabled {
+		return fmt.Errorf("postgresql flexible server %s has public network access %q", name, server.PublicNetworkAccess)
+	}
+	actualSubnetID := ""
+	if server.DelegatedSubnetArguments != nil {
+		actualSubnetID = to.String(server.DelegatedSubnetArguments.SubnetArmResourceID)
+	}
+	if !strings.EqualFold(actualSubnetID, subnetID) {
+		return fmt.Errorf("postgresql flexible server %s is delegated subnet %q, not %q", name, actualSubnetID, subnetID)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/preview/postgresql/mgmt/2020-11-05-preview/postgresqlflexibleservers"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when CRUD methods are introduced for Azure PostgreSQL flexible servers, these tests can be extended
+*/
+
+func TestGetPostgreSQLFlexibleServerE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetPostgreSQLFlexibleServerE(t, "", "", "")
+	require.Error(t, err)
+}
+
+func TestGetPostgreSQLFlexibleDatabaseE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetPostgreSQLFlexibleDatabaseE(t, "", "", "", "")
+	require.Error(t, err)
+}
+
+func TestCheckPostgreSQLFlexibleServerReady(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(state postgresqlflexibleservers.ServerState) *postgresqlflexibleservers.Server {
+		return &postgresqlflexibleservers.Server{
+			Name:             to.StringPtr("psql"),
+			ServerProperties: &postgresqlflexibleservers.ServerProperties{State: state},
+		}
+	}
+
+	assert.NoError(t, checkPostgreSQLFlexibleServerReady(newServer(postgresqlflexibleservers.ServerStateReady)))
+
+	err := checkPostgreSQLFlexibleServerReady(newServer(postgresqlflexibleservers.ServerStateStarting))
+	require.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+
+	err = checkPostgreSQLFlexibleServerReady(newServer(postgresqlflexibleservers.ServerStateDropping))
+	_, isFatal = err.(retry.FatalError)
+	assert.True(t, isFatal)
+
+	assert.Error(t, checkPostgreSQLFlexibleServerReady(&postgresqlflexibleservers.Server{}))
+}
+
+func TestCheckPostgreSQLFlexibleServerPrivateAccess(t *testing.T) {
+	t.Parallel()
+
+	subnetID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/db"
+	server := &postgresqlflexibleservers.Server{
+		Name: to.StringPtr("psql"),
+		ServerProperties: &postgresqlflexibleservers.ServerProperties{
+			PublicNetworkAccess: postgresqlflexibleservers.ServerPublicNetworkAccessStateDisabled,
+			DelegatedSubnetArguments: &postgresqlflexibleservers.ServerPropertiesDelegatedSubnetArguments{
+				SubnetArmResourceID: to.StringPtr(subnetID),
+			},
+		},
+	}
+
+	assert.NoError(t, checkPostgreSQLFlexibleServerPrivateAccess(server, subnetID))
+	assert.NoError(t, checkPostgreSQLFlexibleServerPrivateAccess(server, "/SUBSCRIPTIONS/00000000-0000-0000-0000-000000000000/resourceGroups/RG/providers/Microsoft.Network/virtualNetworks/vnet/subnets/db"))
+	assert.Error(t, checkPostgreSQLFlexibleServerPrivateAccess(server, subnetID+"2"))
+
+	server.PublicNetworkAccess = postgresqlflexibleservers.ServerPublicNetworkAccessStateEnabled
+	assert.Error(t, checkPostgreSQLFlexibleServerPrivateAccess(server, subnetID))
+}
@@ -0,0 +1,152 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// RouteSummary is a string-based (non-pointer) summary of a route of a route table.
+type RouteSummary struct {
+	Name             string
+	AddressPrefix    string
+	NextHopType      string
+	NextHopIPAddress string
+}
+
+// RouteTableExists indicates whether the specified route table exists.
+// This function would fail the test if there is an error.
+func RouteTableExists(t testing.TestingT, routeTableName string, resGroupName string, subscriptionID string) bool {
+	exists, err := RouteTableExistsE(routeTableName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return exists
+}
+
+// RouteTableExistsE indicates whether the specified route table exists.
+func RouteTableExistsE(routeTableName string, resGroupName string, subscriptionID string) (bool, error) {
+	_, err := GetRouteTableE(routeTableName, resGroupName, subscriptionID)
+	if err != nil {
+		if ResourceNotFoundErrorExists(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetRouteTableRoutes gets summaries of the routes of the route table.
+// This function would fail the test if there is an error.
+func GetRouteTableRoutes(t testing.TestingT, routeTableName string, resGroupName string, subscriptionID string) []RouteSummary {
+	routes, err := GetRouteTableRoutesE(routeTableName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return routes
+}
+
+// GetRouteTableRoutesE gets summaries of the routes of the route table.
+func GetRouteTableRoutesE(routeTableName string, resGroupName string, subscriptionID string) ([]RouteSummary, error) {
+	routeTable, err := GetRouteTableE(routeTableName, resGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []RouteSummary{}
+	if routeTable.RouteTablePropertiesFormat == nil || routeTable.Routes == nil {
+		return routes, nil
+	}
+	for _, route := range *routeTable.Routes {
+		routes = append(routes, convertToRouteSummary(route))
+	}
+	return routes, nil
+}
+
+// AssertRouteTableRoute checks that the route table has a route for the given address prefix to the given next hop.
+// This function would fail the test if there is an error or it does not.
+func AssertRouteTableRoute(t testing.TestingT, addressPrefix string, nextHopType network.RouteNextHopType, nextHopIPAddress string, routeTableName string, resGroupName string, subscriptionID string) {
+	err := AssertRouteTableRouteE(addressPrefix, nextHopType, nextHopIPAddress, routeTableName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertRouteTableRouteE checks that the route table has a route for the given address prefix to the given next hop.
+// The next hop IP address only applies to the VirtualAppliance next hop type, so leave it empty for the others.
+func AssertRouteTableRouteE(addressPrefix string, nextHopType network.RouteNextHopType, nextHopIPAddress string, routeTableName string, resGroupName string, subscriptionID string) error {
+	routes, err := GetRouteTableRoutesE(routeTableName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkRouteTableRoute(routes, addressPrefix, nextHopType, nextHopIPAddress, routeTableName)
+}
+
+// GetRouteTableE gets the route table.
+func GetRouteTableE(routeTableName string, resGroupName string, subscriptionID string) (*network.RouteTable, error) {
+	// Validate Azure Resource Group
+	resGroupName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the client reference
+	client, err := GetRouteTablesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the route table
+	routeTable, err := client.Get(context.Background(), resGroupName, routeTableName, "")
+	if err != nil {
+		return nil, err
+	}
+	return &routeTable, nil
+}
+
+// GetRouteTablesClientE creates a route tables client in the specified Azure Subscription.
+func GetRouteTablesClientE(subscriptionID string) (*network.RouteTablesClient, error) {
+	// Create a new route tables client from client factory
+	client, err := CreateRouteTablesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an authorizer
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	client.Authorizer = *authorizer
+
+	return client, nil
+}
+
+// convertToRouteSummary converts the raw SDK route type into a summarized struct.
+func convertToRouteSummary(route network.Route) RouteSummary {
+	summary := RouteSummary{Name: safePtrToString(route.Name)}
+	if route.RoutePropertiesFormat != nil {
+		summary.AddressPrefix = safePtrToString(route.AddressPrefix)
+		summary.NextHopType = string(route.NextHopType)
+		summary.NextHopIPAddress = safePtrToString(route.NextHopIPAddress)
+	}
+	return summary
+}
+
+// checkRouteTableRoute returns an error if none of the given routes is for the given address prefix, or if it is not
+// to the given next hop.
+func checkRouteTableRoute(routes []RouteSummary, addressPrefix string, nextHopType network.RouteNextHopType, nextHopIPAddress string, routeTableName string) error {
+	for _, route := range routes {
+		if !strings.EqualFold(route.AddressPrefix, addressPrefix) {
+			continue
+		}
+		if !strings.EqualFold(route.NextHopType, string(nextHopType)) || route.NextHopIPAddress != nextHopIPAddress {
+			return fmt.Errorf("route %s of route table %s for %s is to %s, expected %s", route.Name, routeTableName, addressPrefix, formatNextHop(route.NextHopType, route.NextHopIPAddress), formatNextHop(string(nextHopType), nextHopIPAddress))
+		}
+		return nil
+	}
+	return fmt.Errorf("route table %s has no route for %s", routeTableName, addressPrefix)
+}
+
+// formatNextHop formats a next hop for error messages, e.g., "VirtualAppliance 10.0.0.4" or "Internet".
+func formatNextHop(nextHopType string, nextHopIPAddress string) string {
+	return strings.TrimSpace(nextHopType + " " + nextHopIPAddress)
+}
@@ -0,0 +1,85 @@
+//go:build azure || (azureslim && network)
+// +build azure azureslim,network
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when methods can be mocked or Create/Delete APIs are added, these tests can be extended.
+*/
+
+func TestGetRouteTableE(t *testing.T) {
+	t.Parallel()
+
+	routeTableName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetRouteTableE(routeTableName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestRouteTableExistsE(t *testing.T) {
+	t.Parallel()
+
+	routeTableName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := RouteTableExistsE(routeTableName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestGetRouteTableRoutesE(t *testing.T) {
+	t.Parallel()
+
+	routeTableName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetRouteTableRoutesE(routeTableName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestCheckRouteTableRoute(t *testing.T) {
+	t.Parallel()
+
+	routes := []RouteSummary{
+		convertToRouteSummary(network.Route{
+			Name: to.StringPtr("to-firewall"),
+			RoutePropertiesFormat: &network.RoutePropertiesFormat{
+				AddressPrefix:    to.StringPtr("0.0.0.0/0"),
+				NextHopType:      network.RouteNextHopTypeVirtualAppliance,
+				NextHopIPAddress: to.StringPtr("10.1.0.4"),
+			},
+		}),
+		convertToRouteSummary(network.Route{
+			Name: to.StringPtr("blackhole"),
+			RoutePropertiesFormat: &network.RoutePropertiesFormat{
+				AddressPrefix: to.StringPtr("192.168.0.0/16"),
+				NextHopType:   network.RouteNextHopTypeNone,
+			},
+		}),
+	}
+
+	assert.NoError(t, checkRouteTableRoute(routes, "0.0.0.0/0", network.RouteNextHopTypeVirtualAppliance, "10.1.0.4", "terratest-rt"))
+	assert.NoError(t, checkRouteTableRoute(routes, "192.168.0.0/16", network.RouteNextHopTypeNone, "", "terratest-rt"))
+	assert.Error(t, checkRouteTableRoute(routes, "0.0.0.0/0", network.RouteNextHopTypeInternet, "", "terratest-rt"))
+	assert.Error(t, checkRouteTableRoute(routes, "0.0.0.0/0", network.RouteNextHopTypeVirtualAppliance, "10.1.0.5", "terratest-rt"))
+	assert.Error(t, checkRouteTableRoute(routes, "10.0.0.0/8", network.RouteNextHopTypeNone, "", "terratest-rt"))
+}
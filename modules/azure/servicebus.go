@@ -1,13 +1,33 @@
 package azure
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/servicebus/mgmt/2017-04-01/servicebus"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/stretchr/testify/require"
 )
 
+// serviceBusRootAuthRuleName is the name of the authorization rule that Azure creates with every Service Bus and Event
+// Hubs namespace, which grants the manage, send and listen rights on all its entities.
+const serviceBusRootAuthRuleName = "RootManageSharedAccessKey"
+
+// sasTokenValidity is how long the shared access signatures used to send and receive messages are valid for.
+const sasTokenValidity = time.Hour
+
 func serviceBusNamespaceClientE(subscriptionID string) (*servicebus.NamespacesClient, error) {
 	authorizer, err := NewAuthorizer()
 	if err != nil {
@@ -30,6 +50,17 @@ func serviceBusTopicClientE(subscriptionID string) (*servicebus.TopicsClient, er
 	return &tClient, nil
 }
 
+func serviceBusQueuesClientE(subscriptionID string) (*servicebus.QueuesClient, error) {
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	qClient := servicebus.NewQueuesClient(subscriptionID)
+	qClient.Authorizer = *authorizer
+	return &qClient, nil
+}
+
 func serviceBusSubscriptionsClientE(subscriptionID string) (*servicebus.SubscriptionsClient, error) {
 	authorizer, err := NewAuthorizer()
 	if err != nil {
@@ -368,3 +399,345 @@ func ListTopicAuthRules(t *testing.T, subscriptionID string, namespace string, r
 
 	return results
 }
+
+// ServiceBusMessage is a message sent to or received from a Service Bus queue or subscription.
+type ServiceBusMessage struct {
+	Body          string
+	MessageID     string
+	CorrelationID string
+	Label         string
+	// Properties are the custom properties of the message. They are only set on the messages that are sent.
+	Properties map[string]string
+	// DeliveryCount, SequenceNumber and the dead letter fields are only set on the messages that are received.
+	DeliveryCount              int
+	SequenceNumber             int64
+	DeadLetterReason           string
+	DeadLetterErrorDescription string
+}
+
+// serviceBusBrokerProperties are the standard properties of a message in the Service Bus REST API.
+type serviceBusBrokerProperties struct {
+	MessageID      string `json:"MessageId,omitempty"`
+	CorrelationID  string `json:"CorrelationId,omitempty"`
+	Label          string `json:"Label,omitempty"`
+	PartitionKey   string `json:"PartitionKey,omitempty"`
+	DeliveryCount  int    `json:"DeliveryCount,omitempty"`
+	SequenceNumber int64  `json:"SequenceNumber,omitempty"`
+	LockToken      string `json:"LockToken,omitempty"`
+}
+
+// ServiceBusSubscriptionPath returns the path of the given subscription of the given topic, to receive messages from it.
+func ServiceBusSubscriptionPath(topicName string, subscriptionName string) string {
+	return topicName + "/subscriptions/" + subscriptionName
+}
+
+// ServiceBusDeadLetterPath returns the path of the dead letter queue of the queue or subscription with the given path.
+func ServiceBusDeadLetterPath(entityPath string) string {
+	return entityPath + "/$DeadLetterQueue"
+}
+
+// SendServiceBusMessageE - send a message to a queue or a topic of the given namespace, authenticating with a key of
+// its RootManageSharedAccessKey authorization rule.
+func SendServiceBusMessageE(subscriptionID string, namespace string, resourceGroup string, entityPath string, message ServiceBusMessage) error {
+	endpoint, err := getServiceBusSASEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return err
+	}
+
+	brokerProperties, err := json.Marshal(serviceBusBrokerProperties{
+		MessageID:     message.MessageID,
+		CorrelationID: message.CorrelationID,
+		Label:         message.Label,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"BrokerProperties": string(brokerProperties)}
+	for name, value := range message.Properties {
+		// Custom properties are headers whose values are quoted strings.
+		headers[name] = strconv.Quote(value)
+	}
+
+	_, _, err = endpoint.do(http.MethodPost, entityPath+"/messages", []byte(message.Body), headers, http.StatusCreated)
+	return err
+}
+
+// SendServiceBusMessage - send a message to a queue or a topic of the given namespace. This function would fail the
+// test if there is an error.
+func SendServiceBusMessage(t *testing.T, subscriptionID string, namespace string, resourceGroup string, entityPath string, message ServiceBusMessage) {
+	err := SendServiceBusMessageE(subscriptionID, namespace, resourceGroup, entityPath, message)
+	require.NoError(t, err)
+}
+
+// ReceiveServiceBusMessageE - receive the next message from a queue, a subscription (see ServiceBusSubscriptionPath)
+// or a dead letter queue (see ServiceBusDeadLetterPath) of the given namespace, waiting up to the given timeout for
+// one to be available, and complete it so that it is removed.
+func ReceiveServiceBusMessageE(subscriptionID string, namespace string, resourceGroup string, entityPath string, timeout time.Duration) (*ServiceBusMessage, error) {
+	endpoint, err := getServiceBusSASEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	message, lockToken, err := endpoint.lockServiceBusMessage(entityPath, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, fmt.Errorf("no message received from %s of namespace %s in %s", entityPath, namespace, timeout)
+	}
+	if err := endpoint.settleServiceBusMessage(entityPath, message, lockToken, true); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// ReceiveServiceBusMessage - receive the next message from a queue, a subscription or a dead letter queue of the given
+// namespace. This function would fail the test if there is an error or no message is received in time.
+func ReceiveServiceBusMessage(t *testing.T, subscriptionID string, namespace string, resourceGroup string, entityPath string, timeout time.Duration) *ServiceBusMessage {
+	message, err := ReceiveServiceBusMessageE(subscriptionID, namespace, resourceGroup, entityPath, timeout)
+	require.NoError(t, err)
+	return message
+}
+
+// ReceiveServiceBusDeadLetterMessageE - receive the message with the given ID from the dead letter queue of a queue or
+// a subscription of the given namespace, and complete it. The other dead letter messages are left in the queue. This
+// is useful to check that a consumer dead letters the messages it can't process, and why (see DeadLetterReason).
+func ReceiveServiceBusDeadLetterMessageE(subscriptionID string, namespace string, resourceGroup string, entityPath string, messageID string, timeout time.Duration) (*ServiceBusMessage, error) {
+	endpoint, err := getServiceBusSASEndpointE(subscriptionID, namespace, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetterPath := ServiceBusDeadLetterPath(entityPath)
+	lockTokens := map[*ServiceBusMessage]string{}
+	// Keep the other messages locked while looking for the message, so that they are not received again, and unlock
+	// them at the end.
+	defer func() {
+		for message, lockToken := range lockTokens {
+			endpoint.settleServiceBusMessage(deadLetterPath, message, lockToken, false)
+		}
+	}()
+
+	for {
+		message, lockToken, err := endpoint.lockServiceBusMessage(deadLetterPath, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if message == nil {
+			return nil, fmt.Errorf("message %s was not dead lettered by %s of namespace %s", messageID, entityPath, namespace)
+		}
+		if message.MessageID != messageID {
+			lockTokens[message] = lockToken
+			continue
+		}
+		if err := endpoint.settleServiceBusMessage(deadLetterPath, message, lockToken, true); err != nil {
+			return nil, err
+		}
+		return message, nil
+	}
+}
+
+// ReceiveServiceBusDeadLetterMessage - receive the message with the given ID from the dead letter queue of a queue or
+// a subscription of the given namespace. This function would fail the test if there is an error or the message was not
+// dead lettered.
+func ReceiveServiceBusDeadLetterMessage(t *testing.T, subscriptionID string, namespace string, resourceGroup string, entityPath string, messageID string, timeout time.Duration) *ServiceBusMessage {
+	message, err := ReceiveServiceBusDeadLetterMessageE(subscriptionID, namespace, resourceGroup, entityPath, messageID, timeout)
+	require.NoError(t, err)
+	return message
+}
+
+// GetServiceBusQueueDeadLetterCountE - get the number of dead letter messages of the given queue.
+func GetServiceBusQueueDeadLetterCountE(subscriptionID string, namespace string, resourceGroup string, queueName string) (int64, error) {
+	qClient, err := serviceBusQueuesClientE(subscriptionID)
+	if err != nil {
+		return 0, err
+	}
+
+	queue, err := qClient.Get(context.Background(), resourceGroup, namespace, queueName)
+	if err != nil {
+		return 0, err
+	}
+	if queue.SBQueueProperties == nil {
+		return 0, nil
+	}
+	return getServiceBusDeadLetterCount(queue.CountDetails), nil
+}
+
+// GetServiceBusQueueDeadLetterCount - get the number of dead letter messages of the given queue. This function would
+// fail the test if there is an error.
+func GetServiceBusQueueDeadLetterCount(t *testing.T, subscriptionID string, namespace string, resourceGroup string, queueName string) int64 {
+	count, err := GetServiceBusQueueDeadLetterCountE(subscriptionID, namespace, resourceGroup, queueName)
+	require.NoError(t, err)
+	return count
+}
+
+// GetServiceBusSubscriptionDeadLetterCountE - get the number of dead letter messages of the given topic subscription.
+func GetServiceBusSubscriptionDeadLetterCountE(subscriptionID string, namespace string, resourceGroup string, topicName string, subscriptionName string) (int64, error) {
+	sClient, err := serviceBusSubscriptionsClientE(subscriptionID)
+	if err != nil {
+		return 0, err
+	}
+
+	subscription, err := sClient.Get(context.Background(), resourceGroup, namespace, topicName, subscriptionName)
+	if err != nil {
+		return 0, err
+	}
+	if subscription.SBSubscriptionProperties == nil {
+		return 0, nil
+	}
+	return getServiceBusDeadLetterCount(subscription.CountDetails), nil
+}
+
+// GetServiceBusSubscriptionDeadLetterCount - get the number of dead letter messages of the given topic subscription.
+// This function would fail the test if there is an error.
+func GetServiceBusSubscriptionDeadLetterCount(t *testing.T, subscriptionID string, namespace string, resourceGroup string, topicName string, subscriptionName string) int64 {
+	count, err := GetServiceBusSubscriptionDeadLetterCountE(subscriptionID, namespace, resourceGroup, topicName, subscriptionName)
+	require.NoError(t, err)
+	return count
+}
+
+// getServiceBusDeadLetterCount returns the number of dead letter messages in the given message counts.
+func getServiceBusDeadLetterCount(countDetails *servicebus.MessageCountDetails) int64 {
+	if countDetails == nil {
+		return 0
+	}
+	return to.Int64(countDetails.DeadLetterMessageCount)
+}
+
+// sasEndpoint is the data plane endpoint of a Service Bus or Event Hubs namespace, with the key of an authorization
+// rule to sign requests with.
+type sasEndpoint struct {
+	uri     string
+	keyName string
+	key     string
+}
+
+// newSASEndpoint returns the endpoint of a namespace with the given Service Bus endpoint (e.g.,
+// https://my-namespace.servicebus.windows.net:443/), which signs requests with the given key.
+func newSASEndpoint(serviceBusEndpoint string, keyName string, key string) (*sasEndpoint, error) {
+	endpointURL, err := url.Parse(serviceBusEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &sasEndpoint{uri: "https://" + endpointURL.Hostname() + "/", keyName: keyName, key: key}, nil
+}
+
+// getServiceBusSASEndpointE returns the endpoint of the given Service Bus namespace, with the primary key of its
+// RootManageSharedAccessKey authorization rule.
+func getServiceBusSASEndpointE(subscriptionID string, namespace string, resourceGroup string) (*sasEndpoint, error) {
+	nsClient, err := serviceBusNamespaceClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sbNamespace, err := nsClient.Get(context.Background(), resourceGroup, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if sbNamespace.SBNamespaceProperties == nil || sbNamespace.ServiceBusEndpoint == nil {
+		return nil, fmt.Errorf("namespace %s has no Service Bus endpoint", namespace)
+	}
+	keys, err := nsClient.ListKeys(context.Background(), resourceGroup, namespace, serviceBusRootAuthRuleName)
+	if err != nil {
+		return nil, err
+	}
+	return newSASEndpoint(*sbNamespace.ServiceBusEndpoint, serviceBusRootAuthRuleName, to.String(keys.PrimaryKey))
+}
+
+// newSharedAccessSignature returns a shared access signature token for the given resource URI, signed with the given
+// key of the authorization rule with the given name, that expires at the given time.
+// See https://docs.microsoft.com/en-us/azure/service-bus-messaging/service-bus-sas
+func newSharedAccessSignature(resourceURI string, keyName string, key string, expiry time.Time) string {
+	encodedURI := url.QueryEscape(resourceURI)
+	expiryString := strconv.FormatInt(expiry.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(encodedURI + "\n" + expiryString))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s&skn=%s", encodedURI, url.QueryEscape(signature), expiryString, keyName)
+}
+
+// do sends a request for the given path to the endpoint, and returns the headers and the body of the response if it
+// has one of the expected statuses.
+func (endpoint *sasEndpoint) do(method string, path string, body []byte, headers map[string]string, expectedStatuses ...int) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, endpoint.uri+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", newSharedAccessSignature(endpoint.uri, endpoint.keyName, endpoint.key, time.Now().Add(sasTokenValidity)))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, status := range expectedStatuses {
+		if resp.StatusCode == status {
+			return resp, respBody, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s %s returned status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// lockServiceBusMessage peek-locks the next message of the queue or subscription with the given path, waiting up to the
+// given timeout for one to be available, and returns it with its lock token, or nil if none is available.
+func (endpoint *sasEndpoint) lockServiceBusMessage(entityPath string, timeout time.Duration) (*ServiceBusMessage, string, error) {
+	path := fmt.Sprintf("%s/messages/head?timeout=%d", entityPath, int(timeout.Seconds()))
+	resp, body, err := endpoint.do(http.MethodPost, path, nil, nil, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, "", nil
+	}
+	message, lockToken, err := parseServiceBusMessage(resp.Header, body)
+	if err != nil {
+		return nil, "", err
+	}
+	return message, lockToken, nil
+}
+
+// settleServiceBusMessage completes the given locked message, so that it is removed from the queue or subscription with
+// the given path, or unlocks it, so that it can be received again.
+func (endpoint *sasEndpoint) settleServiceBusMessage(entityPath string, message *ServiceBusMessage, lockToken string, complete bool) error {
+	method := http.MethodPut
+	if complete {
+		method = http.MethodDelete
+	}
+	path := fmt.Sprintf("%s/messages/%s/%s", entityPath, url.PathEscape(message.MessageID), url.PathEscape(lockToken))
+	_, _, err := endpoint.do(method, path, nil, nil, http.StatusOK)
+	return err
+}
+
+// parseServiceBusMessage returns the message with the given headers and body received from the Service Bus REST API,
+// and its lock token.
+func parseServiceBusMessage(headers http.Header, body []byte) (*ServiceBusMessage, string, error) {
+	var brokerProperties serviceBusBrokerProperties
+	if err := json.Unmarshal([]byte(headers.Get("BrokerProperties")), &brokerProperties); err != nil {
+		return nil, "", fmt.Errorf("invalid broker properties %q: %v", headers.Get("BrokerProperties"), err)
+	}
+	return &ServiceBusMessage{
+		Body:                       string(body),
+		MessageID:                  brokerProperties.MessageID,
+		CorrelationID:              brokerProperties.CorrelationID,
+		Label:                      brokerProperties.Label,
+		DeliveryCount:              brokerProperties.DeliveryCount,
+		SequenceNumber:             brokerProperties.SequenceNumber,
+		DeadLetterReason:           unquoteServiceBusProperty(headers.Get("DeadLetterReason")),
+		DeadLetterErrorDescription: unquoteServiceBusProperty(headers.Get("DeadLetterErrorDescription")),
+	}, brokerProperties.LockToken, nil
+}
+
+// unquoteServiceBusProperty returns the value of a custom property header, which is quoted if it is a string.
+func unquoteServiceBusProperty(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return value
+}
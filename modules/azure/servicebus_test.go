@@ -6,8 +6,18 @@
 package azure
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/servicebus/mgmt/2017-04-01/servicebus"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -78,3 +88,69 @@ func TestListTopicSubscriptionsNameE(t *testing.T) {
 	_, err := ListTopicSubscriptionsNameE(subscriptionID, namespace, resourceGroup, topicName)
 	require.Error(t, err)
 }
+
+func TestSendServiceBusMessageE(t *testing.T) {
+	t.Parallel()
+
+	subscriptionID := ""
+	resourceGroup := ""
+	namespace := ""
+
+	err := SendServiceBusMessageE(subscriptionID, namespace, resourceGroup, "queue", ServiceBusMessage{Body: "test"})
+	require.Error(t, err)
+}
+
+func TestServiceBusPaths(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "orders/subscriptions/billing", ServiceBusSubscriptionPath("orders", "billing"))
+	assert.Equal(t, "orders/subscriptions/billing/$DeadLetterQueue", ServiceBusDeadLetterPath(ServiceBusSubscriptionPath("orders", "billing")))
+}
+
+func TestNewSharedAccessSignature(t *testing.T) {
+	t.Parallel()
+
+	endpoint, err := newSASEndpoint("https://my-namespace.servicebus.windows.net:443/", "RootManageSharedAccessKey", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "https://my-namespace.servicebus.windows.net/", endpoint.uri)
+
+	token := newSharedAccessSignature(endpoint.uri, endpoint.keyName, endpoint.key, time.Unix(1600000000, 0))
+	require.True(t, strings.HasPrefix(token, "SharedAccessSignature "))
+	values, err := url.ParseQuery(strings.TrimPrefix(token, "SharedAccessSignature "))
+	require.NoError(t, err)
+	assert.Equal(t, "https://my-namespace.servicebus.windows.net/", values.Get("sr"))
+	assert.Equal(t, "1600000000", values.Get("se"))
+	assert.Equal(t, "RootManageSharedAccessKey", values.Get("skn"))
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(url.QueryEscape("https://my-namespace.servicebus.windows.net/") + "\n1600000000"))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), values.Get("sig"))
+}
+
+func TestParseServiceBusMessage(t *testing.T) {
+	t.Parallel()
+
+	headers := http.Header{}
+	headers.Set("BrokerProperties", `{"MessageId":"message-1","DeliveryCount":10,"SequenceNumber":42,"LockToken":"lock-1"}`)
+	headers.Set("DeadLetterReason", `"MaxDeliveryCountExceeded"`)
+	message, lockToken, err := parseServiceBusMessage(headers, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "lock-1", lockToken)
+	assert.Equal(t, &ServiceBusMessage{
+		Body:             "hello",
+		MessageID:        "message-1",
+		DeliveryCount:    10,
+		SequenceNumber:   42,
+		DeadLetterReason: "MaxDeliveryCountExceeded",
+	}, message)
+
+	_, _, err = parseServiceBusMessage(http.Header{}, []byte("hello"))
+	assert.Error(t, err)
+}
+
+func TestGetServiceBusDeadLetterCount(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, int64(0), getServiceBusDeadLetterCount(nil))
+	assert.Equal(t, int64(3), getServiceBusDeadLetterCount(&servicebus.MessageCountDetails{DeadLetterMessageCount: to.Int64Ptr(3)}))
+}
@@ -2,8 +2,13 @@ package azure
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/preview/sql/mgmt/sql"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -135,3 +140,137 @@ func GetSQLDatabaseE(t testing.TestingT, subscriptionID string, resGroupName str
 	// Return DB
 	return &sqlDb, nil
 }
+
+// WaitUntilSQLServerReady waits until the sql server is ready, retrying the check for the specified amount of times,
+// sleeping for the provided duration between each try. This function would fail the test if there is an error or the
+// server is not ready in time.
+func WaitUntilSQLServerReady(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilSQLServerReadyE(t, subscriptionID, resGroupName, serverName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilSQLServerReadyE waits until the sql server is ready, retrying the check for the specified amount of times,
+// sleeping for the provided duration between each try.
+func WaitUntilSQLServerReadyE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for sql server %s to be ready.", serverName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			sqlServer, err := GetSQLServerE(t, subscriptionID, resGroupName, serverName)
+			if err != nil {
+				return "", err
+			}
+			if sqlServer.ServerProperties == nil || sqlServer.State != sql.ServerStateReady {
+				var state sql.ServerState
+				if sqlServer.ServerProperties != nil {
+					state = sqlServer.State
+				}
+				return "", fmt.Errorf("sql server %s is in state %q", serverName, state)
+			}
+			return fmt.Sprintf("sql server %s is ready", serverName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// WaitUntilSQLDatabaseOnline waits until the sql database is online, retrying the check for the specified amount of
+// times, sleeping for the provided duration between each try. This function would fail the test if there is an error
+// or the database is not online in time.
+func WaitUntilSQLDatabaseOnline(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, dbName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilSQLDatabaseOnlineE(t, subscriptionID, resGroupName, serverName, dbName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilSQLDatabaseOnlineE waits until the sql database is online, e.g., after it has been created, restored or
+// scaled, retrying the check for the specified amount of times, sleeping for the provided duration between each try.
+func WaitUntilSQLDatabaseOnlineE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, dbName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for sql database %s to be online.", dbName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			sqlDB, err := GetSQLDatabaseE(t, subscriptionID, resGroupName, serverName, dbName)
+			if err != nil {
+				return "", err
+			}
+			status := ""
+			if sqlDB.DatabaseProperties != nil {
+				status = to.String(sqlDB.Status)
+			}
+			if status != "Online" {
+				return "", fmt.Errorf("sql database %s has status %q", dbName, status)
+			}
+			return fmt.Sprintf("sql database %s is online", dbName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// GetSQLServerFirewallRules is a helper function that gets the firewall rules of the sql server.
+// This function would fail the test if there is an error.
+func GetSQLServerFirewallRules(t testing.TestingT, subscriptionID string, resGroupName string, serverName string) []sql.FirewallRule {
+	rules, err := GetSQLServerFirewallRulesE(t, subscriptionID, resGroupName, serverName)
+	require.NoError(t, err)
+
+	return rules
+}
+
+// GetSQLServerFirewallRulesE is a helper function that gets the firewall rules of the sql server.
+func GetSQLServerFirewallRulesE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string) ([]sql.FirewallRule, error) {
+	// Create a firewall rules client
+	firewallClient, err := CreateSQLServerFirewallRulesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+	firewallClient.Authorizer = *authorizer
+
+	// Get the firewall rules of the server
+	rules, err := firewallClient.ListByServer(context.Background(), resGroupName, serverName)
+	if err != nil {
+		return nil, err
+	}
+	if rules.Value == nil {
+		return []sql.FirewallRule{}, nil
+	}
+
+	return *rules.Value, nil
+}
+
+// AssertSQLServerFirewallAllowsIP checks that a firewall rule of the sql server allows the given IP address.
+// This function would fail the test if there is an error or no rule allows it.
+func AssertSQLServerFirewallAllowsIP(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, ipAddress string) {
+	err := AssertSQLServerFirewallAllowsIPE(t, subscriptionID, resGroupName, serverName, ipAddress)
+	require.NoError(t, err)
+}
+
+// AssertSQLServerFirewallAllowsIPE checks that a firewall rule of the sql server allows the given IP address, e.g., the
+// public IP address of the test runner before connecting to the server with ConnectAndQueryE.
+func AssertSQLServerFirewallAllowsIPE(t testing.TestingT, subscriptionID string, resGroupName string, serverName string, ipAddress string) error {
+	rules, err := GetSQLServerFirewallRulesE(t, subscriptionID, resGroupName, serverName)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if rule.FirewallRuleProperties == nil {
+			continue
+		}
+		allowed, err := firewallRangeAllowsIP(to.String(rule.StartIPAddress), to.String(rule.EndIPAddress), ipAddress)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("no firewall rule of sql server %s allows %s", serverName, ipAddress)
+}
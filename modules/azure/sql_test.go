@@ -38,3 +38,17 @@ func TestGetSQLDatabaseE(t *testing.T) {
 	_, err := GetSQLDatabaseE(t, resGroupName, serverName, dbName, subscriptionID)
 	require.Error(t, err)
 }
+
+func TestGetSQLServerFirewallRulesE(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetSQLServerFirewallRulesE(t, "", "", "")
+	require.Error(t, err)
+}
+
+func TestAssertSQLServerFirewallAllowsIPE(t *testing.T) {
+	t.Parallel()
+
+	err := AssertSQLServerFirewallAllowsIPE(t, "", "", "", "203.0.113.10")
+	require.Error(t, err)
+}
@@ -2,9 +2,12 @@ package azure
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -217,3 +220,233 @@ func GetVirtualNetworksClientE(subscriptionID string) (*network.VirtualNetworksC
 
 	return client, nil
 }
+
+// VirtualNetworkPeeringSummary is a string-based (non-pointer) summary of a virtual network peering.
+type VirtualNetworkPeeringSummary struct {
+	Name                      string
+	RemoteVirtualNetworkID    string
+	RemoteAddressSpace        []string
+	PeeringState              string
+	AllowVirtualNetworkAccess bool
+	AllowForwardedTraffic     bool
+	AllowGatewayTransit       bool
+	UseRemoteGateways         bool
+}
+
+// SubnetSummary is a string-based (non-pointer) summary of a subnet and the resources associated with it.
+type SubnetSummary struct {
+	Name                   string
+	AddressPrefixes        []string
+	NetworkSecurityGroupID string
+	RouteTableID           string
+	NatGatewayID           string
+	ServiceEndpoints       []string
+	Delegations            []string
+}
+
+// GetVirtualNetworkAddressSpace gets the address prefixes of the address space of the Virtual Network.
+// This function would fail the test if there is an error.
+func GetVirtualNetworkAddressSpace(t testing.TestingT, vnetName string, resGroupName string, subscriptionID string) []string {
+	addressPrefixes, err := GetVirtualNetworkAddressSpaceE(vnetName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return addressPrefixes
+}
+
+// GetVirtualNetworkAddressSpaceE gets the address prefixes of the address space of the Virtual Network.
+func GetVirtualNetworkAddressSpaceE(vnetName string, resGroupName string, subscriptionID string) ([]string, error) {
+	vnet, err := GetVirtualNetworkE(vnetName, resGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if vnet.VirtualNetworkPropertiesFormat == nil || vnet.AddressSpace == nil {
+		return []string{}, nil
+	}
+	return to.StringSlice(vnet.AddressSpace.AddressPrefixes), nil
+}
+
+// GetSubnetSummary gets a summary of the subnet, including the IDs of its network security group and route table.
+// This function would fail the test if there is an error.
+func GetSubnetSummary(t testing.TestingT, subnetName string, vnetName string, resGroupName string, subscriptionID string) SubnetSummary {
+	summary, err := GetSubnetSummaryE(subnetName, vnetName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return summary
+}
+
+// GetSubnetSummaryE gets a summary of the subnet, including the IDs of its network security group and route table.
+func GetSubnetSummaryE(subnetName string, vnetName string, resGroupName string, subscriptionID string) (SubnetSummary, error) {
+	subnet, err := GetSubnetE(subnetName, vnetName, resGroupName, subscriptionID)
+	if err != nil {
+		return SubnetSummary{}, err
+	}
+	return convertToSubnetSummary(subnet), nil
+}
+
+// AssertSubnetNetworkSecurityGroup checks that the subnet is associated with the network security group with the
+// given name. This function would fail the test if there is an error or it is not.
+func AssertSubnetNetworkSecurityGroup(t testing.TestingT, nsgName string, subnetName string, vnetName string, resGroupName string, subscriptionID string) {
+	err := AssertSubnetNetworkSecurityGroupE(nsgName, subnetName, vnetName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertSubnetNetworkSecurityGroupE checks that the subnet is associated with the network security group with the
+// given name.
+func AssertSubnetNetworkSecurityGroupE(nsgName string, subnetName string, vnetName string, resGroupName string, subscriptionID string) error {
+	summary, err := GetSubnetSummaryE(subnetName, vnetName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkSubnetAssociation("network security group", nsgName, summary.NetworkSecurityGroupID, subnetName)
+}
+
+// AssertSubnetRouteTable checks that the subnet is associated with the route table with the given name.
+// This function would fail the test if there is an error or it is not.
+func AssertSubnetRouteTable(t testing.TestingT, routeTableName string, subnetName string, vnetName string, resGroupName string, subscriptionID string) {
+	err := AssertSubnetRouteTableE(routeTableName, subnetName, vnetName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertSubnetRouteTableE checks that the subnet is associated with the route table with the given name.
+func AssertSubnetRouteTableE(routeTableName string, subnetName string, vnetName string, resGroupName string, subscriptionID string) error {
+	summary, err := GetSubnetSummaryE(subnetName, vnetName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkSubnetAssociation("route table", routeTableName, summary.RouteTableID, subnetName)
+}
+
+// GetVirtualNetworkPeerings gets summaries of the peerings of the Virtual Network.
+// This function would fail the test if there is an error.
+func GetVirtualNetworkPeerings(t testing.TestingT, vnetName string, resGroupName string, subscriptionID string) []VirtualNetworkPeeringSummary {
+	peerings, err := GetVirtualNetworkPeeringsE(vnetName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return peerings
+}
+
+// GetVirtualNetworkPeeringsE gets summaries of the peerings of the Virtual Network.
+func GetVirtualNetworkPeeringsE(vnetName string, resGroupName string, subscriptionID string) ([]VirtualNetworkPeeringSummary, error) {
+	vnet, err := GetVirtualNetworkE(vnetName, resGroupName, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	peerings := []VirtualNetworkPeeringSummary{}
+	if vnet.VirtualNetworkPropertiesFormat == nil || vnet.VirtualNetworkPeerings == nil {
+		return peerings, nil
+	}
+	for _, peering := range *vnet.VirtualNetworkPeerings {
+		peerings = append(peerings, convertToVirtualNetworkPeeringSummary(peering))
+	}
+	return peerings, nil
+}
+
+// AssertVirtualNetworkPeeringConnected checks that the Virtual Network has a connected peering with the Virtual
+// Network with the given ID. This function would fail the test if there is an error or it does not.
+func AssertVirtualNetworkPeeringConnected(t testing.TestingT, remoteVnetID string, vnetName string, resGroupName string, subscriptionID string) {
+	err := AssertVirtualNetworkPeeringConnectedE(remoteVnetID, vnetName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertVirtualNetworkPeeringConnectedE checks that the Virtual Network has a connected peering with the Virtual
+// Network with the given ID, which requires a peering in both directions.
+func AssertVirtualNetworkPeeringConnectedE(remoteVnetID string, vnetName string, resGroupName string, subscriptionID string) error {
+	peerings, err := GetVirtualNetworkPeeringsE(vnetName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return checkVirtualNetworkPeeringConnected(peerings, remoteVnetID, vnetName)
+}
+
+// convertToSubnetSummary converts the raw SDK subnet type into a summarized struct.
+func convertToSubnetSummary(subnet *network.Subnet) SubnetSummary {
+	summary := SubnetSummary{
+		Name:             safePtrToString(subnet.Name),
+		AddressPrefixes:  []string{},
+		ServiceEndpoints: []string{},
+		Delegations:      []string{},
+	}
+	properties := subnet.SubnetPropertiesFormat
+	if properties == nil {
+		return summary
+	}
+
+	if properties.AddressPrefixes != nil && len(*properties.AddressPrefixes) > 0 {
+		summary.AddressPrefixes = *properties.AddressPrefixes
+	} else if properties.AddressPrefix != nil {
+		summary.AddressPrefixes = []string{*properties.AddressPrefix}
+	}
+	if properties.NetworkSecurityGroup != nil {
+		summary.NetworkSecurityGroupID = safePtrToString(properties.NetworkSecurityGroup.ID)
+	}
+	if properties.RouteTable != nil {
+		summary.RouteTableID = safePtrToString(properties.RouteTable.ID)
+	}
+	if properties.NatGateway != nil {
+		summary.NatGatewayID = safePtrToString(properties.NatGateway.ID)
+	}
+	if properties.ServiceEndpoints != nil {
+		for _, endpoint := range *properties.ServiceEndpoints {
+			summary.ServiceEndpoints = append(summary.ServiceEndpoints, safePtrToString(endpoint.Service))
+		}
+	}
+	if properties.Delegations != nil {
+		for _, delegation := range *properties.Delegations {
+			if delegation.ServiceDelegationPropertiesFormat != nil {
+				summary.Delegations = append(summary.Delegations, safePtrToString(delegation.ServiceName))
+			}
+		}
+	}
+	return summary
+}
+
+// convertToVirtualNetworkPeeringSummary converts the raw SDK virtual network peering type into a summarized struct.
+func convertToVirtualNetworkPeeringSummary(peering network.VirtualNetworkPeering) VirtualNetworkPeeringSummary {
+	summary := VirtualNetworkPeeringSummary{
+		Name:               safePtrToString(peering.Name),
+		RemoteAddressSpace: []string{},
+	}
+	properties := peering.VirtualNetworkPeeringPropertiesFormat
+	if properties == nil {
+		return summary
+	}
+
+	if properties.RemoteVirtualNetwork != nil {
+		summary.RemoteVirtualNetworkID = safePtrToString(properties.RemoteVirtualNetwork.ID)
+	}
+	if properties.RemoteAddressSpace != nil {
+		summary.RemoteAddressSpace = to.StringSlice(properties.RemoteAddressSpace.AddressPrefixes)
+	}
+	summary.PeeringState = string(properties.PeeringState)
+	summary.AllowVirtualNetworkAccess = to.Bool(properties.AllowVirtualNetworkAccess)
+	summary.AllowForwardedTraffic = to.Bool(properties.AllowForwardedTraffic)
+	summary.AllowGatewayTransit = to.Bool(properties.AllowGatewayTransit)
+	summary.UseRemoteGateways = to.Bool(properties.UseRemoteGateways)
+	return summary
+}
+
+// checkSubnetAssociation returns an error if the resource with the given ID associated with the subnet does not have
+// the expected name.
+func checkSubnetAssociation(resourceType string, expectedName string, resourceID string, subnetName string) error {
+	if resourceID == "" {
+		return fmt.Errorf("subnet %s is not associated with a %s, expected %s", subnetName, resourceType, expectedName)
+	}
+	name := GetNameFromResourceID(resourceID)
+	if !strings.EqualFold(name, expectedName) {
+		return fmt.Errorf("subnet %s is associated with %s %s, expected %s", subnetName, resourceType, name, expectedName)
+	}
+	return nil
+}
+
+// checkVirtualNetworkPeeringConnected returns an error if none of the given peerings is a connected peering with the
+// Virtual Network with the given ID.
+func checkVirtualNetworkPeeringConnected(peerings []VirtualNetworkPeeringSummary, remoteVnetID string, vnetName string) error {
+	for _, peering := range peerings {
+		if !strings.EqualFold(peering.RemoteVirtualNetworkID, remoteVnetID) {
+			continue
+		}
+		if peering.PeeringState != string(network.VirtualNetworkPeeringStateConnected) {
+			return fmt.Errorf("peering %s of virtual network %s is %s, expected %s", peering.Name, vnetName, peering.PeeringState, network.VirtualNetworkPeeringStateConnected)
+		}
+		return nil
+	}
+	return fmt.Errorf("virtual network %s has no peering with virtual network %s", vnetName, remoteVnetID)
+}
@@ -9,6 +9,9 @@ package azure
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-09-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -104,3 +107,106 @@ func TestVirtualNetworkExistsE(t *testing.T) {
 
 	require.Error(t, err)
 }
+
+func TestGetVirtualNetworkAddressSpaceE(t *testing.T) {
+	t.Parallel()
+
+	vnetName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetVirtualNetworkAddressSpaceE(vnetName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestGetSubnetSummaryE(t *testing.T) {
+	t.Parallel()
+
+	subnetName := ""
+	vnetName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetSubnetSummaryE(subnetName, vnetName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestGetVirtualNetworkPeeringsE(t *testing.T) {
+	t.Parallel()
+
+	vnetName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetVirtualNetworkPeeringsE(vnetName, rgName, subID)
+
+	require.Error(t, err)
+}
+
+func TestConvertToSubnetSummary(t *testing.T) {
+	t.Parallel()
+
+	nsgID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/terratest-rg/providers/Microsoft.Network/networkSecurityGroups/terratest-nsg"
+	routeTableID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/terratest-rg/providers/Microsoft.Network/routeTables/terratest-rt"
+	subnet := network.Subnet{
+		Name: to.StringPtr("terratest-subnet"),
+		SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
+			AddressPrefix:        to.StringPtr("10.0.1.0/24"),
+			NetworkSecurityGroup: &network.SecurityGroup{ID: to.StringPtr(nsgID)},
+			RouteTable:           &network.RouteTable{ID: to.StringPtr(routeTableID)},
+			ServiceEndpoints:     &[]network.ServiceEndpointPropertiesFormat{{Service: to.StringPtr("Microsoft.Storage")}},
+			Delegations: &[]network.Delegation{{
+				ServiceDelegationPropertiesFormat: &network.ServiceDelegationPropertiesFormat{ServiceName: to.StringPtr("Microsoft.Web/serverFarms")},
+			}},
+		},
+	}
+
+	summary := convertToSubnetSummary(&subnet)
+	assert.Equal(t, "terratest-subnet", summary.Name)
+	assert.Equal(t, []string{"10.0.1.0/24"}, summary.AddressPrefixes)
+	assert.Equal(t, nsgID, summary.NetworkSecurityGroupID)
+	assert.Equal(t, routeTableID, summary.RouteTableID)
+	assert.Equal(t, "", summary.NatGatewayID)
+	assert.Equal(t, []string{"Microsoft.Storage"}, summary.ServiceEndpoints)
+	assert.Equal(t, []string{"Microsoft.Web/serverFarms"}, summary.Delegations)
+
+	assert.NoError(t, checkSubnetAssociation("network security group", "Terratest-NSG", summary.NetworkSecurityGroupID, summary.Name))
+	assert.Error(t, checkSubnetAssociation("route table", "other-rt", summary.RouteTableID, summary.Name))
+	assert.Error(t, checkSubnetAssociation("route table", "terratest-rt", "", summary.Name))
+
+	// Verify the nil values were correctly defaulted without a panic
+	empty := convertToSubnetSummary(&network.Subnet{})
+	assert.Equal(t, []string{}, empty.AddressPrefixes)
+}
+
+func TestCheckVirtualNetworkPeeringConnected(t *testing.T) {
+	t.Parallel()
+
+	hubID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/terratest-rg/providers/Microsoft.Network/virtualNetworks/hub"
+	spokeID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/terratest-rg/providers/Microsoft.Network/virtualNetworks/spoke"
+	peerings := []VirtualNetworkPeeringSummary{
+		convertToVirtualNetworkPeeringSummary(network.VirtualNetworkPeering{
+			Name: to.StringPtr("to-hub"),
+			VirtualNetworkPeeringPropertiesFormat: &network.VirtualNetworkPeeringPropertiesFormat{
+				RemoteVirtualNetwork:      &network.SubResource{ID: to.StringPtr(hubID)},
+				PeeringState:              network.VirtualNetworkPeeringStateConnected,
+				AllowVirtualNetworkAccess: to.BoolPtr(true),
+			},
+		}),
+		convertToVirtualNetworkPeeringSummary(network.VirtualNetworkPeering{
+			Name: to.StringPtr("to-spoke"),
+			VirtualNetworkPeeringPropertiesFormat: &network.VirtualNetworkPeeringPropertiesFormat{
+				RemoteVirtualNetwork: &network.SubResource{ID: to.StringPtr(spokeID)},
+				PeeringState:         network.VirtualNetworkPeeringStateInitiated,
+			},
+		}),
+	}
+
+	assert.True(t, peerings[0].AllowVirtualNetworkAccess)
+	assert.False(t, peerings[0].UseRemoteGateways)
+	assert.NoError(t, checkVirtualNetworkPeeringConnected(peerings, hubID, "terratest-vnet"))
+	assert.Error(t, checkVirtualNetworkPeeringConnected(peerings, spokeID, "terratest-vnet"))
+	assert.Error(t, checkVirtualNetworkPeeringConnected(peerings, "/subscriptions/other", "terratest-vnet"))
+}
@@ -0,0 +1,381 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// GetVmssClientE is a helper function that will setup an Azure Virtual Machine Scale Sets client on your behalf.
+func GetVmssClientE(subscriptionID string) (*compute.VirtualMachineScaleSetsClient, error) {
+	client, err := CreateVirtualMachineScaleSetsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return client, nil
+}
+
+// GetVmssInstancesClientE is a helper function that will setup an Azure Virtual Machine Scale Set VMs client on your
+// behalf, which manages the instances of scale sets.
+func GetVmssInstancesClientE(subscriptionID string) (*compute.VirtualMachineScaleSetVMsClient, error) {
+	client, err := CreateVirtualMachineScaleSetVMsClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return client, nil
+}
+
+// GetVmssRollingUpgradesClientE is a helper function that will setup an Azure Virtual Machine Scale Set Rolling
+// Upgrades client on your behalf.
+func GetVmssRollingUpgradesClientE(subscriptionID string) (*compute.VirtualMachineScaleSetRollingUpgradesClient, error) {
+	client, err := CreateVirtualMachineScaleSetRollingUpgradesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer, err := NewAuthorizer()
+	if err != nil {
+		return nil, err
+	}
+
+	client.Authorizer = *authorizer
+	return client, nil
+}
+
+// GetVmss gets a Virtual Machine Scale Set in the specified Azure Resource Group.
+// This function would fail the test if there is an error.
+func GetVmss(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string) *compute.VirtualMachineScaleSet {
+	vmss, err := GetVmssE(vmssName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return vmss
+}
+
+// GetVmssE gets a Virtual Machine Scale Set in the specified Azure Resource Group.
+func GetVmssE(vmssName string, resGroupName string, subscriptionID string) (*compute.VirtualMachineScaleSet, error) {
+	resGroupName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetVmssClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	vmss, err := client.Get(context.Background(), resGroupName, vmssName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vmss, nil
+}
+
+// GetVmssInstances gets the instances of a Virtual Machine Scale Set, with their instance view.
+// This function would fail the test if there is an error.
+func GetVmssInstances(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string) []compute.VirtualMachineScaleSetVM {
+	instances, err := GetVmssInstancesE(vmssName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return instances
+}
+
+// GetVmssInstancesE gets the instances of a Virtual Machine Scale Set, with their instance view, which has their power
+// state and their health as reported by the application health extension or the load balancer health probe.
+func GetVmssInstancesE(vmssName string, resGroupName string, subscriptionID string) ([]compute.VirtualMachineScaleSetVM, error) {
+	resGroupName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetVmssInstancesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := client.ListComplete(context.Background(), resGroupName, vmssName, "", "", string(compute.InstanceView))
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []compute.VirtualMachineScaleSetVM{}
+	for iterator.NotDone() {
+		instances = append(instances, iterator.Value())
+		if err := iterator.NextWithContext(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return instances, nil
+}
+
+// WaitUntilVmssInstancesHealthy waits until all the instances of a Virtual Machine Scale Set are healthy, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the instances are not healthy in time.
+func WaitUntilVmssInstancesHealthy(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilVmssInstancesHealthyE(t, vmssName, resGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilVmssInstancesHealthyE waits until a Virtual Machine Scale Set has as many instances as its capacity, and all
+// of them are provisioned, running and healthy (if the scale set reports application health), retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try.
+func WaitUntilVmssInstancesHealthyE(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	return waitUntilVmssCapacityHealthyE(t, vmssName, resGroupName, subscriptionID, -1, maxRetries, sleepBetweenRetries)
+}
+
+// WaitUntilVmssCapacity waits until a Virtual Machine Scale Set has been scaled to the given capacity, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the scale set is not scaled in time.
+func WaitUntilVmssCapacity(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, capacity int64, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilVmssCapacityE(t, vmssName, resGroupName, subscriptionID, capacity, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilVmssCapacityE waits until a Virtual Machine Scale Set has the given capacity and as many healthy instances,
+// e.g., after an autoscale rule scales it out or in, retrying the check for the specified amount of times, sleeping for
+// the provided duration between each try.
+func WaitUntilVmssCapacityE(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, capacity int64, maxRetries int, sleepBetweenRetries time.Duration) error {
+	return waitUntilVmssCapacityHealthyE(t, vmssName, resGroupName, subscriptionID, capacity, maxRetries, sleepBetweenRetries)
+}
+
+// waitUntilVmssCapacityHealthyE waits until a Virtual Machine Scale Set has the given capacity (or any capacity if it is
+// negative) and as many healthy instances.
+func waitUntilVmssCapacityHealthyE(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, capacity int64, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the instances of VMSS %s to be healthy.", vmssName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			vmss, err := GetVmssE(vmssName, resGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			actualCapacity := int64(0)
+			if vmss.Sku != nil {
+				actualCapacity = to.Int64(vmss.Sku.Capacity)
+			}
+			if capacity >= 0 && actualCapacity != capacity {
+				return "", fmt.Errorf("VMSS %s has capacity %d rather than %d", vmssName, actualCapacity, capacity)
+			}
+
+			instances, err := GetVmssInstancesE(vmssName, resGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			if err := checkVmssInstancesHealthy(instances, actualCapacity); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("All %d instances of VMSS %s are healthy", actualCapacity, vmssName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkVmssInstancesHealthy returns an error if there isn't the given number of scale set instances, or if any of them
+// is not healthy.
+func checkVmssInstancesHealthy(instances []compute.VirtualMachineScaleSetVM, capacity int64) error {
+	if int64(len(instances)) != capacity {
+		return fmt.Errorf("VMSS has %d instances rather than %d", len(instances), capacity)
+	}
+
+	unhealthy := []string{}
+	for _, instance := range instances {
+		if err := checkVmssInstanceHealthy(instance); err != nil {
+			unhealthy = append(unhealthy, err.Error())
+		}
+	}
+	if len(unhealthy) > 0 {
+		sort.Strings(unhealthy)
+		return fmt.Errorf("%d of %d VMSS instances are not healthy: %s", len(unhealthy), capacity, strings.Join(unhealthy, "; "))
+	}
+	return nil
+}
+
+// checkVmssInstanceHealthy returns an error if the given scale set instance is not provisioned, not running, or not
+// healthy. Instances that don't report application health are only required to be running.
+func checkVmssInstanceHealthy(instance compute.VirtualMachineScaleSetVM) error {
+	id := to.String(instance.InstanceID)
+	if instance.VirtualMachineScaleSetVMProperties == nil {
+		return fmt.Errorf("instance %s has no properties", id)
+	}
+	if state := to.String(instance.ProvisioningState); state != "Succeeded" {
+		return fmt.Errorf("instance %s provisioning state is %q", id, state)
+	}
+	if instance.InstanceView == nil {
+		return fmt.Errorf("instance %s has no instance view", id)
+	}
+
+	powerState := ""
+	if instance.InstanceView.Statuses != nil {
+		for _, status := range *instance.InstanceView.Statuses {
+			if code := to.String(status.Code); strings.HasPrefix(code, "PowerState/") {
+				powerState = code
+			}
+		}
+	}
+	if powerState != "PowerState/running" {
+		return fmt.Errorf("instance %s power state is %q", id, powerState)
+	}
+
+	if instance.InstanceView.VMHealth != nil && instance.InstanceView.VMHealth.Status != nil {
+		if health := to.String(instance.InstanceView.VMHealth.Status.Code); health != "HealthState/healthy" {
+			return fmt.Errorf("instance %s health state is %q", id, health)
+		}
+	}
+	return nil
+}
+
+// GetVmssRollingUpgradeStatus gets the status of the latest rolling upgrade of a Virtual Machine Scale Set.
+// This function would fail the test if there is an error.
+func GetVmssRollingUpgradeStatus(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string) *compute.RollingUpgradeStatusInfo {
+	status, err := GetVmssRollingUpgradeStatusE(vmssName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+	return status
+}
+
+// GetVmssRollingUpgradeStatusE gets the status of the latest rolling upgrade of a Virtual Machine Scale Set, including
+// the number of instances that have been upgraded so far.
+func GetVmssRollingUpgradeStatusE(vmssName string, resGroupName string, subscriptionID string) (*compute.RollingUpgradeStatusInfo, error) {
+	resGroupName, err := getTargetAzureResourceGroupName(resGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := GetVmssRollingUpgradesClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := client.GetLatest(context.Background(), resGroupName, vmssName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// WaitUntilVmssRollingUpgradeCompleted waits until the latest rolling upgrade of a Virtual Machine Scale Set has
+// completed, retrying the check for the specified amount of times, sleeping for the provided duration between each try.
+// This function would fail the test if there is an error or the rolling upgrade fails.
+func WaitUntilVmssRollingUpgradeCompleted(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilVmssRollingUpgradeCompletedE(t, vmssName, resGroupName, subscriptionID, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilVmssRollingUpgradeCompletedE waits until the latest rolling upgrade of a Virtual Machine Scale Set has
+// completed, logging its progress, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. This stops retrying if the rolling upgrade is cancelled or faulted, e.g., because too
+// many upgraded instances are unhealthy.
+func WaitUntilVmssRollingUpgradeCompletedE(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the rolling upgrade of VMSS %s to complete.", vmssName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			status, err := GetVmssRollingUpgradeStatusE(vmssName, resGroupName, subscriptionID)
+			if err != nil {
+				return "", err
+			}
+			logger.Logf(t, "Rolling upgrade of VMSS %s: %s", vmssName, formatVmssRollingUpgradeProgress(status))
+			if err := checkVmssRollingUpgradeCompleted(status); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Rolling upgrade of VMSS %s completed", vmssName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkVmssRollingUpgradeCompleted returns an error if the given rolling upgrade has not completed, which is a
+// retry.FatalError if it was cancelled or faulted.
+func checkVmssRollingUpgradeCompleted(status *compute.RollingUpgradeStatusInfo) error {
+	if status.RollingUpgradeStatusInfoProperties == nil || status.RunningStatus == nil {
+		return fmt.Errorf("rolling upgrade has no status yet")
+	}
+	switch status.RunningStatus.Code {
+	case compute.RollingUpgradeStatusCodeCompleted:
+		return nil
+	case compute.RollingUpgradeStatusCodeCancelled, compute.RollingUpgradeStatusCodeFaulted:
+		message := ""
+		if status.Error != nil {
+			message = ": " + to.String(status.Error.Message)
+		}
+		return retry.FatalError{Underlying: fmt.Errorf("rolling upgrade is %s%s", status.RunningStatus.Code, message)}
+	default:
+		return fmt.Errorf("rolling upgrade is %s", status.RunningStatus.Code)
+	}
+}
+
+// formatVmssRollingUpgradeProgress returns a summary of the progress of the given rolling upgrade.
+func formatVmssRollingUpgradeProgress(status *compute.RollingUpgradeStatusInfo) string {
+	if status.RollingUpgradeStatusInfoProperties == nil || status.Progress == nil {
+		return "no progress yet"
+	}
+	progress := status.Progress
+	return fmt.Sprintf(
+		"%d succeeded, %d in progress, %d pending, %d failed",
+		to.Int32(progress.SuccessfulInstanceCount),
+		to.Int32(progress.InProgressInstanceCount),
+		to.Int32(progress.PendingInstanceCount),
+		to.Int32(progress.FailedInstanceCount),
+	)
+}
+
+// AssertVmssInstancesLatestModel checks that all the instances of a Virtual Machine Scale Set run its latest model.
+// This function would fail the test if they do not.
+func AssertVmssInstancesLatestModel(t testing.TestingT, vmssName string, resGroupName string, subscriptionID string) {
+	err := AssertVmssInstancesLatestModelE(vmssName, resGroupName, subscriptionID)
+	require.NoError(t, err)
+}
+
+// AssertVmssInstancesLatestModelE checks that all the instances of a Virtual Machine Scale Set run its latest model,
+// e.g., that a new image has been rolled out to all of them, and returns an error with the outdated instances if not.
+func AssertVmssInstancesLatestModelE(vmssName string, resGroupName string, subscriptionID string) error {
+	instances, err := GetVmssInstancesE(vmssName, resGroupName, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	outdated := getVmssInstancesNotOnLatestModel(instances)
+	if len(outdated) > 0 {
+		return fmt.Errorf("instances %s of VMSS %s don't run its latest model", strings.Join(outdated, ", "), vmssName)
+	}
+	return nil
+}
+
+// getVmssInstancesNotOnLatestModel returns the IDs of the given scale set instances that don't run the latest model.
+func getVmssInstancesNotOnLatestModel(instances []compute.VirtualMachineScaleSetVM) []string {
+	outdated := []string{}
+	for _, instance := range instances {
+		if instance.VirtualMachineScaleSetVMProperties == nil || !to.Bool(instance.LatestModelApplied) {
+			outdated = append(outdated, to.String(instance.InstanceID))
+		}
+	}
+	return outdated
+}
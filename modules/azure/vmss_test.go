@@ -0,0 +1,114 @@
+//go:build azure
+// +build azure
+
+// NOTE: We use build tags to differentiate azure testing because we currently do not have azure access setup for
+// CircleCI.
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+The below tests are currently stubbed out, with the expectation that they will throw errors.
+If/when CRUD methods are introduced for Azure Virtual Machine Scale Sets, these tests can be extended.
+*/
+
+func TestGetVmssE(t *testing.T) {
+	t.Parallel()
+
+	vmssName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetVmssE(vmssName, rgName, subID)
+	require.Error(t, err)
+}
+
+func TestGetVmssRollingUpgradeStatusE(t *testing.T) {
+	t.Parallel()
+
+	vmssName := ""
+	rgName := ""
+	subID := ""
+
+	_, err := GetVmssRollingUpgradeStatusE(vmssName, rgName, subID)
+	require.Error(t, err)
+}
+
+func newTestVmssInstance(id string, health string, latestModel bool) compute.VirtualMachineScaleSetVM {
+	instanceView := &compute.VirtualMachineScaleSetVMInstanceView{
+		Statuses: &[]compute.InstanceViewStatus{
+			{Code: to.StringPtr("ProvisioningState/succeeded")},
+			{Code: to.StringPtr("PowerState/running")},
+		},
+	}
+	if health != "" {
+		instanceView.VMHealth = &compute.VirtualMachineHealthStatus{Status: &compute.InstanceViewStatus{Code: to.StringPtr(health)}}
+	}
+	return compute.VirtualMachineScaleSetVM{
+		InstanceID: to.StringPtr(id),
+		VirtualMachineScaleSetVMProperties: &compute.VirtualMachineScaleSetVMProperties{
+			ProvisioningState:  to.StringPtr("Succeeded"),
+			LatestModelApplied: to.BoolPtr(latestModel),
+			InstanceView:       instanceView,
+		},
+	}
+}
+
+func TestCheckVmssInstancesHealthy(t *testing.T) {
+	t.Parallel()
+
+	instances := []compute.VirtualMachineScaleSetVM{
+		newTestVmssInstance("0", "HealthState/healthy", true),
+		newTestVmssInstance("1", "", true),
+	}
+	assert.NoError(t, checkVmssInstancesHealthy(instances, 2))
+	assert.Error(t, checkVmssInstancesHealthy(instances, 3))
+
+	instances = append(instances, newTestVmssInstance("2", "HealthState/unhealthy", true))
+	err := checkVmssInstancesHealthy(instances, 3)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `instance 2 health state is "HealthState/unhealthy"`)
+}
+
+func TestCheckVmssRollingUpgradeCompleted(t *testing.T) {
+	t.Parallel()
+
+	newStatus := func(code compute.RollingUpgradeStatusCode) *compute.RollingUpgradeStatusInfo {
+		return &compute.RollingUpgradeStatusInfo{RollingUpgradeStatusInfoProperties: &compute.RollingUpgradeStatusInfoProperties{
+			RunningStatus: &compute.RollingUpgradeRunningStatus{Code: code},
+			Progress:      &compute.RollingUpgradeProgressInfo{SuccessfulInstanceCount: to.Int32Ptr(1), PendingInstanceCount: to.Int32Ptr(2)},
+		}}
+	}
+	assert.NoError(t, checkVmssRollingUpgradeCompleted(newStatus(compute.RollingUpgradeStatusCodeCompleted)))
+
+	err := checkVmssRollingUpgradeCompleted(newStatus(compute.RollingUpgradeStatusCodeRollingForward))
+	require.Error(t, err)
+	_, fatal := err.(retry.FatalError)
+	assert.False(t, fatal)
+
+	err = checkVmssRollingUpgradeCompleted(newStatus(compute.RollingUpgradeStatusCodeFaulted))
+	require.Error(t, err)
+	_, fatal = err.(retry.FatalError)
+	assert.True(t, fatal)
+
+	assert.Equal(t, "1 succeeded, 0 in progress, 2 pending, 0 failed", formatVmssRollingUpgradeProgress(newStatus(compute.RollingUpgradeStatusCodeRollingForward)))
+}
+
+func TestGetVmssInstancesNotOnLatestModel(t *testing.T) {
+	t.Parallel()
+
+	instances := []compute.VirtualMachineScaleSetVM{
+		newTestVmssInstance("0", "", true),
+		newTestVmssInstance("1", "", false),
+	}
+	assert.Equal(t, []string{"1"}, getVmssInstancesNotOnLatestModel(instances))
+}
@@ -45,6 +45,27 @@ type BuildOptions struct {
 	// included in the Architectures list.
 	Load bool
 
+	// Secrets to expose to the build with the --secret option, e.g., "id=github-token,env=GITHUB_OAUTH_TOKEN", for
+	// RUN --mount=type=secret instructions. Requires BuildKit, which is enabled if this is set.
+	Secrets []string
+
+	// SSH agent sockets or keys to expose to the build with the --ssh option, e.g., "default", for
+	// RUN --mount=type=ssh instructions. Requires BuildKit, which is enabled if this is set.
+	SSH []string
+
+	// External cache sources to pass to the build with the --cache-from option, e.g., "type=registry,ref=repo:cache".
+	// Requires BuildKit, which is enabled if this is set.
+	CacheFrom []string
+
+	// Cache export destinations to pass to the build with the --cache-to option, e.g., "type=inline". Exporting the
+	// cache requires docker buildx, so setting this builds the image with 'docker buildx build' and loads it into the
+	// docker daemon, unless Outputs is set.
+	CacheTo []string
+
+	// Build outputs to pass to the build with the --output option, e.g., "type=local,dest=out" to export the files of
+	// the image rather than loading it into the docker daemon. Requires BuildKit, which is enabled if this is set.
+	Outputs []string
+
 	// Custom CLI options that will be passed as-is to the 'docker build' command. This is an "escape hatch" that allows
 	// Terratest to not have to support every single command-line option offered by the 'docker build' command, and
 	// solely focus on the most important ones.
@@ -56,6 +77,9 @@ type BuildOptions struct {
 	// Additional environment variables to pass in when running docker build command.
 	Env map[string]string
 
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
 	// Set a logger that should be used. See the logger package for more info.
 	Logger *logger.Logger
 }
@@ -75,13 +99,14 @@ func BuildE(t testing.TestingT, path string, options *BuildOptions) error {
 		env = options.Env
 	}
 
-	if options.EnableBuildKit {
+	if options.EnableBuildKit || requiresBuildKit(options) {
 		env["DOCKER_BUILDKIT"] = "1"
 	}
 
+	command, args := runtimeCommand(options.Runtime, formatDockerBuildArgs(path, options)...)
 	cmd := shell.Command{
-		Command: "docker",
-		Args:    formatDockerBuildArgs(path, options),
+		Command: command,
+		Args:    args,
 		Logger:  options.Logger,
 		Env:     env,
 	}
@@ -95,7 +120,7 @@ func BuildE(t testing.TestingT, path string, options *BuildOptions) error {
 	if len(options.Architectures) == 0 && options.Push {
 		var errorsOccurred = new(multierror.Error)
 		for _, tag := range options.Tags {
-			if err := PushE(t, options.Logger, tag); err != nil {
+			if err := pushE(t, options.Runtime, options.Logger, tag); err != nil {
 				options.Logger.Logf(t, "ERROR: error pushing tag %s", tag)
 				errorsOccurred = multierror.Append(err)
 			}
@@ -105,9 +130,10 @@ func BuildE(t testing.TestingT, path string, options *BuildOptions) error {
 
 	// For multiarch images, if a load is requested call the load command to export the built image into the daemon.
 	if len(options.Architectures) > 0 && options.Load {
+		command, args := runtimeCommand(options.Runtime, formatDockerBuildxLoadArgs(path, options)...)
 		loadCmd := shell.Command{
-			Command: "docker",
-			Args:    formatDockerBuildxLoadArgs(path, options),
+			Command: command,
+			Args:    args,
 			Logger:  options.Logger,
 		}
 		return shell.RunCommandE(t, loadCmd)
@@ -184,13 +210,30 @@ func formatDockerBuildArgs(path string, options *BuildOptions) []string {
 		if options.Push {
 			args = append(args, "--push")
 		}
+	} else if len(options.CacheTo) > 0 {
+		// Only buildx can export the cache, and depending on its builder it may not load the image into the daemon
+		// without --load, which is a shorthand for an output.
+		args = append(args, "buildx", "build")
+		if len(options.Outputs) == 0 {
+			args = append(args, "--load")
+		}
 	} else {
 		args = append(args, "build")
 	}
 
+	for _, output := range options.Outputs {
+		args = append(args, "--output", output)
+	}
+
 	return append(args, formatDockerBuildBaseArgs(path, options)...)
 }
 
+// requiresBuildKit returns whether the given options use features of BuildKit, which the legacy builder does not
+// support.
+func requiresBuildKit(options *BuildOptions) bool {
+	return len(options.Secrets) > 0 || len(options.SSH) > 0 || len(options.CacheFrom) > 0 || len(options.Outputs) > 0
+}
+
 // formatDockerBuildxLoadArgs formats the arguments for calling load on the 'docker buildx' command.
 func formatDockerBuildxLoadArgs(path string, options *BuildOptions) []string {
 	args := []string{
@@ -216,6 +259,22 @@ func formatDockerBuildBaseArgs(path string, options *BuildOptions) []string {
 		args = append(args, "--target", options.Target)
 	}
 
+	for _, secret := range options.Secrets {
+		args = append(args, "--secret", secret)
+	}
+
+	for _, ssh := range options.SSH {
+		args = append(args, "--ssh", ssh)
+	}
+
+	for _, cacheFrom := range options.CacheFrom {
+		args = append(args, "--cache-from", cacheFrom)
+	}
+
+	for _, cacheTo := range options.CacheTo {
+		args = append(args, "--cache-to", cacheTo)
+	}
+
 	args = append(args, options.OtherOptions...)
 
 	args = append(args, path)
@@ -8,6 +8,7 @@ import (
 	"github.com/gruntwork-io/terratest/modules/git"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,6 +46,54 @@ func TestBuildWithBuildKit(t *testing.T) {
 	require.Contains(t, out, testToken)
 }
 
+func TestBuildWithBuildKitSecrets(t *testing.T) {
+	t.Parallel()
+
+	tag := "gruntwork-io/test-image-with-buildkit-secrets:v1"
+	testToken := "testToken"
+	options := &BuildOptions{
+		Tags:    []string{tag},
+		Secrets: []string{"id=github-token,env=GITHUB_OAUTH_TOKEN"},
+		Env:     map[string]string{"GITHUB_OAUTH_TOKEN": testToken},
+	}
+
+	// Setting Secrets enables BuildKit without EnableBuildKit
+	Build(t, "../../test/fixtures/docker-with-buildkit", options)
+	out := Run(t, tag, &RunOptions{Remove: true})
+	require.Contains(t, out, testToken)
+}
+
+func TestFormatDockerBuildArgsWithBuildKitOptions(t *testing.T) {
+	t.Parallel()
+
+	options := &BuildOptions{
+		Tags:      []string{"gruntwork-io/test-image:v1"},
+		Secrets:   []string{"id=github-token,env=GITHUB_OAUTH_TOKEN"},
+		SSH:       []string{"default"},
+		CacheFrom: []string{"type=registry,ref=gruntwork-io/test-image:cache"},
+	}
+	assert.True(t, requiresBuildKit(options))
+	assert.Equal(t, []string{
+		"build",
+		"--tag", "gruntwork-io/test-image:v1",
+		"--secret", "id=github-token,env=GITHUB_OAUTH_TOKEN",
+		"--ssh", "default",
+		"--cache-from", "type=registry,ref=gruntwork-io/test-image:cache",
+		".",
+	}, formatDockerBuildArgs(".", options))
+
+	// Exporting the cache requires buildx, which must load the image into the daemon
+	options.CacheTo = []string{"type=inline"}
+	assert.Equal(t, []string{"buildx", "build", "--load"}, formatDockerBuildArgs(".", options)[:3])
+	assert.Contains(t, formatDockerBuildArgs(".", options), "--cache-to")
+
+	// Unless the build exports its output elsewhere
+	options.Outputs = []string{"type=local,dest=out"}
+	assert.Equal(t, []string{"buildx", "build", "--output", "type=local,dest=out"}, formatDockerBuildArgs(".", options)[:4])
+
+	assert.False(t, requiresBuildKit(&BuildOptions{Tags: []string{"gruntwork-io/test-image:v1"}}))
+}
+
 func TestBuildMultiArch(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,298 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// BuildxOptions defines options that can be passed to the 'docker buildx build' command for multi-platform builds.
+type BuildxOptions struct {
+	// Platforms to build the image for, e.g., []string{"linux/amd64", "linux/arm64"}
+	Platforms []string
+
+	// Tags for the Docker image. Leave this empty with PushByDigest, and set Repository instead.
+	Tags []string
+
+	// Build args to pass the 'docker buildx build' command
+	BuildArgs []string
+
+	// Target build arg to pass to the 'docker buildx build' command
+	Target string
+
+	// Name of an existing buildx builder instance to use. If empty, a builder with the docker-container driver, which
+	// supports multi-platform builds, is created for the build and removed afterwards.
+	Builder string
+
+	// Whether or not to push the image to the registry of its tags.
+	Push bool
+
+	// Whether or not to push the image to Repository by digest only, without tags, as multi-arch publish pipelines do
+	// for each platform before merging the digests into a tagged image index with 'docker buildx imagetools create'.
+	PushByDigest bool
+
+	// Repository to push the image to with PushByDigest, e.g., "ghcr.io/gruntwork-io/test-image"
+	Repository string
+
+	// Secrets, SSH agent sockets or keys, and cache sources and destinations of the build, as in BuildOptions
+	Secrets   []string
+	SSH       []string
+	CacheFrom []string
+	CacheTo   []string
+
+	// Custom CLI options that will be passed as-is to the 'docker buildx build' command.
+	OtherOptions []string
+
+	// Additional environment variables to pass in when running docker buildx commands.
+	Env map[string]string
+
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+}
+
+// BuildxResult is the result of a 'docker buildx build'.
+type BuildxResult struct {
+	// Digest of the image, i.e., of the image index for multi-platform builds, if the build exported an image
+	Digest string
+
+	// Digests of the image manifest of each platform, keyed by the platforms of BuildxOptions, which are only known if
+	// the image was pushed
+	PlatformDigests map[string]string
+}
+
+// buildxIndexManifest is a manifest of an OCI image index or a Docker manifest list, as returned by
+// 'docker buildx imagetools inspect --raw'.
+type buildxIndexManifest struct {
+	Digest   string
+	Platform struct {
+		Architecture string
+		OS           string
+		Variant      string
+	}
+}
+
+// Buildx runs the 'docker buildx build' command at the given path with the given options and returns the digests of
+// the image. This will fail the test if there are any errors.
+func Buildx(t testing.TestingT, path string, options *BuildxOptions) *BuildxResult {
+	result, err := BuildxE(t, path, options)
+	require.NoError(t, err)
+	return result
+}
+
+// BuildxE runs the 'docker buildx build' command at the given path with the given options and returns the digests of
+// the image: the digest of the image index and, if it was pushed, those of the image of each platform, which it reads
+// back from the registry.
+func BuildxE(t testing.TestingT, path string, options *BuildxOptions) (*BuildxResult, error) {
+	if err := validateBuildxOptions(options); err != nil {
+		return nil, err
+	}
+
+	builder := options.Builder
+	if builder == "" {
+		builder = "terratest-" + strings.ToLower(random.UniqueId())
+		if err := createBuildxBuilderE(t, builder, options); err != nil {
+			return nil, err
+		}
+		defer removeBuildxBuilder(t, builder, options)
+	}
+
+	metadataFile, err := ioutil.TempFile("", "buildx-metadata-*.json")
+	if err != nil {
+		return nil, err
+	}
+	metadataFile.Close()
+	defer os.Remove(metadataFile.Name())
+
+	options.Logger.Logf(t, "Running 'docker buildx build' in %s for platforms %s", path, strings.Join(options.Platforms, ","))
+	command, args := runtimeCommand(options.Runtime, formatDockerBuildxArgs(path, builder, metadataFile.Name(), options)...)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  options.Logger,
+		Env:     options.Env,
+	}
+	if err := shell.RunCommandE(t, cmd); err != nil {
+		return nil, err
+	}
+
+	metadata, err := ioutil.ReadFile(metadataFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	digest, err := parseBuildxMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	result := &BuildxResult{Digest: digest, PlatformDigests: map[string]string{}}
+	if !options.Push && !options.PushByDigest {
+		return result, nil
+	}
+	if digest == "" {
+		return nil, errors.New("buildx metadata has no image digest")
+	}
+
+	repository := options.Repository
+	if !options.PushByDigest {
+		repository = imageRepository(options.Tags[0])
+	}
+	command, args = runtimeCommand(options.Runtime, "buildx", "imagetools", "inspect", "--raw", repository+"@"+digest)
+	inspectCmd := shell.Command{
+		Command: command,
+		Args:    args,
+		// inspect is a short-running command, don't print the output.
+		Logger: logger.Discard,
+		Env:    options.Env,
+	}
+	raw, err := shell.RunCommandAndGetStdOutE(t, inspectCmd)
+	if err != nil {
+		return nil, err
+	}
+	result.PlatformDigests, err = parseImageIndexPlatformDigests(raw, digest, options.Platforms)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createBuildxBuilderE creates a buildx builder instance with the docker-container driver.
+func createBuildxBuilderE(t testing.TestingT, builder string, options *BuildxOptions) error {
+	options.Logger.Logf(t, "Creating buildx builder %s", builder)
+	command, args := runtimeCommand(options.Runtime, "buildx", "create", "--name", builder, "--driver", "docker-container")
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  options.Logger,
+		Env:     options.Env,
+	}
+	return shell.RunCommandE(t, cmd)
+}
+
+// removeBuildxBuilder removes the given buildx builder instance, only logging errors as it runs during cleanup.
+func removeBuildxBuilder(t testing.TestingT, builder string, options *BuildxOptions) {
+	options.Logger.Logf(t, "Removing buildx builder %s", builder)
+	command, args := runtimeCommand(options.Runtime, "buildx", "rm", builder)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  options.Logger,
+		Env:     options.Env,
+	}
+	if err := shell.RunCommandE(t, cmd); err != nil {
+		options.Logger.Logf(t, "ERROR: error removing buildx builder %s: %s", builder, err)
+	}
+}
+
+// validateBuildxOptions returns an error if the given options are inconsistent.
+func validateBuildxOptions(options *BuildxOptions) error {
+	if len(options.Platforms) == 0 {
+		return errors.New("BuildxOptions.Platforms must not be empty")
+	}
+	if options.PushByDigest {
+		if options.Repository == "" {
+			return errors.New("BuildxOptions.Repository must be set with PushByDigest")
+		}
+		if len(options.Tags) > 0 {
+			return errors.New("BuildxOptions.Tags must be empty with PushByDigest, which pushes to Repository")
+		}
+	} else if options.Push && len(options.Tags) == 0 {
+		return errors.New("BuildxOptions.Tags must not be empty with Push")
+	}
+	return nil
+}
+
+// formatDockerBuildxArgs formats the arguments for the 'docker buildx build' command.
+func formatDockerBuildxArgs(path string, builder string, metadataFile string, options *BuildxOptions) []string {
+	args := []string{
+		"buildx",
+		"build",
+		"--builder", builder,
+		"--platform", strings.Join(options.Platforms, ","),
+		"--metadata-file", metadataFile,
+	}
+	if options.PushByDigest {
+		args = append(args, "--output", fmt.Sprintf("type=image,name=%s,push-by-digest=true,name-canonical=true,push=true", options.Repository))
+	} else if options.Push {
+		args = append(args, "--push")
+	}
+
+	buildOptions := &BuildOptions{
+		Tags:         options.Tags,
+		BuildArgs:    options.BuildArgs,
+		Target:       options.Target,
+		Secrets:      options.Secrets,
+		SSH:          options.SSH,
+		CacheFrom:    options.CacheFrom,
+		CacheTo:      options.CacheTo,
+		OtherOptions: options.OtherOptions,
+	}
+	return append(args, formatDockerBuildBaseArgs(path, buildOptions)...)
+}
+
+// parseBuildxMetadata returns the image digest in the given metadata file written by 'docker buildx build', which is
+// empty if the build did not export an image, e.g., a multi-platform build that only stays in the build cache.
+func parseBuildxMetadata(metadata []byte) (string, error) {
+	var parsed struct {
+		Digest string `json:"containerimage.digest"`
+	}
+	if err := json.Unmarshal(metadata, &parsed); err != nil {
+		return "", err
+	}
+	return parsed.Digest, nil
+}
+
+// parseImageIndexPlatformDigests returns the digests of the image manifest of each platform in the given raw image
+// index, skipping attestation manifests. If the raw manifest is not an index, it is the image of the only platform.
+// Platforms are reported with their variant (e.g., "linux/arm/v7") unless they were requested without it.
+func parseImageIndexPlatformDigests(raw string, digest string, platforms []string) (map[string]string, error) {
+	var index struct {
+		Manifests []buildxIndexManifest
+	}
+	if err := json.Unmarshal([]byte(raw), &index); err != nil {
+		return nil, err
+	}
+
+	platformDigests := map[string]string{}
+	if len(index.Manifests) == 0 {
+		if len(platforms) != 1 {
+			return nil, fmt.Errorf("image %s is not an image index for platforms %s", digest, strings.Join(platforms, ","))
+		}
+		platformDigests[platforms[0]] = digest
+		return platformDigests, nil
+	}
+
+	for _, manifest := range index.Manifests {
+		if manifest.Platform.OS == "" || manifest.Platform.OS == "unknown" {
+			continue
+		}
+		// Report the platforms as requested, e.g., "linux/arm64" rather than "linux/arm64/v8"
+		platform := manifest.Platform.OS + "/" + manifest.Platform.Architecture
+		if manifest.Platform.Variant != "" && !collections.ListContains(platforms, platform) {
+			platform += "/" + manifest.Platform.Variant
+		}
+		platformDigests[platform] = manifest.Digest
+	}
+	return platformDigests, nil
+}
+
+// imageRepository returns the repository of the given image tag, e.g., "localhost:5000/test-image" for
+// "localhost:5000/test-image:v1".
+func imageRepository(tag string) string {
+	lastColon := strings.LastIndex(tag, ":")
+	if lastColon > strings.LastIndex(tag, "/") {
+		return tag[:lastColon]
+	}
+	return tag
+}
@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBuildxOptions(t *testing.T) {
+	t.Parallel()
+
+	platforms := []string{"linux/amd64", "linux/arm64"}
+	assert.NoError(t, validateBuildxOptions(&BuildxOptions{Platforms: platforms}))
+	assert.NoError(t, validateBuildxOptions(&BuildxOptions{Platforms: platforms, Push: true, Tags: []string{"localhost:5000/test-image:v1"}}))
+	assert.NoError(t, validateBuildxOptions(&BuildxOptions{Platforms: platforms, PushByDigest: true, Repository: "localhost:5000/test-image"}))
+
+	assert.Error(t, validateBuildxOptions(&BuildxOptions{}))
+	assert.Error(t, validateBuildxOptions(&BuildxOptions{Platforms: platforms, Push: true}))
+	assert.Error(t, validateBuildxOptions(&BuildxOptions{Platforms: platforms, PushByDigest: true}))
+	assert.Error(t, validateBuildxOptions(&BuildxOptions{Platforms: platforms, PushByDigest: true, Repository: "localhost:5000/test-image", Tags: []string{"localhost:5000/test-image:v1"}}))
+}
+
+func TestFormatDockerBuildxArgs(t *testing.T) {
+	t.Parallel()
+
+	options := &BuildxOptions{
+		Platforms:    []string{"linux/amd64", "linux/arm64"},
+		PushByDigest: true,
+		Repository:   "localhost:5000/test-image",
+		BuildArgs:    []string{"text=Hello"},
+	}
+	assert.Equal(t, []string{
+		"buildx", "build",
+		"--builder", "terratest",
+		"--platform", "linux/amd64,linux/arm64",
+		"--metadata-file", "metadata.json",
+		"--output", "type=image,name=localhost:5000/test-image,push-by-digest=true,name-canonical=true,push=true",
+		"--build-arg", "text=Hello",
+		".",
+	}, formatDockerBuildxArgs(".", "terratest", "metadata.json", options))
+
+	options = &BuildxOptions{
+		Platforms: []string{"linux/amd64"},
+		Push:      true,
+		Tags:      []string{"localhost:5000/test-image:v1"},
+	}
+	assert.Equal(t, []string{
+		"buildx", "build",
+		"--builder", "terratest",
+		"--platform", "linux/amd64",
+		"--metadata-file", "metadata.json",
+		"--push",
+		"--tag", "localhost:5000/test-image:v1",
+		".",
+	}, formatDockerBuildxArgs(".", "terratest", "metadata.json", options))
+}
+
+func TestParseBuildxMetadata(t *testing.T) {
+	t.Parallel()
+
+	metadata := `{
+  "buildx.build.ref": "terratest/terratest0/abc",
+  "containerimage.descriptor": {"mediaType": "application/vnd.oci.image.index.v1+json", "digest": "sha256:1111", "size": 1609},
+  "containerimage.digest": "sha256:1111",
+  "image.name": "localhost:5000/test-image"
+}`
+	digest, err := parseBuildxMetadata([]byte(metadata))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:1111", digest)
+
+	digest, err = parseBuildxMetadata([]byte(`{"buildx.build.ref": "terratest/terratest0/abc"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "", digest)
+
+	_, err = parseBuildxMetadata([]byte(""))
+	assert.Error(t, err)
+}
+
+func TestParseImageIndexPlatformDigests(t *testing.T) {
+	t.Parallel()
+
+	index := `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:aaaa", "size": 1000, "platform": {"architecture": "amd64", "os": "linux"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:bbbb", "size": 1000, "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:cccc", "size": 1000, "platform": {"architecture": "arm", "os": "linux", "variant": "v7"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:dddd", "size": 566, "annotations": {"vnd.docker.reference.type": "attestation-manifest"}, "platform": {"architecture": "unknown", "os": "unknown"}}
+  ]
+}`
+	digests, err := parseImageIndexPlatformDigests(index, "sha256:1111", []string{"linux/amd64", "linux/arm64", "linux/arm/v7"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"linux/amd64":  "sha256:aaaa",
+		"linux/arm64":  "sha256:bbbb",
+		"linux/arm/v7": "sha256:cccc",
+	}, digests)
+
+	manifest := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json", "layers": []}`
+	digests, err = parseImageIndexPlatformDigests(manifest, "sha256:1111", []string{"linux/amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"linux/amd64": "sha256:1111"}, digests)
+
+	_, err = parseImageIndexPlatformDigests(manifest, "sha256:1111", []string{"linux/amd64", "linux/arm64"})
+	assert.Error(t, err)
+}
+
+func TestImageRepository(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "localhost:5000/test-image", imageRepository("localhost:5000/test-image:v1"))
+	assert.Equal(t, "localhost:5000/test-image", imageRepository("localhost:5000/test-image"))
+	assert.Equal(t, "gruntwork-io/test-image", imageRepository("gruntwork-io/test-image:v1"))
+}
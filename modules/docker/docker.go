@@ -1,2 +1,3 @@
-// Package docker allows to interact with Docker and docker compose resources.
+// Package docker allows to interact with Docker and docker compose resources, using the docker CLI or a compatible
+// container runtime such as podman or nerdctl (see ContainerRuntime).
 package docker
@@ -22,6 +22,17 @@ type Options struct {
 	// Set a logger that should be used. See the logger package for more info.
 	Logger      *logger.Logger
 	ProjectName string
+
+	// Profiles of the Compose file to enable, in addition to the services without a profile. You can find more
+	// information about profiles here https://docs.docker.com/compose/profiles/.
+	Profiles []string
+
+	// Container runtime to run 'compose' with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
+	// Directory to capture the state of the project in before 'docker compose down' runs, if the test failed, so that
+	// the logs and containers can still be inspected after the teardown. See CaptureComposeDiagnosticsE. (default none)
+	DiagnosticsDir string
 }
 
 // RunDockerCompose runs docker compose with the given arguments and options and return stdout/stderr.
@@ -40,21 +51,39 @@ func RunDockerComposeAndGetStdOut(t testing.TestingT, options *Options, args ...
 	return out
 }
 
+// RunDockerComposeAndGetStdOutE runs docker compose with the given arguments and options and returns only stdout.
+func RunDockerComposeAndGetStdOutE(t testing.TestingT, options *Options, args ...string) (string, error) {
+	return runDockerComposeE(t, true, options, args...)
+}
+
 // RunDockerComposeE runs docker compose with the given arguments and options and return stdout/stderr.
 func RunDockerComposeE(t testing.TestingT, options *Options, args ...string) (string, error) {
 	return runDockerComposeE(t, false, options, args...)
 }
 
+// DownWithVolumes runs 'docker compose down' for the project, removing its containers, networks and volumes, as well
+// as the containers of services no longer in the Compose file. This method fails the test if there are any errors.
+func DownWithVolumes(t testing.TestingT, options *Options) string {
+	out, err := DownWithVolumesE(t, options)
+	require.NoError(t, err)
+	return out
+}
+
+// DownWithVolumesE runs 'docker compose down' for the project, removing its containers, networks and volumes, as well
+// as the containers of services no longer in the Compose file, so that named volumes do not leak state into the next
+// test run. If Options.DiagnosticsDir is set and the test failed, this captures the state of the project there first.
+func DownWithVolumesE(t testing.TestingT, options *Options) (string, error) {
+	return runDockerComposeE(t, false, options, "down", "--volumes", "--remove-orphans")
+}
+
 func runDockerComposeE(t testing.TestingT, stdout bool, options *Options, args ...string) (string, error) {
 	var cmd shell.Command
 
-	projectName := options.ProjectName
-	if len(projectName) <= 0 {
-		projectName = strings.ToLower(t.Name())
-	}
+	projectName := composeProjectName(t, options)
 
-	dockerComposeVersionCmd := icmd.Command("docker", "compose", "version")
-	result := icmd.RunCmd(dockerComposeVersionCmd)
+	if len(args) > 0 && args[0] == "down" {
+		captureComposeDiagnosticsOnFailure(t, options)
+	}
 
 	if options.EnableBuildKit {
 		if options.EnvVars == nil {
@@ -65,10 +94,11 @@ func runDockerComposeE(t testing.TestingT, stdout bool, options *Options, args .
 		options.EnvVars["COMPOSE_DOCKER_CLI_BUILD"] = "1"
 	}
 
-	if result.ExitCode == 0 {
+	if isDockerComposeV2Available(options.Runtime) {
+		command, composeArgs := runtimeCommand(options.Runtime, append([]string{"compose"}, formatDockerComposeArgs(projectName, options.Profiles, args)...)...)
 		cmd = shell.Command{
-			Command:    "docker",
-			Args:       append([]string{"compose", "--project-name", generateValidDockerComposeProjectName(projectName)}, args...),
+			Command:    command,
+			Args:       composeArgs,
 			WorkingDir: options.WorkingDir,
 			Env:        options.EnvVars,
 			Logger:     options.Logger,
@@ -78,7 +108,7 @@ func runDockerComposeE(t testing.TestingT, stdout bool, options *Options, args .
 			Command: "docker-compose",
 			// We append --project-name to ensure containers from multiple different tests using Docker Compose don't end
 			// up in the same project and end up conflicting with each other.
-			Args:       append([]string{"--project-name", generateValidDockerComposeProjectName(projectName)}, args...),
+			Args:       formatDockerComposeArgs(projectName, options.Profiles, args),
 			WorkingDir: options.WorkingDir,
 			Env:        options.EnvVars,
 			Logger:     options.Logger,
@@ -86,12 +116,39 @@ func runDockerComposeE(t testing.TestingT, stdout bool, options *Options, args .
 	}
 
 	if stdout {
-		return shell.RunCommandAndGetStdOut(t, cmd), nil
+		return shell.RunCommandAndGetStdOutE(t, cmd)
 	}
 
 	return shell.RunCommandAndGetOutputE(t, cmd)
 }
 
+// composeProjectName returns the project name of the given options, which defaults to the name of the test.
+func composeProjectName(t testing.TestingT, options *Options) string {
+	if len(options.ProjectName) <= 0 {
+		return strings.ToLower(t.Name())
+	}
+	return options.ProjectName
+}
+
+// isDockerComposeV2Available returns whether the Docker Compose v2 plugin is installed, i.e., 'docker compose' works
+// with the given container runtime, rather than only the legacy 'docker-compose' binary.
+func isDockerComposeV2Available(runtime ContainerRuntime) bool {
+	command, args := runtimeCommand(runtime, "compose", "version")
+	dockerComposeVersionCmd := icmd.Command(command, args...)
+	result := icmd.RunCmd(dockerComposeVersionCmd)
+	return result.ExitCode == 0
+}
+
+// formatDockerComposeArgs formats the global arguments of docker compose, i.e., the project name and the profiles to
+// enable, followed by the given command arguments.
+func formatDockerComposeArgs(projectName string, profiles []string, args []string) []string {
+	composeArgs := []string{"--project-name", generateValidDockerComposeProjectName(projectName)}
+	for _, profile := range profiles {
+		composeArgs = append(composeArgs, "--profile", profile)
+	}
+	return append(composeArgs, args...)
+}
+
 // Note: docker-compose command doesn't like lower case or special characters, other than -.
 func generateValidDockerComposeProjectName(str string) string {
 	lower_str := strings.ToLower(str)
@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/require"
+)
+
+// invalidDiagnosticsFileNameChars are the characters that are replaced in the names of the diagnostics files.
+var invalidDiagnosticsFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// CaptureComposeDiagnostics writes the state of the Docker Compose project to a directory named after the project in
+// the given directory, and returns the path of that directory. This method fails the test if there are any errors.
+func CaptureComposeDiagnostics(t testing.TestingT, options *Options, dir string) string {
+	projectDir, err := CaptureComposeDiagnosticsE(t, options, dir)
+	require.NoError(t, err)
+	return projectDir
+}
+
+// CaptureComposeDiagnosticsE writes the state of the Docker Compose project to a directory named after the project in
+// the given directory, and returns the path of that directory:
+//
+//	ps.txt                      the output of 'docker compose ps --all'
+//	logs/<service>.log          the logs of each service, with timestamps
+//	inspect/<container>.json    the output of 'docker inspect' for each container
+//
+// This captures as much as it can, and returns the errors of the parts it could not capture. The logs and inspect
+// output require the Docker Compose v2 plugin, which lists the containers of the project. It is called automatically
+// before 'docker compose down' if Options.DiagnosticsDir is set and the test failed.
+func CaptureComposeDiagnosticsE(t testing.TestingT, options *Options, dir string) (string, error) {
+	projectName := composeProjectName(t, options)
+	projectDir := filepath.Join(dir, diagnosticsFileName(generateValidDockerComposeProjectName(projectName)))
+	options.Logger.Logf(t, "Capturing diagnostics of Docker Compose project %s in %s", projectName, projectDir)
+
+	for _, subDir := range []string{"logs", "inspect"} {
+		if err := os.MkdirAll(filepath.Join(projectDir, subDir), os.ModePerm); err != nil {
+			return projectDir, err
+		}
+	}
+
+	// The logs and inspect output can be large, and are written to files, don't print them.
+	captureOptions := *options
+	captureOptions.Logger = logger.Discard
+
+	var errorsOccurred = new(multierror.Error)
+
+	ps, err := RunDockerComposeAndGetStdOutE(t, &captureOptions, "ps", "--all")
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(projectDir, "ps.txt"), []byte(ps), 0644)
+	}
+	if err != nil {
+		errorsOccurred = multierror.Append(errorsOccurred, err)
+	}
+
+	states, err := GetComposeServicesE(t, &captureOptions)
+	if err != nil {
+		errorsOccurred = multierror.Append(errorsOccurred, err)
+		return projectDir, errorsOccurred.ErrorOrNil()
+	}
+
+	for _, service := range composeServiceNames(states) {
+		logs, err := RunDockerComposeE(t, &captureOptions, "logs", "--no-color", "--timestamps", service)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(projectDir, "logs", diagnosticsFileName(service)+".log"), []byte(logs), 0644)
+		}
+		if err != nil {
+			errorsOccurred = multierror.Append(errorsOccurred, err)
+		}
+	}
+
+	for _, state := range states {
+		command, args := runtimeCommand(options.Runtime, "inspect", state.ID)
+		cmd := shell.Command{
+			Command: command,
+			Args:    args,
+			Logger:  logger.Discard,
+		}
+		inspect, err := shell.RunCommandAndGetStdOutE(t, cmd)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(projectDir, "inspect", diagnosticsFileName(state.Name)+".json"), []byte(inspect), 0644)
+		}
+		if err != nil {
+			errorsOccurred = multierror.Append(errorsOccurred, err)
+		}
+	}
+
+	return projectDir, errorsOccurred.ErrorOrNil()
+}
+
+// captureComposeDiagnosticsOnFailure captures the diagnostics of the Docker Compose project in Options.DiagnosticsDir
+// if it is set and the test failed, logging the errors rather than returning them, so that the teardown still runs.
+func captureComposeDiagnosticsOnFailure(t testing.TestingT, options *Options) {
+	if options.DiagnosticsDir == "" || !testFailed(t) {
+		return
+	}
+	if _, err := CaptureComposeDiagnosticsE(t, options, options.DiagnosticsDir); err != nil {
+		options.Logger.Logf(t, "ERROR: error capturing diagnostics of the Docker Compose project: %s", err)
+	}
+}
+
+// testFailed returns whether the given test has failed, if it reports it like testing.T does.
+func testFailed(t testing.TestingT) bool {
+	failer, ok := t.(interface{ Failed() bool })
+	return ok && failer.Failed()
+}
+
+// composeServiceNames returns the names of the services of the given containers, in order and without duplicates.
+func composeServiceNames(states []ComposeServiceState) []string {
+	services := []string{}
+	seen := map[string]bool{}
+	for _, state := range states {
+		if !seen[state.Service] {
+			seen[state.Service] = true
+			services = append(services, state.Service)
+		}
+	}
+	return services
+}
+
+// diagnosticsFileName returns the given name with the characters that are not safe in file names replaced.
+func diagnosticsFileName(name string) string {
+	return invalidDiagnosticsFileNameChars.ReplaceAllString(name, "-")
+}
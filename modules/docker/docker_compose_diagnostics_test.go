@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failedTestingT reports a test as failed, without failing the actual test.
+type failedTestingT struct {
+	*testing.T
+}
+
+func (t failedTestingT) Failed() bool {
+	return true
+}
+
+func TestDockerComposeCapturesDiagnosticsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	diagnosticsDir := t.TempDir()
+	options := &Options{
+		WorkingDir:     "../../test/fixtures/docker-compose-with-custom-project-name",
+		ProjectName:    "diagnostics",
+		DiagnosticsDir: diagnosticsDir,
+	}
+
+	RunDockerCompose(t, options, "up", "-d")
+	DownWithVolumes(failedTestingT{t}, options)
+
+	ps, err := ioutil.ReadFile(filepath.Join(diagnosticsDir, "diagnostics", "ps.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(ps), "test-docker-image")
+	assert.FileExists(t, filepath.Join(diagnosticsDir, "diagnostics", "logs", "test-docker-image.log"))
+
+	inspectFiles, err := filepath.Glob(filepath.Join(diagnosticsDir, "diagnostics", "inspect", "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, inspectFiles, 1)
+}
+
+func TestDockerComposeSkipsDiagnosticsOnSuccess(t *testing.T) {
+	t.Parallel()
+
+	diagnosticsDir := t.TempDir()
+	options := &Options{
+		WorkingDir:     "../../test/fixtures/docker-compose-with-custom-project-name",
+		ProjectName:    "nodiagnostics",
+		DiagnosticsDir: diagnosticsDir,
+	}
+
+	RunDockerCompose(t, options, "up", "-d")
+	DownWithVolumes(t, options)
+
+	assert.NoDirExists(t, filepath.Join(diagnosticsDir, "nodiagnostics"))
+}
+
+func TestTestFailed(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, testFailed(t))
+	assert.True(t, testFailed(failedTestingT{t}))
+}
+
+func TestComposeServiceNames(t *testing.T) {
+	t.Parallel()
+
+	states := []ComposeServiceState{
+		{Name: "project-web-1", Service: "web"},
+		{Name: "project-db-1", Service: "db"},
+		{Name: "project-web-2", Service: "web"},
+	}
+	assert.Equal(t, []string{"web", "db"}, composeServiceNames(states))
+	assert.Equal(t, []string{}, composeServiceNames(nil))
+}
+
+func TestDiagnosticsFileName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "project-web-1", diagnosticsFileName("project-web-1"))
+	assert.Equal(t, "project_web.1", diagnosticsFileName("project_web.1"))
+	assert.Equal(t, "-project-web-1", diagnosticsFileName("/project/web 1"))
+}
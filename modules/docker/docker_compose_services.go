@@ -0,0 +1,235 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// ComposeServiceState is the state of a container of a Docker Compose service, as returned by
+// 'docker compose ps --format json'
+type ComposeServiceState struct {
+	// ID of the container
+	ID string
+
+	// Name of the container
+	Name string
+
+	// Name of the service of the container
+	Service string
+
+	// Name of the Compose project of the container
+	Project string
+
+	// Image of the container
+	Image string
+
+	// State of the container, e.g., "running" or "exited"
+	State string
+
+	// Health check status of the container: "starting", "healthy" or "unhealthy", or empty if it has no health check
+	Health string
+
+	// Exit code of the container, if it exited
+	ExitCode int
+
+	// Human readable status of the container, e.g., "Up 2 minutes (healthy)"
+	Status string
+
+	// Ports published by the container
+	Publishers []ComposePublisher
+}
+
+// ComposePublisher is a port published by a container of a Docker Compose service
+type ComposePublisher struct {
+	URL           string
+	TargetPort    uint16
+	PublishedPort uint16
+	Protocol      string
+}
+
+const (
+	composeStateRunning = "running"
+	composeHealthy      = "healthy"
+)
+
+// GetComposeServices runs 'docker compose ps --all --format json' and returns the state of the containers of the
+// project, optionally only of the given services. This method fails the test if there are any errors.
+func GetComposeServices(t testing.TestingT, options *Options, services ...string) []ComposeServiceState {
+	states, err := GetComposeServicesE(t, options, services...)
+	require.NoError(t, err)
+	return states
+}
+
+// GetComposeServicesE runs 'docker compose ps --all --format json' and returns the state of the containers of the
+// project, optionally only of the given services, including the stopped ones. This requires the Docker Compose v2
+// plugin, as the legacy docker-compose binary has no JSON output.
+func GetComposeServicesE(t testing.TestingT, options *Options, services ...string) ([]ComposeServiceState, error) {
+	if !isDockerComposeV2Available(options.Runtime) {
+		return nil, errors.New("'docker compose ps --format json' requires the Docker Compose v2 plugin")
+	}
+
+	// ps is a short-running command that the waiters call repeatedly, don't print the output.
+	psOptions := *options
+	psOptions.Logger = logger.Discard
+
+	args := append([]string{"ps", "--all", "--format", "json"}, services...)
+	out, err := RunDockerComposeAndGetStdOutE(t, &psOptions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseComposePsOutput(out)
+}
+
+// GetComposeService returns the state of the containers of the given Docker Compose service. This method fails the
+// test if there are any errors.
+func GetComposeService(t testing.TestingT, options *Options, service string) []ComposeServiceState {
+	states, err := GetComposeServiceE(t, options, service)
+	require.NoError(t, err)
+	return states
+}
+
+// GetComposeServiceE returns the state of the containers of the given Docker Compose service, i.e., one for each
+// replica, or an error if it has no container.
+func GetComposeServiceE(t testing.TestingT, options *Options, service string) ([]ComposeServiceState, error) {
+	states, err := GetComposeServicesE(t, options, service)
+	if err != nil {
+		return nil, err
+	}
+	return filterComposeService(states, service)
+}
+
+// WaitUntilComposeServiceHealthy waits until all the containers of the given Docker Compose service pass their health
+// check, retrying the check for the specified amount of times, sleeping for the provided duration between each try.
+// This method fails the test if there are any errors.
+func WaitUntilComposeServiceHealthy(t testing.TestingT, options *Options, service string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilComposeServiceHealthyE(t, options, service, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilComposeServiceHealthyE waits until all the containers of the given Docker Compose service pass their
+// health check, retrying the check for the specified amount of times, sleeping for the provided duration between each
+// try. This fails right away if the service has no health check or one of its containers exits; use
+// WaitUntilComposeServiceRunningE for services without a health check.
+func WaitUntilComposeServiceHealthyE(t testing.TestingT, options *Options, service string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	return waitUntilComposeServiceE(t, options, service, composeHealthy, checkComposeServiceHealthy, maxRetries, sleepBetweenRetries)
+}
+
+// WaitUntilComposeServiceRunning waits until all the containers of the given Docker Compose service are running,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This
+// method fails the test if there are any errors.
+func WaitUntilComposeServiceRunning(t testing.TestingT, options *Options, service string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilComposeServiceRunningE(t, options, service, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilComposeServiceRunningE waits until all the containers of the given Docker Compose service are running,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This
+// fails right away if one of its containers exits.
+func WaitUntilComposeServiceRunningE(t testing.TestingT, options *Options, service string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	return waitUntilComposeServiceE(t, options, service, composeStateRunning, checkComposeServiceRunning, maxRetries, sleepBetweenRetries)
+}
+
+// waitUntilComposeServiceE waits until the given check holds for the containers of the given Docker Compose service.
+func waitUntilComposeServiceE(t testing.TestingT, options *Options, service string, description string, check func([]ComposeServiceState, string) error, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Docker Compose service %s to be %s", service, description),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			states, err := GetComposeServiceE(t, options, service)
+			if err != nil {
+				return "", err
+			}
+			if err := check(states, service); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Docker Compose service %s is %s", service, description), nil
+		},
+	)
+	options.Logger.Logf(t, msg)
+	return err
+}
+
+// parseComposePsOutput parses the output of 'docker compose ps --format json', which is a JSON array before Docker
+// Compose v2.21 and one JSON object per line since.
+func parseComposePsOutput(out string) ([]ComposeServiceState, error) {
+	out = strings.TrimSpace(out)
+	states := []ComposeServiceState{}
+	if out == "" {
+		return states, nil
+	}
+
+	if strings.HasPrefix(out, "[") {
+		if err := json.Unmarshal([]byte(out), &states); err != nil {
+			return nil, err
+		}
+		return states, nil
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var state ComposeServiceState
+		if err := json.Unmarshal([]byte(line), &state); err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// filterComposeService returns the states of the containers of the given service, or an error if it has none.
+func filterComposeService(states []ComposeServiceState, service string) ([]ComposeServiceState, error) {
+	serviceStates := []ComposeServiceState{}
+	for _, state := range states {
+		if state.Service == service {
+			serviceStates = append(serviceStates, state)
+		}
+	}
+	if len(serviceStates) == 0 {
+		return nil, fmt.Errorf("Docker Compose service %s has no container", service)
+	}
+	return serviceStates, nil
+}
+
+// checkComposeServiceRunning returns an error if any of the given containers of the service is not running, which is
+// a retry.FatalError if it exited.
+func checkComposeServiceRunning(states []ComposeServiceState, service string) error {
+	for _, state := range states {
+		if state.State == "exited" || state.State == "dead" {
+			return retry.FatalError{Underlying: fmt.Errorf("container %s of Docker Compose service %s %s with exit code %d", state.Name, service, state.State, state.ExitCode)}
+		}
+		if state.State != composeStateRunning {
+			return fmt.Errorf("container %s of Docker Compose service %s is %s", state.Name, service, state.State)
+		}
+	}
+	return nil
+}
+
+// checkComposeServiceHealthy returns an error if any of the given containers of the service is not healthy, which is
+// a retry.FatalError if it has no health check or exited.
+func checkComposeServiceHealthy(states []ComposeServiceState, service string) error {
+	if err := checkComposeServiceRunning(states, service); err != nil {
+		return err
+	}
+	for _, state := range states {
+		if state.Health == "" {
+			return retry.FatalError{Underlying: fmt.Errorf("container %s of Docker Compose service %s has no health check", state.Name, service)}
+		}
+		if state.Health != composeHealthy {
+			return fmt.Errorf("container %s of Docker Compose service %s is %s", state.Name, service, state.Health)
+		}
+	}
+	return nil
+}
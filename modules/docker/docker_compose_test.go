@@ -3,6 +3,8 @@ package docker
 import (
 	"testing"
 
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,3 +64,76 @@ func TestDockerComposeWithCustomProjectName(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatDockerComposeArgs(t *testing.T) {
+	t.Parallel()
+
+	args := formatDockerComposeArgs("TestDockerCompose/Profiles", []string{"debug", "tools"}, []string{"up", "-d"})
+	assert.Equal(t, []string{"--project-name", "testdockercompose-profiles", "--profile", "debug", "--profile", "tools", "up", "-d"}, args)
+
+	args = formatDockerComposeArgs("project", nil, []string{"down", "--volumes", "--remove-orphans"})
+	assert.Equal(t, []string{"--project-name", "project", "down", "--volumes", "--remove-orphans"}, args)
+}
+
+func TestParseComposePsOutput(t *testing.T) {
+	t.Parallel()
+
+	// Docker Compose v2.21 and later print one JSON object per line
+	lines := `{"ID":"1f2e","Name":"project-web-1","Service":"web","Project":"project","Image":"nginx:1.25","State":"running","Health":"healthy","ExitCode":0,"Status":"Up 2 minutes (healthy)","Publishers":[{"URL":"0.0.0.0","TargetPort":80,"PublishedPort":8080,"Protocol":"tcp"}]}
+{"ID":"3d4c","Name":"project-migrate-1","Service":"migrate","Project":"project","Image":"migrate:latest","State":"exited","Health":"","ExitCode":1,"Status":"Exited (1) 1 minute ago","Publishers":null}
+`
+	states, err := parseComposePsOutput(lines)
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, "web", states[0].Service)
+	assert.Equal(t, "healthy", states[0].Health)
+	assert.Equal(t, []ComposePublisher{{URL: "0.0.0.0", TargetPort: 80, PublishedPort: 8080, Protocol: "tcp"}}, states[0].Publishers)
+	assert.Equal(t, "exited", states[1].State)
+	assert.Equal(t, 1, states[1].ExitCode)
+
+	// Earlier versions print a JSON array
+	array, err := parseComposePsOutput(`[{"ID":"1f2e","Name":"project-web-1","Service":"web","State":"running"}]`)
+	require.NoError(t, err)
+	require.Len(t, array, 1)
+	assert.Equal(t, "project-web-1", array[0].Name)
+
+	empty, err := parseComposePsOutput("\n")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = parseComposePsOutput("NAME IMAGE COMMAND")
+	assert.Error(t, err)
+}
+
+func TestCheckComposeServiceState(t *testing.T) {
+	t.Parallel()
+
+	states := []ComposeServiceState{
+		{Name: "project-web-1", Service: "web", State: "running", Health: "healthy"},
+		{Name: "project-web-2", Service: "web", State: "running", Health: "starting"},
+		{Name: "project-db-1", Service: "db", State: "running"},
+		{Name: "project-migrate-1", Service: "migrate", State: "exited", ExitCode: 1},
+	}
+
+	web, err := filterComposeService(states, "web")
+	require.NoError(t, err)
+	assert.Len(t, web, 2)
+	_, err = filterComposeService(states, "cache")
+	assert.Error(t, err)
+
+	assert.NoError(t, checkComposeServiceRunning(web, "web"))
+	assert.NoError(t, checkComposeServiceHealthy(web[:1], "web"))
+
+	err = checkComposeServiceHealthy(web, "web")
+	require.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+
+	err = checkComposeServiceHealthy(states[2:3], "db")
+	require.Error(t, err)
+	assert.IsType(t, retry.FatalError{}, err)
+
+	err = checkComposeServiceRunning(states[3:], "migrate")
+	require.Error(t, err)
+	assert.IsType(t, retry.FatalError{}, err)
+}
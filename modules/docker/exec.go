@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// ExecOptions defines options that can be passed to the 'docker exec' command.
+type ExecOptions struct {
+	// Set environment variables
+	EnvironmentVariables []string
+
+	// If set to true, pass the --privileged flag to 'docker exec' to give extended privileges to the command
+	Privileged bool
+
+	// Username or UID
+	User string
+
+	// Working directory inside the container
+	WorkingDir string
+
+	// Custom CLI options that will be passed as-is to the 'docker exec' command.
+	OtherOptions []string
+
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+}
+
+// ExecResult is the result of a command run with 'docker exec'.
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Exec runs the 'docker exec' command to run the given command in the container with the given ID and returns its
+// stdout, stderr and exit code. This method fails the test if there are any errors, including a non-zero exit code.
+func Exec(t testing.TestingT, id string, command []string, options *ExecOptions) *ExecResult {
+	result, err := ExecE(t, id, command, options)
+	require.NoError(t, err)
+	return result
+}
+
+// ExecE runs the 'docker exec' command to run the given command in the container with the given ID and returns its
+// stdout, stderr and exit code. This returns an error if the command exits with a non-zero exit code, along with the
+// result, so that tests expecting a command to fail can check its exit code and stderr.
+func ExecE(t testing.TestingT, id string, command []string, options *ExecOptions) (*ExecResult, error) {
+	options.Logger.Logf(t, "Running 'docker exec' on container '%s' with command %s", id, command)
+
+	args, err := formatDockerExecArgs(id, command, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	binary, args := runtimeCommand(options.Runtime, args...)
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, exited := err.(*exec.ExitError)
+		if !exited {
+			return nil, err
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	result := &ExecResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: exitCode}
+	options.Logger.Logf(t, "Command %s in container '%s' exited with code %d:\n%s%s", command, id, exitCode, result.Stdout, result.Stderr)
+	if exitCode != 0 {
+		return result, fmt.Errorf("command %s in container %s exited with code %d: %s", command, id, exitCode, result.Stderr)
+	}
+	return result, nil
+}
+
+// formatDockerExecArgs formats the arguments for the 'docker exec' command.
+func formatDockerExecArgs(id string, command []string, options *ExecOptions) ([]string, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no command to run in container %s", id)
+	}
+
+	args := []string{"exec"}
+
+	for _, envVar := range options.EnvironmentVariables {
+		args = append(args, "--env", envVar)
+	}
+
+	if options.Privileged {
+		args = append(args, "--privileged")
+	}
+
+	if options.User != "" {
+		args = append(args, "--user", options.User)
+	}
+
+	if options.WorkingDir != "" {
+		args = append(args, "--workdir", options.WorkingDir)
+	}
+
+	args = append(args, options.OtherOptions...)
+
+	args = append(args, id)
+	args = append(args, command...)
+
+	return args, nil
+}
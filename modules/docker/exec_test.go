@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	runOptions := &RunOptions{
+		Command:    []string{"-c", "sleep 60"},
+		Entrypoint: "sh",
+		Detach:     true,
+	}
+	id := strings.TrimSpace(Run(t, "alpine:3.7", runOptions))
+	defer removeContainer(t, id)
+
+	options := &ExecOptions{
+		EnvironmentVariables: []string{"NAME=World"},
+		User:                 "nobody",
+		WorkingDir:           "/tmp",
+	}
+	result := Exec(t, id, []string{"sh", "-c", `echo "Hello, $NAME!"; whoami; pwd; echo oops >&2`}, options)
+	require.Equal(t, "Hello, World!\nnobody\n/tmp\n", result.Stdout)
+	require.Equal(t, "oops\n", result.Stderr)
+	require.Equal(t, 0, result.ExitCode)
+
+	result, err := ExecE(t, id, []string{"sh", "-c", "exit 3"}, &ExecOptions{})
+	require.Error(t, err)
+	require.Equal(t, 3, result.ExitCode)
+}
+
+func TestFormatDockerExecArgs(t *testing.T) {
+	t.Parallel()
+
+	options := &ExecOptions{
+		EnvironmentVariables: []string{"NAME=World"},
+		Privileged:           true,
+		User:                 "nobody",
+		WorkingDir:           "/tmp",
+		OtherOptions:         []string{"--interactive"},
+	}
+	args, err := formatDockerExecArgs("abc123", []string{"ls", "-l"}, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "--env", "NAME=World", "--privileged", "--user", "nobody", "--workdir", "/tmp", "--interactive", "abc123", "ls", "-l"}, args)
+
+	_, err = formatDockerExecArgs("abc123", nil, &ExecOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// healthCheckRetryInterval is how long the health waiters sleep between checks of the container.
+const healthCheckRetryInterval = time.Second
+
+// HealthProbe is a check of the service of a container from the test, on the host port that a container port is
+// published to (e.g., with "--publish" in RunOptions.OtherOptions).
+type HealthProbe struct {
+	// Container port to probe, which must be published
+	Port uint16
+
+	// Path to send an HTTP GET request to, which must return a status code below 400. If empty, the probe only checks
+	// that a TCP connection can be opened.
+	HTTPPath string
+}
+
+// RunAndWaitHealthy runs the 'docker run' command on the given image with the given options in the background, and
+// waits until the container is healthy, for at most the given timeout. It returns the ID of the container. This method
+// fails the test if there are any errors.
+func RunAndWaitHealthy(t testing.TestingT, image string, options *RunOptions, timeout time.Duration) string {
+	id, err := RunAndWaitHealthyE(t, image, options, timeout)
+	require.NoError(t, err)
+	return id
+}
+
+// RunAndWaitHealthyE runs the 'docker run' command on the given image with the given options in the background, and
+// waits until the container is healthy, for at most the given timeout: until its HEALTHCHECK passes, or the
+// HealthProbe of the options succeeds if set. It returns the ID of the container, also with an error once it started,
+// so that the test can get its logs and stop it.
+func RunAndWaitHealthyE(t testing.TestingT, image string, options *RunOptions, timeout time.Duration) (string, error) {
+	runOptions := *options
+	runOptions.Detach = true
+
+	out, err := RunAndGetIDE(t, image, &runOptions)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(out)
+
+	return id, waitUntilContainerHealthyE(t, options.Runtime, id, options.HealthProbe, timeout, options.Logger)
+}
+
+// WaitUntilContainerHealthy waits until the container with the given ID is healthy, for at most the given timeout.
+// This method fails the test if there are any errors.
+func WaitUntilContainerHealthy(t testing.TestingT, id string, probe *HealthProbe, timeout time.Duration, logger *logger.Logger) {
+	require.NoError(t, WaitUntilContainerHealthyE(t, id, probe, timeout, logger))
+}
+
+// WaitUntilContainerHealthyE waits until the container with the given ID is healthy, for at most the given timeout:
+// until its HEALTHCHECK passes, or the given probe succeeds if it is not nil. This fails right away if the container
+// exits, or if it has no HEALTHCHECK and there is no probe.
+func WaitUntilContainerHealthyE(t testing.TestingT, id string, probe *HealthProbe, timeout time.Duration, logger *logger.Logger) error {
+	return waitUntilContainerHealthyE(t, nil, id, probe, timeout, logger)
+}
+
+// waitUntilContainerHealthyE waits until the container with the given ID of the given container runtime is healthy,
+// for at most the given timeout.
+func waitUntilContainerHealthyE(t testing.TestingT, runtime ContainerRuntime, id string, probe *HealthProbe, timeout time.Duration, logger *logger.Logger) error {
+	maxRetries := int(timeout/healthCheckRetryInterval) + 1
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for container %s to be healthy", id),
+		maxRetries,
+		healthCheckRetryInterval,
+		func() (string, error) {
+			container, err := inspectContainerE(t, runtime, id)
+			if err != nil {
+				return "", err
+			}
+			if err := checkContainerHealth(container, probe == nil); err != nil {
+				return "", err
+			}
+			if probe != nil {
+				if err := probeContainerE(GetDockerHost(), container, *probe); err != nil {
+					return "", err
+				}
+			}
+			return fmt.Sprintf("Container %s is healthy", id), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// inspectContainerE runs the 'docker container inspect' command of the given container runtime for the container with
+// the given ID.
+func inspectContainerE(t testing.TestingT, runtime ContainerRuntime, id string) (inspectOutput, error) {
+	command, args := runtimeCommand(runtime, "container", "inspect", id)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		// inspect is a short-running command, don't print the output.
+		Logger: logger.Discard,
+	}
+
+	out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return inspectOutput{}, err
+	}
+
+	var containers []inspectOutput
+	if err := json.Unmarshal([]byte(out), &containers); err != nil {
+		return inspectOutput{}, err
+	}
+	if len(containers) == 0 {
+		return inspectOutput{}, fmt.Errorf("no container found with ID %s", id)
+	}
+	return containers[0], nil
+}
+
+// checkContainerHealth returns an error if the given container is not running, which is a retry.FatalError if it
+// exited, or if its HEALTHCHECK has not passed, when requireHealthCheck is set.
+func checkContainerHealth(container inspectOutput, requireHealthCheck bool) error {
+	name := strings.TrimLeft(container.Name, "/")
+	switch container.State.Status {
+	case "exited", "dead":
+		return retry.FatalError{Underlying: fmt.Errorf("container %s %s with exit code %d", name, container.State.Status, container.State.ExitCode)}
+	case "running":
+	default:
+		return fmt.Errorf("container %s is %s", name, container.State.Status)
+	}
+
+	if !requireHealthCheck {
+		return nil
+	}
+	switch container.State.Health.Status {
+	case "healthy":
+		return nil
+	case "":
+		return retry.FatalError{Underlying: fmt.Errorf("container %s has no HEALTHCHECK, set a HealthProbe to wait for it", name)}
+	default:
+		return fmt.Errorf("container %s is %s, with %d failing health checks", name, container.State.Health.Status, container.State.Health.FailingStreak)
+	}
+}
+
+// probeContainerE runs the given probe on the host port that its container port is published to on the given host.
+func probeContainerE(host string, container inspectOutput, probe HealthProbe) error {
+	hostPort := ""
+	for _, binding := range container.NetworkSettings.Ports[fmt.Sprintf("%d/tcp", probe.Port)] {
+		if binding.HostPort != "" {
+			hostPort = binding.HostPort
+			break
+		}
+	}
+	if hostPort == "" {
+		return retry.FatalError{Underlying: fmt.Errorf("port %d of container %s is not published", probe.Port, strings.TrimLeft(container.Name, "/"))}
+	}
+	address := net.JoinHostPort(host, hostPort)
+
+	if probe.HTTPPath == "" {
+		conn, err := net.DialTimeout("tcp", address, healthCheckRetryInterval)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	client := http.Client{Timeout: healthCheckRetryInterval}
+	url := "http://" + address + "/" + strings.TrimPrefix(probe.HTTPPath, "/")
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("GET %s returned status code %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAndWaitHealthyWithHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Name: "health-test-" + random.UniqueId(),
+		OtherOptions: []string{
+			"--health-cmd=wget -q -O /dev/null http://localhost || exit 1",
+			"--health-interval=1s",
+		},
+	}
+
+	id := RunAndWaitHealthy(t, dockerInspectTestImage, options, time.Minute)
+	defer removeContainer(t, id)
+
+	require.Equal(t, "healthy", Inspect(t, id).Health.Status)
+}
+
+func TestRunAndWaitHealthyWithHTTPProbe(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Name:         "health-test-" + random.UniqueId(),
+		OtherOptions: []string{"--publish", "80"},
+		HealthProbe:  &HealthProbe{Port: 80, HTTPPath: "/"},
+	}
+
+	id := RunAndWaitHealthy(t, dockerInspectTestImage, options, time.Minute)
+	defer removeContainer(t, id)
+}
+
+func TestRunAndWaitHealthyFailsWithoutHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	id, err := RunAndWaitHealthyE(t, dockerInspectTestImage, &RunOptions{}, time.Minute)
+	defer removeContainer(t, id)
+
+	require.Error(t, err)
+}
+
+func TestCheckContainerHealth(t *testing.T) {
+	t.Parallel()
+
+	container := inspectOutput{Name: "/healthy"}
+	container.State.Status = "running"
+	container.State.Health.Status = "healthy"
+	assert.NoError(t, checkContainerHealth(container, true))
+
+	container.State.Health.Status = "starting"
+	err := checkContainerHealth(container, true)
+	require.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+	assert.NoError(t, checkContainerHealth(container, false))
+
+	container.State.Health.Status = ""
+	assert.IsType(t, retry.FatalError{}, checkContainerHealth(container, true))
+
+	container.State.Status = "created"
+	_, isFatal = checkContainerHealth(container, false).(retry.FatalError)
+	assert.False(t, isFatal)
+
+	container.State.Status = "exited"
+	container.State.ExitCode = 1
+	assert.IsType(t, retry.FatalError{}, checkContainerHealth(container, false))
+}
+
+func TestProbeContainer(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host, port, err := net.SplitHostPort(serverURL.Host)
+	require.NoError(t, err)
+
+	container := inspectOutput{Name: "/probed"}
+	container.NetworkSettings.Ports = map[string][]struct {
+		HostIp   string
+		HostPort string
+	}{"8080/tcp": {{HostIp: "0.0.0.0", HostPort: port}}}
+
+	assert.NoError(t, probeContainerE(host, container, HealthProbe{Port: 8080}))
+	assert.NoError(t, probeContainerE(host, container, HealthProbe{Port: 8080, HTTPPath: "health"}))
+	assert.Error(t, probeContainerE(host, container, HealthProbe{Port: 8080, HTTPPath: "/"}))
+	assert.IsType(t, retry.FatalError{}, probeContainerE(host, container, HealthProbe{Port: 9090}))
+}
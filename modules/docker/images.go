@@ -58,9 +58,10 @@ func DeleteImage(t testing.TestingT, img string, logger *logger.Logger) {
 
 // DeleteImageE removes a docker image using the Docker CLI.
 func DeleteImageE(t testing.TestingT, img string, logger *logger.Logger) error {
+	command, args := runtimeCommand(nil, "rmi", img)
 	cmd := shell.Command{
-		Command: "docker",
-		Args:    []string{"rmi", img},
+		Command: command,
+		Args:    args,
 		Logger:  logger,
 	}
 	return shell.RunCommandE(t, cmd)
@@ -75,9 +76,10 @@ func ListImages(t testing.TestingT, logger *logger.Logger) []Image {
 
 // ListImagesE calls docker images using the Docker CLI to list the available images on the local docker daemon.
 func ListImagesE(t testing.TestingT, logger *logger.Logger) ([]Image, error) {
+	command, args := runtimeCommand(nil, "images", "--format", "{{ json . }}")
 	cmd := shell.Command{
-		Command: "docker",
-		Args:    []string{"images", "--format", "{{ json . }}"},
+		Command: command,
+		Args:    args,
 		Logger:  logger,
 	}
 	out, err := shell.RunCommandAndGetOutputE(t, cmd)
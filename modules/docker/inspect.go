@@ -3,6 +3,7 @@ package docker
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -45,6 +46,27 @@ type ContainerInspect struct {
 
 	// Health check
 	Health HealthCheck
+
+	// Name of the image the container was created from, e.g., "nginx:1.17-alpine"
+	Image string
+
+	// Environment variables of the container, including those set by its image
+	Env map[string]string
+
+	// Labels of the container, including those set by its image
+	Labels map[string]string
+
+	// Mounts of the container: bind mounts, volumes and tmpfs mounts
+	Mounts []Mount
+
+	// Restart policy of the container
+	RestartPolicy RestartPolicy
+
+	// Configuration of the health check of the container, or nil if it has none
+	HealthCheckConfig *HealthCheckConfig
+
+	// Networks the container is attached to
+	Networks []ContainerNetwork
 }
 
 // Port represents a single port mapping exported by the container
@@ -72,6 +94,69 @@ type HealthCheck struct {
 	Log []HealthLog
 }
 
+// Mount represents a single mount of the container
+type Mount struct {
+	// Type of the mount: "bind", "volume" or "tmpfs"
+	Type string
+
+	// Name of the volume, for volume mounts
+	Name string
+
+	// Path on the Docker host, for bind mounts and volume mounts
+	Source string
+
+	// Path in the container
+	Destination string
+
+	// Whether the mount is read-only
+	ReadOnly bool
+}
+
+// RestartPolicy represents the restart policy of the container
+type RestartPolicy struct {
+	// Name of the policy: "no", "always", "unless-stopped" or "on-failure"
+	Name string
+
+	// Maximum number of restarts with the "on-failure" policy
+	MaximumRetryCount int
+}
+
+// HealthCheckConfig represents the configuration of the health check of the container
+type HealthCheckConfig struct {
+	// Command of the health check, e.g., ["CMD-SHELL", "curl -f http://localhost/"]
+	Test []string
+
+	// Time between health checks
+	Interval time.Duration
+
+	// Time after which a health check is considered failed
+	Timeout time.Duration
+
+	// Time during which failing health checks do not count, after the container starts
+	StartPeriod time.Duration
+
+	// Number of consecutive failing health checks after which the container is unhealthy
+	Retries int
+}
+
+// ContainerNetwork represents a network the container is attached to
+type ContainerNetwork struct {
+	// Name of the network
+	Name string
+
+	// ID of the network
+	NetworkID string
+
+	// IPv4 address of the container on the network
+	IPAddress string
+
+	// Gateway of the network
+	Gateway string
+
+	// Aliases of the container on the network, by which other containers can reach it
+	Aliases []string
+}
+
 // HealthLog represents the output of a single Health check of the container
 type HealthLog struct {
 	// Start time of health check
@@ -105,10 +190,34 @@ type inspectOutput struct {
 			HostIp   string
 			HostPort string
 		}
+		Networks map[string]networkEndpointOutput
 	}
 	HostConfig struct {
-		Binds []string
+		Binds         []string
+		RestartPolicy RestartPolicy
+	}
+	Config struct {
+		Image       string
+		Env         []string
+		Labels      map[string]string
+		Healthcheck *HealthCheckConfig
 	}
+	Mounts []struct {
+		Type        string
+		Name        string
+		Source      string
+		Destination string
+		RW          bool
+	}
+}
+
+// networkEndpointOutput defines the options of a network the container is attached to, as returned by
+// 'docker inspect', in JSON format.
+type networkEndpointOutput struct {
+	NetworkID string
+	IPAddress string
+	Gateway   string
+	Aliases   []string
 }
 
 // Inspect runs the 'docker inspect {container id}' command and returns a ContainerInspect
@@ -123,9 +232,10 @@ func Inspect(t *testing.T, id string) *ContainerInspect {
 // InspectE runs the 'docker inspect {container id}' command and returns a ContainerInspect
 // struct, converted from the output JSON, along with any errors
 func InspectE(t *testing.T, id string) (*ContainerInspect, error) {
+	command, args := runtimeCommand(nil, "container", "inspect", id)
 	cmd := shell.Command{
-		Command: "docker",
-		Args:    []string{"container", "inspect", id},
+		Command: command,
+		Args:    args,
 		// inspect is a short-running command, don't print the output.
 		Logger: logger.Discard,
 	}
@@ -181,6 +291,16 @@ func transformContainer(t *testing.T, container inspectOutput) (*ContainerInspec
 			FailingStreak: container.State.Health.FailingStreak,
 			Log:           container.State.Health.Log,
 		},
+		Image:             container.Config.Image,
+		Env:               transformContainerEnv(container),
+		Labels:            container.Config.Labels,
+		Mounts:            transformContainerMounts(container),
+		RestartPolicy:     container.HostConfig.RestartPolicy,
+		HealthCheckConfig: container.Config.Healthcheck,
+		Networks:          transformContainerNetworks(container),
+	}
+	if inspect.Labels == nil {
+		inspect.Labels = map[string]string{}
 	}
 
 	return &inspect, nil
@@ -241,6 +361,87 @@ func (inspectOutput ContainerInspect) GetExposedHostPort(containerPort uint16) u
 	return uint16(0)
 }
 
+// GetEnv returns the value of the given environment variable of the container, and whether it is set.
+func (inspectOutput ContainerInspect) GetEnv(key string) (string, bool) {
+	value, ok := inspectOutput.Env[key]
+	return value, ok
+}
+
+// HasLabel returns whether the container has the given label with the given value.
+func (inspectOutput ContainerInspect) HasLabel(key string, value string) bool {
+	actual, ok := inspectOutput.Labels[key]
+	return ok && actual == value
+}
+
+// GetMount returns the mount of the container at the given path in the container, and whether there is one.
+func (inspectOutput ContainerInspect) GetMount(destination string) (Mount, bool) {
+	for _, mount := range inspectOutput.Mounts {
+		if mount.Destination == destination {
+			return mount, true
+		}
+	}
+	return Mount{}, false
+}
+
+// GetNetwork returns the attachment of the container to the network with the given name, and whether it is attached to
+// it.
+func (inspectOutput ContainerInspect) GetNetwork(name string) (ContainerNetwork, bool) {
+	for _, network := range inspectOutput.Networks {
+		if network.Name == name {
+			return network, true
+		}
+	}
+	return ContainerNetwork{}, false
+}
+
+// transformContainerEnv converts Docker's environment variables from the format "KEY=value" into a map. Variables
+// without a value are set to an empty string.
+func transformContainerEnv(container inspectOutput) map[string]string {
+	env := map[string]string{}
+	for _, envVar := range container.Config.Env {
+		split := strings.SplitN(envVar, "=", 2)
+		if len(split) == 2 {
+			env[split[0]] = split[1]
+		} else {
+			env[split[0]] = ""
+		}
+	}
+	return env
+}
+
+// transformContainerMounts converts Docker's mounts into a more testable format
+func transformContainerMounts(container inspectOutput) []Mount {
+	mounts := make([]Mount, 0, len(container.Mounts))
+	for _, mount := range container.Mounts {
+		mounts = append(mounts, Mount{
+			Type:        mount.Type,
+			Name:        mount.Name,
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			ReadOnly:    !mount.RW,
+		})
+	}
+	return mounts
+}
+
+// transformContainerNetworks converts Docker's networks of the container, keyed by name, into a list sorted by name
+func transformContainerNetworks(container inspectOutput) []ContainerNetwork {
+	networks := make([]ContainerNetwork, 0, len(container.NetworkSettings.Networks))
+	for name, network := range container.NetworkSettings.Networks {
+		networks = append(networks, ContainerNetwork{
+			Name:      name,
+			NetworkID: network.NetworkID,
+			IPAddress: network.IPAddress,
+			Gateway:   network.Gateway,
+			Aliases:   network.Aliases,
+		})
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Name < networks[j].Name
+	})
+	return networks
+}
+
 // transformContainerVolumes converts Docker's volume bindings from the
 // format "/foo/bar:/foo/baz" into a more testable one
 func transformContainerVolumes(container inspectOutput) []VolumeBind {
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -140,6 +141,99 @@ func TestInspectReturnsCorrectHealthCheckWhenUnhealthy(t *testing.T) {
 	require.Equal(t, "/bin/sh: service nginx status: not found\n", c.Health.Log[0].Output)
 }
 
+func TestInspectReturnsContainerConfig(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Detach:               true,
+		EnvironmentVariables: []string{"GREETING=Hello, World!"},
+		Volumes:              []string{"/tmp:/foo/bar:ro"},
+		OtherOptions: []string{
+			"--label=com.example.team=platform",
+			"--restart=on-failure:3",
+			"--health-cmd=wget -q -O /dev/null http://localhost",
+			"--health-interval=5s",
+			"--health-retries=2",
+		},
+	}
+
+	id := RunAndGetID(t, dockerInspectTestImage, options)
+	defer removeContainer(t, id)
+
+	c := Inspect(t, id)
+
+	require.Equal(t, dockerInspectTestImage, c.Image)
+	greeting, ok := c.GetEnv("GREETING")
+	require.True(t, ok)
+	require.Equal(t, "Hello, World!", greeting)
+	require.True(t, c.HasLabel("com.example.team", "platform"))
+	require.Equal(t, RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}, c.RestartPolicy)
+
+	require.NotNil(t, c.HealthCheckConfig)
+	require.Equal(t, []string{"CMD-SHELL", "wget -q -O /dev/null http://localhost"}, c.HealthCheckConfig.Test)
+	require.Equal(t, 5*time.Second, c.HealthCheckConfig.Interval)
+	require.Equal(t, 2, c.HealthCheckConfig.Retries)
+
+	mount, ok := c.GetMount("/foo/bar")
+	require.True(t, ok)
+	require.Equal(t, Mount{Type: "bind", Source: "/tmp", Destination: "/foo/bar", ReadOnly: true}, mount)
+
+	network, ok := c.GetNetwork("bridge")
+	require.True(t, ok)
+	require.NotEmpty(t, network.IPAddress)
+}
+
+func TestTransformContainerConfig(t *testing.T) {
+	t.Parallel()
+
+	out := `{
+		"Id": "abc123",
+		"Created": "2023-05-06T16:57:20.123456789Z",
+		"Name": "/test",
+		"Config": {
+			"Image": "nginx:1.17-alpine",
+			"Env": ["PATH=/usr/local/bin:/usr/bin", "EMPTY=", "UNSET"],
+			"Labels": {"com.example.team": "platform"},
+			"Healthcheck": {"Test": ["CMD", "true"], "Interval": 5000000000, "Timeout": 1000000000, "StartPeriod": 0, "Retries": 2}
+		},
+		"HostConfig": {"RestartPolicy": {"Name": "always", "MaximumRetryCount": 0}},
+		"Mounts": [
+			{"Type": "volume", "Name": "data", "Source": "/var/lib/docker/volumes/data/_data", "Destination": "/data", "RW": true},
+			{"Type": "tmpfs", "Destination": "/cache", "RW": false}
+		],
+		"NetworkSettings": {
+			"Networks": {
+				"frontend": {"NetworkID": "net-2", "IPAddress": "172.30.0.2", "Gateway": "172.30.0.1", "Aliases": ["web"]},
+				"backend": {"NetworkID": "net-1", "IPAddress": "172.31.0.2", "Gateway": "172.31.0.1"}
+			}
+		}
+	}`
+	var container inspectOutput
+	require.NoError(t, json.Unmarshal([]byte(out), &container))
+
+	c, err := transformContainer(t, container)
+	require.NoError(t, err)
+
+	require.Equal(t, "nginx:1.17-alpine", c.Image)
+	require.Equal(t, map[string]string{"PATH": "/usr/local/bin:/usr/bin", "EMPTY": "", "UNSET": ""}, c.Env)
+	_, ok := c.GetEnv("MISSING")
+	require.False(t, ok)
+	require.True(t, c.HasLabel("com.example.team", "platform"))
+	require.False(t, c.HasLabel("com.example.team", "data"))
+	require.Equal(t, RestartPolicy{Name: "always"}, c.RestartPolicy)
+	require.Equal(t, &HealthCheckConfig{Test: []string{"CMD", "true"}, Interval: 5 * time.Second, Timeout: time.Second, Retries: 2}, c.HealthCheckConfig)
+	require.Equal(t, []Mount{
+		{Type: "volume", Name: "data", Source: "/var/lib/docker/volumes/data/_data", Destination: "/data"},
+		{Type: "tmpfs", Destination: "/cache", ReadOnly: true},
+	}, c.Mounts)
+	require.Equal(t, []ContainerNetwork{
+		{Name: "backend", NetworkID: "net-1", IPAddress: "172.31.0.2", Gateway: "172.31.0.1"},
+		{Name: "frontend", NetworkID: "net-2", IPAddress: "172.30.0.2", Gateway: "172.30.0.1", Aliases: []string{"web"}},
+	}, c.Networks)
+	_, ok = c.GetNetwork("bridge")
+	require.False(t, ok)
+}
+
 func runWithHealthCheck(t *testing.T, check string, frequency time.Duration, delay time.Duration) *ContainerInspect {
 	// append timestamp to container name to allow running tests in parallel
 	name := "inspect-test-" + random.UniqueId()
@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// maxLogLineSize is the size of the longest line of container logs that the log helpers can read.
+const maxLogLineSize = 1024 * 1024
+
+// LogTailer streams the logs of a container to a logger until it is stopped.
+type LogTailer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop stops streaming the logs of the container, and waits until all the lines read so far are logged.
+func (tailer *LogTailer) Stop() {
+	tailer.cancel()
+	<-tailer.done
+}
+
+// WaitForLogMessage follows the logs of the container with the given ID until a line matches the given regular
+// expression, for at most the given timeout, and returns that line. This method fails the test if there are any errors.
+func WaitForLogMessage(t testing.TestingT, id string, regex string, timeout time.Duration) string {
+	line, err := WaitForLogMessageE(t, id, regex, timeout)
+	require.NoError(t, err)
+	return line
+}
+
+// WaitForLogMessageE follows the logs of the container with the given ID, both stdout and stderr and from the start,
+// until a line matches the given regular expression, for at most the given timeout, and returns that line. This is
+// useful to wait until the application in the container logs that it is ready, e.g., "listening on port \d+". This
+// fails right away if the container stops without logging such a line.
+func WaitForLogMessageE(t testing.TestingT, id string, regex string, timeout time.Duration) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Logf(t, "Waiting for container %s to log a line matching %s", id, regex)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logs, err := followContainerLogs(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	defer logs.Close()
+
+	line, found, err := findLogLine(logs, re)
+	if found {
+		logger.Logf(t, "Container %s logged: %s", id, line)
+		return line, nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("container %s did not log a line matching %s within %s", id, regex, timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("container %s stopped without logging a line matching %s", id, regex)
+}
+
+// TailContainerLogs streams the logs of the container with the given ID to the given logger, line by line, until the
+// returned LogTailer is stopped. This method fails the test if there are any errors.
+func TailContainerLogs(t testing.TestingT, id string, logger *logger.Logger) *LogTailer {
+	tailer, err := TailContainerLogsE(t, id, logger)
+	require.NoError(t, err)
+	return tailer
+}
+
+// TailContainerLogsE streams the logs of the container with the given ID, both stdout and stderr and from the start,
+// to the given logger, line by line and prefixed with the ID, until the returned LogTailer is stopped or the container
+// stops. This gives the output of the container along with that of the test, which helps to debug failing tests. Stop
+// the tailer before the test ends:
+//
+//	tailer := docker.TailContainerLogs(t, id, logger.Default)
+//	defer tailer.Stop()
+func TailContainerLogsE(t testing.TestingT, id string, logger *logger.Logger) (*LogTailer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	logs, err := followContainerLogs(ctx, id)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	tailer := &LogTailer{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(tailer.done)
+		defer logs.Close()
+		streamLogLines(t, logs, logger, id)
+	}()
+	return tailer, nil
+}
+
+// followContainerLogs runs the 'docker logs --follow' command for the container with the given ID until the given
+// context is done, and returns its combined stdout and stderr. The stream ends with the error of the command, if any.
+// Close the stream to stop reading it.
+func followContainerLogs(ctx context.Context, id string) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+	binary, args := runtimeCommand(nil, "logs", "--follow", id)
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		writer.CloseWithError(cmd.Wait())
+	}()
+	return reader, nil
+}
+
+// findLogLine returns the first line of the given logs that matches the given regular expression, and whether there
+// is one, reading until the end of the logs otherwise.
+func findLogLine(logs io.Reader, re *regexp.Regexp) (string, bool, error) {
+	scanner := newLogScanner(logs)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if re.MatchString(line) {
+			return line, true, nil
+		}
+	}
+	return "", false, scanner.Err()
+}
+
+// streamLogLines logs each line of the given logs of the container with the given ID to the given logger.
+func streamLogLines(t testing.TestingT, logs io.Reader, logger *logger.Logger, id string) {
+	scanner := newLogScanner(logs)
+	for scanner.Scan() {
+		logger.Logf(t, "[%s] %s", id, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+}
+
+// newLogScanner returns a scanner of the lines of the given logs, which may be longer than the default limit of
+// bufio.Scanner.
+func newLogScanner(logs io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	return scanner
+}
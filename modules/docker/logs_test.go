@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	ttesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger is a logger.TestLogger that keeps all the messages it logs.
+type recordingLogger struct {
+	messages *[]string
+}
+
+func (l recordingLogger) Logf(t ttesting.TestingT, format string, args ...interface{}) {
+	*l.messages = append(*l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestWaitForLogMessage(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Command:    []string{"-c", `echo starting; sleep 2; echo "listening on port 8080"; sleep 60`},
+		Entrypoint: "sh",
+		Detach:     true,
+	}
+	id := strings.TrimSpace(Run(t, "alpine:3.7", options))
+	defer removeContainer(t, id)
+
+	line := WaitForLogMessage(t, id, `listening on port \d+`, time.Minute)
+	require.Equal(t, "listening on port 8080", line)
+}
+
+func TestWaitForLogMessageFailsWhenContainerStops(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Command:    []string{"-c", `echo starting`},
+		Entrypoint: "sh",
+		Detach:     true,
+	}
+	id := strings.TrimSpace(Run(t, "alpine:3.7", options))
+	defer removeContainer(t, id)
+
+	_, err := WaitForLogMessageE(t, id, `listening on port \d+`, time.Minute)
+	require.Error(t, err)
+}
+
+func TestFindLogLine(t *testing.T) {
+	t.Parallel()
+
+	re := regexp.MustCompile(`listening on port \d+`)
+
+	line, found, err := findLogLine(strings.NewReader("starting\r\nlistening on port 8080\r\nready\r\n"), re)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "listening on port 8080", line)
+
+	_, found, err = findLogLine(strings.NewReader("starting\nstopping\n"), re)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, found, err = findLogLine(strings.NewReader(strings.Repeat("x", maxLogLineSize+1)), re)
+	require.Error(t, err)
+	assert.False(t, found)
+}
+
+func TestStreamLogLines(t *testing.T) {
+	t.Parallel()
+
+	messages := []string{}
+	streamLogLines(t, strings.NewReader("starting\r\nready"), logger.New(recordingLogger{messages: &messages}), "abc123")
+	assert.Equal(t, []string{"[abc123] starting", "[abc123] ready"}, messages)
+}
+
+func TestTailContainerLogs(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Command:    []string{"-c", `echo "Hello, World!"`},
+		Entrypoint: "sh",
+		Detach:     true,
+	}
+	id := strings.TrimSpace(Run(t, "alpine:3.7", options))
+	defer removeContainer(t, id)
+
+	messages := []string{}
+	tailer := TailContainerLogs(t, id, logger.New(recordingLogger{messages: &messages}))
+	// The container exits right away, which ends its logs
+	<-tailer.done
+	tailer.Stop()
+
+	require.Contains(t, messages, fmt.Sprintf("[%s] Hello, World!", id))
+}
@@ -0,0 +1,406 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// networkProbeImage is the image of the container that the connectivity assertions run their probe in.
+	networkProbeImage = "busybox:1.36"
+	// networkProbeTimeoutSeconds is how long the probe waits for a connection.
+	networkProbeTimeoutSeconds = 5
+	// networkProbeFailedExitCode is the exit code of the probe if it can't connect, as opposed to the exit code of
+	// 'docker run' if it can't run the probe, which is 125 or above.
+	networkProbeFailedExitCode = 1
+)
+
+// NetworkOptions defines options that can be passed to the 'docker network create' command.
+type NetworkOptions struct {
+	// Driver of the network, e.g., "bridge" or "overlay" (default "bridge")
+	Driver string
+
+	// Subnets of the network in CIDR format, e.g., "172.28.0.0/16"
+	Subnets []string
+
+	// If set to true, pass the --internal flag to 'docker network create' to restrict external access to the network
+	Internal bool
+
+	// If set to true, pass the --attachable flag to 'docker network create' to allow standalone containers to attach
+	// to an overlay network
+	Attachable bool
+
+	// Set metadata on the network, e.g., {"com.example.team": "platform"}
+	Labels map[string]string
+
+	// Custom CLI options that will be passed as-is to the 'docker network create' command, e.g., driver options.
+	OtherOptions []string
+
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+}
+
+// NetworkInspect defines the output of the InspectNetwork method, with the options returned by
+// 'docker network inspect'
+type NetworkInspect struct {
+	// ID of the network
+	ID string
+
+	// Name of the network
+	Name string
+
+	// Driver of the network
+	Driver string
+
+	// Whether external access to the network is restricted
+	Internal bool
+
+	// Subnets of the network in CIDR format
+	Subnets []string
+
+	// Metadata of the network
+	Labels map[string]string
+
+	// Containers attached to the network
+	Containers []NetworkContainer
+}
+
+// NetworkContainer represents a container attached to a network
+type NetworkContainer struct {
+	ID   string
+	Name string
+
+	// Address of the container on the network in CIDR format, e.g., "172.28.0.2/16"
+	IPv4Address string
+}
+
+// networkInspectOutput defines options that will be returned by 'docker network inspect', in JSON format.
+type networkInspectOutput struct {
+	Id       string
+	Name     string
+	Driver   string
+	Internal bool
+	IPAM     struct {
+		Config []struct {
+			Subnet string
+		}
+	}
+	Labels     map[string]string
+	Containers map[string]struct {
+		Name        string
+		IPv4Address string
+	}
+}
+
+// CreateNetwork runs the 'docker network create' command to create a network with the given name and options, and
+// returns its ID. This method fails the test if there are any errors.
+func CreateNetwork(t testing.TestingT, name string, options *NetworkOptions) string {
+	id, err := CreateNetworkE(t, name, options)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateNetworkE runs the 'docker network create' command to create a network with the given name and options, and
+// returns its ID. Containers on a network other than the default bridge can reach each other by container name.
+func CreateNetworkE(t testing.TestingT, name string, options *NetworkOptions) (string, error) {
+	options.Logger.Logf(t, "Running 'docker network create' for network %s", name)
+
+	command, args := runtimeCommand(options.Runtime, formatDockerNetworkCreateArgs(name, options)...)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  options.Logger,
+	}
+	out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// RemoveNetwork runs the 'docker network rm' command to remove the given network. This method fails the test if there
+// are any errors.
+func RemoveNetwork(t testing.TestingT, network string, logger *logger.Logger) {
+	require.NoError(t, RemoveNetworkE(t, network, logger))
+}
+
+// RemoveNetworkE runs the 'docker network rm' command to remove the given network, which must have no container
+// attached.
+func RemoveNetworkE(t testing.TestingT, network string, logger *logger.Logger) error {
+	logger.Logf(t, "Running 'docker network rm' for network %s", network)
+
+	command, args := runtimeCommand(nil, "network", "rm", network)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  logger,
+	}
+	return shell.RunCommandE(t, cmd)
+}
+
+// ConnectNetwork runs the 'docker network connect' command to attach the given container to the given network. This
+// method fails the test if there are any errors.
+func ConnectNetwork(t testing.TestingT, network string, container string, logger *logger.Logger) {
+	require.NoError(t, ConnectNetworkE(t, network, container, logger))
+}
+
+// ConnectNetworkE runs the 'docker network connect' command to attach the given container to the given network.
+func ConnectNetworkE(t testing.TestingT, network string, container string, logger *logger.Logger) error {
+	logger.Logf(t, "Running 'docker network connect' for container %s and network %s", container, network)
+
+	command, args := runtimeCommand(nil, "network", "connect", network, container)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  logger,
+	}
+	return shell.RunCommandE(t, cmd)
+}
+
+// DisconnectNetwork runs the 'docker network disconnect' command to detach the given container from the given
+// network. This method fails the test if there are any errors.
+func DisconnectNetwork(t testing.TestingT, network string, container string, logger *logger.Logger) {
+	require.NoError(t, DisconnectNetworkE(t, network, container, logger))
+}
+
+// DisconnectNetworkE runs the 'docker network disconnect' command to detach the given container from the given
+// network.
+func DisconnectNetworkE(t testing.TestingT, network string, container string, logger *logger.Logger) error {
+	logger.Logf(t, "Running 'docker network disconnect' for container %s and network %s", container, network)
+
+	command, args := runtimeCommand(nil, "network", "disconnect", network, container)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  logger,
+	}
+	return shell.RunCommandE(t, cmd)
+}
+
+// InspectNetwork runs the 'docker network inspect' command for the given network and returns a NetworkInspect struct,
+// converted from the output JSON. This method fails the test if there are any errors.
+func InspectNetwork(t testing.TestingT, network string) *NetworkInspect {
+	inspect, err := InspectNetworkE(t, network)
+	require.NoError(t, err)
+	return inspect
+}
+
+// InspectNetworkE runs the 'docker network inspect' command for the given network and returns a NetworkInspect
+// struct, converted from the output JSON, along with any errors.
+func InspectNetworkE(t testing.TestingT, network string) (*NetworkInspect, error) {
+	command, args := runtimeCommand(nil, "network", "inspect", network)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		// inspect is a short-running command, don't print the output.
+		Logger: logger.Discard,
+	}
+
+	out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []networkInspectOutput
+	if err := json.Unmarshal([]byte(out), &networks); err != nil {
+		return nil, err
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("no network found with name or ID %s", network)
+	}
+	return transformNetwork(networks[0]), nil
+}
+
+// AssertContainersCanConnect checks that the given source container can open a TCP connection to the given port of the
+// given target container. This method fails the test if it can't.
+func AssertContainersCanConnect(t testing.TestingT, fromContainer string, toContainer string, port uint16) {
+	require.NoError(t, AssertContainersCanConnectE(t, fromContainer, toContainer, port))
+}
+
+// AssertContainersCanConnectE checks that the given source container can open a TCP connection to the given port of
+// the given target container, at its address on a network they share. The probe runs in a container that shares the
+// network stack of the source container, so the source image needs no network tools, and the check holds whatever the
+// network driver.
+func AssertContainersCanConnectE(t testing.TestingT, fromContainer string, toContainer string, port uint16) error {
+	connected, address, err := probeContainerConnectionE(t, fromContainer, toContainer, port)
+	if err != nil {
+		return err
+	}
+	if !connected {
+		return fmt.Errorf("container %s can't connect to container %s on %s", fromContainer, toContainer, address)
+	}
+	return nil
+}
+
+// AssertContainersCannotConnect checks that the given source container can't open a TCP connection to the given port
+// of the given target container. This method fails the test if it can.
+func AssertContainersCannotConnect(t testing.TestingT, fromContainer string, toContainer string, port uint16) {
+	require.NoError(t, AssertContainersCannotConnectE(t, fromContainer, toContainer, port))
+}
+
+// AssertContainersCannotConnectE checks that the given source container can't open a TCP connection to the given port
+// of the given target container, e.g., to test that a network policy or the isolation of networks holds. If the
+// containers share no network, they can't connect.
+func AssertContainersCannotConnectE(t testing.TestingT, fromContainer string, toContainer string, port uint16) error {
+	connected, address, err := probeContainerConnectionE(t, fromContainer, toContainer, port)
+	if err != nil {
+		if _, noSharedNetwork := err.(noSharedNetworkError); noSharedNetwork {
+			return nil
+		}
+		return err
+	}
+	if connected {
+		return fmt.Errorf("container %s can connect to container %s on %s", fromContainer, toContainer, address)
+	}
+	return nil
+}
+
+// noSharedNetworkError is returned when two containers are attached to no common network.
+type noSharedNetworkError struct {
+	fromContainer string
+	toContainer   string
+}
+
+func (err noSharedNetworkError) Error() string {
+	return fmt.Sprintf("containers %s and %s share no network", err.fromContainer, err.toContainer)
+}
+
+// probeContainerConnectionE runs a probe in the network stack of the given source container that opens a TCP
+// connection to the given port of the given target container, and returns whether it connected and to which address.
+func probeContainerConnectionE(t testing.TestingT, fromContainer string, toContainer string, port uint16) (bool, string, error) {
+	from, err := inspectContainerE(t, nil, fromContainer)
+	if err != nil {
+		return false, "", err
+	}
+	to, err := inspectContainerE(t, nil, toContainer)
+	if err != nil {
+		return false, "", err
+	}
+	ip, err := sharedNetworkAddress(from, to)
+	if err != nil {
+		return false, "", err
+	}
+	address := net.JoinHostPort(ip, strconv.Itoa(int(port)))
+
+	logger.Logf(t, "Probing connection from container %s to container %s on %s", fromContainer, toContainer, address)
+	command, args := runtimeCommand(
+		nil,
+		"run", "--rm",
+		"--network", "container:"+from.Id,
+		networkProbeImage,
+		"nc", "-z", "-w", strconv.Itoa(networkProbeTimeoutSeconds), ip, strconv.Itoa(int(port)),
+	)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  logger.Discard,
+	}
+	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	if err == nil {
+		return true, address, nil
+	}
+	exitCode, exitCodeErr := shell.GetExitCodeForRunCommandError(err)
+	if exitCodeErr == nil && exitCode == networkProbeFailedExitCode {
+		return false, address, nil
+	}
+	return false, address, err
+}
+
+// sharedNetworkAddress returns the IP address of the given target container on a network that the given source
+// container is attached to, or a noSharedNetworkError if there is none. Networks are considered in name order, so
+// the address is the same across runs.
+func sharedNetworkAddress(from inspectOutput, to inspectOutput) (string, error) {
+	names := []string{}
+	for name := range to.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		toNetwork := to.NetworkSettings.Networks[name]
+		for _, fromNetwork := range from.NetworkSettings.Networks {
+			if fromNetwork.NetworkID == toNetwork.NetworkID && toNetwork.IPAddress != "" {
+				return toNetwork.IPAddress, nil
+			}
+		}
+	}
+	return "", noSharedNetworkError{fromContainer: strings.TrimLeft(from.Name, "/"), toContainer: strings.TrimLeft(to.Name, "/")}
+}
+
+// formatDockerNetworkCreateArgs formats the arguments for the 'docker network create' command.
+func formatDockerNetworkCreateArgs(name string, options *NetworkOptions) []string {
+	args := []string{"network", "create"}
+
+	if options.Driver != "" {
+		args = append(args, "--driver", options.Driver)
+	}
+
+	for _, subnet := range options.Subnets {
+		args = append(args, "--subnet", subnet)
+	}
+
+	if options.Internal {
+		args = append(args, "--internal")
+	}
+
+	if options.Attachable {
+		args = append(args, "--attachable")
+	}
+
+	args = append(args, formatLabelArgs(options.Labels)...)
+
+	args = append(args, options.OtherOptions...)
+
+	return append(args, name)
+}
+
+// formatLabelArgs formats the --label arguments for the given labels, sorted by key so that the arguments are the same
+// across runs.
+func formatLabelArgs(labels map[string]string) []string {
+	keys := []string{}
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{}
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	return args
+}
+
+// transformNetwork converts 'docker network inspect' output JSON into a more friendly and testable format.
+func transformNetwork(network networkInspectOutput) *NetworkInspect {
+	inspect := &NetworkInspect{
+		ID:         network.Id,
+		Name:       network.Name,
+		Driver:     network.Driver,
+		Internal:   network.Internal,
+		Subnets:    []string{},
+		Labels:     network.Labels,
+		Containers: []NetworkContainer{},
+	}
+	for _, config := range network.IPAM.Config {
+		inspect.Subnets = append(inspect.Subnets, config.Subnet)
+	}
+	for id, container := range network.Containers {
+		inspect.Containers = append(inspect.Containers, NetworkContainer{ID: id, Name: container.Name, IPv4Address: container.IPv4Address})
+	}
+	sort.Slice(inspect.Containers, func(i, j int) bool {
+		return inspect.Containers[i].Name < inspect.Containers[j].Name
+	})
+	return inspect
+}
@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainersCanConnectOnNetwork(t *testing.T) {
+	t.Parallel()
+
+	network := "terratest-" + strings.ToLower(random.UniqueId())
+	CreateNetwork(t, network, &NetworkOptions{Subnets: []string{"172.30.0.0/24"}, Labels: map[string]string{"terratest": "true"}})
+	defer RemoveNetwork(t, network, nil)
+
+	server := strings.TrimSpace(Run(t, dockerInspectTestImage, &RunOptions{Detach: true, OtherOptions: []string{"--network", network}}))
+	defer removeContainer(t, server)
+	client := strings.TrimSpace(Run(t, "alpine:3.7", &RunOptions{Detach: true, Entrypoint: "sleep", Command: []string{"60"}, OtherOptions: []string{"--network", network}}))
+	defer removeContainer(t, client)
+	isolated := strings.TrimSpace(Run(t, "alpine:3.7", &RunOptions{Detach: true, Entrypoint: "sleep", Command: []string{"60"}}))
+	defer removeContainer(t, isolated)
+
+	inspect := InspectNetwork(t, network)
+	assert.Equal(t, network, inspect.Name)
+	assert.Equal(t, []string{"172.30.0.0/24"}, inspect.Subnets)
+	assert.Equal(t, "true", inspect.Labels["terratest"])
+	assert.Len(t, inspect.Containers, 2)
+
+	AssertContainersCanConnect(t, client, server, 80)
+	AssertContainersCannotConnect(t, client, server, 8080)
+	AssertContainersCannotConnect(t, isolated, server, 80)
+
+	ConnectNetwork(t, network, isolated, nil)
+	AssertContainersCanConnect(t, isolated, server, 80)
+	DisconnectNetwork(t, network, isolated, nil)
+}
+
+func TestFormatDockerNetworkCreateArgs(t *testing.T) {
+	t.Parallel()
+
+	options := &NetworkOptions{
+		Driver:       "bridge",
+		Subnets:      []string{"172.30.0.0/24"},
+		Internal:     true,
+		Attachable:   true,
+		Labels:       map[string]string{"b": "2", "a": "1"},
+		OtherOptions: []string{"--opt", "com.docker.network.bridge.enable_icc=false"},
+	}
+	assert.Equal(t, []string{
+		"network", "create",
+		"--driver", "bridge",
+		"--subnet", "172.30.0.0/24",
+		"--internal",
+		"--attachable",
+		"--label", "a=1",
+		"--label", "b=2",
+		"--opt", "com.docker.network.bridge.enable_icc=false",
+		"test-network",
+	}, formatDockerNetworkCreateArgs("test-network", options))
+	assert.Equal(t, []string{"network", "create", "test-network"}, formatDockerNetworkCreateArgs("test-network", &NetworkOptions{}))
+}
+
+func TestSharedNetworkAddress(t *testing.T) {
+	t.Parallel()
+
+	from := inspectOutput{Name: "/client"}
+	to := inspectOutput{Name: "/server"}
+	from.NetworkSettings.Networks = map[string]networkEndpointOutput{
+		"frontend": {NetworkID: "net-frontend", IPAddress: "172.30.0.2"},
+	}
+	to.NetworkSettings.Networks = map[string]networkEndpointOutput{
+		"backend":  {NetworkID: "net-backend", IPAddress: "172.31.0.3"},
+		"frontend": {NetworkID: "net-frontend", IPAddress: "172.30.0.3"},
+	}
+
+	ip, err := sharedNetworkAddress(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, "172.30.0.3", ip)
+
+	delete(to.NetworkSettings.Networks, "frontend")
+	_, err = sharedNetworkAddress(from, to)
+	require.Error(t, err)
+	assert.IsType(t, noSharedNetworkError{}, err)
+	assert.EqualError(t, err, "containers client and server share no network")
+}
+
+func TestTransformNetwork(t *testing.T) {
+	t.Parallel()
+
+	network := networkInspectOutput{Id: "abc", Name: "test-network", Driver: "bridge", Internal: true}
+	network.IPAM.Config = []struct {
+		Subnet string
+	}{{Subnet: "172.30.0.0/24"}}
+	network.Containers = map[string]struct {
+		Name        string
+		IPv4Address string
+	}{
+		"id-2": {Name: "server", IPv4Address: "172.30.0.3/24"},
+		"id-1": {Name: "client", IPv4Address: "172.30.0.2/24"},
+	}
+
+	inspect := transformNetwork(network)
+	assert.Equal(t, "abc", inspect.ID)
+	assert.True(t, inspect.Internal)
+	assert.Equal(t, []string{"172.30.0.0/24"}, inspect.Subnets)
+	assert.Equal(t, []NetworkContainer{
+		{ID: "id-1", Name: "client", IPv4Address: "172.30.0.2/24"},
+		{ID: "id-2", Name: "server", IPv4Address: "172.30.0.3/24"},
+	}, inspect.Containers)
+}
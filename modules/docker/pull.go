@@ -0,0 +1,26 @@
+package docker
+
+import (
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Pull runs the 'docker pull' command to pull the given image. This will fail the test if there are any errors.
+func Pull(t testing.TestingT, logger *logger.Logger, image string) {
+	require.NoError(t, PullE(t, logger, image))
+}
+
+// PullE runs the 'docker pull' command to pull the given image.
+func PullE(t testing.TestingT, logger *logger.Logger, image string) error {
+	logger.Logf(t, "Running 'docker pull' for image %s", image)
+
+	command, args := runtimeCommand(nil, "pull", image)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  logger,
+	}
+	return shell.RunCommandE(t, cmd)
+}
@@ -14,11 +14,17 @@ func Push(t testing.TestingT, logger *logger.Logger, tag string) {
 
 // PushE runs the 'docker push' command to push the given tag.
 func PushE(t testing.TestingT, logger *logger.Logger, tag string) error {
+	return pushE(t, nil, logger, tag)
+}
+
+// pushE runs the 'docker push' command of the given container runtime to push the given tag.
+func pushE(t testing.TestingT, runtime ContainerRuntime, logger *logger.Logger, tag string) error {
 	logger.Logf(t, "Running 'docker push' for tag %s", tag)
 
+	command, args := runtimeCommand(runtime, "push", tag)
 	cmd := shell.Command{
-		Command: "docker",
-		Args:    []string{"push", tag},
+		Command: command,
+		Args:    args,
 		Logger:  logger,
 	}
 	return shell.RunCommandE(t, cmd)
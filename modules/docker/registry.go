@@ -0,0 +1,353 @@
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// defaultRegistryImage is the image of the registry that StartRegistryE runs by default.
+	defaultRegistryImage = "registry:2"
+	// registryContainerPort is the port the registry listens on in its container.
+	registryContainerPort = 5000
+	// registryConfigDir is where the htpasswd file and TLS certificate of the registry are mounted in its container.
+	registryConfigDir = "/terratest"
+	// registryStartTimeout is how long to wait for the registry to serve requests.
+	registryStartTimeout = time.Minute
+)
+
+// RegistryOptions defines options for the ephemeral registry started by StartRegistryE.
+type RegistryOptions struct {
+	// Image of the registry to run (default "registry:2")
+	Image string
+
+	// Username and password that clients must log in with, using htpasswd authentication. If Username is empty, the
+	// registry allows anonymous access.
+	Username string
+	Password string
+
+	// If set to true, the registry serves HTTPS with a self-signed certificate for localhost, 127.0.0.1 and the Docker
+	// host, rather than plain HTTP.
+	TLS bool
+
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+}
+
+// Registry is an ephemeral Docker registry running in a container.
+type Registry struct {
+	// ID of the container of the registry
+	ContainerID string
+
+	// Address of the registry as seen by the Docker engine, e.g., "localhost:54321", to tag images with. Docker treats
+	// registries on localhost as insecure, so it can push to and pull from them without trusting their certificate.
+	Address string
+
+	// URL of the registry for clients of the test itself, e.g., "https://localhost:54321"
+	URL string
+
+	// Username and password to log in to the registry with, if it requires authentication
+	Username string
+	Password string
+
+	// Path to the self-signed certificate of the registry in PEM format if it serves HTTPS, for clients that need to
+	// trust it, e.g., with 'helm registry login --ca-file'
+	CACertificate string
+
+	configDir string
+	loggedIn  bool
+	runtime   ContainerRuntime
+	logger    *logger.Logger
+}
+
+// ImageName returns the name of an image with the given repository and tag in the registry, e.g.,
+// "localhost:54321/test-image:v1" for "test-image:v1".
+func (registry *Registry) ImageName(name string) string {
+	return registry.Address + "/" + name
+}
+
+// StartRegistry starts an ephemeral registry in a container and waits until it serves requests. This will fail the
+// test if there are any errors.
+func StartRegistry(t testing.TestingT, options *RegistryOptions) *Registry {
+	registry, err := StartRegistryE(t, options)
+	require.NoError(t, err)
+	return registry
+}
+
+// StartRegistryE starts an ephemeral registry in a container, on a random port of the Docker host, and waits until it
+// serves requests. This allows hermetic tests of pushing and pulling images, e.g., of image promotion or of Helm charts
+// stored in OCI registries, without credentials to a real registry. Stop the registry when done:
+//
+//	registry := docker.StartRegistry(t, &docker.RegistryOptions{Username: "test", Password: "secret"})
+//	defer docker.StopRegistry(t, registry)
+//	docker.LoginToRegistry(t, registry)
+//	docker.Push(t, logger, registry.ImageName("test-image:v1"))
+func StartRegistryE(t testing.TestingT, options *RegistryOptions) (*Registry, error) {
+	image := options.Image
+	if image == "" {
+		image = defaultRegistryImage
+	}
+
+	configDir, err := ioutil.TempDir("", "terratest-registry-")
+	if err != nil {
+		return nil, err
+	}
+	registry := &Registry{
+		Username:  options.Username,
+		Password:  options.Password,
+		configDir: configDir,
+		runtime:   options.Runtime,
+		logger:    options.Logger,
+	}
+
+	runOptions, err := registryRunOptions(registry, options)
+	if err != nil {
+		os.RemoveAll(configDir)
+		return nil, err
+	}
+
+	options.Logger.Logf(t, "Starting registry with image %s", image)
+	out, err := RunAndGetIDE(t, image, runOptions)
+	if err != nil {
+		os.RemoveAll(configDir)
+		return nil, err
+	}
+	registry.ContainerID = strings.TrimSpace(out)
+
+	if err := waitUntilRegistryReady(t, registry, options.TLS); err != nil {
+		StopRegistryE(t, registry)
+		return nil, err
+	}
+	return registry, nil
+}
+
+// StopRegistry logs out of the given registry and removes its container. This will fail the test if there are any
+// errors.
+func StopRegistry(t testing.TestingT, registry *Registry) {
+	require.NoError(t, StopRegistryE(t, registry))
+}
+
+// StopRegistryE logs out of the given registry if LoginToRegistryE logged in to it, and removes its container, along
+// with the images pushed to it.
+func StopRegistryE(t testing.TestingT, registry *Registry) error {
+	if registry.loggedIn {
+		command, args := runtimeCommand(registry.runtime, "logout", registry.Address)
+		cmd := shell.Command{
+			Command: command,
+			Args:    args,
+			Logger:  registry.logger,
+		}
+		if err := shell.RunCommandE(t, cmd); err != nil {
+			return err
+		}
+		registry.loggedIn = false
+	}
+
+	registry.logger.Logf(t, "Removing registry container %s", registry.ContainerID)
+	command, args := runtimeCommand(registry.runtime, "rm", "--force", "--volumes", registry.ContainerID)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  registry.logger,
+	}
+	if err := shell.RunCommandE(t, cmd); err != nil {
+		return err
+	}
+	return os.RemoveAll(registry.configDir)
+}
+
+// LoginToRegistry runs the 'docker login' command to log in to the given registry with its username and password.
+// This will fail the test if there are any errors.
+func LoginToRegistry(t testing.TestingT, registry *Registry) {
+	require.NoError(t, LoginToRegistryE(t, registry))
+}
+
+// LoginToRegistryE runs the 'docker login' command to log in to the given registry with its username and password,
+// passing the password on stdin. StopRegistryE logs out again.
+func LoginToRegistryE(t testing.TestingT, registry *Registry) error {
+	registry.logger.Logf(t, "Running 'docker login' for registry %s as %s", registry.Address, registry.Username)
+
+	binary, args := runtimeCommand(registry.runtime, "login", "--username", registry.Username, "--password-stdin", registry.Address)
+	cmd := exec.Command(binary, args...)
+	cmd.Stdin = strings.NewReader(registry.Password)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error logging in to registry %s: %s: %s", registry.Address, err, out)
+	}
+	registry.loggedIn = true
+	return nil
+}
+
+// registryRunOptions writes the htpasswd file and the TLS certificate of the given registry to its config directory,
+// depending on the options, and returns the options to run its container with.
+func registryRunOptions(registry *Registry, options *RegistryOptions) (*RunOptions, error) {
+	runOptions := &RunOptions{
+		Detach:       true,
+		Volumes:      []string{registry.configDir + ":" + registryConfigDir + ":ro"},
+		OtherOptions: []string{"--publish", fmt.Sprint(registryContainerPort)},
+		Runtime:      options.Runtime,
+		Logger:       options.Logger,
+	}
+
+	if options.Username != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(options.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		htpasswd := fmt.Sprintf("%s:%s\n", options.Username, hash)
+		if err := ioutil.WriteFile(filepath.Join(registry.configDir, "htpasswd"), []byte(htpasswd), 0644); err != nil {
+			return nil, err
+		}
+		runOptions.EnvironmentVariables = append(runOptions.EnvironmentVariables,
+			"REGISTRY_AUTH=htpasswd",
+			"REGISTRY_AUTH_HTPASSWD_REALM=terratest",
+			"REGISTRY_AUTH_HTPASSWD_PATH="+registryConfigDir+"/htpasswd",
+		)
+	}
+
+	if options.TLS {
+		certificate, key, err := generateRegistryCertificate([]string{GetDockerHost()})
+		if err != nil {
+			return nil, err
+		}
+		registry.CACertificate = filepath.Join(registry.configDir, "registry.crt")
+		if err := ioutil.WriteFile(registry.CACertificate, certificate, 0644); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(registry.configDir, "registry.key"), key, 0600); err != nil {
+			return nil, err
+		}
+		runOptions.EnvironmentVariables = append(runOptions.EnvironmentVariables,
+			"REGISTRY_HTTP_TLS_CERTIFICATE="+registryConfigDir+"/registry.crt",
+			"REGISTRY_HTTP_TLS_KEY="+registryConfigDir+"/registry.key",
+		)
+	}
+
+	return runOptions, nil
+}
+
+// waitUntilRegistryReady sets the address and URL of the given registry, from the host port that its container port is
+// published to, and waits until it serves requests to its API.
+func waitUntilRegistryReady(t testing.TestingT, registry *Registry, useTLS bool) error {
+	container, err := inspectContainerE(t, registry.runtime, registry.ContainerID)
+	if err != nil {
+		return err
+	}
+	hostPort := ""
+	for _, binding := range container.NetworkSettings.Ports[fmt.Sprintf("%d/tcp", registryContainerPort)] {
+		if binding.HostPort != "" {
+			hostPort = binding.HostPort
+			break
+		}
+	}
+	if hostPort == "" {
+		return fmt.Errorf("port %d of registry container %s is not published", registryContainerPort, registry.ContainerID)
+	}
+
+	scheme := "http"
+	client := http.Client{Timeout: time.Second}
+	if useTLS {
+		scheme = "https"
+		certificate, err := ioutil.ReadFile(registry.CACertificate)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(certificate)
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+	registry.Address = net.JoinHostPort("localhost", hostPort)
+	registry.URL = scheme + "://" + net.JoinHostPort(GetDockerHost(), hostPort)
+
+	_, err = retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for registry %s to serve requests", registry.URL),
+		int(registryStartTimeout/time.Second),
+		time.Second,
+		func() (string, error) {
+			resp, err := client.Get(registry.URL + "/v2/")
+			if err != nil {
+				return "", err
+			}
+			resp.Body.Close()
+			// The registry returns 401 before login if it requires authentication.
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+				return "", fmt.Errorf("registry %s returned status code %d", registry.URL, resp.StatusCode)
+			}
+			return "", nil
+		},
+	)
+	return err
+}
+
+// generateRegistryCertificate generates a self-signed certificate and its private key, in PEM format, for the given
+// host names or IP addresses, along with localhost.
+func generateRegistryCertificate(hosts []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"Terratest"}, CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	for _, host := range append([]string{"localhost"}, hosts...) {
+		if collections.ListContains(template.DNSNames, host) {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certificate, keyPEM, nil
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestStartRegistryWithAuthAndTLS(t *testing.T) {
+	t.Parallel()
+
+	registry := StartRegistry(t, &RegistryOptions{Username: "terratest", Password: "correct-horse-battery-staple", TLS: true})
+	defer StopRegistry(t, registry)
+
+	LoginToRegistry(t, registry)
+
+	image := registry.ImageName("alpine:3.7")
+	Pull(t, logger.Default, "alpine:3.7")
+	shell.RunCommand(t, shell.Command{Command: "docker", Args: []string{"tag", "alpine:3.7", image}})
+	Push(t, logger.Default, image)
+	DeleteImage(t, image, logger.Default)
+
+	Pull(t, logger.Default, image)
+	require.True(t, DoesImageExist(t, image, logger.Default))
+}
+
+func TestRegistryRunOptions(t *testing.T) {
+	t.Parallel()
+
+	configDir, err := ioutil.TempDir("", "terratest-registry-")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+
+	registry := &Registry{configDir: configDir}
+	runOptions, err := registryRunOptions(registry, &RegistryOptions{Username: "terratest", Password: "secret", TLS: true})
+	require.NoError(t, err)
+
+	assert.True(t, runOptions.Detach)
+	assert.Equal(t, []string{configDir + ":/terratest:ro"}, runOptions.Volumes)
+	assert.Equal(t, []string{"--publish", "5000"}, runOptions.OtherOptions)
+	assert.Contains(t, runOptions.EnvironmentVariables, "REGISTRY_AUTH_HTPASSWD_PATH=/terratest/htpasswd")
+	assert.Contains(t, runOptions.EnvironmentVariables, "REGISTRY_HTTP_TLS_CERTIFICATE=/terratest/registry.crt")
+	assert.Equal(t, filepath.Join(configDir, "registry.crt"), registry.CACertificate)
+
+	htpasswd, err := ioutil.ReadFile(filepath.Join(configDir, "htpasswd"))
+	require.NoError(t, err)
+	parts := strings.SplitN(strings.TrimSpace(string(htpasswd)), ":", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "terratest", parts[0])
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(parts[1]), []byte("secret")))
+
+	anonymousOptions, err := registryRunOptions(&Registry{configDir: configDir}, &RegistryOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, anonymousOptions.EnvironmentVariables)
+}
+
+func TestGenerateRegistryCertificate(t *testing.T) {
+	t.Parallel()
+
+	certificatePEM, keyPEM, err := generateRegistryCertificate([]string{"localhost", "docker.example.com", "192.0.2.10"})
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(keyPEM)
+	require.NotNil(t, block)
+	_, err = x509.ParseECPrivateKey(block.Bytes)
+	require.NoError(t, err)
+
+	block, _ = pem.Decode(certificatePEM)
+	require.NotNil(t, block)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"localhost", "docker.example.com"}, certificate.DNSNames)
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	for _, host := range []string{"localhost", "127.0.0.1", "docker.example.com", "192.0.2.10"} {
+		_, err := certificate.Verify(x509.VerifyOptions{DNSName: host, Roots: pool})
+		assert.NoError(t, err, host)
+	}
+}
@@ -43,11 +43,18 @@ type RunOptions struct {
 	// Bind mount these volume(s) when running the container
 	Volumes []string
 
+	// How RunAndWaitHealthyE checks that the container is ready, for images without a HEALTHCHECK. If nil, it waits on
+	// the HEALTHCHECK status of the container.
+	HealthProbe *HealthProbe
+
 	// Custom CLI options that will be passed as-is to the 'docker run' command. This is an "escape hatch" that allows
 	// Terratest to not have to support every single command-line option offered by the 'docker run' command, and
 	// solely focus on the most important ones.
 	OtherOptions []string
 
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
 	// Set a logger that should be used. See the logger package for more info.
 	Logger *logger.Logger
 }
@@ -69,8 +76,9 @@ func RunE(t testing.TestingT, image string, options *RunOptions) (string, error)
 		return "", err
 	}
 
+	command, args := runtimeCommand(options.Runtime, args...)
 	cmd := shell.Command{
-		Command: "docker",
+		Command: command,
 		Args:    args,
 		Logger:  options.Logger,
 	}
@@ -96,8 +104,9 @@ func RunAndGetIDE(t testing.TestingT, image string, options *RunOptions) (string
 		return "", err
 	}
 
+	command, args := runtimeCommand(options.Runtime, args...)
 	cmd := shell.Command{
-		Command: "docker",
+		Command: command,
 		Args:    args,
 		Logger:  options.Logger,
 	}
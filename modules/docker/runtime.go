@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// ContainerRuntimeEnvVar is the environment variable that selects the container runtime of the functions of this
+// package when DefaultRuntime is not set: "docker", "podman" or "nerdctl".
+const ContainerRuntimeEnvVar = "TERRATEST_CONTAINER_RUNTIME"
+
+// ContainerRuntime is a container CLI compatible with the docker CLI, such as podman or nerdctl, that the functions of
+// this package run instead of docker. Rootless runtimes work the same, as long as the containers they run can publish
+// ports on the host.
+type ContainerRuntime interface {
+	// Binary returns the name or path of the CLI of the runtime, e.g., "podman".
+	Binary() string
+
+	// FormatArgs returns the arguments of the CLI of the runtime for the given arguments of the docker CLI, e.g., with
+	// global flags of the runtime.
+	FormatArgs(args []string) []string
+}
+
+// DockerRuntime runs containers with the docker CLI.
+type DockerRuntime struct{}
+
+// Binary returns "docker".
+func (runtime DockerRuntime) Binary() string {
+	return "docker"
+}
+
+// FormatArgs returns the given arguments as they are.
+func (runtime DockerRuntime) FormatArgs(args []string) []string {
+	return args
+}
+
+// PodmanRuntime runs containers with the podman CLI, including rootless podman. 'podman compose' requires the
+// podman-compose or docker-compose provider, and buildx builders are not supported.
+type PodmanRuntime struct{}
+
+// Binary returns "podman".
+func (runtime PodmanRuntime) Binary() string {
+	return "podman"
+}
+
+// FormatArgs returns the given arguments as they are, as podman accepts the commands and flags of docker.
+func (runtime PodmanRuntime) FormatArgs(args []string) []string {
+	return args
+}
+
+// NerdctlRuntime runs containers with the nerdctl CLI of containerd, including rootless containerd.
+type NerdctlRuntime struct {
+	// Namespace of containerd to run containers in (default "default")
+	Namespace string
+}
+
+// Binary returns "nerdctl".
+func (runtime NerdctlRuntime) Binary() string {
+	return "nerdctl"
+}
+
+// FormatArgs returns the given arguments, preceded by the namespace flag if a namespace is set.
+func (runtime NerdctlRuntime) FormatArgs(args []string) []string {
+	if runtime.Namespace == "" {
+		return args
+	}
+	return append([]string{"--namespace", runtime.Namespace}, args...)
+}
+
+// DefaultRuntime is the container runtime of the functions of this package whose options do not set one, or that have
+// no options. If it is nil, the runtime is detected: the one named by the TERRATEST_CONTAINER_RUNTIME environment
+// variable, or else the first of docker, podman and nerdctl that is installed. Set it before the tests run, e.g., in
+// TestMain.
+var DefaultRuntime ContainerRuntime
+
+var (
+	detectedRuntime      ContainerRuntime
+	detectedRuntimeError error
+	detectedRuntimeOnce  sync.Once
+)
+
+// GetContainerRuntime returns DefaultRuntime if it is set, or else the detected container runtime. This panics if
+// TERRATEST_CONTAINER_RUNTIME names an unknown runtime, rather than running the commands with another one.
+func GetContainerRuntime() ContainerRuntime {
+	runtime, err := GetContainerRuntimeE()
+	if err != nil {
+		panic(err)
+	}
+	return runtime
+}
+
+// GetContainerRuntimeE returns DefaultRuntime if it is set, or else the detected container runtime. This returns an
+// UnknownContainerRuntime error if TERRATEST_CONTAINER_RUNTIME names an unknown runtime.
+func GetContainerRuntimeE() (ContainerRuntime, error) {
+	if DefaultRuntime != nil {
+		return DefaultRuntime, nil
+	}
+	detectedRuntimeOnce.Do(func() {
+		detectedRuntime, detectedRuntimeError = detectContainerRuntime(os.Getenv(ContainerRuntimeEnvVar), func(binary string) bool {
+			_, err := exec.LookPath(binary)
+			return err == nil
+		})
+	})
+	return detectedRuntime, detectedRuntimeError
+}
+
+// detectContainerRuntime returns the runtime with the given name if it is set, or else the first of docker, podman and
+// nerdctl that is installed, according to the given function, falling back to docker. This returns an
+// UnknownContainerRuntime error if the name is set to none of them.
+func detectContainerRuntime(name string, isInstalled func(binary string) bool) (ContainerRuntime, error) {
+	runtimes := []ContainerRuntime{DockerRuntime{}, PodmanRuntime{}, NerdctlRuntime{}}
+	if name != "" {
+		for _, runtime := range runtimes {
+			if name == runtime.Binary() {
+				return runtime, nil
+			}
+		}
+		return nil, UnknownContainerRuntime{Name: name}
+	}
+	for _, runtime := range runtimes {
+		if isInstalled(runtime.Binary()) {
+			return runtime, nil
+		}
+	}
+	return DockerRuntime{}, nil
+}
+
+// UnknownContainerRuntime is returned when TERRATEST_CONTAINER_RUNTIME is set to a name other than docker, podman and
+// nerdctl.
+type UnknownContainerRuntime struct {
+	Name string
+}
+
+func (err UnknownContainerRuntime) Error() string {
+	return fmt.Sprintf("unknown container runtime %q in %s: expected docker, podman or nerdctl", err.Name, ContainerRuntimeEnvVar)
+}
+
+// runtimeCommand returns the binary and arguments of the given container runtime, or of the default one if it is nil,
+// for the given arguments of the docker CLI.
+func runtimeCommand(runtime ContainerRuntime, args ...string) (string, []string) {
+	if runtime == nil {
+		runtime = GetContainerRuntime()
+	}
+	return runtime.Binary(), runtime.FormatArgs(args)
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContainerRuntime(t *testing.T) {
+	t.Parallel()
+
+	installed := func(binaries ...string) func(string) bool {
+		return func(binary string) bool {
+			for _, installedBinary := range binaries {
+				if binary == installedBinary {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	assertDetectedRuntime := func(expected ContainerRuntime, name string, isInstalled func(string) bool) {
+		runtime, err := detectContainerRuntime(name, isInstalled)
+		require.NoError(t, err)
+		assert.Equal(t, expected, runtime)
+	}
+
+	assertDetectedRuntime(DockerRuntime{}, "", installed("docker", "podman"))
+	assertDetectedRuntime(PodmanRuntime{}, "", installed("podman", "nerdctl"))
+	assertDetectedRuntime(NerdctlRuntime{}, "", installed("nerdctl"))
+	assertDetectedRuntime(DockerRuntime{}, "", installed())
+	assertDetectedRuntime(PodmanRuntime{}, "podman", installed("docker"))
+	assertDetectedRuntime(NerdctlRuntime{}, "nerdctl", installed("docker"))
+
+	// A misspelled runtime is an error rather than falling back to an installed one.
+	_, err := detectContainerRuntime("podamn", installed("docker", "podman"))
+	assert.Equal(t, UnknownContainerRuntime{Name: "podamn"}, err)
+	assert.Contains(t, err.Error(), ContainerRuntimeEnvVar)
+}
+
+func TestRuntimeCommand(t *testing.T) {
+	t.Parallel()
+
+	command, args := runtimeCommand(PodmanRuntime{}, "run", "--rm", "alpine:3.7")
+	assert.Equal(t, "podman", command)
+	assert.Equal(t, []string{"run", "--rm", "alpine:3.7"}, args)
+
+	command, args = runtimeCommand(NerdctlRuntime{Namespace: "k8s.io"}, "images")
+	assert.Equal(t, "nerdctl", command)
+	assert.Equal(t, []string{"--namespace", "k8s.io", "images"}, args)
+
+	command, args = runtimeCommand(NerdctlRuntime{}, "images")
+	assert.Equal(t, "nerdctl", command)
+	assert.Equal(t, []string{"images"}, args)
+
+	command, args = runtimeCommand(DockerRuntime{}, "compose", "version")
+	assert.Equal(t, "docker", command)
+	assert.Equal(t, []string{"compose", "version"}, args)
+}
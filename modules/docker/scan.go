@@ -0,0 +1,324 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// Severity is the severity of a vulnerability, as reported by the vulnerability scanners.
+type Severity string
+
+// Severities of vulnerabilities, from the least to the most severe.
+const (
+	SeverityUnknown  Severity = "UNKNOWN"
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+// severityRanks orders the severities, from the least to the most severe.
+var severityRanks = map[Severity]int{
+	SeverityUnknown:  0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// AtLeast returns whether the severity is at least as severe as the given one.
+func (severity Severity) AtLeast(other Severity) bool {
+	return severityRanks[severity] >= severityRanks[other]
+}
+
+// Vulnerability is a vulnerability that a scanner found in a package of an image.
+type Vulnerability struct {
+	// ID of the vulnerability, e.g., "CVE-2023-0286"
+	ID string
+
+	// Name of the vulnerable package
+	Package string
+
+	// Version of the package in the image
+	InstalledVersion string
+
+	// Version of the package that fixes the vulnerability, or empty if there is no fix
+	FixedVersion string
+
+	// Severity of the vulnerability
+	Severity Severity
+
+	// Short description of the vulnerability
+	Title string
+}
+
+// String returns a summary of the vulnerability, e.g., "CVE-2023-0286 (HIGH) in openssl 3.0.7-r0, fixed in 3.0.8-r0".
+func (vulnerability Vulnerability) String() string {
+	fixed := "not fixed"
+	if vulnerability.FixedVersion != "" {
+		fixed = "fixed in " + vulnerability.FixedVersion
+	}
+	return fmt.Sprintf("%s (%s) in %s %s, %s", vulnerability.ID, vulnerability.Severity, vulnerability.Package, vulnerability.InstalledVersion, fixed)
+}
+
+// ScanResult is the result of a vulnerability scan of an image.
+type ScanResult struct {
+	// Image that was scanned
+	Image string
+
+	// Vulnerabilities found in the image, after the filters of the ScanOptions, from the most to the least severe
+	Vulnerabilities []Vulnerability
+}
+
+// AtLeast returns the vulnerabilities of the result that are at least as severe as the given severity.
+func (result *ScanResult) AtLeast(severity Severity) []Vulnerability {
+	vulnerabilities := []Vulnerability{}
+	for _, vulnerability := range result.Vulnerabilities {
+		if vulnerability.Severity.AtLeast(severity) {
+			vulnerabilities = append(vulnerabilities, vulnerability)
+		}
+	}
+	return vulnerabilities
+}
+
+// CountBySeverity returns the number of vulnerabilities of the result of each severity.
+func (result *ScanResult) CountBySeverity() map[Severity]int {
+	counts := map[Severity]int{}
+	for _, vulnerability := range result.Vulnerabilities {
+		counts[vulnerability.Severity]++
+	}
+	return counts
+}
+
+// ImageScanner is a vulnerability scanner CLI that ScanImageE can run, such as TrivyScanner or GrypeScanner.
+type ImageScanner interface {
+	// Command returns the command and arguments that scan the given image and write a JSON report to stdout.
+	Command(image string) (string, []string)
+
+	// ParseReport returns the vulnerabilities in the given JSON report.
+	ParseReport(report string) ([]Vulnerability, error)
+}
+
+// ScanOptions defines options for the vulnerability scan of an image.
+type ScanOptions struct {
+	// Scanner to run (default TrivyScanner)
+	Scanner ImageScanner
+
+	// Only report vulnerabilities that are at least as severe as this one (default all)
+	MinSeverity Severity
+
+	// If set to true, only report vulnerabilities that have a fix
+	IgnoreUnfixed bool
+
+	// Additional environment variables to pass in when running the scanner, e.g., credentials to the registry.
+	Env map[string]string
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+}
+
+// TrivyScanner scans images with Trivy (https://trivy.dev).
+type TrivyScanner struct {
+	// Custom CLI options that will be passed as-is to the 'trivy image' command, e.g., "--skip-db-update".
+	OtherOptions []string
+}
+
+// Command returns the 'trivy image' command that scans the given image for vulnerabilities.
+func (scanner TrivyScanner) Command(image string) (string, []string) {
+	args := []string{"image", "--format", "json", "--quiet", "--scanners", "vuln"}
+	args = append(args, scanner.OtherOptions...)
+	return "trivy", append(args, image)
+}
+
+// ParseReport returns the vulnerabilities in the given JSON report of Trivy.
+func (scanner TrivyScanner) ParseReport(report string) ([]Vulnerability, error) {
+	var parsed struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+				Title            string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(report), &parsed); err != nil {
+		return nil, err
+	}
+
+	vulnerabilities := []Vulnerability{}
+	for _, result := range parsed.Results {
+		for _, vulnerability := range result.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, Vulnerability{
+				ID:               vulnerability.VulnerabilityID,
+				Package:          vulnerability.PkgName,
+				InstalledVersion: vulnerability.InstalledVersion,
+				FixedVersion:     vulnerability.FixedVersion,
+				Severity:         parseSeverity(vulnerability.Severity),
+				Title:            vulnerability.Title,
+			})
+		}
+	}
+	return vulnerabilities, nil
+}
+
+// GrypeScanner scans images with Grype (https://github.com/anchore/grype).
+type GrypeScanner struct {
+	// Custom CLI options that will be passed as-is to the 'grype' command, e.g., "--only-fixed".
+	OtherOptions []string
+}
+
+// Command returns the 'grype' command that scans the given image for vulnerabilities.
+func (scanner GrypeScanner) Command(image string) (string, []string) {
+	args := []string{"--output", "json", "--quiet"}
+	args = append(args, scanner.OtherOptions...)
+	return "grype", append(args, image)
+}
+
+// ParseReport returns the vulnerabilities in the given JSON report of Grype.
+func (scanner GrypeScanner) ParseReport(report string) ([]Vulnerability, error) {
+	var parsed struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID          string
+				Severity    string
+				Description string
+				Fix         struct {
+					Versions []string
+				}
+			}
+			Artifact struct {
+				Name    string
+				Version string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(report), &parsed); err != nil {
+		return nil, err
+	}
+
+	vulnerabilities := []Vulnerability{}
+	for _, match := range parsed.Matches {
+		vulnerabilities = append(vulnerabilities, Vulnerability{
+			ID:               match.Vulnerability.ID,
+			Package:          match.Artifact.Name,
+			InstalledVersion: match.Artifact.Version,
+			FixedVersion:     strings.Join(match.Vulnerability.Fix.Versions, ", "),
+			Severity:         parseSeverity(match.Vulnerability.Severity),
+			Title:            match.Vulnerability.Description,
+		})
+	}
+	return vulnerabilities, nil
+}
+
+// ScanImage scans the given image for vulnerabilities with the scanner of the given options and returns the findings.
+// This method fails the test if there are any errors.
+func ScanImage(t testing.TestingT, image string, options *ScanOptions) *ScanResult {
+	result, err := ScanImageE(t, image, options)
+	require.NoError(t, err)
+	return result
+}
+
+// ScanImageE scans the given image for vulnerabilities with the scanner of the given options and returns the findings,
+// filtered by the options. This requires the CLI of the scanner, e.g., trivy, which pulls the image if it is not local.
+func ScanImageE(t testing.TestingT, image string, options *ScanOptions) (*ScanResult, error) {
+	scanner := options.Scanner
+	if scanner == nil {
+		scanner = TrivyScanner{}
+	}
+	command, args := scanner.Command(image)
+
+	options.Logger.Logf(t, "Scanning image %s for vulnerabilities with %s", image, command)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		// The JSON report can be large, don't print it.
+		Logger: logger.Discard,
+		Env:    options.Env,
+	}
+	report, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	vulnerabilities, err := scanner.ParseReport(report)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the %s report of image %s: %s", command, image, err)
+	}
+
+	result := &ScanResult{Image: image, Vulnerabilities: filterVulnerabilities(vulnerabilities, options)}
+	options.Logger.Logf(t, "Found vulnerabilities in image %s: %v", image, result.CountBySeverity())
+	return result, nil
+}
+
+// RequireNoCriticalVulns scans the given image for vulnerabilities and fails the test if there are any critical ones.
+func RequireNoCriticalVulns(t testing.TestingT, image string, options *ScanOptions) {
+	require.NoError(t, RequireNoCriticalVulnsE(t, image, options))
+}
+
+// RequireNoCriticalVulnsE scans the given image for vulnerabilities and returns an error listing the critical ones, if
+// there are any.
+func RequireNoCriticalVulnsE(t testing.TestingT, image string, options *ScanOptions) error {
+	result, err := ScanImageE(t, image, options)
+	if err != nil {
+		return err
+	}
+	return CheckVulnerabilityBudget(result, map[Severity]int{SeverityCritical: 0})
+}
+
+// CheckVulnerabilityBudget returns an error if the given scan result has more vulnerabilities of a severity than the
+// given budget allows, e.g., {SeverityCritical: 0, SeverityHigh: 5}. Severities that are not in the budget are not
+// limited.
+func CheckVulnerabilityBudget(result *ScanResult, budget map[Severity]int) error {
+	counts := result.CountBySeverity()
+	exceeded := []string{}
+	for _, vulnerability := range result.Vulnerabilities {
+		if limit, limited := budget[vulnerability.Severity]; limited && counts[vulnerability.Severity] > limit {
+			exceeded = append(exceeded, vulnerability.String())
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return fmt.Errorf("image %s exceeds its vulnerability budget %v with %v:\n%s", result.Image, budget, counts, strings.Join(exceeded, "\n"))
+}
+
+// filterVulnerabilities returns the given vulnerabilities that match the filters of the given options, from the most
+// to the least severe.
+func filterVulnerabilities(vulnerabilities []Vulnerability, options *ScanOptions) []Vulnerability {
+	filtered := []Vulnerability{}
+	for _, vulnerability := range vulnerabilities {
+		if options.MinSeverity != "" && !vulnerability.Severity.AtLeast(options.MinSeverity) {
+			continue
+		}
+		if options.IgnoreUnfixed && vulnerability.FixedVersion == "" {
+			continue
+		}
+		filtered = append(filtered, vulnerability)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return severityRanks[filtered[i].Severity] > severityRanks[filtered[j].Severity]
+	})
+	return filtered
+}
+
+// parseSeverity converts the severity reported by a scanner, e.g., "High" or "Negligible", to a Severity.
+func parseSeverity(severity string) Severity {
+	parsed := Severity(strings.ToUpper(severity))
+	if parsed == "NEGLIGIBLE" {
+		return SeverityLow
+	}
+	if _, known := severityRanks[parsed]; !known {
+		return SeverityUnknown
+	}
+	return parsed
+}
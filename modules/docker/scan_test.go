@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trivyTestReport = `{
+  "ArtifactName": "alpine:3.17.0",
+  "Results": [
+    {
+      "Target": "alpine:3.17.0 (alpine 3.17.0)",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2023-0286", "PkgName": "libcrypto3", "InstalledVersion": "3.0.7-r0", "FixedVersion": "3.0.8-r0", "Severity": "HIGH", "Title": "openssl: X.400 address type confusion"},
+        {"VulnerabilityID": "CVE-2022-48174", "PkgName": "busybox", "InstalledVersion": "1.35.0-r29", "Severity": "CRITICAL", "Title": "busybox: stack overflow"}
+      ]
+    },
+    {"Target": "usr/local/bin/app"}
+  ]
+}`
+
+const grypeTestReport = `{
+  "matches": [
+    {
+      "vulnerability": {"id": "CVE-2023-0286", "severity": "High", "description": "X.400 address type confusion", "fix": {"versions": ["3.0.8-r0"], "state": "fixed"}},
+      "artifact": {"name": "libcrypto3", "version": "3.0.7-r0"}
+    },
+    {
+      "vulnerability": {"id": "CVE-2022-3996", "severity": "Negligible", "fix": {"versions": [], "state": "not-fixed"}},
+      "artifact": {"name": "libssl3", "version": "3.0.7-r0"}
+    }
+  ]
+}`
+
+func TestTrivyScannerParseReport(t *testing.T) {
+	t.Parallel()
+
+	command, args := TrivyScanner{OtherOptions: []string{"--skip-db-update"}}.Command("alpine:3.17.0")
+	assert.Equal(t, "trivy", command)
+	assert.Equal(t, []string{"image", "--format", "json", "--quiet", "--scanners", "vuln", "--skip-db-update", "alpine:3.17.0"}, args)
+
+	vulnerabilities, err := TrivyScanner{}.ParseReport(trivyTestReport)
+	require.NoError(t, err)
+	assert.Equal(t, []Vulnerability{
+		{ID: "CVE-2023-0286", Package: "libcrypto3", InstalledVersion: "3.0.7-r0", FixedVersion: "3.0.8-r0", Severity: SeverityHigh, Title: "openssl: X.400 address type confusion"},
+		{ID: "CVE-2022-48174", Package: "busybox", InstalledVersion: "1.35.0-r29", Severity: SeverityCritical, Title: "busybox: stack overflow"},
+	}, vulnerabilities)
+
+	_, err = TrivyScanner{}.ParseReport("not json")
+	require.Error(t, err)
+}
+
+func TestGrypeScannerParseReport(t *testing.T) {
+	t.Parallel()
+
+	command, args := GrypeScanner{}.Command("alpine:3.17.0")
+	assert.Equal(t, "grype", command)
+	assert.Equal(t, []string{"--output", "json", "--quiet", "alpine:3.17.0"}, args)
+
+	vulnerabilities, err := GrypeScanner{}.ParseReport(grypeTestReport)
+	require.NoError(t, err)
+	assert.Equal(t, []Vulnerability{
+		{ID: "CVE-2023-0286", Package: "libcrypto3", InstalledVersion: "3.0.7-r0", FixedVersion: "3.0.8-r0", Severity: SeverityHigh, Title: "X.400 address type confusion"},
+		{ID: "CVE-2022-3996", Package: "libssl3", InstalledVersion: "3.0.7-r0", Severity: SeverityLow},
+	}, vulnerabilities)
+}
+
+func TestFilterVulnerabilities(t *testing.T) {
+	t.Parallel()
+
+	vulnerabilities := []Vulnerability{
+		{ID: "low", Severity: SeverityLow, FixedVersion: "1.0.1"},
+		{ID: "critical-unfixed", Severity: SeverityCritical},
+		{ID: "medium", Severity: SeverityMedium, FixedVersion: "1.0.1"},
+		{ID: "critical", Severity: SeverityCritical, FixedVersion: "1.0.1"},
+	}
+
+	ids := func(vulnerabilities []Vulnerability) []string {
+		result := []string{}
+		for _, vulnerability := range vulnerabilities {
+			result = append(result, vulnerability.ID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"critical-unfixed", "critical", "medium", "low"}, ids(filterVulnerabilities(vulnerabilities, &ScanOptions{})))
+	assert.Equal(t, []string{"critical-unfixed", "critical", "medium"}, ids(filterVulnerabilities(vulnerabilities, &ScanOptions{MinSeverity: SeverityMedium})))
+	assert.Equal(t, []string{"critical", "medium", "low"}, ids(filterVulnerabilities(vulnerabilities, &ScanOptions{IgnoreUnfixed: true})))
+
+	result := &ScanResult{Image: "test-image", Vulnerabilities: filterVulnerabilities(vulnerabilities, &ScanOptions{})}
+	assert.Equal(t, []string{"critical-unfixed", "critical"}, ids(result.AtLeast(SeverityHigh)))
+	assert.Equal(t, map[Severity]int{SeverityCritical: 2, SeverityMedium: 1, SeverityLow: 1}, result.CountBySeverity())
+}
+
+func TestCheckVulnerabilityBudget(t *testing.T) {
+	t.Parallel()
+
+	result := &ScanResult{
+		Image: "test-image",
+		Vulnerabilities: []Vulnerability{
+			{ID: "CVE-2022-48174", Package: "busybox", InstalledVersion: "1.35.0-r29", Severity: SeverityCritical},
+			{ID: "CVE-2023-0286", Package: "libcrypto3", InstalledVersion: "3.0.7-r0", FixedVersion: "3.0.8-r0", Severity: SeverityHigh},
+		},
+	}
+
+	assert.NoError(t, CheckVulnerabilityBudget(result, map[Severity]int{SeverityCritical: 1, SeverityHigh: 1}))
+	assert.NoError(t, CheckVulnerabilityBudget(result, map[Severity]int{SeverityMedium: 0}))
+
+	err := CheckVulnerabilityBudget(result, map[Severity]int{SeverityCritical: 0, SeverityHigh: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CVE-2022-48174 (CRITICAL) in busybox 1.35.0-r29, not fixed")
+	assert.NotContains(t, err.Error(), "CVE-2023-0286")
+}
+
+func TestParseSeverity(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, SeverityCritical, parseSeverity("Critical"))
+	assert.Equal(t, SeverityHigh, parseSeverity("HIGH"))
+	assert.Equal(t, SeverityLow, parseSeverity("Negligible"))
+	assert.Equal(t, SeverityUnknown, parseSeverity(""))
+	assert.True(t, SeverityCritical.AtLeast(SeverityHigh))
+	assert.False(t, SeverityLow.AtLeast(SeverityMedium))
+}
@@ -0,0 +1,284 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// byteSizeUnits are the units of the sizes in the output of 'docker stats': decimal for IO, binary for memory.
+var byteSizeUnits = map[string]float64{
+	"B":   1,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// ContainerStats is a snapshot of the resource usage of a container, as returned by 'docker stats'.
+type ContainerStats struct {
+	// ID of the container
+	ID string
+
+	// Name of the container
+	Name string
+
+	// When the snapshot was taken
+	Time time.Time
+
+	// CPU usage of the container, as a percentage of one CPU, so above 100 if it uses more than one
+	CPUPercent float64
+
+	// Memory usage and limit of the container in bytes
+	MemoryUsage uint64
+	MemoryLimit uint64
+
+	// Memory usage of the container as a percentage of its limit
+	MemoryPercent float64
+
+	// Bytes received and sent by the container over the network since it started
+	NetworkRx uint64
+	NetworkTx uint64
+
+	// Bytes read from and written to block devices by the container since it started
+	BlockRead  uint64
+	BlockWrite uint64
+
+	// Number of processes or threads of the container
+	PIDs int
+}
+
+// StatsSamples are snapshots of the resource usage of a container over time, from the oldest to the newest.
+type StatsSamples []ContainerStats
+
+// MaxCPUPercent returns the highest CPU usage of the samples.
+func (samples StatsSamples) MaxCPUPercent() float64 {
+	max := 0.0
+	for _, sample := range samples {
+		if sample.CPUPercent > max {
+			max = sample.CPUPercent
+		}
+	}
+	return max
+}
+
+// AverageCPUPercent returns the average CPU usage of the samples.
+func (samples StatsSamples) AverageCPUPercent() float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, sample := range samples {
+		total += sample.CPUPercent
+	}
+	return total / float64(len(samples))
+}
+
+// MaxMemoryUsage returns the highest memory usage of the samples in bytes.
+func (samples StatsSamples) MaxMemoryUsage() uint64 {
+	var max uint64
+	for _, sample := range samples {
+		if sample.MemoryUsage > max {
+			max = sample.MemoryUsage
+		}
+	}
+	return max
+}
+
+// NetworkIO returns the bytes received and sent by the container over the network between the first and the last
+// sample.
+func (samples StatsSamples) NetworkIO() (uint64, uint64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	first := samples[0]
+	last := samples[len(samples)-1]
+	return subtractCounter(last.NetworkRx, first.NetworkRx), subtractCounter(last.NetworkTx, first.NetworkTx)
+}
+
+// statsOutput defines the output of 'docker stats --format "{{ json . }}"', where the values are human readable.
+type statsOutput struct {
+	ID       string
+	Name     string
+	CPUPerc  string
+	MemUsage string
+	MemPerc  string
+	NetIO    string
+	BlockIO  string
+	PIDs     string
+}
+
+// GetStats runs the 'docker stats' command to take a snapshot of the resource usage of the container with the given
+// ID. This method fails the test if there are any errors.
+func GetStats(t testing.TestingT, id string) *ContainerStats {
+	stats, err := GetStatsE(t, id)
+	require.NoError(t, err)
+	return stats
+}
+
+// GetStatsE runs the 'docker stats' command to take a snapshot of the resource usage of the container with the given
+// ID: its CPU and memory usage, and its network and block IO since it started. This takes a second or two, as Docker
+// measures the CPU usage over an interval.
+func GetStatsE(t testing.TestingT, id string) (*ContainerStats, error) {
+	command, args := runtimeCommand(nil, "stats", "--no-stream", "--format", "{{ json . }}", id)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		// stats is a short-running command that the sampling helper calls repeatedly, don't print the output.
+		Logger: logger.Discard,
+	}
+
+	out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var output statsOutput
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &output); err != nil {
+		return nil, err
+	}
+	stats, err := parseStatsOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the stats of container %s: %s", id, err)
+	}
+	stats.Time = time.Now()
+	return stats, nil
+}
+
+// SampleStats takes snapshots of the resource usage of the container with the given ID for the given duration, one
+// every interval. This method fails the test if there are any errors.
+func SampleStats(t testing.TestingT, id string, duration time.Duration, interval time.Duration) StatsSamples {
+	samples, err := SampleStatsE(t, id, duration, interval)
+	require.NoError(t, err)
+	return samples
+}
+
+// SampleStatsE takes snapshots of the resource usage of the container with the given ID for the given duration, one
+// every interval and at least one, e.g., while a load test runs against it, so that the test can check the peak or
+// average usage:
+//
+//	samples := docker.SampleStats(t, id, time.Minute, 5*time.Second)
+//	assert.Less(t, samples.MaxMemoryUsage(), uint64(256*1024*1024))
+func SampleStatsE(t testing.TestingT, id string, duration time.Duration, interval time.Duration) (StatsSamples, error) {
+	logger.Logf(t, "Sampling stats of container %s for %s every %s", id, duration, interval)
+
+	deadline := time.Now().Add(duration)
+	samples := StatsSamples{}
+	for {
+		start := time.Now()
+		stats, err := GetStatsE(t, id)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, *stats)
+
+		next := start.Add(interval)
+		if next.After(deadline) {
+			break
+		}
+		time.Sleep(time.Until(next))
+	}
+
+	logger.Logf(t, "Container %s used at most %.2f%% CPU and %d bytes of memory over %d samples", id, samples.MaxCPUPercent(), samples.MaxMemoryUsage(), len(samples))
+	return samples, nil
+}
+
+// parseStatsOutput converts the human readable output of 'docker stats' into a ContainerStats.
+func parseStatsOutput(output statsOutput) (*ContainerStats, error) {
+	stats := &ContainerStats{ID: output.ID, Name: output.Name}
+
+	var err error
+	if stats.CPUPercent, err = parsePercent(output.CPUPerc); err != nil {
+		return nil, err
+	}
+	if stats.MemoryPercent, err = parsePercent(output.MemPerc); err != nil {
+		return nil, err
+	}
+	if stats.MemoryUsage, stats.MemoryLimit, err = parseByteSizePair(output.MemUsage); err != nil {
+		return nil, err
+	}
+	if stats.NetworkRx, stats.NetworkTx, err = parseByteSizePair(output.NetIO); err != nil {
+		return nil, err
+	}
+	if stats.BlockRead, stats.BlockWrite, err = parseByteSizePair(output.BlockIO); err != nil {
+		return nil, err
+	}
+	if output.PIDs != "" && output.PIDs != "--" {
+		if stats.PIDs, err = strconv.Atoi(output.PIDs); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// parsePercent parses a percentage, e.g., "12.34%". A stopped container has no usage, shown as "--".
+func parsePercent(value string) (float64, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "%")
+	if value == "" || value == "--" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// parseByteSizePair parses a pair of sizes, e.g., "1.5MiB / 1.944GiB", into bytes.
+func parseByteSizePair(value string) (uint64, uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "--" || value == "-- / --" {
+		return 0, 0, nil
+	}
+	split := strings.Split(value, "/")
+	if len(split) != 2 {
+		return 0, 0, fmt.Errorf("invalid size pair %q", value)
+	}
+	first, err := parseByteSize(split[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	second, err := parseByteSize(split[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return first, second, nil
+}
+
+// parseByteSize parses a size, e.g., "1.5MiB" or "648B", into bytes.
+func parseByteSize(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	unitStart := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if unitStart <= 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	multiplier, ok := byteSizeUnits[strings.TrimSpace(value[unitStart:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit of size %q", value)
+	}
+	number, err := strconv.ParseFloat(value[:unitStart], 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Round(number * multiplier)), nil
+}
+
+// subtractCounter returns the growth of a counter from the given previous value, which is 0 if it was reset, e.g., by
+// a restart of the container.
+func subtractCounter(current uint64, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
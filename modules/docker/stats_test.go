@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleStats(t *testing.T) {
+	t.Parallel()
+
+	options := &RunOptions{
+		Command:    []string{"-c", "while true; do :; done"},
+		Entrypoint: "sh",
+		Detach:     true,
+		OtherOptions: []string{
+			"--memory", "64m",
+		},
+	}
+	id := strings.TrimSpace(Run(t, "alpine:3.7", options))
+	defer removeContainer(t, id)
+
+	stats := GetStats(t, id)
+	assert.True(t, strings.HasPrefix(id, stats.ID))
+	assert.Equal(t, uint64(64*1024*1024), stats.MemoryLimit)
+	assert.NotZero(t, stats.PIDs)
+
+	samples := SampleStats(t, id, 5*time.Second, 2*time.Second)
+	require.NotEmpty(t, samples)
+	assert.Greater(t, samples.MaxCPUPercent(), 10.0)
+	assert.Less(t, samples.MaxMemoryUsage(), uint64(64*1024*1024))
+}
+
+func TestParseStatsOutput(t *testing.T) {
+	t.Parallel()
+
+	stats, err := parseStatsOutput(statsOutput{
+		ID:       "abc123",
+		Name:     "test",
+		CPUPerc:  "150.25%",
+		MemUsage: "1.5MiB / 1GiB",
+		MemPerc:  "0.15%",
+		NetIO:    "1.2kB / 648B",
+		BlockIO:  "0B / 4.1MB",
+		PIDs:     "3",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerStats{
+		ID:            "abc123",
+		Name:          "test",
+		CPUPercent:    150.25,
+		MemoryUsage:   1572864,
+		MemoryLimit:   1073741824,
+		MemoryPercent: 0.15,
+		NetworkRx:     1200,
+		NetworkTx:     648,
+		BlockWrite:    4100000,
+		PIDs:          3,
+	}, stats)
+
+	stats, err = parseStatsOutput(statsOutput{ID: "abc123", CPUPerc: "--", MemUsage: "-- / --", MemPerc: "--", NetIO: "--", BlockIO: "--", PIDs: "--"})
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerStats{ID: "abc123"}, stats)
+
+	_, err = parseStatsOutput(statsOutput{CPUPerc: "1%", MemUsage: "1.5 parsecs / 1GiB"})
+	require.Error(t, err)
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+
+	for value, expected := range map[string]uint64{
+		"0B":       0,
+		"648B":     648,
+		"1.2kB":    1200,
+		"2MB":      2000000,
+		"1KiB":     1024,
+		"1.5MiB":   1572864,
+		"1.944GiB": 2087354106,
+	} {
+		size, err := parseByteSize(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	for _, value := range []string{"", "MiB", "12", "12XB"} {
+		_, err := parseByteSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestStatsSamples(t *testing.T) {
+	t.Parallel()
+
+	samples := StatsSamples{
+		{CPUPercent: 10, MemoryUsage: 100, NetworkRx: 1000, NetworkTx: 500},
+		{CPUPercent: 50, MemoryUsage: 300, NetworkRx: 1500, NetworkTx: 600},
+		{CPUPercent: 30, MemoryUsage: 200, NetworkRx: 4000, NetworkTx: 900},
+	}
+	assert.Equal(t, 50.0, samples.MaxCPUPercent())
+	assert.Equal(t, 30.0, samples.AverageCPUPercent())
+	assert.Equal(t, uint64(300), samples.MaxMemoryUsage())
+	rx, tx := samples.NetworkIO()
+	assert.Equal(t, uint64(3000), rx)
+	assert.Equal(t, uint64(400), tx)
+
+	empty := StatsSamples{}
+	assert.Equal(t, 0.0, empty.AverageCPUPercent())
+	rx, tx = empty.NetworkIO()
+	assert.Zero(t, rx)
+	assert.Zero(t, tx)
+}
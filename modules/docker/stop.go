@@ -14,6 +14,9 @@ type StopOptions struct {
 	// Seconds to wait for stop before killing the container (default 10)
 	Time int
 
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
 	// Set a logger that should be used. See the logger package for more info.
 	Logger *logger.Logger
 }
@@ -35,8 +38,9 @@ func StopE(t testing.TestingT, containers []string, options *StopOptions) (strin
 		return "", err
 	}
 
+	command, args := runtimeCommand(options.Runtime, args...)
 	cmd := shell.Command{
-		Command: "docker",
+		Command: command,
 		Args:    args,
 		Logger:  options.Logger,
 	}
@@ -0,0 +1,251 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// volumeScratchImage is the image of the container that the volume content helpers mount the volume in.
+	volumeScratchImage = "busybox:1.36"
+	// volumeMountPath is where the volume content helpers mount the volume in the scratch container.
+	volumeMountPath = "/volume"
+)
+
+// VolumeOptions defines options that can be passed to the 'docker volume create' command.
+type VolumeOptions struct {
+	// Driver of the volume (default "local")
+	Driver string
+
+	// Options of the driver, e.g., {"type": "tmpfs", "device": "tmpfs"} for the local driver
+	DriverOptions map[string]string
+
+	// Set metadata on the volume, e.g., {"com.example.team": "platform"}
+	Labels map[string]string
+
+	// Container runtime to run the command with (default GetContainerRuntime())
+	Runtime ContainerRuntime
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+}
+
+// VolumeInspect defines the output of the InspectVolume method, with the options returned by 'docker volume inspect'
+type VolumeInspect struct {
+	// Name of the volume
+	Name string
+
+	// Driver of the volume
+	Driver string
+
+	// Path of the volume on the Docker host
+	Mountpoint string
+
+	// Scope of the volume: "local" or "global"
+	Scope string
+
+	// Metadata of the volume
+	Labels map[string]string
+
+	// Options of the driver of the volume
+	Options map[string]string
+}
+
+// CreateVolume runs the 'docker volume create' command to create a volume with the given name and options, and returns
+// its name. This method fails the test if there are any errors.
+func CreateVolume(t testing.TestingT, name string, options *VolumeOptions) string {
+	volume, err := CreateVolumeE(t, name, options)
+	require.NoError(t, err)
+	return volume
+}
+
+// CreateVolumeE runs the 'docker volume create' command to create a volume with the given name and options, and
+// returns its name, which Docker generates if the given name is empty.
+func CreateVolumeE(t testing.TestingT, name string, options *VolumeOptions) (string, error) {
+	options.Logger.Logf(t, "Running 'docker volume create' for volume %s", name)
+
+	command, args := runtimeCommand(options.Runtime, formatDockerVolumeCreateArgs(name, options)...)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  options.Logger,
+	}
+	out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// RemoveVolume runs the 'docker volume rm' command to remove the given volume. This method fails the test if there are
+// any errors.
+func RemoveVolume(t testing.TestingT, volume string, logger *logger.Logger) {
+	require.NoError(t, RemoveVolumeE(t, volume, logger))
+}
+
+// RemoveVolumeE runs the 'docker volume rm' command to remove the given volume, which must not be in use by a
+// container.
+func RemoveVolumeE(t testing.TestingT, volume string, logger *logger.Logger) error {
+	logger.Logf(t, "Running 'docker volume rm' for volume %s", volume)
+
+	command, args := runtimeCommand(nil, "volume", "rm", volume)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		Logger:  logger,
+	}
+	return shell.RunCommandE(t, cmd)
+}
+
+// InspectVolume runs the 'docker volume inspect' command for the given volume and returns a VolumeInspect struct,
+// converted from the output JSON. This method fails the test if there are any errors.
+func InspectVolume(t testing.TestingT, volume string) *VolumeInspect {
+	inspect, err := InspectVolumeE(t, volume)
+	require.NoError(t, err)
+	return inspect
+}
+
+// InspectVolumeE runs the 'docker volume inspect' command for the given volume and returns a VolumeInspect struct,
+// converted from the output JSON, along with any errors.
+func InspectVolumeE(t testing.TestingT, volume string) (*VolumeInspect, error) {
+	command, args := runtimeCommand(nil, "volume", "inspect", volume)
+	cmd := shell.Command{
+		Command: command,
+		Args:    args,
+		// inspect is a short-running command, don't print the output.
+		Logger: logger.Discard,
+	}
+
+	out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []VolumeInspect
+	if err := json.Unmarshal([]byte(out), &volumes); err != nil {
+		return nil, err
+	}
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("no volume found with name %s", volume)
+	}
+	return &volumes[0], nil
+}
+
+// ListVolumeFiles returns the paths of the files in the given volume, relative to its root and sorted. This method
+// fails the test if there are any errors.
+func ListVolumeFiles(t testing.TestingT, volume string) []string {
+	files, err := ListVolumeFilesE(t, volume)
+	require.NoError(t, err)
+	return files
+}
+
+// ListVolumeFilesE returns the paths of the files in the given volume, relative to its root and sorted, which it lists
+// from a scratch container that mounts the volume read-only.
+func ListVolumeFilesE(t testing.TestingT, volume string) ([]string, error) {
+	out, err := runInVolumeE(t, volume, "find", volumeMountPath, "-type", "f")
+	if err != nil {
+		return nil, err
+	}
+	return parseVolumeFileList(out), nil
+}
+
+// ReadVolumeFile returns the content of the file at the given path, relative to the root of the given volume. This
+// method fails the test if there are any errors.
+func ReadVolumeFile(t testing.TestingT, volume string, filePath string) string {
+	content, err := ReadVolumeFileE(t, volume, filePath)
+	require.NoError(t, err)
+	return content
+}
+
+// ReadVolumeFileE returns the content of the file at the given path, relative to the root of the given volume, which
+// it reads from a scratch container that mounts the volume read-only.
+func ReadVolumeFileE(t testing.TestingT, volume string, filePath string) (string, error) {
+	return runInVolumeE(t, volume, "cat", volumeFilePath(filePath))
+}
+
+// AssertVolumeFileContent checks that the file at the given path in the given volume has the given content. This
+// method fails the test if it doesn't.
+func AssertVolumeFileContent(t testing.TestingT, volume string, filePath string, expected string) {
+	require.NoError(t, AssertVolumeFileContentE(t, volume, filePath, expected))
+}
+
+// AssertVolumeFileContentE checks that the file at the given path in the given volume has the given content, e.g., that
+// the data that a container wrote is still there after the container was removed and a new one started.
+func AssertVolumeFileContentE(t testing.TestingT, volume string, filePath string, expected string) error {
+	content, err := ReadVolumeFileE(t, volume, filePath)
+	if err != nil {
+		return err
+	}
+	if content != expected {
+		return fmt.Errorf("file %s in volume %s has content %q, expected %q", filePath, volume, content, expected)
+	}
+	return nil
+}
+
+// runInVolumeE runs the given command in a scratch container that mounts the given volume read-only, and returns its
+// stdout.
+func runInVolumeE(t testing.TestingT, volume string, command ...string) (string, error) {
+	runArgs := append([]string{"run", "--rm", "--volume", volume + ":" + volumeMountPath + ":ro", volumeScratchImage}, command...)
+	binary, args := runtimeCommand(nil, runArgs...)
+	cmd := shell.Command{
+		Command: binary,
+		Args:    args,
+		// The scratch container runs a short-lived command, don't print the output.
+		Logger: logger.Discard,
+	}
+	return shell.RunCommandAndGetStdOutE(t, cmd)
+}
+
+// volumeFilePath returns the path of the file at the given path, relative to the root of a volume, in the scratch
+// container, which is always within the volume.
+func volumeFilePath(filePath string) string {
+	return path.Join(volumeMountPath, path.Clean("/"+filePath))
+}
+
+// parseVolumeFileList converts the paths listed by find in the scratch container to paths relative to the root of the
+// volume.
+func parseVolumeFileList(out string) []string {
+	files := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		files = append(files, strings.TrimPrefix(line, volumeMountPath+"/"))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// formatDockerVolumeCreateArgs formats the arguments for the 'docker volume create' command.
+func formatDockerVolumeCreateArgs(name string, options *VolumeOptions) []string {
+	args := []string{"volume", "create"}
+
+	if options.Driver != "" {
+		args = append(args, "--driver", options.Driver)
+	}
+
+	optionKeys := []string{}
+	for key := range options.DriverOptions {
+		optionKeys = append(optionKeys, key)
+	}
+	sort.Strings(optionKeys)
+	for _, key := range optionKeys {
+		args = append(args, "--opt", key+"="+options.DriverOptions[key])
+	}
+
+	args = append(args, formatLabelArgs(options.Labels)...)
+
+	if name != "" {
+		args = append(args, name)
+	}
+	return args
+}
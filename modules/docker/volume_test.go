@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumeDataPersistsAcrossContainers(t *testing.T) {
+	t.Parallel()
+
+	volume := CreateVolume(t, "terratest-"+strings.ToLower(random.UniqueId()), &VolumeOptions{Labels: map[string]string{"terratest": "true"}})
+	defer RemoveVolume(t, volume, nil)
+
+	inspect := InspectVolume(t, volume)
+	assert.Equal(t, volume, inspect.Name)
+	assert.Equal(t, "local", inspect.Driver)
+	assert.Equal(t, "true", inspect.Labels["terratest"])
+
+	options := &RunOptions{
+		Command:    []string{"-c", `mkdir -p /data/nested && echo -n "Hello, World!" > /data/nested/greeting.txt && touch /data/empty`},
+		Entrypoint: "sh",
+		Volumes:    []string{volume + ":/data"},
+		Remove:     true,
+	}
+	Run(t, "alpine:3.7", options)
+
+	assert.Equal(t, []string{"empty", "nested/greeting.txt"}, ListVolumeFiles(t, volume))
+	AssertVolumeFileContent(t, volume, "nested/greeting.txt", "Hello, World!")
+	AssertVolumeFileContent(t, volume, "/empty", "")
+	require.Error(t, AssertVolumeFileContentE(t, volume, "nested/greeting.txt", "Goodbye"))
+	_, err := ReadVolumeFileE(t, volume, "missing.txt")
+	require.Error(t, err)
+}
+
+func TestFormatDockerVolumeCreateArgs(t *testing.T) {
+	t.Parallel()
+
+	options := &VolumeOptions{
+		Driver:        "local",
+		DriverOptions: map[string]string{"type": "tmpfs", "device": "tmpfs"},
+		Labels:        map[string]string{"terratest": "true"},
+	}
+	assert.Equal(t, []string{
+		"volume", "create",
+		"--driver", "local",
+		"--opt", "device=tmpfs",
+		"--opt", "type=tmpfs",
+		"--label", "terratest=true",
+		"test-volume",
+	}, formatDockerVolumeCreateArgs("test-volume", options))
+	assert.Equal(t, []string{"volume", "create"}, formatDockerVolumeCreateArgs("", &VolumeOptions{}))
+}
+
+func TestVolumeFilePath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/volume/data/file.txt", volumeFilePath("data/file.txt"))
+	assert.Equal(t, "/volume/data/file.txt", volumeFilePath("/data/file.txt"))
+	assert.Equal(t, "/volume/etc/passwd", volumeFilePath("../../etc/passwd"))
+}
+
+func TestParseVolumeFileList(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"empty", "nested/greeting.txt"}, parseVolumeFileList("/volume/nested/greeting.txt\n/volume/empty\n"))
+	assert.Equal(t, []string{}, parseVolumeFileList(""))
+}
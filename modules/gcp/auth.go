@@ -0,0 +1,345 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	gcrgoogle "github.com/google/go-containerregistry/pkg/v1/google"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// ImpersonateServiceAccountEnvVar is the environment variable through which the email of a service account to
+// impersonate may be passed, as for the Terraform Google provider. When it is set, all the clients of this module make
+// their calls as that service account, using short-lived tokens minted from the default (or federated) credentials,
+// which must have the roles/iam.serviceAccountTokenCreator role on the service account. This allows running tests with
+// the exact permissions of the service account production automation uses.
+const ImpersonateServiceAccountEnvVar = "GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"
+
+// cloudPlatformScope is the OAuth2 scope that grants access to all the Google Cloud APIs.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+const (
+	// stsTokenURL is the URL of the Security Token Service, which exchanges external tokens for Google access tokens.
+	stsTokenURL = "https://sts.googleapis.com/v1/token"
+
+	// jwtSubjectTokenType is the type of the OIDC ID tokens exchanged with the Security Token Service.
+	jwtSubjectTokenType = "urn:ietf:params:oauth:token-type:jwt"
+
+	// serviceAccountImpersonationURLFormat is the URL through which the Security Token Service impersonates a service
+	// account, given its email.
+	serviceAccountImpersonationURLFormat = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+)
+
+// AuthOptions configures the clients of this module to authenticate with Workload Identity Federation, exchanging an
+// OIDC ID token issued by an external identity provider (e.g., a CI system) for Google credentials, instead of using
+// the default credentials. Exactly one of SubjectTokenFile and SubjectTokenURL must be set. Note that an external
+// account credential configuration file created with gcloud can also be passed through GOOGLE_APPLICATION_CREDENTIALS,
+// as the default credentials support them.
+type AuthOptions struct {
+	// WorkloadIdentityProvider is the Workload Identity Federation provider to exchange the ID token with, either as its
+	// resource name (projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider>) or as the
+	// audience of the Security Token Service (the resource name prefixed with //iam.googleapis.com/).
+	WorkloadIdentityProvider string
+
+	// ServiceAccountEmail is the email of the service account to impersonate with the federated credentials. If it is
+	// empty, the federated identity is used directly, which must have been granted access to the resources under test.
+	ServiceAccountEmail string
+
+	// SubjectTokenFile is the path of a file to read the ID token from.
+	SubjectTokenFile string
+
+	// SubjectTokenURL is a URL to fetch the ID token from, with an HTTP GET request with the given headers.
+	SubjectTokenURL        string
+	SubjectTokenURLHeaders map[string]string
+
+	// SubjectTokenFieldName is the name of the field of the JSON object that holds the ID token, if the file or the
+	// response of the URL is a JSON object rather than the raw token.
+	SubjectTokenFieldName string
+}
+
+var (
+	authOptions      *AuthOptions
+	authOptionsMutex sync.RWMutex
+)
+
+// SetAuthOptions configures all the clients of this module to authenticate with the given Workload Identity
+// Federation options, which applies to all the tests running in the process. Passing nil restores the default
+// credentials. If ImpersonateServiceAccountEnvVar is also set, the federated credentials are used to impersonate that
+// service account.
+func SetAuthOptions(options *AuthOptions) {
+	authOptionsMutex.Lock()
+	defer authOptionsMutex.Unlock()
+	authOptions = options
+}
+
+// getAuthOptions returns the Workload Identity Federation options set with SetAuthOptions, or nil if the default
+// credentials should be used.
+func getAuthOptions() *AuthOptions {
+	authOptionsMutex.RLock()
+	defer authOptionsMutex.RUnlock()
+	return authOptions
+}
+
+// NewGitHubActionsAuthOptions returns the options to authenticate with Workload Identity Federation from a GitHub
+// Actions workflow. This will fail the test if the workflow can't request ID tokens.
+func NewGitHubActionsAuthOptions(t testing.TestingT, workloadIdentityProvider string, serviceAccountEmail string) *AuthOptions {
+	options, err := NewGitHubActionsAuthOptionsE(t, workloadIdentityProvider, serviceAccountEmail)
+	require.NoError(t, err)
+	return options
+}
+
+// NewGitHubActionsAuthOptionsE returns the options to authenticate with Workload Identity Federation from a GitHub
+// Actions workflow, which fetch ID tokens from GitHub with the default audience of Workload Identity Federation
+// providers. The workflow (or job) must have the id-token: write permission, e.g.:
+//
+//	gcp.SetAuthOptions(gcp.NewGitHubActionsAuthOptions(t, "projects/123456789/locations/global/workloadIdentityPools/github/providers/my-repo", "ci@my-project.iam.gserviceaccount.com"))
+func NewGitHubActionsAuthOptionsE(t testing.TestingT, workloadIdentityProvider string, serviceAccountEmail string) (*AuthOptions, error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return nil, errors.New("ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN are not set: make sure the workflow has the id-token: write permission")
+	}
+
+	return newGitHubActionsAuthOptions(requestURL, requestToken, workloadIdentityProvider, serviceAccountEmail), nil
+}
+
+// newGitHubActionsAuthOptions returns the options to fetch ID tokens from the given GitHub Actions token endpoint, for
+// the default audience of the given Workload Identity Federation provider.
+func newGitHubActionsAuthOptions(requestURL string, requestToken string, workloadIdentityProvider string, serviceAccountEmail string) *AuthOptions {
+	audience := "https:" + getWorkloadIdentityAudience(workloadIdentityProvider)
+	separator := "&"
+	if !strings.Contains(requestURL, "?") {
+		separator = "?"
+	}
+
+	return &AuthOptions{
+		WorkloadIdentityProvider: workloadIdentityProvider,
+		ServiceAccountEmail:      serviceAccountEmail,
+		SubjectTokenURL:          requestURL + separator + "audience=" + url.QueryEscape(audience),
+		SubjectTokenURLHeaders:   map[string]string{"Authorization": "Bearer " + requestToken},
+		SubjectTokenFieldName:    "value",
+	}
+}
+
+// getWorkloadIdentityAudience returns the audience of the Security Token Service for the given Workload Identity
+// Federation provider, which may be given as its resource name or already as an audience.
+func getWorkloadIdentityAudience(workloadIdentityProvider string) string {
+	if strings.HasPrefix(workloadIdentityProvider, "//") {
+		return workloadIdentityProvider
+	}
+	return "//iam.googleapis.com/" + strings.TrimPrefix(workloadIdentityProvider, "/")
+}
+
+// externalAccountCredentials is the external account credential configuration, in the format of the files created by
+// gcloud iam workload-identity-pools create-cred-config.
+type externalAccountCredentials struct {
+	Type                           string                          `json:"type"`
+	Audience                       string                          `json:"audience"`
+	SubjectTokenType               string                          `json:"subject_token_type"`
+	TokenURL                       string                          `json:"token_url"`
+	ServiceAccountImpersonationURL string                          `json:"service_account_impersonation_url,omitempty"`
+	CredentialSource               externalAccountCredentialSource `json:"credential_source"`
+}
+
+type externalAccountCredentialSource struct {
+	File    string                                 `json:"file,omitempty"`
+	URL     string                                 `json:"url,omitempty"`
+	Headers map[string]string                      `json:"headers,omitempty"`
+	Format  *externalAccountCredentialSourceFormat `json:"format,omitempty"`
+}
+
+type externalAccountCredentialSourceFormat struct {
+	Type                  string `json:"type"`
+	SubjectTokenFieldName string `json:"subject_token_field_name"`
+}
+
+// newExternalAccountCredentialsJSON returns the external account credential configuration for the given options.
+func newExternalAccountCredentialsJSON(options *AuthOptions) ([]byte, error) {
+	if options.WorkloadIdentityProvider == "" {
+		return nil, errors.New("AuthOptions.WorkloadIdentityProvider must be set")
+	}
+	if (options.SubjectTokenFile == "") == (options.SubjectTokenURL == "") {
+		return nil, errors.New("exactly one of AuthOptions.SubjectTokenFile and AuthOptions.SubjectTokenURL must be set")
+	}
+
+	credentials := externalAccountCredentials{
+		Type:             "external_account",
+		Audience:         getWorkloadIdentityAudience(options.WorkloadIdentityProvider),
+		SubjectTokenType: jwtSubjectTokenType,
+		TokenURL:         stsTokenURL,
+		CredentialSource: externalAccountCredentialSource{
+			File:    options.SubjectTokenFile,
+			URL:     options.SubjectTokenURL,
+			Headers: options.SubjectTokenURLHeaders,
+		},
+	}
+	if options.ServiceAccountEmail != "" {
+		credentials.ServiceAccountImpersonationURL = fmt.Sprintf(serviceAccountImpersonationURLFormat, options.ServiceAccountEmail)
+	}
+	if options.SubjectTokenFieldName != "" {
+		credentials.CredentialSource.Format = &externalAccountCredentialSourceFormat{
+			Type:                  "json",
+			SubjectTokenFieldName: options.SubjectTokenFieldName,
+		}
+	}
+
+	return json.Marshal(credentials)
+}
+
+// getImpersonatedServiceAccount returns the email of the service account to impersonate, or an empty string if the
+// default credentials should be used as they are.
+func getImpersonatedServiceAccount() string {
+	return os.Getenv(ImpersonateServiceAccountEnvVar)
+}
+
+// usesDefaultCredentials returns true if the clients of this module should use the default credentials as they are,
+// i.e., without Workload Identity Federation or impersonation.
+func usesDefaultCredentials() bool {
+	return getAuthOptions() == nil && getImpersonatedServiceAccount() == ""
+}
+
+// newBaseClientOptionsE returns the options with which the clients that impersonate service accounts authenticate,
+// i.e., the federated credentials if there are Workload Identity Federation options. Otherwise, there are no options,
+// so they use the default credentials.
+func newBaseClientOptionsE(ctx context.Context) ([]option.ClientOption, error) {
+	options := getAuthOptions()
+	if options == nil {
+		return nil, nil
+	}
+	tokenSource, err := newExternalAccountTokenSourceE(ctx, options, cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	return []option.ClientOption{option.WithTokenSource(tokenSource)}, nil
+}
+
+// newExternalAccountTokenSourceE returns a source of OAuth2 access tokens with the given scopes, which exchanges ID
+// tokens for Google credentials with Workload Identity Federation per the given options.
+func newExternalAccountTokenSourceE(ctx context.Context, options *AuthOptions, scopes ...string) (oauth2.TokenSource, error) {
+	credentialsJSON, err := newExternalAccountCredentialsJSON(options)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := google.CredentialsFromJSON(ctx, credentialsJSON, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Workload Identity Federation credentials: %v", err)
+	}
+	return credentials.TokenSource, nil
+}
+
+// newTokenSourceE returns a source of OAuth2 access tokens with the given scopes, for the impersonated service account
+// if there is one, or for the federated or default credentials otherwise.
+func newTokenSourceE(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	serviceAccount := getImpersonatedServiceAccount()
+	if serviceAccount == "" {
+		if options := getAuthOptions(); options != nil {
+			return newExternalAccountTokenSourceE(ctx, options, scopes...)
+		}
+		return google.DefaultTokenSource(ctx, scopes...)
+	}
+	baseOpts, err := newBaseClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: serviceAccount,
+		Scopes:          scopes,
+	}, baseOpts...)
+}
+
+// newClientOptionsE returns the options to create Google API clients with, which authenticate as the impersonated
+// service account or with the federated credentials if there are any. Otherwise, there are no options, so the clients
+// use the default credentials.
+func newClientOptionsE(ctx context.Context) ([]option.ClientOption, error) {
+	if usesDefaultCredentials() {
+		return nil, nil
+	}
+	tokenSource, err := newTokenSourceE(ctx, cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	return []option.ClientOption{option.WithTokenSource(tokenSource)}, nil
+}
+
+// newHttpClientE returns an HTTP client that authenticates its requests with access tokens with the given scopes, for
+// the impersonated service account or the federated credentials if there are any.
+func newHttpClientE(ctx context.Context, scopes ...string) (*http.Client, error) {
+	if usesDefaultCredentials() {
+		return google.DefaultClient(ctx, scopes...)
+	}
+	tokenSource, err := newTokenSourceE(ctx, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
+// newIDTokenClientE returns an HTTP client that authenticates its requests with ID tokens for the given audience, for
+// the impersonated service account if there is one. With Workload Identity Federation, the ID tokens are for the
+// service account of the AuthOptions, as federated identities can't get ID tokens themselves.
+func newIDTokenClientE(ctx context.Context, audience string) (*http.Client, error) {
+	if usesDefaultCredentials() {
+		return idtoken.NewClient(ctx, audience)
+	}
+
+	var baseOpts []option.ClientOption
+	serviceAccount := getImpersonatedServiceAccount()
+	if serviceAccount == "" {
+		// Get the ID tokens with the federated identity itself, so that it only needs the
+		// roles/iam.workloadIdentityUser role on the service account.
+		options := *getAuthOptions()
+		serviceAccount = options.ServiceAccountEmail
+		if serviceAccount == "" {
+			return nil, errors.New("ID tokens can't be created for federated identities: set AuthOptions.ServiceAccountEmail or " + ImpersonateServiceAccountEnvVar)
+		}
+		options.ServiceAccountEmail = ""
+		tokenSource, err := newExternalAccountTokenSourceE(ctx, &options, cloudPlatformScope)
+		if err != nil {
+			return nil, err
+		}
+		baseOpts = []option.ClientOption{option.WithTokenSource(tokenSource)}
+	} else {
+		opts, err := newBaseClientOptionsE(ctx)
+		if err != nil {
+			return nil, err
+		}
+		baseOpts = opts
+	}
+
+	tokenSource, err := impersonate.IDTokenSource(ctx, impersonate.IDTokenConfig{
+		Audience:        audience,
+		TargetPrincipal: serviceAccount,
+		IncludeEmail:    true,
+	}, baseOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
+// newGcrAuthenticatorE returns an authenticator for Container Registry, which authenticates as the impersonated
+// service account or with the federated credentials if there are any.
+func newGcrAuthenticatorE(ctx context.Context) (authn.Authenticator, error) {
+	if usesDefaultCredentials() {
+		return gcrgoogle.NewEnvAuthenticator()
+	}
+	tokenSource, err := newTokenSourceE(ctx, cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	return gcrgoogle.NewTokenSourceAuthenticator(tokenSource), nil
+}
@@ -0,0 +1,65 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientOptionsWithoutImpersonation(t *testing.T) {
+	t.Setenv(ImpersonateServiceAccountEnvVar, "")
+
+	opts, err := newClientOptionsE(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, opts)
+}
+
+func TestNewExternalAccountCredentialsJSON(t *testing.T) {
+	t.Parallel()
+
+	credentialsJSON, err := newExternalAccountCredentialsJSON(&AuthOptions{
+		WorkloadIdentityProvider: "projects/123456789/locations/global/workloadIdentityPools/ci/providers/gitlab",
+		ServiceAccountEmail:      "ci@my-project.iam.gserviceaccount.com",
+		SubjectTokenFile:         "/tmp/oidc-token",
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "external_account",
+		"audience": "//iam.googleapis.com/projects/123456789/locations/global/workloadIdentityPools/ci/providers/gitlab",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url": "https://sts.googleapis.com/v1/token",
+		"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/ci@my-project.iam.gserviceaccount.com:generateAccessToken",
+		"credential_source": {"file": "/tmp/oidc-token"}
+	}`, string(credentialsJSON))
+
+	_, err = newExternalAccountCredentialsJSON(&AuthOptions{WorkloadIdentityProvider: "projects/123456789/locations/global/workloadIdentityPools/ci/providers/gitlab"})
+	assert.Error(t, err)
+	_, err = newExternalAccountCredentialsJSON(&AuthOptions{SubjectTokenFile: "/tmp/oidc-token"})
+	assert.Error(t, err)
+}
+
+func TestNewGitHubActionsAuthOptions(t *testing.T) {
+	t.Parallel()
+
+	options := newGitHubActionsAuthOptions(
+		"https://token.actions.githubusercontent.com/request?api-version=2.0",
+		"request-token",
+		"projects/123456789/locations/global/workloadIdentityPools/github/providers/my-repo",
+		"",
+	)
+	assert.Equal(t, "https://token.actions.githubusercontent.com/request?api-version=2.0&audience=https%3A%2F%2Fiam.googleapis.com%2Fprojects%2F123456789%2Flocations%2Fglobal%2FworkloadIdentityPools%2Fgithub%2Fproviders%2Fmy-repo", options.SubjectTokenURL)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer request-token"}, options.SubjectTokenURLHeaders)
+	assert.Equal(t, "value", options.SubjectTokenFieldName)
+
+	credentialsJSON, err := newExternalAccountCredentialsJSON(options)
+	require.NoError(t, err)
+	assert.Contains(t, string(credentialsJSON), `"format":{"type":"json","subject_token_field_name":"value"}`)
+	assert.NotContains(t, string(credentialsJSON), "service_account_impersonation_url")
+}
@@ -0,0 +1,443 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/bigquery/v2"
+)
+
+// bigQueryQueryTimeout is how long each call to the BigQuery API waits for a query to complete before returning, after
+// which the results are requested again.
+const bigQueryQueryTimeout = 10 * time.Second
+
+// AssertBigQueryDatasetExists checks if the given BigQuery dataset exists and fails the test if it does not.
+func AssertBigQueryDatasetExists(t testing.TestingT, projectID string, datasetID string) {
+	err := AssertBigQueryDatasetExistsE(t, projectID, datasetID)
+	require.NoError(t, err)
+}
+
+// AssertBigQueryDatasetExistsE checks if the given BigQuery dataset exists and returns an error if it does not.
+func AssertBigQueryDatasetExistsE(t testing.TestingT, projectID string, datasetID string) error {
+	logger.Logf(t, "Finding BigQuery dataset %s", datasetID)
+
+	ctx := context.Background()
+	service, err := NewBigQueryServiceE(t)
+	if err != nil {
+		return err
+	}
+
+	if _, err := service.Datasets.Get(projectID, datasetID).Context(ctx).Do(); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("BigQuery dataset %s does not exist in project %s", datasetID, projectID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetBigQueryTable gets the given BigQuery table. This will fail the test if there is an error.
+func GetBigQueryTable(t testing.TestingT, projectID string, datasetID string, tableID string) *bigquery.Table {
+	table, err := GetBigQueryTableE(t, projectID, datasetID, tableID)
+	require.NoError(t, err)
+	return table
+}
+
+// GetBigQueryTableE gets the given BigQuery table.
+func GetBigQueryTableE(t testing.TestingT, projectID string, datasetID string, tableID string) (*bigquery.Table, error) {
+	ctx := context.Background()
+	service, err := NewBigQueryServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := service.Tables.Get(projectID, datasetID, tableID).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("BigQuery table %s does not exist in dataset %s of project %s", tableID, datasetID, projectID)
+		}
+		return nil, fmt.Errorf("GetBigQueryTableE.Get(%s, %s, %s) got error: %v", projectID, datasetID, tableID, err)
+	}
+
+	return table, nil
+}
+
+// AssertBigQueryTableExists checks if the given BigQuery table exists and fails the test if it does not.
+func AssertBigQueryTableExists(t testing.TestingT, projectID string, datasetID string, tableID string) {
+	err := AssertBigQueryTableExistsE(t, projectID, datasetID, tableID)
+	require.NoError(t, err)
+}
+
+// AssertBigQueryTableExistsE checks if the given BigQuery table exists and returns an error if it does not.
+func AssertBigQueryTableExistsE(t testing.TestingT, projectID string, datasetID string, tableID string) error {
+	logger.Logf(t, "Finding BigQuery table %s in dataset %s", tableID, datasetID)
+
+	_, err := GetBigQueryTableE(t, projectID, datasetID, tableID)
+	return err
+}
+
+// GetBigQueryTableSchema returns the type of each column of the given BigQuery table. This will fail the test if there
+// is an error.
+func GetBigQueryTableSchema(t testing.TestingT, projectID string, datasetID string, tableID string) map[string]string {
+	schema, err := GetBigQueryTableSchemaE(t, projectID, datasetID, tableID)
+	require.NoError(t, err)
+	return schema
+}
+
+// GetBigQueryTableSchemaE returns the type of each column of the given BigQuery table, keyed by the name of the column.
+// The columns of a RECORD are keyed by their path, e.g., address.city, and the types are normalized to their legacy
+// names, e.g., INTEGER instead of INT64, as that is how BigQuery reports them.
+func GetBigQueryTableSchemaE(t testing.TestingT, projectID string, datasetID string, tableID string) (map[string]string, error) {
+	table, err := GetBigQueryTableE(t, projectID, datasetID, tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := map[string]string{}
+	if table.Schema != nil {
+		flattenBigQuerySchema("", table.Schema.Fields, schema)
+	}
+	return schema, nil
+}
+
+// flattenBigQuerySchema adds the type of each of the given fields, and of the fields nested in them, to the given schema.
+func flattenBigQuerySchema(prefix string, fields []*bigquery.TableFieldSchema, schema map[string]string) {
+	for _, field := range fields {
+		name := prefix + field.Name
+		schema[name] = normalizeBigQueryType(field.Type)
+		flattenBigQuerySchema(name+".", field.Fields, schema)
+	}
+}
+
+// normalizeBigQueryType returns the legacy name of the given BigQuery type, which is the name the API reports.
+func normalizeBigQueryType(fieldType string) string {
+	switch strings.ToUpper(fieldType) {
+	case "INT64":
+		return "INTEGER"
+	case "FLOAT64":
+		return "FLOAT"
+	case "BOOL":
+		return "BOOLEAN"
+	case "STRUCT":
+		return "RECORD"
+	}
+	return strings.ToUpper(fieldType)
+}
+
+// AssertBigQueryTableSchema checks that the columns of the given BigQuery table have the expected types. This will fail
+// the test if they do not.
+func AssertBigQueryTableSchema(t testing.TestingT, projectID string, datasetID string, tableID string, expectedSchema map[string]string) {
+	err := AssertBigQueryTableSchemaE(t, projectID, datasetID, tableID, expectedSchema)
+	require.NoError(t, err)
+}
+
+// AssertBigQueryTableSchemaE checks that the columns of the given BigQuery table have the expected types, where
+// expectedSchema is the type of each column keyed by the name of the column, with the columns of a RECORD keyed by their
+// path, e.g.:
+//
+//	gcp.AssertBigQueryTableSchemaE(t, projectID, "analytics", "events", map[string]string{
+//		"event_id":     "STRING",
+//		"event_time":   "TIMESTAMP",
+//		"user":         "RECORD",
+//		"user.user_id": "INT64",
+//	})
+//
+// Columns of the table that are not in expectedSchema are ignored.
+func AssertBigQueryTableSchemaE(t testing.TestingT, projectID string, datasetID string, tableID string, expectedSchema map[string]string) error {
+	schema, err := GetBigQueryTableSchemaE(t, projectID, datasetID, tableID)
+	if err != nil {
+		return err
+	}
+	return checkBigQueryTableSchema(tableID, schema, expectedSchema)
+}
+
+// checkBigQueryTableSchema returns an error if any of the expected columns is missing from the given schema or has a
+// different type.
+func checkBigQueryTableSchema(tableID string, schema map[string]string, expectedSchema map[string]string) error {
+	for column, expectedType := range expectedSchema {
+		actualType, exists := schema[column]
+		if !exists {
+			return fmt.Errorf("BigQuery table %s has no column %s", tableID, column)
+		}
+		if actualType != normalizeBigQueryType(expectedType) {
+			return fmt.Errorf("BigQuery table %s has column %s of type %s instead of %s", tableID, column, actualType, expectedType)
+		}
+	}
+	return nil
+}
+
+// AssertBigQueryTablePartitioning checks that the given BigQuery table is partitioned as expected. This will fail the
+// test if it is not.
+func AssertBigQueryTablePartitioning(t testing.TestingT, projectID string, datasetID string, tableID string, expectedType string, expectedField string) {
+	err := AssertBigQueryTablePartitioningE(t, projectID, datasetID, tableID, expectedType, expectedField)
+	require.NoError(t, err)
+}
+
+// AssertBigQueryTablePartitioningE checks that the given BigQuery table is partitioned as expected, where expectedType
+// is the granularity of time-unit partitioning (HOUR, DAY, MONTH or YEAR), or RANGE for integer-range partitioning, and
+// expectedField is the column the table is partitioned by, or empty for ingestion-time partitioning.
+func AssertBigQueryTablePartitioningE(t testing.TestingT, projectID string, datasetID string, tableID string, expectedType string, expectedField string) error {
+	table, err := GetBigQueryTableE(t, projectID, datasetID, tableID)
+	if err != nil {
+		return err
+	}
+	return checkBigQueryTablePartitioning(table, expectedType, expectedField)
+}
+
+// checkBigQueryTablePartitioning returns an error if the given table is not partitioned as expected.
+func checkBigQueryTablePartitioning(table *bigquery.Table, expectedType string, expectedField string) error {
+	partitionType, partitionField := "", ""
+	switch {
+	case table.TimePartitioning != nil:
+		partitionType, partitionField = table.TimePartitioning.Type, table.TimePartitioning.Field
+	case table.RangePartitioning != nil:
+		partitionType, partitionField = "RANGE", table.RangePartitioning.Field
+	default:
+		return fmt.Errorf("BigQuery table %s is not partitioned", table.Id)
+	}
+
+	if !strings.EqualFold(partitionType, expectedType) || partitionField != expectedField {
+		return fmt.Errorf("BigQuery table %s is partitioned by %s on %q instead of %s on %q", table.Id, partitionType, partitionField, expectedType, expectedField)
+	}
+	return nil
+}
+
+// AssertBigQueryTableClustering checks that the given BigQuery table is clustered by the expected columns, in order.
+// This will fail the test if it is not.
+func AssertBigQueryTableClustering(t testing.TestingT, projectID string, datasetID string, tableID string, expectedFields []string) {
+	err := AssertBigQueryTableClusteringE(t, projectID, datasetID, tableID, expectedFields)
+	require.NoError(t, err)
+}
+
+// AssertBigQueryTableClusteringE checks that the given BigQuery table is clustered by the expected columns, in order.
+func AssertBigQueryTableClusteringE(t testing.TestingT, projectID string, datasetID string, tableID string, expectedFields []string) error {
+	table, err := GetBigQueryTableE(t, projectID, datasetID, tableID)
+	if err != nil {
+		return err
+	}
+
+	var fields []string
+	if table.Clustering != nil {
+		fields = table.Clustering.Fields
+	}
+	if len(fields) != len(expectedFields) || (len(fields) > 0 && !reflect.DeepEqual(fields, expectedFields)) {
+		return fmt.Errorf("BigQuery table %s is clustered by %v instead of %v", tableID, fields, expectedFields)
+	}
+	return nil
+}
+
+// RunBigQueryQuery runs the given standard SQL query in the given project and returns the rows of its result. This will
+// fail the test if there is an error.
+func RunBigQueryQuery(t testing.TestingT, projectID string, query string) []map[string]interface{} {
+	rows, err := RunBigQueryQueryE(t, projectID, query)
+	require.NoError(t, err)
+	return rows
+}
+
+// RunBigQueryQueryE runs the given standard SQL query in the given project, waits for it to complete and returns all
+// the rows of its result. Each row maps the name of each column to its value, converted to a Go type according to the
+// type of the column:
+//
+//   - INTEGER columns are int64, FLOAT columns are float64 and BOOLEAN columns are bool.
+//   - TIMESTAMP columns are time.Time, in UTC.
+//   - BYTES columns are []byte.
+//   - RECORD columns are map[string]interface{}, and REPEATED columns are []interface{}.
+//   - Columns of any other type, e.g., NUMERIC, DATE or DATETIME, are strings as formatted by BigQuery.
+//   - NULL values are nil.
+func RunBigQueryQueryE(t testing.TestingT, projectID string, query string) ([]map[string]interface{}, error) {
+	logger.Logf(t, "Running BigQuery query in project %s: %s", projectID, query)
+
+	ctx := context.Background()
+	service, err := NewBigQueryServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	useLegacySql := false
+	resp, err := service.Jobs.Query(projectID, &bigquery.QueryRequest{
+		Query:        query,
+		UseLegacySql: &useLegacySql,
+		TimeoutMs:    bigQueryQueryTimeout.Milliseconds(),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("RunBigQueryQueryE.Query(%s) got error: %v", projectID, err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("BigQuery query failed: %s", resp.Errors[0].Message)
+	}
+
+	schema, tableRows, pageToken, complete := resp.Schema, resp.Rows, resp.PageToken, resp.JobComplete
+	for !complete || pageToken != "" {
+		var results *bigquery.GetQueryResultsResponse
+		call := service.Jobs.GetQueryResults(projectID, resp.JobReference.JobId).
+			Location(resp.JobReference.Location).
+			TimeoutMs(bigQueryQueryTimeout.Milliseconds())
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		results, err = call.Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("RunBigQueryQueryE.GetQueryResults(%s, %s) got error: %v", projectID, resp.JobReference.JobId, err)
+		}
+		if len(results.Errors) > 0 {
+			return nil, fmt.Errorf("BigQuery query failed: %s", results.Errors[0].Message)
+		}
+		if !results.JobComplete {
+			continue
+		}
+		if !complete {
+			// The first page of the results comes with the response that completes the query.
+			schema, complete = results.Schema, true
+		}
+		tableRows = append(tableRows, results.Rows...)
+		pageToken = results.PageToken
+	}
+
+	if schema == nil {
+		return []map[string]interface{}{}, nil
+	}
+	return newBigQueryRows(schema.Fields, tableRows)
+}
+
+// newBigQueryRows converts the given rows of a query result to maps from the name of each column to its typed value.
+func newBigQueryRows(fields []*bigquery.TableFieldSchema, tableRows []*bigquery.TableRow) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0, len(tableRows))
+	for _, tableRow := range tableRows {
+		row, err := newBigQueryRecord(fields, tableRow.F)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// newBigQueryRecord converts the given cells of a row or RECORD value to a map from the name of each field to its typed
+// value.
+func newBigQueryRecord(fields []*bigquery.TableFieldSchema, cells []*bigquery.TableCell) (map[string]interface{}, error) {
+	if len(cells) != len(fields) {
+		return nil, fmt.Errorf("BigQuery returned %d values for %d columns", len(cells), len(fields))
+	}
+
+	record := map[string]interface{}{}
+	for i, field := range fields {
+		value, err := newBigQueryValue(field, cells[i].V)
+		if err != nil {
+			return nil, err
+		}
+		record[field.Name] = value
+	}
+	return record, nil
+}
+
+// newBigQueryValue converts the given raw value of the given field, as returned in the JSON response of the API, to a Go
+// type.
+func newBigQueryValue(field *bigquery.TableFieldSchema, raw interface{}) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	if field.Mode == "REPEATED" {
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("BigQuery returned %v for REPEATED column %s", raw, field.Name)
+		}
+		itemField := *field
+		itemField.Mode = ""
+		values := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			cell, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("BigQuery returned %v for an item of column %s", item, field.Name)
+			}
+			value, err := newBigQueryValue(&itemField, cell["v"])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+
+	if normalizeBigQueryType(field.Type) == "RECORD" {
+		record, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("BigQuery returned %v for RECORD column %s", raw, field.Name)
+		}
+		items, _ := record["f"].([]interface{})
+		cells := make([]*bigquery.TableCell, 0, len(items))
+		for _, item := range items {
+			cell, _ := item.(map[string]interface{})
+			cells = append(cells, &bigquery.TableCell{V: cell["v"]})
+		}
+		return newBigQueryRecord(field.Fields, cells)
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("BigQuery returned %v for %s column %s", raw, field.Type, field.Name)
+	}
+	return parseBigQueryValue(field, value)
+}
+
+// parseBigQueryValue parses the given value of the given scalar field, which BigQuery always returns as a string.
+func parseBigQueryValue(field *bigquery.TableFieldSchema, value string) (interface{}, error) {
+	var parsed interface{}
+	var err error
+
+	switch normalizeBigQueryType(field.Type) {
+	case "INTEGER":
+		parsed, err = strconv.ParseInt(value, 10, 64)
+	case "FLOAT":
+		parsed, err = strconv.ParseFloat(value, 64)
+	case "BOOLEAN":
+		parsed, err = strconv.ParseBool(value)
+	case "BYTES":
+		parsed, err = base64.StdEncoding.DecodeString(value)
+	case "TIMESTAMP":
+		// Timestamps are returned as the number of seconds since the epoch, e.g., 1.672531200123456E9.
+		var seconds float64
+		seconds, err = strconv.ParseFloat(value, 64)
+		parsed = time.UnixMicro(int64(math.Round(seconds * 1e6))).UTC()
+	default:
+		return value, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse value %s of %s column %s: %v", value, field.Type, field.Name, err)
+	}
+	return parsed, nil
+}
+
+// NewBigQueryService creates a new BigQuery service, which is used to make BigQuery API calls.
+func NewBigQueryService(t testing.TestingT) *bigquery.Service {
+	service, err := NewBigQueryServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewBigQueryServiceE creates a new BigQuery service, which is used to make BigQuery API calls.
+func NewBigQueryServiceE(t testing.TestingT) (*bigquery.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create BigQuery service: %v", err)
+	}
+
+	return service, nil
+}
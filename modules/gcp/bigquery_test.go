@@ -0,0 +1,93 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestCheckBigQueryTableSchema(t *testing.T) {
+	t.Parallel()
+
+	schema := map[string]string{}
+	flattenBigQuerySchema("", []*bigquery.TableFieldSchema{
+		{Name: "event_id", Type: "STRING"},
+		{Name: "user", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+			{Name: "user_id", Type: "INTEGER"},
+		}},
+	}, schema)
+	assert.Equal(t, map[string]string{"event_id": "STRING", "user": "RECORD", "user.user_id": "INTEGER"}, schema)
+
+	assert.NoError(t, checkBigQueryTableSchema("events", schema, map[string]string{"user": "STRUCT", "user.user_id": "INT64"}))
+	assert.Error(t, checkBigQueryTableSchema("events", schema, map[string]string{"event_id": "INT64"}))
+	assert.Error(t, checkBigQueryTableSchema("events", schema, map[string]string{"event_time": "TIMESTAMP"}))
+}
+
+func TestCheckBigQueryTablePartitioning(t *testing.T) {
+	t.Parallel()
+
+	timePartitioned := &bigquery.Table{Id: "events", TimePartitioning: &bigquery.TimePartitioning{Type: "DAY", Field: "event_time"}}
+	assert.NoError(t, checkBigQueryTablePartitioning(timePartitioned, "DAY", "event_time"))
+	assert.Error(t, checkBigQueryTablePartitioning(timePartitioned, "HOUR", "event_time"))
+	assert.Error(t, checkBigQueryTablePartitioning(timePartitioned, "DAY", ""))
+
+	rangePartitioned := &bigquery.Table{Id: "events", RangePartitioning: &bigquery.RangePartitioning{Field: "customer_id"}}
+	assert.NoError(t, checkBigQueryTablePartitioning(rangePartitioned, "RANGE", "customer_id"))
+
+	assert.Error(t, checkBigQueryTablePartitioning(&bigquery.Table{Id: "events"}, "DAY", ""))
+}
+
+func TestNewBigQueryRows(t *testing.T) {
+	t.Parallel()
+
+	fields := []*bigquery.TableFieldSchema{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "score", Type: "FLOAT"},
+		{Name: "active", Type: "BOOLEAN"},
+		{Name: "created_at", Type: "TIMESTAMP"},
+		{Name: "payload", Type: "BYTES"},
+		{Name: "amount", Type: "NUMERIC"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "user", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+			{Name: "name", Type: "STRING"},
+		}},
+		{Name: "deleted_at", Type: "TIMESTAMP"},
+	}
+	tableRows := []*bigquery.TableRow{{F: []*bigquery.TableCell{
+		{V: "42"},
+		{V: "1.5"},
+		{V: "true"},
+		{V: "1.672531200123456E9"},
+		{V: "aGVsbG8="},
+		{V: "12.30"},
+		{V: []interface{}{map[string]interface{}{"v": "a"}, map[string]interface{}{"v": "b"}}},
+		{V: map[string]interface{}{"f": []interface{}{map[string]interface{}{"v": "alice"}}}},
+		{V: nil},
+	}}}
+
+	rows, err := newBigQueryRows(fields, tableRows)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, map[string]interface{}{
+		"id":         int64(42),
+		"score":      1.5,
+		"active":     true,
+		"created_at": time.Date(2023, 1, 1, 0, 0, 0, 123456000, time.UTC),
+		"payload":    []byte("hello"),
+		"amount":     "12.30",
+		"tags":       []interface{}{"a", "b"},
+		"user":       map[string]interface{}{"name": "alice"},
+		"deleted_at": nil,
+	}, rows[0])
+
+	_, err = newBigQueryRows(fields[:1], []*bigquery.TableRow{{F: []*bigquery.TableCell{{V: "not-a-number"}}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,267 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	dns "google.golang.org/api/dns/v1"
+)
+
+// GetManagedZone gets the Cloud DNS managed zone with the given name. This will fail the test if there is an error.
+func GetManagedZone(t testing.TestingT, projectID string, zoneName string) *dns.ManagedZone {
+	zone, err := GetManagedZoneE(t, projectID, zoneName)
+	require.NoError(t, err)
+	return zone
+}
+
+// GetManagedZoneE gets the Cloud DNS managed zone with the given name (not its DNS name), e.g., to check its
+// visibility, DNSSEC config or the name servers it is delegated to.
+func GetManagedZoneE(t testing.TestingT, projectID string, zoneName string) (*dns.ManagedZone, error) {
+	ctx := context.Background()
+	service, err := NewDnsServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := service.ManagedZones.Get(projectID, zoneName).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetManagedZoneE.Get(%s, %s) got error: %v", projectID, zoneName, err)
+	}
+
+	return zone, nil
+}
+
+// ListDnsRecordSets lists all the record sets in the given Cloud DNS managed zone. This will fail the test if there is
+// an error.
+func ListDnsRecordSets(t testing.TestingT, projectID string, zoneName string) []*dns.ResourceRecordSet {
+	recordSets, err := ListDnsRecordSetsE(t, projectID, zoneName)
+	require.NoError(t, err)
+	return recordSets
+}
+
+// ListDnsRecordSetsE lists all the record sets in the given Cloud DNS managed zone, including the NS and SOA records
+// Cloud DNS creates.
+func ListDnsRecordSetsE(t testing.TestingT, projectID string, zoneName string) ([]*dns.ResourceRecordSet, error) {
+	ctx := context.Background()
+	service, err := NewDnsServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var recordSets []*dns.ResourceRecordSet
+	err = service.ResourceRecordSets.List(projectID, zoneName).Pages(ctx, func(resp *dns.ResourceRecordSetsListResponse) error {
+		recordSets = append(recordSets, resp.Rrsets...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListDnsRecordSetsE.List(%s, %s) got error: %v", projectID, zoneName, err)
+	}
+
+	return recordSets, nil
+}
+
+// GetDnsRecordSet gets the record set with the given name and type (e.g., A or CNAME) from the given Cloud DNS managed
+// zone. This will fail the test if there is an error.
+func GetDnsRecordSet(t testing.TestingT, projectID string, zoneName string, recordName string, recordType string) *dns.ResourceRecordSet {
+	recordSet, err := GetDnsRecordSetE(t, projectID, zoneName, recordName, recordType)
+	require.NoError(t, err)
+	return recordSet
+}
+
+// GetDnsRecordSetE gets the record set with the given name and type (e.g., A or CNAME) from the given Cloud DNS managed
+// zone. The name is the fully qualified domain name of the record, with or without the trailing dot.
+func GetDnsRecordSetE(t testing.TestingT, projectID string, zoneName string, recordName string, recordType string) (*dns.ResourceRecordSet, error) {
+	ctx := context.Background()
+	service, err := NewDnsServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	recordSet, err := service.ResourceRecordSets.Get(projectID, zoneName, toDnsFqdn(recordName), recordType).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("Cloud DNS managed zone %s has no %s record %s", zoneName, recordType, recordName)
+		}
+		return nil, fmt.Errorf("GetDnsRecordSetE.Get(%s, %s, %s, %s) got error: %v", projectID, zoneName, recordName, recordType, err)
+	}
+
+	return recordSet, nil
+}
+
+// AssertDnsRecordSet checks that the record set with the given name and type in the given Cloud DNS managed zone has
+// exactly the expected data. This will fail the test if it does not.
+func AssertDnsRecordSet(t testing.TestingT, projectID string, zoneName string, recordName string, recordType string, expectedRrdatas []string) {
+	err := AssertDnsRecordSetE(t, projectID, zoneName, recordName, recordType, expectedRrdatas)
+	require.NoError(t, err)
+}
+
+// AssertDnsRecordSetE checks that the record set with the given name and type in the given Cloud DNS managed zone has
+// exactly the expected data (e.g., the IP addresses of an A record), in any order. Domain names are compared ignoring
+// case and the trailing dot.
+func AssertDnsRecordSetE(t testing.TestingT, projectID string, zoneName string, recordName string, recordType string, expectedRrdatas []string) error {
+	recordSet, err := GetDnsRecordSetE(t, projectID, zoneName, recordName, recordType)
+	if err != nil {
+		return err
+	}
+	return checkDnsRecordSetRrdatas(recordSet, expectedRrdatas)
+}
+
+// checkDnsRecordSetRrdatas returns an error if the given record set doesn't have exactly the expected data.
+func checkDnsRecordSetRrdatas(recordSet *dns.ResourceRecordSet, expectedRrdatas []string) error {
+	actual := normalizeDnsRrdatas(recordSet.Rrdatas)
+	expected := normalizeDnsRrdatas(expectedRrdatas)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		return fmt.Errorf("%s record %s has data %v instead of %v", recordSet.Type, recordSet.Name, recordSet.Rrdatas, expectedRrdatas)
+	}
+	return nil
+}
+
+// normalizeDnsRrdatas returns the given record data sorted, lowercased and without trailing dots, so that data that
+// refers to the same values are equal.
+func normalizeDnsRrdatas(rrdatas []string) []string {
+	normalized := []string{}
+	for _, rrdata := range rrdatas {
+		normalized = append(normalized, normalizeDnsName(rrdata))
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// WaitUntilDnsRecordResolves waits until the given fully qualified domain name resolves to all of the expected values
+// via every given resolver, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try. This will fail the test if it doesn't.
+func WaitUntilDnsRecordResolves(t testing.TestingT, fqdn string, expected []string, resolverAddrs []string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilDnsRecordResolvesE(t, fqdn, expected, resolverAddrs, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilDnsRecordResolvesE waits until the given fully qualified domain name resolves to all of the expected values
+// via every given resolver (e.g., "8.8.8.8:53"), retrying the check for the specified amount of times, sleeping for the
+// provided duration between each try. If no resolvers are given, the system resolver is used. This is useful to check
+// that a record created with terraform has propagated. To check the name servers of the managed zone itself, pass them
+// as the resolvers, e.g.:
+//
+//	zone := gcp.GetManagedZone(t, projectID, "my-zone")
+//	var resolvers []string
+//	for _, nameServer := range zone.NameServers {
+//		resolvers = append(resolvers, strings.TrimSuffix(nameServer, ".")+":53")
+//	}
+//	gcp.WaitUntilDnsRecordResolves(t, "app.example.com", []string{"203.0.113.10"}, resolvers, 30, 10*time.Second)
+//
+// The expected values can be IP addresses or the canonical name of the domain (e.g., the target of a CNAME record).
+// The name may resolve to other addresses too.
+func WaitUntilDnsRecordResolvesE(t testing.TestingT, fqdn string, expected []string, resolverAddrs []string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	resolvers := resolverAddrs
+	if len(resolvers) == 0 {
+		resolvers = []string{""}
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for %s to resolve to %v.", fqdn, expected),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			for _, resolverAddr := range resolvers {
+				answers, err := resolveDnsName(fqdn, resolverAddr)
+				if err != nil {
+					return "", err
+				}
+				if missing := getMissingDnsAnswers(answers, expected); len(missing) > 0 {
+					return "", fmt.Errorf("%s resolves to %v via resolver %q, which is missing %v", fqdn, answers, resolverAddr, missing)
+				}
+			}
+			return fmt.Sprintf("%s now resolves to %v", fqdn, expected), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// resolveDnsName returns the addresses and the canonical name the given name resolves to via the given resolver, or
+// via the system resolver if the resolver is empty.
+func resolveDnsName(fqdn string, resolverAddr string) ([]string, error) {
+	resolver := net.DefaultResolver
+	if resolverAddr != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	answers, err := resolver.LookupHost(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	if canonicalName, err := resolver.LookupCNAME(ctx, fqdn); err == nil {
+		answers = append(answers, canonicalName)
+	}
+	return answers, nil
+}
+
+// getMissingDnsAnswers returns the expected values that are not in the given answers.
+func getMissingDnsAnswers(answers []string, expected []string) []string {
+	found := map[string]bool{}
+	for _, answer := range answers {
+		found[normalizeDnsName(answer)] = true
+	}
+	missing := []string{}
+	for _, value := range expected {
+		if !found[normalizeDnsName(value)] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// normalizeDnsName lowercases the given DNS name and removes its trailing dot, so that names that refer to the same
+// domain are equal.
+func normalizeDnsName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// toDnsFqdn returns the given domain name with a trailing dot, as Cloud DNS expects it.
+func toDnsFqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// NewDnsService creates a new Cloud DNS service, which is used to make Cloud DNS API calls.
+func NewDnsService(t testing.TestingT) *dns.Service {
+	service, err := NewDnsServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewDnsServiceE creates a new Cloud DNS service, which is used to make Cloud DNS API calls.
+func NewDnsServiceE(t testing.TestingT) (*dns.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := dns.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Cloud DNS service: %v", err)
+	}
+
+	return service, nil
+}
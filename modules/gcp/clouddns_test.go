@@ -0,0 +1,39 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dns "google.golang.org/api/dns/v1"
+)
+
+func TestCheckDnsRecordSetRrdatas(t *testing.T) {
+	t.Parallel()
+
+	aRecord := &dns.ResourceRecordSet{Name: "app.example.com.", Type: "A", Rrdatas: []string{"203.0.113.2", "203.0.113.1"}}
+	assert.NoError(t, checkDnsRecordSetRrdatas(aRecord, []string{"203.0.113.1", "203.0.113.2"}))
+	assert.Error(t, checkDnsRecordSetRrdatas(aRecord, []string{"203.0.113.1"}))
+
+	cnameRecord := &dns.ResourceRecordSet{Name: "www.example.com.", Type: "CNAME", Rrdatas: []string{"App.Example.com."}}
+	assert.NoError(t, checkDnsRecordSetRrdatas(cnameRecord, []string{"app.example.com"}))
+}
+
+func TestGetMissingDnsAnswers(t *testing.T) {
+	t.Parallel()
+
+	answers := []string{"203.0.113.1", "203.0.113.2", "lb.example.com."}
+	assert.Empty(t, getMissingDnsAnswers(answers, []string{"203.0.113.1", "LB.example.com"}))
+	assert.Equal(t, []string{"203.0.113.3"}, getMissingDnsAnswers(answers, []string{"203.0.113.1", "203.0.113.3"}))
+}
+
+func TestToDnsFqdn(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "app.example.com.", toDnsFqdn("app.example.com"))
+	assert.Equal(t, "app.example.com.", toDnsFqdn("app.example.com."))
+}
@@ -0,0 +1,265 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	cloudfunctions "google.golang.org/api/cloudfunctions/v2"
+	logging "google.golang.org/api/logging/v2"
+)
+
+// GetCloudFunction gets the Cloud Function with the given name in the given region, which can be of either the 1st or
+// the 2nd generation. This will fail the test if there is an error.
+func GetCloudFunction(t testing.TestingT, projectID string, region string, functionName string) *cloudfunctions.Function {
+	function, err := GetCloudFunctionE(t, projectID, region, functionName)
+	require.NoError(t, err)
+	return function
+}
+
+// GetCloudFunctionE gets the Cloud Function with the given name in the given region, which can be of either the 1st or
+// the 2nd generation, as told by its Environment (GEN_1 or GEN_2).
+func GetCloudFunctionE(t testing.TestingT, projectID string, region string, functionName string) (*cloudfunctions.Function, error) {
+	ctx := context.Background()
+	service, err := NewCloudFunctionsServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("projects/%s/locations/%s/functions/%s", projectID, region, functionName)
+	function, err := service.Projects.Locations.Functions.Get(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetCloudFunctionE.Get(%s, %s, %s) got error: %v", projectID, region, functionName, err)
+	}
+
+	return function, nil
+}
+
+// WaitUntilCloudFunctionActive waits until the given Cloud Function is ACTIVE, retrying the check for the specified
+// amount of times, sleeping for the provided duration between each try. This will fail the test if there is an error or
+// if the function does not become active.
+func WaitUntilCloudFunctionActive(t testing.TestingT, projectID string, region string, functionName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilCloudFunctionActiveE(t, projectID, region, functionName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilCloudFunctionActiveE waits until the given Cloud Function is ACTIVE, retrying the check for the specified
+// amount of times, sleeping for the provided duration between each try. This stops waiting if the deployment of the
+// function fails.
+func WaitUntilCloudFunctionActiveE(t testing.TestingT, projectID string, region string, functionName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Cloud Function %s to be active.", functionName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			function, err := GetCloudFunctionE(t, projectID, region, functionName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkCloudFunctionActive(function); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Cloud Function %s is now active", functionName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkCloudFunctionActive returns an error if the given function is not active, wrapped in a retry.FatalError if its
+// deployment failed.
+func checkCloudFunctionActive(function *cloudfunctions.Function) error {
+	if function.State == "ACTIVE" {
+		return nil
+	}
+
+	var messages []string
+	for _, message := range function.StateMessages {
+		messages = append(messages, message.Message)
+	}
+	err := fmt.Errorf("Cloud Function %s is %s: %s", function.Name, function.State, strings.Join(messages, "; "))
+	if function.State == "FAILED" {
+		return retry.FatalError{Underlying: err}
+	}
+	return err
+}
+
+// InvokeHttpCloudFunction sends a request with the given method, body and headers to the given path of the given
+// HTTP-triggered Cloud Function, authenticated with an ID token, and returns the status code and body of the response.
+// This will fail the test if there is an error.
+func InvokeHttpCloudFunction(t testing.TestingT, projectID string, region string, functionName string, method string, path string, body io.Reader, headers map[string]string) (int, string) {
+	statusCode, respBody, err := InvokeHttpCloudFunctionE(t, projectID, region, functionName, method, path, body, headers)
+	require.NoError(t, err)
+	return statusCode, respBody
+}
+
+// InvokeHttpCloudFunctionE sends a request with the given method, body and headers to the given path of the given
+// HTTP-triggered Cloud Function and returns the status code and body of the response. The request is authenticated with
+// an ID token for the URL of the function minted from the application default credentials, so that functions that don't
+// allow unauthenticated invocations can be tested too, as long as the credentials have the roles/cloudfunctions.invoker
+// role (or roles/run.invoker for 2nd gen functions). This requires service account credentials, as Google doesn't mint
+// ID tokens for user credentials, unless a service account is impersonated (see ImpersonateServiceAccountEnvVar).
+func InvokeHttpCloudFunctionE(t testing.TestingT, projectID string, region string, functionName string, method string, path string, body io.Reader, headers map[string]string) (int, string, error) {
+	function, err := GetCloudFunctionE(t, projectID, region, functionName)
+	if err != nil {
+		return -1, "", err
+	}
+	if function.EventTrigger != nil {
+		return -1, "", fmt.Errorf("Cloud Function %s is triggered by %s events, not HTTP requests", functionName, function.EventTrigger.EventType)
+	}
+	if function.ServiceConfig == nil || function.ServiceConfig.Uri == "" {
+		return -1, "", fmt.Errorf("Cloud Function %s has no URL yet", functionName)
+	}
+
+	return invokeWithIDTokenE(t, "Cloud Function "+functionName, function.ServiceConfig.Uri, method, path, body, headers)
+}
+
+// TriggerCloudFunctionWithPubSub publishes a message with the given data and attributes to the Pub/Sub topic that
+// triggers the given Cloud Function, and returns the ID of the message. This will fail the test if there is an error.
+func TriggerCloudFunctionWithPubSub(t testing.TestingT, projectID string, region string, functionName string, data string, attributes map[string]string) string {
+	messageID, err := TriggerCloudFunctionWithPubSubE(t, projectID, region, functionName, data, attributes)
+	require.NoError(t, err)
+	return messageID
+}
+
+// TriggerCloudFunctionWithPubSubE publishes a message with the given data and attributes to the Pub/Sub topic that
+// triggers the given Cloud Function, and returns the ID of the message. Use AssertCloudFunctionLogContainsE to check
+// that the function handled it.
+func TriggerCloudFunctionWithPubSubE(t testing.TestingT, projectID string, region string, functionName string, data string, attributes map[string]string) (string, error) {
+	function, err := GetCloudFunctionE(t, projectID, region, functionName)
+	if err != nil {
+		return "", err
+	}
+	if function.EventTrigger == nil || function.EventTrigger.PubsubTopic == "" {
+		return "", fmt.Errorf("Cloud Function %s is not triggered by a Pub/Sub topic", functionName)
+	}
+
+	// The topic is of the form projects/<project>/topics/<topic>, and may be in another project than the function.
+	parts := strings.Split(function.EventTrigger.PubsubTopic, "/")
+	if len(parts) != 4 {
+		return "", fmt.Errorf("Cloud Function %s is triggered by unexpected Pub/Sub topic %s", functionName, function.EventTrigger.PubsubTopic)
+	}
+	return PublishMessageE(t, parts[1], parts[3], data, attributes)
+}
+
+// GetCloudFunctionLogEntries gets the Cloud Logging entries written by the given Cloud Function since the given time,
+// oldest first. This will fail the test if there is an error.
+func GetCloudFunctionLogEntries(t testing.TestingT, projectID string, region string, functionName string, since time.Time) []*logging.LogEntry {
+	entries, err := GetCloudFunctionLogEntriesE(t, projectID, region, functionName, since)
+	require.NoError(t, err)
+	return entries
+}
+
+// GetCloudFunctionLogEntriesE gets the Cloud Logging entries written by the given Cloud Function since the given time,
+// oldest first. These include the entries the function logs itself, as well as those Cloud Functions logs for each
+// execution.
+func GetCloudFunctionLogEntriesE(t testing.TestingT, projectID string, region string, functionName string, since time.Time) ([]*logging.LogEntry, error) {
+	function, err := GetCloudFunctionE(t, projectID, region, functionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetLogEntriesE(t, projectID, cloudFunctionLogFilter(function, since))
+}
+
+// cloudFunctionLogFilter returns the Cloud Logging filter that matches the entries written by the given function since
+// the given time. 2nd gen functions run as Cloud Run services, so their entries are logged for the underlying service.
+func cloudFunctionLogFilter(function *cloudfunctions.Function, since time.Time) string {
+	parts := strings.Split(function.Name, "/")
+	functionName, region := parts[len(parts)-1], parts[len(parts)-3]
+
+	resourceFilter := fmt.Sprintf(`resource.type="cloud_function" AND resource.labels.function_name="%s" AND resource.labels.region="%s"`, functionName, region)
+	if function.Environment == "GEN_2" && function.ServiceConfig != nil && function.ServiceConfig.Service != "" {
+		service := function.ServiceConfig.Service[strings.LastIndex(function.ServiceConfig.Service, "/")+1:]
+		resourceFilter = fmt.Sprintf(`resource.type="cloud_run_revision" AND resource.labels.service_name="%s" AND resource.labels.location="%s"`, service, region)
+	}
+
+	return fmt.Sprintf(`%s AND timestamp>="%s"`, resourceFilter, since.UTC().Format(time.RFC3339))
+}
+
+// AssertCloudFunctionLogContains checks that the given Cloud Function writes a log entry containing the given text
+// since the given time, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try, and returns the matching entry. This will fail the test if there is an error or if there is no such
+// entry.
+func AssertCloudFunctionLogContains(t testing.TestingT, projectID string, region string, functionName string, since time.Time, expectedText string, maxRetries int, sleepBetweenRetries time.Duration) *logging.LogEntry {
+	entry, err := AssertCloudFunctionLogContainsE(t, projectID, region, functionName, since, expectedText, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return entry
+}
+
+// AssertCloudFunctionLogContainsE checks that the given Cloud Function writes a log entry containing the given text
+// since the given time, retrying the check for the specified amount of times, sleeping for the provided duration
+// between each try, as entries take a while to show up in Cloud Logging. Both text and structured entries are checked,
+// the latter as JSON. Pass the time right before invoking the function as since, to only check the entries produced by
+// the invocation, e.g.:
+//
+//	since := time.Now()
+//	gcp.TriggerCloudFunctionWithPubSub(t, projectID, region, "my-function", "hello", nil)
+//	gcp.AssertCloudFunctionLogContains(t, projectID, region, "my-function", since, "Received hello", 30, 10*time.Second)
+func AssertCloudFunctionLogContainsE(t testing.TestingT, projectID string, region string, functionName string, since time.Time, expectedText string, maxRetries int, sleepBetweenRetries time.Duration) (*logging.LogEntry, error) {
+	var match *logging.LogEntry
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Cloud Function %s to log %q.", functionName, expectedText),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			entries, err := GetCloudFunctionLogEntriesE(t, projectID, region, functionName, since)
+			if err != nil {
+				return "", err
+			}
+			match = findLogEntryContaining(entries, expectedText)
+			if match == nil {
+				return "", fmt.Errorf("Cloud Function %s has not logged %q yet", functionName, expectedText)
+			}
+			return fmt.Sprintf("Cloud Function %s logged %q", functionName, expectedText), nil
+		},
+	)
+	logger.Logf(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// findLogEntryContaining returns the first of the given log entries whose payload contains the given text, or nil if
+// there is none.
+func findLogEntryContaining(entries []*logging.LogEntry, text string) *logging.LogEntry {
+	for _, entry := range entries {
+		if strings.Contains(entry.TextPayload, text) || strings.Contains(string(entry.JsonPayload), text) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// NewCloudFunctionsService creates a new Cloud Functions service, which is used to make Cloud Functions API calls.
+func NewCloudFunctionsService(t testing.TestingT) *cloudfunctions.Service {
+	service, err := NewCloudFunctionsServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewCloudFunctionsServiceE creates a new Cloud Functions service, which is used to make Cloud Functions API calls.
+func NewCloudFunctionsServiceE(t testing.TestingT) (*cloudfunctions.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := cloudfunctions.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Cloud Functions service: %v", err)
+	}
+
+	return service, nil
+}
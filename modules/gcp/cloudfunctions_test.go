@@ -0,0 +1,67 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	cloudfunctions "google.golang.org/api/cloudfunctions/v2"
+	logging "google.golang.org/api/logging/v2"
+)
+
+func TestCheckCloudFunctionActive(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkCloudFunctionActive(&cloudfunctions.Function{Name: "my-function", State: "ACTIVE"}))
+
+	err := checkCloudFunctionActive(&cloudfunctions.Function{Name: "my-function", State: "DEPLOYING"})
+	assert.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+
+	failed := &cloudfunctions.Function{
+		Name:          "my-function",
+		State:         "FAILED",
+		StateMessages: []*cloudfunctions.GoogleCloudFunctionsV2StateMessage{{Message: "Build failed"}},
+	}
+	err = checkCloudFunctionActive(failed)
+	assert.IsType(t, retry.FatalError{}, err)
+	assert.Contains(t, err.Error(), "Build failed")
+}
+
+func TestCloudFunctionLogFilter(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	gen1 := &cloudfunctions.Function{
+		Name:        "projects/my-project/locations/us-central1/functions/my-function",
+		Environment: "GEN_1",
+	}
+	assert.Equal(t, `resource.type="cloud_function" AND resource.labels.function_name="my-function" AND resource.labels.region="us-central1" AND timestamp>="2023-01-01T12:00:00Z"`, cloudFunctionLogFilter(gen1, since))
+
+	gen2 := &cloudfunctions.Function{
+		Name:          "projects/my-project/locations/us-central1/functions/my-function",
+		Environment:   "GEN_2",
+		ServiceConfig: &cloudfunctions.ServiceConfig{Service: "projects/my-project/locations/us-central1/services/my-function"},
+	}
+	assert.Equal(t, `resource.type="cloud_run_revision" AND resource.labels.service_name="my-function" AND resource.labels.location="us-central1" AND timestamp>="2023-01-01T12:00:00Z"`, cloudFunctionLogFilter(gen2, since))
+}
+
+func TestFindLogEntryContaining(t *testing.T) {
+	t.Parallel()
+
+	entries := []*logging.LogEntry{
+		{InsertId: "1", TextPayload: "Function execution started"},
+		{InsertId: "2", JsonPayload: []byte(`{"message":"Received hello"}`)},
+	}
+	assert.Equal(t, "2", findLogEntryContaining(entries, "Received hello").InsertId)
+	assert.Equal(t, "1", findLogEntryContaining(entries, "execution started").InsertId)
+	assert.Nil(t, findLogEntryContaining(entries, "goodbye"))
+}
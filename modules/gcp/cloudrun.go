@@ -0,0 +1,235 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	run "google.golang.org/api/run/v2"
+)
+
+// GetCloudRunService gets the Cloud Run service with the given name in the given region. This will fail the test if
+// there is an error.
+func GetCloudRunService(t testing.TestingT, projectID string, region string, serviceName string) *run.GoogleCloudRunV2Service {
+	service, err := GetCloudRunServiceE(t, projectID, region, serviceName)
+	require.NoError(t, err)
+	return service
+}
+
+// GetCloudRunServiceE gets the Cloud Run service with the given name in the given region.
+func GetCloudRunServiceE(t testing.TestingT, projectID string, region string, serviceName string) (*run.GoogleCloudRunV2Service, error) {
+	ctx := context.Background()
+	runService, err := NewCloudRunServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("projects/%s/locations/%s/services/%s", projectID, region, serviceName)
+	service, err := runService.Projects.Locations.Services.Get(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetCloudRunServiceE.Get(%s, %s, %s) got error: %v", projectID, region, serviceName, err)
+	}
+
+	return service, nil
+}
+
+// WaitUntilCloudRunServiceReady waits until the given Cloud Run service has finished deploying its latest changes,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This will
+// fail the test if there is an error or if the service is not ready.
+func WaitUntilCloudRunServiceReady(t testing.TestingT, projectID string, region string, serviceName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilCloudRunServiceReadyE(t, projectID, region, serviceName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilCloudRunServiceReadyE waits until the given Cloud Run service has finished deploying its latest changes,
+// retrying the check for the specified amount of times, sleeping for the provided duration between each try. This stops
+// waiting if the deployment fails, e.g., because the container doesn't listen on the expected port.
+func WaitUntilCloudRunServiceReadyE(t testing.TestingT, projectID string, region string, serviceName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Cloud Run service %s to be ready.", serviceName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			service, err := GetCloudRunServiceE(t, projectID, region, serviceName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkCloudRunServiceReady(service); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Cloud Run service %s is now ready", serviceName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkCloudRunServiceReady returns an error if the given service is not ready, wrapped in a retry.FatalError if its
+// latest deployment failed.
+func checkCloudRunServiceReady(service *run.GoogleCloudRunV2Service) error {
+	condition := service.TerminalCondition
+	if condition == nil {
+		return fmt.Errorf("Cloud Run service %s has no status yet", service.Name)
+	}
+	if !service.Reconciling && condition.State == "CONDITION_SUCCEEDED" {
+		return nil
+	}
+	err := fmt.Errorf("Cloud Run service %s is %s: %s", service.Name, condition.State, condition.Message)
+	if !service.Reconciling && condition.State == "CONDITION_FAILED" {
+		return retry.FatalError{Underlying: err}
+	}
+	return err
+}
+
+// GetCloudRunServiceTraffic returns the percent of the traffic of the given Cloud Run service that each revision
+// serves. This will fail the test if there is an error.
+func GetCloudRunServiceTraffic(t testing.TestingT, projectID string, region string, serviceName string) map[string]int64 {
+	traffic, err := GetCloudRunServiceTrafficE(t, projectID, region, serviceName)
+	require.NoError(t, err)
+	return traffic
+}
+
+// GetCloudRunServiceTrafficE returns the percent of the traffic of the given Cloud Run service that each revision
+// serves, keyed by the short name of the revision (e.g., my-service-00002-abc). The traffic sent to the latest revision
+// is counted for the latest ready revision, and revisions that serve no traffic are left out.
+func GetCloudRunServiceTrafficE(t testing.TestingT, projectID string, region string, serviceName string) (map[string]int64, error) {
+	service, err := GetCloudRunServiceE(t, projectID, region, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	return getCloudRunServiceTraffic(service), nil
+}
+
+// getCloudRunServiceTraffic returns the percent of the traffic of the given service that each revision serves.
+func getCloudRunServiceTraffic(service *run.GoogleCloudRunV2Service) map[string]int64 {
+	traffic := map[string]int64{}
+	for _, status := range service.TrafficStatuses {
+		revision := status.Revision
+		if status.Type == "TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST" || revision == "" {
+			revision = service.LatestReadyRevision[strings.LastIndex(service.LatestReadyRevision, "/")+1:]
+		}
+		if status.Percent > 0 {
+			traffic[revision] += status.Percent
+		}
+	}
+	return traffic
+}
+
+// AssertCloudRunServiceTraffic checks that the traffic of the given Cloud Run service is split between revisions as
+// expected. This will fail the test if it is not.
+func AssertCloudRunServiceTraffic(t testing.TestingT, projectID string, region string, serviceName string, expectedTraffic map[string]int64) {
+	err := AssertCloudRunServiceTrafficE(t, projectID, region, serviceName, expectedTraffic)
+	require.NoError(t, err)
+}
+
+// AssertCloudRunServiceTrafficE checks that the traffic of the given Cloud Run service is split between revisions as
+// expected, where expectedTraffic is the percent of the traffic each revision serves, keyed by the short name of the
+// revision, e.g.:
+//
+//	gcp.AssertCloudRunServiceTrafficE(t, projectID, region, "my-service", map[string]int64{
+//		"my-service-00001-abc": 90,
+//		"my-service-00002-def": 10,
+//	})
+func AssertCloudRunServiceTrafficE(t testing.TestingT, projectID string, region string, serviceName string, expectedTraffic map[string]int64) error {
+	traffic, err := GetCloudRunServiceTrafficE(t, projectID, region, serviceName)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(traffic, expectedTraffic) {
+		return fmt.Errorf("Cloud Run service %s splits traffic as %v instead of %v", serviceName, traffic, expectedTraffic)
+	}
+	return nil
+}
+
+// InvokeCloudRunService sends a request with the given method, body and headers to the given path of the given Cloud
+// Run service, authenticated with an ID token, and returns the status code and body of the response. This will fail the
+// test if there is an error.
+func InvokeCloudRunService(t testing.TestingT, projectID string, region string, serviceName string, method string, path string, body io.Reader, headers map[string]string) (int, string) {
+	statusCode, respBody, err := InvokeCloudRunServiceE(t, projectID, region, serviceName, method, path, body, headers)
+	require.NoError(t, err)
+	return statusCode, respBody
+}
+
+// InvokeCloudRunServiceE sends a request with the given method, body and headers to the given path of the given Cloud
+// Run service and returns the status code and body of the response. The request is authenticated with an ID token for
+// the URL of the service minted from the application default credentials, so that services that don't allow
+// unauthenticated invocations can be tested too, as long as the credentials have the roles/run.invoker role. This
+// requires service account credentials, as Google doesn't mint ID tokens for user credentials, unless a service account
+// is impersonated (see ImpersonateServiceAccountEnvVar).
+func InvokeCloudRunServiceE(t testing.TestingT, projectID string, region string, serviceName string, method string, path string, body io.Reader, headers map[string]string) (int, string, error) {
+	service, err := GetCloudRunServiceE(t, projectID, region, serviceName)
+	if err != nil {
+		return -1, "", err
+	}
+	if service.Uri == "" {
+		return -1, "", fmt.Errorf("Cloud Run service %s has no URL yet", serviceName)
+	}
+
+	return invokeWithIDTokenE(t, "Cloud Run service "+serviceName, service.Uri, method, path, body, headers)
+}
+
+// invokeWithIDTokenE sends a request to the given path under the given base URL, authenticated with an ID token for the
+// base URL minted from the application default credentials, or for the impersonated service account, and returns the status code and body of the response.
+func invokeWithIDTokenE(t testing.TestingT, description string, baseURL string, method string, path string, body io.Reader, headers map[string]string) (int, string, error) {
+	ctx := context.Background()
+	client, err := newIDTokenClientE(ctx, baseURL)
+	if err != nil {
+		return -1, "", fmt.Errorf("Failed to create a client with an ID token for %s: %v", baseURL, err)
+	}
+
+	url := strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return -1, "", err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	logger.Logf(t, "Making an HTTP %s call to %s at %s", method, description, url)
+	resp, err := client.Do(req)
+	if err != nil {
+		return -1, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return -1, "", err
+	}
+	return resp.StatusCode, strings.TrimSpace(string(respBody)), nil
+}
+
+// NewCloudRunService creates a new Cloud Run service, which is used to make Cloud Run API calls.
+func NewCloudRunService(t testing.TestingT) *run.Service {
+	service, err := NewCloudRunServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewCloudRunServiceE creates a new Cloud Run service, which is used to make Cloud Run API calls.
+func NewCloudRunServiceE(t testing.TestingT) (*run.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := run.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Cloud Run service: %v", err)
+	}
+
+	return service, nil
+}
@@ -0,0 +1,56 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	run "google.golang.org/api/run/v2"
+)
+
+func TestCheckCloudRunServiceReady(t *testing.T) {
+	t.Parallel()
+
+	ready := &run.GoogleCloudRunV2Service{
+		Name:              "my-service",
+		TerminalCondition: &run.GoogleCloudRunV2Condition{State: "CONDITION_SUCCEEDED"},
+	}
+	assert.NoError(t, checkCloudRunServiceReady(ready))
+
+	deploying := &run.GoogleCloudRunV2Service{
+		Name:              "my-service",
+		Reconciling:       true,
+		TerminalCondition: &run.GoogleCloudRunV2Condition{State: "CONDITION_SUCCEEDED"},
+	}
+	err := checkCloudRunServiceReady(deploying)
+	assert.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+
+	failed := &run.GoogleCloudRunV2Service{
+		Name:              "my-service",
+		TerminalCondition: &run.GoogleCloudRunV2Condition{State: "CONDITION_FAILED", Message: "container failed to start"},
+	}
+	err = checkCloudRunServiceReady(failed)
+	assert.IsType(t, retry.FatalError{}, err)
+	assert.Contains(t, err.Error(), "container failed to start")
+}
+
+func TestGetCloudRunServiceTraffic(t *testing.T) {
+	t.Parallel()
+
+	service := &run.GoogleCloudRunV2Service{
+		LatestReadyRevision: "projects/my-project/locations/us-central1/services/my-service/revisions/my-service-00002-def",
+		TrafficStatuses: []*run.GoogleCloudRunV2TrafficTargetStatus{
+			{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: "my-service-00001-abc", Percent: 90},
+			{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST", Percent: 10},
+			{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: "my-service-00003-ghi", Tag: "canary"},
+		},
+	}
+	assert.Equal(t, map[string]int64{"my-service-00001-abc": 90, "my-service-00002-def": 10}, getCloudRunServiceTraffic(service))
+}
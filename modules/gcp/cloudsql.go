@@ -0,0 +1,531 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+const (
+	// CloudSqlIpTypePublic is the type of the public IP address of a Cloud SQL instance, for use in
+	// CloudSqlConnectionOptions.
+	CloudSqlIpTypePublic = "PRIMARY"
+	// CloudSqlIpTypePrivate is the type of the private IP address of a Cloud SQL instance, for use in
+	// CloudSqlConnectionOptions.
+	CloudSqlIpTypePrivate = "PRIVATE"
+
+	// cloudSqlServerProxyPort is the port on which Cloud SQL instances accept the TLS connections of the Cloud SQL
+	// connectors and the Cloud SQL Auth proxy.
+	cloudSqlServerProxyPort = 3307
+
+	// cloudSqlDialTimeout is how long to wait to connect to a Cloud SQL instance or to its bastion host.
+	cloudSqlDialTimeout = 30 * time.Second
+)
+
+// The states of a Cloud SQL instance that it doesn't recover from by itself.
+var cloudSqlInstanceFailedStates = map[string]bool{
+	"FAILED":    true,
+	"SUSPENDED": true,
+}
+
+// GetCloudSqlInstance gets the Cloud SQL instance with the given name. This will fail the test if there is an error.
+func GetCloudSqlInstance(t testing.TestingT, projectID string, instanceName string) *sqladmin.DatabaseInstance {
+	instance, err := GetCloudSqlInstanceE(t, projectID, instanceName)
+	require.NoError(t, err)
+	return instance
+}
+
+// GetCloudSqlInstanceE gets the Cloud SQL instance with the given name.
+func GetCloudSqlInstanceE(t testing.TestingT, projectID string, instanceName string) (*sqladmin.DatabaseInstance, error) {
+	ctx := context.Background()
+	service, err := NewSqlAdminServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := service.Instances.Get(projectID, instanceName).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetCloudSqlInstanceE.Get(%s, %s) got error: %v", projectID, instanceName, err)
+	}
+
+	return instance, nil
+}
+
+// WaitUntilCloudSqlInstanceRunnable waits until the given Cloud SQL instance is runnable, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. This will fail the test if there is
+// an error or if the instance is not runnable.
+func WaitUntilCloudSqlInstanceRunnable(t testing.TestingT, projectID string, instanceName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilCloudSqlInstanceRunnableE(t, projectID, instanceName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilCloudSqlInstanceRunnableE waits until the given Cloud SQL instance is runnable, retrying the check for the
+// specified amount of times, sleeping for the provided duration between each try. This stops waiting if the instance
+// failed to be created or is suspended, as it won't become runnable.
+func WaitUntilCloudSqlInstanceRunnableE(t testing.TestingT, projectID string, instanceName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Cloud SQL instance %s to be runnable.", instanceName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			instance, err := GetCloudSqlInstanceE(t, projectID, instanceName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkCloudSqlInstanceRunnable(instance); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Cloud SQL instance %s is now runnable", instanceName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkCloudSqlInstanceRunnable returns an error if the given instance is not runnable, wrapped in a retry.FatalError
+// if it won't become runnable.
+func checkCloudSqlInstanceRunnable(instance *sqladmin.DatabaseInstance) error {
+	if instance.State == "RUNNABLE" {
+		return nil
+	}
+	err := fmt.Errorf("Cloud SQL instance %s is %s", instance.Name, instance.State)
+	if cloudSqlInstanceFailedStates[instance.State] {
+		return retry.FatalError{Underlying: err}
+	}
+	return err
+}
+
+// CloudSqlConnectionOptions are the options to connect to a Cloud SQL instance with ConnectToCloudSqlAndQuery.
+type CloudSqlConnectionOptions struct {
+	IpType   string // The IP address to connect to: CloudSqlIpTypePublic (the default) or CloudSqlIpTypePrivate
+	Username string // The database user, or the IAM user or service account email if UseIamAuth is set
+	Password string // The password of the database user, unless UseIamAuth is set
+	Database string // The name of the database to connect to (optional)
+
+	// Authenticate as the IAM user or service account of the application default credentials instead of with a
+	// password. The instance must have the cloudsql.iam_authentication flag on. For service accounts, the username of
+	// MySQL users is the email without the domain, and the username of Postgres users is the email without the
+	// .gserviceaccount.com suffix.
+	UseIamAuth bool
+
+	// The name of the database/sql driver to use. Defaults to "mysql" or "postgres", depending on the engine of the
+	// instance. Terratest only registers a MySQL driver, so to connect to a Postgres instance, import a driver in your
+	// test that registers as "postgres" (e.g., github.com/lib/pq), or set this to the name of the driver you import.
+	DriverName string
+
+	SshBastion *ssh.Host // Connect through an SSH tunnel via this host, e.g., to reach a private IP (optional)
+}
+
+// ConnectToCloudSqlAndQuery connects to the given Cloud SQL instance with the given options, runs the given query, and
+// returns the rows it returns, with each column formatted as a string. This will fail the test if there is an error.
+func ConnectToCloudSqlAndQuery(t testing.TestingT, projectID string, instanceName string, options CloudSqlConnectionOptions, query string, args ...interface{}) [][]string {
+	rows, err := ConnectToCloudSqlAndQueryE(t, projectID, instanceName, options, query, args...)
+	require.NoError(t, err)
+	return rows
+}
+
+// ConnectToCloudSqlAndQueryE connects to the given Cloud SQL instance with the given options, runs the given query, and
+// returns the rows it returns, with each column formatted as a string (NULL values are returned as empty strings). This
+// is useful to check the connectivity of the instance and the grants of its users, e.g., by running SHOW GRANTS.
+//
+// This connects in the same way as the Cloud SQL connectors and the Cloud SQL Auth proxy: it mints an ephemeral client
+// certificate from the application default credentials, and connects to the instance with TLS through a local proxy
+// that is closed once the query is done. So this requires the roles/cloudsql.client role, but not authorized networks.
+func ConnectToCloudSqlAndQueryE(t testing.TestingT, projectID string, instanceName string, options CloudSqlConnectionOptions, query string, args ...interface{}) ([][]string, error) {
+	ctx := context.Background()
+	service, err := NewSqlAdminServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := service.Connect.Get(projectID, instanceName).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("ConnectToCloudSqlAndQueryE.Get(%s, %s) got error: %v", projectID, instanceName, err)
+	}
+	ipType := options.IpType
+	if ipType == "" {
+		ipType = CloudSqlIpTypePublic
+	}
+	address, err := getCloudSqlIpAddress(settings, ipType)
+	if err != nil {
+		return nil, fmt.Errorf("Cloud SQL instance %s: %v", instanceName, err)
+	}
+	engine, err := getCloudSqlEngine(settings.DatabaseVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken := ""
+	password := options.Password
+	if options.UseIamAuth {
+		tokenSource, err := newTokenSourceE(ctx, sqladmin.SqlserviceAdminScope)
+		if err != nil {
+			return nil, err
+		}
+		token, err := tokenSource.Token()
+		if err != nil {
+			return nil, err
+		}
+		accessToken, password = token.AccessToken, token.AccessToken
+	}
+	tlsConfig, err := newCloudSqlTlsConfig(ctx, service, projectID, instanceName, settings, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := func() (net.Conn, error) {
+		return net.DialTimeout("tcp", net.JoinHostPort(address, strconv.Itoa(cloudSqlServerProxyPort)), cloudSqlDialTimeout)
+	}
+	if options.SshBastion != nil {
+		client, err := dialCloudSqlBastion(t, *options.SshBastion)
+		if err != nil {
+			return nil, err
+		}
+		defer client.Close()
+		dial = func() (net.Conn, error) {
+			return client.Dial("tcp", net.JoinHostPort(address, strconv.Itoa(cloudSqlServerProxyPort)))
+		}
+	}
+	proxy, err := newCloudSqlProxy(func() (net.Conn, error) {
+		conn, err := dial()
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer proxy.Close()
+
+	driverName := options.DriverName
+	if driverName == "" {
+		driverName = engine
+	}
+	dataSourceName := formatCloudSqlDataSourceName(engine, proxy.LocalPort(), options.Username, password, options.Database, options.UseIamAuth)
+
+	logger.Logf(t, "Running query on Cloud SQL instance %s at %s", instanceName, address)
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCloudSqlRows(rows)
+}
+
+// getCloudSqlIpAddress returns the IP address of the given type in the given connect settings.
+func getCloudSqlIpAddress(settings *sqladmin.ConnectSettings, ipType string) (string, error) {
+	for _, ip := range settings.IpAddresses {
+		if ip.Type == ipType {
+			return ip.IpAddress, nil
+		}
+	}
+	return "", fmt.Errorf("no IP address of type %s", ipType)
+}
+
+// getCloudSqlEngine returns the database/sql driver name of the engine of the given database version (e.g.,
+// POSTGRES_14).
+func getCloudSqlEngine(databaseVersion string) (string, error) {
+	switch {
+	case strings.HasPrefix(databaseVersion, "MYSQL"):
+		return "mysql", nil
+	case strings.HasPrefix(databaseVersion, "POSTGRES"):
+		return "postgres", nil
+	default:
+		return "", fmt.Errorf("Unsupported Cloud SQL database version %q: expected a MySQL or Postgres version", databaseVersion)
+	}
+}
+
+// formatCloudSqlDataSourceName returns the data source name to connect to a database of the given engine through the
+// local proxy listening on the given port. The proxy does the TLS, so the driver connects without it.
+func formatCloudSqlDataSourceName(engine string, port int, username string, password string, database string, useIamAuth bool) string {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	if engine == "mysql" {
+		config := mysql.NewConfig()
+		config.User = username
+		config.Passwd = password
+		config.Net = "tcp"
+		config.Addr = address
+		config.DBName = database
+		// MySQL IAM authentication sends the token as a cleartext password, which the proxy encrypts.
+		config.AllowCleartextPasswords = useIamAuth
+		return config.FormatDSN()
+	}
+	dataSourceURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     address,
+		Path:     "/" + database,
+		RawQuery: "sslmode=disable",
+	}
+	return dataSourceURL.String()
+}
+
+// newCloudSqlTlsConfig mints an ephemeral client certificate for the given instance, and returns the TLS config to
+// connect to the instance with it. If the access token is set, the instance authenticates the connection as the IAM
+// identity the token belongs to.
+func newCloudSqlTlsConfig(ctx context.Context, service *sqladmin.Service, projectID string, instanceName string, settings *sqladmin.ConnectSettings, accessToken string) (*tls.Config, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := service.Connect.GenerateEphemeralCert(projectID, instanceName, &sqladmin.GenerateEphemeralCertRequest{
+		PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		AccessToken: accessToken,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate an ephemeral certificate for Cloud SQL instance %s: %v", instanceName, err)
+	}
+
+	certBlock, _ := pem.Decode([]byte(resp.EphemeralCert.Cert))
+	if certBlock == nil {
+		return nil, fmt.Errorf("Failed to decode the ephemeral certificate of Cloud SQL instance %s", instanceName)
+	}
+	if settings.ServerCaCert == nil {
+		return nil, fmt.Errorf("Cloud SQL instance %s has no server CA certificate", instanceName)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(settings.ServerCaCert.Cert)) {
+		return nil, fmt.Errorf("Failed to decode the server CA certificate of Cloud SQL instance %s", instanceName)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certBlock.Bytes}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+		// The server certificate is issued to <project>:<instance> rather than to a host name, so it's verified by
+		// verifyCloudSqlServerCertificate instead.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyCloudSqlServerCertificate(roots, projectID+":"+instanceName),
+	}, nil
+}
+
+// verifyCloudSqlServerCertificate returns a function that checks that the certificate of a Cloud SQL server is issued
+// by one of the given roots to the given instance.
+func verifyCloudSqlServerCertificate(roots *x509.CertPool, instanceID string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("Cloud SQL instance %s presented no certificate", instanceID)
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: roots}); err != nil {
+			return err
+		}
+		if cert.Subject.CommonName != instanceID {
+			return fmt.Errorf("certificate of Cloud SQL instance %s was issued to %s", instanceID, cert.Subject.CommonName)
+		}
+		return nil
+	}
+}
+
+// dialCloudSqlBastion opens an SSH connection to the given bastion host.
+func dialCloudSqlBastion(t testing.TestingT, bastion ssh.Host) (*gossh.Client, error) {
+	authMethods, err := createCloudSqlBastionAuthMethods(bastion)
+	if err != nil {
+		return nil, err
+	}
+	port := bastion.CustomPort
+	if port == 0 {
+		port = 22
+	}
+
+	logger.Logf(t, "Opening SSH tunnel to Cloud SQL through %s", bastion.Hostname)
+	return gossh.Dial("tcp", net.JoinHostPort(bastion.Hostname, strconv.Itoa(port)), &gossh.ClientConfig{
+		User: bastion.SshUserName,
+		Auth: authMethods,
+		// Do not do a host key check, as Terratest is only used for testing, not prod
+		HostKeyCallback: ssh.NoOpHostKeyCallback,
+		Timeout:         cloudSqlDialTimeout,
+	})
+}
+
+// createCloudSqlBastionAuthMethods returns the SSH authentication methods the given host is configured with.
+func createCloudSqlBastionAuthMethods(host ssh.Host) ([]gossh.AuthMethod, error) {
+	methods := []gossh.AuthMethod{}
+
+	agentSockets := []string{}
+	if host.OverrideSshAgent != nil {
+		agentSockets = append(agentSockets, host.OverrideSshAgent.SocketFile())
+	}
+	if host.SshAgent {
+		agentSockets = append(agentSockets, os.Getenv("SSH_AUTH_SOCK"))
+	}
+	for _, socket := range agentSockets {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, gossh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	if host.SshKeyPair != nil {
+		signer, err := gossh.ParsePrivateKey([]byte(host.SshKeyPair.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, gossh.PublicKeys(signer))
+	}
+	if host.Password != "" {
+		methods = append(methods, gossh.Password(host.Password))
+	}
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("No SSH authentication method defined for bastion host %s", host.Hostname)
+	}
+	return methods, nil
+}
+
+// cloudSqlProxy listens on a random local port, and copies the data of each connection it accepts to and from a
+// connection to a Cloud SQL instance opened with its dial function.
+type cloudSqlProxy struct {
+	listener net.Listener
+	dial     func() (net.Conn, error)
+	wg       sync.WaitGroup
+}
+
+// newCloudSqlProxy starts forwarding connections to a random local port to connections opened with the given dial
+// function.
+func newCloudSqlProxy(dial func() (net.Conn, error)) (*cloudSqlProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	proxy := &cloudSqlProxy{listener: listener, dial: dial}
+	proxy.wg.Add(1)
+	go proxy.acceptConnections()
+	return proxy, nil
+}
+
+func (proxy *cloudSqlProxy) LocalPort() int {
+	return proxy.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops accepting connections. Connections that were already accepted are closed once either end closes them.
+func (proxy *cloudSqlProxy) Close() {
+	proxy.listener.Close()
+	proxy.wg.Wait()
+}
+
+func (proxy *cloudSqlProxy) acceptConnections() {
+	defer proxy.wg.Done()
+	for {
+		localConn, err := proxy.listener.Accept()
+		if err != nil {
+			return
+		}
+		go proxy.forward(localConn)
+	}
+}
+
+func (proxy *cloudSqlProxy) forward(localConn net.Conn) {
+	defer localConn.Close()
+	remoteConn, err := proxy.dial()
+	if err != nil {
+		return
+	}
+	defer remoteConn.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remoteConn, localConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(localConn, remoteConn)
+		done <- struct{}{}
+	}()
+	// Close both connections as soon as either direction is done.
+	<-done
+}
+
+// scanCloudSqlRows reads all the given rows, formatting each column as a string.
+func scanCloudSqlRows(rows *sql.Rows) ([][]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := [][]string{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make([]string, len(columns))
+		for i, value := range values {
+			row[i] = value.String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// NewSqlAdminService creates a new Cloud SQL Admin service, which is used to make Cloud SQL API calls.
+func NewSqlAdminService(t testing.TestingT) *sqladmin.Service {
+	service, err := NewSqlAdminServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewSqlAdminServiceE creates a new Cloud SQL Admin service, which is used to make Cloud SQL API calls.
+func NewSqlAdminServiceE(t testing.TestingT) (*sqladmin.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := sqladmin.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Cloud SQL Admin service: %v", err)
+	}
+
+	return service, nil
+}
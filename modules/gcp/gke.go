@@ -0,0 +1,145 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/container/v1"
+)
+
+// The statuses of a GKE node pool that it doesn't recover from by itself.
+var gkeNodePoolFailedStatuses = map[string]bool{
+	"ERROR":    true,
+	"STOPPING": true,
+}
+
+// GetGkeCluster gets the GKE cluster with the given name in the given location, which is a region for a regional
+// cluster or a zone for a zonal cluster. This will fail the test if there is an error.
+func GetGkeCluster(t testing.TestingT, projectID string, location string, clusterName string) *container.Cluster {
+	cluster, err := GetGkeClusterE(t, projectID, location, clusterName)
+	require.NoError(t, err)
+	return cluster
+}
+
+// GetGkeClusterE gets the GKE cluster with the given name in the given location, which is a region for a regional
+// cluster or a zone for a zonal cluster.
+func GetGkeClusterE(t testing.TestingT, projectID string, location string, clusterName string) (*container.Cluster, error) {
+	logger.Logf(t, "Getting GKE cluster %s in %s", clusterName, location)
+
+	ctx := context.Background()
+	service, err := NewContainerServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := service.Projects.Locations.Clusters.Get(gkeClusterName(projectID, location, clusterName)).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetGkeClusterE.Get(%s, %s, %s) got error: %v", projectID, location, clusterName, err)
+	}
+
+	return cluster, nil
+}
+
+// GetGkeNodePool gets the node pool with the given name of the given GKE cluster. This will fail the test if there is an
+// error.
+func GetGkeNodePool(t testing.TestingT, projectID string, location string, clusterName string, nodePoolName string) *container.NodePool {
+	nodePool, err := GetGkeNodePoolE(t, projectID, location, clusterName, nodePoolName)
+	require.NoError(t, err)
+	return nodePool
+}
+
+// GetGkeNodePoolE gets the node pool with the given name of the given GKE cluster.
+func GetGkeNodePoolE(t testing.TestingT, projectID string, location string, clusterName string, nodePoolName string) (*container.NodePool, error) {
+	ctx := context.Background()
+	service, err := NewContainerServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s/nodePools/%s", gkeClusterName(projectID, location, clusterName), nodePoolName)
+	nodePool, err := service.Projects.Locations.Clusters.NodePools.Get(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetGkeNodePoolE.Get(%s, %s, %s, %s) got error: %v", projectID, location, clusterName, nodePoolName, err)
+	}
+
+	return nodePool, nil
+}
+
+// WaitUntilGkeNodePoolReady waits until the given node pool of the given GKE cluster is running, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try. This will fail the test if there
+// is an error or if the node pool is not ready.
+func WaitUntilGkeNodePoolReady(t testing.TestingT, projectID string, location string, clusterName string, nodePoolName string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilGkeNodePoolReadyE(t, projectID, location, clusterName, nodePoolName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilGkeNodePoolReadyE waits until the given node pool of the given GKE cluster is running, retrying the check for
+// the specified amount of times, sleeping for the provided duration between each try. This stops waiting if the node
+// pool is in the ERROR or STOPPING status, as it won't become ready.
+func WaitUntilGkeNodePoolReadyE(t testing.TestingT, projectID string, location string, clusterName string, nodePoolName string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for node pool %s of GKE cluster %s to be ready.", nodePoolName, clusterName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			nodePool, err := GetGkeNodePoolE(t, projectID, location, clusterName, nodePoolName)
+			if err != nil {
+				return "", err
+			}
+			if err := checkGkeNodePoolReady(nodePool); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Node pool %s of GKE cluster %s is now ready", nodePoolName, clusterName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkGkeNodePoolReady returns an error if the given node pool is not running, wrapped in a retry.FatalError if it
+// won't become ready.
+func checkGkeNodePoolReady(nodePool *container.NodePool) error {
+	if nodePool.Status == "RUNNING" {
+		return nil
+	}
+	err := fmt.Errorf("node pool %s is %s: %s", nodePool.Name, nodePool.Status, nodePool.StatusMessage)
+	if gkeNodePoolFailedStatuses[nodePool.Status] {
+		return retry.FatalError{Underlying: err}
+	}
+	return err
+}
+
+// gkeClusterName returns the full resource name of the GKE cluster with the given name.
+func gkeClusterName(projectID string, location string, clusterName string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/clusters/%s", projectID, location, clusterName)
+}
+
+// NewContainerService creates a new Container service, which is used to make GKE API calls.
+func NewContainerService(t testing.TestingT) *container.Service {
+	service, err := NewContainerServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewContainerServiceE creates a new Container service, which is used to make GKE API calls.
+func NewContainerServiceE(t testing.TestingT) (*container.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := container.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Container service: %v", err)
+	}
+
+	return service, nil
+}
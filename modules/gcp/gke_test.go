@@ -0,0 +1,35 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/container/v1"
+)
+
+func TestCheckGkeNodePoolReady(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkGkeNodePoolReady(&container.NodePool{Name: "default", Status: "RUNNING"}))
+
+	err := checkGkeNodePoolReady(&container.NodePool{Name: "default", Status: "PROVISIONING"})
+	assert.Error(t, err)
+	_, isFatal := err.(retry.FatalError)
+	assert.False(t, isFatal)
+
+	err = checkGkeNodePoolReady(&container.NodePool{Name: "default", Status: "ERROR", StatusMessage: "quota exceeded"})
+	assert.IsType(t, retry.FatalError{}, err)
+	assert.Contains(t, err.Error(), "quota exceeded")
+}
+
+func TestGkeClusterName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "projects/my-project/locations/us-central1/clusters/test", gkeClusterName("my-project", "us-central1", "test"))
+}
@@ -0,0 +1,265 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	iam "google.golang.org/api/iam/v1"
+)
+
+// iamPolicyVersion is the version of the IAM policies to request, which is the only one that includes the bindings
+// with conditions.
+const iamPolicyVersion = 3
+
+// RequireIamBinding checks that the IAM policy of the given resource grants the given role to the given member, and
+// fails the test if it does not. See RequireIamBindingE for the supported resources.
+func RequireIamBinding(t testing.TestingT, resource string, role string, member string) {
+	err := RequireIamBindingE(t, resource, role, member)
+	require.NoError(t, err)
+}
+
+// RequireIamBindingE checks that the IAM policy of the given resource grants the given role (e.g.,
+// roles/storage.objectViewer) to the given member (e.g., serviceAccount:app@my-project.iam.gserviceaccount.com), and
+// returns an error if it does not. Bindings with conditions count too. The resource is identified by its full name,
+// which is one of:
+//
+//	projects/<project>
+//	projects/<project>/serviceAccounts/<email>
+//	projects/<project>/secrets/<secret>
+//	projects/<project>/topics/<topic>
+//	projects/<project>/subscriptions/<subscription>
+//	projects/_/buckets/<bucket>
+//	projects/<project>/locations/<region>/services/<cloud-run-service>
+//	projects/<project>/locations/<region>/functions/<cloud-function>
+//
+// Only the policy of the resource itself is checked, not the policies it inherits, e.g., from its project.
+func RequireIamBindingE(t testing.TestingT, resource string, role string, member string) error {
+	logger.Logf(t, "Checking that %s has role %s on %s", member, role, resource)
+
+	bindings, err := GetIamPolicyBindingsE(t, resource)
+	if err != nil {
+		return err
+	}
+	if !iamBindingsHaveMember(bindings, role, member) {
+		return fmt.Errorf("IAM policy of %s does not grant role %s to %s", resource, role, member)
+	}
+	return nil
+}
+
+// RequireNoIamBinding checks that the IAM policy of the given resource does not grant the given role to the given
+// member, and fails the test if it does. See RequireIamBindingE for the supported resources.
+func RequireNoIamBinding(t testing.TestingT, resource string, role string, member string) {
+	err := RequireNoIamBindingE(t, resource, role, member)
+	require.NoError(t, err)
+}
+
+// RequireNoIamBindingE checks that the IAM policy of the given resource does not grant the given role to the given
+// member, and returns an error if it does. This is useful to check that a module applies least privilege, e.g., that a
+// service account can read from a bucket but not administer it. See RequireIamBindingE for the supported resources.
+func RequireNoIamBindingE(t testing.TestingT, resource string, role string, member string) error {
+	logger.Logf(t, "Checking that %s does not have role %s on %s", member, role, resource)
+
+	bindings, err := GetIamPolicyBindingsE(t, resource)
+	if err != nil {
+		return err
+	}
+	if iamBindingsHaveMember(bindings, role, member) {
+		return fmt.Errorf("IAM policy of %s grants role %s to %s", resource, role, member)
+	}
+	return nil
+}
+
+// GetIamPolicyBindings returns the members the IAM policy of the given resource grants each role to. This will fail the
+// test if there is an error. See RequireIamBindingE for the supported resources.
+func GetIamPolicyBindings(t testing.TestingT, resource string) map[string][]string {
+	bindings, err := GetIamPolicyBindingsE(t, resource)
+	require.NoError(t, err)
+	return bindings
+}
+
+// GetIamPolicyBindingsE returns the members the IAM policy of the given resource grants each role to, keyed by role,
+// including the members of bindings with conditions. See RequireIamBindingE for the supported resources.
+func GetIamPolicyBindingsE(t testing.TestingT, resource string) (map[string][]string, error) {
+	ctx := context.Background()
+
+	policy, err := getIamPolicyE(t, ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("GetIamPolicyBindingsE.GetIamPolicy(%s) got error: %v", resource, err)
+	}
+
+	return newIamBindings(policy)
+}
+
+// getIamPolicyE gets the IAM policy of the given resource, with the client of the API that serves it.
+func getIamPolicyE(t testing.TestingT, ctx context.Context, resource string) (interface{}, error) {
+	kind, err := getIamResourceKind(resource)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(resource, "/")
+
+	switch kind {
+	case "projects":
+		service, err := NewResourceManagerServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		req := &cloudresourcemanager.GetIamPolicyRequest{
+			Options: &cloudresourcemanager.GetPolicyOptions{RequestedPolicyVersion: iamPolicyVersion},
+		}
+		return service.Projects.GetIamPolicy(parts[1], req).Context(ctx).Do()
+	case "serviceAccounts":
+		service, err := NewIamServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		return service.Projects.ServiceAccounts.GetIamPolicy(resource).OptionsRequestedPolicyVersion(iamPolicyVersion).Context(ctx).Do()
+	case "secrets":
+		service, err := NewSecretManagerServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		return service.Projects.Secrets.GetIamPolicy(resource).OptionsRequestedPolicyVersion(iamPolicyVersion).Context(ctx).Do()
+	case "topics":
+		service, err := NewPubSubServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		return service.Projects.Topics.GetIamPolicy(resource).OptionsRequestedPolicyVersion(iamPolicyVersion).Context(ctx).Do()
+	case "subscriptions":
+		service, err := NewPubSubServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		return service.Projects.Subscriptions.GetIamPolicy(resource).OptionsRequestedPolicyVersion(iamPolicyVersion).Context(ctx).Do()
+	case "buckets":
+		client, err := newStorageClientE(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.Bucket(parts[3]).IAM().V3().Policy(ctx)
+	case "services":
+		service, err := NewCloudRunServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		return service.Projects.Locations.Services.GetIamPolicy(resource).OptionsRequestedPolicyVersion(iamPolicyVersion).Context(ctx).Do()
+	case "functions":
+		service, err := NewCloudFunctionsServiceE(t)
+		if err != nil {
+			return nil, err
+		}
+		return service.Projects.Locations.Functions.GetIamPolicy(resource).OptionsRequestedPolicyVersion(iamPolicyVersion).Context(ctx).Do()
+	}
+	return nil, fmt.Errorf("Getting the IAM policy of %s is not supported", resource)
+}
+
+// getIamResourceKind returns the kind of the given resource, i.e., the collection it belongs to (e.g., secrets), or an
+// error if getting the IAM policy of that kind of resource is not supported.
+func getIamResourceKind(resource string) (string, error) {
+	parts := strings.Split(resource, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "projects":
+		return "projects", nil
+	case len(parts) == 4 && parts[0] == "projects":
+		switch parts[2] {
+		case "serviceAccounts", "secrets", "topics", "subscriptions", "buckets":
+			return parts[2], nil
+		}
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "locations":
+		switch parts[4] {
+		case "services", "functions":
+			return parts[4], nil
+		}
+	}
+	return "", fmt.Errorf("Getting the IAM policy of %s is not supported", resource)
+}
+
+// newIamBindings returns the members the given IAM policy grants each role to. As the policy types of the Google API
+// clients differ but all marshal to the same JSON, the policy is converted through JSON.
+func newIamBindings(policy interface{}) (map[string][]string, error) {
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Bindings []struct {
+			Role    string   `json:"role"`
+			Members []string `json:"members"`
+		} `json:"bindings"`
+	}
+	if err := json.Unmarshal(policyJSON, &parsed); err != nil {
+		return nil, err
+	}
+
+	bindings := map[string][]string{}
+	for _, binding := range parsed.Bindings {
+		bindings[binding.Role] = append(bindings[binding.Role], binding.Members...)
+	}
+	return bindings, nil
+}
+
+// iamBindingsHaveMember returns true if the given bindings grant the given role to the given member.
+func iamBindingsHaveMember(bindings map[string][]string, role string, member string) bool {
+	for _, m := range bindings[role] {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+// NewResourceManagerService creates a new Resource Manager service, which is used to make Resource Manager API calls.
+func NewResourceManagerService(t testing.TestingT) *cloudresourcemanager.Service {
+	service, err := NewResourceManagerServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewResourceManagerServiceE creates a new Resource Manager service, which is used to make Resource Manager API calls.
+func NewResourceManagerServiceE(t testing.TestingT) (*cloudresourcemanager.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := cloudresourcemanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Resource Manager service: %v", err)
+	}
+
+	return service, nil
+}
+
+// NewIamService creates a new IAM service, which is used to make IAM API calls.
+func NewIamService(t testing.TestingT) *iam.Service {
+	service, err := NewIamServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewIamServiceE creates a new IAM service, which is used to make IAM API calls.
+func NewIamServiceE(t testing.TestingT) (*iam.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := iam.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create IAM service: %v", err)
+	}
+
+	return service, nil
+}
@@ -0,0 +1,64 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"cloud.google.com/go/iam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+	iampb "google.golang.org/genproto/googleapis/iam/v1"
+)
+
+func TestGetIamResourceKind(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"projects/my-project": "projects",
+		"projects/my-project/serviceAccounts/app@my-project.iam.gserviceaccount.com": "serviceAccounts",
+		"projects/my-project/secrets/my-secret":                                      "secrets",
+		"projects/my-project/topics/my-topic":                                        "topics",
+		"projects/my-project/subscriptions/my-subscription":                          "subscriptions",
+		"projects/_/buckets/my-bucket":                                               "buckets",
+		"projects/my-project/locations/us-central1/services/my-service":              "services",
+		"projects/my-project/locations/us-central1/functions/my-function":            "functions",
+	}
+	for resource, expectedKind := range testCases {
+		kind, err := getIamResourceKind(resource)
+		require.NoError(t, err, resource)
+		assert.Equal(t, expectedKind, kind, resource)
+	}
+
+	_, err := getIamResourceKind("projects/my-project/instances/my-instance")
+	assert.Error(t, err)
+	_, err = getIamResourceKind("my-bucket")
+	assert.Error(t, err)
+}
+
+func TestNewIamBindings(t *testing.T) {
+	t.Parallel()
+
+	secretPolicy := &secretmanager.Policy{Bindings: []*secretmanager.Binding{
+		{Role: "roles/secretmanager.secretAccessor", Members: []string{"serviceAccount:app@my-project.iam.gserviceaccount.com"}},
+		{Role: "roles/secretmanager.secretAccessor", Members: []string{"group:ops@example.com"}, Condition: &secretmanager.Expr{Expression: "true"}},
+	}}
+	bindings, err := newIamBindings(secretPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"roles/secretmanager.secretAccessor": {"serviceAccount:app@my-project.iam.gserviceaccount.com", "group:ops@example.com"},
+	}, bindings)
+	assert.True(t, iamBindingsHaveMember(bindings, "roles/secretmanager.secretAccessor", "group:ops@example.com"))
+	assert.False(t, iamBindingsHaveMember(bindings, "roles/secretmanager.admin", "group:ops@example.com"))
+
+	bucketPolicy := &iam.Policy3{Bindings: []*iampb.Binding{
+		{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}},
+	}}
+	bindings, err = newIamBindings(bucketPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"roles/storage.objectViewer": {"allUsers"}}, bindings)
+}
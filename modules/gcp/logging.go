@@ -0,0 +1,155 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	logging "google.golang.org/api/logging/v2"
+)
+
+// loggingPollInterval is how often Cloud Logging is polled for new log entries.
+const loggingPollInterval = 5 * time.Second
+
+// GetLogEntries gets the Cloud Logging entries of the given project that match the given filter, oldest first. This
+// will fail the test if there is an error.
+func GetLogEntries(t testing.TestingT, projectID string, filter string) []*logging.LogEntry {
+	entries, err := GetLogEntriesE(t, projectID, filter)
+	require.NoError(t, err)
+	return entries
+}
+
+// GetLogEntriesE gets the Cloud Logging entries of the given project that match the given filter (see
+// https://cloud.google.com/logging/docs/view/logging-query-language), oldest first. Note that Cloud Logging only
+// returns the entries of the last 24 hours, unless the filter restricts the timestamp.
+func GetLogEntriesE(t testing.TestingT, projectID string, filter string) ([]*logging.LogEntry, error) {
+	ctx := context.Background()
+	service, err := NewLoggingServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &logging.ListLogEntriesRequest{
+		ResourceNames: []string{"projects/" + projectID},
+		Filter:        filter,
+		OrderBy:       "timestamp asc",
+		PageSize:      1000,
+	}
+	var entries []*logging.LogEntry
+	err = service.Entries.List(req).Pages(ctx, func(resp *logging.ListLogEntriesResponse) error {
+		entries = append(entries, resp.Entries...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetLogEntriesE.List(%s, %s) got error: %v", projectID, filter, err)
+	}
+
+	return entries, nil
+}
+
+// WaitForLogEntryMatching waits until a log entry that matches the given Cloud Logging filter (or any log entry, if the
+// filter is empty) is written to the given project within the given timeout, and returns it. This will fail the test
+// if there are any errors, or if no matching log entry is found.
+func WaitForLogEntryMatching(t testing.TestingT, projectID string, filter string, timeout time.Duration) *logging.LogEntry {
+	entry, err := WaitForLogEntryMatchingE(t, projectID, filter, timeout)
+	require.NoError(t, err)
+	return entry
+}
+
+// WaitForLogEntryMatchingE waits until a log entry that matches the given Cloud Logging filter (or any log entry, if
+// the filter is empty) is written to the given project within the given timeout, and returns the oldest one. Log
+// entries that were written up to the timeout before this is called are also matched, so that entries caused by an
+// action taken right before calling this are found even though Cloud Logging takes a few seconds to ingest them.
+// Structured entries can be matched on their fields, and checked with GetLogEntryJsonPayload, e.g.:
+//
+//	filter := `resource.type="cloud_run_revision" AND jsonPayload.event="order_created"`
+//	entry := gcp.WaitForLogEntryMatching(t, projectID, filter, 2*time.Minute)
+//	assert.Equal(t, "42", gcp.GetLogEntryJsonPayload(t, entry)["orderId"])
+func WaitForLogEntryMatchingE(t testing.TestingT, projectID string, filter string, timeout time.Duration) (*logging.LogEntry, error) {
+	logger.Logf(t, "Waiting for a log entry matching '%s' in project %s", filter, projectID)
+	timedFilter := newTimedLogFilter(filter, time.Now().Add(-timeout))
+	deadline := time.Now().Add(timeout)
+	for {
+		entries, err := GetLogEntriesE(t, projectID, timedFilter)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			return entries[0], nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, LogEntryNotFound{ProjectID: projectID, Filter: filter, Timeout: timeout}
+		}
+		time.Sleep(loggingPollInterval)
+	}
+}
+
+// newTimedLogFilter returns a Cloud Logging filter that matches the entries that match the given filter and were
+// written since the given time.
+func newTimedLogFilter(filter string, since time.Time) string {
+	timeFilter := fmt.Sprintf(`timestamp>="%s"`, since.UTC().Format(time.RFC3339))
+	if filter == "" {
+		return timeFilter
+	}
+	return fmt.Sprintf("(%s) AND %s", filter, timeFilter)
+}
+
+// GetLogEntryJsonPayload returns the payload of the given structured log entry. This will fail the test if the entry
+// is not structured.
+func GetLogEntryJsonPayload(t testing.TestingT, entry *logging.LogEntry) map[string]interface{} {
+	payload, err := GetLogEntryJsonPayloadE(entry)
+	require.NoError(t, err)
+	return payload
+}
+
+// GetLogEntryJsonPayloadE returns the payload of the given structured log entry, i.e., the fields of its jsonPayload.
+func GetLogEntryJsonPayloadE(entry *logging.LogEntry) (map[string]interface{}, error) {
+	if len(entry.JsonPayload) == 0 {
+		return nil, fmt.Errorf("Log entry %s is not structured", entry.InsertId)
+	}
+
+	payload := map[string]interface{}{}
+	if err := json.Unmarshal(entry.JsonPayload, &payload); err != nil {
+		return nil, fmt.Errorf("Failed to parse the payload of log entry %s: %v", entry.InsertId, err)
+	}
+	return payload, nil
+}
+
+// LogEntryNotFound is an error that occurs if no log entry matching a filter is written within a timeout.
+type LogEntryNotFound struct {
+	ProjectID string
+	Filter    string
+	Timeout   time.Duration
+}
+
+func (err LogEntryNotFound) Error() string {
+	return fmt.Sprintf("No log entry matching '%s' was written to project %s within %s", err.Filter, err.ProjectID, err.Timeout)
+}
+
+// NewLoggingService creates a new Cloud Logging service, which is used to make Cloud Logging API calls.
+func NewLoggingService(t testing.TestingT) *logging.Service {
+	service, err := NewLoggingServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewLoggingServiceE creates a new Cloud Logging service, which is used to make Cloud Logging API calls.
+func NewLoggingServiceE(t testing.TestingT) (*logging.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := logging.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Cloud Logging service: %v", err)
+	}
+
+	return service, nil
+}
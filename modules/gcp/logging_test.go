@@ -0,0 +1,34 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logging "google.golang.org/api/logging/v2"
+)
+
+func TestNewTimedLogFilter(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, `timestamp>="2023-01-02T03:04:05Z"`, newTimedLogFilter("", since))
+	assert.Equal(t, `(severity>=ERROR OR jsonPayload.event="failed") AND timestamp>="2023-01-02T03:04:05Z"`, newTimedLogFilter(`severity>=ERROR OR jsonPayload.event="failed"`, since))
+}
+
+func TestGetLogEntryJsonPayload(t *testing.T) {
+	t.Parallel()
+
+	payload, err := GetLogEntryJsonPayloadE(&logging.LogEntry{InsertId: "abc", JsonPayload: []byte(`{"event": "order_created", "orderId": "42"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"event": "order_created", "orderId": "42"}, payload)
+
+	_, err = GetLogEntryJsonPayloadE(&logging.LogEntry{InsertId: "abc", TextPayload: "order created"})
+	assert.Error(t, err)
+}
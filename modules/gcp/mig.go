@@ -0,0 +1,343 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+)
+
+// MigUpdateProgress is the progress of a rolling update of a Managed Instance Group.
+type MigUpdateProgress struct {
+	// TargetSize is the number of instances the group should have.
+	TargetSize int64
+	// UpToDate is the number of instances created from the instance template of the version they should run.
+	UpToDate int64
+	// Updating is the number of instances that are being created, recreated, deleted or otherwise acted on.
+	Updating int64
+	// VersionTargetReached is true if all the instances run the versions they should.
+	VersionTargetReached bool
+	// Stable is true if no instance is being acted on.
+	Stable bool
+}
+
+// GetManagedInstanceGroup gets the Managed Instance Group with the given name in the given location, which is a zone
+// for a zonal group, or a region for a regional group. This will fail the test if there is an error.
+func GetManagedInstanceGroup(t testing.TestingT, projectID string, location string, name string) *compute.InstanceGroupManager {
+	mig, err := GetManagedInstanceGroupE(t, projectID, location, name)
+	require.NoError(t, err)
+	return mig
+}
+
+// GetManagedInstanceGroupE gets the Managed Instance Group with the given name in the given location, which is a zone
+// for a zonal group, or a region for a regional group.
+func GetManagedInstanceGroupE(t testing.TestingT, projectID string, location string, name string) (*compute.InstanceGroupManager, error) {
+	ctx := context.Background()
+	service, err := NewComputeServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var mig *compute.InstanceGroupManager
+	if isZone(location) {
+		mig, err = service.InstanceGroupManagers.Get(projectID, location, name).Context(ctx).Do()
+	} else {
+		mig, err = service.RegionInstanceGroupManagers.Get(projectID, location, name).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetManagedInstanceGroupE.Get(%s, %s, %s) got error: %v", projectID, location, name, err)
+	}
+
+	return mig, nil
+}
+
+// WaitUntilMigStable waits until the given Managed Instance Group is stable, retrying the check for the specified
+// amount of times, sleeping for the provided duration between each try. This will fail the test if there is an error or
+// if the group does not become stable.
+func WaitUntilMigStable(t testing.TestingT, projectID string, location string, name string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilMigStableE(t, projectID, location, name, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilMigStableE waits until the given Managed Instance Group is stable, i.e., none of its instances is being
+// created, recreated, deleted or otherwise acted on, retrying the check for the specified amount of times, sleeping for
+// the provided duration between each try.
+func WaitUntilMigStableE(t testing.TestingT, projectID string, location string, name string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for Managed Instance Group %s to be stable.", name),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			mig, err := GetManagedInstanceGroupE(t, projectID, location, name)
+			if err != nil {
+				return "", err
+			}
+			if err := checkMigStable(mig); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Managed Instance Group %s is now stable", name), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// checkMigStable returns an error if the given group is not stable.
+func checkMigStable(mig *compute.InstanceGroupManager) error {
+	if pending := countMigPendingActions(mig.CurrentActions); pending > 0 {
+		return fmt.Errorf("Managed Instance Group %s has %d instances being acted on", mig.Name, pending)
+	}
+	if mig.Status == nil || !mig.Status.IsStable {
+		return fmt.Errorf("Managed Instance Group %s is not stable yet", mig.Name)
+	}
+	return nil
+}
+
+// countMigPendingActions returns the number of instances that are being acted on, i.e., that have any current action
+// other than none.
+func countMigPendingActions(actions *compute.InstanceGroupManagerActionsSummary) int64 {
+	if actions == nil {
+		return 0
+	}
+	return actions.Abandoning + actions.Creating + actions.CreatingWithoutRetries + actions.Deleting + actions.Recreating +
+		actions.Refreshing + actions.Restarting + actions.Resuming + actions.Starting + actions.Stopping + actions.Suspending +
+		actions.Verifying
+}
+
+// GetMigAutoscaler gets the autoscaler of the given Managed Instance Group. This will fail the test if there is an
+// error.
+func GetMigAutoscaler(t testing.TestingT, projectID string, location string, migName string) *compute.Autoscaler {
+	autoscaler, err := GetMigAutoscalerE(t, projectID, location, migName)
+	require.NoError(t, err)
+	return autoscaler
+}
+
+// GetMigAutoscalerE gets the autoscaler of the given Managed Instance Group.
+func GetMigAutoscalerE(t testing.TestingT, projectID string, location string, migName string) (*compute.Autoscaler, error) {
+	mig, err := GetManagedInstanceGroupE(t, projectID, location, migName)
+	if err != nil {
+		return nil, err
+	}
+	if mig.Status == nil || mig.Status.Autoscaler == "" {
+		return nil, fmt.Errorf("Managed Instance Group %s has no autoscaler", migName)
+	}
+	autoscalerName := mig.Status.Autoscaler[strings.LastIndex(mig.Status.Autoscaler, "/")+1:]
+
+	ctx := context.Background()
+	service, err := NewComputeServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var autoscaler *compute.Autoscaler
+	if isZone(location) {
+		autoscaler, err = service.Autoscalers.Get(projectID, location, autoscalerName).Context(ctx).Do()
+	} else {
+		autoscaler, err = service.RegionAutoscalers.Get(projectID, location, autoscalerName).Context(ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetMigAutoscalerE.Get(%s, %s, %s) got error: %v", projectID, location, autoscalerName, err)
+	}
+
+	return autoscaler, nil
+}
+
+// AssertMigAutoscaler checks that the autoscaler of the given Managed Instance Group scales between the given numbers
+// of instances, targeting the given CPU utilization. This will fail the test if it does not.
+func AssertMigAutoscaler(t testing.TestingT, projectID string, location string, migName string, expectedMinReplicas int64, expectedMaxReplicas int64, expectedCpuTarget float64) {
+	err := AssertMigAutoscalerE(t, projectID, location, migName, expectedMinReplicas, expectedMaxReplicas, expectedCpuTarget)
+	require.NoError(t, err)
+}
+
+// AssertMigAutoscalerE checks that the autoscaler of the given Managed Instance Group scales between the given numbers
+// of instances, targeting the given CPU utilization (e.g., 0.6). Pass 0 as expectedCpuTarget to skip checking the CPU
+// utilization target, e.g., for autoscalers that scale on other signals.
+func AssertMigAutoscalerE(t testing.TestingT, projectID string, location string, migName string, expectedMinReplicas int64, expectedMaxReplicas int64, expectedCpuTarget float64) error {
+	autoscaler, err := GetMigAutoscalerE(t, projectID, location, migName)
+	if err != nil {
+		return err
+	}
+	return checkMigAutoscaler(autoscaler, expectedMinReplicas, expectedMaxReplicas, expectedCpuTarget)
+}
+
+// checkMigAutoscaler returns an error if the policy of the given autoscaler is not as expected.
+func checkMigAutoscaler(autoscaler *compute.Autoscaler, expectedMinReplicas int64, expectedMaxReplicas int64, expectedCpuTarget float64) error {
+	policy := autoscaler.AutoscalingPolicy
+	if policy == nil {
+		return fmt.Errorf("Autoscaler %s has no autoscaling policy", autoscaler.Name)
+	}
+	if policy.MinNumReplicas != expectedMinReplicas || policy.MaxNumReplicas != expectedMaxReplicas {
+		return fmt.Errorf("Autoscaler %s scales between %d and %d instances instead of %d and %d", autoscaler.Name, policy.MinNumReplicas, policy.MaxNumReplicas, expectedMinReplicas, expectedMaxReplicas)
+	}
+	if expectedCpuTarget == 0 {
+		return nil
+	}
+	if policy.CpuUtilization == nil || policy.CpuUtilization.UtilizationTarget != expectedCpuTarget {
+		cpuTarget := 0.0
+		if policy.CpuUtilization != nil {
+			cpuTarget = policy.CpuUtilization.UtilizationTarget
+		}
+		return fmt.Errorf("Autoscaler %s targets a CPU utilization of %v instead of %v", autoscaler.Name, cpuTarget, expectedCpuTarget)
+	}
+	return nil
+}
+
+// AssertMigInstanceTemplate checks that the given Managed Instance Group creates its instances from the instance
+// template with the given name. This will fail the test if it does not.
+func AssertMigInstanceTemplate(t testing.TestingT, projectID string, location string, migName string, expectedTemplate string) {
+	err := AssertMigInstanceTemplateE(t, projectID, location, migName, expectedTemplate)
+	require.NoError(t, err)
+}
+
+// AssertMigInstanceTemplateE checks that the given Managed Instance Group creates its instances from the instance
+// template with the given name. If the group runs several versions, e.g., during a canary update, the template of any
+// of them matches.
+func AssertMigInstanceTemplateE(t testing.TestingT, projectID string, location string, migName string, expectedTemplate string) error {
+	mig, err := GetManagedInstanceGroupE(t, projectID, location, migName)
+	if err != nil {
+		return err
+	}
+
+	var templates []string
+	for _, version := range mig.Versions {
+		template := version.InstanceTemplate[strings.LastIndex(version.InstanceTemplate, "/")+1:]
+		if template == expectedTemplate {
+			return nil
+		}
+		templates = append(templates, template)
+	}
+	return fmt.Errorf("Managed Instance Group %s creates instances from templates %v instead of %s", migName, templates, expectedTemplate)
+}
+
+// GetInstanceTemplate gets the global instance template with the given name. This will fail the test if there is an
+// error.
+func GetInstanceTemplate(t testing.TestingT, projectID string, name string) *compute.InstanceTemplate {
+	template, err := GetInstanceTemplateE(t, projectID, name)
+	require.NoError(t, err)
+	return template
+}
+
+// GetInstanceTemplateE gets the global instance template with the given name, e.g., to check the machine type, image
+// or metadata of the instances of a Managed Instance Group.
+func GetInstanceTemplateE(t testing.TestingT, projectID string, name string) (*compute.InstanceTemplate, error) {
+	ctx := context.Background()
+	service, err := NewComputeServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := service.InstanceTemplates.Get(projectID, name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetInstanceTemplateE.Get(%s, %s) got error: %v", projectID, name, err)
+	}
+
+	return template, nil
+}
+
+// GetMigUpdateProgress returns the progress of the rolling update of the given Managed Instance Group. This will fail
+// the test if there is an error.
+func GetMigUpdateProgress(t testing.TestingT, projectID string, location string, name string) MigUpdateProgress {
+	progress, err := GetMigUpdateProgressE(t, projectID, location, name)
+	require.NoError(t, err)
+	return progress
+}
+
+// GetMigUpdateProgressE returns the progress of the rolling update of the given Managed Instance Group, i.e., how many
+// of its instances already run the instance template of the version they should.
+func GetMigUpdateProgressE(t testing.TestingT, projectID string, location string, name string) (MigUpdateProgress, error) {
+	mig, err := GetManagedInstanceGroupE(t, projectID, location, name)
+	if err != nil {
+		return MigUpdateProgress{}, err
+	}
+
+	ctx := context.Background()
+	service, err := NewComputeServiceE(t)
+	if err != nil {
+		return MigUpdateProgress{}, err
+	}
+
+	var instances []*compute.ManagedInstance
+	if isZone(location) {
+		err = service.InstanceGroupManagers.ListManagedInstances(projectID, location, name).Pages(ctx, func(resp *compute.InstanceGroupManagersListManagedInstancesResponse) error {
+			instances = append(instances, resp.ManagedInstances...)
+			return nil
+		})
+	} else {
+		err = service.RegionInstanceGroupManagers.ListManagedInstances(projectID, location, name).Pages(ctx, func(resp *compute.RegionInstanceGroupManagersListInstancesResponse) error {
+			instances = append(instances, resp.ManagedInstances...)
+			return nil
+		})
+	}
+	if err != nil {
+		return MigUpdateProgress{}, fmt.Errorf("GetMigUpdateProgressE.ListManagedInstances(%s, %s, %s) got error: %v", projectID, location, name, err)
+	}
+
+	return newMigUpdateProgress(mig, instances), nil
+}
+
+// newMigUpdateProgress returns the progress of the rolling update of the given group, which has the given instances.
+func newMigUpdateProgress(mig *compute.InstanceGroupManager, instances []*compute.ManagedInstance) MigUpdateProgress {
+	templates := map[string]bool{}
+	for _, version := range mig.Versions {
+		templates[version.InstanceTemplate] = true
+	}
+
+	progress := MigUpdateProgress{
+		TargetSize: mig.TargetSize,
+		Updating:   countMigPendingActions(mig.CurrentActions),
+	}
+	if mig.Status != nil {
+		progress.Stable = mig.Status.IsStable
+		progress.VersionTargetReached = mig.Status.VersionTarget != nil && mig.Status.VersionTarget.IsReached
+	}
+	for _, instance := range instances {
+		if instance.Version != nil && templates[instance.Version.InstanceTemplate] && instance.CurrentAction == "NONE" {
+			progress.UpToDate++
+		}
+	}
+	return progress
+}
+
+// WaitUntilMigUpdated waits until the rolling update of the given Managed Instance Group is complete, retrying the
+// check for the specified amount of times, sleeping for the provided duration between each try, and logging the
+// progress of the update. This will fail the test if there is an error or if the update does not complete.
+func WaitUntilMigUpdated(t testing.TestingT, projectID string, location string, name string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := WaitUntilMigUpdatedE(t, projectID, location, name, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// WaitUntilMigUpdatedE waits until the rolling update of the given Managed Instance Group is complete, i.e., all its
+// instances run the versions they should and the group is stable, retrying the check for the specified amount of
+// times, sleeping for the provided duration between each try, and logging the progress of the update.
+func WaitUntilMigUpdatedE(t testing.TestingT, projectID string, location string, name string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the rolling update of Managed Instance Group %s to complete.", name),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			progress, err := GetMigUpdateProgressE(t, projectID, location, name)
+			if err != nil {
+				return "", err
+			}
+			if !progress.VersionTargetReached || !progress.Stable {
+				return "", fmt.Errorf("Managed Instance Group %s has %d of %d instances up to date and %d being updated", name, progress.UpToDate, progress.TargetSize, progress.Updating)
+			}
+			return fmt.Sprintf("Rolling update of Managed Instance Group %s is now complete", name), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// isZone returns true if the given location is a zone (e.g., us-central1-a) rather than a region (e.g., us-central1).
+func isZone(location string) bool {
+	return strings.Count(location, "-") == 2
+}
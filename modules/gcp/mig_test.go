@@ -0,0 +1,75 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestCheckMigStable(t *testing.T) {
+	t.Parallel()
+
+	stable := &compute.InstanceGroupManager{
+		Name:           "my-mig",
+		CurrentActions: &compute.InstanceGroupManagerActionsSummary{None: 3},
+		Status:         &compute.InstanceGroupManagerStatus{IsStable: true},
+	}
+	assert.NoError(t, checkMigStable(stable))
+
+	recreating := &compute.InstanceGroupManager{
+		Name:           "my-mig",
+		CurrentActions: &compute.InstanceGroupManagerActionsSummary{None: 2, Recreating: 1},
+		Status:         &compute.InstanceGroupManagerStatus{IsStable: true},
+	}
+	assert.Error(t, checkMigStable(recreating))
+
+	assert.Error(t, checkMigStable(&compute.InstanceGroupManager{Name: "my-mig", Status: &compute.InstanceGroupManagerStatus{}}))
+}
+
+func TestCheckMigAutoscaler(t *testing.T) {
+	t.Parallel()
+
+	autoscaler := &compute.Autoscaler{Name: "my-autoscaler", AutoscalingPolicy: &compute.AutoscalingPolicy{
+		MinNumReplicas: 2,
+		MaxNumReplicas: 10,
+		CpuUtilization: &compute.AutoscalingPolicyCpuUtilization{UtilizationTarget: 0.6},
+	}}
+
+	assert.NoError(t, checkMigAutoscaler(autoscaler, 2, 10, 0.6))
+	assert.NoError(t, checkMigAutoscaler(autoscaler, 2, 10, 0))
+	assert.Error(t, checkMigAutoscaler(autoscaler, 1, 10, 0.6))
+	assert.Error(t, checkMigAutoscaler(autoscaler, 2, 10, 0.8))
+}
+
+func TestNewMigUpdateProgress(t *testing.T) {
+	t.Parallel()
+
+	newTemplate := "https://www.googleapis.com/compute/v1/projects/my-project/global/instanceTemplates/my-template-v2"
+	oldTemplate := "https://www.googleapis.com/compute/v1/projects/my-project/global/instanceTemplates/my-template-v1"
+	mig := &compute.InstanceGroupManager{
+		TargetSize:     3,
+		Versions:       []*compute.InstanceGroupManagerVersion{{InstanceTemplate: newTemplate}},
+		CurrentActions: &compute.InstanceGroupManagerActionsSummary{None: 2, Creating: 1},
+		Status:         &compute.InstanceGroupManagerStatus{VersionTarget: &compute.InstanceGroupManagerStatusVersionTarget{}},
+	}
+	instances := []*compute.ManagedInstance{
+		{CurrentAction: "NONE", Version: &compute.ManagedInstanceVersion{InstanceTemplate: newTemplate}},
+		{CurrentAction: "CREATING", Version: &compute.ManagedInstanceVersion{InstanceTemplate: newTemplate}},
+		{CurrentAction: "NONE", Version: &compute.ManagedInstanceVersion{InstanceTemplate: oldTemplate}},
+	}
+
+	assert.Equal(t, MigUpdateProgress{TargetSize: 3, UpToDate: 1, Updating: 1}, newMigUpdateProgress(mig, instances))
+}
+
+func TestIsZone(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isZone("us-central1-a"))
+	assert.False(t, isZone("us-central1"))
+}
@@ -0,0 +1,265 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	monitoring "google.golang.org/api/monitoring/v3"
+)
+
+// AlertIncident is an incident of a Cloud Monitoring alert policy, as received by a Pub/Sub notification channel.
+type AlertIncident struct {
+	ID string
+	// PolicyName is the display name of the alert policy.
+	PolicyName string
+	// ConditionName is the display name of the condition that caused the incident.
+	ConditionName string
+	// State is either "open" or "closed".
+	State   string
+	Summary string
+	URL     string
+}
+
+// alertNotification is the payload of the messages Cloud Monitoring publishes to Pub/Sub notification channels.
+type alertNotification struct {
+	Incident struct {
+		IncidentID    string `json:"incident_id"`
+		PolicyName    string `json:"policy_name"`
+		ConditionName string `json:"condition_name"`
+		Condition     struct {
+			Name string `json:"name"`
+		} `json:"condition"`
+		State   string `json:"state"`
+		Summary string `json:"summary"`
+		URL     string `json:"url"`
+	} `json:"incident"`
+}
+
+// GetTimeSeries gets the time series of the given project that match the given Cloud Monitoring filter, with the
+// points since the given time. This will fail the test if there is an error.
+func GetTimeSeries(t testing.TestingT, projectID string, filter string, since time.Time) []*monitoring.TimeSeries {
+	timeSeries, err := GetTimeSeriesE(t, projectID, filter, since)
+	require.NoError(t, err)
+	return timeSeries
+}
+
+// GetTimeSeriesE gets the time series of the given project that match the given Cloud Monitoring filter (e.g.,
+// metric.type="run.googleapis.com/request_count" AND resource.labels.service_name="my-service"), with the points since
+// the given time, newest first. The filter must specify a single metric type.
+func GetTimeSeriesE(t testing.TestingT, projectID string, filter string, since time.Time) ([]*monitoring.TimeSeries, error) {
+	ctx := context.Background()
+	service, err := NewMonitoringServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeSeries []*monitoring.TimeSeries
+	err = service.Projects.TimeSeries.List("projects/"+projectID).
+		Filter(filter).
+		IntervalStartTime(since.UTC().Format(time.RFC3339)).
+		IntervalEndTime(time.Now().UTC().Format(time.RFC3339)).
+		Pages(ctx, func(resp *monitoring.ListTimeSeriesResponse) error {
+			timeSeries = append(timeSeries, resp.TimeSeries...)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("GetTimeSeriesE.List(%s, %s) got error: %v", projectID, filter, err)
+	}
+
+	return timeSeries, nil
+}
+
+// GetLatestMetricValue gets the value of the latest point of the time series of the given project that match the given
+// Cloud Monitoring filter, since the given time. This will fail the test if there is an error or no point.
+func GetLatestMetricValue(t testing.TestingT, projectID string, filter string, since time.Time) float64 {
+	value, err := GetLatestMetricValueE(t, projectID, filter, since)
+	require.NoError(t, err)
+	return value
+}
+
+// GetLatestMetricValueE gets the value of the latest point of the time series of the given project that match the given
+// Cloud Monitoring filter, since the given time. Boolean, integer and double values are supported; distributions are
+// represented by their mean. Note that metrics usually take a few minutes to show up in Cloud Monitoring.
+func GetLatestMetricValueE(t testing.TestingT, projectID string, filter string, since time.Time) (float64, error) {
+	timeSeries, err := GetTimeSeriesE(t, projectID, filter, since)
+	if err != nil {
+		return 0, err
+	}
+	return getLatestTimeSeriesValue(timeSeries, filter)
+}
+
+// getLatestTimeSeriesValue returns the value of the latest point of the given time series.
+func getLatestTimeSeriesValue(timeSeries []*monitoring.TimeSeries, filter string) (float64, error) {
+	var latest *monitoring.Point
+	var latestTime time.Time
+	for _, series := range timeSeries {
+		// The points of each time series are in reverse time order.
+		if len(series.Points) == 0 {
+			continue
+		}
+		point := series.Points[0]
+		pointTime, _ := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+		if latest == nil || pointTime.After(latestTime) {
+			latest, latestTime = point, pointTime
+		}
+	}
+	if latest == nil {
+		return 0, fmt.Errorf("No time series matching '%s' has points", filter)
+	}
+	return getTypedValue(latest.Value)
+}
+
+// getTypedValue returns the given metric value as a float.
+func getTypedValue(value *monitoring.TypedValue) (float64, error) {
+	switch {
+	case value.DoubleValue != nil:
+		return *value.DoubleValue, nil
+	case value.Int64Value != nil:
+		return float64(*value.Int64Value), nil
+	case value.BoolValue != nil:
+		if *value.BoolValue {
+			return 1, nil
+		}
+		return 0, nil
+	case value.DistributionValue != nil:
+		return value.DistributionValue.Mean, nil
+	default:
+		return 0, fmt.Errorf("Unsupported metric value %+v", value)
+	}
+}
+
+// GetAlertPolicy gets the Cloud Monitoring alert policy with the given resource name (e.g.,
+// projects/my-project/alertPolicies/1234567890). This will fail the test if there is an error.
+func GetAlertPolicy(t testing.TestingT, policyName string) *monitoring.AlertPolicy {
+	policy, err := GetAlertPolicyE(t, policyName)
+	require.NoError(t, err)
+	return policy
+}
+
+// GetAlertPolicyE gets the Cloud Monitoring alert policy with the given resource name (e.g.,
+// projects/my-project/alertPolicies/1234567890), which is the id terraform exports for google_monitoring_alert_policy.
+func GetAlertPolicyE(t testing.TestingT, policyName string) (*monitoring.AlertPolicy, error) {
+	ctx := context.Background()
+	service, err := NewMonitoringServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := service.Projects.AlertPolicies.Get(policyName).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetAlertPolicyE.Get(%s) got error: %v", policyName, err)
+	}
+
+	return policy, nil
+}
+
+// WaitUntilAlertPolicyFires waits until the given Cloud Monitoring alert policy opens an incident, retrying the check
+// for the specified amount of times, sleeping for the provided duration between each try, and returns the incident.
+// This will fail the test if there is an error or if the policy doesn't fire.
+func WaitUntilAlertPolicyFires(t testing.TestingT, policyName string, projectID string, subscriptionName string, maxRetries int, sleepBetweenRetries time.Duration) *AlertIncident {
+	incident, err := WaitUntilAlertPolicyFiresE(t, policyName, projectID, subscriptionName, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return incident
+}
+
+// WaitUntilAlertPolicyFiresE waits until the given Cloud Monitoring alert policy (given by its resource name) opens an
+// incident, retrying the check for the specified amount of times, sleeping for the provided duration between each try,
+// and returns the incident. As Cloud Monitoring has no API to list incidents, this watches the notifications of a
+// Pub/Sub notification channel of the policy: pass a subscription (in the given project) to the topic of that channel,
+// which should be dedicated to the test, as all the messages pulled from it are acknowledged. Note that policies take
+// at least the duration of their conditions to fire once the conditions are induced, e.g.:
+//
+//	gcp.WaitUntilAlertPolicyFires(t, policyName, projectID, "alerts-test", 60, 10*time.Second)
+func WaitUntilAlertPolicyFiresE(t testing.TestingT, policyName string, projectID string, subscriptionName string, maxRetries int, sleepBetweenRetries time.Duration) (*AlertIncident, error) {
+	policy, err := GetAlertPolicyE(t, policyName)
+	if err != nil {
+		return nil, err
+	}
+
+	var incident *AlertIncident
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for alert policy %s to fire.", policy.DisplayName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			messages, err := PullMessagesE(t, projectID, subscriptionName, 100, true)
+			if err != nil {
+				return "", err
+			}
+			incident = findOpenAlertIncident(messages, policy)
+			if incident == nil {
+				return "", fmt.Errorf("Alert policy %s has not fired yet", policy.DisplayName)
+			}
+			return fmt.Sprintf("Alert policy %s fired: %s", policy.DisplayName, incident.Summary), nil
+		},
+	)
+	logger.Logf(t, msg)
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// findOpenAlertIncident returns the first open incident of the given policy in the given notification messages, or nil
+// if there is none. Incidents are matched by the resource name of their condition, which starts with that of the
+// policy, or by the display name of the policy if their condition is not included.
+func findOpenAlertIncident(messages []PubSubMessage, policy *monitoring.AlertPolicy) *AlertIncident {
+	for _, message := range messages {
+		var notification alertNotification
+		if err := json.Unmarshal([]byte(message.Data), &notification); err != nil {
+			continue
+		}
+		incident := notification.Incident
+		if incident.State != "open" {
+			continue
+		}
+		if incident.Condition.Name != "" {
+			if !strings.HasPrefix(incident.Condition.Name, policy.Name+"/") {
+				continue
+			}
+		} else if incident.PolicyName != policy.DisplayName {
+			continue
+		}
+		return &AlertIncident{
+			ID:            incident.IncidentID,
+			PolicyName:    incident.PolicyName,
+			ConditionName: incident.ConditionName,
+			State:         incident.State,
+			Summary:       incident.Summary,
+			URL:           incident.URL,
+		}
+	}
+	return nil
+}
+
+// NewMonitoringService creates a new Cloud Monitoring service, which is used to make Cloud Monitoring API calls.
+func NewMonitoringService(t testing.TestingT) *monitoring.Service {
+	service, err := NewMonitoringServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewMonitoringServiceE creates a new Cloud Monitoring service, which is used to make Cloud Monitoring API calls.
+func NewMonitoringServiceE(t testing.TestingT) (*monitoring.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := monitoring.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Cloud Monitoring service: %v", err)
+	}
+
+	return service, nil
+}
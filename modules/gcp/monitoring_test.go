@@ -0,0 +1,62 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	monitoring "google.golang.org/api/monitoring/v3"
+)
+
+func TestGetLatestTimeSeriesValue(t *testing.T) {
+	t.Parallel()
+
+	requests := int64(7)
+	latency := 0.25
+	timeSeries := []*monitoring.TimeSeries{
+		{Points: []*monitoring.Point{
+			{Interval: &monitoring.TimeInterval{EndTime: "2023-01-02T03:04:00Z"}, Value: &monitoring.TypedValue{Int64Value: &requests}},
+		}},
+		{Points: []*monitoring.Point{
+			{Interval: &monitoring.TimeInterval{EndTime: "2023-01-02T03:05:00.5Z"}, Value: &monitoring.TypedValue{DoubleValue: &latency}},
+			{Interval: &monitoring.TimeInterval{EndTime: "2023-01-02T03:04:00Z"}, Value: &monitoring.TypedValue{Int64Value: &requests}},
+		}},
+		{},
+	}
+
+	value, err := getLatestTimeSeriesValue(timeSeries, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0.25, value)
+
+	value, err = getLatestTimeSeriesValue(timeSeries[:1], "")
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, value)
+
+	_, err = getLatestTimeSeriesValue(timeSeries[2:], "")
+	assert.Error(t, err)
+}
+
+func TestFindOpenAlertIncident(t *testing.T) {
+	t.Parallel()
+
+	policy := &monitoring.AlertPolicy{Name: "projects/my-project/alertPolicies/123", DisplayName: "High error rate"}
+	messages := []PubSubMessage{
+		{Data: "not json"},
+		{Data: `{"incident": {"incident_id": "1", "state": "closed", "condition": {"name": "projects/my-project/alertPolicies/123/conditions/456"}}}`},
+		{Data: `{"incident": {"incident_id": "2", "state": "open", "condition": {"name": "projects/my-project/alertPolicies/1234/conditions/456"}}}`},
+		{Data: `{"incident": {"incident_id": "3", "state": "open", "policy_name": "High error rate", "summary": "Error rate is above 5%", "condition": {"name": "projects/my-project/alertPolicies/123/conditions/456"}}}`},
+	}
+
+	incident := findOpenAlertIncident(messages, policy)
+	require.NotNil(t, incident)
+	assert.Equal(t, "3", incident.ID)
+	assert.Equal(t, "Error rate is above 5%", incident.Summary)
+
+	assert.Nil(t, findOpenAlertIncident(messages[:3], policy))
+	assert.NotNil(t, findOpenAlertIncident([]PubSubMessage{{Data: `{"incident": {"state": "open", "policy_name": "High error rate"}}`}}, policy))
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/oslogin/v1"
 )
@@ -120,7 +119,7 @@ func GetLoginProfileE(t testing.TestingT, user string) (*oslogin.LoginProfile, e
 func NewOSLoginServiceE(t testing.TestingT) (*oslogin.Service, error) {
 	ctx := context.Background()
 
-	client, err := google.DefaultClient(ctx, compute.CloudPlatformScope)
+	client, err := newHttpClientE(ctx, compute.CloudPlatformScope)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get default client: %v", err)
 	}
@@ -0,0 +1,358 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/pubsub/v1"
+)
+
+// pubSubTestIDAttribute is the attribute AssertMessageDeadLetteredE tags the message it publishes with, to find it on the
+// dead-letter topic.
+const pubSubTestIDAttribute = "terratest-id"
+
+// PubSubMessage is a message pulled from a Pub/Sub subscription.
+type PubSubMessage struct {
+	ID         string
+	Data       string
+	Attributes map[string]string
+	// DeliveryAttempt is the number of times the message was delivered, if the subscription has a dead-letter policy.
+	DeliveryAttempt int64
+	// AckID is the ID to acknowledge the message with, if it was not acknowledged when it was pulled.
+	AckID string
+}
+
+// AssertPubSubTopicExists checks if the given Pub/Sub topic exists and fails the test if it does not.
+func AssertPubSubTopicExists(t testing.TestingT, projectID string, topicName string) {
+	err := AssertPubSubTopicExistsE(t, projectID, topicName)
+	require.NoError(t, err)
+}
+
+// AssertPubSubTopicExistsE checks if the given Pub/Sub topic exists and returns an error if it does not.
+func AssertPubSubTopicExistsE(t testing.TestingT, projectID string, topicName string) error {
+	logger.Logf(t, "Finding Pub/Sub topic %s", topicName)
+
+	ctx := context.Background()
+	service, err := NewPubSubServiceE(t)
+	if err != nil {
+		return err
+	}
+
+	if _, err := service.Projects.Topics.Get(pubSubTopicName(projectID, topicName)).Context(ctx).Do(); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("Pub/Sub topic %s does not exist in project %s", topicName, projectID)
+		}
+		return err
+	}
+	return nil
+}
+
+// AssertPubSubSubscriptionExists checks if the given Pub/Sub subscription exists and fails the test if it does not.
+func AssertPubSubSubscriptionExists(t testing.TestingT, projectID string, subscriptionName string) {
+	err := AssertPubSubSubscriptionExistsE(t, projectID, subscriptionName)
+	require.NoError(t, err)
+}
+
+// AssertPubSubSubscriptionExistsE checks if the given Pub/Sub subscription exists and returns an error if it does not.
+func AssertPubSubSubscriptionExistsE(t testing.TestingT, projectID string, subscriptionName string) error {
+	logger.Logf(t, "Finding Pub/Sub subscription %s", subscriptionName)
+
+	_, err := getPubSubSubscriptionE(t, projectID, subscriptionName)
+	return err
+}
+
+// PublishMessage publishes a message with the given data and attributes to the given Pub/Sub topic, and returns its ID.
+// This will fail the test if there is an error.
+func PublishMessage(t testing.TestingT, projectID string, topicName string, data string, attributes map[string]string) string {
+	messageID, err := PublishMessageE(t, projectID, topicName, data, attributes)
+	require.NoError(t, err)
+	return messageID
+}
+
+// PublishMessageE publishes a message with the given data and attributes to the given Pub/Sub topic, and returns its ID.
+func PublishMessageE(t testing.TestingT, projectID string, topicName string, data string, attributes map[string]string) (string, error) {
+	logger.Logf(t, "Publishing message to Pub/Sub topic %s", topicName)
+
+	ctx := context.Background()
+	service, err := NewPubSubServiceE(t)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := service.Projects.Topics.Publish(pubSubTopicName(projectID, topicName), &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString([]byte(data)),
+			Attributes: attributes,
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("PublishMessageE.Publish(%s, %s) got error: %v", projectID, topicName, err)
+	}
+	return resp.MessageIds[0], nil
+}
+
+// PullMessages pulls up to the given number of messages from the given Pub/Sub subscription, acknowledging them if ack
+// is true. This will fail the test if there is an error.
+func PullMessages(t testing.TestingT, projectID string, subscriptionName string, maxMessages int64, ack bool) []PubSubMessage {
+	messages, err := PullMessagesE(t, projectID, subscriptionName, maxMessages, ack)
+	require.NoError(t, err)
+	return messages
+}
+
+// PullMessagesE pulls up to the given number of messages from the given Pub/Sub subscription, waiting a few seconds for
+// messages if there are none, and acknowledging them if ack is true. If ack is false, the messages are redelivered once
+// their ack deadline expires, unless they are acknowledged with AcknowledgeMessagesE.
+func PullMessagesE(t testing.TestingT, projectID string, subscriptionName string, maxMessages int64, ack bool) ([]PubSubMessage, error) {
+	ctx := context.Background()
+	service, err := NewPubSubServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	name := pubSubSubscriptionName(projectID, subscriptionName)
+	resp, err := service.Projects.Subscriptions.Pull(name, &pubsub.PullRequest{MaxMessages: maxMessages}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("PullMessagesE.Pull(%s, %s) got error: %v", projectID, subscriptionName, err)
+	}
+
+	messages, err := newPubSubMessages(resp.ReceivedMessages)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logf(t, "Pulled %d messages from Pub/Sub subscription %s", len(messages), subscriptionName)
+
+	if ack && len(messages) > 0 {
+		ackIDs := []string{}
+		for _, message := range messages {
+			ackIDs = append(ackIDs, message.AckID)
+		}
+		if err := AcknowledgeMessagesE(t, projectID, subscriptionName, ackIDs); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// AcknowledgeMessages acknowledges the messages with the given ack IDs on the given Pub/Sub subscription, so that they
+// are not redelivered. This will fail the test if there is an error.
+func AcknowledgeMessages(t testing.TestingT, projectID string, subscriptionName string, ackIDs []string) {
+	err := AcknowledgeMessagesE(t, projectID, subscriptionName, ackIDs)
+	require.NoError(t, err)
+}
+
+// AcknowledgeMessagesE acknowledges the messages with the given ack IDs on the given Pub/Sub subscription, so that they
+// are not redelivered.
+func AcknowledgeMessagesE(t testing.TestingT, projectID string, subscriptionName string, ackIDs []string) error {
+	ctx := context.Background()
+	service, err := NewPubSubServiceE(t)
+	if err != nil {
+		return err
+	}
+
+	name := pubSubSubscriptionName(projectID, subscriptionName)
+	if _, err := service.Projects.Subscriptions.Acknowledge(name, &pubsub.AcknowledgeRequest{AckIds: ackIDs}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("AcknowledgeMessagesE.Acknowledge(%s, %s) got error: %v", projectID, subscriptionName, err)
+	}
+	return nil
+}
+
+// AssertMessageDeadLettered publishes a message with the given data to the topic of the given Pub/Sub subscription, and
+// checks that it's forwarded to the dead-letter topic of the subscription once the subscription fails to deliver it.
+// This will fail the test if it is not.
+func AssertMessageDeadLettered(t testing.TestingT, projectID string, subscriptionName string, data string, maxRetries int, sleepBetweenRetries time.Duration) {
+	err := AssertMessageDeadLetteredE(t, projectID, subscriptionName, data, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+}
+
+// AssertMessageDeadLetteredE publishes a message with the given data to the topic of the given pull subscription, and
+// checks that it's forwarded to the dead-letter topic of the subscription once it has been delivered the max number of
+// times of the dead-letter policy, retrying the check for the specified amount of times, sleeping for the provided
+// duration between each try. This plays the part of a consumer that fails to process the message: it pulls the message
+// from the subscription and nacks it, so that it's redelivered. As the other messages it pulls count as delivery
+// attempts too, don't publish other messages to the topic while this runs. To observe the dead-lettering, this creates a
+// temporary subscription to the dead-letter topic, which is deleted before returning.
+//
+// This checks that the Pub/Sub service account is allowed to publish to the dead-letter topic and to acknowledge the
+// messages of the subscription, which is easily missed when setting up a dead-letter policy.
+func AssertMessageDeadLetteredE(t testing.TestingT, projectID string, subscriptionName string, data string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	ctx := context.Background()
+	service, err := NewPubSubServiceE(t)
+	if err != nil {
+		return err
+	}
+	subscription, err := getPubSubSubscriptionE(t, projectID, subscriptionName)
+	if err != nil {
+		return err
+	}
+	if subscription.DeadLetterPolicy == nil || subscription.DeadLetterPolicy.DeadLetterTopic == "" {
+		return fmt.Errorf("Pub/Sub subscription %s has no dead-letter policy", subscriptionName)
+	}
+
+	deadLetterSubscriptionName := RandomValidGcpName()
+	logger.Logf(t, "Creating temporary subscription %s to dead-letter topic %s", deadLetterSubscriptionName, subscription.DeadLetterPolicy.DeadLetterTopic)
+	_, err = service.Projects.Subscriptions.Create(pubSubSubscriptionName(projectID, deadLetterSubscriptionName), &pubsub.Subscription{
+		Topic: subscription.DeadLetterPolicy.DeadLetterTopic,
+	}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := service.Projects.Subscriptions.Delete(pubSubSubscriptionName(projectID, deadLetterSubscriptionName)).Context(ctx).Do(); err != nil {
+			logger.Logf(t, "Failed to delete temporary subscription %s: %v", deadLetterSubscriptionName, err)
+		}
+	}()
+
+	testID := random.UniqueId()
+	topicName := subscription.Topic[strings.LastIndex(subscription.Topic, "/")+1:]
+	if _, err := PublishMessageE(t, projectID, topicName, data, map[string]string{pubSubTestIDAttribute: testID}); err != nil {
+		return err
+	}
+
+	msg, err := retry.DoWithRetryE(
+		t,
+		fmt.Sprintf("Waiting for the message to be dead-lettered by Pub/Sub subscription %s.", subscriptionName),
+		maxRetries,
+		sleepBetweenRetries,
+		func() (string, error) {
+			messages, err := PullMessagesE(t, projectID, subscriptionName, 10, false)
+			if err != nil {
+				return "", err
+			}
+			if err := nackPubSubTestMessagesE(ctx, service, projectID, subscriptionName, messages, testID); err != nil {
+				return "", err
+			}
+
+			deadLettered, err := PullMessagesE(t, projectID, deadLetterSubscriptionName, 10, true)
+			if err != nil {
+				return "", err
+			}
+			if findPubSubTestMessage(deadLettered, testID) == nil {
+				return "", fmt.Errorf("message %s was not dead-lettered yet", testID)
+			}
+			return fmt.Sprintf("Pub/Sub subscription %s dead-lettered the message", subscriptionName), nil
+		},
+	)
+	logger.Logf(t, msg)
+	return err
+}
+
+// nackPubSubTestMessagesE makes the given subscription redeliver the given messages that are tagged with the given test
+// ID right away.
+func nackPubSubTestMessagesE(ctx context.Context, service *pubsub.Service, projectID string, subscriptionName string, messages []PubSubMessage, testID string) error {
+	ackIDs := []string{}
+	for _, message := range messages {
+		if message.Attributes[pubSubTestIDAttribute] == testID {
+			ackIDs = append(ackIDs, message.AckID)
+		}
+	}
+	if len(ackIDs) == 0 {
+		return nil
+	}
+	_, err := service.Projects.Subscriptions.ModifyAckDeadline(pubSubSubscriptionName(projectID, subscriptionName), &pubsub.ModifyAckDeadlineRequest{
+		AckIds:             ackIDs,
+		AckDeadlineSeconds: 0,
+		ForceSendFields:    []string{"AckDeadlineSeconds"},
+	}).Context(ctx).Do()
+	return err
+}
+
+// findPubSubTestMessage returns the message among the given ones that is tagged with the given test ID, or nil if there
+// is none.
+func findPubSubTestMessage(messages []PubSubMessage, testID string) *PubSubMessage {
+	for i, message := range messages {
+		if message.Attributes[pubSubTestIDAttribute] == testID {
+			return &messages[i]
+		}
+	}
+	return nil
+}
+
+// newPubSubMessages converts the given messages received from the Pub/Sub API, decoding their data.
+func newPubSubMessages(received []*pubsub.ReceivedMessage) ([]PubSubMessage, error) {
+	messages := []PubSubMessage{}
+	for _, receivedMessage := range received {
+		if receivedMessage.Message == nil {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(receivedMessage.Message.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode data of Pub/Sub message %s: %v", receivedMessage.Message.MessageId, err)
+		}
+		messages = append(messages, PubSubMessage{
+			ID:              receivedMessage.Message.MessageId,
+			Data:            string(data),
+			Attributes:      receivedMessage.Message.Attributes,
+			DeliveryAttempt: receivedMessage.DeliveryAttempt,
+			AckID:           receivedMessage.AckId,
+		})
+	}
+	return messages, nil
+}
+
+// getPubSubSubscriptionE gets the given Pub/Sub subscription, returning an error if it does not exist.
+func getPubSubSubscriptionE(t testing.TestingT, projectID string, subscriptionName string) (*pubsub.Subscription, error) {
+	ctx := context.Background()
+	service, err := NewPubSubServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := service.Projects.Subscriptions.Get(pubSubSubscriptionName(projectID, subscriptionName)).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("Pub/Sub subscription %s does not exist in project %s", subscriptionName, projectID)
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// pubSubTopicName returns the full resource name of the given Pub/Sub topic.
+func pubSubTopicName(projectID string, topicName string) string {
+	return fmt.Sprintf("projects/%s/topics/%s", projectID, topicName)
+}
+
+// pubSubSubscriptionName returns the full resource name of the given Pub/Sub subscription.
+func pubSubSubscriptionName(projectID string, subscriptionName string) string {
+	return fmt.Sprintf("projects/%s/subscriptions/%s", projectID, subscriptionName)
+}
+
+// isNotFound returns true if the given error is a Google API error for a resource that does not exist.
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	return ok && apiErr.Code == http.StatusNotFound
+}
+
+// NewPubSubService creates a new Pub/Sub service, which is used to make Pub/Sub API calls.
+func NewPubSubService(t testing.TestingT) *pubsub.Service {
+	service, err := NewPubSubServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewPubSubServiceE creates a new Pub/Sub service, which is used to make Pub/Sub API calls.
+func NewPubSubServiceE(t testing.TestingT) (*pubsub.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Pub/Sub service: %v", err)
+	}
+
+	return service, nil
+}
@@ -0,0 +1,53 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/pubsub/v1"
+)
+
+func TestNewPubSubMessages(t *testing.T) {
+	t.Parallel()
+
+	messages, err := newPubSubMessages([]*pubsub.ReceivedMessage{
+		{
+			AckId:           "ack-1",
+			DeliveryAttempt: 2,
+			Message: &pubsub.PubsubMessage{
+				MessageId:  "1",
+				Data:       base64.StdEncoding.EncodeToString([]byte("hello")),
+				Attributes: map[string]string{pubSubTestIDAttribute: "abc"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []PubSubMessage{{
+		ID:              "1",
+		Data:            "hello",
+		Attributes:      map[string]string{pubSubTestIDAttribute: "abc"},
+		DeliveryAttempt: 2,
+		AckID:           "ack-1",
+	}}, messages)
+
+	_, err = newPubSubMessages([]*pubsub.ReceivedMessage{{Message: &pubsub.PubsubMessage{Data: "not base64!"}}})
+	assert.Error(t, err)
+}
+
+func TestFindPubSubTestMessage(t *testing.T) {
+	t.Parallel()
+
+	messages := []PubSubMessage{
+		{ID: "1", Attributes: map[string]string{"other": "abc"}},
+		{ID: "2", Attributes: map[string]string{pubSubTestIDAttribute: "abc"}},
+	}
+	assert.Equal(t, "2", findPubSubTestMessage(messages, "abc").ID)
+	assert.Nil(t, findPubSubTestMessage(messages, "def"))
+}
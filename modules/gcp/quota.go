@@ -0,0 +1,142 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+)
+
+// QuotaNotAvailable is an error that occurs if a Compute Engine quota doesn't have enough headroom left for a test.
+type QuotaNotAvailable struct {
+	ProjectID string
+	Region    string
+	Metric    string
+	Needed    float64
+	Usage     float64
+	Limit     float64
+}
+
+func (err QuotaNotAvailable) Error() string {
+	scope := "region " + err.Region
+	if err.Region == "" {
+		scope = "all regions"
+	}
+	return fmt.Sprintf(
+		"Quota %s of project %s in %s has %g available (usage %g of limit %g), but the test needs %g: free up resources or request a quota increase",
+		err.Metric, err.ProjectID, scope, err.Limit-err.Usage, err.Usage, err.Limit, err.Needed,
+	)
+}
+
+// GetQuota gets the Compute Engine quota with the given metric (e.g., CPUS or IN_USE_ADDRESSES) in the given region, or
+// the project-wide quota if the region is empty. This will fail the test if there is an error.
+func GetQuota(t testing.TestingT, projectID string, region string, metric string) *compute.Quota {
+	quota, err := GetQuotaE(t, projectID, region, metric)
+	require.NoError(t, err)
+	return quota
+}
+
+// GetQuotaE gets the Compute Engine quota with the given metric (e.g., CPUS or IN_USE_ADDRESSES) in the given region,
+// or the project-wide quota (e.g., for SNAPSHOTS or NETWORKS) if the region is empty. The quota has both its current
+// usage and its limit.
+func GetQuotaE(t testing.TestingT, projectID string, region string, metric string) (*compute.Quota, error) {
+	ctx := context.Background()
+	service, err := NewComputeServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotas []*compute.Quota
+	if region == "" {
+		project, err := service.Projects.Get(projectID).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("GetQuotaE.Projects.Get(%s) got error: %v", projectID, err)
+		}
+		quotas = project.Quotas
+	} else {
+		computeRegion, err := service.Regions.Get(projectID, region).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("GetQuotaE.Regions.Get(%s, %s) got error: %v", projectID, region, err)
+		}
+		quotas = computeRegion.Quotas
+	}
+
+	quota := findQuota(quotas, metric)
+	if quota == nil {
+		return nil, fmt.Errorf("Project %s has no Compute Engine quota %s in region %q", projectID, metric, region)
+	}
+	return quota, nil
+}
+
+// findQuota returns the quota with the given metric, or nil if there is none.
+func findQuota(quotas []*compute.Quota, metric string) *compute.Quota {
+	for _, quota := range quotas {
+		if strings.EqualFold(quota.Metric, metric) {
+			return quota
+		}
+	}
+	return nil
+}
+
+// RequireQuotaAvailable checks that the given Compute Engine quota has at least the needed amount available. This will
+// fail the test right away if it does not.
+func RequireQuotaAvailable(t testing.TestingT, projectID string, region string, metric string, needed float64) {
+	err := RequireQuotaAvailableE(t, projectID, region, metric, needed)
+	require.NoError(t, err)
+}
+
+// RequireQuotaAvailableE checks that the given Compute Engine quota (e.g., CPUS in us-central1, or a project-wide quota
+// if the region is empty) has at least the needed amount available, and returns a QuotaNotAvailable error if it does
+// not. Call this before terraform apply, so that the test fails fast with a clear message instead of with a
+// RESOURCE_EXHAUSTED error in the middle of the apply, e.g.:
+//
+//	gcp.RequireQuotaAvailable(t, projectID, region, "CPUS", 8)
+//	gcp.RequireQuotaAvailable(t, projectID, region, "IN_USE_ADDRESSES", 2)
+//	terraform.InitAndApply(t, terraformOptions)
+//
+// Note that tests running in parallel consume the same quota, so this is only a best-effort check.
+func RequireQuotaAvailableE(t testing.TestingT, projectID string, region string, metric string, needed float64) error {
+	quota, err := GetQuotaE(t, projectID, region, metric)
+	if err != nil {
+		return err
+	}
+	return checkQuotaAvailable(quota, projectID, region, needed)
+}
+
+// checkQuotaAvailable returns a QuotaNotAvailable error if the given quota doesn't have the needed amount available.
+func checkQuotaAvailable(quota *compute.Quota, projectID string, region string, needed float64) error {
+	// A negative limit means that the quota is unlimited.
+	if quota.Limit < 0 || quota.Limit-quota.Usage >= needed {
+		return nil
+	}
+	return QuotaNotAvailable{
+		ProjectID: projectID,
+		Region:    region,
+		Metric:    quota.Metric,
+		Needed:    needed,
+		Usage:     quota.Usage,
+		Limit:     quota.Limit,
+	}
+}
+
+// skipper is implemented by the test types that can be skipped, such as *testing.T.
+type skipper interface {
+	Skipf(format string, args ...interface{})
+}
+
+// SkipIfQuotaNotAvailable skips the test if the given Compute Engine quota doesn't have at least the needed amount
+// available, e.g., so that a shared test project that's temporarily exhausted doesn't fail the whole suite. The test
+// is failed instead if the quota can't be checked, or if the test can't be skipped.
+func SkipIfQuotaNotAvailable(t testing.TestingT, projectID string, region string, metric string, needed float64) {
+	err := RequireQuotaAvailableE(t, projectID, region, metric, needed)
+	var notAvailable QuotaNotAvailable
+	if s, ok := t.(skipper); ok && errors.As(err, &notAvailable) {
+		s.Skipf("Skipping test: %v", err)
+		return
+	}
+	require.NoError(t, err)
+}
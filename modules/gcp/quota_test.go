@@ -0,0 +1,36 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestFindQuota(t *testing.T) {
+	t.Parallel()
+
+	quotas := []*compute.Quota{{Metric: "CPUS", Limit: 24}, {Metric: "IN_USE_ADDRESSES", Limit: 8}}
+	assert.Equal(t, quotas[1], findQuota(quotas, "in_use_addresses"))
+	assert.Nil(t, findQuota(quotas, "SSD_TOTAL_GB"))
+}
+
+func TestCheckQuotaAvailable(t *testing.T) {
+	t.Parallel()
+
+	quota := &compute.Quota{Metric: "CPUS", Limit: 24, Usage: 20}
+	assert.NoError(t, checkQuotaAvailable(quota, "my-project", "us-central1", 4))
+
+	err := checkQuotaAvailable(quota, "my-project", "us-central1", 8)
+	require.Error(t, err)
+	assert.IsType(t, QuotaNotAvailable{}, err)
+	assert.Contains(t, err.Error(), "Quota CPUS of project my-project in region us-central1 has 4 available")
+
+	assert.NoError(t, checkQuotaAvailable(&compute.Quota{Metric: "CPUS", Limit: -1, Usage: 100}, "my-project", "", 8))
+}
@@ -0,0 +1,273 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+// CreateSecret creates a Secret Manager secret with the given ID and adds a first version with the given value, and
+// returns the name of the version. This will fail the test if there is an error.
+func CreateSecret(t testing.TestingT, projectID string, secretID string, secretString string, replicaLocations []string) string {
+	versionName, err := CreateSecretE(t, projectID, secretID, secretString, replicaLocations)
+	require.NoError(t, err)
+	return versionName
+}
+
+// CreateSecretE creates a Secret Manager secret with the given ID and adds a first version with the given value, and
+// returns the name of the version. The secret is replicated to the given locations (e.g., us-east1), or automatically
+// if there are none.
+func CreateSecretE(t testing.TestingT, projectID string, secretID string, secretString string, replicaLocations []string) (string, error) {
+	logger.Logf(t, "Creating Secret Manager secret %s in project %s", secretID, projectID)
+
+	ctx := context.Background()
+	service, err := NewSecretManagerServiceE(t)
+	if err != nil {
+		return "", err
+	}
+
+	replication := &secretmanager.Replication{Automatic: &secretmanager.Automatic{}}
+	if len(replicaLocations) > 0 {
+		replication = &secretmanager.Replication{UserManaged: &secretmanager.UserManaged{}}
+		for _, location := range replicaLocations {
+			replication.UserManaged.Replicas = append(replication.UserManaged.Replicas, &secretmanager.Replica{Location: location})
+		}
+	}
+
+	secret := &secretmanager.Secret{Replication: replication}
+	if _, err := service.Projects.Secrets.Create("projects/"+projectID, secret).SecretId(secretID).Context(ctx).Do(); err != nil {
+		return "", fmt.Errorf("CreateSecretE.Create(%s, %s) got error: %v", projectID, secretID, err)
+	}
+
+	return AddSecretVersionE(t, projectID, secretID, secretString)
+}
+
+// AddSecretVersion adds a version with the given value to the given Secret Manager secret, and returns the name of the
+// version. This will fail the test if there is an error.
+func AddSecretVersion(t testing.TestingT, projectID string, secretID string, secretString string) string {
+	versionName, err := AddSecretVersionE(t, projectID, secretID, secretString)
+	require.NoError(t, err)
+	return versionName
+}
+
+// AddSecretVersionE adds a version with the given value to the given Secret Manager secret, and returns the name of the
+// version, i.e., projects/<project-number>/secrets/<secret>/versions/<version>.
+func AddSecretVersionE(t testing.TestingT, projectID string, secretID string, secretString string) (string, error) {
+	logger.Logf(t, "Adding version to Secret Manager secret %s", secretID)
+
+	ctx := context.Background()
+	service, err := NewSecretManagerServiceE(t)
+	if err != nil {
+		return "", err
+	}
+
+	req := &secretmanager.AddSecretVersionRequest{
+		Payload: &secretmanager.SecretPayload{Data: base64.StdEncoding.EncodeToString([]byte(secretString))},
+	}
+	version, err := service.Projects.Secrets.AddVersion(secretName(projectID, secretID), req).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("AddSecretVersionE.AddVersion(%s, %s) got error: %v", projectID, secretID, err)
+	}
+
+	return version.Name, nil
+}
+
+// AccessSecretVersion returns the value of the given version (e.g., 1, or latest) of the given Secret Manager secret.
+// This will fail the test if there is an error.
+func AccessSecretVersion(t testing.TestingT, projectID string, secretID string, version string) string {
+	value, err := AccessSecretVersionE(t, projectID, secretID, version)
+	require.NoError(t, err)
+	return value
+}
+
+// AccessSecretVersionE returns the value of the given version (e.g., 1, or latest) of the given Secret Manager secret.
+func AccessSecretVersionE(t testing.TestingT, projectID string, secretID string, version string) (string, error) {
+	logger.Logf(t, "Accessing version %s of Secret Manager secret %s", version, secretID)
+
+	ctx := context.Background()
+	service, err := NewSecretManagerServiceE(t)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := service.Projects.Secrets.Versions.Access(secretVersionName(projectID, secretID, version)).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("AccessSecretVersionE.Access(%s, %s, %s) got error: %v", projectID, secretID, version, err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// DestroySecretVersion irreversibly destroys the value of the given version of the given Secret Manager secret. This
+// will fail the test if there is an error.
+func DestroySecretVersion(t testing.TestingT, projectID string, secretID string, version string) {
+	err := DestroySecretVersionE(t, projectID, secretID, version)
+	require.NoError(t, err)
+}
+
+// DestroySecretVersionE irreversibly destroys the value of the given version of the given Secret Manager secret.
+func DestroySecretVersionE(t testing.TestingT, projectID string, secretID string, version string) error {
+	logger.Logf(t, "Destroying version %s of Secret Manager secret %s", version, secretID)
+
+	ctx := context.Background()
+	service, err := NewSecretManagerServiceE(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.Projects.Secrets.Versions.Destroy(secretVersionName(projectID, secretID, version), &secretmanager.DestroySecretVersionRequest{}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("DestroySecretVersionE.Destroy(%s, %s, %s) got error: %v", projectID, secretID, version, err)
+	}
+	return nil
+}
+
+// DeleteSecret deletes the given Secret Manager secret along with all its versions. This will fail the test if there is
+// an error.
+func DeleteSecret(t testing.TestingT, projectID string, secretID string) {
+	err := DeleteSecretE(t, projectID, secretID)
+	require.NoError(t, err)
+}
+
+// DeleteSecretE deletes the given Secret Manager secret along with all its versions.
+func DeleteSecretE(t testing.TestingT, projectID string, secretID string) error {
+	logger.Logf(t, "Deleting Secret Manager secret %s", secretID)
+
+	ctx := context.Background()
+	service, err := NewSecretManagerServiceE(t)
+	if err != nil {
+		return err
+	}
+
+	if _, err := service.Projects.Secrets.Delete(secretName(projectID, secretID)).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("DeleteSecretE.Delete(%s, %s) got error: %v", projectID, secretID, err)
+	}
+	return nil
+}
+
+// GetSecret gets the given Secret Manager secret. This will fail the test if there is an error.
+func GetSecret(t testing.TestingT, projectID string, secretID string) *secretmanager.Secret {
+	secret, err := GetSecretE(t, projectID, secretID)
+	require.NoError(t, err)
+	return secret
+}
+
+// GetSecretE gets the given Secret Manager secret.
+func GetSecretE(t testing.TestingT, projectID string, secretID string) (*secretmanager.Secret, error) {
+	ctx := context.Background()
+	service, err := NewSecretManagerServiceE(t)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := service.Projects.Secrets.Get(secretName(projectID, secretID)).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("GetSecretE.Get(%s, %s) got error: %v", projectID, secretID, err)
+	}
+	return secret, nil
+}
+
+// AssertSecretReplication checks that the given Secret Manager secret is replicated to the expected locations, or
+// automatically if there are none. This will fail the test if it is not.
+func AssertSecretReplication(t testing.TestingT, projectID string, secretID string, expectedLocations []string) {
+	err := AssertSecretReplicationE(t, projectID, secretID, expectedLocations)
+	require.NoError(t, err)
+}
+
+// AssertSecretReplicationE checks that the given Secret Manager secret is replicated to the expected locations, in any
+// order, or automatically if there are none.
+func AssertSecretReplicationE(t testing.TestingT, projectID string, secretID string, expectedLocations []string) error {
+	secret, err := GetSecretE(t, projectID, secretID)
+	if err != nil {
+		return err
+	}
+	return checkSecretReplication(secret, expectedLocations)
+}
+
+// checkSecretReplication returns an error if the given secret is not replicated to the expected locations.
+func checkSecretReplication(secret *secretmanager.Secret, expectedLocations []string) error {
+	if secret.Replication == nil {
+		return fmt.Errorf("Secret %s has no replication policy", secret.Name)
+	}
+
+	if len(expectedLocations) == 0 {
+		if secret.Replication.Automatic == nil {
+			return fmt.Errorf("Secret %s is not replicated automatically", secret.Name)
+		}
+		return nil
+	}
+
+	if secret.Replication.UserManaged == nil {
+		return fmt.Errorf("Secret %s is replicated automatically instead of to %v", secret.Name, expectedLocations)
+	}
+	var locations []string
+	for _, replica := range secret.Replication.UserManaged.Replicas {
+		locations = append(locations, replica.Location)
+	}
+	expected := append([]string{}, expectedLocations...)
+	sort.Strings(locations)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(locations, expected) {
+		return fmt.Errorf("Secret %s is replicated to %v instead of %v", secret.Name, locations, expected)
+	}
+	return nil
+}
+
+// AssertSecretIamBinding checks that the given member (e.g., serviceAccount:app@my-project.iam.gserviceaccount.com) is
+// granted the given role on the given Secret Manager secret, and fails the test if it is not.
+func AssertSecretIamBinding(t testing.TestingT, projectID string, secretID string, role string, member string) {
+	err := AssertSecretIamBindingE(t, projectID, secretID, role, member)
+	require.NoError(t, err)
+}
+
+// AssertSecretIamBindingE checks that the given member (e.g., serviceAccount:app@my-project.iam.gserviceaccount.com) is
+// granted the given role (e.g., roles/secretmanager.secretAccessor) on the given Secret Manager secret, and returns an
+// error if it is not. Bindings with conditions count too, as the version 3 policy of the secret is checked.
+func AssertSecretIamBindingE(t testing.TestingT, projectID string, secretID string, role string, member string) error {
+	return RequireIamBindingE(t, secretName(projectID, secretID), role, member)
+}
+
+// secretName returns the full resource name of the given secret.
+func secretName(projectID string, secretID string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", projectID, secretID)
+}
+
+// secretVersionName returns the full resource name of the given version of the given secret.
+func secretVersionName(projectID string, secretID string, version string) string {
+	return fmt.Sprintf("%s/versions/%s", secretName(projectID, secretID), version)
+}
+
+// NewSecretManagerService creates a new Secret Manager service, which is used to make Secret Manager API calls.
+func NewSecretManagerService(t testing.TestingT) *secretmanager.Service {
+	service, err := NewSecretManagerServiceE(t)
+	require.NoError(t, err)
+	return service
+}
+
+// NewSecretManagerServiceE creates a new Secret Manager service, which is used to make Secret Manager API calls.
+func NewSecretManagerServiceE(t testing.TestingT) (*secretmanager.Service, error) {
+	ctx := context.Background()
+
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Secret Manager service: %v", err)
+	}
+
+	return service, nil
+}
@@ -0,0 +1,49 @@
+//go:build gcp
+// +build gcp
+
+// NOTE: We use build tags to differentiate GCP testing for better isolation and parallelism when executing our tests.
+
+package gcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+func TestCreateAccessAndDeleteSecret(t *testing.T) {
+	t.Parallel()
+
+	projectID := GetGoogleProjectIDFromEnvVar(t)
+	secretID := "terratest-" + strings.ToLower(random.UniqueId())
+
+	CreateSecret(t, projectID, secretID, "first", []string{"us-east1"})
+	defer DeleteSecret(t, projectID, secretID)
+
+	AddSecretVersion(t, projectID, secretID, "second")
+	assert.Equal(t, "first", AccessSecretVersion(t, projectID, secretID, "1"))
+	assert.Equal(t, "second", AccessSecretVersion(t, projectID, secretID, "latest"))
+	AssertSecretReplication(t, projectID, secretID, []string{"us-east1"})
+
+	DestroySecretVersion(t, projectID, secretID, "1")
+	_, err := AccessSecretVersionE(t, projectID, secretID, "1")
+	assert.Error(t, err)
+}
+
+func TestCheckSecretReplication(t *testing.T) {
+	t.Parallel()
+
+	automatic := &secretmanager.Secret{Name: "my-secret", Replication: &secretmanager.Replication{Automatic: &secretmanager.Automatic{}}}
+	assert.NoError(t, checkSecretReplication(automatic, nil))
+	assert.Error(t, checkSecretReplication(automatic, []string{"us-east1"}))
+
+	userManaged := &secretmanager.Secret{Name: "my-secret", Replication: &secretmanager.Replication{UserManaged: &secretmanager.UserManaged{
+		Replicas: []*secretmanager.Replica{{Location: "us-west1"}, {Location: "us-east1"}},
+	}}}
+	assert.NoError(t, checkSecretReplication(userManaged, []string{"us-east1", "us-west1"}))
+	assert.Error(t, checkSecretReplication(userManaged, []string{"us-east1"}))
+	assert.Error(t, checkSecretReplication(userManaged, nil))
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/gruntwork-io/terratest/modules/logger"
@@ -26,7 +28,7 @@ func CreateStorageBucketE(t testing.TestingT, projectID string, name string, att
 	ctx := context.Background()
 
 	// Creates a client.
-	client, err := storage.NewClient(ctx)
+	client, err := newStorageClientE(ctx)
 	if err != nil {
 		return err
 	}
@@ -52,7 +54,7 @@ func DeleteStorageBucketE(t testing.TestingT, name string) error {
 
 	ctx := context.Background()
 
-	client, err := storage.NewClient(ctx)
+	client, err := newStorageClientE(ctx)
 	if err != nil {
 		return err
 	}
@@ -75,7 +77,7 @@ func ReadBucketObjectE(t testing.TestingT, bucketName string, filePath string) (
 
 	ctx := context.Background()
 
-	client, err := storage.NewClient(ctx)
+	client, err := newStorageClientE(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +111,7 @@ func WriteBucketObjectE(t testing.TestingT, bucketName string, filePath string,
 
 	ctx := context.Background()
 
-	client, err := storage.NewClient(ctx)
+	client, err := newStorageClientE(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -147,7 +149,7 @@ func EmptyStorageBucketE(t testing.TestingT, name string) error {
 
 	ctx := context.Background()
 
-	client, err := storage.NewClient(ctx)
+	client, err := newStorageClientE(ctx)
 	if err != nil {
 		return err
 	}
@@ -192,7 +194,7 @@ func AssertStorageBucketExistsE(t testing.TestingT, name string) error {
 	ctx := context.Background()
 
 	// Creates a client.
-	client, err := storage.NewClient(ctx)
+	client, err := newStorageClientE(ctx)
 	if err != nil {
 		return err
 	}
@@ -217,3 +219,216 @@ func AssertStorageBucketExistsE(t testing.TestingT, name string) error {
 
 	return nil
 }
+
+// GetStorageObjectSignedURL returns a V4 signed URL that can be used to make a request with the given HTTP method (e.g.,
+// GET or PUT) to the given object in the given Storage Bucket, without any other credentials, until the URL expires.
+func GetStorageObjectSignedURL(t testing.TestingT, bucketName string, filePath string, method string, expiry time.Duration) string {
+	url, err := GetStorageObjectSignedURLE(t, bucketName, filePath, method, expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+// GetStorageObjectSignedURLE returns a V4 signed URL that can be used to make a request with the given HTTP method (e.g.,
+// GET or PUT) to the given object in the given Storage Bucket, without any other credentials, until the URL expires.
+// The URL is signed with the private key of the service account of the default credentials if they include one, or
+// with the IAM signBlob API otherwise, which requires the roles/iam.serviceAccountTokenCreator role.
+func GetStorageObjectSignedURLE(t testing.TestingT, bucketName string, filePath string, method string, expiry time.Duration) (string, error) {
+	logger.Logf(t, "Generating signed URL for %s request to object %s in bucket %s", method, filePath, bucketName)
+
+	ctx := context.Background()
+
+	client, err := newStorageClientE(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return client.Bucket(bucketName).SignedURL(filePath, &storage.SignedURLOptions{
+		Method:  strings.ToUpper(method),
+		Expires: time.Now().Add(expiry),
+		Scheme:  storage.SigningSchemeV4,
+	})
+}
+
+// GetStorageBucketAttrs gets the attributes of the given Storage Bucket, e.g., its lifecycle rules, retention policy and
+// encryption settings.
+func GetStorageBucketAttrs(t testing.TestingT, bucketName string) *storage.BucketAttrs {
+	attrs, err := GetStorageBucketAttrsE(t, bucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attrs
+}
+
+// GetStorageBucketAttrsE gets the attributes of the given Storage Bucket, e.g., its lifecycle rules, retention policy and
+// encryption settings.
+func GetStorageBucketAttrsE(t testing.TestingT, bucketName string) (*storage.BucketAttrs, error) {
+	ctx := context.Background()
+
+	client, err := newStorageClientE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := client.Bucket(bucketName).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetStorageBucketAttrsE.Attrs(%s) got error: %v", bucketName, err)
+	}
+	return attrs, nil
+}
+
+// AssertStorageBucketLifecycleRule checks that the given Storage Bucket has a lifecycle rule with the given action type
+// (e.g., Delete or SetStorageClass) that applies to objects older than the given number of days, and fails the test if
+// it does not.
+func AssertStorageBucketLifecycleRule(t testing.TestingT, bucketName string, actionType string, ageInDays int64) {
+	err := AssertStorageBucketLifecycleRuleE(t, bucketName, actionType, ageInDays)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertStorageBucketLifecycleRuleE checks that the given Storage Bucket has a lifecycle rule with the given action type
+// (e.g., storage.DeleteAction or storage.SetStorageClassAction) that applies to objects older than the given number of
+// days, and returns an error if it does not.
+func AssertStorageBucketLifecycleRuleE(t testing.TestingT, bucketName string, actionType string, ageInDays int64) error {
+	attrs, err := GetStorageBucketAttrsE(t, bucketName)
+	if err != nil {
+		return err
+	}
+	return checkStorageBucketLifecycleRule(attrs, actionType, ageInDays)
+}
+
+// checkStorageBucketLifecycleRule returns an error if the given bucket has no lifecycle rule with the given action type
+// and age.
+func checkStorageBucketLifecycleRule(attrs *storage.BucketAttrs, actionType string, ageInDays int64) error {
+	for _, rule := range attrs.Lifecycle.Rules {
+		if rule.Action.Type == actionType && rule.Condition.AgeInDays == ageInDays {
+			return nil
+		}
+	}
+	return fmt.Errorf("Storage bucket %s has no lifecycle rule to %s objects older than %d days", attrs.Name, actionType, ageInDays)
+}
+
+// AssertStorageBucketRetentionPolicy checks that the given Storage Bucket retains objects for the given period, and
+// that its retention policy is locked if expected. This will fail the test if it does not.
+func AssertStorageBucketRetentionPolicy(t testing.TestingT, bucketName string, expectedPeriod time.Duration, expectedLocked bool) {
+	err := AssertStorageBucketRetentionPolicyE(t, bucketName, expectedPeriod, expectedLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertStorageBucketRetentionPolicyE checks that the given Storage Bucket retains objects for the given period, and
+// that its retention policy is locked if expected, so that it can no longer be shortened or removed.
+func AssertStorageBucketRetentionPolicyE(t testing.TestingT, bucketName string, expectedPeriod time.Duration, expectedLocked bool) error {
+	attrs, err := GetStorageBucketAttrsE(t, bucketName)
+	if err != nil {
+		return err
+	}
+	return checkStorageBucketRetentionPolicy(attrs, expectedPeriod, expectedLocked)
+}
+
+// checkStorageBucketRetentionPolicy returns an error if the retention policy of the given bucket is not as expected.
+func checkStorageBucketRetentionPolicy(attrs *storage.BucketAttrs, expectedPeriod time.Duration, expectedLocked bool) error {
+	policy := attrs.RetentionPolicy
+	if policy == nil {
+		return fmt.Errorf("Storage bucket %s has no retention policy", attrs.Name)
+	}
+	if policy.RetentionPeriod != expectedPeriod {
+		return fmt.Errorf("Storage bucket %s retains objects for %s instead of %s", attrs.Name, policy.RetentionPeriod, expectedPeriod)
+	}
+	if policy.IsLocked != expectedLocked {
+		return fmt.Errorf("Storage bucket %s has a retention policy with locked set to %t instead of %t", attrs.Name, policy.IsLocked, expectedLocked)
+	}
+	return nil
+}
+
+// AssertStorageBucketIamBinding checks that the given member (e.g., serviceAccount:app@my-project.iam.gserviceaccount.com)
+// is granted the given role on the given Storage Bucket, and fails the test if it is not.
+func AssertStorageBucketIamBinding(t testing.TestingT, bucketName string, role string, member string) {
+	err := AssertStorageBucketIamBindingE(t, bucketName, role, member)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertStorageBucketIamBindingE checks that the given member (e.g., serviceAccount:app@my-project.iam.gserviceaccount.com)
+// is granted the given role (e.g., roles/storage.objectViewer) on the given Storage Bucket, and returns an error if it
+// is not. Bindings with conditions count too, as the version 3 policy of the bucket is checked.
+func AssertStorageBucketIamBindingE(t testing.TestingT, bucketName string, role string, member string) error {
+	return RequireIamBindingE(t, "projects/_/buckets/"+bucketName, role, member)
+}
+
+// AssertStorageBucketEncryptionKey checks that the given Storage Bucket encrypts new objects with the given Cloud KMS
+// key by default, and fails the test if it does not.
+func AssertStorageBucketEncryptionKey(t testing.TestingT, bucketName string, kmsKeyName string) {
+	err := AssertStorageBucketEncryptionKeyE(t, bucketName, kmsKeyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertStorageBucketEncryptionKeyE checks that the given Storage Bucket encrypts new objects with the given Cloud KMS
+// key (i.e., projects/<project>/locations/<location>/keyRings/<key-ring>/cryptoKeys/<key>) by default, and returns an
+// error if it does not.
+func AssertStorageBucketEncryptionKeyE(t testing.TestingT, bucketName string, kmsKeyName string) error {
+	attrs, err := GetStorageBucketAttrsE(t, bucketName)
+	if err != nil {
+		return err
+	}
+
+	keyName := ""
+	if attrs.Encryption != nil {
+		keyName = attrs.Encryption.DefaultKMSKeyName
+	}
+	if keyName != kmsKeyName {
+		return fmt.Errorf("Storage bucket %s is encrypted with key %q instead of %q", bucketName, keyName, kmsKeyName)
+	}
+	return nil
+}
+
+// AssertStorageObjectEncryptionKey checks that the given object in the given Storage Bucket is encrypted with the
+// given Cloud KMS key, and fails the test if it is not.
+func AssertStorageObjectEncryptionKey(t testing.TestingT, bucketName string, filePath string, kmsKeyName string) {
+	err := AssertStorageObjectEncryptionKeyE(t, bucketName, filePath, kmsKeyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertStorageObjectEncryptionKeyE checks that the given object in the given Storage Bucket is encrypted with any
+// version of the given Cloud KMS key, and returns an error if it is not.
+func AssertStorageObjectEncryptionKeyE(t testing.TestingT, bucketName string, filePath string, kmsKeyName string) error {
+	ctx := context.Background()
+
+	client, err := newStorageClientE(ctx)
+	if err != nil {
+		return err
+	}
+
+	attrs, err := client.Bucket(bucketName).Object(filePath).Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("AssertStorageObjectEncryptionKeyE.Attrs(%s, %s) got error: %v", bucketName, filePath, err)
+	}
+	if !isStorageKmsKeyVersionOf(attrs.KMSKeyName, kmsKeyName) {
+		return fmt.Errorf("Storage object %s in bucket %s is encrypted with key %q instead of %q", filePath, bucketName, attrs.KMSKeyName, kmsKeyName)
+	}
+	return nil
+}
+
+// isStorageKmsKeyVersionOf returns true if the given key name, which Cloud Storage reports with the version of the key
+// that encrypted the object (e.g., .../cryptoKeys/my-key/cryptoKeyVersions/1), refers to the given key.
+func isStorageKmsKeyVersionOf(keyVersionName string, kmsKeyName string) bool {
+	return keyVersionName == kmsKeyName || strings.HasPrefix(keyVersionName, kmsKeyName+"/cryptoKeyVersions/")
+}
+
+// newStorageClientE creates a new Cloud Storage client, which authenticates as the impersonated service account if
+// there is one.
+func newStorageClientE(ctx context.Context) (*storage.Client, error) {
+	opts, err := newClientOptionsE(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewClient(ctx, opts...)
+}
@@ -10,9 +10,12 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"cloud.google.com/go/storage"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,3 +78,37 @@ func TestAssertStorageBucketExistsNoFalsePositive(t *testing.T) {
 		t.Fatalf("Function claimed that the Storage Bucket '%s' exists, but in fact it does not.", gsBucketName)
 	}
 }
+
+func TestCheckStorageBucketLifecycleRule(t *testing.T) {
+	t.Parallel()
+
+	attrs := &storage.BucketAttrs{Name: "my-bucket", Lifecycle: storage.Lifecycle{Rules: []storage.LifecycleRule{
+		{Action: storage.LifecycleAction{Type: storage.SetStorageClassAction, StorageClass: "NEARLINE"}, Condition: storage.LifecycleCondition{AgeInDays: 30}},
+		{Action: storage.LifecycleAction{Type: storage.DeleteAction}, Condition: storage.LifecycleCondition{AgeInDays: 365}},
+	}}}
+
+	assert.NoError(t, checkStorageBucketLifecycleRule(attrs, storage.DeleteAction, 365))
+	assert.NoError(t, checkStorageBucketLifecycleRule(attrs, storage.SetStorageClassAction, 30))
+	assert.Error(t, checkStorageBucketLifecycleRule(attrs, storage.DeleteAction, 30))
+}
+
+func TestCheckStorageBucketRetentionPolicy(t *testing.T) {
+	t.Parallel()
+
+	attrs := &storage.BucketAttrs{Name: "my-bucket", RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 24 * time.Hour, IsLocked: true}}
+
+	assert.NoError(t, checkStorageBucketRetentionPolicy(attrs, 24*time.Hour, true))
+	assert.Error(t, checkStorageBucketRetentionPolicy(attrs, 24*time.Hour, false))
+	assert.Error(t, checkStorageBucketRetentionPolicy(attrs, time.Hour, true))
+	assert.Error(t, checkStorageBucketRetentionPolicy(&storage.BucketAttrs{Name: "my-bucket"}, time.Hour, false))
+}
+
+func TestIsStorageKmsKeyVersionOf(t *testing.T) {
+	t.Parallel()
+
+	key := "projects/my-project/locations/us/keyRings/my-ring/cryptoKeys/my-key"
+	assert.True(t, isStorageKmsKeyVersionOf(key+"/cryptoKeyVersions/1", key))
+	assert.True(t, isStorageKmsKeyVersionOf(key, key))
+	assert.False(t, isStorageKmsKeyVersionOf(key+"-2/cryptoKeyVersions/1", key))
+	assert.False(t, isStorageKmsKeyVersionOf("", key))
+}
@@ -0,0 +1,115 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-11-01/containerservice"
+	gwErrors "github.com/gruntwork-io/go-commons/errors"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/gruntwork-io/terratest/modules/azure"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// aksAuthPlugin is the kubectl credential plugin that authenticates to AKS clusters that use Azure AD.
+const aksAuthPlugin = "kubelogin"
+
+// NewKubectlOptionsForAksCluster will write a kubeconfig for the AKS cluster with the given name in the given resource
+// group to a temp file, and return a pointer to a new instance of KubectlOptions that uses it with the given
+// namespace. This will fail the test if there is an error.
+func NewKubectlOptionsForAksCluster(t testing.TestingT, resourceGroupName string, clusterName string, subscriptionID string, namespace string, admin bool) *KubectlOptions {
+	options, err := NewKubectlOptionsForAksClusterE(t, resourceGroupName, clusterName, subscriptionID, namespace, admin)
+	require.NoError(t, err)
+	return options
+}
+
+// NewKubectlOptionsForAksClusterE will write a kubeconfig for the AKS cluster with the given name in the given resource
+// group to a temp file, and return a pointer to a new instance of KubectlOptions that uses it with the given
+// namespace. This is the equivalent of running az aks get-credentials, without requiring the Azure CLI or modifying
+// the kubeconfig in the home directory.
+//
+// If admin is true, the kubeconfig authenticates with the cluster admin certificate, which bypasses Azure AD and
+// Kubernetes RBAC (and is not available if local accounts are disabled). Otherwise, it has the cluster user
+// credentials: for clusters that use Azure AD, these authenticate as the current Azure CLI user (or service principal)
+// with kubelogin, which must be installed. This allows testing Azure AD role bindings.
+func NewKubectlOptionsForAksClusterE(t testing.TestingT, resourceGroupName string, clusterName string, subscriptionID string, namespace string, admin bool) (*KubectlOptions, error) {
+	client, err := azure.GetManagedClustersClientE(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials containerservice.CredentialResults
+	if admin {
+		credentials, err = client.ListClusterAdminCredentials(context.Background(), resourceGroupName, clusterName)
+	} else {
+		credentials, err = client.ListClusterUserCredentials(context.Background(), resourceGroupName, clusterName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	config, err := newAksKubeConfig(credentials, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpConfig, err := ioutil.TempFile("", "kubeconfig-aks-")
+	if err != nil {
+		return nil, gwErrors.WithStackTrace(err)
+	}
+	defer tmpConfig.Close()
+	if err := clientcmd.WriteToFile(*config, tmpConfig.Name()); err != nil {
+		return nil, err
+	}
+
+	logger.Logf(t, "Wrote kubeconfig for AKS cluster %s to %s", clusterName, tmpConfig.Name())
+	return NewKubectlOptions(config.CurrentContext, tmpConfig.Name(), namespace), nil
+}
+
+// newAksKubeConfig parses the kubeconfig in the given AKS credentials, converting the Azure AD credentials to use
+// kubelogin if it is installed, as the azure auth provider of kubectl no longer works with AKS.
+func newAksKubeConfig(credentials containerservice.CredentialResults, clusterName string) (*api.Config, error) {
+	if credentials.Kubeconfigs == nil || len(*credentials.Kubeconfigs) == 0 || (*credentials.Kubeconfigs)[0].Value == nil {
+		return nil, fmt.Errorf("AKS cluster %s returned no kubeconfig", clusterName)
+	}
+	config, err := clientcmd.Load(*(*credentials.Kubeconfigs)[0].Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := exec.LookPath(aksAuthPlugin); err == nil {
+		convertAksAuthProviders(config)
+	}
+	return config, nil
+}
+
+// convertAksAuthProviders replaces the azure auth provider credentials of the given kubeconfig with credentials that
+// run kubelogin to get a token for the cluster with the Azure CLI credentials, like kubelogin convert-kubeconfig does.
+func convertAksAuthProviders(config *api.Config) {
+	for _, authInfo := range config.AuthInfos {
+		if authInfo.AuthProvider == nil || authInfo.AuthProvider.Name != "azure" {
+			continue
+		}
+		serverID := authInfo.AuthProvider.Config["apiserver-id"]
+		authInfo.AuthProvider = nil
+		authInfo.Exec = newAksExecAuthInfo(serverID).Exec
+	}
+}
+
+// newAksExecAuthInfo returns credentials that run kubelogin to get a token for the Azure AD application of the cluster
+// API server with the given ID.
+func newAksExecAuthInfo(serverID string) *api.AuthInfo {
+	return &api.AuthInfo{
+		Exec: &api.ExecConfig{
+			APIVersion:  "client.authentication.k8s.io/v1beta1",
+			Command:     aksAuthPlugin,
+			Args:        []string{"get-token", "--login", "azurecli", "--server-id", serverID},
+			InstallHint: "Install kubelogin with: az aks install-cli",
+		},
+	}
+}
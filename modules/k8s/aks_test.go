@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2019-11-01/containerservice"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const aksTestKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://test-cluster-dns-1234.hcp.eastus.azmk8s.io:443
+contexts:
+- name: test-cluster
+  context:
+    cluster: test-cluster
+    user: clusterUser_test-rg_test-cluster
+current-context: test-cluster
+users:
+- name: clusterUser_test-rg_test-cluster
+  user:
+    auth-provider:
+      name: azure
+      config:
+        apiserver-id: 6dae42f8-4368-4678-94ff-3960e28e3630
+        client-id: 80faf920-1908-4b52-b5ef-a8e7bedfc67a
+        config-mode: "1"
+        environment: AzurePublicCloud
+        tenant-id: 00000000-0000-0000-0000-000000000000
+`
+
+func TestNewAksKubeConfig(t *testing.T) {
+	t.Parallel()
+
+	kubeconfig := []byte(aksTestKubeConfig)
+	credentials := containerservice.CredentialResults{Kubeconfigs: &[]containerservice.CredentialResult{
+		{Name: to.StringPtr("clusterUser"), Value: &kubeconfig},
+	}}
+	config, err := newAksKubeConfig(credentials, "test-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", config.CurrentContext)
+
+	_, err = newAksKubeConfig(containerservice.CredentialResults{}, "test-cluster")
+	assert.Error(t, err)
+}
+
+func TestConvertAksAuthProviders(t *testing.T) {
+	t.Parallel()
+
+	config, err := clientcmd.Load([]byte(aksTestKubeConfig))
+	require.NoError(t, err)
+	convertAksAuthProviders(config)
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://test-cluster-dns-1234.hcp.eastus.azmk8s.io:443", restConfig.Host)
+	assert.Nil(t, restConfig.AuthProvider)
+	require.NotNil(t, restConfig.ExecProvider)
+	assert.Equal(t, "kubelogin", restConfig.ExecProvider.Command)
+	assert.Equal(t, []string{"get-token", "--login", "azurecli", "--server-id", "6dae42f8-4368-4678-94ff-3960e28e3630"}, restConfig.ExecProvider.Args)
+}
@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+
+	awsgo "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	gwErrors "github.com/gruntwork-io/go-commons/errors"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// The names of aws.AuthProfileEnvVar and aws.AuthAssumeRoleExternalIDEnvVar, which the version of the aws module that
+// this module builds against doesn't have yet.
+const (
+	eksAuthProfileEnvVar              = "TERRATEST_AWS_PROFILE"
+	eksAuthAssumeRoleExternalIDEnvVar = "TERRATEST_IAM_ROLE_EXTERNAL_ID"
+)
+
+// NewKubectlOptionsForEksCluster will write a kubeconfig for the EKS cluster with the given name to a temp file, and
+// return a pointer to a new instance of KubectlOptions that uses it with the given namespace. This will fail the test
+// if there is an error.
+func NewKubectlOptionsForEksCluster(t testing.TestingT, region string, clusterName string, namespace string) *KubectlOptions {
+	options, err := NewKubectlOptionsForEksClusterE(t, region, clusterName, namespace)
+	require.NoError(t, err)
+	return options
+}
+
+// NewKubectlOptionsForEksClusterE will write a kubeconfig for the EKS cluster with the given name to a temp file, and
+// return a pointer to a new instance of KubectlOptions that uses it with the given namespace. This is the equivalent of
+// running aws eks update-kubeconfig, without modifying the kubeconfig in the home directory.
+//
+// Like the kubeconfig of aws eks update-kubeconfig, this authenticates with an exec plugin that runs aws eks get-token
+// whenever a token is needed, so that it keeps working for tests that run longer than the 15 minutes a token is valid
+// for. This requires the AWS CLI, which gets the token as the same identity as aws.NewAuthenticatedSession: with the
+// named profile of TERRATEST_AWS_PROFILE and as the IAM role of TERRATEST_IAM_ROLE, if they are set. As aws eks
+// get-token can't pass an external ID when assuming the role, this returns an error if TERRATEST_IAM_ROLE_EXTERNAL_ID
+// is set.
+func NewKubectlOptionsForEksClusterE(t testing.TestingT, region string, clusterName string, namespace string) (*KubectlOptions, error) {
+	auth := eksAuthOptionsFromEnv()
+	if auth.ExternalID != "" {
+		return nil, EksExternalIDNotSupported{RoleARN: auth.RoleARN}
+	}
+
+	sess, err := aws.NewAuthenticatedSession(region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := eks.New(sess).DescribeCluster(&eks.DescribeClusterInput{Name: awsgo.String(clusterName)})
+	if err != nil {
+		return nil, err
+	}
+	config, err := newEksKubeConfig(output.Cluster, region, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpConfig, err := ioutil.TempFile("", "kubeconfig-eks-")
+	if err != nil {
+		return nil, gwErrors.WithStackTrace(err)
+	}
+	defer tmpConfig.Close()
+	if err := clientcmd.WriteToFile(*config, tmpConfig.Name()); err != nil {
+		return nil, err
+	}
+
+	logger.Logf(t, "Wrote kubeconfig for EKS cluster %s to %s", clusterName, tmpConfig.Name())
+	return NewKubectlOptions(clusterName, tmpConfig.Name(), namespace), nil
+}
+
+// newEksKubeConfig returns a kubeconfig with a single context, named after the given EKS cluster in the given region,
+// that authenticates to the cluster with the tokens of aws eks get-token, as configured by the given options.
+func newEksKubeConfig(cluster *eks.Cluster, region string, auth eksAuthOptions) (*api.Config, error) {
+	clusterName := awsgo.StringValue(cluster.Name)
+	certificateAuthorityData := []byte{}
+	if cluster.CertificateAuthority != nil {
+		data, err := base64.StdEncoding.DecodeString(awsgo.StringValue(cluster.CertificateAuthority.Data))
+		if err != nil {
+			return nil, gwErrors.WithStackTrace(err)
+		}
+		certificateAuthorityData = data
+	}
+
+	config := api.NewConfig()
+	config.Clusters[clusterName] = &api.Cluster{
+		Server:                   awsgo.StringValue(cluster.Endpoint),
+		CertificateAuthorityData: certificateAuthorityData,
+	}
+	config.AuthInfos[clusterName] = &api.AuthInfo{Exec: newEksExecConfig(clusterName, region, auth)}
+	UpsertConfigContext(config, clusterName, clusterName, clusterName)
+	config.CurrentContext = clusterName
+	return config, nil
+}
+
+// newEksExecConfig returns the exec plugin config that gets a token for the given EKS cluster with aws eks get-token,
+// with the profile and as the IAM role of the given options if they are set.
+func newEksExecConfig(clusterName string, region string, auth eksAuthOptions) *api.ExecConfig {
+	args := []string{"--region", region, "eks", "get-token", "--cluster-name", clusterName}
+	if auth.RoleARN != "" {
+		args = append(args, "--role-arn", auth.RoleARN)
+	}
+	env := []api.ExecEnvVar{}
+	if auth.Profile != "" {
+		env = append(env, api.ExecEnvVar{Name: "AWS_PROFILE", Value: auth.Profile})
+	}
+	return &api.ExecConfig{
+		APIVersion:  "client.authentication.k8s.io/v1beta1",
+		Command:     "aws",
+		Args:        args,
+		Env:         env,
+		InstallHint: "The AWS CLI is required to authenticate to EKS clusters: https://aws.amazon.com/cli/",
+	}
+}
+
+// eksAuthOptions are the settings of the AWS credentials of aws.NewAuthenticatedSession that the exec plugin of the
+// kubeconfig authenticates with, so that the test calls the API server as the same identity that described the cluster.
+type eksAuthOptions struct {
+	Profile    string
+	RoleARN    string
+	ExternalID string
+}
+
+// eksAuthOptionsFromEnv returns the eksAuthOptions configured through the TERRATEST_ environment variables, in the same
+// way as aws.AuthOptionsFromEnv.
+func eksAuthOptionsFromEnv() eksAuthOptions {
+	return eksAuthOptions{
+		Profile:    os.Getenv(eksAuthProfileEnvVar),
+		RoleARN:    os.Getenv(aws.AuthAssumeRoleEnvVar),
+		ExternalID: os.Getenv(eksAuthAssumeRoleExternalIDEnvVar),
+	}
+}
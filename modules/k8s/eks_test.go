@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"testing"
+
+	awsgo "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+)
+
+func TestNewEksKubeConfig(t *testing.T) {
+	t.Parallel()
+
+	cluster := &eks.Cluster{
+		Name:     awsgo.String("test-cluster"),
+		Endpoint: awsgo.String("https://example.eks.amazonaws.com"),
+		CertificateAuthority: &eks.Certificate{
+			Data: awsgo.String(base64.StdEncoding.EncodeToString([]byte("test-ca"))),
+		},
+	}
+	config, err := newEksKubeConfig(cluster, "us-east-1", eksAuthOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "test-cluster", config.CurrentContext)
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.eks.amazonaws.com", restConfig.Host)
+	assert.Equal(t, []byte("test-ca"), restConfig.CAData)
+	require.NotNil(t, restConfig.ExecProvider)
+	assert.Equal(t, "aws", restConfig.ExecProvider.Command)
+	assert.Equal(t, []string{"--region", "us-east-1", "eks", "get-token", "--cluster-name", "test-cluster"}, restConfig.ExecProvider.Args)
+	assert.Equal(t, "client.authentication.k8s.io/v1beta1", restConfig.ExecProvider.APIVersion)
+}
+
+func TestNewEksExecConfig(t *testing.T) {
+	t.Parallel()
+
+	execConfig := newEksExecConfig("test-cluster", "eu-west-1", eksAuthOptions{RoleARN: "arn:aws:iam::111111111111:role/terratest"})
+	assert.Equal(t, []string{"--region", "eu-west-1", "eks", "get-token", "--cluster-name", "test-cluster", "--role-arn", "arn:aws:iam::111111111111:role/terratest"}, execConfig.Args)
+	assert.Empty(t, execConfig.Env)
+
+	execConfig = newEksExecConfig("test-cluster", "eu-west-1", eksAuthOptions{Profile: "staging"})
+	assert.Equal(t, []string{"--region", "eu-west-1", "eks", "get-token", "--cluster-name", "test-cluster"}, execConfig.Args)
+	assert.Equal(t, []api.ExecEnvVar{{Name: "AWS_PROFILE", Value: "staging"}}, execConfig.Env)
+}
+
+func TestNewKubectlOptionsForEksClusterERejectsExternalID(t *testing.T) {
+	t.Setenv(aws.AuthAssumeRoleEnvVar, "arn:aws:iam::111111111111:role/terratest")
+	t.Setenv(eksAuthAssumeRoleExternalIDEnvVar, "terratest")
+
+	_, err := NewKubectlOptionsForEksClusterE(t, "us-east-1", "test-cluster", "default")
+	require.IsType(t, EksExternalIDNotSupported{}, err)
+}
@@ -260,3 +260,13 @@ type JSONPathMalformedJSONPathResultErr struct {
 func (err JSONPathMalformedJSONPathResultErr) Error() string {
 	return fmt.Sprintf("Error unmarshaling json path output: %s", err.underlyingErr)
 }
+
+// EksExternalIDNotSupported is returned when generating a kubeconfig for an EKS cluster with an IAM role that requires an
+// external ID, which the aws eks get-token exec plugin of the kubeconfig can't pass when assuming the role.
+type EksExternalIDNotSupported struct {
+	RoleARN string
+}
+
+func (err EksExternalIDNotSupported) Error() string {
+	return fmt.Sprintf("Can't generate an EKS kubeconfig that assumes the IAM role %s with an external ID, as aws eks get-token doesn't support external IDs. Unset TERRATEST_IAM_ROLE_EXTERNAL_ID or use a role without one.", err.RoleARN)
+}
@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+
+	gwErrors "github.com/gruntwork-io/go-commons/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/container/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// gkeAuthPlugin is the kubectl credential plugin that authenticates to GKE clusters with the gcloud credentials.
+const gkeAuthPlugin = "gke-gcloud-auth-plugin"
+
+// NewKubectlOptionsForGkeCluster will write a kubeconfig for the GKE cluster with the given name in the given location
+// to a temp file, and return a pointer to a new instance of KubectlOptions that uses it with the given namespace. This
+// will fail the test if there is an error.
+func NewKubectlOptionsForGkeCluster(t testing.TestingT, projectID string, location string, clusterName string, namespace string) *KubectlOptions {
+	options, err := NewKubectlOptionsForGkeClusterE(t, projectID, location, clusterName, namespace)
+	require.NoError(t, err)
+	return options
+}
+
+// NewKubectlOptionsForGkeClusterE will write a kubeconfig for the GKE cluster with the given name in the given location
+// (a region or a zone) to a temp file, and return a pointer to a new instance of KubectlOptions that uses it with the
+// given namespace. This is the equivalent of running gcloud container clusters get-credentials, without modifying the
+// kubeconfig in the home directory.
+//
+// If gke-gcloud-auth-plugin is installed, the kubeconfig uses it to get fresh tokens from the gcloud credentials, like
+// the kubeconfig gcloud writes. Otherwise, it authenticates with a token from the application default credentials,
+// which GKE accepts for an hour. If your test runs for longer than that, call this again to get a fresh kubeconfig.
+func NewKubectlOptionsForGkeClusterE(t testing.TestingT, projectID string, location string, clusterName string, namespace string) (*KubectlOptions, error) {
+	ctx := context.Background()
+	service, err := container.NewService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("projects/%s/locations/%s/clusters/%s", projectID, location, clusterName)
+	cluster, err := service.Projects.Locations.Clusters.Get(name).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	authInfo, err := newGkeAuthInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contextName := fmt.Sprintf("gke_%s_%s_%s", projectID, location, clusterName)
+	config, err := newGkeKubeConfig(cluster, contextName, authInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpConfig, err := ioutil.TempFile("", "kubeconfig-gke-")
+	if err != nil {
+		return nil, gwErrors.WithStackTrace(err)
+	}
+	defer tmpConfig.Close()
+	if err := clientcmd.WriteToFile(*config, tmpConfig.Name()); err != nil {
+		return nil, err
+	}
+
+	logger.Logf(t, "Wrote kubeconfig for GKE cluster %s to %s", clusterName, tmpConfig.Name())
+	return NewKubectlOptions(contextName, tmpConfig.Name(), namespace), nil
+}
+
+// newGkeAuthInfo returns the credentials to authenticate to GKE clusters with: gke-gcloud-auth-plugin if it is
+// installed, or else a token from the application default credentials.
+func newGkeAuthInfo(ctx context.Context) (*api.AuthInfo, error) {
+	if _, err := exec.LookPath(gkeAuthPlugin); err == nil {
+		return newGkeExecAuthInfo(), nil
+	}
+	tokenSource, err := google.DefaultTokenSource(ctx, container.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	token, err := tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	return &api.AuthInfo{Token: token.AccessToken}, nil
+}
+
+// newGkeExecAuthInfo returns credentials that run gke-gcloud-auth-plugin to get a token for the cluster.
+func newGkeExecAuthInfo() *api.AuthInfo {
+	return &api.AuthInfo{
+		Exec: &api.ExecConfig{
+			APIVersion:         "client.authentication.k8s.io/v1beta1",
+			Command:            gkeAuthPlugin,
+			InstallHint:        "Install gke-gcloud-auth-plugin with: gcloud components install gke-gcloud-auth-plugin",
+			ProvideClusterInfo: true,
+		},
+	}
+}
+
+// newGkeKubeConfig returns a kubeconfig with a single context with the given name that authenticates to the given GKE
+// cluster with the given credentials.
+func newGkeKubeConfig(cluster *container.Cluster, contextName string, authInfo *api.AuthInfo) (*api.Config, error) {
+	certificateAuthorityData := []byte{}
+	if cluster.MasterAuth != nil {
+		data, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate)
+		if err != nil {
+			return nil, gwErrors.WithStackTrace(err)
+		}
+		certificateAuthorityData = data
+	}
+
+	config := api.NewConfig()
+	config.Clusters[contextName] = &api.Cluster{
+		Server:                   "https://" + cluster.Endpoint,
+		CertificateAuthorityData: certificateAuthorityData,
+	}
+	config.AuthInfos[contextName] = authInfo
+	UpsertConfigContext(config, contextName, contextName, contextName)
+	config.CurrentContext = contextName
+	return config, nil
+}
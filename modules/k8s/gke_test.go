@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/container/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestNewGkeKubeConfig(t *testing.T) {
+	t.Parallel()
+
+	cluster := &container.Cluster{
+		Name:     "test-cluster",
+		Endpoint: "203.0.113.10",
+		MasterAuth: &container.MasterAuth{
+			ClusterCaCertificate: base64.StdEncoding.EncodeToString([]byte("test-ca")),
+		},
+	}
+	config, err := newGkeKubeConfig(cluster, "gke_project_us-central1_test-cluster", &api.AuthInfo{Token: "test-token"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "gke_project_us-central1_test-cluster", config.CurrentContext)
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://203.0.113.10", restConfig.Host)
+	assert.Equal(t, "test-token", restConfig.BearerToken)
+	assert.Equal(t, []byte("test-ca"), restConfig.CAData)
+}
+
+func TestNewGkeExecAuthInfo(t *testing.T) {
+	t.Parallel()
+
+	config, err := newGkeKubeConfig(&container.Cluster{Endpoint: "203.0.113.10"}, "test", newGkeExecAuthInfo())
+	require.NoError(t, err)
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+	require.NoError(t, err)
+	require.NotNil(t, restConfig.ExecProvider)
+	assert.Equal(t, "gke-gcloud-auth-plugin", restConfig.ExecProvider.Command)
+	assert.True(t, restConfig.ExecProvider.ProvideClusterInfo)
+}
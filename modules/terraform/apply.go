@@ -2,7 +2,9 @@ package terraform
 
 import (
 	"errors"
+	"os"
 
+	"github.com/gruntwork-io/terratest/modules/collections"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/require"
 )
@@ -112,3 +114,41 @@ func InitAndApplyAndIdempotentE(t testing.TestingT, options *Options) (string, e
 
 	return ApplyAndIdempotentE(t, options)
 }
+
+// ApplyAndAssertReplaced runs terraform plan with the given options, verifies that the set of resources terraform plans
+// to replace matches expectedReplaced exactly, and then applies that plan, returning stdout/stderr from the apply
+// command. Combine this with options.Replace to test resource recreation behavior. This will fail the test if there is
+// an error in the commands or if the replaced resources don't match. Note that this method does NOT call destroy and
+// assumes the caller is responsible for cleaning up any resources created by running apply.
+func ApplyAndAssertReplaced(t testing.TestingT, options *Options, expectedReplaced []string) string {
+	out, err := ApplyAndAssertReplacedE(t, options, expectedReplaced)
+	require.NoError(t, err)
+	return out
+}
+
+// ApplyAndAssertReplacedE runs terraform plan with the given options, verifies that the set of resources terraform
+// plans to replace matches expectedReplaced exactly, and then applies that plan, returning stdout/stderr from the apply
+// command. Combine this with options.Replace to test resource recreation behavior. Note that this method does NOT call
+// destroy and assumes the caller is responsible for cleaning up any resources created by running apply.
+func ApplyAndAssertReplacedE(t testing.TestingT, options *Options, expectedReplaced []string) (string, error) {
+	planOptions, err := planToTempFileE(t, options)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(planOptions.PlanFilePath)
+
+	plan, err := ShowWithStructE(t, planOptions)
+	if err != nil {
+		return "", err
+	}
+
+	actualReplaced := GetReplacedResourceAddresses(plan)
+	unexpected := collections.ListSubtract(actualReplaced, expectedReplaced)
+	missing := collections.ListSubtract(expectedReplaced, actualReplaced)
+	if len(unexpected) > 0 || len(missing) > 0 {
+		return "", ReplacedResourcesMismatch{Unexpected: unexpected, Missing: missing}
+	}
+
+	// Apply the exact plan that was verified, rather than planning again.
+	return ApplyE(t, planOptions)
+}
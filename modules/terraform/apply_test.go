@@ -228,3 +228,28 @@ func TestApplyWithPlanFile(t *testing.T) {
 	require.Contains(t, out, "1 added, 0 changed, 0 destroyed.")
 	require.NotRegexp(t, `\[\d*m`, out, "Output should not contain color escape codes")
 }
+
+func TestApplyAndAssertReplaced(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+
+	options := &Options{
+		TerraformDir: testFolder,
+		Vars: map[string]interface{}{
+			"cnt": 2,
+		},
+		NoColor: true,
+	}
+	InitAndApply(t, options)
+
+	options.Replace = []string{"null_resource.test[1]"}
+	out, err := ApplyAndAssertReplacedE(t, options, []string{"null_resource.test[1]"})
+	require.NoError(t, err)
+	require.Contains(t, out, "1 added, 0 changed, 1 destroyed.")
+
+	_, err = ApplyAndAssertReplacedE(t, options, []string{"null_resource.test[0]"})
+	require.Error(t, err)
+	assert.IsType(t, ReplacedResourcesMismatch{}, err)
+}
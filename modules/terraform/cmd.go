@@ -14,12 +14,22 @@ func generateCommand(options *Options, args ...string) shell.Command {
 		Command:    options.TerraformBinary,
 		Args:       args,
 		WorkingDir: options.TerraformDir,
-		Env:        options.EnvVars,
-		Logger:     options.Logger,
+		Env:        getCommandEnvVars(options),
+		Logger:     getCommandLogger(options),
 	}
 	return cmd
 }
 
+// prepareCommandE runs the checks and setup required by the options before running any terraform command.
+func prepareCommandE(t testing.TestingT, options *Options) error {
+	if options.CaptureTerraformLogs {
+		if err := configureTerraformLogCapture(options); err != nil {
+			return err
+		}
+	}
+	return checkEnvVarConflictsE(t, options)
+}
+
 var commandsWithParallelism = []string{
 	"plan",
 	"apply",
@@ -67,11 +77,20 @@ func RunTerraformCommand(t testing.TestingT, additionalOptions *Options, args ..
 // RunTerraformCommandE runs terraform with the given arguments and options and return stdout/stderr.
 func RunTerraformCommandE(t testing.TestingT, additionalOptions *Options, additionalArgs ...string) (string, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
+	if err := prepareCommandE(t, options); err != nil {
+		return "", err
+	}
 
 	cmd := generateCommand(options, args...)
-	description := fmt.Sprintf("%s %v", options.TerraformBinary, args)
-	return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
-		return shell.RunCommandAndGetOutputE(t, cmd)
+	description := redactSensitiveValues(options, fmt.Sprintf("%s %v", options.TerraformBinary, args))
+	return runWithCommandHooks(t, options, args, func() (string, error) {
+		return runWithStateLockWait(t, options, func() (string, error) {
+			return runWithTerraformLogCapture(options, func() (string, error) {
+				return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
+					return runCommandAndGetOutputE(t, options, cmd, false)
+				})
+			})
+		})
 	})
 }
 
@@ -79,11 +98,20 @@ func RunTerraformCommandE(t testing.TestingT, additionalOptions *Options, additi
 // (but not stderr).
 func RunTerraformCommandAndGetStdoutE(t testing.TestingT, additionalOptions *Options, additionalArgs ...string) (string, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
+	if err := prepareCommandE(t, options); err != nil {
+		return "", err
+	}
 
 	cmd := generateCommand(options, args...)
-	description := fmt.Sprintf("%s %v", options.TerraformBinary, args)
-	return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
-		return shell.RunCommandAndGetStdOutE(t, cmd)
+	description := redactSensitiveValues(options, fmt.Sprintf("%s %v", options.TerraformBinary, args))
+	return runWithCommandHooks(t, options, args, func() (string, error) {
+		return runWithStateLockWait(t, options, func() (string, error) {
+			return runWithTerraformLogCapture(options, func() (string, error) {
+				return retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
+					return runCommandAndGetOutputE(t, options, cmd, true)
+				})
+			})
+		})
 	})
 }
 
@@ -99,13 +127,30 @@ func GetExitCodeForTerraformCommand(t testing.TestingT, additionalOptions *Optio
 // GetExitCodeForTerraformCommandE runs terraform with the given arguments and options and returns exit code
 func GetExitCodeForTerraformCommandE(t testing.TestingT, additionalOptions *Options, additionalArgs ...string) (int, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
+	if err := prepareCommandE(t, options); err != nil {
+		return DefaultErrorExitCode, err
+	}
 
-	additionalOptions.Logger.Logf(t, "Running %s with args %v", options.TerraformBinary, args)
+	getCommandLogger(options).Logf(t, "Running %s with args %v", options.TerraformBinary, args)
 	cmd := generateCommand(options, args...)
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	var err error
+	_, hookErr := runWithCommandHooks(t, options, args, func() (string, error) {
+		return runWithStateLockWait(t, options, func() (string, error) {
+			var out string
+			out, err = runCommandAndGetOutputE(t, options, cmd, false)
+			return out, err
+		})
+	})
+	// Errors returned by the hooks or the state lock wait rather than terraform don't carry an exit code.
+	if hookErr != nil && hookErr != err {
+		return DefaultErrorExitCode, hookErr
+	}
 	if err == nil {
 		return DefaultSuccessExitCode, nil
 	}
+	if streamedErr, isStreamedErr := err.(StreamedCommandError); isStreamedErr {
+		err = streamedErr.Underlying
+	}
 	exitCode, getExitCodeErr := shell.GetExitCodeForRunCommandError(err)
 	if getExitCodeErr == nil {
 		return exitCode, nil
@@ -19,8 +19,14 @@ func TgDestroyAll(t testing.TestingT, options *Options) string {
 	return out
 }
 
-// DestroyE runs terraform destroy with the given options and return stdout/stderr.
+// DestroyE runs terraform destroy with the given options and return stdout/stderr. If options.DestroyGuard is set, this
+// returns a DestroyNotAllowed error without destroying anything if any of the targets fail the guard.
 func DestroyE(t testing.TestingT, options *Options) (string, error) {
+	if options.DestroyGuard != nil {
+		if err := checkDestroyGuardE(t, options); err != nil {
+			return "", err
+		}
+	}
 	return RunTerraformCommandE(t, options, FormatArgs(options, "destroy", "-auto-approve", "-input=false")...)
 }
 
@@ -0,0 +1,170 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// DestroyGuard configures the destroy safety checks. When set on Options, Destroy refuses to run unless everything it
+// is about to destroy looks like it was created by a test, which protects against tests that are accidentally pointed
+// at a real environment (e.g., through leftover credentials or a copy pasted backend config).
+type DestroyGuard struct {
+	// A regular expression that the names of everything about to be destroyed must match (e.g., `^terratest-`). This is
+	// checked against the current workspace (unless it is the default workspace), the state key configured in
+	// BackendConfig (key, prefix, or path), and the name attributes of every managed resource in state.
+	NamePattern string
+
+	// The resource attributes that hold the name of a resource. Dots can be used to refer to nested attributes. Defaults
+	// to DefaultDestroyGuardNameAttributes.
+	NameAttributes []string
+
+	// An optional matcher consulted for every target that doesn't match NamePattern. Destroy is allowed if it returns
+	// true for every such target.
+	AllowDestroyOf func(target DestroyTarget) bool
+}
+
+// DestroyTarget is something the DestroyGuard checks before allowing a destroy.
+type DestroyTarget struct {
+	Kind    string // One of DestroyTargetWorkspace, DestroyTargetStateKey, or DestroyTargetResource
+	Name    string // The workspace name, state key, or resource name
+	Address string // The address of the resource in state. Only set for resources.
+}
+
+// The kinds of DestroyTarget.
+const (
+	DestroyTargetWorkspace = "workspace"
+	DestroyTargetStateKey  = "state-key"
+	DestroyTargetResource  = "resource"
+)
+
+// DefaultDestroyGuardNameAttributes are the resource attributes that commonly hold the name of a resource across
+// providers.
+var DefaultDestroyGuardNameAttributes = []string{"name", "bucket", "function_name", "cluster_name", "tags.Name"}
+
+// The backend config keys that identify where the state is stored for the common backends.
+var destroyGuardStateKeyBackendConfigs = []string{"key", "prefix", "path"}
+
+// checkDestroyGuardE verifies that everything that would be destroyed with the given options passes the DestroyGuard
+// that is configured on the options, returning a DestroyNotAllowed error if it does not.
+func checkDestroyGuardE(t testing.TestingT, options *Options) error {
+	guard := options.DestroyGuard
+	namePattern, err := regexp.Compile(guard.NamePattern)
+	if err != nil {
+		return err
+	}
+
+	targets := []DestroyTarget{}
+
+	workspace, err := RunTerraformCommandAndGetStdoutE(t, options, "workspace", "show")
+	if err != nil {
+		return err
+	}
+	workspace = strings.TrimSpace(workspace)
+	if workspace != "default" {
+		targets = append(targets, DestroyTarget{Kind: DestroyTargetWorkspace, Name: workspace})
+	}
+
+	for _, key := range destroyGuardStateKeyBackendConfigs {
+		if value, hasKey := options.BackendConfig[key]; hasKey {
+			targets = append(targets, DestroyTarget{Kind: DestroyTargetStateKey, Name: fmt.Sprintf("%v", value)})
+		}
+	}
+
+	resourceTargets, err := getDestroyGuardResourceTargetsE(t, options)
+	if err != nil {
+		return err
+	}
+	targets = append(targets, resourceTargets...)
+
+	notAllowed := DestroyNotAllowed{}
+	for _, target := range targets {
+		if namePattern.MatchString(target.Name) {
+			continue
+		}
+		if guard.AllowDestroyOf != nil && guard.AllowDestroyOf(target) {
+			continue
+		}
+		notAllowed.Targets = append(notAllowed.Targets, target)
+	}
+	if len(notAllowed.Targets) > 0 {
+		notAllowed.NamePattern = guard.NamePattern
+		return notAllowed
+	}
+	return nil
+}
+
+// getDestroyGuardResourceTargetsE reads the current state and returns a DestroyTarget for each name attribute of each
+// managed resource in it, sorted by resource address.
+func getDestroyGuardResourceTargetsE(t testing.TestingT, options *Options) ([]DestroyTarget, error) {
+	// Make sure we show the state, and not a plan file that may be configured on the options.
+	stateOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	stateOptions.PlanFilePath = ""
+
+	out, err := ShowE(t, stateOptions)
+	if err != nil {
+		return nil, err
+	}
+	state := &tfjson.State{}
+	if err := json.Unmarshal([]byte(out), state); err != nil {
+		return nil, err
+	}
+	if state.Values == nil || state.Values.RootModule == nil {
+		return nil, nil
+	}
+
+	nameAttributes := options.DestroyGuard.NameAttributes
+	if len(nameAttributes) == 0 {
+		nameAttributes = DefaultDestroyGuardNameAttributes
+	}
+
+	resources := parseModulePlannedValues(state.Values.RootModule)
+	addresses := []string{}
+	for address, resource := range resources {
+		if resource.Mode == tfjson.ManagedResourceMode {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+
+	targets := []DestroyTarget{}
+	for _, address := range addresses {
+		for _, attribute := range nameAttributes {
+			if name, hasName := lookupNestedAttribute(resources[address].AttributeValues, attribute); hasName {
+				targets = append(targets, DestroyTarget{Kind: DestroyTargetResource, Name: name, Address: address})
+			}
+		}
+	}
+	return targets, nil
+}
+
+// lookupNestedAttribute looks up the dot separated attribute path in the given attribute values, returning the value
+// and true if it is set to a non empty string.
+func lookupNestedAttribute(values map[string]interface{}, path string) (string, bool) {
+	parts := strings.Split(path, ".")
+	current := values
+	for i, part := range parts {
+		value, exists := current[part]
+		if !exists {
+			return "", false
+		}
+		if i == len(parts)-1 {
+			str, isString := value.(string)
+			return str, isString && str != ""
+		}
+		next, isMap := value.(map[string]interface{})
+		if !isMap {
+			return "", false
+		}
+		current = next
+	}
+	return "", false
+}
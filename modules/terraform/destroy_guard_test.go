@@ -0,0 +1,55 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupNestedAttribute(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]interface{}{
+		"name":  "terratest-abc123",
+		"count": 3,
+		"tags":  map[string]interface{}{"Name": "prod-db"},
+		"empty": "",
+	}
+
+	testCases := []struct {
+		path          string
+		expectedValue string
+		expectedFound bool
+	}{
+		{"name", "terratest-abc123", true},
+		{"tags.Name", "prod-db", true},
+		{"tags.Missing", "", false},
+		{"count", "", false},
+		{"empty", "", false},
+		{"name.nested", "", false},
+	}
+
+	for _, testCase := range testCases {
+		value, found := lookupNestedAttribute(values, testCase.path)
+		assert.Equal(t, testCase.expectedValue, value, testCase.path)
+		assert.Equal(t, testCase.expectedFound, found, testCase.path)
+	}
+}
+
+func TestOptionsCloneKeepsDestroyGuard(t *testing.T) {
+	t.Parallel()
+
+	original := Options{
+		DestroyGuard: &DestroyGuard{
+			NamePattern:    "^terratest-",
+			AllowDestroyOf: func(target DestroyTarget) bool { return target.Kind == DestroyTargetWorkspace },
+		},
+	}
+	copied, err := original.Clone()
+	require.NoError(t, err)
+	require.NotNil(t, copied.DestroyGuard)
+	assert.Equal(t, "^terratest-", copied.DestroyGuard.NamePattern)
+	require.NotNil(t, copied.DestroyGuard.AllowDestroyOf)
+	assert.True(t, copied.DestroyGuard.AllowDestroyOf(DestroyTarget{Kind: DestroyTargetWorkspace}))
+}
@@ -0,0 +1,175 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// EnvVarSource identifies one of the layers the environment of a terraform command is built from.
+type EnvVarSource string
+
+// The layers the environment of a terraform command is built from, in order of increasing precedence: values in
+// Options.EnvVars override the process environment, and Options.Vars (whether passed as -var flags or, with
+// PassVarsAsEnvVars, as TF_VAR_ environment variables) override both.
+const (
+	EnvVarSourceProcess EnvVarSource = "process environment"
+	EnvVarSourceEnvVars EnvVarSource = "Options.EnvVars"
+	EnvVarSourceVars    EnvVarSource = "Options.Vars"
+)
+
+// RedactedValue is what sensitive values are replaced with in the logs.
+const RedactedValue = "[REDACTED]"
+
+const tfVarEnvPrefix = "TF_VAR_"
+
+// EnvVarConflict describes a terraform variable that is set to different values by more than one layer of the
+// environment. The value from the last source wins.
+type EnvVarConflict struct {
+	Variable string         // The name of the terraform variable
+	Sources  []EnvVarSource // The layers that set the variable, in order of increasing precedence
+}
+
+// GetTerraformEnv returns the full environment that terraform commands run with for the given options, after layering
+// the process environment, EnvVars, and (if PassVarsAsEnvVars is set) Vars as TF_VAR_ environment variables.
+func GetTerraformEnv(options *Options) map[string]string {
+	env := map[string]string{}
+	for _, keyVal := range os.Environ() {
+		parts := strings.SplitN(keyVal, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	for key, val := range getCommandEnvVars(options) {
+		env[key] = val
+	}
+	return env
+}
+
+// GetEnvVarConflicts returns all the terraform variables that are set to different values by more than one layer of the
+// environment for the given options, sorted by variable name.
+func GetEnvVarConflicts(options *Options) []EnvVarConflict {
+	names := map[string]bool{}
+	for name := range options.Vars {
+		names[name] = true
+	}
+	for key := range options.EnvVars {
+		if strings.HasPrefix(key, tfVarEnvPrefix) {
+			names[strings.TrimPrefix(key, tfVarEnvPrefix)] = true
+		}
+	}
+
+	conflicts := []EnvVarConflict{}
+	for name := range names {
+		key := tfVarEnvPrefix + name
+		sources := []EnvVarSource{}
+		values := map[string]bool{}
+		if val, isSet := os.LookupEnv(key); isSet {
+			sources = append(sources, EnvVarSourceProcess)
+			values[val] = true
+		}
+		if val, isSet := options.EnvVars[key]; isSet {
+			sources = append(sources, EnvVarSourceEnvVars)
+			values[val] = true
+		}
+		if val, isSet := options.Vars[name]; isSet {
+			sources = append(sources, EnvVarSourceVars)
+			values[toHclString(val, false)] = true
+		}
+
+		// Setting the same value in multiple places is harmless.
+		if len(values) > 1 {
+			conflicts = append(conflicts, EnvVarConflict{Variable: name, Sources: sources})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Variable < conflicts[j].Variable })
+	return conflicts
+}
+
+// checkEnvVarConflictsE reports the terraform variables that are set by multiple layers of the environment. These are
+// logged as warnings, or returned as an EnvVarConflicts error if StrictEnvVars is set.
+func checkEnvVarConflictsE(t testing.TestingT, options *Options) error {
+	conflicts := GetEnvVarConflicts(options)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if options.StrictEnvVars {
+		return EnvVarConflicts(conflicts)
+	}
+	for _, conflict := range conflicts {
+		options.Logger.Logf(t, "WARNING: terraform variable %s is set by multiple sources %v. The value from %s will be used.", conflict.Variable, conflict.Sources, conflict.Sources[len(conflict.Sources)-1])
+	}
+	return nil
+}
+
+// getCommandEnvVars returns the environment variables to set on top of the process environment when running terraform
+// commands with the given options.
+func getCommandEnvVars(options *Options) map[string]string {
+	if !options.PassVarsAsEnvVars || len(options.Vars) == 0 {
+		return options.EnvVars
+	}
+
+	env := map[string]string{}
+	for key, val := range options.EnvVars {
+		env[key] = val
+	}
+	for name, val := range options.Vars {
+		env[tfVarEnvPrefix+name] = toHclString(val, false)
+	}
+	return env
+}
+
+// getSensitiveValues returns the values of all the Vars and EnvVars that are marked as sensitive on the options, along
+// with the values registered with MaskSensitiveValue, longest first so that a value that contains another one is
+// redacted as a whole.
+func getSensitiveValues(options *Options) []string {
+	values := getMaskedValues()
+	for _, name := range options.SensitiveVars {
+		if val, isSet := options.Vars[name]; isSet {
+			values = append(values, toHclString(val, false))
+		}
+		if val, isSet := options.EnvVars[name]; isSet {
+			values = append(values, val)
+		}
+	}
+
+	nonEmpty := []string{}
+	for _, val := range values {
+		if val != "" {
+			nonEmpty = append(nonEmpty, val)
+		}
+	}
+	sort.Slice(nonEmpty, func(i, j int) bool { return len(nonEmpty[i]) > len(nonEmpty[j]) })
+	return nonEmpty
+}
+
+// redactSensitiveValues replaces all the sensitive values of the options in the given string with RedactedValue.
+func redactSensitiveValues(options *Options, str string) string {
+	for _, val := range getSensitiveValues(options) {
+		str = strings.ReplaceAll(str, val, RedactedValue)
+	}
+	return str
+}
+
+// getCommandLogger returns the logger to use for terraform commands run with the given options, which redacts any
+// sensitive values from the log output.
+func getCommandLogger(options *Options) *logger.Logger {
+	if len(getSensitiveValues(options)) == 0 {
+		return options.Logger
+	}
+	return logger.New(redactingLogger{options: options})
+}
+
+// redactingLogger is a logger.TestLogger that redacts sensitive values before forwarding messages to the logger
+// configured on the options.
+type redactingLogger struct {
+	options *Options
+}
+
+func (l redactingLogger) Logf(t testing.TestingT, format string, args ...interface{}) {
+	l.options.Logger.Logf(t, "%s", redactSensitiveValues(l.options, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,73 @@
+package terraform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEnvVarConflicts(t *testing.T) {
+	t.Parallel()
+
+	processVar := "terratest_env_test_" + random.UniqueId()
+	require.NoError(t, os.Setenv("TF_VAR_"+processVar, "from-process"))
+	defer os.Unsetenv("TF_VAR_" + processVar)
+
+	options := &Options{
+		Vars: map[string]interface{}{
+			"region":   "us-east-1",
+			"same":     "same-value",
+			processVar: "from-vars",
+		},
+		EnvVars: map[string]string{
+			"TF_VAR_region": "eu-west-1",
+			"TF_VAR_same":   "same-value",
+		},
+	}
+
+	assert.Equal(t, []EnvVarConflict{
+		{Variable: "region", Sources: []EnvVarSource{EnvVarSourceEnvVars, EnvVarSourceVars}},
+		{Variable: processVar, Sources: []EnvVarSource{EnvVarSourceProcess, EnvVarSourceVars}},
+	}, GetEnvVarConflicts(options))
+
+	options.StrictEnvVars = true
+	err := checkEnvVarConflictsE(t, options)
+	require.Error(t, err)
+	assert.IsType(t, EnvVarConflicts{}, err)
+}
+
+func TestPassVarsAsEnvVars(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{
+		Vars:              map[string]interface{}{"list": []int{1, 2}},
+		EnvVars:           map[string]string{"FOO": "bar"},
+		PassVarsAsEnvVars: true,
+	}
+
+	assert.Equal(t, []string{"plan", "-lock=false"}, FormatArgs(options, "plan"))
+	assert.Equal(t, map[string]string{"FOO": "bar", "TF_VAR_list": "[1, 2]"}, getCommandEnvVars(options))
+	assert.Equal(t, "[1, 2]", GetTerraformEnv(options)["TF_VAR_list"])
+
+	// The EnvVars on the options must not be modified
+	assert.Equal(t, map[string]string{"FOO": "bar"}, options.EnvVars)
+}
+
+func TestRedactSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{
+		Vars:          map[string]interface{}{"password": "hunter2", "name": "terratest"},
+		EnvVars:       map[string]string{"API_TOKEN": "abc123"},
+		SensitiveVars: []string{"password", "API_TOKEN"},
+	}
+
+	assert.Equal(
+		t,
+		"terraform [plan -var password=[REDACTED] -var name=terratest] token=[REDACTED]",
+		redactSensitiveValues(options, "terraform [plan -var password=hunter2 -var name=terratest] token=abc123"),
+	)
+}
@@ -3,6 +3,8 @@ package terraform
 import (
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 )
 
 // TgInvalidBinary occurs when a terragrunt function is called and the TerraformBinary is
@@ -101,3 +103,211 @@ type WorkspaceDoesNotExist string
 func (err WorkspaceDoesNotExist) Error() string {
 	return fmt.Sprintf("The workspace %q does not exist.", string(err))
 }
+
+// ReplacedResourcesMismatch is returned when the resources terraform plans to replace are not exactly the ones that were
+// expected.
+type ReplacedResourcesMismatch struct {
+	Unexpected []string
+	Missing    []string
+}
+
+func (err ReplacedResourcesMismatch) Error() string {
+	return fmt.Sprintf("Planned resource replacements did not match. Unexpectedly replaced: %v. Expected but not replaced: %v", err.Unexpected, err.Missing)
+}
+
+// RefactorNotSafe is returned when a plan that was expected to contain only no-op moves would change real resources.
+type RefactorNotSafe struct {
+	Destroyed []string
+	Changed   []string
+}
+
+func (err RefactorNotSafe) Error() string {
+	return fmt.Sprintf("Plan contains changes other than moves. Resources that would be destroyed: %v. Other changes: %v", err.Destroyed, err.Changed)
+}
+
+// PlanSnapshotMismatch is returned when a plan does not match its golden snapshot file.
+type PlanSnapshotMismatch struct {
+	GoldenPath string
+	Diff       string
+}
+
+func (err PlanSnapshotMismatch) Error() string {
+	return fmt.Sprintf("Plan does not match the snapshot in %s (run the tests with TERRATEST_UPDATE_SNAPSHOTS=true to update it):\n%s", err.GoldenPath, err.Diff)
+}
+
+// DestroyNotAllowed is returned when Destroy is refused because some of the targets did not pass the DestroyGuard.
+type DestroyNotAllowed struct {
+	NamePattern string
+	Targets     []DestroyTarget
+}
+
+func (err DestroyNotAllowed) Error() string {
+	descriptions := []string{}
+	for _, target := range err.Targets {
+		if target.Address != "" {
+			descriptions = append(descriptions, fmt.Sprintf("%s %q (%s)", target.Kind, target.Name, target.Address))
+		} else {
+			descriptions = append(descriptions, fmt.Sprintf("%s %q", target.Kind, target.Name))
+		}
+	}
+	return fmt.Sprintf("Refusing to destroy: the following do not match the destroy guard pattern %q and were not explicitly allowed: %s", err.NamePattern, strings.Join(descriptions, ", "))
+}
+
+// ErrWithTerraformLogs wraps the error of a failed terraform command with the last lines terraform logged while running
+// it. This is only returned when CaptureTerraformLogs is set.
+type ErrWithTerraformLogs struct {
+	Underlying error
+	LogLines   []string
+}
+
+func (err ErrWithTerraformLogs) Error() string {
+	return fmt.Sprintf("%v\nLast %d lines of the terraform log:\n%s", err.Underlying, len(err.LogLines), strings.Join(err.LogLines, "\n"))
+}
+
+func (err ErrWithTerraformLogs) Unwrap() error {
+	return err.Underlying
+}
+
+// TerraformLogsNotCaptured is returned when trying to read terraform logs for options that don't capture them.
+type TerraformLogsNotCaptured struct{}
+
+func (err TerraformLogsNotCaptured) Error() string {
+	return "Terraform logs were not captured. Set CaptureTerraformLogs on the options to capture them."
+}
+
+// ProviderErrorsLogged is returned when providers logged records at the error level.
+type ProviderErrorsLogged struct {
+	Messages []string
+}
+
+func (err ProviderErrorsLogged) Error() string {
+	return fmt.Sprintf("Providers logged %d errors:\n%s", len(err.Messages), strings.Join(err.Messages, "\n"))
+}
+
+// EnvVarConflicts is returned when StrictEnvVars is set and terraform variables are set to different values by multiple
+// layers of the environment.
+type EnvVarConflicts []EnvVarConflict
+
+func (err EnvVarConflicts) Error() string {
+	descriptions := []string{}
+	for _, conflict := range err {
+		descriptions = append(descriptions, fmt.Sprintf("%s (set by %v)", conflict.Variable, conflict.Sources))
+	}
+	return fmt.Sprintf("Terraform variables are set to different values by multiple sources: %s", strings.Join(descriptions, ", "))
+}
+
+// ModuleValidationFailures is returned by ValidateAll when some of the modules fail validation.
+type ModuleValidationFailures []ModuleValidationResult
+
+func (err ModuleValidationFailures) Error() string {
+	messages := []string{}
+	for _, result := range err {
+		for _, checkErr := range []struct {
+			check string
+			err   error
+		}{
+			{"init", result.InitError},
+			{"validate", result.ValidateError},
+			{"fmt", result.FmtError},
+		} {
+			if checkErr.err != nil {
+				messages = append(messages, fmt.Sprintf("%s: %s failed: %v", result.Dir, checkErr.check, checkErr.err))
+			}
+		}
+	}
+	return fmt.Sprintf("%d Terraform modules failed validation:\n%s", len(err), strings.Join(messages, "\n"))
+}
+
+// UnformattedFiles is the error for a module that has files that are not formatted according to `terraform fmt`.
+type UnformattedFiles struct {
+	Files      []string
+	Underlying error
+}
+
+func (err UnformattedFiles) Error() string {
+	if len(err.Files) == 0 {
+		return err.Underlying.Error()
+	}
+	return fmt.Sprintf("Files need to be formatted with terraform fmt: %s", strings.Join(err.Files, ", "))
+}
+
+// UnexpectedUpgradeChanges is returned when the plan made after upgrading terraform or provider versions changes
+// resources that are not expected to change.
+type UnexpectedUpgradeChanges struct {
+	Addresses []string
+}
+
+func (err UnexpectedUpgradeChanges) Error() string {
+	return fmt.Sprintf("Upgrading versions results in unexpected changes to: %s", strings.Join(err.Addresses, ", "))
+}
+
+// TerraformDownloadFailed is returned when a terraform release can't be downloaded.
+type TerraformDownloadFailed struct {
+	Version    string
+	URL        string
+	StatusCode int
+}
+
+func (err TerraformDownloadFailed) Error() string {
+	return fmt.Sprintf("Failed to download terraform %s from %s: got status code %d", err.Version, err.URL, err.StatusCode)
+}
+
+// StateLocked is returned when a terraform command fails because another process holds the state lock.
+type StateLocked struct {
+	Lock       StateLockInfo
+	Underlying error
+}
+
+func (err StateLocked) Error() string {
+	return fmt.Sprintf("State %s is locked by %s (lock ID %s, operation %s, created %s): %v", err.Lock.Path, err.Lock.Who, err.Lock.ID, err.Lock.Operation, err.Lock.Created, err.Underlying)
+}
+
+func (err StateLocked) Unwrap() error {
+	return err.Underlying
+}
+
+// InitBackendChanged is returned when terraform init fails because the backend configuration changed and terraform
+// needs to be told what to do with the existing state.
+type InitBackendChanged struct {
+	Underlying error
+}
+
+func (err InitBackendChanged) Error() string {
+	return fmt.Sprintf("The backend configuration changed. Set MigrateState on the options to migrate the existing state to the new backend, or Reconfigure to use the new backend without migrating the state: %v", err.Underlying)
+}
+
+func (err InitBackendChanged) Unwrap() error {
+	return err.Underlying
+}
+
+// MigrateStateAndReconfigureSet is returned when both MigrateState and Reconfigure are set on the options, which
+// terraform init does not allow.
+type MigrateStateAndReconfigureSet struct{}
+
+func (err MigrateStateAndReconfigureSet) Error() string {
+	return "MigrateState and Reconfigure can't both be set: terraform init either migrates the state to the new backend or ignores it."
+}
+
+// OutputStalled is returned when a terraform command is killed because it didn't write any output for longer than the
+// OutputStallTimeout.
+type OutputStalled struct {
+	Timeout time.Duration
+}
+
+func (err OutputStalled) Error() string {
+	return fmt.Sprintf("Terraform did not write any output for %s and was killed", err.Timeout)
+}
+
+// StreamedCommandError is returned when a terraform command whose output is streamed fails.
+type StreamedCommandError struct {
+	Underlying error
+	Stderr     string
+}
+
+func (err StreamedCommandError) Error() string {
+	return fmt.Sprintf("error while running command: %v; %s", err.Underlying, err.Stderr)
+}
+
+func (err StreamedCommandError) Unwrap() error {
+	return err.Underlying
+}
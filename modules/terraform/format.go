@@ -36,6 +36,13 @@ var TerraformCommandsWithPlanFileSupport = []string{
 	"graph",
 }
 
+// TerraformCommandsWithReplaceSupport is a list of all the Terraform commands that support forcing the replacement of
+// resources with the -replace flag.
+var TerraformCommandsWithReplaceSupport = []string{
+	"plan",
+	"apply",
+}
+
 // FormatArgs converts the inputs to a format palatable to terraform. This includes converting the given vars to the
 // format the Terraform CLI expects (-var key=value).
 func FormatArgs(options *Options, args ...string) []string {
@@ -49,6 +56,7 @@ func FormatArgs(options *Options, args ...string) []string {
 	}
 	lockSupported := collections.ListContains(TerraformCommandsWithLockSupport, commandType)
 	planFileSupported := collections.ListContains(TerraformCommandsWithPlanFileSupport, commandType)
+	replaceSupported := collections.ListContains(TerraformCommandsWithReplaceSupport, commandType)
 
 	// Include -var and -var-file flags unless we're running 'apply' with a plan file
 	includeVars := !(commandType == "apply" && len(options.PlanFilePath) > 0)
@@ -56,17 +64,28 @@ func FormatArgs(options *Options, args ...string) []string {
 	terraformArgs = append(terraformArgs, args...)
 
 	if includeVars {
+		// With PassVarsAsEnvVars, the vars are passed to terraform as TF_VAR_ environment variables instead.
+		varArgs := FormatTerraformVarsAsArgs(options.Vars)
+		if options.PassVarsAsEnvVars {
+			varArgs = nil
+		}
+
 		if options.SetVarsAfterVarFiles {
 			terraformArgs = append(terraformArgs, FormatTerraformArgs("-var-file", options.VarFiles)...)
-			terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(options.Vars)...)
+			terraformArgs = append(terraformArgs, varArgs...)
 		} else {
-			terraformArgs = append(terraformArgs, FormatTerraformVarsAsArgs(options.Vars)...)
+			terraformArgs = append(terraformArgs, varArgs...)
 			terraformArgs = append(terraformArgs, FormatTerraformArgs("-var-file", options.VarFiles)...)
 		}
 	}
 
 	terraformArgs = append(terraformArgs, FormatTerraformArgs("-target", options.Targets)...)
 
+	// Like vars, the resources to replace are baked into a plan file, so terraform rejects -replace when applying one.
+	if replaceSupported && includeVars {
+		terraformArgs = append(terraformArgs, FormatTerraformArgs("-replace", options.Replace)...)
+	}
+
 	if options.NoColor {
 		terraformArgs = append(terraformArgs, "-no-color")
 	}
@@ -312,3 +312,24 @@ func TestFormatSetVarsAfterVarFilesFormatsCorrectly(t *testing.T) {
 		assert.Equal(t, testCase.expected[len(testCase.expected)-1], result[len(result)-1])
 	}
 }
+
+func TestFormatArgsAppliesReplaceCorrectly(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		command      []string
+		planFilePath string
+		expected     []string
+	}{
+		{[]string{"plan"}, "", []string{"plan", "-replace", "null_resource.test[0]", "-lock=false"}},
+		{[]string{"apply"}, "", []string{"apply", "-replace", "null_resource.test[0]", "-lock=false"}},
+		{[]string{"apply"}, "/some/plan/output", []string{"apply", "-lock=false", "/some/plan/output"}},
+		{[]string{"destroy"}, "", []string{"destroy", "-lock=false"}},
+		{[]string{"validate"}, "", []string{"validate"}},
+	}
+
+	for _, testCase := range testCases {
+		options := &Options{Replace: []string{"null_resource.test[0]"}, PlanFilePath: testCase.planFilePath}
+		assert.Equal(t, testCase.expected, FormatArgs(options, testCase.command...))
+	}
+}
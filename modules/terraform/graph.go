@@ -0,0 +1,152 @@
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GraphStruct is a Go representation of the dependency graph returned by `terraform graph`. Node names are normalized
+// to the plain resource, data source, module, or provider address (e.g., module.foo.aws_instance.bar), with the
+// decorations terraform adds in the DOT output (such as `[root] ` and ` (expand)`) stripped.
+type GraphStruct struct {
+	// A map that maps each node in the graph to the nodes it directly depends on.
+	Dependencies map[string][]string
+}
+
+var (
+	// Node names are quoted, and may contain escaped quotes (e.g., provider["registry.terraform.io/hashicorp/null"]).
+	graphEdgeRegexp = regexp.MustCompile(`^\s*"((?:[^"\\]|\\.)+)"\s*->\s*"((?:[^"\\]|\\.)+)"`)
+	graphNodeRegexp = regexp.MustCompile(`^\s*"((?:[^"\\]|\\.)+)"\s*(\[|;|$)`)
+
+	// Suffixes terraform appends to node names to distinguish the different phases of a node.
+	graphNodeSuffixRegexp = regexp.MustCompile(` \((expand|close|destroy|prepare state|orphan|deposed [^)]*)\)$`)
+)
+
+// Graph runs terraform graph with the given options and parses the DOT output into a GraphStruct. If PlanFilePath is
+// set on the options, the graph is generated for that plan file. This will fail the test if there is an error in the
+// command.
+func Graph(t testing.TestingT, options *Options) *GraphStruct {
+	graph, err := GraphE(t, options)
+	require.NoError(t, err)
+	return graph
+}
+
+// GraphE runs terraform graph with the given options and parses the DOT output into a GraphStruct. If PlanFilePath is
+// set on the options, the graph is generated for that plan file.
+func GraphE(t testing.TestingT, options *Options) (*GraphStruct, error) {
+	// We manually construct the args here instead of using `FormatArgs`, because graph only accepts a limited set of
+	// args, and takes the plan file as a flag rather than a positional argument.
+	args := []string{"graph"}
+	if options.PlanFilePath != "" {
+		args = append(args, fmt.Sprintf("-plan=%s", options.PlanFilePath))
+	}
+
+	out, err := RunTerraformCommandAndGetStdoutE(t, options, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseGraphDot(out), nil
+}
+
+// parseGraphDot parses the DOT representation of the graph returned by terraform graph. We only parse the subset of
+// DOT that terraform emits: one node or edge statement per line, with quoted node names.
+func parseGraphDot(dot string) *GraphStruct {
+	graph := &GraphStruct{Dependencies: map[string][]string{}}
+
+	for _, line := range strings.Split(dot, "\n") {
+		if matches := graphEdgeRegexp.FindStringSubmatch(line); matches != nil {
+			from := normalizeGraphNodeName(matches[1])
+			to := normalizeGraphNodeName(matches[2])
+			graph.addNode(to)
+			if from != to && !graph.DependsDirectlyOn(from, to) {
+				graph.Dependencies[from] = append(graph.Dependencies[from], to)
+			}
+			continue
+		}
+		if matches := graphNodeRegexp.FindStringSubmatch(line); matches != nil {
+			graph.addNode(normalizeGraphNodeName(matches[1]))
+		}
+	}
+
+	for _, deps := range graph.Dependencies {
+		sort.Strings(deps)
+	}
+	return graph
+}
+
+func normalizeGraphNodeName(name string) string {
+	name = strings.ReplaceAll(name, `\"`, `"`)
+	name = strings.TrimPrefix(name, "[root] ")
+	return graphNodeSuffixRegexp.ReplaceAllString(name, "")
+}
+
+func (graph *GraphStruct) addNode(node string) {
+	if _, exists := graph.Dependencies[node]; !exists {
+		graph.Dependencies[node] = []string{}
+	}
+}
+
+// Nodes returns all the nodes in the graph, sorted alphabetically.
+func (graph *GraphStruct) Nodes() []string {
+	nodes := []string{}
+	for node := range graph.Dependencies {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// HasNode returns true if the given node is in the graph.
+func (graph *GraphStruct) HasNode(node string) bool {
+	_, exists := graph.Dependencies[node]
+	return exists
+}
+
+// DependsDirectlyOn returns true if there is an edge from node to dependency in the graph.
+func (graph *GraphStruct) DependsDirectlyOn(node string, dependency string) bool {
+	for _, dep := range graph.Dependencies[node] {
+		if dep == dependency {
+			return true
+		}
+	}
+	return false
+}
+
+// DependsOn returns true if node depends on dependency, either directly or transitively. This means terraform will
+// always finish creating dependency before it starts creating node.
+func (graph *GraphStruct) DependsOn(node string, dependency string) bool {
+	visited := map[string]bool{}
+	queue := []string{node}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range graph.Dependencies[current] {
+			if dep == dependency {
+				return true
+			}
+			if !visited[dep] {
+				visited[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return false
+}
+
+// AssertDependsOn checks that node depends on dependency in the graph, either directly or transitively, failing the
+// test if it does not.
+func AssertDependsOn(t testing.TestingT, graph *GraphStruct, node string, dependency string) {
+	assert.Truef(t, graph.DependsOn(node, dependency), "Expected %s to depend on %s", node, dependency)
+}
+
+// RequireDependsOn checks that node depends on dependency in the graph, either directly or transitively, failing and
+// halting the test if it does not.
+func RequireDependsOn(t testing.TestingT, graph *GraphStruct, node string, dependency string) {
+	require.Truef(t, graph.DependsOn(node, dependency), "Expected %s to depend on %s", node, dependency)
+}
@@ -0,0 +1,52 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGraphDotLegacyFormat(t *testing.T) {
+	t.Parallel()
+
+	dot := `digraph {
+	compound = "true"
+	newrank = "true"
+	subgraph "root" {
+		"[root] null_resource.a (expand)" [label = "null_resource.a", shape = "box"]
+		"[root] null_resource.b (expand)" [label = "null_resource.b", shape = "box"]
+		"[root] module.foo.null_resource.c (expand)" [label = "module.foo.null_resource.c", shape = "box"]
+		"[root] provider[\"registry.terraform.io/hashicorp/null\"]" [label = "provider[\"registry.terraform.io/hashicorp/null\"]", shape = "diamond"]
+		"[root] null_resource.a (expand)" -> "[root] provider[\"registry.terraform.io/hashicorp/null\"]"
+		"[root] null_resource.b (expand)" -> "[root] null_resource.a (expand)"
+		"[root] module.foo.null_resource.c (expand)" -> "[root] null_resource.b (expand)"
+		"[root] root" -> "[root] module.foo.null_resource.c (expand)"
+	}
+}`
+
+	graph := parseGraphDot(dot)
+
+	assert.True(t, graph.HasNode("null_resource.a"))
+	assert.True(t, graph.DependsDirectlyOn("null_resource.a", `provider["registry.terraform.io/hashicorp/null"]`))
+	assert.True(t, graph.DependsDirectlyOn("null_resource.b", "null_resource.a"))
+	assert.False(t, graph.DependsDirectlyOn("module.foo.null_resource.c", "null_resource.a"))
+	AssertDependsOn(t, graph, "module.foo.null_resource.c", "null_resource.a")
+	assert.False(t, graph.DependsOn("null_resource.a", "null_resource.b"))
+}
+
+func TestParseGraphDotModernFormat(t *testing.T) {
+	t.Parallel()
+
+	dot := `digraph G {
+  rankdir = "RL";
+  node [shape = rect, fontname = "sans-serif"];
+  "null_resource.a" [label="null_resource.a"];
+  "null_resource.b" [label="null_resource.b"];
+  "null_resource.b" -> "null_resource.a";
+}`
+
+	graph := parseGraphDot(dot)
+
+	assert.Equal(t, []string{"null_resource.a", "null_resource.b"}, graph.Nodes())
+	RequireDependsOn(t, graph, "null_resource.b", "null_resource.a")
+}
@@ -0,0 +1,94 @@
+package terraform
+
+import (
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// CommandHook is a function that is called before or after a terraform command runs, which allows attaching cross
+// cutting concerns (e.g., cost capture, notifications, or extra validation) to every command run with a set of options
+// without wrapping every call site. If a hook that runs before a command returns an error, the command is not run and
+// the error is returned. If a hook that runs after a successful command returns an error, that error is returned
+// instead of the output of the command.
+type CommandHook func(t testing.TestingT, hookContext *CommandHookContext) error
+
+// CommandHookContext is the information about a terraform command that is passed to a CommandHook.
+type CommandHookContext struct {
+	Command   string    // The terraform command being run (e.g., apply). For terragrunt run-all, this is the command being run in every module.
+	Args      []string  // The full list of arguments passed to terraform
+	Options   *Options  // The options the command is run with
+	StartTime time.Time // The time the command started (or, for hooks that run before the command, is about to start)
+
+	// The following are only set for hooks that run after the command.
+	Duration      time.Duration  // How long the command took to run
+	Output        string         // The output of the command
+	Err           error          // The error returned by the command, if any
+	ResourceCount *ResourceCount // The number of resources affected, parsed from the output of plan, apply, and destroy. Nil if the output can't be parsed.
+}
+
+// getCommandHooks returns the hooks configured on the options for the given terraform command.
+func getCommandHooks(options *Options, command string) (before CommandHook, after CommandHook) {
+	switch command {
+	case "init":
+		return options.BeforeInit, options.AfterInit
+	case "plan":
+		return options.BeforePlan, options.AfterPlan
+	case "apply":
+		return options.BeforeApply, options.AfterApply
+	case "destroy":
+		return options.BeforeDestroy, options.AfterDestroy
+	}
+	return nil, nil
+}
+
+// getHookCommand returns the terraform command that the given args run.
+func getHookCommand(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if args[0] == "run-all" && len(args) > 1 {
+		return args[1]
+	}
+	return args[0]
+}
+
+// runWithCommandHooks runs the given terraform command, calling the hooks that are configured on the options for it
+// before and after.
+func runWithCommandHooks(t testing.TestingT, options *Options, args []string, runCommand func() (string, error)) (string, error) {
+	command := getHookCommand(args)
+	before, after := getCommandHooks(options, command)
+	if before == nil && after == nil {
+		return runCommand()
+	}
+
+	hookContext := &CommandHookContext{
+		Command:   command,
+		Args:      args,
+		Options:   options,
+		StartTime: time.Now(),
+	}
+	if before != nil {
+		if err := before(t, hookContext); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := runCommand()
+	if after == nil {
+		return out, err
+	}
+
+	hookContext.Duration = time.Since(hookContext.StartTime)
+	hookContext.Output = out
+	hookContext.Err = err
+	if command == "plan" || command == "apply" || command == "destroy" {
+		if resourceCount, parseErr := GetResourceCountE(t, out); parseErr == nil {
+			hookContext.ResourceCount = resourceCount
+		}
+	}
+	if hookErr := after(t, hookContext); hookErr != nil && err == nil {
+		return out, hookErr
+	}
+	return out, err
+}
@@ -0,0 +1,90 @@
+package terraform
+
+import (
+	"errors"
+	"testing"
+
+	ttesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHookCommand(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "apply", getHookCommand([]string{"apply", "-auto-approve"}))
+	assert.Equal(t, "destroy", getHookCommand([]string{"run-all", "destroy", "-auto-approve"}))
+	assert.Equal(t, "", getHookCommand([]string{}))
+}
+
+func TestRunWithCommandHooks(t *testing.T) {
+	t.Parallel()
+
+	calls := []string{}
+	var afterContext *CommandHookContext
+	options := &Options{
+		BeforeApply: func(t ttesting.TestingT, hookContext *CommandHookContext) error {
+			calls = append(calls, "before "+hookContext.Command)
+			return nil
+		},
+		AfterApply: func(t ttesting.TestingT, hookContext *CommandHookContext) error {
+			calls = append(calls, "after "+hookContext.Command)
+			afterContext = hookContext
+			return nil
+		},
+	}
+
+	out, err := runWithCommandHooks(t, options, []string{"apply", "-auto-approve"}, func() (string, error) {
+		calls = append(calls, "command")
+		return "Apply complete! Resources: 2 added, 1 changed, 0 destroyed.", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Apply complete! Resources: 2 added, 1 changed, 0 destroyed.", out)
+	assert.Equal(t, []string{"before apply", "command", "after apply"}, calls)
+	assert.Equal(t, &ResourceCount{Add: 2, Change: 1, Destroy: 0}, afterContext.ResourceCount)
+	assert.Equal(t, out, afterContext.Output)
+	assert.Same(t, options, afterContext.Options)
+
+	// Hooks for other commands must not be called.
+	calls = []string{}
+	_, err = runWithCommandHooks(t, options, []string{"plan"}, func() (string, error) {
+		calls = append(calls, "command")
+		return "", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"command"}, calls)
+}
+
+func TestRunWithCommandHooksErrors(t *testing.T) {
+	t.Parallel()
+
+	hookErr := errors.New("hook failed")
+	commandErr := errors.New("command failed")
+
+	// An error in a before hook aborts the command.
+	options := &Options{
+		BeforeDestroy: func(t ttesting.TestingT, hookContext *CommandHookContext) error { return hookErr },
+	}
+	ran := false
+	_, err := runWithCommandHooks(t, options, []string{"destroy"}, func() (string, error) {
+		ran = true
+		return "", nil
+	})
+	assert.Equal(t, hookErr, err)
+	assert.False(t, ran)
+
+	// An error in an after hook is only returned if the command succeeded.
+	var afterErr error
+	options = &Options{
+		AfterDestroy: func(t ttesting.TestingT, hookContext *CommandHookContext) error {
+			afterErr = hookContext.Err
+			return hookErr
+		},
+	}
+	_, err = runWithCommandHooks(t, options, []string{"destroy"}, func() (string, error) { return "", nil })
+	assert.Equal(t, hookErr, err)
+
+	_, err = runWithCommandHooks(t, options, []string{"destroy"}, func() (string, error) { return "", commandErr })
+	assert.Equal(t, commandErr, err)
+	assert.Equal(t, commandErr, afterErr)
+}
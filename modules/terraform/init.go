@@ -2,6 +2,8 @@ package terraform
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
@@ -15,9 +17,59 @@ func Init(t testing.TestingT, options *Options) string {
 	return out
 }
 
-// InitE calls terraform init and return stdout/stderr.
+// initSemaphore limits the number of terraform init commands that run at the same time. It is nil when the number is
+// not limited.
+var (
+	initSemaphore      chan struct{}
+	initSemaphoreMutex sync.Mutex
+)
+
+// SetMaxConcurrentInits limits the number of terraform init commands that run at the same time across all the tests in
+// this process, so that many parallel tests don't download the same providers and modules simultaneously and trip the
+// rate limits of the registry. Inits that are already running are not affected. Pass 0 to remove the limit.
+func SetMaxConcurrentInits(n int) {
+	initSemaphoreMutex.Lock()
+	defer initSemaphoreMutex.Unlock()
+
+	if n <= 0 {
+		initSemaphore = nil
+		return
+	}
+	initSemaphore = make(chan struct{}, n)
+}
+
+// acquireInitSlot waits until an init is allowed to run under the limit set with SetMaxConcurrentInits, and returns the
+// function to call once the init is done.
+func acquireInitSlot() func() {
+	initSemaphoreMutex.Lock()
+	semaphore := initSemaphore
+	initSemaphoreMutex.Unlock()
+
+	if semaphore == nil {
+		return func() {}
+	}
+	semaphore <- struct{}{}
+	return func() { <-semaphore }
+}
+
+// The messages terraform prints when init requires a decision about what to do with the existing state after the
+// backend configuration changes. These are prompts when input is enabled, and errors when it is not.
+var initBackendChangeMessages = []string{
+	"Backend configuration changed",
+	"Do you want to copy existing state to the new backend?",
+	"Do you want to migrate all workspaces to",
+	"Do you want to copy only your current workspace?",
+}
+
+// InitE calls terraform init and return stdout/stderr. Input is disabled so that terraform never hangs waiting for an
+// answer on stdin: if the backend configuration changed and the existing state needs to be migrated, this returns an
+// InitBackendChanged error, and the test should set either MigrateState or Reconfigure on the options.
 func InitE(t testing.TestingT, options *Options) (string, error) {
-	args := []string{"init", fmt.Sprintf("-upgrade=%t", options.Upgrade)}
+	if options.MigrateState && options.Reconfigure {
+		return "", MigrateStateAndReconfigureSet{}
+	}
+
+	args := []string{"init", fmt.Sprintf("-upgrade=%t", options.Upgrade), "-input=false"}
 
 	// Append reconfigure option if specified
 	if options.Reconfigure {
@@ -34,5 +86,22 @@ func InitE(t testing.TestingT, options *Options) (string, error) {
 
 	args = append(args, FormatTerraformBackendConfigAsArgs(options.BackendConfig)...)
 	args = append(args, FormatTerraformPluginDirAsArgs(options.PluginDir)...)
-	return RunTerraformCommandE(t, options, args...)
+	release := acquireInitSlot()
+	defer release()
+	out, err := RunTerraformCommandE(t, options, args...)
+	if err != nil && needsBackendChangeDecision(out+"\n"+err.Error()) {
+		return out, InitBackendChanged{Underlying: err}
+	}
+	return out, err
+}
+
+// needsBackendChangeDecision returns true if the given output of terraform init shows that the backend configuration
+// changed and terraform needs to be told what to do with the existing state.
+func needsBackendChangeDecision(output string) bool {
+	for _, message := range initBackendChangeMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
 }
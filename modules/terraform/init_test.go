@@ -3,7 +3,10 @@ package terraform
 import (
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/stretchr/testify/assert"
@@ -99,6 +102,7 @@ func TestInitReconfigureBackend(t *testing.T) {
 	options.BackendConfig["workspace_dir"] = "new"
 	_, err = InitE(t, options)
 	assert.Error(t, err, "Backend initialization with changed configuration should fail without -reconfigure option")
+	assert.IsType(t, InitBackendChanged{}, err)
 
 	options.Reconfigure = true
 	_, err = InitE(t, options)
@@ -127,6 +131,7 @@ func TestInitBackendMigration(t *testing.T) {
 	options.BackendConfig["workspace_dir"] = "new"
 	_, err = InitE(t, options)
 	assert.Error(t, err, "Backend initialization with changed configuration should fail without -migrate-state option")
+	assert.IsType(t, InitBackendChanged{}, err)
 
 	options.MigrateState = true
 	_, err = InitE(t, options)
@@ -151,3 +156,48 @@ func TestInitNoColorOption(t *testing.T) {
 	// Check that NoColor correctly doesn't output the colour escape codes which look like [0m,[1m or [32m
 	require.NotRegexp(t, `\[\d*m`, out, "Output should not contain color escape codes")
 }
+
+func TestInitMigrateStateAndReconfigureAreExclusive(t *testing.T) {
+	t.Parallel()
+
+	_, err := InitE(t, &Options{TerraformDir: t.TempDir(), MigrateState: true, Reconfigure: true})
+	assert.Equal(t, MigrateStateAndReconfigureSet{}, err)
+}
+
+func TestNeedsBackendChangeDecision(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, needsBackendChangeDecision("Error: Backend configuration changed\n\nA change in the backend configuration has been detected"))
+	assert.True(t, needsBackendChangeDecision("Do you want to copy existing state to the new backend?\n  Enter a value:"))
+	assert.False(t, needsBackendChangeDecision("Error: Failed to query available provider packages"))
+}
+
+func TestSetMaxConcurrentInits(t *testing.T) {
+	// Not parallel, since this changes the limit for all the tests in the package.
+	SetMaxConcurrentInits(2)
+	defer SetMaxConcurrentInits(0)
+
+	var running, maxRunning int32
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := acquireInitSlot()
+			defer release()
+
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), maxRunning)
+}
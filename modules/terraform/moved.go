@@ -0,0 +1,117 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// ResourceMove represents a resource whose address terraform plans to change, without otherwise modifying it (e.g., as
+// the result of a `moved {}` block).
+type ResourceMove struct {
+	From string
+	To   string
+}
+
+// refactorPlan is the subset of the plan JSON we need to check refactors. We parse it ourselves because the version of
+// terraform-json we depend on does not expose the previous_address field of resource changes.
+type refactorPlan struct {
+	ResourceChanges []struct {
+		Address         string `json:"address"`
+		PreviousAddress string `json:"previous_address"`
+		Mode            string `json:"mode"`
+		Change          struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// InitAndPlanAndAssertOnlyMoves runs terraform init and plan with the given options and checks that every change in the
+// plan is a no-op move of an existing resource. This is intended to be run against existing state after refactoring a
+// module, to validate that the `moved {}` blocks cover every resource whose address changed. Returns the list of
+// planned moves. This will fail the test if there is an error in the commands or if terraform plans any other changes.
+func InitAndPlanAndAssertOnlyMoves(t testing.TestingT, options *Options) []ResourceMove {
+	moves, err := InitAndPlanAndAssertOnlyMovesE(t, options)
+	require.NoError(t, err)
+	return moves
+}
+
+// InitAndPlanAndAssertOnlyMovesE runs terraform init and plan with the given options and checks that every change in
+// the plan is a no-op move of an existing resource. This is intended to be run against existing state after refactoring
+// a module, to validate that the `moved {}` blocks cover every resource whose address changed. Returns the list of
+// planned moves, or a RefactorNotSafe error listing every resource terraform would create, update, or destroy.
+func InitAndPlanAndAssertOnlyMovesE(t testing.TestingT, options *Options) ([]ResourceMove, error) {
+	if _, err := InitE(t, options); err != nil {
+		return nil, err
+	}
+	return PlanAndAssertOnlyMovesE(t, options)
+}
+
+// PlanAndAssertOnlyMoves runs terraform plan with the given options and checks that every change in the plan is a no-op
+// move of an existing resource. Returns the list of planned moves. This will fail the test if there is an error in the
+// commands or if terraform plans any other changes.
+func PlanAndAssertOnlyMoves(t testing.TestingT, options *Options) []ResourceMove {
+	moves, err := PlanAndAssertOnlyMovesE(t, options)
+	require.NoError(t, err)
+	return moves
+}
+
+// PlanAndAssertOnlyMovesE runs terraform plan with the given options and checks that every change in the plan is a
+// no-op move of an existing resource. Returns the list of planned moves, or a RefactorNotSafe error listing every
+// resource terraform would create, update, or destroy.
+func PlanAndAssertOnlyMovesE(t testing.TestingT, options *Options) ([]ResourceMove, error) {
+	planOptions, err := planToTempFileE(t, options)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(planOptions.PlanFilePath)
+
+	jsonOut, err := ShowE(t, planOptions)
+	if err != nil {
+		return nil, err
+	}
+	return parseRefactorPlanJson(jsonOut)
+}
+
+// parseRefactorPlanJson takes in the json string representation of the terraform plan and returns the resource moves it
+// contains, or a RefactorNotSafe error if there are any changes other than no-op moves.
+func parseRefactorPlanJson(jsonStr string) ([]ResourceMove, error) {
+	plan := refactorPlan{}
+	if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
+		return nil, err
+	}
+
+	moves := []ResourceMove{}
+	notSafe := RefactorNotSafe{}
+	for _, change := range plan.ResourceChanges {
+		// Data sources are read on every plan and can't be moved, so they are irrelevant for refactor safety.
+		if change.Mode == "data" {
+			continue
+		}
+
+		actions := strings.Join(change.Change.Actions, ",")
+		switch {
+		case actions == "no-op" || actions == "":
+			if change.PreviousAddress != "" && change.PreviousAddress != change.Address {
+				moves = append(moves, ResourceMove{From: change.PreviousAddress, To: change.Address})
+			}
+		case strings.Contains(actions, "delete"):
+			notSafe.Destroyed = append(notSafe.Destroyed, change.Address)
+		default:
+			notSafe.Changed = append(notSafe.Changed, fmt.Sprintf("%s (%s)", change.Address, actions))
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].To < moves[j].To })
+	if len(notSafe.Destroyed) > 0 || len(notSafe.Changed) > 0 {
+		sort.Strings(notSafe.Destroyed)
+		sort.Strings(notSafe.Changed)
+		return moves, notSafe
+	}
+	return moves, nil
+}
@@ -0,0 +1,44 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRefactorPlanJsonOnlyMoves(t *testing.T) {
+	t.Parallel()
+
+	jsonPlan := `{
+  "resource_changes": [
+    {"address": "module.new.null_resource.foo", "previous_address": "null_resource.foo", "mode": "managed", "change": {"actions": ["no-op"]}},
+    {"address": "null_resource.bar", "mode": "managed", "change": {"actions": ["no-op"]}},
+    {"address": "data.null_data_source.baz", "mode": "data", "change": {"actions": ["read"]}}
+  ]
+}`
+
+	moves, err := parseRefactorPlanJson(jsonPlan)
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceMove{{From: "null_resource.foo", To: "module.new.null_resource.foo"}}, moves)
+}
+
+func TestParseRefactorPlanJsonWithDestroy(t *testing.T) {
+	t.Parallel()
+
+	jsonPlan := `{
+  "resource_changes": [
+    {"address": "module.new.null_resource.foo", "previous_address": "null_resource.foo", "mode": "managed", "change": {"actions": ["no-op"]}},
+    {"address": "null_resource.bar", "mode": "managed", "change": {"actions": ["delete"]}},
+    {"address": "null_resource.baz", "mode": "managed", "change": {"actions": ["create"]}},
+    {"address": "null_resource.qux", "mode": "managed", "change": {"actions": ["delete", "create"]}}
+  ]
+}`
+
+	_, err := parseRefactorPlanJson(jsonPlan)
+	require.Error(t, err)
+	notSafe, isNotSafe := err.(RefactorNotSafe)
+	require.True(t, isNotSafe)
+	assert.Equal(t, []string{"null_resource.bar", "null_resource.qux"}, notSafe.Destroyed)
+	assert.Equal(t, []string{"null_resource.baz (create)"}, notSafe.Changed)
+}
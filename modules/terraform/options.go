@@ -52,6 +52,7 @@ type Options struct {
 
 	VarFiles                 []string               // The var file paths to pass to Terraform commands using -var-file option.
 	Targets                  []string               // The target resources to pass to the terraform command with -target
+	Replace                  []string               // The resource addresses to force replacement of with -replace (plan and apply only)
 	Lock                     bool                   // The lock option to pass to the terraform command with -lock
 	LockTimeout              string                 // The lock timeout option to pass to the terraform command with -lock-timeout
 	EnvVars                  map[string]string      // Environment variables to set when running Terraform
@@ -71,6 +72,25 @@ type Options struct {
 	PlanFilePath             string                 // The path to output a plan file to (for the plan command) or read one from (for the apply command)
 	PluginDir                string                 // The path of downloaded plugins to pass to the terraform init command (-plugin-dir)
 	SetVarsAfterVarFiles     bool                   // Pass -var options after -var-file options to Terraform commands
+	DestroyGuard             *DestroyGuard          // If set, Destroy refuses to run unless everything it would destroy passes the guard. See DestroyGuard.
+	CaptureTerraformLogs     bool                   // Run terraform with TF_LOG=json and capture the log records. See GetTerraformLogRecords.
+	TerraformLogPath         string                 // The file to capture terraform logs to when CaptureTerraformLogs is set. Defaults to a new temporary file (set it explicitly to share logs across cloned options).
+	TerraformLogLinesOnError int                    // The number of captured log lines to attach to the error of a failed command. Defaults to DefaultTerraformLogLinesOnError.
+	PassVarsAsEnvVars        bool                   // Pass Vars to terraform as TF_VAR_ environment variables instead of -var flags, which keeps them out of the command line. See GetTerraformEnv for how the environment is layered.
+	StrictEnvVars            bool                   // Fail terraform commands with an EnvVarConflicts error if a terraform variable is set to different values by the process environment, EnvVars, or Vars, instead of logging a warning
+	SensitiveVars            []string               // The names of the Vars and EnvVars whose values are redacted from the logs of terraform commands
+	BeforeInit               CommandHook            // Called before every terraform init. Returning an error aborts the command. See CommandHook.
+	AfterInit                CommandHook            // Called after every terraform init, whether it succeeded or not. See CommandHook.
+	BeforePlan               CommandHook            // Called before every terraform plan. Returning an error aborts the command. See CommandHook.
+	AfterPlan                CommandHook            // Called after every terraform plan, whether it succeeded or not. See CommandHook.
+	BeforeApply              CommandHook            // Called before every terraform apply. Returning an error aborts the command. See CommandHook.
+	AfterApply               CommandHook            // Called after every terraform apply, whether it succeeded or not. See CommandHook.
+	BeforeDestroy            CommandHook            // Called before every terraform destroy. Returning an error aborts the command. See CommandHook.
+	AfterDestroy             CommandHook            // Called after every terraform destroy, whether it succeeded or not. See CommandHook.
+	StateLockWaitTimeout     time.Duration          // If the state is locked by another process, keep retrying the command for up to this long for the lock to be released, instead of failing right away
+	StateLockWaitInterval    time.Duration          // How long to wait between retries while the state is locked. Defaults to DefaultStateLockWaitInterval.
+	OutputLineCallback       func(line string)      // Called with every line of output (stdout and stderr) of terraform commands as soon as it is written (e.g., for custom progress reporting). Calls are never concurrent.
+	OutputStallTimeout       time.Duration          // Kill terraform commands that don't write any output for this long, and return an OutputStalled error
 }
 
 // Clone makes a deep copy of most fields on the Options object and returns it.
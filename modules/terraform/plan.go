@@ -96,6 +96,32 @@ func InitAndPlanAndShowWithStructE(t testing.TestingT, options *Options) (*PlanS
 	return parsePlanJson(jsonOut)
 }
 
+// planToTempFileE runs terraform plan with the given options, saving the plan to a new temporary file, and returns a
+// copy of the options with PlanFilePath pointing at that file. The caller's options are left untouched. The caller is
+// responsible for removing the plan file when done with it.
+func planToTempFileE(t testing.TestingT, options *Options) (*Options, error) {
+	planFile, err := ioutil.TempFile("", "terratest-plan-file-")
+	if err != nil {
+		return nil, err
+	}
+	if err := planFile.Close(); err != nil {
+		return nil, err
+	}
+
+	planOptions, err := options.Clone()
+	if err != nil {
+		os.Remove(planFile.Name())
+		return nil, err
+	}
+	planOptions.PlanFilePath = planFile.Name()
+
+	if _, err := PlanE(t, planOptions); err != nil {
+		os.Remove(planFile.Name())
+		return nil, err
+	}
+	return planOptions, nil
+}
+
 // InitAndPlanWithExitCode runs terraform init and plan with the given options and returns exitcode for the plan command.
 // This will fail the test if there is an error in the command.
 func InitAndPlanWithExitCode(t testing.TestingT, options *Options) int {
@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/gruntwork-io/terratest/modules/testing"
 	tfjson "github.com/hashicorp/terraform-json"
@@ -91,6 +92,19 @@ func parseModulePlannedValues(module *tfjson.StateModule) map[string]*tfjson.Sta
 	return out
 }
 
+// GetReplacedResourceAddresses returns the full addresses of all the resources in the plan that terraform will
+// replace (destroy and recreate, in either order), sorted alphabetically.
+func GetReplacedResourceAddresses(plan *PlanStruct) []string {
+	out := []string{}
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change != nil && change.Change.Actions.Replace() {
+			out = append(out, address)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
 // AssertPlannedValuesMapKeyExists checks if the given key exists in the map, failing the test if it does not.
 func AssertPlannedValuesMapKeyExists(t testing.TestingT, plan *PlanStruct, keyQuery string) {
 	_, hasKey := plan.ResourcePlannedValuesMap[keyQuery]
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	http_helper "github.com/gruntwork-io/terratest/modules/http-helper"
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -79,3 +80,23 @@ func TestResourceChangesJson(t *testing.T) {
 	assert.Equal(t, barChanges.Change.After.(map[string]interface{})["triggers"].(map[string]interface{})["foo_id"].(string), "424881806176056736")
 
 }
+
+func TestGetReplacedResourceAddresses(t *testing.T) {
+	t.Parallel()
+
+	plan := &PlanStruct{
+		ResourceChangesMap: map[string]*tfjson.ResourceChange{
+			"null_resource.create":         {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+			"null_resource.delete_create":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+			"null_resource.create_delete":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate, tfjson.ActionDelete}}},
+			"null_resource.noop":           {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+			"module.foo.null_resource.bar": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]string{"module.foo.null_resource.bar", "null_resource.create_delete", "null_resource.delete_create"},
+		GetReplacedResourceAddresses(plan),
+	)
+}
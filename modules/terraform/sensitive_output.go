@@ -0,0 +1,175 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// MinMaskedValueLength is the length of the shortest value that MaskSensitiveValue registers. Shorter values, e.g.,
+// "1" or "on", are ignored, as replacing them would redact unrelated parts of the logs.
+const MinMaskedValueLength = 6
+
+// maskedValues holds the values registered with MaskSensitiveValue and MaskSensitiveValueForTest, which are redacted
+// from the logs of all terraform commands, with the number of times each was registered.
+var (
+	maskedValues      = map[string]int{}
+	maskedValuesMutex sync.RWMutex
+)
+
+// MaskSensitiveValue registers the given value as sensitive, so that it is replaced with RedactedValue in the logs of
+// all subsequent terraform commands run by this package, regardless of the options they are run with, until
+// ClearMaskedValues is called. Use MaskSensitiveValueForTest to only register it while a test runs. Values shorter
+// than MinMaskedValueLength are ignored.
+func MaskSensitiveValue(value string) {
+	registerMaskedValue(value)
+}
+
+// MaskSensitiveValueForTest registers the given value as sensitive like MaskSensitiveValue, until the given test ends,
+// if it supports Cleanup like testing.T does. Values fetched with OutputSensitive are registered this way
+// automatically.
+func MaskSensitiveValueForTest(t testing.TestingT, value string) {
+	if !registerMaskedValue(value) {
+		return
+	}
+	if cleaner, ok := t.(interface{ Cleanup(func()) }); ok {
+		cleaner.Cleanup(func() {
+			unregisterMaskedValue(value)
+		})
+	}
+}
+
+// ClearMaskedValues unregisters all the values registered with MaskSensitiveValue and MaskSensitiveValueForTest.
+func ClearMaskedValues() {
+	maskedValuesMutex.Lock()
+	defer maskedValuesMutex.Unlock()
+
+	maskedValues = map[string]int{}
+}
+
+// registerMaskedValue registers the given value as sensitive, and returns whether it is long enough to be.
+func registerMaskedValue(value string) bool {
+	if len(value) < MinMaskedValueLength {
+		return false
+	}
+	maskedValuesMutex.Lock()
+	defer maskedValuesMutex.Unlock()
+
+	maskedValues[value]++
+	return true
+}
+
+// unregisterMaskedValue undoes one registration of the given value, which stays registered if it was registered more
+// than once, e.g., by tests running in parallel.
+func unregisterMaskedValue(value string) {
+	maskedValuesMutex.Lock()
+	defer maskedValuesMutex.Unlock()
+
+	if maskedValues[value] <= 1 {
+		delete(maskedValues, value)
+		return
+	}
+	maskedValues[value]--
+}
+
+// getMaskedValues returns all the registered sensitive values.
+func getMaskedValues() []string {
+	maskedValuesMutex.RLock()
+	defer maskedValuesMutex.RUnlock()
+
+	values := []string{}
+	for value := range maskedValues {
+		values = append(values, value)
+	}
+	return values
+}
+
+// OutputSensitive calls terraform output for the given sensitive variable and returns its string value representation,
+// without logging the value. The value is registered with MaskSensitiveValueForTest so that it is redacted from the logs
+// of the terraform commands run until the test ends. It only designed to work with primitive terraform types: string, number and bool.
+// Please use OutputSensitiveStruct for anything else.
+func OutputSensitive(t testing.TestingT, options *Options, key string) string {
+	out, err := OutputSensitiveE(t, options, key)
+	require.NoError(t, err)
+	return out
+}
+
+// OutputSensitiveE calls terraform output for the given sensitive variable and returns its string value
+// representation, without logging the value. A string value is registered with MaskSensitiveValueForTest so that it is
+// redacted from the logs of the terraform commands run until the test ends. It only designed to work with primitive terraform types: string,
+// number and bool. Please use OutputSensitiveStructE for anything else.
+func OutputSensitiveE(t testing.TestingT, options *Options, key string) (string, error) {
+	var val interface{}
+	if err := OutputSensitiveStructE(t, options, key, &val); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+// OutputSensitiveStruct calls terraform output for the given sensitive variable and stores the result in the value
+// pointed to by v, without logging the value. All the strings in the value are registered with
+// MaskSensitiveValueForTest so that they are redacted from the logs of the terraform commands run until the test ends.
+// This will fail the test if there is an error.
+func OutputSensitiveStruct(t testing.TestingT, options *Options, key string, v interface{}) {
+	require.NoError(t, OutputSensitiveStructE(t, options, key, v))
+}
+
+// OutputSensitiveStructE calls terraform output for the given sensitive variable and stores the result in the value
+// pointed to by v, without logging the value. All the strings in the value are registered with
+// MaskSensitiveValueForTest so that they are redacted from the logs of the terraform commands run until the test ends.
+// Numbers and bools, including strings that hold one, are not registered, as they can't be told apart from the other
+// numbers and bools in the logs.
+func OutputSensitiveStructE(t testing.TestingT, options *Options, key string, v interface{}) error {
+	quietOptions, err := options.Clone()
+	if err != nil {
+		return err
+	}
+	quietOptions.Logger = logger.Discard
+
+	options.Logger.Logf(t, "Reading sensitive output %s", key)
+	out, err := OutputJsonE(t, quietOptions, key)
+	if err != nil {
+		return err
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(out), &raw); err != nil {
+		return err
+	}
+	maskStringLeaves(t, raw)
+
+	return json.Unmarshal([]byte(out), &v)
+}
+
+// maskStringLeaves registers all the strings in the given decoded json value, other than those that hold a number or a
+// bool, with MaskSensitiveValueForTest.
+func maskStringLeaves(t testing.TestingT, value interface{}) {
+	switch typed := value.(type) {
+	case string:
+		if !isNumberOrBool(typed) {
+			MaskSensitiveValueForTest(t, typed)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			maskStringLeaves(t, item)
+		}
+	case map[string]interface{}:
+		for _, item := range typed {
+			maskStringLeaves(t, item)
+		}
+	}
+}
+
+// isNumberOrBool returns whether the given string is a number or a bool, e.g., "8080" or "true".
+func isNumberOrBool(value string) bool {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseBool(value)
+	return err == nil
+}
@@ -0,0 +1,94 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	ttesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger is a logger.TestLogger that keeps all the messages it logs. Commands log stdout and stderr
+// concurrently, so access to the messages is synchronized.
+type recordingLogger struct {
+	mutex    *sync.Mutex
+	messages *[]string
+}
+
+func (l recordingLogger) Logf(t ttesting.TestingT, format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	*l.messages = append(*l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestMaskSensitiveValue(t *testing.T) {
+	t.Parallel()
+
+	maskStringLeaves(t, map[string]interface{}{
+		"nested": []interface{}{"masked-leaf-2c1af0", 42.0, "8080", "true"},
+	})
+	MaskSensitiveValue("masked-value-9d8e7f")
+	MaskSensitiveValue("abc")
+
+	options := &Options{}
+	assert.Equal(
+		t,
+		"token=[REDACTED] leaf=[REDACTED] count=42 port=8080 enabled=true name=abc",
+		redactSensitiveValues(options, "token=masked-value-9d8e7f leaf=masked-leaf-2c1af0 count=42 port=8080 enabled=true name=abc"),
+	)
+}
+
+func TestMaskSensitiveValueForTest(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{}
+	t.Run("test", func(t *testing.T) {
+		MaskSensitiveValueForTest(t, "test-scoped-value-7b3e2a")
+		assert.Equal(t, "token=[REDACTED]", redactSensitiveValues(options, "token=test-scoped-value-7b3e2a"))
+	})
+
+	// The value is unregistered once the test ends.
+	assert.Equal(t, "token=test-scoped-value-7b3e2a", redactSensitiveValues(options, "token=test-scoped-value-7b3e2a"))
+}
+
+func TestClearMaskedValues(t *testing.T) {
+	// Not parallel, as this unregisters the values of the other tests.
+	MaskSensitiveValue("cleared-value-4e5f6a")
+	ClearMaskedValues()
+	assert.Equal(t, "token=cleared-value-4e5f6a", redactSensitiveValues(&Options{}, "token=cleared-value-4e5f6a"))
+}
+
+func TestOutputSensitive(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-output-sensitive", t.Name())
+	require.NoError(t, err)
+
+	messages := []string{}
+	options := &Options{
+		TerraformDir: testFolder,
+		Logger:       logger.New(recordingLogger{mutex: &sync.Mutex{}, messages: &messages}),
+	}
+
+	InitAndApply(t, options)
+
+	password := OutputSensitive(t, options, "password")
+	require.Equal(t, "correct-horse-battery-staple", password)
+
+	credentials := map[string]string{}
+	OutputSensitiveStruct(t, options, "credentials", &credentials)
+	require.Equal(t, "tok-5f4dcc3b5aa765d61d8327deb882cf99", credentials["token"])
+
+	// Later commands that print the values must have them redacted.
+	OutputJson(t, options, "")
+
+	logs := strings.Join(messages, "\n")
+	assert.NotContains(t, logs, "correct-horse-battery-staple")
+	assert.NotContains(t, logs, "tok-5f4dcc3b5aa765d61d8327deb882cf99")
+	assert.Contains(t, logs, RedactedValue)
+}
@@ -0,0 +1,206 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/stretchr/testify/require"
+)
+
+// UpdatePlanSnapshotsEnvVar is the environment variable that makes SnapshotPlan overwrite the golden files with the
+// current plan instead of comparing against them when set to true, e.g., `TERRATEST_UPDATE_SNAPSHOTS=true go test`.
+const UpdatePlanSnapshotsEnvVar = "TERRATEST_UPDATE_SNAPSHOTS"
+
+// PlanSnapshotVolatileAttributes is a list of regular expressions, matched against the attribute names of a resource,
+// for attributes whose values change on every run (e.g., timestamps or generated names). The values of matching
+// attributes are masked in plan snapshots so that the snapshot is stable. Values that are unknown until apply and
+// sensitive values are always masked.
+var PlanSnapshotVolatileAttributes = []string{}
+
+const (
+	planSnapshotUnknownValue   = "(known after apply)"
+	planSnapshotSensitiveValue = "(sensitive)"
+	planSnapshotVolatileValue  = "(volatile)"
+)
+
+// SnapshotPlan runs terraform init and plan with the given options, and compares a normalized view of the plan against
+// the golden file at goldenPath. Run the tests with TERRATEST_UPDATE_SNAPSHOTS=true to (re)generate the golden files. This will fail
+// the test if there is an error in the commands or if the plan does not match the golden file.
+func SnapshotPlan(t testing.TestingT, options *Options, goldenPath string) {
+	require.NoError(t, SnapshotPlanE(t, options, goldenPath))
+}
+
+// SnapshotPlanE runs terraform init and plan with the given options, and compares a normalized view of the plan against
+// the golden file at goldenPath. The normalized view contains the address and actions of every resource change along
+// with the attributes that change, with volatile values masked (see PlanSnapshotVolatileAttributes). Run the tests with
+// TERRATEST_UPDATE_SNAPSHOTS=true to (re)generate the golden files, which should be committed alongside the tests.
+func SnapshotPlanE(t testing.TestingT, options *Options, goldenPath string) error {
+	if _, err := InitE(t, options); err != nil {
+		return err
+	}
+
+	planOptions, err := planToTempFileE(t, options)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(planOptions.PlanFilePath)
+
+	plan, err := ShowWithStructE(t, planOptions)
+	if err != nil {
+		return err
+	}
+	snapshot, err := formatPlanSnapshot(plan)
+	if err != nil {
+		return err
+	}
+
+	if updatePlanSnapshots() {
+		options.Logger.Logf(t, "Updating plan snapshot %s", goldenPath)
+		if err := os.MkdirAll(filepath.Dir(goldenPath), os.ModePerm); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(goldenPath, []byte(snapshot), 0644)
+	}
+
+	golden, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		return err
+	}
+	if string(golden) == snapshot {
+		return nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(golden)),
+		B:        difflib.SplitLines(snapshot),
+		FromFile: goldenPath,
+		ToFile:   "plan",
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	return PlanSnapshotMismatch{GoldenPath: goldenPath, Diff: diff}
+}
+
+// updatePlanSnapshots returns whether TERRATEST_UPDATE_SNAPSHOTS is set to true.
+func updatePlanSnapshots() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdatePlanSnapshotsEnvVar))
+	return update
+}
+
+// formatPlanSnapshot renders the normalized, deterministic text representation of the plan used for snapshots.
+func formatPlanSnapshot(plan *PlanStruct) (string, error) {
+	volatileRegexps := []*regexp.Regexp{}
+	for _, pattern := range PlanSnapshotVolatileAttributes {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		volatileRegexps = append(volatileRegexps, re)
+	}
+
+	addresses := []string{}
+	for address := range plan.ResourceChangesMap {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var builder strings.Builder
+	for _, address := range addresses {
+		change := plan.ResourceChangesMap[address].Change
+		if change == nil || change.Actions.NoOp() || change.Actions.Read() {
+			continue
+		}
+
+		actions := []string{}
+		for _, action := range change.Actions {
+			actions = append(actions, string(action))
+		}
+		fmt.Fprintf(&builder, "%s: %s\n", address, strings.Join(actions, ","))
+
+		// The attributes of a resource that is only being destroyed are irrelevant.
+		if change.Actions.Delete() {
+			continue
+		}
+		lines, err := formatChangedAttributes(change, volatileRegexps)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range lines {
+			fmt.Fprintf(&builder, "  %s\n", line)
+		}
+	}
+	return builder.String(), nil
+}
+
+// formatChangedAttributes returns a sorted `name = value` line for every top level attribute that differs between the
+// before and after states of the given change, masking unknown, sensitive, and volatile values.
+func formatChangedAttributes(change *tfjson.Change, volatileRegexps []*regexp.Regexp) ([]string, error) {
+	before := toAttributeMap(change.Before)
+	after := toAttributeMap(change.After)
+	afterUnknown := toAttributeMap(change.AfterUnknown)
+	afterSensitive := toAttributeMap(change.AfterSensitive)
+
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	for name := range afterUnknown {
+		names[name] = true
+	}
+
+	lines := []string{}
+	for name := range names {
+		var value string
+		switch {
+		case afterUnknown[name] == true:
+			value = planSnapshotUnknownValue
+		case reflect.DeepEqual(before[name], after[name]):
+			continue
+		case afterSensitive[name] == true:
+			value = planSnapshotSensitiveValue
+		case matchesAnyRegexp(name, volatileRegexps):
+			value = planSnapshotVolatileValue
+		default:
+			encoded, err := json.Marshal(after[name])
+			if err != nil {
+				return nil, err
+			}
+			value = string(encoded)
+		}
+		lines = append(lines, fmt.Sprintf("%s = %s", name, value))
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+func toAttributeMap(value interface{}) map[string]interface{} {
+	if m, isMap := value.(map[string]interface{}); isMap {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+func matchesAnyRegexp(value string, regexps []*regexp.Regexp) bool {
+	for _, re := range regexps {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package terraform
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPlanSnapshot(t *testing.T) {
+	t.Parallel()
+
+	plan := &PlanStruct{
+		ResourceChangesMap: map[string]*tfjson.ResourceChange{
+			"null_resource.created": {Change: &tfjson.Change{
+				Actions:      tfjson.Actions{tfjson.ActionCreate},
+				After:        map[string]interface{}{"triggers": map[string]interface{}{"b": "2", "a": "1"}},
+				AfterUnknown: map[string]interface{}{"id": true},
+			}},
+			"null_resource.updated": {Change: &tfjson.Change{
+				Actions:        tfjson.Actions{tfjson.ActionUpdate},
+				Before:         map[string]interface{}{"id": "123", "name": "old", "password": "foo"},
+				After:          map[string]interface{}{"id": "123", "name": "new", "password": "bar"},
+				AfterSensitive: map[string]interface{}{"password": true},
+			}},
+			"null_resource.deleted": {Change: &tfjson.Change{
+				Actions: tfjson.Actions{tfjson.ActionDelete},
+				Before:  map[string]interface{}{"id": "456"},
+			}},
+			"null_resource.unchanged": {Change: &tfjson.Change{
+				Actions: tfjson.Actions{tfjson.ActionNoop},
+			}},
+		},
+	}
+
+	snapshot, err := formatPlanSnapshot(plan)
+	require.NoError(t, err)
+	assert.Equal(t, `null_resource.created: create
+  id = (known after apply)
+  triggers = {"a":"1","b":"2"}
+null_resource.deleted: delete
+null_resource.updated: update
+  name = "new"
+  password = (sensitive)
+`, snapshot)
+}
+
+func TestUpdatePlanSnapshots(t *testing.T) {
+	t.Setenv(UpdatePlanSnapshotsEnvVar, "")
+	assert.False(t, updatePlanSnapshots())
+
+	t.Setenv(UpdatePlanSnapshotsEnvVar, "true")
+	assert.True(t, updatePlanSnapshots())
+
+	t.Setenv(UpdatePlanSnapshotsEnvVar, "false")
+	assert.False(t, updatePlanSnapshots())
+}
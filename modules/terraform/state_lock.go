@@ -0,0 +1,99 @@
+package terraform
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultStateLockWaitInterval is how often a command is retried while waiting for the state lock when
+// StateLockWaitTimeout is set and StateLockWaitInterval is not.
+const DefaultStateLockWaitInterval = 10 * time.Second
+
+const stateLockErrorMessage = "Error acquiring the state lock"
+
+// StateLockInfo describes who holds the state lock, as reported by terraform when it fails to acquire the lock.
+type StateLockInfo struct {
+	ID        string // The lock ID, which can be passed to ForceUnlock
+	Path      string // The path of the state that is locked
+	Operation string // The operation the lock holder is running (e.g., OperationTypeApply)
+	Who       string // The user and host that hold the lock
+	Version   string // The terraform version of the lock holder
+	Created   string // When the lock was acquired
+	Info      string // Extra information stored with the lock
+}
+
+// ParseStateLockError checks if the given output of a terraform command contains a state lock error, and if so,
+// returns the information about who holds the lock.
+func ParseStateLockError(output string) (*StateLockInfo, bool) {
+	index := strings.Index(output, stateLockErrorMessage)
+	if index < 0 {
+		return nil, false
+	}
+	lockInfoText := output[index:]
+	if lockInfoIndex := strings.Index(lockInfoText, "Lock Info:"); lockInfoIndex >= 0 {
+		lockInfoText = lockInfoText[lockInfoIndex:]
+	}
+
+	// Newer versions of terraform prefix each line of the error with a box drawing character.
+	field := func(name string) string {
+		re := regexp.MustCompile(`(?m)^[│\s]*` + name + `:[ \t]*(.*?)\s*$`)
+		if match := re.FindStringSubmatch(lockInfoText); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+	return &StateLockInfo{
+		ID:        field("ID"),
+		Path:      field("Path"),
+		Operation: field("Operation"),
+		Who:       field("Who"),
+		Version:   field("Version"),
+		Created:   field("Created"),
+		Info:      field("Info"),
+	}, true
+}
+
+// runWithStateLockWait runs the given terraform command. If the command fails because another process holds the state
+// lock, the error is replaced with a StateLocked error that reports who holds the lock. If StateLockWaitTimeout is set
+// on the options, the command is retried until the lock is released or the timeout expires.
+func runWithStateLockWait(t testing.TestingT, options *Options, runCommand func() (string, error)) (string, error) {
+	interval := options.StateLockWaitInterval
+	if interval <= 0 {
+		interval = DefaultStateLockWaitInterval
+	}
+	deadline := time.Now().Add(options.StateLockWaitTimeout)
+
+	for {
+		out, err := runCommand()
+		if err == nil {
+			return out, nil
+		}
+		lock, isLocked := ParseStateLockError(out + "\n" + err.Error())
+		if !isLocked {
+			return out, err
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			return out, StateLocked{Lock: *lock, Underlying: err}
+		}
+		options.Logger.Logf(t, "State %s is locked by %s (lock ID %s, operation %s, created %s). Waiting %s for the lock to be released.", lock.Path, lock.Who, lock.ID, lock.Operation, lock.Created, interval)
+		time.Sleep(interval)
+	}
+}
+
+// ForceUnlock runs terraform force-unlock to release the state lock with the given ID (e.g., from StateLockInfo) and
+// returns stdout/stderr. This will fail the test if there is an error.
+func ForceUnlock(t testing.TestingT, options *Options, lockID string) string {
+	out, err := ForceUnlockE(t, options, lockID)
+	require.NoError(t, err)
+	return out
+}
+
+// ForceUnlockE runs terraform force-unlock to release the state lock with the given ID (e.g., from StateLockInfo) and
+// returns stdout/stderr. This is useful in cleanup paths, to release a lock left behind by a test that was interrupted.
+func ForceUnlockE(t testing.TestingT, options *Options, lockID string) (string, error) {
+	return RunTerraformCommandE(t, options, "force-unlock", "-force", lockID)
+}
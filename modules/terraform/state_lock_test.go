@@ -0,0 +1,107 @@
+package terraform
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stateLockErrorOutput = `
+╷
+│ Error: Error acquiring the state lock
+│
+│ Error message: ConditionalCheckFailedException: The conditional request failed
+│ Lock Info:
+│   ID:        2a5f3c4e-1b6d-7e8f-9a0b-c1d2e3f4a5b6
+│   Path:      terratest-state/terraform.tfstate
+│   Operation: OperationTypeApply
+│   Who:       ci@runner-1
+│   Version:   1.4.6
+│   Created:   2023-05-01 12:00:00.000000 +0000 UTC
+│   Info:
+│
+╵
+`
+
+func TestParseStateLockError(t *testing.T) {
+	t.Parallel()
+
+	lock, isLocked := ParseStateLockError(stateLockErrorOutput)
+	require.True(t, isLocked)
+	assert.Equal(t, StateLockInfo{
+		ID:        "2a5f3c4e-1b6d-7e8f-9a0b-c1d2e3f4a5b6",
+		Path:      "terratest-state/terraform.tfstate",
+		Operation: "OperationTypeApply",
+		Who:       "ci@runner-1",
+		Version:   "1.4.6",
+		Created:   "2023-05-01 12:00:00.000000 +0000 UTC",
+		Info:      "",
+	}, *lock)
+
+	_, isLocked = ParseStateLockError("Error: Invalid reference")
+	assert.False(t, isLocked)
+}
+
+func TestRunWithStateLockWait(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	options := &Options{StateLockWaitTimeout: time.Second, StateLockWaitInterval: 10 * time.Millisecond}
+	out, err := runWithStateLockWait(t, options, func() (string, error) {
+		attempts++
+		if attempts < 3 {
+			return stateLockErrorOutput, errors.New("exit status 1")
+		}
+		return "Apply complete!", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Apply complete!", out)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRunWithStateLockWaitTimesOut(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	options := &Options{}
+	_, err := runWithStateLockWait(t, options, func() (string, error) {
+		attempts++
+		return stateLockErrorOutput, errors.New("exit status 1")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts)
+
+	stateLocked, isStateLocked := err.(StateLocked)
+	require.True(t, isStateLocked)
+	assert.Equal(t, "ci@runner-1", stateLocked.Lock.Who)
+
+	// Other errors are returned as is.
+	otherErr := errors.New("exit status 1")
+	_, err = runWithStateLockWait(t, options, func() (string, error) { return "Error: Invalid reference", otherErr })
+	assert.Equal(t, otherErr, err)
+}
+
+func TestGetExitCodeForTerraformCommandEWaitsForStateLock(t *testing.T) {
+	t.Parallel()
+
+	// A fake terraform that fails on the state lock on the first run, and reports changes on the next one.
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "terraform")
+	script := "#!/bin/sh\nif [ ! -f " + filepath.Join(dir, "locked") + " ]; then\n  touch " + filepath.Join(dir, "locked") + "\n  printf '%s' '" + stateLockErrorOutput + "' >&2\n  exit 1\nfi\nexit 2\n"
+	require.NoError(t, ioutil.WriteFile(binary, []byte(script), 0755))
+
+	options := &Options{
+		TerraformDir:          dir,
+		TerraformBinary:       binary,
+		StateLockWaitTimeout:  time.Second,
+		StateLockWaitInterval: 10 * time.Millisecond,
+	}
+	exitCode, err := GetExitCodeForTerraformCommandE(t, options, "plan", "-detailed-exitcode")
+	require.NoError(t, err)
+	assert.Equal(t, TerraformPlanChangesPresentExitCode, exitCode)
+}
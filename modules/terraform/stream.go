@@ -0,0 +1,186 @@
+package terraform
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// isStreamingOutput returns true if the options require the output of terraform commands to be streamed as it is
+// written, rather than only returned once the command is done.
+func isStreamingOutput(options *Options) bool {
+	return options.OutputLineCallback != nil || options.OutputStallTimeout > 0
+}
+
+// runCommandAndGetOutputE runs the given terraform command and returns its stdout and stderr, or only its stdout if
+// stdoutOnly is set. If the options configure output streaming, each line is passed to the OutputLineCallback as soon
+// as it is written, and the command is killed if it doesn't write anything for OutputStallTimeout.
+func runCommandAndGetOutputE(t testing.TestingT, options *Options, cmd shell.Command, stdoutOnly bool) (string, error) {
+	if !isStreamingOutput(options) {
+		if stdoutOnly {
+			return shell.RunCommandAndGetStdOutE(t, cmd)
+		}
+		return shell.RunCommandAndGetOutputE(t, cmd)
+	}
+	return runStreamingCommandE(t, options, cmd, stdoutOnly)
+}
+
+// streamedOutput collects the output of a command as it is streamed.
+type streamedOutput struct {
+	mutex        sync.Mutex
+	combined     []string
+	stdout       []string
+	stderr       []string
+	lastOutputAt time.Time
+}
+
+// runStreamingCommandE runs the given command like the shell package does, but passes each line of output to the
+// OutputLineCallback of the options as it is written, and kills the command if it stalls for longer than the
+// OutputStallTimeout of the options.
+func runStreamingCommandE(t testing.TestingT, options *Options, command shell.Command, stdoutOnly bool) (string, error) {
+	command.Logger.Logf(t, "Running command %s with args %s", command.Command, command.Args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command.Command, command.Args...)
+	cmd.Dir = command.WorkingDir
+	cmd.Stdin = os.Stdin
+	cmd.Env = os.Environ()
+	for key, value := range command.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	out := &streamedOutput{lastOutputAt: time.Now()}
+	stalled := false
+	done := make(chan struct{})
+	if options.OutputStallTimeout > 0 {
+		go func() {
+			ticker := time.NewTicker(stallCheckInterval(options.OutputStallTimeout))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					out.mutex.Lock()
+					if time.Since(out.lastOutputAt) >= options.OutputStallTimeout {
+						stalled = true
+						out.mutex.Unlock()
+						command.Logger.Logf(t, "%s has not written any output for %s. Killing it.", command.Command, options.OutputStallTimeout)
+						cancel()
+						// Processes started by the command (e.g., providers) may keep the pipes open after it is killed.
+						stdout.Close()
+						stderr.Close()
+						return
+					}
+					out.mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	var stdoutErr, stderrErr error
+	go func() {
+		defer wg.Done()
+		stdoutErr = readStreamedLines(t, options, command, stdout, out, &out.stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		stderrErr = readStreamedLines(t, options, command, stderr, out, &out.stderr)
+	}()
+	wg.Wait()
+	err = cmd.Wait()
+	close(done)
+
+	out.mutex.Lock()
+	defer out.mutex.Unlock()
+	result := strings.Join(out.combined, "\n")
+	if stdoutOnly {
+		result = strings.Join(out.stdout, "\n")
+	}
+
+	if stalled {
+		return result, OutputStalled{Timeout: options.OutputStallTimeout}
+	}
+	if stdoutErr != nil {
+		return result, stdoutErr
+	}
+	if stderrErr != nil {
+		return result, stderrErr
+	}
+	if err != nil {
+		return result, StreamedCommandError{Underlying: err, Stderr: strings.Join(out.stderr, "\n")}
+	}
+	return result, nil
+}
+
+// readStreamedLines reads the lines from the given reader, logging each one, passing it to the OutputLineCallback of
+// the options, and recording it in the given stream of the output.
+func readStreamedLines(t testing.TestingT, options *Options, command shell.Command, reader io.Reader, out *streamedOutput, stream *[]string) error {
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		line, readErr := bufferedReader.ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 && readErr != nil {
+			if readErr == io.EOF {
+				return nil
+			}
+			return readErr
+		}
+
+		command.Logger.Logf(t, "%s", line)
+
+		// Hold the lock while calling the callback, so that callbacks never run concurrently.
+		out.mutex.Lock()
+		out.lastOutputAt = time.Now()
+		out.combined = append(out.combined, line)
+		*stream = append(*stream, line)
+		if options.OutputLineCallback != nil {
+			options.OutputLineCallback(line)
+		}
+		out.mutex.Unlock()
+
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// stallCheckInterval returns how often to check if a command has stalled for the given timeout.
+func stallCheckInterval(timeout time.Duration) time.Duration {
+	interval := timeout / 10
+	if interval < 10*time.Millisecond {
+		return 10 * time.Millisecond
+	}
+	if interval > time.Second {
+		return time.Second
+	}
+	return interval
+}
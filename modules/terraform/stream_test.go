@@ -0,0 +1,53 @@
+package terraform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStreamingCommand(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{}
+	options := &Options{OutputLineCallback: func(line string) { lines = append(lines, line) }}
+	cmd := shell.Command{Command: "sh", Args: []string{"-c", "echo creating; sleep 0.1; echo created >&2; echo done"}}
+
+	out, err := runStreamingCommandE(t, options, cmd, false)
+	require.NoError(t, err)
+	assert.Equal(t, "creating\ncreated\ndone", out)
+	assert.Equal(t, []string{"creating", "created", "done"}, lines)
+
+	stdout, err := runStreamingCommandE(t, options, cmd, true)
+	require.NoError(t, err)
+	assert.Equal(t, "creating\ndone", stdout)
+}
+
+func TestRunStreamingCommandFails(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{TerraformBinary: "sh", OutputLineCallback: func(line string) {}}
+	exitCode, err := GetExitCodeForTerraformCommandE(t, options, "-c", "echo 'Error: boom' >&2; exit 2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, exitCode)
+
+	_, err = RunTerraformCommandE(t, options, "-c", "echo 'Error: boom' >&2; exit 1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Error: boom")
+}
+
+func TestRunStreamingCommandStalls(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{OutputStallTimeout: 200 * time.Millisecond}
+	cmd := shell.Command{Command: "sh", Args: []string{"-c", "echo started; sleep 10; echo never"}}
+
+	start := time.Now()
+	out, err := runStreamingCommandE(t, options, cmd, false)
+	assert.Equal(t, OutputStalled{Timeout: 200 * time.Millisecond}, err)
+	assert.Equal(t, "started", out)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
@@ -0,0 +1,449 @@
+package terraform
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// DefaultTerragruntConfigFileName is the name of the terragrunt configuration file.
+const DefaultTerragruntConfigFileName = "terragrunt.hcl"
+
+// TerragruntConfig is the parsed content of a terragrunt.hcl file.
+type TerragruntConfig struct {
+	// The path of the parsed file.
+	Path string
+
+	// The source of the terraform module, from the terraform block.
+	TerraformSource string
+
+	// The include blocks of the configuration.
+	Includes []TerragruntInclude
+
+	// The dependency blocks of the configuration.
+	Dependencies []TerragruntDependency
+
+	// The paths of the dependencies block of the configuration.
+	DependencyPaths []string
+
+	// The remote_state block of the configuration, or nil if there is none.
+	RemoteState *TerragruntRemoteState
+
+	// The locals of the configuration that could be evaluated.
+	Locals map[string]interface{}
+
+	// The inputs of the configuration that could be evaluated, merged on top of the inputs of the included
+	// configurations.
+	Inputs map[string]interface{}
+
+	// The inputs that could not be evaluated (e.g., because they call a terragrunt function that is not supported, or
+	// refer to the outputs of a dependency that has no mock_outputs), mapped to the source of their expression.
+	UnresolvedInputs map[string]string
+}
+
+// TerragruntInclude is an include block of a terragrunt configuration.
+type TerragruntInclude struct {
+	Name string // The label of the block. Empty for an unlabeled include block.
+	Path string // The path of the included configuration, if it could be evaluated
+}
+
+// TerragruntDependency is a dependency block of a terragrunt configuration.
+type TerragruntDependency struct {
+	Name        string                 // The label of the block
+	ConfigPath  string                 // The path to the configuration of the dependency
+	MockOutputs map[string]interface{} // The mock_outputs of the dependency, if set
+}
+
+// TerragruntRemoteState is the remote_state block of a terragrunt configuration.
+type TerragruntRemoteState struct {
+	Backend string                 // The backend type (e.g., s3)
+	Config  map[string]interface{} // The config of the backend that could be evaluated
+}
+
+// TerraformVars returns the inputs of the configuration, which can be used as the Vars of Options to run terraform
+// directly on the underlying module (see TerraformSourceDir).
+func (config *TerragruntConfig) TerraformVars() map[string]interface{} {
+	vars := map[string]interface{}{}
+	for name, value := range config.Inputs {
+		vars[name] = value
+	}
+	return vars
+}
+
+// TerraformSourceDir returns the local directory of the underlying terraform module, and true, if the source in the
+// terraform block is a local path. A double slash in the source, which terragrunt uses to separate the root of the
+// code that gets copied from the path of the module within it, is resolved as a regular path separator.
+func (config *TerragruntConfig) TerraformSourceDir() (string, bool) {
+	source := config.TerraformSource
+	if source == "" || strings.Contains(source, "::") || strings.Contains(source, "://") {
+		return "", false
+	}
+	if !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "/") {
+		return "", false
+	}
+	source = strings.Replace(source, "//", "/", 1)
+	if filepath.IsAbs(source) {
+		return filepath.Clean(source), true
+	}
+	return filepath.Join(filepath.Dir(config.Path), source), true
+}
+
+// ParseTerragruntConfig parses the terragrunt configuration at the given path (either a terragrunt.hcl file, or a
+// folder that contains one). This will fail the test if the configuration can't be parsed.
+func ParseTerragruntConfig(t testing.TestingT, path string) *TerragruntConfig {
+	config, err := ParseTerragruntConfigE(t, path)
+	require.NoError(t, err)
+	return config
+}
+
+// ParseTerragruntConfigE parses the terragrunt configuration at the given path (either a terragrunt.hcl file, or a
+// folder that contains one). Expressions are evaluated with support for the locals of the configuration, the
+// mock_outputs of dependencies, the get_env, get_terragrunt_dir, find_in_parent_folders, and path_relative_to_include
+// terragrunt functions, and the common terraform string and collection functions. Expressions that can't be evaluated
+// are ignored, except for inputs, which are reported in UnresolvedInputs. The inputs of included configurations are
+// merged into Inputs, but the rest of their configuration is not.
+func ParseTerragruntConfigE(t testing.TestingT, path string) (*TerragruntConfig, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, DefaultTerragruntConfigFileName)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseTerragruntConfigFile(path, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := map[string]interface{}{}
+	for _, include := range config.Includes {
+		if include.Path == "" {
+			continue
+		}
+		// Included configurations are evaluated in the context of the including configuration, like terragrunt does.
+		includedConfig, err := parseTerragruntConfigFile(include.Path, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range includedConfig.Inputs {
+			inputs[name] = value
+		}
+	}
+	for name, value := range config.Inputs {
+		inputs[name] = value
+	}
+	config.Inputs = inputs
+	return config, nil
+}
+
+// parseTerragruntConfigFile parses the terragrunt configuration file at path, evaluating the terragrunt functions as if
+// the configuration is in terragruntDir.
+func parseTerragruntConfigFile(path string, terragruntDir string) (*TerragruntConfig, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	config := &TerragruntConfig{
+		Path:             path,
+		Locals:           map[string]interface{}{},
+		Inputs:           map[string]interface{}{},
+		UnresolvedInputs: map[string]string{},
+	}
+	evalContext := &hcl.EvalContext{
+		Variables: map[string]cty.Value{},
+		Functions: getTerragruntFunctions(terragruntDir, path),
+	}
+
+	// Locals can refer to each other, so keep evaluating them until no more can be resolved.
+	locals := map[string]cty.Value{}
+	for _, block := range body.Blocks {
+		if block.Type != "locals" {
+			continue
+		}
+		pending := block.Body.Attributes
+		for len(pending) > 0 {
+			unresolved := map[string]*hclsyntax.Attribute{}
+			for name, attr := range pending {
+				evalContext.Variables["local"] = cty.ObjectVal(locals)
+				if value, diags := attr.Expr.Value(evalContext); !diags.HasErrors() {
+					locals[name] = value
+				} else {
+					unresolved[name] = attr
+				}
+			}
+			if len(unresolved) == len(pending) {
+				break
+			}
+			pending = unresolved
+		}
+	}
+	evalContext.Variables["local"] = cty.ObjectVal(locals)
+	for name, value := range locals {
+		if goValue, err := ctyValueToGo(value); err == nil {
+			config.Locals[name] = goValue
+		}
+	}
+
+	dependencyOutputs := map[string]cty.Value{}
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "terraform":
+			if source, isSet := evalStringAttribute(block.Body, "source", evalContext); isSet {
+				config.TerraformSource = source
+			}
+		case "include":
+			include := TerragruntInclude{}
+			if len(block.Labels) > 0 {
+				include.Name = block.Labels[0]
+			}
+			if includePath, isSet := evalStringAttribute(block.Body, "path", evalContext); isSet {
+				if !filepath.IsAbs(includePath) {
+					includePath = filepath.Join(filepath.Dir(path), includePath)
+				}
+				include.Path = includePath
+			}
+			config.Includes = append(config.Includes, include)
+		case "dependency":
+			dependency := TerragruntDependency{}
+			if len(block.Labels) > 0 {
+				dependency.Name = block.Labels[0]
+			}
+			dependency.ConfigPath, _ = evalStringAttribute(block.Body, "config_path", evalContext)
+			if attr, hasMocks := block.Body.Attributes["mock_outputs"]; hasMocks {
+				if value, diags := attr.Expr.Value(evalContext); !diags.HasErrors() {
+					dependencyOutputs[dependency.Name] = cty.ObjectVal(map[string]cty.Value{"outputs": value})
+					if goValue, err := ctyValueToGo(value); err == nil {
+						dependency.MockOutputs, _ = goValue.(map[string]interface{})
+					}
+				}
+			}
+			config.Dependencies = append(config.Dependencies, dependency)
+		case "dependencies":
+			if attr, hasPaths := block.Body.Attributes["paths"]; hasPaths {
+				if value, diags := attr.Expr.Value(evalContext); !diags.HasErrors() {
+					if goValue, err := ctyValueToGo(value); err == nil {
+						config.DependencyPaths = append(config.DependencyPaths, toStringList(goValue)...)
+					}
+				}
+			}
+		}
+	}
+	evalContext.Variables["dependency"] = cty.ObjectVal(dependencyOutputs)
+
+	// remote_state is usually an attribute in older configurations, and a block in newer ones.
+	if attr, hasRemoteState := body.Attributes["remote_state"]; hasRemoteState {
+		config.RemoteState = parseTerragruntRemoteState(evalObjectItems(attr.Expr, evalContext, src))
+	}
+	for _, block := range body.Blocks {
+		if block.Type == "remote_state" {
+			items := map[string]objectItem{}
+			for name, attr := range block.Body.Attributes {
+				items[name] = evalObjectItem(attr.Expr, evalContext, src)
+			}
+			config.RemoteState = parseTerragruntRemoteState(items)
+		}
+	}
+
+	if attr, hasInputs := body.Attributes["inputs"]; hasInputs {
+		for name, item := range evalObjectItems(attr.Expr, evalContext, src) {
+			if item.resolved {
+				config.Inputs[name] = item.value
+			} else {
+				config.UnresolvedInputs[name] = item.source
+			}
+		}
+	}
+	return config, nil
+}
+
+// objectItem is the result of evaluating the value of an item of an object.
+type objectItem struct {
+	value    interface{}
+	resolved bool
+	source   string
+}
+
+// evalObjectItems evaluates each item of the given object expression separately, so that items that can't be evaluated
+// don't prevent the others from being evaluated.
+func evalObjectItems(expr hclsyntax.Expression, evalContext *hcl.EvalContext, src []byte) map[string]objectItem {
+	items := map[string]objectItem{}
+
+	objectExpr, isObject := expr.(*hclsyntax.ObjectConsExpr)
+	if !isObject {
+		// The object may come from a function call such as merge(), in which case it is all or nothing.
+		item := evalObjectItem(expr, evalContext, src)
+		if values, isMap := item.value.(map[string]interface{}); item.resolved && isMap {
+			for name, value := range values {
+				items[name] = objectItem{value: value, resolved: true}
+			}
+		}
+		return items
+	}
+
+	for _, objectItemExpr := range objectExpr.Items {
+		key, diags := objectItemExpr.KeyExpr.Value(evalContext)
+		if diags.HasErrors() || key.Type() != cty.String || !key.IsKnown() || key.IsNull() {
+			continue
+		}
+		items[key.AsString()] = evalObjectItem(objectItemExpr.ValueExpr, evalContext, src)
+	}
+	return items
+}
+
+// evalObjectItem evaluates the given expression, recording its source if it can't be evaluated.
+func evalObjectItem(expr hclsyntax.Expression, evalContext *hcl.EvalContext, src []byte) objectItem {
+	unresolved := objectItem{source: string(expr.Range().SliceBytes(src))}
+	value, diags := expr.Value(evalContext)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return unresolved
+	}
+	goValue, err := ctyValueToGo(value)
+	if err != nil {
+		return unresolved
+	}
+	return objectItem{value: goValue, resolved: true}
+}
+
+func parseTerragruntRemoteState(items map[string]objectItem) *TerragruntRemoteState {
+	remoteState := &TerragruntRemoteState{Config: map[string]interface{}{}}
+	if backend, isString := items["backend"].value.(string); isString {
+		remoteState.Backend = backend
+	}
+	if backendConfig, isMap := items["config"].value.(map[string]interface{}); isMap {
+		remoteState.Config = backendConfig
+	}
+	return remoteState
+}
+
+// evalStringAttribute evaluates the attribute with the given name of the body, returning its value and true if it is
+// set to a string that can be evaluated.
+func evalStringAttribute(body *hclsyntax.Body, name string, evalContext *hcl.EvalContext) (string, bool) {
+	attr, isSet := body.Attributes[name]
+	if !isSet {
+		return "", false
+	}
+	value, diags := attr.Expr.Value(evalContext)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// ctyValueToGo converts the given cty value to the equivalent go value, as decoded from json.
+func ctyValueToGo(value cty.Value) (interface{}, error) {
+	jsonBytes, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return nil, err
+	}
+	var goValue interface{}
+	if err := json.Unmarshal(jsonBytes, &goValue); err != nil {
+		return nil, err
+	}
+	return goValue, nil
+}
+
+func toStringList(value interface{}) []string {
+	list, isList := value.([]interface{})
+	if !isList {
+		return nil
+	}
+	strs := []string{}
+	for _, item := range list {
+		if str, isString := item.(string); isString {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+// getTerragruntFunctions returns the functions available when evaluating the terragrunt configuration at configPath.
+func getTerragruntFunctions(terragruntDir string, configPath string) map[string]function.Function {
+	return map[string]function.Function{
+		"get_env": function.New(&function.Spec{
+			Params:   []function.Parameter{{Name: "name", Type: cty.String}},
+			VarParam: &function.Parameter{Name: "default", Type: cty.String},
+			Type:     function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				if value, isSet := os.LookupEnv(args[0].AsString()); isSet {
+					return cty.StringVal(value), nil
+				}
+				if len(args) > 1 {
+					return args[1], nil
+				}
+				return cty.StringVal(""), nil
+			},
+		}),
+		"get_terragrunt_dir": function.New(&function.Spec{
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				return cty.StringVal(terragruntDir), nil
+			},
+		}),
+		"path_relative_to_include": function.New(&function.Spec{
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				relPath, err := filepath.Rel(filepath.Dir(configPath), terragruntDir)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				return cty.StringVal(filepath.ToSlash(relPath)), nil
+			},
+		}),
+		"find_in_parent_folders": function.New(&function.Spec{
+			VarParam: &function.Parameter{Name: "name", Type: cty.String},
+			Type:     function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				name := DefaultTerragruntConfigFileName
+				if len(args) > 0 {
+					name = args[0].AsString()
+				}
+				for dir := filepath.Dir(terragruntDir); ; dir = filepath.Dir(dir) {
+					candidate := filepath.Join(dir, name)
+					if _, err := os.Stat(candidate); err == nil {
+						return cty.StringVal(candidate), nil
+					}
+					if dir == filepath.Dir(dir) {
+						break
+					}
+				}
+				if len(args) > 1 {
+					return args[1], nil
+				}
+				return cty.NilVal, os.ErrNotExist
+			},
+		}),
+		"concat":     stdlib.ConcatFunc,
+		"format":     stdlib.FormatFunc,
+		"join":       stdlib.JoinFunc,
+		"jsonencode": stdlib.JSONEncodeFunc,
+		"lower":      stdlib.LowerFunc,
+		"merge":      stdlib.MergeFunc,
+		"replace":    stdlib.ReplaceFunc,
+		"split":      stdlib.SplitFunc,
+		"tolist":     stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType)),
+		"tomap":      stdlib.MakeToFunc(cty.Map(cty.DynamicPseudoType)),
+		"upper":      stdlib.UpperFunc,
+	}
+}
@@ -0,0 +1,126 @@
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const terragruntRootConfig = `
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "terratest-state"
+    key    = "${path_relative_to_include()}/terraform.tfstate"
+  }
+}
+
+inputs = {
+  region      = "us-east-1"
+  environment = "test"
+}
+`
+
+const terragruntChildConfig = `
+include "root" {
+  path = find_in_parent_folders()
+}
+
+locals {
+  full_name = "${local.prefix}-app"
+  prefix    = "terratest"
+}
+
+terraform {
+  source = "../../modules//app"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+
+  mock_outputs = {
+    vpc_id = "vpc-123456"
+  }
+}
+
+dependency "db" {
+  config_path = "../db"
+}
+
+dependencies {
+  paths = ["../vpc", "../db"]
+}
+
+inputs = {
+  name        = local.full_name
+  environment = upper("test")
+  vpc_id      = dependency.vpc.outputs.vpc_id
+  db_endpoint = dependency.db.outputs.endpoint
+  tags        = { Owner = "terratest" }
+}
+`
+
+func TestParseTerragruntConfig(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "terratest-terragrunt-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	childDir := filepath.Join(rootDir, "live", "app")
+	require.NoError(t, os.MkdirAll(childDir, os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootDir, "terragrunt.hcl"), []byte(terragruntRootConfig), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(childDir, "terragrunt.hcl"), []byte(terragruntChildConfig), 0644))
+
+	config := ParseTerragruntConfig(t, childDir)
+
+	assert.Equal(t, filepath.Join(childDir, "terragrunt.hcl"), config.Path)
+	assert.Equal(t, "../../modules//app", config.TerraformSource)
+	sourceDir, isLocal := config.TerraformSourceDir()
+	assert.True(t, isLocal)
+	assert.Equal(t, filepath.Join(rootDir, "modules", "app"), sourceDir)
+
+	assert.Equal(t, []TerragruntInclude{{Name: "root", Path: filepath.Join(rootDir, "terragrunt.hcl")}}, config.Includes)
+	assert.Equal(t, []TerragruntDependency{
+		{Name: "vpc", ConfigPath: "../vpc", MockOutputs: map[string]interface{}{"vpc_id": "vpc-123456"}},
+		{Name: "db", ConfigPath: "../db"},
+	}, config.Dependencies)
+	assert.Equal(t, []string{"../vpc", "../db"}, config.DependencyPaths)
+	assert.Equal(t, map[string]interface{}{"prefix": "terratest", "full_name": "terratest-app"}, config.Locals)
+
+	assert.Equal(t, map[string]interface{}{
+		"region":      "us-east-1",
+		"environment": "TEST",
+		"name":        "terratest-app",
+		"vpc_id":      "vpc-123456",
+		"tags":        map[string]interface{}{"Owner": "terratest"},
+	}, config.TerraformVars())
+	assert.Equal(t, map[string]string{"db_endpoint": "dependency.db.outputs.endpoint"}, config.UnresolvedInputs)
+
+	// The remote_state of included configurations is not merged.
+	assert.Nil(t, config.RemoteState)
+
+	rootConfig := ParseTerragruntConfig(t, filepath.Join(rootDir, "terragrunt.hcl"))
+	require.NotNil(t, rootConfig.RemoteState)
+	assert.Equal(t, "s3", rootConfig.RemoteState.Backend)
+	assert.Equal(t, map[string]interface{}{"bucket": "terratest-state", "key": "./terraform.tfstate"}, rootConfig.RemoteState.Config)
+}
+
+func TestTerraformSourceDirRemote(t *testing.T) {
+	t.Parallel()
+
+	for _, source := range []string{
+		"",
+		"git::git@github.com:acme/modules.git//vpc?ref=v1.0.0",
+		"tfr:///terraform-aws-modules/vpc/aws?version=3.5.0",
+		"github.com/acme/modules//vpc",
+	} {
+		config := &TerragruntConfig{Path: "/live/app/terragrunt.hcl", TerraformSource: source}
+		_, isLocal := config.TerraformSourceDir()
+		assert.False(t, isLocal, source)
+	}
+}
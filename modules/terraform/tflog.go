@@ -0,0 +1,215 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultTerraformLogLinesOnError is the number of captured log lines attached to the error of a failed command when
+// CaptureTerraformLogs is set and TerraformLogLinesOnError is not.
+const DefaultTerraformLogLinesOnError = 20
+
+// TerraformLogRecord is a single record of the json logs terraform writes when run with TF_LOG=json.
+type TerraformLogRecord struct {
+	Level     string `json:"@level"`
+	Message   string `json:"@message"`
+	Module    string `json:"@module"`
+	Timestamp string `json:"@timestamp"`
+
+	// All the fields of the record, including the ones above.
+	Fields map[string]interface{} `json:"-"`
+}
+
+// IsFromProvider returns true if the record was logged by a provider plugin, as opposed to terraform core.
+func (record TerraformLogRecord) IsFromProvider() bool {
+	return strings.HasPrefix(record.Module, "provider")
+}
+
+// IsError returns true if the record was logged at the error level.
+func (record TerraformLogRecord) IsError() bool {
+	return record.Level == "error"
+}
+
+// configureTerraformLogCapture sets up the environment so that terraform writes json logs to
+// options.TerraformLogPath, creating a temporary log file on first use.
+func configureTerraformLogCapture(options *Options) error {
+	if options.TerraformLogPath == "" {
+		logFile, err := ioutil.TempFile("", "terratest-terraform-log-")
+		if err != nil {
+			return err
+		}
+		if err := logFile.Close(); err != nil {
+			return err
+		}
+		options.TerraformLogPath = logFile.Name()
+	}
+
+	// Initialize EnvVars, if it hasn't been set yet
+	if options.EnvVars == nil {
+		options.EnvVars = map[string]string{}
+	}
+	options.EnvVars["TF_LOG"] = "json"
+	options.EnvVars["TF_LOG_PATH"] = options.TerraformLogPath
+	return nil
+}
+
+// runWithTerraformLogCapture runs the given terraform command. If log capture is enabled on the options and the command
+// fails, the last lines terraform logged while running the command are attached to the returned error.
+func runWithTerraformLogCapture(options *Options, runCommand func() (string, error)) (string, error) {
+	if !options.CaptureTerraformLogs {
+		return runCommand()
+	}
+
+	// TF_LOG_PATH is appended to, so remember where the logs for this command start.
+	var offset int64
+	if info, err := os.Stat(options.TerraformLogPath); err == nil {
+		offset = info.Size()
+	}
+
+	out, err := runCommand()
+	if err == nil {
+		return out, nil
+	}
+
+	numLines := options.TerraformLogLinesOnError
+	if numLines == 0 {
+		numLines = DefaultTerraformLogLinesOnError
+	}
+	lines, readErr := readTerraformLogTail(options.TerraformLogPath, offset, numLines)
+	if readErr != nil || len(lines) == 0 {
+		return out, err
+	}
+	return out, ErrWithTerraformLogs{Underlying: err, LogLines: lines}
+}
+
+// readTerraformLogTail returns the last numLines lines of the log file at path, starting from the given offset.
+func readTerraformLogTail(path string, offset int64, numLines int) ([]string, error) {
+	logFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+
+	if _, err := logFile.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(logFile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > numLines {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// GetTerraformLogRecords returns all the log records terraform wrote while running commands with the given options.
+// This requires CaptureTerraformLogs to be set on the options. This will fail the test if the logs can't be read.
+func GetTerraformLogRecords(t testing.TestingT, options *Options) []TerraformLogRecord {
+	records, err := GetTerraformLogRecordsE(t, options)
+	require.NoError(t, err)
+	return records
+}
+
+// GetTerraformLogRecordsE returns all the log records terraform wrote while running commands with the given options.
+// This requires CaptureTerraformLogs to be set on the options.
+func GetTerraformLogRecordsE(t testing.TestingT, options *Options) ([]TerraformLogRecord, error) {
+	if !options.CaptureTerraformLogs || options.TerraformLogPath == "" {
+		return nil, TerraformLogsNotCaptured{}
+	}
+
+	logFile, err := os.Open(options.TerraformLogPath)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+	return parseTerraformLogRecords(logFile)
+}
+
+// parseTerraformLogRecords parses line delimited json log records. Lines that aren't json (such as panic output) are
+// skipped.
+func parseTerraformLogRecords(reader io.Reader) ([]TerraformLogRecord, error) {
+	records := []TerraformLogRecord{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		record := TerraformLogRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		if err := json.Unmarshal(line, &record.Fields); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// CountTerraformLogRecordsMatching returns the number of captured log records whose message matches the given regular
+// expression (e.g., to count how many times a provider retried an API call). This will fail the test if the logs
+// can't be read or the regular expression is invalid.
+func CountTerraformLogRecordsMatching(t testing.TestingT, options *Options, messageRegexp string) int {
+	count, err := CountTerraformLogRecordsMatchingE(t, options, messageRegexp)
+	require.NoError(t, err)
+	return count
+}
+
+// CountTerraformLogRecordsMatchingE returns the number of captured log records whose message matches the given regular
+// expression (e.g., to count how many times a provider retried an API call).
+func CountTerraformLogRecordsMatchingE(t testing.TestingT, options *Options, messageRegexp string) (int, error) {
+	re, err := regexp.Compile(messageRegexp)
+	if err != nil {
+		return 0, err
+	}
+	records, err := GetTerraformLogRecordsE(t, options)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, record := range records {
+		if re.MatchString(record.Message) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RequireNoProviderErrors fails the test if any provider logged a record at the error level while running commands with
+// the given options. This catches errors that providers log but recover from, which never show up in stdout.
+func RequireNoProviderErrors(t testing.TestingT, options *Options) {
+	require.NoError(t, RequireNoProviderErrorsE(t, options))
+}
+
+// RequireNoProviderErrorsE returns a ProviderErrorsLogged error if any provider logged a record at the error level while
+// running commands with the given options.
+func RequireNoProviderErrorsE(t testing.TestingT, options *Options) error {
+	records, err := GetTerraformLogRecordsE(t, options)
+	if err != nil {
+		return err
+	}
+
+	providerErrors := ProviderErrorsLogged{}
+	for _, record := range records {
+		if record.IsFromProvider() && record.IsError() {
+			providerErrors.Messages = append(providerErrors.Messages, fmt.Sprintf("[%s] %s", record.Module, record.Message))
+		}
+	}
+	if len(providerErrors.Messages) > 0 {
+		return providerErrors
+	}
+	return nil
+}
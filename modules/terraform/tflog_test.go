@@ -0,0 +1,93 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTerraformLog = `{"@level":"info","@message":"Terraform version: 1.5.0","@module":"terraform","@timestamp":"2023-06-01T10:00:00.000000Z"}
+{"@level":"debug","@message":"[aws-sdk-go] DEBUG: Retrying Request ec2/DescribeInstances, attempt 1","@module":"provider.terraform-provider-aws_v5.0.0_x5","@timestamp":"2023-06-01T10:00:01.000000Z"}
+panic: this line is not json
+{"@level":"error","@message":"operation error EC2: DescribeInstances, throttled","@module":"provider.terraform-provider-aws_v5.0.0_x5","@timestamp":"2023-06-01T10:00:02.000000Z","tf_rpc":"ReadResource"}
+{"@level":"error","@message":"core error","@module":"terraform","@timestamp":"2023-06-01T10:00:03.000000Z"}
+`
+
+func TestParseTerraformLogRecords(t *testing.T) {
+	t.Parallel()
+
+	records, err := parseTerraformLogRecords(strings.NewReader(testTerraformLog))
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	assert.False(t, records[0].IsFromProvider())
+	assert.True(t, records[2].IsFromProvider())
+	assert.True(t, records[2].IsError())
+	assert.Equal(t, "ReadResource", records[2].Fields["tf_rpc"])
+}
+
+func TestTerraformLogHelpers(t *testing.T) {
+	t.Parallel()
+
+	logFile, err := ioutil.TempFile("", "terratest-terraform-log-test-")
+	require.NoError(t, err)
+	defer os.Remove(logFile.Name())
+	_, err = logFile.WriteString(testTerraformLog)
+	require.NoError(t, err)
+	require.NoError(t, logFile.Close())
+
+	options := &Options{CaptureTerraformLogs: true, TerraformLogPath: logFile.Name()}
+
+	assert.Equal(t, 1, CountTerraformLogRecordsMatching(t, options, `Retrying Request`))
+
+	err = RequireNoProviderErrorsE(t, options)
+	require.Error(t, err)
+	providerErrors, isProviderErrors := err.(ProviderErrorsLogged)
+	require.True(t, isProviderErrors)
+	assert.Len(t, providerErrors.Messages, 1)
+
+	_, err = GetTerraformLogRecordsE(t, &Options{})
+	assert.IsType(t, TerraformLogsNotCaptured{}, err)
+}
+
+func TestRunWithTerraformLogCaptureAttachesCommandLogs(t *testing.T) {
+	t.Parallel()
+
+	options := &Options{CaptureTerraformLogs: true, TerraformLogLinesOnError: 2}
+	require.NoError(t, configureTerraformLogCapture(options))
+	defer os.Remove(options.TerraformLogPath)
+	assert.Equal(t, "json", options.EnvVars["TF_LOG"])
+	assert.Equal(t, options.TerraformLogPath, options.EnvVars["TF_LOG_PATH"])
+
+	writeLog := func(lines ...string) {
+		logFile, err := os.OpenFile(options.TerraformLogPath, os.O_APPEND|os.O_WRONLY, 0600)
+		require.NoError(t, err)
+		defer logFile.Close()
+		for _, line := range lines {
+			fmt.Fprintln(logFile, line)
+		}
+	}
+
+	// Logs of earlier commands must not be attached.
+	_, err := runWithTerraformLogCapture(options, func() (string, error) {
+		writeLog("first command")
+		return "", nil
+	})
+	require.NoError(t, err)
+
+	_, err = runWithTerraformLogCapture(options, func() (string, error) {
+		writeLog("line 1", "line 2", "line 3")
+		return "", errors.New("command failed")
+	})
+	require.Error(t, err)
+	withLogs, hasLogs := err.(ErrWithTerraformLogs)
+	require.True(t, hasLogs)
+	assert.Equal(t, []string{"line 2", "line 3"}, withLogs.LogLines)
+	assert.EqualError(t, errors.Unwrap(err), "command failed")
+}
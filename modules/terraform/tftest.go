@@ -0,0 +1,169 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	go_test "testing"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// TerraformTestRunResult is the outcome of a single run block in a native terraform test file.
+type TerraformTestRunResult struct {
+	File        string   // The test file the run block is defined in (e.g., tests/main.tftest.hcl)
+	Run         string   // The name of the run block
+	Status      string   // One of pass, fail, error, skip, or pending
+	Diagnostics []string // The summary and detail of every diagnostic terraform reported for this run
+}
+
+// Passed returns true if the run block passed.
+func (result TerraformTestRunResult) Passed() bool {
+	return result.Status == "pass"
+}
+
+// TerraformTestResults is the outcome of running `terraform test`.
+type TerraformTestResults struct {
+	Runs    []TerraformTestRunResult
+	Status  string // The overall status reported by terraform (pass, fail, error, skip, or pending)
+	Passed  int
+	Failed  int
+	Errored int
+	Skipped int
+}
+
+// terraformTestJsonLine is a single line of the machine readable output of `terraform test -json`. Only the fields we
+// need are defined.
+type terraformTestJsonLine struct {
+	TestFile     string `json:"@testfile"`
+	TestRun      string `json:"@testrun"`
+	TestRunValue *struct {
+		Path   string `json:"path"`
+		Run    string `json:"run"`
+		Status string `json:"status"`
+	} `json:"test_run"`
+	TestSummary *struct {
+		Status  string `json:"status"`
+		Passed  int    `json:"passed"`
+		Failed  int    `json:"failed"`
+		Errored int    `json:"errored"`
+		Skipped int    `json:"skipped"`
+	} `json:"test_summary"`
+	Diagnostic *struct {
+		Summary string `json:"summary"`
+		Detail  string `json:"detail"`
+	} `json:"diagnostic"`
+}
+
+// RunTerraformTests runs `terraform test` with the given options and reports the result of each run block as a Go
+// subtest named <file>/<run>, so that native terraform tests show up in the same report as the Terratest tests. Returns
+// the parsed results. This will fail the test if the command could not be run or any of the run blocks did not pass.
+func RunTerraformTests(t *go_test.T, options *Options) *TerraformTestResults {
+	results, err := RunTerraformTestsE(t, options)
+	require.NoError(t, err)
+
+	for _, run := range results.Runs {
+		run := run
+		t.Run(fmt.Sprintf("%s/%s", run.File, run.Run), func(t *go_test.T) {
+			switch run.Status {
+			case "pass":
+			case "skip", "pending":
+				t.Skipf("terraform test run %s in %s was not executed (%s)", run.Run, run.File, run.Status)
+			default:
+				t.Errorf("terraform test run %s in %s finished with status %s:\n%s", run.Run, run.File, run.Status, strings.Join(run.Diagnostics, "\n"))
+			}
+		})
+	}
+	return results
+}
+
+// RunTerraformTestsE runs `terraform test` with the given options and parses the json output into per run block
+// results. Failing run blocks are not returned as an error: inspect the returned results for those. An error is only
+// returned if the command could not be run or its output could not be parsed.
+func RunTerraformTestsE(t testing.TestingT, options *Options) (*TerraformTestResults, error) {
+	// We manually construct the args here instead of using `FormatArgs`, because test does not accept -target or -lock.
+	args := []string{"test", "-json"}
+	if !options.PassVarsAsEnvVars {
+		args = append(args, FormatTerraformVarsAsArgs(options.Vars)...)
+	}
+	args = append(args, FormatTerraformArgs("-var-file", options.VarFiles)...)
+	if options.NoColor {
+		args = append(args, "-no-color")
+	}
+
+	out, cmdErr := RunTerraformCommandAndGetStdoutE(t, options, args...)
+	results, err := parseTerraformTestJson(out)
+	if err != nil {
+		if cmdErr != nil {
+			return nil, cmdErr
+		}
+		return nil, err
+	}
+
+	// terraform test exits non zero when any of the runs fail, which we report through the results. If we got no
+	// summary at all, the command failed for a different reason (e.g., invalid configuration).
+	if cmdErr != nil && results.Status == "" {
+		return results, cmdErr
+	}
+	return results, nil
+}
+
+// parseTerraformTestJson parses the line delimited json output of `terraform test -json`.
+func parseTerraformTestJson(out string) (*TerraformTestResults, error) {
+	results := &TerraformTestResults{}
+	runIndex := map[string]int{}
+	diagnostics := map[string][]string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		parsed := terraformTestJsonLine{}
+		if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case parsed.TestRunValue != nil:
+			key := parsed.TestRunValue.Path + "/" + parsed.TestRunValue.Run
+			idx, exists := runIndex[key]
+			if !exists {
+				idx = len(results.Runs)
+				runIndex[key] = idx
+				results.Runs = append(results.Runs, TerraformTestRunResult{File: parsed.TestRunValue.Path, Run: parsed.TestRunValue.Run})
+			}
+			if parsed.TestRunValue.Status != "" {
+				results.Runs[idx].Status = parsed.TestRunValue.Status
+			}
+		case parsed.TestSummary != nil:
+			results.Status = parsed.TestSummary.Status
+			results.Passed = parsed.TestSummary.Passed
+			results.Failed = parsed.TestSummary.Failed
+			results.Errored = parsed.TestSummary.Errored
+			results.Skipped = parsed.TestSummary.Skipped
+		case parsed.Diagnostic != nil && parsed.TestRun != "":
+			key := parsed.TestFile + "/" + parsed.TestRun
+			message := parsed.Diagnostic.Summary
+			if parsed.Diagnostic.Detail != "" {
+				message = fmt.Sprintf("%s: %s", message, parsed.Diagnostic.Detail)
+			}
+			diagnostics[key] = append(diagnostics[key], message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for key, messages := range diagnostics {
+		if idx, exists := runIndex[key]; exists {
+			results.Runs[idx].Diagnostics = messages
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,32 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTerraformTestJson(t *testing.T) {
+	t.Parallel()
+
+	out := `{"@level":"info","@message":"Terraform 1.6.0","type":"version","terraform":"1.6.0"}
+{"@level":"info","@message":"Found 1 file and 2 run blocks","type":"test_abstract","test_abstract":{"main.tftest.hcl":["setup","check"]}}
+{"@level":"info","@message":"  \"setup\"... pass","@testfile":"main.tftest.hcl","@testrun":"setup","type":"test_run","test_run":{"path":"main.tftest.hcl","run":"setup","progress":"complete","status":"pass"}}
+{"@level":"info","@message":"  \"check\"... in progress","@testfile":"main.tftest.hcl","@testrun":"check","type":"test_run","test_run":{"path":"main.tftest.hcl","run":"check","progress":"starting","elapsed":0}}
+{"@level":"error","@message":"Error: Test assertion failed","@testfile":"main.tftest.hcl","@testrun":"check","type":"diagnostic","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"count is wrong"}}
+{"@level":"info","@message":"  \"check\"... fail","@testfile":"main.tftest.hcl","@testrun":"check","type":"test_run","test_run":{"path":"main.tftest.hcl","run":"check","progress":"complete","status":"fail"}}
+{"@level":"info","@message":"Failure! 1 passed, 1 failed.","type":"test_summary","test_summary":{"status":"fail","passed":1,"failed":1,"errored":0,"skipped":0}}`
+
+	results, err := parseTerraformTestJson(out)
+	require.NoError(t, err)
+
+	assert.Equal(t, "fail", results.Status)
+	assert.Equal(t, 1, results.Passed)
+	assert.Equal(t, 1, results.Failed)
+	require.Len(t, results.Runs, 2)
+	assert.Equal(t, TerraformTestRunResult{File: "main.tftest.hcl", Run: "setup", Status: "pass"}, results.Runs[0])
+	assert.Equal(t, "fail", results.Runs[1].Status)
+	assert.False(t, results.Runs[1].Passed())
+	assert.Equal(t, []string{"Test assertion failed: count is wrong"}, results.Runs[1].Diagnostics)
+}
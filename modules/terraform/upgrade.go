@@ -0,0 +1,292 @@
+package terraform
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// TerraformReleasesURL is the base URL terraform binaries are downloaded from by the upgrade harness. Point this at a
+// mirror of releases.hashicorp.com to avoid downloading from the internet.
+var TerraformReleasesURL = "https://releases.hashicorp.com/terraform"
+
+// upgradeOverrideFileName is the override file the upgrade harness writes into the TerraformDir to pin provider
+// versions. Terraform merges override files on top of the rest of the configuration.
+const upgradeOverrideFileName = "terratest_upgrade_override.tf"
+
+// ProviderRequirement is an entry of the required_providers block of a terraform configuration.
+type ProviderRequirement struct {
+	Source  string // The source address of the provider (e.g., hashicorp/aws)
+	Version string // The version constraint for the provider (e.g., = 4.67.0)
+}
+
+// VersionStage describes the terraform and provider versions to use for one side of an upgrade test.
+type VersionStage struct {
+	// The version of terraform to use (e.g., 1.3.9). The binary is downloaded from TerraformReleasesURL and cached
+	// across tests. Ignored if TerraformBinary is set.
+	TerraformVersion string
+
+	// The path to the terraform binary to use. If neither this nor TerraformVersion is set, the TerraformBinary of the
+	// options is used.
+	TerraformBinary string
+
+	// The providers to pin, keyed by the local name of the provider (e.g., aws). These are written to an override file
+	// in the TerraformDir, which replaces the matching entries of the required_providers block of the module.
+	Providers map[string]ProviderRequirement
+
+	// The plugin dir to point init at for this stage (e.g., a mirror created with `terraform providers mirror`), to
+	// avoid downloading providers from the registry.
+	PluginDir string
+}
+
+// VersionUpgrade describes an upgrade test: the module is applied with the From versions, and then planned with the To
+// versions.
+type VersionUpgrade struct {
+	From VersionStage
+	To   VersionStage
+
+	// The addresses of the resources that are expected to change when upgrading. Any other change fails the test.
+	AllowedChanges []string
+}
+
+// ApplyAndPlanUpgrade runs terraform init and apply with the From versions of the upgrade, then runs init and plan with
+// the To versions, and checks that the plan contains no changes other than the allowed ones. This writes an override
+// file into the TerraformDir, so it should be run on a copy of the module (e.g., made with
+// files.CopyTerraformFolderToTemp). Returns the plan made with the To versions. This will fail the test if there is an
+// error in the commands or if the plan has unexpected changes.
+func ApplyAndPlanUpgrade(t testing.TestingT, options *Options, upgrade VersionUpgrade) *PlanStruct {
+	plan, err := ApplyAndPlanUpgradeE(t, options, upgrade)
+	require.NoError(t, err)
+	return plan
+}
+
+// ApplyAndPlanUpgradeE runs terraform init and apply with the From versions of the upgrade, then runs init and plan with
+// the To versions, and checks that the plan contains no changes other than the allowed ones. This writes an override
+// file into the TerraformDir, so it should be run on a copy of the module (e.g., made with
+// files.CopyTerraformFolderToTemp). Returns the plan made with the To versions, and an UnexpectedUpgradeChanges error if
+// the plan has unexpected changes. The resources are left in place, so callers should defer a Destroy with the returned
+// options of the To stage, which can be retrieved with GetUpgradeStageOptionsE.
+func ApplyAndPlanUpgradeE(t testing.TestingT, options *Options, upgrade VersionUpgrade) (*PlanStruct, error) {
+	defer os.Remove(filepath.Join(options.TerraformDir, upgradeOverrideFileName))
+
+	fromOptions, err := GetUpgradeStageOptionsE(t, options, upgrade.From)
+	if err != nil {
+		return nil, err
+	}
+	options.Logger.Logf(t, "Applying with the versions to upgrade from using %s", fromOptions.TerraformBinary)
+	if _, err := InitAndApplyE(t, fromOptions); err != nil {
+		return nil, err
+	}
+
+	toOptions, err := GetUpgradeStageOptionsE(t, options, upgrade.To)
+	if err != nil {
+		return nil, err
+	}
+	options.Logger.Logf(t, "Planning with the versions to upgrade to using %s", toOptions.TerraformBinary)
+	if _, err := InitE(t, toOptions); err != nil {
+		return nil, err
+	}
+	planOptions, err := planToTempFileE(t, toOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(planOptions.PlanFilePath)
+
+	plan, err := ShowWithStructE(t, planOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	unexpected := collections.ListSubtract(getChangedResourceAddresses(plan), upgrade.AllowedChanges)
+	if len(unexpected) > 0 {
+		return plan, UnexpectedUpgradeChanges{Addresses: unexpected}
+	}
+	return plan, nil
+}
+
+// GetUpgradeStageOptionsE returns a copy of the options configured to run terraform with the versions of the given
+// stage, downloading terraform if necessary, and writes the provider override file for the stage into the TerraformDir.
+func GetUpgradeStageOptionsE(t testing.TestingT, options *Options, stage VersionStage) (*Options, error) {
+	stageOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case stage.TerraformBinary != "":
+		stageOptions.TerraformBinary = stage.TerraformBinary
+	case stage.TerraformVersion != "":
+		binary, err := DownloadTerraformVersionE(t, options, stage.TerraformVersion)
+		if err != nil {
+			return nil, err
+		}
+		stageOptions.TerraformBinary = binary
+	}
+	if stage.PluginDir != "" {
+		stageOptions.PluginDir = stage.PluginDir
+	}
+	// The lock file pins the providers of the previous stage, so they need to be upgraded.
+	stageOptions.Upgrade = true
+
+	overridePath := filepath.Join(options.TerraformDir, upgradeOverrideFileName)
+	if len(stage.Providers) == 0 {
+		if err := os.Remove(overridePath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return stageOptions, nil
+	}
+	if err := ioutil.WriteFile(overridePath, []byte(formatProviderOverride(stage.Providers)), 0644); err != nil {
+		return nil, err
+	}
+	return stageOptions, nil
+}
+
+// formatProviderOverride renders a terraform block that pins the given providers.
+func formatProviderOverride(providers map[string]ProviderRequirement) string {
+	names := []string{}
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	builder.WriteString("terraform {\n  required_providers {\n")
+	for _, name := range names {
+		provider := providers[name]
+		fmt.Fprintf(&builder, "    %s = {\n", name)
+		if provider.Source != "" {
+			fmt.Fprintf(&builder, "      source  = %q\n", provider.Source)
+		}
+		fmt.Fprintf(&builder, "      version = %q\n", provider.Version)
+		builder.WriteString("    }\n")
+	}
+	builder.WriteString("  }\n}\n")
+	return builder.String()
+}
+
+// getChangedResourceAddresses returns the sorted addresses of all the resources that the plan changes in any way.
+func getChangedResourceAddresses(plan *PlanStruct) []string {
+	addresses := []string{}
+	for address, resourceChange := range plan.ResourceChangesMap {
+		change := resourceChange.Change
+		if change == nil || change.Actions.NoOp() || change.Actions.Read() {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// Downloads of the same version by parallel tests are serialized so that they don't clobber each other.
+var terraformDownloadMutex sync.Mutex
+
+// DownloadTerraformVersion downloads the given version of terraform for the current platform, caching it across tests,
+// and returns the path to the binary. This will fail the test if the download fails.
+func DownloadTerraformVersion(t testing.TestingT, options *Options, version string) string {
+	binary, err := DownloadTerraformVersionE(t, options, version)
+	require.NoError(t, err)
+	return binary
+}
+
+// DownloadTerraformVersionE downloads the given version of terraform for the current platform, caching it across tests,
+// and returns the path to the binary.
+func DownloadTerraformVersionE(t testing.TestingT, options *Options, version string) (string, error) {
+	version = strings.TrimPrefix(version, "v")
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	binaryDir := filepath.Join(cacheDir, "terratest", "terraform", version)
+	binaryName := "terraform"
+	if runtime.GOOS == "windows" {
+		binaryName = "terraform.exe"
+	}
+	binaryPath := filepath.Join(binaryDir, binaryName)
+
+	terraformDownloadMutex.Lock()
+	defer terraformDownloadMutex.Unlock()
+
+	if _, err := os.Stat(binaryPath); err == nil {
+		return binaryPath, nil
+	}
+
+	url := fmt.Sprintf("%s/%s/terraform_%s_%s_%s.zip", strings.TrimSuffix(TerraformReleasesURL, "/"), version, version, runtime.GOOS, runtime.GOARCH)
+	options.Logger.Logf(t, "Downloading terraform %s from %s", version, url)
+
+	zipFile, err := ioutil.TempFile("", "terratest-terraform-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", TerraformDownloadFailed{Version: version, URL: url, StatusCode: resp.StatusCode}
+	}
+	if _, err := io.Copy(zipFile, resp.Body); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(binaryDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := extractFileFromZip(zipFile.Name(), binaryName, binaryPath); err != nil {
+		return "", err
+	}
+	return binaryPath, nil
+}
+
+// extractFileFromZip extracts the file with the given name from the zip archive to destPath, making it executable. The
+// file is written to a temporary path first so that an interrupted extraction doesn't leave a broken binary behind.
+func extractFileFromZip(zipPath string, name string, destPath string) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		tmpPath := destPath + ".tmp"
+		dest, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dest, src); err != nil {
+			dest.Close()
+			return err
+		}
+		if err := dest.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmpPath, destPath)
+	}
+	return fmt.Errorf("%s not found in %s", name, zipPath)
+}
@@ -0,0 +1,102 @@
+package terraform
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatProviderOverride(t *testing.T) {
+	t.Parallel()
+
+	override := formatProviderOverride(map[string]ProviderRequirement{
+		"random": {Source: "hashicorp/random", Version: "= 3.4.3"},
+		"aws":    {Version: "~> 4.0"},
+	})
+	expected := `terraform {
+  required_providers {
+    aws = {
+      version = "~> 4.0"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = "= 3.4.3"
+    }
+  }
+}
+`
+	assert.Equal(t, expected, override)
+}
+
+func TestGetChangedResourceAddresses(t *testing.T) {
+	t.Parallel()
+
+	plan := &PlanStruct{
+		ResourceChangesMap: map[string]*tfjson.ResourceChange{
+			"null_resource.unchanged": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+			"data.null_data_source.x": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionRead}}},
+			"null_resource.updated":   {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+			"null_resource.replaced":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+		},
+	}
+	assert.Equal(t, []string{"null_resource.replaced", "null_resource.updated"}, getChangedResourceAddresses(plan))
+}
+
+func TestDownloadTerraformVersion(t *testing.T) {
+	// Not parallel, since this changes TerraformReleasesURL.
+	binaryName := "terraform"
+	if runtime.GOOS == "windows" {
+		binaryName = "terraform.exe"
+	}
+	version := fmt.Sprintf("0.0.0-test.%d", time.Now().UnixNano())
+
+	archive := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(archive)
+	file, err := zipWriter.Create(binaryName)
+	require.NoError(t, err)
+	_, err = file.Write([]byte("fake terraform"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	requests := 0
+	expectedPath := fmt.Sprintf("/%s/terraform_%s_%s_%s.zip", version, version, runtime.GOOS, runtime.GOARCH)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != expectedPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	originalURL := TerraformReleasesURL
+	TerraformReleasesURL = server.URL
+	defer func() { TerraformReleasesURL = originalURL }()
+
+	binary := DownloadTerraformVersion(t, &Options{}, "v"+version)
+	defer os.RemoveAll(filepath.Dir(binary))
+
+	contents, err := ioutil.ReadFile(binary)
+	require.NoError(t, err)
+	assert.Equal(t, "fake terraform", string(contents))
+
+	// The second call should be served from the cache.
+	assert.Equal(t, binary, DownloadTerraformVersion(t, &Options{}, version))
+	assert.Equal(t, 1, requests)
+
+	_, err = DownloadTerraformVersionE(t, &Options{}, "0.0.0-missing")
+	assert.IsType(t, TerraformDownloadFailed{}, err)
+}
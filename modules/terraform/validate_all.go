@@ -0,0 +1,181 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultValidateAllConcurrency is the number of modules ValidateAll checks at the same time if no concurrency is
+// configured.
+const DefaultValidateAllConcurrency = 4
+
+// ValidateAllOptions configures how ValidateAll sweeps a repository.
+type ValidateAllOptions struct {
+	// Paths of directories, relative to the root dir, to skip (including all their subdirectories).
+	ExcludeDirs []string
+
+	// The number of modules to check at the same time. Defaults to DefaultValidateAllConcurrency.
+	Concurrency int
+
+	// Set to true to skip the `terraform fmt -check` step.
+	SkipFmtCheck bool
+
+	// The options to use as a template for running terraform in each module (e.g., to set TerraformBinary, EnvVars, or
+	// Logger). TerraformDir is set for each module automatically.
+	TerraformOptions *Options
+}
+
+// ModuleValidationResult is the outcome of checking a single module with ValidateAll.
+type ModuleValidationResult struct {
+	Dir           string // The path of the module, relative to the root dir
+	InitError     error  // The error from `terraform init -backend=false`, if any
+	ValidateError error  // The error from `terraform validate`, if any
+	FmtError      error  // The error from `terraform fmt -check`, if any (this lists the files that need formatting)
+}
+
+// Failed returns true if any of the checks on the module failed.
+func (result ModuleValidationResult) Failed() bool {
+	return result.InitError != nil || result.ValidateError != nil || result.FmtError != nil
+}
+
+// ValidateAll finds all the terraform modules (folders with .tf files) under rootDir and runs `terraform init
+// -backend=false`, `terraform validate`, and `terraform fmt -check` on each of them concurrently. Returns the result for
+// each module. This will fail the test if any of the modules fail any of the checks.
+func ValidateAll(t testing.TestingT, rootDir string, opts *ValidateAllOptions) []ModuleValidationResult {
+	results, err := ValidateAllE(t, rootDir, opts)
+	require.NoError(t, err)
+	return results
+}
+
+// ValidateAllE finds all the terraform modules (folders with .tf files) under rootDir and runs `terraform init
+// -backend=false`, `terraform validate`, and `terraform fmt -check` on each of them concurrently. The checks run on a
+// temporary copy of rootDir, so the .terraform folders and lock files init creates don't pollute the repository.
+// Returns the result for each module, sorted by path, and a ModuleValidationFailures error if any of the modules fail any
+// of the checks.
+func ValidateAllE(t testing.TestingT, rootDir string, opts *ValidateAllOptions) ([]ModuleValidationResult, error) {
+	if opts == nil {
+		opts = &ValidateAllOptions{}
+	}
+	templateOptions := opts.TerraformOptions
+	if templateOptions == nil {
+		templateOptions = &Options{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultValidateAllConcurrency
+	}
+
+	testFolder, err := files.CopyTerraformFolderToTemp(rootDir, "terratest-validate-all")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(testFolder)
+
+	moduleDirs, err := findTerraformModuleDirs(testFolder, opts.ExcludeDirs)
+	if err != nil {
+		return nil, err
+	}
+	templateOptions.Logger.Logf(t, "Validating %d terraform modules found in %s", len(moduleDirs), rootDir)
+
+	results := make([]ModuleValidationResult, len(moduleDirs))
+	semaphore := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	for i, moduleDir := range moduleDirs {
+		wg.Add(1)
+		go func(i int, moduleDir string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = validateModule(t, templateOptions, filepath.Join(testFolder, moduleDir), !opts.SkipFmtCheck)
+			results[i].Dir = moduleDir
+		}(i, moduleDir)
+	}
+	wg.Wait()
+
+	failures := ModuleValidationFailures{}
+	for _, result := range results {
+		if result.Failed() {
+			failures = append(failures, result)
+		}
+	}
+	if len(failures) > 0 {
+		return results, failures
+	}
+	return results, nil
+}
+
+// validateModule runs the ValidateAll checks on the module in the given dir.
+func validateModule(t testing.TestingT, templateOptions *Options, moduleDir string, checkFmt bool) ModuleValidationResult {
+	result := ModuleValidationResult{}
+
+	options, err := templateOptions.Clone()
+	if err != nil {
+		result.InitError = err
+		return result
+	}
+	options.TerraformDir = moduleDir
+
+	initArgs := []string{"init", "-backend=false", "-input=false"}
+	initArgs = append(initArgs, FormatTerraformPluginDirAsArgs(options.PluginDir)...)
+	if options.NoColor {
+		initArgs = append(initArgs, "-no-color")
+	}
+	release := acquireInitSlot()
+	_, err = RunTerraformCommandE(t, options, initArgs...)
+	release()
+	if err != nil {
+		result.InitError = err
+	} else if _, err := ValidateE(t, options); err != nil {
+		result.ValidateError = err
+	}
+
+	// fmt doesn't depend on init, so we check it even if init failed.
+	if checkFmt {
+		if out, err := RunTerraformCommandAndGetStdoutE(t, options, "fmt", "-check", "-list=true"); err != nil {
+			result.FmtError = UnformattedFiles{Files: strings.Fields(out), Underlying: err}
+		}
+	}
+	return result
+}
+
+// findTerraformModuleDirs returns the paths, relative to rootDir, of all the folders under rootDir that contain .tf
+// files, excluding hidden folders (such as .terraform) and the given exclude dirs.
+func findTerraformModuleDirs(rootDir string, excludeDirs []string) ([]string, error) {
+	tfFiles, err := files.FindTerraformSourceFilesInDir(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{}
+	for _, tfFile := range tfFiles {
+		relDir, err := filepath.Rel(rootDir, filepath.Dir(tfFile))
+		if err != nil {
+			return nil, err
+		}
+		if isExcludedDir(relDir, excludeDirs) || collections.ListContains(dirs, relDir) {
+			continue
+		}
+		dirs = append(dirs, relDir)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+func isExcludedDir(relDir string, excludeDirs []string) bool {
+	for _, excludeDir := range excludeDirs {
+		excludeDir = filepath.Clean(excludeDir)
+		if relDir == excludeDir || strings.HasPrefix(relDir, excludeDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package terraform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindTerraformModuleDirs(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "terratest-find-modules")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	for _, path := range []string{
+		"main.tf",
+		"modules/vpc/main.tf",
+		"modules/vpc/outputs.tf",
+		"modules/vpc/README.md",
+		"modules/vpc/.terraform/modules/foo/main.tf",
+		"examples/basic/main.tf",
+		"examples/legacy/main.tf",
+		"docs/README.md",
+	} {
+		fullPath := filepath.Join(rootDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(fullPath, []byte{}, 0644))
+	}
+
+	dirs, err := findTerraformModuleDirs(rootDir, []string{"examples/legacy/"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".", "examples/basic", "modules/vpc"}, dirs)
+}
+
+func TestValidateAll(t *testing.T) {
+	t.Parallel()
+
+	results := ValidateAll(t, "../../test/fixtures/terraform-validation-valid", &ValidateAllOptions{
+		TerraformOptions: &Options{NoColor: true},
+	})
+	require.Len(t, results, 1)
+	assert.Equal(t, ".", results[0].Dir)
+	assert.False(t, results[0].Failed())
+}
+
+func TestValidateAllReportsFailuresPerModule(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "terratest-validate-all")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "good"), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootDir, "good", "main.tf"), []byte("output \"foo\" {\n  value = \"bar\"\n}\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "unformatted"), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootDir, "unformatted", "main.tf"), []byte("output \"foo\" {\nvalue=\"bar\"\n}\n"), 0644))
+
+	results, err := ValidateAllE(t, rootDir, &ValidateAllOptions{TerraformOptions: &Options{NoColor: true}})
+	require.Error(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "good", results[0].Dir)
+	assert.False(t, results[0].Failed())
+
+	assert.Equal(t, "unformatted", results[1].Dir)
+	require.IsType(t, UnformattedFiles{}, results[1].FmtError)
+	assert.Equal(t, []string{"main.tf"}, results[1].FmtError.(UnformattedFiles).Files)
+	assert.NoError(t, results[1].ValidateError)
+}
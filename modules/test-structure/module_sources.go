@@ -0,0 +1,248 @@
+package test_structure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ModuleSourceOverride records a module source that was rewritten to a local path.
+type ModuleSourceOverride struct {
+	File           string // The terraform file the module block is in
+	Module         string // The name of the module block
+	OriginalSource string // The source of the module before it was rewritten
+	LocalSource    string // The local path the source was rewritten to, relative to the folder of the file
+}
+
+// CopyTerraformFolderToTempWithModuleSources copies the given root folder to a randomly-named temp folder, like
+// CopyTerraformFolderToTemp, and then rewrites the source of every module block in the copy that matches one of the
+// given module sources to the local path it maps to. This is useful to test a change to a shared module against the
+// modules that consume it, without having to publish a new version of the shared module first. For example:
+//
+//	tempTestFolder := test_structure.CopyTerraformFolderToTempWithModuleSources(t, "..", "examples/consumer", map[string]string{
+//		"git::https://github.com/org/terraform-shared-modules.git": "../../terraform-shared-modules",
+//		"org/vpc/aws":                                              "../../terraform-aws-vpc",
+//	})
+//
+// The keys are registry addresses or git URLs without a ref, and the values are paths to local checkouts, relative to
+// the current working directory. See OverrideModuleSources for how sources are matched.
+//
+// Unlike CopyTerraformFolderToTemp, this always copies the root folder, even if a SKIP_<stage> environment variable is
+// set, as the original folder must not be modified.
+func CopyTerraformFolderToTempWithModuleSources(t testing.TestingT, rootFolder string, terraformModuleFolder string, moduleSources map[string]string) string {
+	fullTerraformModuleFolder := filepath.Join(rootFolder, terraformModuleFolder)
+
+	exists, err := files.FileExistsE(fullTerraformModuleFolder)
+	require.NoError(t, err)
+	if !exists {
+		t.Fatal(files.DirNotFoundError{Directory: fullTerraformModuleFolder})
+	}
+
+	tmpRootFolder, err := files.CopyTerraformFolderToTemp(rootFolder, cleanName(t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpTestFolder := filepath.Join(tmpRootFolder, terraformModuleFolder)
+	logger.Logf(t, "Copied terraform folder %s to %s", fullTerraformModuleFolder, tmpTestFolder)
+
+	OverrideModuleSources(t, tmpRootFolder, moduleSources)
+	return tmpTestFolder
+}
+
+// OverrideModuleSources rewrites the source of every module block in the terraform files in the given folder (and its
+// subfolders) that matches one of the given module sources to the local path it maps to, and returns the sources that
+// were rewritten. This modifies the files in place, so only run it on a copy of your code (e.g., one made with
+// CopyTerraformFolderToTemp). This will fail the test if there is an error.
+func OverrideModuleSources(t testing.TestingT, folder string, moduleSources map[string]string) []ModuleSourceOverride {
+	overrides, err := OverrideModuleSourcesE(t, folder, moduleSources)
+	require.NoError(t, err)
+	return overrides
+}
+
+// OverrideModuleSourcesE rewrites the source of every module block in the terraform files in the given folder (and its
+// subfolders) that matches one of the given module sources to the local path it maps to, and returns the sources that
+// were rewritten. This modifies the files in place, so only run it on a copy of your code.
+//
+// A module source matches if it refers to the same registry module or git repository as one of the given sources,
+// ignoring the version, the ref, the forced getter prefix (e.g., git::), and the protocol used to clone a repository.
+// For example, "github.com/org/repo" matches both "git::https://github.com/org/repo.git?ref=v1.0.0" and
+// "git@github.com:org/repo.git". If the module source refers to a subfolder (e.g., "org/repo//modules/vpc"), the same
+// subfolder of the local path is used. The version argument of rewritten modules is removed, as local modules don't
+// support it. Files in hidden folders (e.g., .terraform) and JSON terraform files are not rewritten.
+func OverrideModuleSourcesE(t testing.TestingT, folder string, moduleSources map[string]string) ([]ModuleSourceOverride, error) {
+	localPaths := map[string]string{}
+	for source, localPath := range moduleSources {
+		absLocalPath, err := filepath.Abs(localPath)
+		if err != nil {
+			return nil, err
+		}
+		if !files.IsExistingDir(absLocalPath) {
+			return nil, files.DirNotFoundError{Directory: absLocalPath}
+		}
+		localPaths[normalizeModuleSource(source)] = absLocalPath
+	}
+
+	overrides := []ModuleSourceOverride{}
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != folder && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".tf") {
+			return nil
+		}
+		fileOverrides, err := overrideModuleSourcesInFile(path, localPaths)
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, fileOverrides...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, override := range overrides {
+		logger.Logf(t, "Overriding source %s of module %s in %s with %s", override.OriginalSource, override.Module, override.File, override.LocalSource)
+	}
+	return overrides, nil
+}
+
+// overrideModuleSourcesInFile rewrites the sources of the module blocks in the given terraform file that match one of
+// the given normalized module sources, and returns the sources that were rewritten.
+func overrideModuleSourcesInFile(path string, localPaths map[string]string) ([]ModuleSourceOverride, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, diags := hclwrite.ParseConfig(contents, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	overrides := []ModuleSourceOverride{}
+	for _, block := range file.Body().Blocks() {
+		if block.Type() != "module" || len(block.Labels()) != 1 {
+			continue
+		}
+		source, isLiteral := getLiteralStringAttribute(block.Body(), "source")
+		if !isLiteral {
+			continue
+		}
+		base, subdir := splitModuleSource(source)
+		localPath, hasOverride := localPaths[normalizeModuleSource(base)]
+		if !hasOverride {
+			continue
+		}
+
+		localSource, err := formatLocalModuleSource(filepath.Dir(path), filepath.Join(localPath, subdir))
+		if err != nil {
+			return nil, err
+		}
+		block.Body().SetAttributeValue("source", cty.StringVal(localSource))
+		block.Body().RemoveAttribute("version")
+		overrides = append(overrides, ModuleSourceOverride{
+			File:           path,
+			Module:         block.Labels()[0],
+			OriginalSource: source,
+			LocalSource:    localSource,
+		})
+	}
+
+	if len(overrides) == 0 {
+		return overrides, nil
+	}
+	return overrides, ioutil.WriteFile(path, file.Bytes(), 0644)
+}
+
+// getLiteralStringAttribute returns the value of the given attribute of the body, if it is set to a literal string.
+func getLiteralStringAttribute(body *hclwrite.Body, name string) (string, bool) {
+	attribute := body.GetAttribute(name)
+	if attribute == nil {
+		return "", false
+	}
+	tokens := attribute.Expr().BuildTokens(nil)
+	if len(tokens) != 3 || tokens[0].Type != hclsyntax.TokenOQuote || tokens[1].Type != hclsyntax.TokenQuotedLit || tokens[2].Type != hclsyntax.TokenCQuote {
+		return "", false
+	}
+	return string(tokens[1].Bytes), true
+}
+
+// splitModuleSource splits the given module source into the source of the package and the subfolder of the package the
+// module is in (e.g., "git::https://example.com/repo.git//modules/vpc?ref=v1.0.0" is split into
+// "git::https://example.com/repo.git?ref=v1.0.0" and "modules/vpc").
+func splitModuleSource(source string) (string, string) {
+	query := ""
+	if index := strings.Index(source, "?"); index >= 0 {
+		source, query = source[:index], source[index:]
+	}
+
+	// Skip the "//" of the protocol, if any.
+	offset := 0
+	if index := strings.Index(source, "://"); index >= 0 {
+		offset = index + len("://")
+	}
+	index := strings.Index(source[offset:], "//")
+	if index < 0 {
+		return source + query, ""
+	}
+	return source[:offset+index] + query, source[offset+index+len("//"):]
+}
+
+var (
+	forcedGetterRegex = regexp.MustCompile(`^[a-z0-9]+::`)
+	protocolRegex     = regexp.MustCompile(`^[a-z0-9+]+://([^@/]+@)?`)
+	scpLikeGitRegex   = regexp.MustCompile(`^[^@/]+@([^:/]+):`)
+)
+
+// normalizeModuleSource normalizes the given module source (without a subfolder), so that sources that refer to the
+// same registry module or git repository are equal.
+func normalizeModuleSource(source string) string {
+	source = strings.TrimSpace(source)
+	if index := strings.Index(source, "?"); index >= 0 {
+		source = source[:index]
+	}
+	source = forcedGetterRegex.ReplaceAllString(source, "")
+	source = protocolRegex.ReplaceAllString(source, "")
+	source = scpLikeGitRegex.ReplaceAllString(source, "$1/")
+	source = strings.TrimPrefix(source, "registry.terraform.io/")
+	source = strings.TrimSuffix(source, "/")
+	source = strings.TrimSuffix(source, ".git")
+	return strings.ToLower(source)
+}
+
+// formatLocalModuleSource returns the source to use to refer to the module at the given path from a module in the given
+// folder. Terraform only treats sources that start with ./ or ../ as local paths.
+func formatLocalModuleSource(fromFolder string, modulePath string) (string, error) {
+	absFromFolder, err := filepath.Abs(fromFolder)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(absFromFolder, modulePath)
+	if err != nil {
+		return "", err
+	}
+	relPath = filepath.ToSlash(relPath)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return relPath, nil
+	}
+	return fmt.Sprintf("./%s", relPath), nil
+}
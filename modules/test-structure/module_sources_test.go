@@ -0,0 +1,120 @@
+package test_structure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitModuleSource(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		source         string
+		expectedBase   string
+		expectedSubdir string
+	}{
+		{"org/vpc/aws", "org/vpc/aws", ""},
+		{"org/network/aws//modules/vpc", "org/network/aws", "modules/vpc"},
+		{"git::https://github.com/org/repo.git//modules/vpc?ref=v1.0.0", "git::https://github.com/org/repo.git?ref=v1.0.0", "modules/vpc"},
+		{"git::https://github.com/org/repo.git?ref=v1.0.0", "git::https://github.com/org/repo.git?ref=v1.0.0", ""},
+		{"git@github.com:org/repo.git//modules/vpc", "git@github.com:org/repo.git", "modules/vpc"},
+	}
+
+	for _, testCase := range testCases {
+		base, subdir := splitModuleSource(testCase.source)
+		assert.Equal(t, testCase.expectedBase, base, testCase.source)
+		assert.Equal(t, testCase.expectedSubdir, subdir, testCase.source)
+	}
+}
+
+func TestNormalizeModuleSource(t *testing.T) {
+	t.Parallel()
+
+	for _, source := range []string{
+		"github.com/org/repo",
+		"git::https://github.com/org/repo.git?ref=v1.0.0",
+		"git::ssh://git@github.com/org/repo.git",
+		"git@github.com:org/repo.git",
+		"https://github.com/Org/repo",
+	} {
+		assert.Equal(t, "github.com/org/repo", normalizeModuleSource(source), source)
+	}
+	assert.Equal(t, "org/vpc/aws", normalizeModuleSource("registry.terraform.io/org/vpc/aws"))
+	assert.Equal(t, "app.terraform.io/org/vpc/aws", normalizeModuleSource("app.terraform.io/org/vpc/aws"))
+}
+
+func TestOverrideModuleSources(t *testing.T) {
+	t.Parallel()
+
+	sharedModules := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sharedModules, "modules", "vpc"), 0777))
+	vpcModule := t.TempDir()
+
+	consumer := t.TempDir()
+	mainTf := `module "vpc" {
+  source  = "org/vpc/aws"
+  version = "~> 1.0"
+
+  name = "test"
+}
+
+module "network" {
+  source = "git::https://github.com/org/shared.git//modules/vpc?ref=v2.0.0"
+}
+
+module "other" {
+  source  = "org/other/aws"
+  version = "1.0.0"
+}
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(consumer, "main.tf"), []byte(mainTf), 0644))
+
+	overrides, err := OverrideModuleSourcesE(t, consumer, map[string]string{
+		"org/vpc/aws":           vpcModule,
+		"github.com/org/shared": sharedModules,
+	})
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+	assert.Equal(t, "vpc", overrides[0].Module)
+	assert.Equal(t, "org/vpc/aws", overrides[0].OriginalSource)
+	assert.Equal(t, "network", overrides[1].Module)
+
+	contents, err := ioutil.ReadFile(filepath.Join(consumer, "main.tf"))
+	require.NoError(t, err)
+	rewritten := string(contents)
+
+	// The rewritten sources are relative, so that terraform treats them as local modules.
+	for _, override := range overrides {
+		assert.Contains(t, override.LocalSource, "../")
+		assert.Contains(t, rewritten, `"`+override.LocalSource+`"`)
+	}
+	absNetworkSource := filepath.Join(consumer, filepath.FromSlash(overrides[1].LocalSource))
+	assert.Equal(t, filepath.Join(sharedModules, "modules", "vpc"), absNetworkSource)
+
+	// Local modules don't support versions, but other modules are left untouched.
+	assert.NotContains(t, rewritten, `"~> 1.0"`)
+	assert.Contains(t, rewritten, `source  = "org/other/aws"`)
+	assert.Contains(t, rewritten, `version = "1.0.0"`)
+	assert.Contains(t, rewritten, `name = "test"`)
+}
+
+func TestOverrideModuleSourcesMissingLocalPath(t *testing.T) {
+	t.Parallel()
+
+	_, err := OverrideModuleSourcesE(t, t.TempDir(), map[string]string{"org/vpc/aws": "/does/not/exist"})
+	require.Error(t, err)
+}
+
+func TestCopyTerraformFolderToTempWithModuleSources(t *testing.T) {
+	t.Parallel()
+
+	vpcModule := t.TempDir()
+	tempFolder := CopyTerraformFolderToTempWithModuleSources(t, "../../test/fixtures", "terraform-basic-configuration", map[string]string{"org/vpc/aws": vpcModule})
+	assert.NotEqual(t, filepath.Join("../../test/fixtures", "terraform-basic-configuration"), tempFolder)
+	assert.DirExists(t, tempFolder)
+}
@@ -0,0 +1,242 @@
+package test_structure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// PlanFileFingerprint identifies the configuration and state a plan file was created from, so that a saved plan can be
+// checked to still be applicable before applying it in a later stage.
+type PlanFileFingerprint struct {
+	ConfigHash string // A hash of the terraform files, var files, and lock file of the module
+	StateHash  string // A hash of the state of the module
+}
+
+// SavePlanFile saves the plan file that was created at terraformOptions.PlanFilePath (e.g., with
+// terraform.InitAndPlan), along with its JSON representation and a fingerprint of the configuration and state it was
+// created from, into the given folder. This allows you to create a plan during one stage, review it (see
+// LoadPlanFileJson), and then apply exactly that plan in a later stage (see LoadPlanFile).
+func SavePlanFile(t testing.TestingT, testFolder string, terraformOptions *terraform.Options) {
+	require.NoError(t, SavePlanFileE(t, testFolder, terraformOptions))
+}
+
+// SavePlanFileE saves the plan file that was created at terraformOptions.PlanFilePath (e.g., with
+// terraform.InitAndPlan), along with its JSON representation and a fingerprint of the configuration and state it was
+// created from, into the given folder.
+func SavePlanFileE(t testing.TestingT, testFolder string, terraformOptions *terraform.Options) error {
+	if terraformOptions.PlanFilePath == "" {
+		return PlanFilePathNotSetErr{}
+	}
+
+	planJson, err := terraform.ShowE(t, terraformOptions)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := getPlanFileFingerprintE(t, terraformOptions)
+	if err != nil {
+		return err
+	}
+
+	planPath := formatPlanFilePath(testFolder)
+	logger.Logf(t, "Storing plan file %s in %s so it can be applied later", terraformOptions.PlanFilePath, planPath)
+	if err := os.MkdirAll(filepath.Dir(planPath), 0777); err != nil {
+		return err
+	}
+	if err := files.CopyFile(terraformOptions.PlanFilePath, planPath); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(formatPlanFileJsonPath(testFolder), []byte(planJson), 0644); err != nil {
+		return err
+	}
+	fingerprintJson, err := json.Marshal(fingerprint)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(formatPlanFileFingerprintPath(testFolder), fingerprintJson, 0644)
+}
+
+// LoadPlanFile verifies that the plan file saved with SavePlanFile in the given folder is still applicable, which means
+// the configuration and state of the module haven't changed since the plan was made, and sets
+// terraformOptions.PlanFilePath to it so that it can be applied with terraform.Apply. Returns the path of the plan file.
+// This will fail the test if the plan is not applicable anymore.
+func LoadPlanFile(t testing.TestingT, testFolder string, terraformOptions *terraform.Options) string {
+	planPath, err := LoadPlanFileE(t, testFolder, terraformOptions)
+	require.NoError(t, err)
+	return planPath
+}
+
+// LoadPlanFileE verifies that the plan file saved with SavePlanFile in the given folder is still applicable, which
+// means the configuration and state of the module haven't changed since the plan was made, and sets
+// terraformOptions.PlanFilePath to it so that it can be applied with terraform.Apply. Returns the path of the plan file,
+// or a StalePlanFileErr if the plan is not applicable anymore.
+func LoadPlanFileE(t testing.TestingT, testFolder string, terraformOptions *terraform.Options) (string, error) {
+	planPath := formatPlanFilePath(testFolder)
+	if !files.FileExists(planPath) {
+		return "", PlanFileNotFoundErr{Path: planPath}
+	}
+
+	fingerprintJson, err := ioutil.ReadFile(formatPlanFileFingerprintPath(testFolder))
+	if err != nil {
+		return "", err
+	}
+	var savedFingerprint PlanFileFingerprint
+	if err := json.Unmarshal(fingerprintJson, &savedFingerprint); err != nil {
+		return "", err
+	}
+
+	currentFingerprint, err := getPlanFileFingerprintE(t, terraformOptions)
+	if err != nil {
+		return "", err
+	}
+	if currentFingerprint.ConfigHash != savedFingerprint.ConfigHash || currentFingerprint.StateHash != savedFingerprint.StateHash {
+		return "", StalePlanFileErr{
+			Path:          planPath,
+			ConfigChanged: currentFingerprint.ConfigHash != savedFingerprint.ConfigHash,
+			StateChanged:  currentFingerprint.StateHash != savedFingerprint.StateHash,
+		}
+	}
+
+	terraformOptions.PlanFilePath = planPath
+	return planPath, nil
+}
+
+// LoadPlanFileJson loads the JSON representation of the plan file saved with SavePlanFile in the given folder, as
+// output by terraform show -json (e.g., to review the plan, or to unmarshal it into a tfjson.Plan).
+func LoadPlanFileJson(t testing.TestingT, testFolder string) string {
+	bytes, err := ioutil.ReadFile(formatPlanFileJsonPath(testFolder))
+	require.NoError(t, err)
+	return string(bytes)
+}
+
+// getPlanFileFingerprintE computes the fingerprint of the current configuration and state of the module.
+func getPlanFileFingerprintE(t testing.TestingT, terraformOptions *terraform.Options) (PlanFileFingerprint, error) {
+	configHash, err := hashTerraformConfig(terraformOptions)
+	if err != nil {
+		return PlanFileFingerprint{}, err
+	}
+
+	// Make sure we read the state, and not the plan file that may be configured on the options.
+	stateOptions, err := terraformOptions.Clone()
+	if err != nil {
+		return PlanFileFingerprint{}, err
+	}
+	stateOptions.PlanFilePath = ""
+	state, err := terraform.RunTerraformCommandAndGetStdoutE(t, stateOptions, "state", "pull")
+	if err != nil {
+		return PlanFileFingerprint{}, err
+	}
+
+	return PlanFileFingerprint{ConfigHash: configHash, StateHash: hashString(state)}, nil
+}
+
+// hashTerraformConfig returns a hash of the terraform files and lock file in the TerraformDir (including the local
+// modules in its subfolders) and the var files configured on the options.
+func hashTerraformConfig(terraformOptions *terraform.Options) (string, error) {
+	paths := []string{}
+	err := filepath.Walk(terraformOptions.TerraformDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != terraformOptions.TerraformDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := info.Name()
+		if strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") || strings.HasSuffix(name, ".tfvars") || name == ".terraform.lock.hcl" {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+	for _, varFile := range terraformOptions.VarFiles {
+		// Terraform resolves relative var file paths from the TerraformDir.
+		if !filepath.IsAbs(varFile) {
+			varFile = filepath.Join(terraformOptions.TerraformDir, varFile)
+		}
+		paths = append(paths, varFile)
+	}
+
+	hash := sha256.New()
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n%d\n", path, len(contents))
+		hash.Write(contents)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func hashString(str string) string {
+	hash := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(hash[:])
+}
+
+// formatPlanFilePath formats a path to save a plan file in the given folder.
+func formatPlanFilePath(testFolder string) string {
+	return FormatTestDataPath(testFolder, "TerraformPlan.tfplan")
+}
+
+// formatPlanFileJsonPath formats a path to save the JSON representation of a plan file in the given folder.
+func formatPlanFileJsonPath(testFolder string) string {
+	return FormatTestDataPath(testFolder, "TerraformPlan.json")
+}
+
+// formatPlanFileFingerprintPath formats a path to save the fingerprint of a plan file in the given folder.
+func formatPlanFileFingerprintPath(testFolder string) string {
+	return FormatTestDataPath(testFolder, "TerraformPlanFingerprint.json")
+}
+
+// PlanFilePathNotSetErr is returned when trying to save a plan file from options that don't have a PlanFilePath.
+type PlanFilePathNotSetErr struct{}
+
+func (e PlanFilePathNotSetErr) Error() string {
+	return "PlanFilePath must be set on the terraform options to save the plan file"
+}
+
+// PlanFileNotFoundErr is returned when loading a plan file that was not saved.
+type PlanFileNotFoundErr struct {
+	Path string
+}
+
+func (e PlanFileNotFoundErr) Error() string {
+	return fmt.Sprintf("No plan file found at %s. Save one with SavePlanFile first.", e.Path)
+}
+
+// StalePlanFileErr is returned when loading a plan file that was made against a different configuration or state than
+// the current one.
+type StalePlanFileErr struct {
+	Path          string
+	ConfigChanged bool
+	StateChanged  bool
+}
+
+func (e StalePlanFileErr) Error() string {
+	changes := []string{}
+	if e.ConfigChanged {
+		changes = append(changes, "configuration")
+	}
+	if e.StateChanged {
+		changes = append(changes, "state")
+	}
+	return fmt.Sprintf("The plan file at %s is stale: the %s changed since the plan was made", e.Path, strings.Join(changes, " and "))
+}
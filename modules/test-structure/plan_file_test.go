@@ -0,0 +1,79 @@
+package test_structure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashTerraformConfig(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(testFolder, ".terraform"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testFolder, "main.tf"), []byte(`output "foo" { value = "bar" }`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testFolder, "prod.tfvars"), []byte(`foo = "bar"`), 0644))
+
+	options := &terraform.Options{TerraformDir: testFolder, VarFiles: []string{"prod.tfvars"}}
+	hash, err := hashTerraformConfig(options)
+	require.NoError(t, err)
+
+	// Files that terraform doesn't read don't change the hash.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testFolder, "README.md"), []byte("# Test"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testFolder, ".terraform", "modules.tf"), []byte(""), 0644))
+	unchangedHash, err := hashTerraformConfig(options)
+	require.NoError(t, err)
+	assert.Equal(t, hash, unchangedHash)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testFolder, "prod.tfvars"), []byte(`foo = "baz"`), 0644))
+	changedHash, err := hashTerraformConfig(options)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changedHash)
+}
+
+func TestSaveAndLoadPlanFile(t *testing.T) {
+	t.Parallel()
+
+	testFolder, err := files.CopyTerraformFolderToTemp("../../test/fixtures/terraform-basic-configuration", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(testFolder)
+
+	planFilePath := filepath.Join(t.TempDir(), "plan.out")
+	options := &terraform.Options{
+		TerraformDir: testFolder,
+		PlanFilePath: planFilePath,
+	}
+	terraform.InitAndPlan(t, options)
+	SavePlanFile(t, testFolder, options)
+	assert.Contains(t, LoadPlanFileJson(t, testFolder), "resource_changes")
+
+	// A later stage applies the saved plan.
+	applyOptions := &terraform.Options{TerraformDir: testFolder}
+	planPath := LoadPlanFile(t, testFolder, applyOptions)
+	assert.Equal(t, planPath, applyOptions.PlanFilePath)
+	terraform.Apply(t, applyOptions)
+
+	// Once applied, the state changed, so the plan can't be applied again.
+	_, err = LoadPlanFileE(t, testFolder, &terraform.Options{TerraformDir: testFolder})
+	require.IsType(t, StalePlanFileErr{}, err)
+	assert.True(t, err.(StalePlanFileErr).StateChanged)
+	assert.False(t, err.(StalePlanFileErr).ConfigChanged)
+}
+
+func TestLoadPlanFileEReturnsErrorForInvalidFingerprint(t *testing.T) {
+	t.Parallel()
+
+	testFolder := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Dir(formatPlanFilePath(testFolder)), 0777))
+	require.NoError(t, ioutil.WriteFile(formatPlanFilePath(testFolder), []byte("plan"), 0644))
+	require.NoError(t, ioutil.WriteFile(formatPlanFileFingerprintPath(testFolder), []byte("not json"), 0644))
+
+	_, err := LoadPlanFileE(t, testFolder, &terraform.Options{TerraformDir: testFolder})
+	require.Error(t, err)
+}
@@ -0,0 +1,12 @@
+output "password" {
+  value     = "correct-horse-battery-staple"
+  sensitive = true
+}
+
+output "credentials" {
+  value = {
+    username = "terratest"
+    token    = "tok-5f4dcc3b5aa765d61d8327deb882cf99"
+  }
+  sensitive = true
+}